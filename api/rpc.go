@@ -0,0 +1,439 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"atulm/cocli/session"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+// JSON-RPC 2.0 error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcServerError    = -32000
+)
+
+// maxRPCLineBytes bounds the size of one request line
+const maxRPCLineBytes = 10 << 20
+
+// rpcRequest is a JSON-RPC request or notification (no id)
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// rpcMessage is a response or server notification
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  interface{}     `json:"params,omitempty"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// SessionInfo describes a session in listSessions results
+type SessionInfo struct {
+	SessionID string    `json:"sessionId"`
+	Model     string    `json:"model"`
+	Turns     int       `json:"turns"`
+	CreatedAt time.Time `json:"createdAt"`
+	Busy      bool      `json:"busy"`
+}
+
+// rpcSession is a conversation held open for an editor
+type rpcSession struct {
+	id        string
+	model     string
+	sess      Session
+	turns     int
+	createdAt time.Time
+	busy      bool
+}
+
+// RPCServer speaks newline-delimited JSON-RPC 2.0, one message per line, so
+// editor extensions can drive cocli without scraping terminal output.
+//
+// Methods:
+//
+//	newSession   {model?}              -> {sessionId, model}
+//	listSessions {}                    -> [SessionInfo]
+//	listModels   {}                    -> [{id, name}]
+//	setModel     {sessionId?, model}   -> {sessionId, model}
+//	prompt       {sessionId?, text}    -> {sessionId, content}
+//	cancel       {sessionId?}          -> {cancelled}
+//
+// While a prompt runs, the server sends "delta" notifications with
+// {sessionId, requestId, text}. Methods that take an optional sessionId use
+// the most recently created session, creating one if there is none.
+// Requests are handled concurrently, so cancel can interrupt a prompt.
+type RPCServer struct {
+	backend      Backend
+	defaultModel string
+
+	outMu sync.Mutex
+	out   *json.Encoder
+
+	mu       sync.Mutex
+	sessions map[string]*rpcSession
+	latest   string
+	nextID   int
+}
+
+// NewRPCServer creates a server that writes responses and notifications to
+// out; defaultModel is used for sessions that don't name a model
+func NewRPCServer(backend Backend, defaultModel string, out io.Writer) *RPCServer {
+	if defaultModel == "" {
+		defaultModel = session.DefaultModel
+	}
+	return &RPCServer{
+		backend:      backend,
+		defaultModel: defaultModel,
+		out:          json.NewEncoder(out),
+		sessions:     make(map[string]*rpcSession),
+	}
+}
+
+// Serve reads requests from in until EOF, waits for requests in flight and
+// closes every session
+func (s *RPCServer) Serve(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), maxRPCLineBytes)
+
+	var wg sync.WaitGroup
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var req rpcRequest
+		if err := json.Unmarshal(line, &req); err != nil {
+			s.send(rpcMessage{Error: &rpcError{Code: rpcParseError, Message: "parse error: " + err.Error()}})
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.handle(req)
+		}()
+	}
+	wg.Wait()
+	s.closeSessions()
+	return scanner.Err()
+}
+
+// handle runs one request and sends its response
+func (s *RPCServer) handle(req rpcRequest) {
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		s.reply(req, nil, &rpcError{Code: rpcInvalidRequest, Message: "invalid request"})
+		return
+	}
+
+	var (
+		result interface{}
+		err    error
+	)
+	switch req.Method {
+	case "newSession":
+		result, err = s.newSession(req)
+	case "listSessions":
+		result = s.listSessions()
+	case "listModels":
+		result, err = s.listModels()
+	case "setModel":
+		result, err = s.setModel(req)
+	case "prompt":
+		result, err = s.prompt(req)
+	case "cancel":
+		result, err = s.cancel(req)
+	default:
+		err = &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("unknown method %q", req.Method)}
+	}
+
+	var rpcErr *rpcError
+	if err != nil && !errors.As(err, &rpcErr) {
+		rpcErr = &rpcError{Code: rpcServerError, Message: err.Error()}
+	}
+	s.reply(req, result, rpcErr)
+}
+
+// reply answers a request; notifications (no id) get no response
+func (s *RPCServer) reply(req rpcRequest, result interface{}, err *rpcError) {
+	if len(req.ID) == 0 {
+		return
+	}
+	if err == nil && result == nil {
+		result = struct{}{}
+	}
+	s.send(rpcMessage{ID: req.ID, Result: result, Error: err})
+}
+
+// notify sends a notification to the client
+func (s *RPCServer) notify(method string, params interface{}) {
+	s.send(rpcMessage{Method: method, Params: params})
+}
+
+// send writes one message as a line
+func (s *RPCServer) send(msg rpcMessage) {
+	msg.JSONRPC = "2.0"
+	s.outMu.Lock()
+	defer s.outMu.Unlock()
+	_ = s.out.Encode(msg)
+}
+
+// decodeParams unmarshals request params into v
+func decodeParams(req rpcRequest, v interface{}) error {
+	if len(req.Params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(req.Params, v); err != nil {
+		return &rpcError{Code: rpcInvalidParams, Message: "invalid params: " + err.Error()}
+	}
+	return nil
+}
+
+func (s *RPCServer) newSession(req rpcRequest) (interface{}, error) {
+	var params struct {
+		Model string `json:"model"`
+	}
+	if err := decodeParams(req, &params); err != nil {
+		return nil, err
+	}
+	rs, err := s.createSession(params.Model)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"sessionId": rs.id, "model": rs.model}, nil
+}
+
+// createSession starts a session on model (or the default model)
+func (s *RPCServer) createSession(model string) (*rpcSession, error) {
+	id, err := resolveModel(s.backend, s.defaultModel, model)
+	if err != nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("unknown model %q", model)}
+	}
+	sess, err := s.backend.NewSession(id, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	rs := &rpcSession{id: strconv.Itoa(s.nextID), model: id, sess: sess, createdAt: time.Now()}
+	s.sessions[rs.id] = rs
+	s.latest = rs.id
+	return rs, nil
+}
+
+// session finds a session by ID; an empty ID means the latest session,
+// which is created if create is set and there is none
+func (s *RPCServer) session(id string, create bool) (*rpcSession, error) {
+	s.mu.Lock()
+	if id == "" {
+		id = s.latest
+	}
+	rs := s.sessions[id]
+	s.mu.Unlock()
+
+	switch {
+	case rs != nil:
+		return rs, nil
+	case id == "" && create:
+		return s.createSession("")
+	case id == "":
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "no session; call newSession or prompt first"}
+	}
+	return nil, &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("unknown session %q", id)}
+}
+
+func (s *RPCServer) listSessions() []SessionInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	infos := make([]SessionInfo, 0, len(s.sessions))
+	for _, rs := range s.sessions {
+		infos = append(infos, SessionInfo{
+			SessionID: rs.id,
+			Model:     rs.model,
+			Turns:     rs.turns,
+			CreatedAt: rs.createdAt,
+			Busy:      rs.busy,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].CreatedAt.Before(infos[j].CreatedAt) })
+	return infos
+}
+
+func (s *RPCServer) listModels() (interface{}, error) {
+	models, err := s.backend.Models()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models: %w", err)
+	}
+	type model struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	list := make([]model, 0, len(models))
+	for _, m := range models {
+		list = append(list, model{ID: m.ID, Name: m.Name})
+	}
+	return list, nil
+}
+
+// setModel replaces a session's copilot session with one on another model.
+// Like /models in the terminal, this starts a fresh conversation.
+func (s *RPCServer) setModel(req rpcRequest) (interface{}, error) {
+	var params struct {
+		SessionID string `json:"sessionId"`
+		Model     string `json:"model"`
+	}
+	if err := decodeParams(req, &params); err != nil {
+		return nil, err
+	}
+	if params.Model == "" {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "model is required"}
+	}
+	rs, err := s.session(params.SessionID, true)
+	if err != nil {
+		return nil, err
+	}
+	model, err := resolveModel(s.backend, s.defaultModel, params.Model)
+	if err != nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("unknown model %q", params.Model)}
+	}
+	sess, err := s.backend.NewSession(model, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	s.mu.Lock()
+	if rs.busy {
+		s.mu.Unlock()
+		_ = sess.Destroy()
+		return nil, &rpcError{Code: rpcServerError, Message: "session is busy"}
+	}
+	old := rs.sess
+	rs.sess, rs.model, rs.turns = sess, model, 0
+	s.mu.Unlock()
+	_ = old.Destroy()
+
+	return map[string]string{"sessionId": rs.id, "model": model}, nil
+}
+
+// prompt sends text to a session, streaming the reply as delta notifications
+func (s *RPCServer) prompt(req rpcRequest) (interface{}, error) {
+	var params struct {
+		SessionID string `json:"sessionId"`
+		Text      string `json:"text"`
+	}
+	if err := decodeParams(req, &params); err != nil {
+		return nil, err
+	}
+	if params.Text == "" {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "text is required"}
+	}
+	rs, err := s.session(params.SessionID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if rs.busy {
+		s.mu.Unlock()
+		return nil, &rpcError{Code: rpcServerError, Message: "session is busy"}
+	}
+	rs.busy = true
+	sess := rs.sess
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		rs.busy = false
+		s.mu.Unlock()
+	}()
+
+	requestID := req.ID
+	unsubscribe := sess.On(func(event copilot.SessionEvent) {
+		if event.Type == copilot.AssistantMessageDelta && event.Data.DeltaContent != nil {
+			s.notify("delta", map[string]interface{}{
+				"sessionId": rs.id,
+				"requestId": requestID,
+				"text":      *event.Data.DeltaContent,
+			})
+		}
+	})
+	defer unsubscribe()
+
+	reply, err := sess.SendAndWait(copilot.MessageOptions{Prompt: params.Text}, requestTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	rs.turns++
+	s.mu.Unlock()
+
+	content := ""
+	if reply != nil && reply.Data.Content != nil {
+		content = *reply.Data.Content
+	}
+	return map[string]string{"sessionId": rs.id, "content": content}, nil
+}
+
+// cancel aborts the prompt running in a session
+func (s *RPCServer) cancel(req rpcRequest) (interface{}, error) {
+	var params struct {
+		SessionID string `json:"sessionId"`
+	}
+	if err := decodeParams(req, &params); err != nil {
+		return nil, err
+	}
+	rs, err := s.session(params.SessionID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	busy, sess := rs.busy, rs.sess
+	s.mu.Unlock()
+	if !busy {
+		return map[string]bool{"cancelled": false}, nil
+	}
+	if err := sess.Abort(); err != nil {
+		return nil, err
+	}
+	return map[string]bool{"cancelled": true}, nil
+}
+
+// closeSessions destroys every session
+func (s *RPCServer) closeSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, rs := range s.sessions {
+		_ = rs.sess.Destroy()
+		delete(s.sessions, id)
+	}
+	s.latest = ""
+}
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rpcConn drives an RPCServer over pipes
+type rpcConn struct {
+	t      *testing.T
+	in     *io.PipeWriter
+	lines  chan map[string]interface{}
+	served chan error
+}
+
+func startRPC(t *testing.T, backend Backend) *rpcConn {
+	t.Helper()
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c := &rpcConn{t: t, in: inW, lines: make(chan map[string]interface{}, 100), served: make(chan error, 1)}
+
+	srv := NewRPCServer(backend, "", outW)
+	go func() {
+		c.served <- srv.Serve(inR)
+		outW.Close()
+	}()
+	go func() {
+		scanner := bufio.NewScanner(outR)
+		for scanner.Scan() {
+			var msg map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+				t.Errorf("server wrote invalid JSON %q: %v", scanner.Text(), err)
+				continue
+			}
+			c.lines <- msg
+		}
+		close(c.lines)
+	}()
+	t.Cleanup(func() { inW.Close() })
+	return c
+}
+
+func (c *rpcConn) write(line string) {
+	c.t.Helper()
+	if _, err := fmt.Fprintln(c.in, line); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// call sends a request and returns the notifications received before its
+// response, and the response
+func (c *rpcConn) call(id int, method, params string) ([]map[string]interface{}, map[string]interface{}) {
+	c.t.Helper()
+	c.write(fmt.Sprintf(`{"jsonrpc": "2.0", "id": %d, "method": %q, "params": %s}`, id, method, params))
+	var notes []map[string]interface{}
+	for {
+		select {
+		case msg := <-c.lines:
+			if msg["id"] == float64(id) {
+				return notes, msg
+			}
+			notes = append(notes, msg)
+		case <-time.After(5 * time.Second):
+			c.t.Fatalf("no response to %s", method)
+		}
+	}
+}
+
+func result(t *testing.T, resp map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	if resp["error"] != nil {
+		t.Fatalf("error response: %v", resp["error"])
+	}
+	r, _ := resp["result"].(map[string]interface{})
+	return r
+}
+
+func TestRPC_PromptStreamsDeltas(t *testing.T) {
+	backend := &fakeBackend{}
+	c := startRPC(t, backend)
+
+	notes, resp := c.call(1, "prompt", `{"text": "hi"}`)
+	r := result(t, resp)
+	if r["content"] != "ok" || r["sessionId"] != "1" {
+		t.Errorf("result = %v, want content ok in session 1", r)
+	}
+
+	var text strings.Builder
+	for _, n := range notes {
+		if n["method"] != "delta" {
+			t.Errorf("unexpected notification %v", n)
+			continue
+		}
+		params := n["params"].(map[string]interface{})
+		if params["requestId"] != float64(1) || params["sessionId"] != "1" {
+			t.Errorf("delta params = %v, want request 1 in session 1", params)
+		}
+		text.WriteString(params["text"].(string))
+	}
+	if text.String() != "ok" {
+		t.Errorf("streamed text = %q, want ok", text.String())
+	}
+	if backend.created[0].prompt != "hi" {
+		t.Errorf("prompt = %q, want hi", backend.created[0].prompt)
+	}
+}
+
+func TestRPC_Sessions(t *testing.T) {
+	backend := &fakeBackend{}
+	c := startRPC(t, backend)
+
+	_, resp := c.call(1, "newSession", `{"model": "GPT-5"}`)
+	if r := result(t, resp); r["sessionId"] != "1" || r["model"] != "gpt-5" {
+		t.Errorf("newSession = %v, want session 1 on gpt-5", r)
+	}
+	c.call(2, "newSession", `{}`)
+	c.call(3, "prompt", `{"sessionId": "1", "text": "hi"}`)
+
+	_, resp = c.call(4, "listSessions", `{}`)
+	list, _ := resp["result"].([]interface{})
+	if len(list) != 2 {
+		t.Fatalf("listSessions = %v, want 2 sessions", resp["result"])
+	}
+	first := list[0].(map[string]interface{})
+	if first["sessionId"] != "1" || first["turns"] != float64(1) {
+		t.Errorf("first session = %v, want session 1 with 1 turn", first)
+	}
+
+	_, resp = c.call(5, "setModel", `{"sessionId": "1", "model": "claude-sonnet-4.5"}`)
+	if r := result(t, resp); r["model"] != "claude-sonnet-4.5" {
+		t.Errorf("setModel = %v, want claude-sonnet-4.5", r)
+	}
+	if !backend.created[0].destroyed {
+		t.Error("setModel should destroy the old copilot session")
+	}
+
+	c.in.Close()
+	if err := <-c.served; err != nil {
+		t.Errorf("Serve() error = %v", err)
+	}
+	for i, sess := range backend.created {
+		if !sess.destroyed {
+			t.Errorf("session %d not destroyed when the client went away", i)
+		}
+	}
+}
+
+func TestRPC_Cancel(t *testing.T) {
+	block := make(chan struct{})
+	backend := &fakeBackend{session: &fakeSession{block: block}}
+	c := startRPC(t, backend)
+
+	_, resp := c.call(1, "cancel", `{}`)
+	if resp["error"] == nil {
+		t.Error("cancel without a session should fail")
+	}
+
+	c.write(`{"jsonrpc": "2.0", "id": 2, "method": "prompt", "params": {"text": "long"}}`)
+	// Wait for the prompt to start
+	for i := 0; ; i++ {
+		_, resp := c.call(100+i, "listSessions", `{}`)
+		if list, _ := resp["result"].([]interface{}); len(list) == 1 && list[0].(map[string]interface{})["busy"] == true {
+			break
+		}
+		if i > 100 {
+			t.Fatal("prompt never started")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	_, resp = c.call(3, "cancel", `{}`)
+	if r := result(t, resp); r["cancelled"] != true {
+		t.Errorf("cancel = %v, want cancelled", r)
+	}
+	_, resp = c.call(2, "prompt", `{}`) // collect the prompt's response
+	if resp["error"] == nil {
+		t.Error("cancelled prompt should return an error")
+	}
+}
+
+func TestRPC_Errors(t *testing.T) {
+	c := startRPC(t, &fakeBackend{})
+
+	tests := []struct {
+		method, params string
+		wantCode       float64
+	}{
+		{"nope", `{}`, rpcMethodNotFound},
+		{"prompt", `{"text": ""}`, rpcInvalidParams},
+		{"prompt", `[1]`, rpcInvalidParams},
+		{"newSession", `{"model": "unknown"}`, rpcInvalidParams},
+		{"prompt", `{"sessionId": "9", "text": "x"}`, rpcInvalidParams},
+	}
+	for i, tt := range tests {
+		_, resp := c.call(i+1, tt.method, tt.params)
+		e, _ := resp["error"].(map[string]interface{})
+		if e == nil || e["code"] != tt.wantCode {
+			t.Errorf("%s %s: error = %v, want code %v", tt.method, tt.params, resp["error"], tt.wantCode)
+		}
+	}
+
+	c.write(`not json`)
+	select {
+	case msg := <-c.lines:
+		if e, _ := msg["error"].(map[string]interface{}); e == nil || e["code"] != float64(rpcParseError) {
+			t.Errorf("parse error response = %v", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no response to invalid JSON")
+	}
+}
@@ -0,0 +1,433 @@
+// Package api exposes copilot sessions to other programs: an
+// OpenAI-compatible HTTP API for tools that speak the OpenAI chat completions
+// protocol, and a JSON-RPC protocol over stdio for editor extensions.
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"atulm/cocli/client"
+	"atulm/cocli/models"
+	"atulm/cocli/session"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+const (
+	// requestTimeout bounds how long a single completion may take
+	requestTimeout = 10 * time.Minute
+
+	// maxRequestBytes bounds the size of a request body
+	maxRequestBytes = 10 << 20
+)
+
+// errModelNotFound is returned when a request names an unknown model
+var errModelNotFound = errors.New("model not found")
+
+// Session is the part of a copilot session the server uses
+type Session interface {
+	On(copilot.SessionEventHandler) func()
+	SendAndWait(copilot.MessageOptions, time.Duration) (*copilot.SessionEvent, error)
+	Abort() error
+	Destroy() error
+}
+
+// Backend creates sessions and lists models
+type Backend interface {
+	// NewSession creates a streaming session; systemMessage is appended to
+	// the default system prompt when non-empty
+	NewSession(model, systemMessage string) (Session, error)
+	Models() ([]copilot.ModelInfo, error)
+}
+
+// clientBackend serves requests from cocli's copilot client
+type clientBackend struct {
+	cli *client.Client
+}
+
+// NewClientBackend returns a Backend that creates sessions with cli
+func NewClientBackend(cli *client.Client) Backend {
+	return &clientBackend{cli: cli}
+}
+
+func (b *clientBackend) NewSession(model, systemMessage string) (Session, error) {
+	cfg := &copilot.SessionConfig{Model: model, Streaming: true}
+	if systemMessage != "" {
+		cfg.SystemMessage = &copilot.SystemMessageConfig{Mode: "append", Content: systemMessage}
+	}
+	sess, err := b.cli.CreateSession(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+func (b *clientBackend) Models() ([]copilot.ModelInfo, error) {
+	return b.cli.GetModels()
+}
+
+// Server handles the OpenAI-compatible endpoints:
+//
+//	POST /v1/chat/completions  (with "stream": true for server-sent events)
+//	GET  /v1/models
+//
+// Requests are stateless, as in the OpenAI API: each one runs in its own
+// copilot session, seeded with the request's earlier messages. Every request
+// must carry the server's key as "Authorization: Bearer <key>".
+type Server struct {
+	backend      Backend
+	defaultModel string
+	apiKey       string
+	mux          *http.ServeMux
+}
+
+// NewServer creates a server that accepts requests bearing apiKey (see
+// NewAPIKey); defaultModel (a model name or ID) is used when a request
+// doesn't name one, and falls back to session.DefaultModel when empty
+func NewServer(backend Backend, defaultModel, apiKey string) *Server {
+	if defaultModel == "" {
+		defaultModel = session.DefaultModel
+	}
+	s := &Server{backend: backend, defaultModel: defaultModel, apiKey: apiKey, mux: http.NewServeMux()}
+	s.mux.HandleFunc("POST /v1/chat/completions", s.handleChat)
+	s.mux.HandleFunc("GET /v1/models", s.handleModels)
+	return s
+}
+
+// ServeHTTP routes a request that bears the server's key. Requests from
+// web pages, which browsers mark with an Origin header, are refused too.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Origin") != "" {
+		writeError(w, http.StatusForbidden, "invalid_request_error", "", "requests from web pages are not allowed")
+		return
+	}
+	key, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || s.apiKey == "" || subtle.ConstantTimeCompare([]byte(key), []byte(s.apiKey)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, "invalid_request_error", "invalid_api_key",
+			"missing or wrong API key; send the key `cocli serve-api` printed as \"Authorization: Bearer <key>\"")
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// NewAPIKey returns a random key for a server
+func NewAPIKey() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	return "cocli-" + hex.EncodeToString(b), nil
+}
+
+// handleModels lists the models copilot offers
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	models, err := s.backend.Models()
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "api_error", "", fmt.Sprintf("failed to list models: %v", err))
+		return
+	}
+	list := ModelList{Object: "list", Data: make([]Model, 0, len(models))}
+	for _, m := range models {
+		list.Data = append(list.Data, Model{ID: m.ID, Object: "model", OwnedBy: "github-copilot"})
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// handleChat answers a chat completion request
+func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
+	// A JSON body can't be sent cross-site without a CORS preflight, which
+	// this server never approves
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, "invalid_request_error", "", "the request body must be application/json")
+		return
+	}
+
+	var req ChatRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "", fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+
+	system, prompt, err := buildPrompt(req.Messages)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "", err.Error())
+		return
+	}
+
+	model, err := resolveModel(s.backend, s.defaultModel, req.Model)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "invalid_request_error", "model_not_found",
+			fmt.Sprintf("the model %q does not exist", req.Model))
+		return
+	}
+
+	sess, err := s.backend.NewSession(model, system)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "api_error", "", fmt.Sprintf("failed to create session: %v", err))
+		return
+	}
+	defer sess.Destroy()
+
+	c := &completion{id: newCompletionID(), created: time.Now().Unix(), model: model, sess: sess}
+	if req.Stream {
+		c.stream(r.Context(), w, prompt)
+	} else {
+		c.complete(r.Context(), w, prompt)
+	}
+}
+
+// resolveModel maps a model name or ID, or defaultModel when name is empty,
+// to a copilot model ID. When models can't be listed, the name is passed
+// through unchanged.
+func resolveModel(backend Backend, defaultModel, name string) (string, error) {
+	if name == "" {
+		name = defaultModel
+	}
+	list, err := backend.Models()
+	if err != nil || len(list) == 0 {
+		return name, nil
+	}
+	if info, ok := models.Find(list, name); ok {
+		return info.ID, nil
+	}
+	return "", errModelNotFound
+}
+
+// buildPrompt turns a message list into a system message and a prompt.
+// System messages and the conversation before the final user message go
+// into the system message; the final user message is the prompt.
+func buildPrompt(messages []ChatMessage) (system, prompt string, err error) {
+	if len(messages) == 0 {
+		return "", "", errors.New("messages must not be empty")
+	}
+	last := messages[len(messages)-1]
+	if last.Role != "user" {
+		return "", "", errors.New("the last message must have role \"user\"")
+	}
+
+	var instructions, history []string
+	for _, m := range messages[:len(messages)-1] {
+		switch m.Role {
+		case "system", "developer":
+			instructions = append(instructions, string(m.Content))
+		case "user":
+			history = append(history, "User: "+string(m.Content))
+		case "assistant":
+			history = append(history, "Assistant: "+string(m.Content))
+		case "tool":
+			history = append(history, "Tool: "+string(m.Content))
+		default:
+			return "", "", fmt.Errorf("unsupported message role %q", m.Role)
+		}
+	}
+
+	parts := instructions
+	if len(history) > 0 {
+		parts = append(parts, "The conversation so far:\n\n"+strings.Join(history, "\n\n"))
+	}
+	return strings.Join(parts, "\n\n"), string(last.Content), nil
+}
+
+// completion is one chat completion in progress
+type completion struct {
+	id      string
+	created int64
+	model   string
+	sess    Session
+}
+
+// send sends the prompt and waits for the reply, aborting it if ctx is
+// cancelled (e.g. the HTTP client went away)
+func (c *completion) send(ctx context.Context, prompt string) (*copilot.SessionEvent, error) {
+	type result struct {
+		reply *copilot.SessionEvent
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		reply, err := c.sess.SendAndWait(copilot.MessageOptions{Prompt: prompt}, requestTimeout)
+		done <- result{reply, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.reply, r.err
+	case <-ctx.Done():
+		_ = c.sess.Abort()
+		return nil, ctx.Err()
+	}
+}
+
+// complete writes the whole reply as a single JSON response
+func (c *completion) complete(ctx context.Context, w http.ResponseWriter, prompt string) {
+	var (
+		mu    sync.Mutex
+		usage *Usage
+	)
+	unsubscribe := c.sess.On(func(event copilot.SessionEvent) {
+		if event.Type == copilot.AssistantUsage {
+			mu.Lock()
+			usage = usageFrom(event)
+			mu.Unlock()
+		}
+	})
+	defer unsubscribe()
+
+	reply, err := c.send(ctx, prompt)
+	if err != nil {
+		if ctx.Err() == nil {
+			writeError(w, http.StatusBadGateway, "api_error", "", err.Error())
+		}
+		return
+	}
+
+	content := ""
+	if reply != nil && reply.Data.Content != nil {
+		content = *reply.Data.Content
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	writeJSON(w, http.StatusOK, ChatCompletion{
+		ID:      c.id,
+		Object:  "chat.completion",
+		Created: c.created,
+		Model:   c.model,
+		Choices: []ChatChoice{{
+			Message:      ChatMessage{Role: "assistant", Content: Content(content)},
+			FinishReason: "stop",
+		}},
+		Usage: usage,
+	})
+}
+
+// stream writes the reply as server-sent events as it is generated
+func (c *completion) stream(ctx context.Context, w http.ResponseWriter, prompt string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "api_error", "", "streaming is not supported by this connection")
+		return
+	}
+
+	// Deltas are handed to this goroutine so only it writes the response
+	deltas := make(chan string, 64)
+	stop := make(chan struct{})
+	defer close(stop)
+	unsubscribe := c.sess.On(func(event copilot.SessionEvent) {
+		if event.Type != copilot.AssistantMessageDelta || event.Data.DeltaContent == nil {
+			return
+		}
+		select {
+		case deltas <- *event.Data.DeltaContent:
+		case <-stop:
+		}
+	})
+	defer unsubscribe()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.send(ctx, prompt)
+		done <- err
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	c.writeChunk(w, ChatDelta{Role: "assistant"}, nil)
+	flusher.Flush()
+
+	for {
+		select {
+		case delta := <-deltas:
+			c.writeChunk(w, ChatDelta{Content: delta}, nil)
+			flusher.Flush()
+		case err := <-done:
+			if ctx.Err() != nil {
+				return
+			}
+			// Every delta was queued before the session went idle
+			for drained := false; !drained; {
+				select {
+				case delta := <-deltas:
+					c.writeChunk(w, ChatDelta{Content: delta}, nil)
+				default:
+					drained = true
+				}
+			}
+			if err != nil {
+				writeEvent(w, errorBody{Error: errorDetail{Message: err.Error(), Type: "api_error"}})
+			} else {
+				stopReason := "stop"
+				c.writeChunk(w, ChatDelta{}, &stopReason)
+			}
+			fmt.Fprint(w, "data: [DONE]\n\n")
+			flusher.Flush()
+			return
+		}
+	}
+}
+
+// writeChunk writes one streaming chunk
+func (c *completion) writeChunk(w http.ResponseWriter, delta ChatDelta, finishReason *string) {
+	writeEvent(w, ChatChunk{
+		ID:      c.id,
+		Object:  "chat.completion.chunk",
+		Created: c.created,
+		Model:   c.model,
+		Choices: []ChatChunkChoice{{Delta: delta, FinishReason: finishReason}},
+	})
+}
+
+// writeEvent writes v as a server-sent event
+func writeEvent(w http.ResponseWriter, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "data: %s\n\n", data)
+}
+
+// usageFrom reads token counts from an assistant.usage event
+func usageFrom(event copilot.SessionEvent) *Usage {
+	u := &Usage{}
+	if event.Data.InputTokens != nil {
+		u.PromptTokens = int(*event.Data.InputTokens)
+	}
+	if event.Data.OutputTokens != nil {
+		u.CompletionTokens = int(*event.Data.OutputTokens)
+	}
+	u.TotalTokens = u.PromptTokens + u.CompletionTokens
+	return u
+}
+
+// newCompletionID returns a random OpenAI-style completion ID
+func newCompletionID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return "chatcmpl-" + hex.EncodeToString(b)
+}
+
+// writeJSON writes v with the given status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an OpenAI-style error response
+func writeError(w http.ResponseWriter, status int, errType, code, message string) {
+	writeJSON(w, status, errorBody{Error: errorDetail{Message: message, Type: errType, Code: code}})
+}
@@ -0,0 +1,341 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+// fakeSession streams its reply in deltas, like a copilot session
+type fakeSession struct {
+	mu        sync.Mutex
+	handlers  []copilot.SessionEventHandler
+	deltas    []string
+	err       error
+	prompt    string
+	destroyed bool
+	// block, when set, holds SendAndWait until Abort closes it
+	block chan struct{}
+}
+
+func (s *fakeSession) On(h copilot.SessionEventHandler) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers = append(s.handlers, h)
+	i := len(s.handlers) - 1
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.handlers[i] = nil
+	}
+}
+
+func (s *fakeSession) emit(event copilot.SessionEvent) {
+	s.mu.Lock()
+	handlers := append([]copilot.SessionEventHandler(nil), s.handlers...)
+	s.mu.Unlock()
+	for _, h := range handlers {
+		if h != nil {
+			h(event)
+		}
+	}
+}
+
+func (s *fakeSession) SendAndWait(opts copilot.MessageOptions, _ time.Duration) (*copilot.SessionEvent, error) {
+	s.mu.Lock()
+	s.prompt = opts.Prompt
+	block := s.block
+	s.mu.Unlock()
+	if block != nil {
+		<-block
+		return nil, errors.New("aborted")
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	for _, d := range s.deltas {
+		d := d
+		s.emit(copilot.SessionEvent{Type: copilot.AssistantMessageDelta, Data: copilot.Data{DeltaContent: &d}})
+	}
+	in, out := 12.0, 5.0
+	s.emit(copilot.SessionEvent{Type: copilot.AssistantUsage, Data: copilot.Data{InputTokens: &in, OutputTokens: &out}})
+	content := strings.Join(s.deltas, "")
+	return &copilot.SessionEvent{Type: copilot.AssistantMessage, Data: copilot.Data{Content: &content}}, nil
+}
+
+func (s *fakeSession) Abort() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.block != nil {
+		close(s.block)
+		s.block = nil
+	}
+	return nil
+}
+
+func (s *fakeSession) Destroy() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.destroyed = true
+	return nil
+}
+
+// fakeBackend hands out session, or a new fakeSession per call when
+// session is nil
+type fakeBackend struct {
+	mu        sync.Mutex
+	session   *fakeSession
+	created   []*fakeSession
+	model     string
+	system    string
+	modelsErr error
+}
+
+func (b *fakeBackend) NewSession(model, systemMessage string) (Session, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.model, b.system = model, systemMessage
+	if b.session != nil {
+		return b.session, nil
+	}
+	sess := &fakeSession{deltas: []string{"o", "k"}}
+	b.created = append(b.created, sess)
+	return sess, nil
+}
+
+func (b *fakeBackend) Models() ([]copilot.ModelInfo, error) {
+	if b.modelsErr != nil {
+		return nil, b.modelsErr
+	}
+	return []copilot.ModelInfo{
+		{ID: "claude-sonnet-4.5", Name: "Claude Sonnet 4.5"},
+		{ID: "gpt-5", Name: "GPT-5"},
+	}, nil
+}
+
+// testKey is the API key test servers accept
+const testKey = "cocli-test"
+
+func post(t *testing.T, srv *Server, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+testKey)
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestChatCompletion(t *testing.T) {
+	backend := &fakeBackend{session: &fakeSession{deltas: []string{"Hello", " there"}}}
+	srv := NewServer(backend, "", testKey)
+
+	rec := post(t, srv, `{"messages": [{"role": "user", "content": "Hi"}]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var resp ChatCompletion
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Object != "chat.completion" || !strings.HasPrefix(resp.ID, "chatcmpl-") {
+		t.Errorf("response = %+v, want a chat.completion", resp)
+	}
+	if len(resp.Choices) != 1 || resp.Choices[0].Message.Content != "Hello there" || resp.Choices[0].FinishReason != "stop" {
+		t.Errorf("choices = %+v, want the reply", resp.Choices)
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 17 {
+		t.Errorf("usage = %+v, want 12+5 tokens", resp.Usage)
+	}
+	if backend.model != "claude-sonnet-4.5" {
+		t.Errorf("model = %q, want the default model's ID", backend.model)
+	}
+	if backend.session.prompt != "Hi" || !backend.session.destroyed {
+		t.Errorf("prompt = %q, destroyed = %v; want Hi and the session destroyed", backend.session.prompt, backend.session.destroyed)
+	}
+}
+
+func TestChatCompletion_Stream(t *testing.T) {
+	backend := &fakeBackend{session: &fakeSession{deltas: []string{"Hel", "lo"}}}
+	srv := NewServer(backend, "", testKey)
+
+	rec := post(t, srv, `{"model": "gpt-5", "stream": true, "messages": [{"role": "user", "content": "Hi"}]}`)
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	var content strings.Builder
+	var events []string
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		line, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		events = append(events, line)
+		if line == "[DONE]" {
+			continue
+		}
+		var chunk ChatChunk
+		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+			t.Fatalf("bad chunk %q: %v", line, err)
+		}
+		content.WriteString(chunk.Choices[0].Delta.Content)
+	}
+
+	if content.String() != "Hello" {
+		t.Errorf("streamed content = %q, want Hello", content.String())
+	}
+	if len(events) != 5 || events[len(events)-1] != "[DONE]" {
+		t.Fatalf("events = %q, want role, 2 deltas, stop and [DONE]", events)
+	}
+	if !strings.Contains(events[0], `"role":"assistant"`) || !strings.Contains(events[3], `"finish_reason":"stop"`) {
+		t.Errorf("events = %q, want a role chunk first and a stop chunk last", events)
+	}
+}
+
+func TestChatCompletion_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		sendErr    error
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "bad json", body: `{`, wantStatus: http.StatusBadRequest},
+		{name: "no messages", body: `{"messages": []}`, wantStatus: http.StatusBadRequest},
+		{name: "last message not user", body: `{"messages": [{"role": "assistant", "content": "x"}]}`, wantStatus: http.StatusBadRequest},
+		{name: "unknown model", body: `{"model": "nope", "messages": [{"role": "user", "content": "x"}]}`, wantStatus: http.StatusNotFound, wantCode: "model_not_found"},
+		{name: "send fails", body: `{"messages": [{"role": "user", "content": "x"}]}`, sendErr: errors.New("boom"), wantStatus: http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := NewServer(&fakeBackend{session: &fakeSession{err: tt.sendErr}}, "", testKey)
+			rec := post(t, srv, tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+			var body errorBody
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error.Message == "" {
+				t.Errorf("body = %s, want an OpenAI-style error", rec.Body)
+			}
+			if body.Error.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", body.Error.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestChatCompletion_RejectsUnauthorized(t *testing.T) {
+	body := `{"messages": [{"role": "user", "content": "x"}]}`
+	tests := []struct {
+		name        string
+		contentType string
+		origin      string
+		auth        string
+		wantStatus  int
+	}{
+		{name: "json with charset", contentType: "application/json; charset=utf-8", wantStatus: http.StatusOK},
+		{name: "no key", contentType: "application/json", auth: "-", wantStatus: http.StatusUnauthorized},
+		{name: "wrong key", contentType: "application/json", auth: "Bearer guess", wantStatus: http.StatusUnauthorized},
+		{name: "form post", contentType: "text/plain", wantStatus: http.StatusUnsupportedMediaType},
+		{name: "no content type", wantStatus: http.StatusUnsupportedMediaType},
+		{name: "web page", contentType: "application/json", origin: "https://evil.example", wantStatus: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := NewServer(&fakeBackend{session: &fakeSession{}}, "", testKey)
+			req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			switch tt.auth {
+			case "":
+				req.Header.Set("Authorization", "Bearer "+testKey)
+			case "-":
+			default:
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+}
+
+func TestNewAPIKey(t *testing.T) {
+	a, err := NewAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := NewAPIKey()
+	if !strings.HasPrefix(a, "cocli-") || len(a) < 32 || a == b {
+		t.Errorf("NewAPIKey() = %q, %q; want distinct random keys", a, b)
+	}
+}
+
+func TestBuildPrompt(t *testing.T) {
+	system, prompt, err := buildPrompt([]ChatMessage{
+		{Role: "system", Content: "Be terse."},
+		{Role: "user", Content: "What is Go?"},
+		{Role: "assistant", Content: "A language."},
+		{Role: "user", Content: "Who made it?"},
+	})
+	if err != nil {
+		t.Fatalf("buildPrompt() error = %v", err)
+	}
+	if prompt != "Who made it?" {
+		t.Errorf("prompt = %q, want the last user message", prompt)
+	}
+	want := "Be terse.\n\nThe conversation so far:\n\nUser: What is Go?\n\nAssistant: A language."
+	if system != want {
+		t.Errorf("system = %q, want %q", system, want)
+	}
+}
+
+func TestContent_Parts(t *testing.T) {
+	var m ChatMessage
+	data := `{"role": "user", "content": [{"type": "text", "text": "a"}, {"type": "image_url"}, {"type": "text", "text": "b"}]}`
+	if err := json.Unmarshal([]byte(data), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Content != "a\nb" {
+		t.Errorf("Content = %q, want the text parts joined", m.Content)
+	}
+}
+
+func TestResolveModel_NoModelList(t *testing.T) {
+	backend := &fakeBackend{modelsErr: errors.New("offline")}
+	if got, err := resolveModel(backend, "my-model", ""); err != nil || got != "my-model" {
+		t.Errorf("resolveModel(\"\") = %q, %v; want the default passed through", got, err)
+	}
+}
+
+func TestModels(t *testing.T) {
+	srv := NewServer(&fakeBackend{}, "", testKey)
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	req.Header.Set("Authorization", "Bearer "+testKey)
+	srv.ServeHTTP(rec, req)
+
+	var list ModelList
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if list.Object != "list" || len(list.Data) != 2 || list.Data[1].ID != "gpt-5" {
+		t.Errorf("models = %+v, want both models", list)
+	}
+}
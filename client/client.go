@@ -1,8 +1,20 @@
 package client
 
 import (
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
 
+	"atulm/cocli/config"
 	"atulm/cocli/server"
 
 	copilot "github.com/github/copilot-sdk/go"
@@ -11,33 +23,100 @@ import (
 // ClientInterface defines the interface for copilot client operations
 type ClientInterface interface {
 	CreateSession(*copilot.SessionConfig) (*copilot.Session, error)
+	ResumeSessionWithOptions(string, *copilot.ResumeSessionConfig) (*copilot.Session, error)
 	ListModels() ([]copilot.ModelInfo, error)
+	Ping(string) (*copilot.PingResponse, error)
+	GetAuthStatus() (*copilot.GetAuthStatusResponse, error)
+	GetStatus() (*copilot.GetStatusResponse, error)
 	Start() error
 	Stop() []error
+	// ForceStop drops the connection without cleaning up on the server, for
+	// a server that is already gone
+	ForceStop()
 }
 
 // DaemonChecker interface for checking daemon status (for testability)
 type DaemonChecker interface {
 	IsRunning() bool
 	GetPort() (int, error)
+	// Token is presented to the daemon's auth gate; "" for daemons without one
+	Token() string
+	// Socket is the daemon's private socket, reached without the token; ""
+	// for daemons without one
+	Socket() string
 }
 
 // sdkClient wraps the actual copilot.Client to implement ClientInterface
 type sdkClient struct {
 	*copilot.Client
+	// tunnel forwards the SDK's connections to a daemon
+	tunnel io.Closer
 }
 
 func (s *sdkClient) CreateSession(config *copilot.SessionConfig) (*copilot.Session, error) {
 	return s.Client.CreateSession(config)
 }
 
+func (s *sdkClient) Stop() []error {
+	errs := s.Client.Stop()
+	closeTunnel(s.tunnel)
+	return errs
+}
+
+func (s *sdkClient) ForceStop() {
+	s.Client.ForceStop()
+	closeTunnel(s.tunnel)
+}
+
 // Client manages the copilot SDK client and model caching
 type Client struct {
-	sdk         ClientInterface
-	models      []copilot.ModelInfo
-	usingDaemon bool
+	sdkMu sync.Mutex
+	sdk   ClientInterface
+	// connect opens a connection like the one the client was created with,
+	// for Reconnect; nil when the client can't reconnect
+	connect func() (ClientInterface, error)
+	// sessions are those created or resumed on the current connection, for
+	// Detach to destroy
+	sessionsMu sync.Mutex
+	sessions   []*copilot.Session
+	modelsMu   sync.Mutex
+	models     []copilot.ModelInfo
+	// modelsAt is when models was fetched
+	modelsAt time.Time
+	// modelsCachePath and modelsCacheTTL persist models across runs; see
+	// SetModelsCache
+	modelsCachePath string
+	modelsCacheTTL  time.Duration
+	usingDaemon     bool
+	daemonPort      int
+	// remoteAddr is the host:port of a daemon on another machine, if used
+	remoteAddr string
+	// quiet suppresses progress messages; see SetQuiet
+	quiet bool
 }
 
+const (
+	// reachTimeout bounds the reachability check of a remote daemon
+	reachTimeout = 5 * time.Second
+
+	// authProbeTimeout is how long a remote daemon is given to greet a new
+	// connection before it is taken to have no auth gate
+	authProbeTimeout = time.Second
+
+	// pingTimeout is how long the server is given to answer Connected
+	pingTimeout = 3 * time.Second
+
+	// reconnectAttempts bounds Reconnect; with the delays below it keeps
+	// trying for about half a minute, long enough for a crashed daemon to be
+	// restarted
+	reconnectAttempts = 8
+	reconnectDelay    = 500 * time.Millisecond
+	maxReconnectDelay = 8 * time.Second
+)
+
+// sleep waits between reconnection attempts; replaced in tests
+var sleep = time.Sleep
+
 // NewClient creates a new client, automatically connecting to a running daemon
 // if available, otherwise starting an embedded server.
 func NewClient() (*Client, error) {
@@ -46,8 +125,8 @@ func NewClient() (*Client, error) {
 
 // NewClientWithDaemonChecker creates a new client with a custom daemon checker (for testing)
 func NewClientWithDaemonChecker(daemonChecker DaemonChecker) (*Client, error) {
-	var sdkCli *copilot.Client
-	var usingDaemon bool
+	var sdkCli *sdkClient
+	var daemonPort int
 
 	// Use default daemon checker if not provided
 	if daemonChecker == nil {
@@ -61,39 +140,370 @@ func NewClientWithDaemonChecker(daemonChecker DaemonChecker) (*Client, error) {
 	if daemonChecker != nil && daemonChecker.IsRunning() {
 		port, err := daemonChecker.GetPort()
 		if err == nil {
-			sdkCli = copilot.NewClient(&copilot.ClientOptions{
-				CLIUrl: fmt.Sprintf("localhost:%d", port),
-			})
-			usingDaemon = true
+			sdkCli, err = startDaemonClient(port, daemonChecker.Socket(), daemonChecker.Token())
+			if err != nil {
+				// Daemon connection failed, fall back to embedded server
+				slog.Warn("daemon did not respond, starting embedded server", "err", err, "fix", "check it with /server status, or restart it with /server stop and /server start")
+			} else {
+				daemonPort = port
+			}
 		}
 	}
 
 	// Fallback to embedded server
 	if sdkCli == nil {
-		sdkCli = copilot.NewClient(nil)
+		var err error
+		if sdkCli, err = startSDKClient("", nil); err != nil {
+			return nil, startError(err)
+		}
 	}
 
-	if err := sdkCli.Start(); err != nil {
-		if usingDaemon {
-			// Daemon connection failed, fall back to embedded server
-			fmt.Println("Warning: daemon connection failed, starting embedded server...")
-			sdkCli = copilot.NewClient(nil)
-			usingDaemon = false
-			if err := sdkCli.Start(); err != nil {
-				return nil, fmt.Errorf("failed to start client: %w", err)
+	if err := checkAuth(sdkCli.Client); err != nil {
+		sdkCli.Stop()
+		return nil, err
+	}
+
+	c := &Client{
+		sdk:         sdkCli,
+		models:      []copilot.ModelInfo{},
+		usingDaemon: daemonPort != 0,
+		daemonPort:  daemonPort,
+	}
+	if c.usingDaemon {
+		// A restarted daemon has a new token, and may have a new port
+		c.connect = func() (ClientInterface, error) {
+			port, err := daemonChecker.GetPort()
+			if err != nil {
+				return nil, err
 			}
-		} else {
-			return nil, fmt.Errorf("failed to start client: %w", err)
+			return startDaemonClient(port, daemonChecker.Socket(), daemonChecker.Token())
+		}
+	} else {
+		c.connect = func() (ClientInterface, error) {
+			return startSDKClient("", nil)
+		}
+	}
+	slog.Debug("connected to the copilot server", "daemon", c.usingDaemon, "port", daemonPort)
+	return c, nil
+}
+
+// startDaemonClient connects to the local daemon: on its private socket, or
+// on port through its auth gate when it has a token
+func startDaemonClient(port int, socket, token string) (*sdkClient, error) {
+	cliURL := fmt.Sprintf("localhost:%d", port)
+	var dial dialer
+	switch {
+	case socket != "":
+		dial = unixDialer(socket)
+	case token != "":
+		dial = tcpDialer(cliURL).withToken(token)
+	}
+	return startSDKClient(cliURL, dial)
+}
+
+// startSDKClient starts an SDK client for the server at cliURL, or for an
+// embedded server when cliURL is "". When dial is non-nil the SDK connects
+// through a private tunnel that opens its connections with dial.
+func startSDKClient(cliURL string, dial dialer) (*sdkClient, error) {
+	var tunnel io.Closer
+	var opts *copilot.ClientOptions
+	if dial != nil {
+		t, err := startTunnel(dial)
+		if err != nil {
+			return nil, err
+		}
+		if opts, err = t.clientOptions(); err != nil {
+			t.Close()
+			return nil, err
+		}
+		tunnel = t
+	} else if cliURL != "" {
+		opts = &copilot.ClientOptions{CLIUrl: cliURL}
+	}
+	sdkCli := copilot.NewClient(opts)
+	if err := sdkCli.Start(); err != nil {
+		closeTunnel(tunnel)
+		return nil, err
+	}
+	return &sdkClient{Client: sdkCli, tunnel: tunnel}, nil
+}
+
+// NewRemoteClient connects to the daemon at addr (host:port), typically one
+// running on another machine, over TLS when tlsOpts is non-nil. token is
+// presented to the daemon's auth gate. Unlike NewClient it never falls back
+// to an embedded server: if the daemon can't be reached, the error says why.
+func NewRemoteClient(addr string, tlsOpts *server.TLSOptions, token string) (*Client, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	port, _ := strconv.Atoi(portStr)
+	if err != nil || host == "" || port < 1 || port > 65535 {
+		return nil, fmt.Errorf("invalid daemon address %q (want host:port, e.g. devbox:4321)", addr)
+	}
+
+	dial := tcpDialer(addr)
+	if tlsOpts != nil {
+		tlsConfig, err := tlsOpts.ClientConfig(host)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkReachableTLS(addr, tlsConfig, reachTimeout); err != nil {
+			return nil, err
+		}
+		dial = tlsDialer(addr, tlsConfig)
+	} else if err := checkReachable(addr, reachTimeout); err != nil {
+		return nil, err
+	}
+
+	// Daemons started by older versions of cocli have no gate
+	gated := token != "" || requiresAuth(dial)
+	if gated {
+		if err := checkToken(addr, dial, token); err != nil {
+			return nil, err
+		}
+		dial = dial.withToken(token)
+	}
+
+	if !gated && tlsOpts == nil {
+		dial = nil
+	}
+	sdkCli, err := startSDKClient(addr, dial)
+	if err != nil {
+		return nil, &server.ActionableError{
+			Err:     fmt.Errorf("daemon at %s did not respond: %w", addr, err),
+			Context: []server.ContextItem{{Label: "daemon", Value: addr}},
+			Fix:     "Something is listening on that port but it is not a copilot server; check the port with `/server help` on the remote machine.",
 		}
 	}
+	if err := checkAuth(sdkCli.Client); err != nil {
+		sdkCli.Stop()
+		return nil, err
+	}
 
 	return &Client{
-		sdk:         &sdkClient{sdkCli},
+		sdk: sdkCli,
+		connect: func() (ClientInterface, error) {
+			return startSDKClient(addr, dial)
+		},
 		models:      []copilot.ModelInfo{},
-		usingDaemon: usingDaemon,
+		usingDaemon: true,
+		daemonPort:  port,
+		remoteAddr:  addr,
 	}, nil
 }
 
+// dialer opens a connection to a daemon
+type dialer func() (net.Conn, error)
+
+// tcpDialer dials addr over plain TCP
+func tcpDialer(addr string) dialer {
+	return func() (net.Conn, error) {
+		return net.DialTimeout("tcp", addr, reachTimeout)
+	}
+}
+
+// unixDialer dials the unix socket at path
+func unixDialer(path string) dialer {
+	return func() (net.Conn, error) {
+		return net.DialTimeout("unix", path, reachTimeout)
+	}
+}
+
+// tlsDialer dials addr over TLS
+func tlsDialer(addr string, tlsConfig *tls.Config) dialer {
+	return func() (net.Conn, error) {
+		return tls.DialWithDialer(&net.Dialer{Timeout: reachTimeout}, "tcp", addr, tlsConfig)
+	}
+}
+
+// withToken authenticates each connection d opens with the daemon's gate
+func (d dialer) withToken(token string) dialer {
+	return func() (net.Conn, error) {
+		conn, err := d()
+		if err != nil {
+			return nil, err
+		}
+		if err := server.Authenticate(conn, token); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return conn, nil
+	}
+}
+
+// requiresAuth reports whether the daemon greets connections with the auth
+// handshake
+func requiresAuth(dial dialer) bool {
+	conn, err := dial()
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+	return server.RequiresAuth(conn, authProbeTimeout)
+}
+
+// checkToken authenticates once with the daemon's gate, explaining a
+// missing or rejected token
+func checkToken(addr string, dial dialer, token string) error {
+	conn, err := dial.withToken(token)()
+	if err == nil {
+		conn.Close()
+		return nil
+	}
+	context := []server.ContextItem{
+		{Label: "daemon", Value: addr},
+		{Label: config.EnvDaemonToken, Value: server.EnvPresence(config.EnvDaemonToken)},
+	}
+	switch {
+	case errors.Is(err, server.ErrAuthRequired):
+		return &server.ActionableError{
+			Err:     fmt.Errorf("the daemon at %s requires a token: %w", addr, err),
+			Context: context,
+			Fix:     "Set COCLI_DAEMON_TOKEN (or daemon.token) to the output of `cocli server token` on that machine, e.g. COCLI_DAEMON_TOKEN=$(ssh <host> cocli server token).",
+		}
+	case errors.Is(err, server.ErrAuthDenied):
+		return &server.ActionableError{
+			Err:     fmt.Errorf("the daemon at %s rejected the token: %w", addr, err),
+			Context: context,
+			Fix:     "The daemon makes a new token each time it starts; copy the current one from `cocli server token` on that machine.",
+		}
+	default:
+		return unreachableError(addr, err)
+	}
+}
+
+// checkReachable opens a TCP connection to addr, returning an actionable
+// error if the daemon can't be reached
+func checkReachable(addr string, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err == nil {
+		conn.Close()
+		return nil
+	}
+	return unreachableError(addr, err)
+}
+
+// checkReachableTLS completes a TLS handshake with addr, telling an
+// unreachable daemon apart from one whose certificate isn't trusted
+func checkReachableTLS(addr string, tlsConfig *tls.Config, timeout time.Duration) error {
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", addr, tlsConfig)
+	if err == nil {
+		conn.Close()
+		return nil
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return unreachableError(addr, err)
+	}
+	var certErr *tls.CertificateVerificationError
+	if errors.As(err, &certErr) {
+		return &server.ActionableError{
+			Err:     fmt.Errorf("the daemon at %s has an untrusted certificate: %w", addr, err),
+			Context: []server.ContextItem{{Label: "daemon", Value: addr}},
+			Fix:     "Set daemon.tls.ca_file to the CA bundle that signed the daemon's certificate. For development only, daemon.tls.insecure_skip_verify skips the check.",
+		}
+	}
+	return &server.ActionableError{
+		Err:     fmt.Errorf("TLS handshake with the daemon at %s failed: %w", addr, err),
+		Context: []server.ContextItem{{Label: "daemon", Value: addr}},
+		Fix:     "Check that the daemon was started with --tls-listen on that port, and set daemon.tls.cert_file and key_file if it requires a client certificate.",
+	}
+}
+
+// unreachableError explains a daemon address that refused or timed out
+func unreachableError(addr string, err error) error {
+	_, port, _ := net.SplitHostPort(addr)
+	return &server.ActionableError{
+		Err: fmt.Errorf("cannot reach the daemon at %s: %w", addr, err),
+		Context: []server.ContextItem{
+			{Label: "daemon", Value: addr},
+			{Label: config.EnvServer, Value: server.EnvValue(config.EnvServer)},
+		},
+		Fix: fmt.Sprintf("Start the daemon on that machine (`cocli server run` or /server start) and check that port %s is reachable from here. "+
+			"If it only listens on localhost, forward it with `ssh -N -L 5400:localhost:%s <host>` and use --server localhost:5400.",
+			port, port),
+	}
+}
+
+// tunnel forwards the SDK's connections to a daemon. The SDK only connects
+// over plain TCP, which anyone on the machine could use, or to a process's
+// stdio, so it is given cocli itself as its CLI: run with server.BridgeEnv
+// set, cocli relays its stdio to the tunnel's socket, which only this user
+// can reach.
+type tunnel struct {
+	ln  net.Listener
+	dir string
+}
+
+// startTunnel listens on a private socket and forwards each connection
+// through dial
+func startTunnel(dial dialer) (*tunnel, error) {
+	dir, err := os.MkdirTemp("", "cocli-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to start daemon tunnel: %w", err)
+	}
+	ln, err := server.ListenPrivate(filepath.Join(dir, "tunnel.sock"))
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to start daemon tunnel: %w", err)
+	}
+	go server.Forward(ln, dial)
+	return &tunnel{ln: ln, dir: dir}, nil
+}
+
+// clientOptions has the SDK reach the tunnel through a cocli bridge
+func (t *tunnel) clientOptions() (*copilot.ClientOptions, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the cocli binary: %w", err)
+	}
+	return &copilot.ClientOptions{
+		CLIPath:  exe,
+		UseStdio: true,
+		Env:      append(os.Environ(), server.BridgeEnv+"="+t.ln.Addr().String()),
+	}, nil
+}
+
+// Close stops the tunnel and removes its socket
+func (t *tunnel) Close() error {
+	err := t.ln.Close()
+	os.RemoveAll(t.dir)
+	return err
+}
+
+func closeTunnel(tunnel io.Closer) {
+	if tunnel != nil {
+		tunnel.Close()
+	}
+}
+
+// startError turns an SDK start failure into an actionable error that names
+// the CLI lookup paths and env vars that were consulted
+func startError(err error) error {
+	wrapped := fmt.Errorf("failed to start client: %w", err)
+	switch {
+	case errors.Is(err, exec.ErrNotFound) || errors.Is(err, os.ErrNotExist):
+		return server.NewCLINotFoundError(wrapped)
+	case server.IsAuthFailure(err):
+		return server.NewAuthError(fmt.Errorf("the copilot CLI is not signed in: %w", err), "")
+	case server.IsOffline(err):
+		return server.NewOfflineError(fmt.Errorf("cannot reach GitHub: %w", err))
+	}
+	return &server.ActionableError{
+		Err:     wrapped,
+		Context: server.CLIEnvContext(),
+		Fix:     "Run `copilot --version` to verify the CLI works, or start the daemon with `/server start` and retry.",
+	}
+}
+
+// checkAuth verifies the CLI is signed in. Servers that don't support the
+// auth status call are assumed to be authenticated.
+func checkAuth(sdkCli *copilot.Client) error {
+	status, err := sdkCli.GetAuthStatus()
+	if err != nil || status.IsAuthenticated {
+		return nil
+	}
+	return server.NewAuthError(errors.New("copilot CLI is not authenticated"), orEmpty(status.StatusMessage))
+}
+
 // NewClientWithSDK creates a client with a custom SDK client (for testing)
 func NewClientWithSDK(sdk ClientInterface) *Client {
 	return &Client{
@@ -103,35 +513,272 @@ func NewClientWithSDK(sdk ClientInterface) *Client {
 	}
 }
 
+// NewClientWithConnector creates a client whose connections, including those
+// made by Reconnect, are opened with connect (for testing)
+func NewClientWithConnector(connect func() (ClientInterface, error)) (*Client, error) {
+	sdk, err := connect()
+	if err != nil {
+		return nil, err
+	}
+	c := NewClientWithSDK(sdk)
+	c.connect = connect
+	return c, nil
+}
+
+// current returns the SDK client for the current connection
+func (c *Client) current() ClientInterface {
+	c.sdkMu.Lock()
+	defer c.sdkMu.Unlock()
+	return c.sdk
+}
+
 // CreateSession creates a new copilot session with the given configuration
 func (c *Client) CreateSession(config *copilot.SessionConfig) (*copilot.Session, error) {
-	return c.sdk.CreateSession(config)
+	return c.track(c.current().CreateSession(config))
+}
+
+// ResumeSession reattaches to a session the server kept alive, such as one
+// a previous run left on the daemon with Detach
+func (c *Client) ResumeSession(id string, config *copilot.ResumeSessionConfig) (*copilot.Session, error) {
+	return c.track(c.current().ResumeSessionWithOptions(id, config))
+}
+
+// track remembers a session opened on the current connection
+func (c *Client) track(sess *copilot.Session, err error) (*copilot.Session, error) {
+	if sess != nil {
+		c.sessionsMu.Lock()
+		c.sessions = append(c.sessions, sess)
+		c.sessionsMu.Unlock()
+	}
+	return sess, err
 }
 
 // ListModels returns available models from the server (no caching)
 func (c *Client) ListModels() ([]copilot.ModelInfo, error) {
-	return c.sdk.ListModels()
+	return c.current().ListModels()
 }
 
-// GetModels returns cached models, fetching from server if needed
+// Connected reports whether the server answers a ping. A connection to a
+// server that died may not fail until it is used, so this is how a failed
+// or stalled request is told apart from a lost connection.
+func (c *Client) Connected() bool {
+	sdk := c.current()
+	done := make(chan error, 1)
+	go func() {
+		_, err := sdk.Ping("cocli")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err == nil
+	case <-time.After(pingTimeout):
+		return false
+	}
+}
+
+// AuthStatus is who the copilot CLI is signed in as
+type AuthStatus struct {
+	Authenticated bool
+	Login         string
+	Host          string
+	// AuthType is how it signed in, such as "user", "gh-cli" or "env"
+	AuthType string
+	// Message is the server's description of the sign-in, if any
+	Message string
+	// CLIVersion is the copilot CLI's version, "" if it didn't say
+	CLIVersion string
+}
+
+// AuthStatus asks the copilot CLI who it is signed in as
+func (c *Client) AuthStatus() (*AuthStatus, error) {
+	sdk := c.current()
+	resp, err := sdk.GetAuthStatus()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auth status: %w", err)
+	}
+	status := &AuthStatus{
+		Authenticated: resp.IsAuthenticated,
+		Login:         orEmpty(resp.Login),
+		Host:          orEmpty(resp.Host),
+		AuthType:      orEmpty(resp.AuthType),
+		Message:       orEmpty(resp.StatusMessage),
+	}
+	if version, err := sdk.GetStatus(); err == nil {
+		status.CLIVersion = version.Version
+	}
+	return status, nil
+}
+
+// orEmpty returns *s, or "" when s is nil
+func orEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// Diagnose explains why a request failed when the cause is one the user
+// can fix: being offline, a signed-out CLI, or a daemon that went down.
+// Other errors are returned unchanged.
+func (c *Client) Diagnose(err error) error {
+	var actionable *server.ActionableError
+	switch {
+	case err == nil || errors.As(err, &actionable):
+		return err
+	case server.IsAuthFailure(err):
+		return server.NewAuthError(fmt.Errorf("the copilot CLI is not signed in: %w", err), "")
+	case server.IsOffline(err):
+		return server.NewOfflineError(fmt.Errorf("cannot reach GitHub: %w", err))
+	case c.Connected():
+		return err
+	case c.remoteAddr != "":
+		return unreachableError(c.remoteAddr, err)
+	case c.usingDaemon:
+		return server.NewDaemonDownError(err, c.daemonPort)
+	}
+	return &server.ActionableError{
+		Err: fmt.Errorf("the embedded copilot server stopped: %w", err),
+		Fix: "Restart cocli and continue the conversation with /resume. Run `copilot --version` if it keeps happening.",
+	}
+}
+
+// Reconnect replaces a lost connection with a new one to the same server,
+// retrying with exponential backoff while it is down. Sessions created
+// before are gone with the old connection.
+func (c *Client) Reconnect() error {
+	if c.connect == nil {
+		return errors.New("this client cannot reconnect")
+	}
+	c.current().ForceStop()
+
+	delay := reconnectDelay
+	var err error
+	for attempt := 1; attempt <= reconnectAttempts; attempt++ {
+		var sdk ClientInterface
+		if sdk, err = c.connect(); err == nil {
+			c.sdkMu.Lock()
+			c.sdk = sdk
+			c.sdkMu.Unlock()
+			c.sessionsMu.Lock()
+			c.sessions = nil
+			c.sessionsMu.Unlock()
+			return nil
+		}
+		if attempt < reconnectAttempts {
+			sleep(delay)
+			delay = min(delay*2, maxReconnectDelay)
+		}
+	}
+	return fmt.Errorf("could not reconnect after %d attempts: %w", reconnectAttempts, err)
+}
+
+// GetModels returns cached models, fetching from server if needed. The
+// cache is read from and saved to disk when SetModelsCache is used. It is
+// safe for concurrent use.
 func (c *Client) GetModels() ([]copilot.ModelInfo, error) {
+	c.modelsMu.Lock()
+	defer c.modelsMu.Unlock()
 	if len(c.models) == 0 {
-		fmt.Println("Fetching available models from server...")
-		var err error
-		c.models, err = c.sdk.ListModels()
-		if err != nil {
+		if models, at, ok := c.readModelsCache(); ok {
+			c.models, c.modelsAt = models, at
+			return c.models, nil
+		}
+		if err := c.fetchModels(); err != nil {
 			return nil, err
 		}
 	}
 	return c.models, nil
 }
 
+// RefreshModels fetches the models from the server, replacing the cached
+// list in memory and on disk
+func (c *Client) RefreshModels() ([]copilot.ModelInfo, error) {
+	c.modelsMu.Lock()
+	defer c.modelsMu.Unlock()
+	if err := c.fetchModels(); err != nil {
+		return nil, err
+	}
+	return c.models, nil
+}
+
+// SetQuiet suppresses progress messages such as the one printed while
+// models are fetched (--quiet)
+func (c *Client) SetQuiet(quiet bool) {
+	c.quiet = quiet
+}
+
+// fetchModels fills the cache from the server. Called with modelsMu held.
+func (c *Client) fetchModels() error {
+	if !c.quiet {
+		fmt.Println("Fetching available models from server...")
+	}
+	models, err := c.current().ListModels()
+	if err != nil {
+		return err
+	}
+	c.models, c.modelsAt = models, time.Now()
+	c.writeModelsCache()
+	return nil
+}
+
+// ModelsCacheAge returns how long ago the cached models were fetched, and
+// false when nothing is cached yet
+func (c *Client) ModelsCacheAge() (time.Duration, bool) {
+	c.modelsMu.Lock()
+	defer c.modelsMu.Unlock()
+	if len(c.models) == 0 {
+		return 0, false
+	}
+	return time.Since(c.modelsAt), true
+}
+
 // IsUsingDaemon returns true if the client is connected to a daemon
 func (c *Client) IsUsingDaemon() bool {
 	return c.usingDaemon
 }
 
+// RemoteAddr returns the host:port of the remote daemon the client is
+// connected to, or "" when the daemon (if any) is local
+func (c *Client) RemoteAddr() string {
+	return c.remoteAddr
+}
+
+// DaemonPort returns the port of the daemon the client is connected to, or 0
+// when using an embedded server
+func (c *Client) DaemonPort() int {
+	if !c.usingDaemon {
+		return 0
+	}
+	return c.daemonPort
+}
+
 // Stop stops the client and cleans up resources
 func (c *Client) Stop() []error {
-	return c.sdk.Stop()
+	return c.current().Stop()
+}
+
+// Detach stops the client like Stop but leaves the session with ID keep
+// alive on the server, for a later run to resume with ResumeSession. The
+// other sessions it opened are destroyed. Only a daemon outlives the client,
+// so with an embedded server this is the same as Stop.
+func (c *Client) Detach(keep string) []error {
+	if !c.usingDaemon {
+		return c.Stop()
+	}
+	c.sessionsMu.Lock()
+	sessions := c.sessions
+	c.sessions = nil
+	c.sessionsMu.Unlock()
+
+	var errs []error
+	for _, sess := range sessions {
+		if sess.SessionID == keep {
+			continue
+		}
+		if err := sess.Destroy(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to destroy session %s: %w", sess.SessionID, err))
+		}
+	}
+	c.current().ForceStop()
+	return errs
 }
@@ -1,12 +1,22 @@
 package client
 
 import (
+	"bufio"
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
+	"time"
+
+	"atulm/cocli/server"
 
 	copilot "github.com/github/copilot-sdk/go"
 )
@@ -18,10 +28,14 @@ type mockSDKClient struct {
 	listError    error
 	startError   error
 	stopErrors   []error
+	pingErr      error
+	authStatus   *copilot.GetAuthStatusResponse
 	startCalled  bool
 	stopCalled   bool
+	forceStopped bool
 	listCalled   int
 	createCalled int
+	resumedID    string
 }
 
 func (m *mockSDKClient) ListModels() ([]copilot.ModelInfo, error) {
@@ -43,6 +57,14 @@ func (m *mockSDKClient) CreateSession(config *copilot.SessionConfig) (*copilot.S
 	return nil, nil // Return nil session for testing
 }
 
+func (m *mockSDKClient) ResumeSessionWithOptions(id string, config *copilot.ResumeSessionConfig) (*copilot.Session, error) {
+	m.resumedID = id
+	if m.createError != nil {
+		return nil, m.createError
+	}
+	return nil, nil
+}
+
 func (m *mockSDKClient) Start() error {
 	m.startCalled = true
 	return m.startError
@@ -53,11 +75,35 @@ func (m *mockSDKClient) Stop() []error {
 	return m.stopErrors
 }
 
+func (m *mockSDKClient) ForceStop() {
+	m.forceStopped = true
+}
+
+func (m *mockSDKClient) GetAuthStatus() (*copilot.GetAuthStatusResponse, error) {
+	if m.authStatus == nil {
+		return &copilot.GetAuthStatusResponse{IsAuthenticated: true}, nil
+	}
+	return m.authStatus, nil
+}
+
+func (m *mockSDKClient) GetStatus() (*copilot.GetStatusResponse, error) {
+	return &copilot.GetStatusResponse{Version: "1.0.0"}, nil
+}
+
+func (m *mockSDKClient) Ping(string) (*copilot.PingResponse, error) {
+	if m.pingErr != nil {
+		return nil, m.pingErr
+	}
+	return &copilot.PingResponse{}, nil
+}
+
 // Mock daemon checker
 type mockDaemonChecker struct {
 	running bool
 	port    int
 	portErr error
+	token   string
+	socket  string
 }
 
 func (m *mockDaemonChecker) IsRunning() bool {
@@ -71,6 +117,14 @@ func (m *mockDaemonChecker) GetPort() (int, error) {
 	return m.port, nil
 }
 
+func (m *mockDaemonChecker) Token() string {
+	return m.token
+}
+
+func (m *mockDaemonChecker) Socket() string {
+	return m.socket
+}
+
 // captureOutput helper
 func captureOutput(f func()) string {
 	old := os.Stdout
@@ -133,6 +187,9 @@ func TestGetModels_Caching(t *testing.T) {
 
 	mock := &mockSDKClient{models: testModels}
 	client := NewClientWithSDK(mock)
+	if _, ok := client.ModelsCacheAge(); ok {
+		t.Error("ModelsCacheAge() reports a cache before the first fetch")
+	}
 
 	// First call should fetch and print message
 	output := captureOutput(func() {
@@ -152,6 +209,9 @@ func TestGetModels_Caching(t *testing.T) {
 	if mock.listCalled != 1 {
 		t.Errorf("Expected ListModels to be called once, called %d times", mock.listCalled)
 	}
+	if age, ok := client.ModelsCacheAge(); !ok || age < 0 || age > time.Minute {
+		t.Errorf("ModelsCacheAge() = %v, %v; want a fresh cache", age, ok)
+	}
 
 	// Second call should use cache (no output, no additional ListModels call)
 	output = captureOutput(func() {
@@ -173,6 +233,19 @@ func TestGetModels_Caching(t *testing.T) {
 	}
 }
 
+func TestGetModels_Quiet(t *testing.T) {
+	client := NewClientWithSDK(&mockSDKClient{models: []copilot.ModelInfo{{ID: "model1"}}})
+	client.SetQuiet(true)
+	output := captureOutput(func() {
+		if _, err := client.GetModels(); err != nil {
+			t.Fatalf("GetModels() error = %v", err)
+		}
+	})
+	if output != "" {
+		t.Errorf("output = %q, want nothing when quiet", output)
+	}
+}
+
 // TestGetModels_Error tests error handling
 func TestGetModels_Error(t *testing.T) {
 	mock := &mockSDKClient{listError: fmt.Errorf("network error")}
@@ -335,6 +408,41 @@ func TestStop(t *testing.T) {
 	}
 }
 
+func TestResumeSession(t *testing.T) {
+	mock := &mockSDKClient{}
+	client := NewClientWithSDK(mock)
+	if _, err := client.ResumeSession("abc", &copilot.ResumeSessionConfig{Streaming: true}); err != nil {
+		t.Fatalf("ResumeSession() error = %v", err)
+	}
+	if mock.resumedID != "abc" {
+		t.Errorf("resumed %q, want abc", mock.resumedID)
+	}
+}
+
+func TestDetach(t *testing.T) {
+	tests := []struct {
+		name        string
+		usingDaemon bool
+		wantStop    bool
+	}{
+		{name: "embedded server is stopped", wantStop: true},
+		{name: "daemon keeps its sessions", usingDaemon: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockSDKClient{}
+			client := NewClientWithSDK(mock)
+			client.usingDaemon = tt.usingDaemon
+
+			client.Detach("abc")
+
+			if mock.stopCalled != tt.wantStop || mock.forceStopped == tt.wantStop {
+				t.Errorf("stopped = %v, force stopped = %v, want stop %v", mock.stopCalled, mock.forceStopped, tt.wantStop)
+			}
+		})
+	}
+}
+
 // TestIsUsingDaemon tests the daemon flag
 func TestIsUsingDaemon(t *testing.T) {
 	mock := &mockSDKClient{}
@@ -377,3 +485,295 @@ func TestListModels_DoesNotAffectCache(t *testing.T) {
 		t.Errorf("Expected ListModels to be called twice, called %d times", mock.listCalled)
 	}
 }
+
+func TestNewRemoteClient_InvalidAddr(t *testing.T) {
+	for _, addr := range []string{"devbox", ":4321", "devbox:0", "devbox:http"} {
+		if _, err := NewRemoteClient(addr, nil, ""); err == nil || !strings.Contains(err.Error(), "invalid daemon address") {
+			t.Errorf("NewRemoteClient(%q) error = %v, want an invalid address error", addr, err)
+		}
+	}
+}
+
+func TestCheckReachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	if err := checkReachable(addr, time.Second); err != nil {
+		t.Errorf("checkReachable(listening) error = %v", err)
+	}
+
+	ln.Close()
+	err = checkReachable(addr, time.Second)
+	var actionable *server.ActionableError
+	if !errors.As(err, &actionable) {
+		t.Fatalf("checkReachable(closed) error = %v, want an actionable error", err)
+	}
+	if !strings.Contains(err.Error(), addr) || actionable.Fix == "" {
+		t.Errorf("error = %v, want the address and a suggested fix", err)
+	}
+}
+
+func TestCheckReachableTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+	addr := srv.Listener.Addr().String()
+
+	err := checkReachableTLS(addr, &tls.Config{ServerName: "127.0.0.1"}, time.Second)
+	if err == nil || !strings.Contains(err.Error(), "untrusted certificate") {
+		t.Errorf("checkReachableTLS(untrusted) error = %v, want an untrusted certificate error", err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	if err := checkReachableTLS(addr, &tls.Config{ServerName: "127.0.0.1", RootCAs: pool}, time.Second); err != nil {
+		t.Errorf("checkReachableTLS(trusted) error = %v", err)
+	}
+	if err := checkReachableTLS(addr, &tls.Config{InsecureSkipVerify: true}, time.Second); err != nil {
+		t.Errorf("checkReachableTLS(skip verify) error = %v", err)
+	}
+
+	srv.Close()
+	if err := checkReachableTLS(addr, &tls.Config{InsecureSkipVerify: true}, time.Second); err == nil || !strings.Contains(err.Error(), "cannot reach") {
+		t.Errorf("checkReachableTLS(closed) error = %v, want an unreachable error", err)
+	}
+}
+
+func TestCheckToken(t *testing.T) {
+	target, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer target.Close()
+	gate, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gate.Close()
+	go server.Gate(gate, "s3cret", func() (net.Conn, error) { return net.Dial("tcp", target.Addr().String()) })
+	addr := gate.Addr().String()
+
+	if !requiresAuth(tcpDialer(addr)) {
+		t.Error("requiresAuth(gate) = false, want true")
+	}
+	tests := []struct {
+		token   string
+		wantErr string
+	}{
+		{token: "s3cret"},
+		{token: "", wantErr: "requires a token"},
+		{token: "guess", wantErr: "rejected the token"},
+	}
+	for _, tt := range tests {
+		err := checkToken(addr, tcpDialer(addr), tt.token)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("checkToken(%q) error = %v", tt.token, err)
+			}
+			continue
+		}
+		var actionable *server.ActionableError
+		if !errors.As(err, &actionable) || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("checkToken(%q) error = %v, want an actionable %q error", tt.token, err, tt.wantErr)
+		}
+	}
+}
+
+func TestTunnel(t *testing.T) {
+	target, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer target.Close()
+	go func() {
+		for {
+			conn, err := target.Accept()
+			if err != nil {
+				return
+			}
+			go func() { io.Copy(conn, conn); conn.Close() }()
+		}
+	}()
+
+	tun, err := startTunnel(tcpDialer(target.Addr().String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts, err := tun.clientOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !opts.UseStdio || opts.CLIUrl != "" {
+		t.Errorf("clientOptions() = %+v, want the SDK on a bridge's stdio", opts)
+	}
+	path := tun.ln.Addr().String()
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("tunnel socket = %v, %v; want mode 0600", info, err)
+	}
+
+	// What the bridge is given reaches the daemon and back
+	stdin, input := io.Pipe()
+	output, stdout := io.Pipe()
+	done := make(chan error, 1)
+	go func() { done <- server.Bridge(path, stdin, stdout) }()
+	input.Write([]byte("ping\n"))
+	if got, err := bufio.NewReader(output).ReadString('\n'); err != nil || got != "ping\n" {
+		t.Errorf("bridge echoed %q, %v; want ping", got, err)
+	}
+	input.Close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("Bridge() didn't return once its input ended")
+	}
+
+	tun.Close()
+	if _, err := os.Stat(tun.dir); !os.IsNotExist(err) {
+		t.Errorf("tunnel directory left behind: %v", err)
+	}
+}
+
+func TestConnected(t *testing.T) {
+	mock := &mockSDKClient{}
+	client := NewClientWithSDK(mock)
+	if !client.Connected() {
+		t.Error("Connected() = false, want true when the server answers pings")
+	}
+	mock.pingErr = errors.New("write: broken pipe")
+	if client.Connected() {
+		t.Error("Connected() = true, want false when pings fail")
+	}
+}
+
+func TestAuthStatus(t *testing.T) {
+	login, host, kind := "octocat", "https://github.com", "user"
+	mock := &mockSDKClient{authStatus: &copilot.GetAuthStatusResponse{IsAuthenticated: true, Login: &login, Host: &host, AuthType: &kind}}
+	status, err := NewClientWithSDK(mock).AuthStatus()
+	if err != nil {
+		t.Fatalf("AuthStatus() error = %v", err)
+	}
+	want := AuthStatus{Authenticated: true, Login: login, Host: host, AuthType: kind, CLIVersion: "1.0.0"}
+	if *status != want {
+		t.Errorf("AuthStatus() = %+v, want %+v", *status, want)
+	}
+
+	message := "Not logged in"
+	mock.authStatus = &copilot.GetAuthStatusResponse{StatusMessage: &message}
+	status, err = NewClientWithSDK(mock).AuthStatus()
+	if err != nil || status.Authenticated || status.Login != "" || status.Message != message {
+		t.Errorf("AuthStatus() = %+v, %v; want signed out with the server's message", status, err)
+	}
+}
+
+func TestDiagnose(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		pingErr error
+		daemon  bool
+		want    string
+	}{
+		{name: "offline", err: errors.New("dial tcp: lookup api.github.com: no such host"), want: "cannot reach GitHub"},
+		{name: "signed out", err: errors.New("session error: not authenticated"), want: "not signed in"},
+		{name: "daemon down", err: errors.New("write: broken pipe"), pingErr: errors.New("connection refused"), daemon: true, want: "daemon on port 4321 is not responding"},
+		{name: "embedded server down", err: errors.New("write: broken pipe"), pingErr: errors.New("EOF"), want: "embedded copilot server stopped"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClientWithSDK(&mockSDKClient{pingErr: tt.pingErr})
+			client.usingDaemon, client.daemonPort = tt.daemon, 4321
+			err := client.Diagnose(tt.err)
+			var actionable *server.ActionableError
+			if !errors.As(err, &actionable) || !strings.Contains(err.Error(), tt.want) || actionable.Fix == "" {
+				t.Errorf("Diagnose() = %v, want an actionable %q error", err, tt.want)
+			}
+		})
+	}
+
+	other := errors.New("model not available")
+	if err := NewClientWithSDK(&mockSDKClient{}).Diagnose(other); err != other {
+		t.Errorf("Diagnose() = %v, want other errors unchanged while connected", err)
+	}
+}
+
+func TestStartError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{err: errors.New("copilot exited: Not logged in"), want: "/login"},
+		{err: errors.New("dial tcp: lookup api.github.com: no such host"), want: "network connection"},
+		{err: errors.New("exit status 1"), want: "copilot --version"},
+	}
+	for _, tt := range tests {
+		if err := startError(tt.err); !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("startError(%v) = %v, want a fix mentioning %q", tt.err, err, tt.want)
+		}
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	var delays []time.Duration
+	origSleep := sleep
+	sleep = func(d time.Duration) { delays = append(delays, d) }
+	t.Cleanup(func() { sleep = origSleep })
+
+	t.Run("retries with backoff", func(t *testing.T) {
+		delays = nil
+		dead, fresh := &mockSDKClient{}, &mockSDKClient{}
+		calls := 0
+		client, err := NewClientWithConnector(func() (ClientInterface, error) {
+			calls++
+			switch {
+			case calls == 1:
+				return dead, nil
+			case calls < 5:
+				return nil, errors.New("connection refused")
+			}
+			return fresh, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := client.Reconnect(); err != nil {
+			t.Fatalf("Reconnect() error = %v", err)
+		}
+		if !dead.forceStopped {
+			t.Error("the lost connection should be force-stopped")
+		}
+		want := []time.Duration{500 * time.Millisecond, time.Second, 2 * time.Second}
+		if fmt.Sprint(delays) != fmt.Sprint(want) {
+			t.Errorf("delays = %v, want %v", delays, want)
+		}
+		client.ListModels()
+		if fresh.listCalled != 1 || dead.listCalled != 0 {
+			t.Error("requests should go to the new connection")
+		}
+	})
+
+	t.Run("gives up", func(t *testing.T) {
+		delays = nil
+		calls := 0
+		client, err := NewClientWithConnector(func() (ClientInterface, error) {
+			if calls++; calls == 1 {
+				return &mockSDKClient{}, nil
+			}
+			return nil, errors.New("connection refused")
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = client.Reconnect()
+		if err == nil || !strings.Contains(err.Error(), "connection refused") {
+			t.Errorf("Reconnect() error = %v, want the last connection error", err)
+		}
+		if calls-1 != reconnectAttempts || delays[len(delays)-1] != maxReconnectDelay {
+			t.Errorf("%d attempts with delays %v, want %d capped at %s", calls-1, delays, reconnectAttempts, maxReconnectDelay)
+		}
+	})
+
+	if err := NewClientWithSDK(&mockSDKClient{}).Reconnect(); err == nil {
+		t.Error("Reconnect() without a connector should fail")
+	}
+}
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"atulm/cocli/storage"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+const (
+	// ModelsCacheFile is the model list cache in the config directory
+	ModelsCacheFile = "models.json"
+
+	// DefaultModelsCacheTTL is how long a cached model list is reused
+	DefaultModelsCacheTTL = 24 * time.Hour
+)
+
+// modelsCache is the on-disk form of the model list
+type modelsCache struct {
+	FetchedAt time.Time           `json:"fetched_at"`
+	Models    []copilot.ModelInfo `json:"models"`
+}
+
+// SetModelsCache makes GetModels reuse the model list saved at path by
+// earlier runs while it is younger than ttl. A ttl of 0 or less keeps the
+// list in memory only.
+func (c *Client) SetModelsCache(path string, ttl time.Duration) {
+	c.modelsMu.Lock()
+	defer c.modelsMu.Unlock()
+	c.modelsCachePath, c.modelsCacheTTL = path, ttl
+}
+
+// readModelsCache returns the cached model list and when it was fetched,
+// and false when there is none or it has expired. Called with modelsMu held.
+func (c *Client) readModelsCache() ([]copilot.ModelInfo, time.Time, bool) {
+	if c.modelsCachePath == "" || c.modelsCacheTTL <= 0 {
+		return nil, time.Time{}, false
+	}
+	data, err := storage.LockedRead(c.modelsCachePath)
+	if err != nil {
+		return nil, time.Time{}, false
+	}
+	var cache modelsCache
+	if err := json.Unmarshal(data, &cache); err != nil || len(cache.Models) == 0 {
+		return nil, time.Time{}, false
+	}
+	if age := time.Since(cache.FetchedAt); age < 0 || age >= c.modelsCacheTTL {
+		return nil, time.Time{}, false
+	}
+	return cache.Models, cache.FetchedAt, true
+}
+
+// writeModelsCache saves the model list for later runs. A failed write
+// only costs the next run a fetch, so errors are ignored. Called with
+// modelsMu held.
+func (c *Client) writeModelsCache() {
+	if c.modelsCachePath == "" || c.modelsCacheTTL <= 0 {
+		return
+	}
+	data, err := json.MarshalIndent(modelsCache{FetchedAt: c.modelsAt, Models: c.models}, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(c.modelsCachePath), 0700); err != nil {
+		return
+	}
+	_ = storage.LockedWrite(c.modelsCachePath, data, 0600)
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+func TestGetModels_DiskCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ModelsCacheFile)
+	testModels := []copilot.ModelInfo{{ID: "model1", Name: "Model 1"}}
+
+	first := NewClientWithSDK(&mockSDKClient{models: testModels})
+	first.SetModelsCache(path, time.Hour)
+	captureOutput(func() {
+		if _, err := first.GetModels(); err != nil {
+			t.Fatalf("GetModels() error = %v", err)
+		}
+	})
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("cache file not written: %v", err)
+	}
+
+	// A later run reuses the saved list without asking the server
+	mock := &mockSDKClient{models: []copilot.ModelInfo{{ID: "model2"}}}
+	second := NewClientWithSDK(mock)
+	second.SetModelsCache(path, time.Hour)
+	var got []copilot.ModelInfo
+	output := captureOutput(func() {
+		got, _ = second.GetModels()
+	})
+	if mock.listCalled != 0 || strings.Contains(output, "Fetching") {
+		t.Errorf("GetModels() fetched (%d calls) despite a fresh cache", mock.listCalled)
+	}
+	if len(got) != 1 || got[0].ID != "model1" {
+		t.Errorf("GetModels() = %v, want the cached list", got)
+	}
+	if age, ok := second.ModelsCacheAge(); !ok || age > time.Minute {
+		t.Errorf("ModelsCacheAge() = %v, %v; want the cached list's age", age, ok)
+	}
+
+	// RefreshModels bypasses the cache and replaces it
+	captureOutput(func() {
+		got, _ = second.RefreshModels()
+	})
+	if mock.listCalled != 1 || len(got) != 1 || got[0].ID != "model2" {
+		t.Errorf("RefreshModels() = %v after %d calls, want the server's list", got, mock.listCalled)
+	}
+	third := NewClientWithSDK(&mockSDKClient{})
+	third.SetModelsCache(path, time.Hour)
+	if got, _ := third.GetModels(); len(got) != 1 || got[0].ID != "model2" {
+		t.Errorf("GetModels() after a refresh = %v, want the refreshed list", got)
+	}
+}
+
+func TestGetModels_DiskCacheExpired(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		ttl     time.Duration
+	}{
+		{"expired", `{"fetched_at":"2000-01-01T00:00:00Z","models":[{"id":"old"}]}`, time.Hour},
+		{"corrupt", `{"models":`, time.Hour},
+		{"empty", `{"fetched_at":"` + time.Now().Format(time.RFC3339) + `","models":[]}`, time.Hour},
+		{"disabled", `{"fetched_at":"` + time.Now().Format(time.RFC3339) + `","models":[{"id":"old"}]}`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ModelsCacheFile)
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			mock := &mockSDKClient{models: []copilot.ModelInfo{{ID: "new"}}}
+			c := NewClientWithSDK(mock)
+			c.SetModelsCache(path, tt.ttl)
+			var got []copilot.ModelInfo
+			captureOutput(func() {
+				got, _ = c.GetModels()
+			})
+			if mock.listCalled != 1 || len(got) != 1 || got[0].ID != "new" {
+				t.Errorf("GetModels() = %v after %d calls, want a fetch", got, mock.listCalled)
+			}
+		})
+	}
+}
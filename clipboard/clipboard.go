@@ -0,0 +1,100 @@
+// Package clipboard copies text to the system clipboard with the platform's
+// clipboard tool or, over SSH and when no tool is installed, the OSC 52
+// terminal escape sequence.
+package clipboard
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// MethodOSC52 is returned by Copy when the text was sent to the terminal
+const MethodOSC52 = "OSC 52"
+
+// MaxOSC52Bytes is the most text sent with OSC 52; many terminals ignore
+// longer sequences
+const MaxOSC52Bytes = 100 << 10
+
+// Hooks replaced by tests
+var (
+	lookPath = exec.LookPath
+	runTool  = func(name string, args []string, text string) error {
+		cmd := exec.Command(name, args...)
+		cmd.Stdin = strings.NewReader(text)
+		return cmd.Run()
+	}
+	goos             = runtime.GOOS
+	oscOut io.Writer = nil // nil means os.Stdout
+)
+
+// tool is a clipboard command that reads the text on stdin
+type tool struct {
+	name string
+	args []string
+}
+
+// tools lists the clipboard commands to try on this platform, in order
+func tools() []tool {
+	switch goos {
+	case "darwin":
+		return []tool{{name: "pbcopy"}}
+	case "windows":
+		return []tool{{name: "clip.exe"}}
+	}
+	var list []tool
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		list = append(list, tool{name: "wl-copy"})
+	}
+	return append(list,
+		tool{name: "xclip", args: []string{"-selection", "clipboard"}},
+		tool{name: "xsel", args: []string{"--clipboard", "--input"}},
+	)
+}
+
+// Copy puts text on the clipboard and returns how: the name of the tool
+// used, or MethodOSC52. Over SSH the local clipboard tools would copy on
+// the remote machine, so OSC 52 is always used.
+func Copy(text string) (string, error) {
+	if !remote() {
+		for _, t := range tools() {
+			if _, err := lookPath(t.name); err != nil {
+				continue
+			}
+			if err := runTool(t.name, t.args, text); err == nil {
+				return t.name, nil
+			}
+		}
+	}
+
+	if len(text) > MaxOSC52Bytes {
+		return "", fmt.Errorf("text is %d KiB, too large to copy through the terminal (limit %d KiB)", len(text)>>10, MaxOSC52Bytes>>10)
+	}
+	out := oscOut
+	if out == nil {
+		out = os.Stdout
+	}
+	if _, err := io.WriteString(out, osc52(text, os.Getenv("TMUX") != "")); err != nil {
+		return "", err
+	}
+	return MethodOSC52, nil
+}
+
+// remote reports whether cocli runs in an SSH session
+func remote() bool {
+	return os.Getenv("SSH_TTY") != "" || os.Getenv("SSH_CONNECTION") != ""
+}
+
+// osc52 returns the escape sequence that sets the clipboard to text. Inside
+// tmux it is wrapped so tmux passes it through to the outer terminal.
+func osc52(text string, tmux bool) string {
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+	if tmux {
+		seq = "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+	return seq
+}
@@ -0,0 +1,113 @@
+package clipboard
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeTools makes the named tools available and records what they copy
+func fakeTools(t *testing.T, available ...string) *[]string {
+	t.Helper()
+	origLook, origRun, origOut, origOS := lookPath, runTool, oscOut, goos
+	t.Cleanup(func() { lookPath, runTool, oscOut, goos = origLook, origRun, origOut, origOS })
+
+	var copied []string
+	lookPath = func(name string) (string, error) {
+		for _, a := range available {
+			if a == name {
+				return "/usr/bin/" + name, nil
+			}
+		}
+		return "", errors.New("not found")
+	}
+	runTool = func(name string, args []string, text string) error {
+		copied = append(copied, name+":"+text)
+		return nil
+	}
+	return &copied
+}
+
+func TestCopy_Tool(t *testing.T) {
+	t.Setenv("SSH_TTY", "")
+	t.Setenv("SSH_CONNECTION", "")
+	t.Setenv("WAYLAND_DISPLAY", "")
+	copied := fakeTools(t, "xsel")
+	goos = "linux"
+
+	method, err := Copy("hello")
+	if err != nil {
+		t.Fatalf("Copy() error = %v", err)
+	}
+	if method != "xsel" || len(*copied) != 1 || (*copied)[0] != "xsel:hello" {
+		t.Errorf("Copy() = %q, copied %q; want xsel", method, *copied)
+	}
+}
+
+func TestCopy_OSC52(t *testing.T) {
+	tests := []struct {
+		name  string
+		ssh   string
+		tmux  string
+		tools []string
+		want  string
+	}{
+		{name: "no tools", want: "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte("hi")) + "\a"},
+		{name: "over ssh", ssh: "/dev/pts/1", tools: []string{"xclip"}, want: "\x1b]52;c;aGk=\a"},
+		{name: "in tmux", tmux: "/tmp/tmux", want: "\x1bPtmux;\x1b\x1b]52;c;aGk=\a\x1b\\"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SSH_TTY", tt.ssh)
+			t.Setenv("SSH_CONNECTION", "")
+			t.Setenv("TMUX", tt.tmux)
+			copied := fakeTools(t, tt.tools...)
+			var buf bytes.Buffer
+			oscOut = &buf
+
+			method, err := Copy("hi")
+			if err != nil {
+				t.Fatalf("Copy() error = %v", err)
+			}
+			if method != MethodOSC52 || buf.String() != tt.want {
+				t.Errorf("Copy() = %q writing %q, want OSC 52 %q", method, buf.String(), tt.want)
+			}
+			if len(*copied) != 0 {
+				t.Errorf("tools ran: %q", *copied)
+			}
+		})
+	}
+}
+
+func TestCopy_OSC52TooLarge(t *testing.T) {
+	t.Setenv("SSH_TTY", "/dev/pts/1")
+	fakeTools(t)
+	oscOut = &bytes.Buffer{}
+	if _, err := Copy(strings.Repeat("x", MaxOSC52Bytes+1)); err == nil {
+		t.Error("Copy() of oversized text over OSC 52 should fail")
+	}
+}
+
+func TestTools(t *testing.T) {
+	orig := goos
+	t.Cleanup(func() { goos = orig })
+	tests := []struct {
+		goos    string
+		wayland string
+		first   string
+	}{
+		{goos: "darwin", first: "pbcopy"},
+		{goos: "windows", first: "clip.exe"},
+		{goos: "linux", first: "xclip"},
+		{goos: "linux", wayland: "wayland-0", first: "wl-copy"},
+	}
+	for _, tt := range tests {
+		goos = tt.goos
+		t.Setenv("WAYLAND_DISPLAY", tt.wayland)
+		if got := tools()[0].name; got != tt.first {
+			t.Errorf("tools() on %s (wayland %q) starts with %q, want %q", tt.goos, tt.wayland, got, tt.first)
+		}
+	}
+}
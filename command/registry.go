@@ -0,0 +1,209 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Prefix marks a line of input as a slash command
+const Prefix = "/"
+
+// ErrExit is returned by a handler to ask the interactive loop to exit
+var ErrExit = errors.New("exit requested")
+
+// Handler runs a command with its whitespace-separated arguments
+type Handler func(args []string) error
+
+// Completer returns completion candidates for the last (partial) argument.
+// args holds the arguments typed so far, including the partial one.
+type Completer func(args []string) []string
+
+// Command describes a slash command
+type Command struct {
+	// Name is the primary name, without the leading slash
+	Name string
+	// Aliases are alternative names, without the leading slash
+	Aliases []string
+	// Usage shows the argument syntax, e.g. "<start|stop|status>"
+	Usage string
+	// Help is a one-line description
+	Help string
+	// Handler runs the command
+	Handler Handler
+	// Complete provides argument completion (optional)
+	Complete Completer
+}
+
+// UnknownCommandError is returned by Dispatch for unregistered commands
+type UnknownCommandError struct {
+	Name        string
+	Suggestions []string
+	Available   []string
+}
+
+func (e *UnknownCommandError) Error() string {
+	if len(e.Suggestions) > 0 {
+		return fmt.Sprintf("unknown command %s%s. Did you mean %s?", Prefix, e.Name, strings.Join(e.Suggestions, " or "))
+	}
+	return fmt.Sprintf("unknown command %s%s. Available: %s", Prefix, e.Name, strings.Join(e.Available, ", "))
+}
+
+// Registry holds the set of slash commands and dispatches input to them
+type Registry struct {
+	commands []*Command
+	byName   map[string]*Command
+}
+
+// NewRegistry creates an empty command registry
+func NewRegistry() *Registry {
+	return &Registry{byName: make(map[string]*Command)}
+}
+
+// Register adds a command to the registry. It fails if the name or any alias
+// is already taken.
+func (r *Registry) Register(cmd *Command) error {
+	if cmd.Name == "" || cmd.Handler == nil {
+		return fmt.Errorf("command must have a name and a handler")
+	}
+	names := append([]string{cmd.Name}, cmd.Aliases...)
+	for _, name := range names {
+		if _, exists := r.byName[name]; exists {
+			return fmt.Errorf("command %s%s is already registered", Prefix, name)
+		}
+	}
+	for _, name := range names {
+		r.byName[name] = cmd
+	}
+	r.commands = append(r.commands, cmd)
+	sort.Slice(r.commands, func(i, j int) bool {
+		return r.commands[i].Name < r.commands[j].Name
+	})
+	return nil
+}
+
+// MustRegister is like Register but panics on error
+func (r *Registry) MustRegister(cmd *Command) {
+	if err := r.Register(cmd); err != nil {
+		panic(err)
+	}
+}
+
+// Lookup finds a command by name or alias (without the leading slash)
+func (r *Registry) Lookup(name string) (*Command, bool) {
+	cmd, ok := r.byName[name]
+	return cmd, ok
+}
+
+// Commands returns all registered commands sorted by name
+func (r *Registry) Commands() []*Command {
+	return append([]*Command(nil), r.commands...)
+}
+
+// Names returns every command name and alias with the leading slash, sorted
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.byName))
+	for name := range r.byName {
+		names = append(names, Prefix+name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsCommand reports whether a line of input is a slash command
+func IsCommand(line string) bool {
+	return strings.HasPrefix(line, Prefix)
+}
+
+// Parse splits a command line into its name (without slash) and arguments
+func Parse(line string) (name string, args []string) {
+	fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(line), Prefix))
+	if len(fields) == 0 {
+		return "", nil
+	}
+	return fields[0], fields[1:]
+}
+
+// Dispatch parses a command line and runs the matching handler
+func (r *Registry) Dispatch(line string) error {
+	name, args := Parse(line)
+	cmd, ok := r.Lookup(name)
+	if !ok {
+		available := make([]string, 0, len(r.commands))
+		for _, c := range r.commands {
+			available = append(available, Prefix+c.Name)
+		}
+		return &UnknownCommandError{Name: name, Suggestions: r.Suggest(name), Available: available}
+	}
+	return cmd.Handler(args)
+}
+
+// Complete returns completion candidates for a partially typed command line.
+// While the command name is being typed it completes names and aliases;
+// afterwards it delegates to the command's Completer.
+func (r *Registry) Complete(line string) []string {
+	if !IsCommand(line) {
+		return nil
+	}
+
+	body := strings.TrimPrefix(line, Prefix)
+	if !strings.ContainsAny(body, " \t") {
+		var matches []string
+		for _, name := range r.Names() {
+			if strings.HasPrefix(name, line) {
+				matches = append(matches, name)
+			}
+		}
+		return matches
+	}
+
+	name, args := Parse(line)
+	cmd, ok := r.Lookup(name)
+	if !ok || cmd.Complete == nil {
+		return nil
+	}
+	// A trailing space means a new, empty argument is being started
+	if strings.HasSuffix(line, " ") || strings.HasSuffix(line, "\t") {
+		args = append(args, "")
+	}
+	return cmd.Complete(args)
+}
+
+// PrintHelp writes a summary of all commands to w
+func (r *Registry) PrintHelp(w io.Writer) {
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range r.commands {
+		usage := Prefix + cmd.Name
+		if cmd.Usage != "" {
+			usage += " " + cmd.Usage
+		}
+		line := fmt.Sprintf("  %-28s %s", usage, cmd.Help)
+		if len(cmd.Aliases) > 0 {
+			aliases := make([]string, len(cmd.Aliases))
+			for i, alias := range cmd.Aliases {
+				aliases[i] = Prefix + alias
+			}
+			line += fmt.Sprintf(" (alias: %s)", strings.Join(aliases, ", "))
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+// FixedCompleter returns a Completer that completes the first argument from a
+// fixed list of choices
+func FixedCompleter(choices ...string) Completer {
+	return func(args []string) []string {
+		if len(args) != 1 {
+			return nil
+		}
+		var matches []string
+		for _, choice := range choices {
+			if strings.HasPrefix(choice, args[0]) {
+				matches = append(matches, choice)
+			}
+		}
+		return matches
+	}
+}
@@ -0,0 +1,182 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTestRegistry creates a registry with a few commands that record their calls
+func newTestRegistry(t *testing.T) (*Registry, *[]string) {
+	t.Helper()
+	var calls []string
+	record := func(name string) Handler {
+		return func(args []string) error {
+			calls = append(calls, name+":"+strings.Join(args, ","))
+			return nil
+		}
+	}
+
+	r := NewRegistry()
+	r.MustRegister(&Command{Name: "models", Aliases: []string{"list"}, Help: "List models", Handler: record("models")})
+	r.MustRegister(&Command{
+		Name:     "server",
+		Usage:    "<start|stop|status>",
+		Help:     "Manage the daemon",
+		Handler:  record("server"),
+		Complete: FixedCompleter("start", "stop", "status"),
+	})
+	r.MustRegister(&Command{Name: "help", Help: "Show help", Handler: record("help")})
+	return r, &calls
+}
+
+func TestRegistry_Dispatch(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{name: "no args", line: "/models", want: "models:"},
+		{name: "alias", line: "/list", want: "models:"},
+		{name: "with args", line: "/server start", want: "server:start"},
+		{name: "extra whitespace", line: "  /server   stop  ", want: "server:stop"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, calls := newTestRegistry(t)
+			if err := r.Dispatch(tt.line); err != nil {
+				t.Fatalf("Dispatch(%q) error = %v", tt.line, err)
+			}
+			if len(*calls) != 1 || (*calls)[0] != tt.want {
+				t.Errorf("Dispatch(%q) calls = %v, want [%s]", tt.line, *calls, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_Dispatch_Unknown(t *testing.T) {
+	r, calls := newTestRegistry(t)
+
+	err := r.Dispatch("/nope")
+	var unknown *UnknownCommandError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("Dispatch() error = %v, want UnknownCommandError", err)
+	}
+	if unknown.Name != "nope" {
+		t.Errorf("UnknownCommandError.Name = %q, want %q", unknown.Name, "nope")
+	}
+	if !strings.Contains(err.Error(), "/help, /models, /server") {
+		t.Errorf("Error() = %q, want available commands listed", err.Error())
+	}
+	if len(*calls) != 0 {
+		t.Errorf("no handler should run, got %v", *calls)
+	}
+}
+
+func TestRegistry_Dispatch_PropagatesError(t *testing.T) {
+	r := NewRegistry()
+	r.MustRegister(&Command{Name: "quit", Handler: func(args []string) error { return ErrExit }})
+
+	if err := r.Dispatch("/quit"); !errors.Is(err, ErrExit) {
+		t.Errorf("Dispatch() error = %v, want ErrExit", err)
+	}
+}
+
+func TestRegistry_Register_Duplicate(t *testing.T) {
+	r, _ := newTestRegistry(t)
+	noop := func(args []string) error { return nil }
+
+	if err := r.Register(&Command{Name: "models", Handler: noop}); err == nil {
+		t.Error("Register() duplicate name should fail")
+	}
+	if err := r.Register(&Command{Name: "other", Aliases: []string{"list"}, Handler: noop}); err == nil {
+		t.Error("Register() duplicate alias should fail")
+	}
+	if _, ok := r.Lookup("other"); ok {
+		t.Error("failed registration should not add the command")
+	}
+}
+
+func TestRegistry_Register_Invalid(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register(&Command{Name: "x"}); err == nil {
+		t.Error("Register() without handler should fail")
+	}
+	if err := r.Register(&Command{Handler: func(args []string) error { return nil }}); err == nil {
+		t.Error("Register() without name should fail")
+	}
+}
+
+func TestRegistry_Commands_Sorted(t *testing.T) {
+	r, _ := newTestRegistry(t)
+
+	var names []string
+	for _, cmd := range r.Commands() {
+		names = append(names, cmd.Name)
+	}
+	want := []string{"help", "models", "server"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Commands() = %v, want %v", names, want)
+	}
+}
+
+func TestRegistry_Complete(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want []string
+	}{
+		{name: "all commands", line: "/", want: []string{"/help", "/list", "/models", "/server"}},
+		{name: "name prefix", line: "/se", want: []string{"/server"}},
+		{name: "no match", line: "/zz", want: nil},
+		{name: "argument prefix", line: "/server st", want: []string{"start", "stop", "status"}},
+		{name: "argument narrow", line: "/server sto", want: []string{"stop"}},
+		{name: "empty argument", line: "/server ", want: []string{"start", "stop", "status"}},
+		{name: "no completer", line: "/models x", want: nil},
+		{name: "not a command", line: "hello", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := newTestRegistry(t)
+			if got := r.Complete(tt.line); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Complete(%q) = %v, want %v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_PrintHelp(t *testing.T) {
+	r, _ := newTestRegistry(t)
+	var buf bytes.Buffer
+	r.PrintHelp(&buf)
+
+	output := buf.String()
+	for _, want := range []string{"/help", "/models", "(alias: /list)", "/server <start|stop|status>", "Manage the daemon"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("PrintHelp() output missing %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		line     string
+		wantName string
+		wantArgs []string
+	}{
+		{line: "/models", wantName: "models", wantArgs: []string{}},
+		{line: "/server start now", wantName: "server", wantArgs: []string{"start", "now"}},
+		{line: "/", wantName: "", wantArgs: nil},
+	}
+
+	for _, tt := range tests {
+		name, args := Parse(tt.line)
+		if name != tt.wantName || len(args) != len(tt.wantArgs) {
+			t.Errorf("Parse(%q) = %q, %v; want %q, %v", tt.line, name, args, tt.wantName, tt.wantArgs)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package command
+
+import (
+	"sort"
+	"strings"
+)
+
+// maxSuggestions caps how many "did you mean" candidates are offered
+const maxSuggestions = 3
+
+// Suggest returns registered command names (with slash) that closely match
+// name, best match first. A candidate matches when it shares a prefix with
+// name or is within a small edit distance relative to its length.
+func (r *Registry) Suggest(name string) []string {
+	name = strings.ToLower(strings.TrimPrefix(name, Prefix))
+	if name == "" {
+		return nil
+	}
+
+	type candidate struct {
+		name     string
+		distance int
+	}
+	var candidates []candidate
+	for registered := range r.byName {
+		distance := levenshtein(name, registered)
+		if strings.HasPrefix(registered, name) || strings.HasPrefix(name, registered) {
+			// Prefix matches rank ahead of anything found by edit distance
+			distance = 0
+		} else if distance > maxDistance(name) {
+			continue
+		}
+		candidates = append(candidates, candidate{name: registered, distance: distance})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].name < candidates[j].name
+	})
+
+	var suggestions []string
+	seen := make(map[*Command]bool)
+	for _, c := range candidates {
+		// Offer each command once, under whichever of its names matched best
+		cmd := r.byName[c.name]
+		if seen[cmd] {
+			continue
+		}
+		seen[cmd] = true
+		suggestions = append(suggestions, Prefix+c.name)
+		if len(suggestions) == maxSuggestions {
+			break
+		}
+	}
+	return suggestions
+}
+
+// maxDistance is the largest edit distance still considered a typo of name
+func maxDistance(name string) int {
+	switch {
+	case len(name) <= 3:
+		return 1
+	case len(name) <= 6:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// levenshtein computes the edit distance between a and b
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,72 @@
+package command
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_Suggest(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{name: "typo", input: "modles", want: []string{"/models"}},
+		{name: "missing letter", input: "servr", want: []string{"/server"}},
+		{name: "prefix", input: "ser", want: []string{"/server"}},
+		{name: "alias typo", input: "lst", want: []string{"/list"}},
+		{name: "with slash", input: "/hlep", want: []string{"/help"}},
+		{name: "case insensitive", input: "MODELS", want: []string{"/models"}},
+		{name: "nothing close", input: "xyzzy", want: nil},
+		{name: "empty", input: "", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := newTestRegistry(t)
+			if got := r.Suggest(tt.input); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Suggest(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_Suggest_OncePerCommand(t *testing.T) {
+	r := NewRegistry()
+	r.MustRegister(&Command{Name: "models", Aliases: []string{"model"}, Handler: func(args []string) error { return nil }})
+
+	got := r.Suggest("mode")
+	if len(got) != 1 {
+		t.Errorf("Suggest() = %v, want a single entry for a command and its alias", got)
+	}
+}
+
+func TestRegistry_Dispatch_DidYouMean(t *testing.T) {
+	r, _ := newTestRegistry(t)
+
+	err := r.Dispatch("/modles")
+	if err == nil || !strings.Contains(err.Error(), "Did you mean /models?") {
+		t.Errorf("Dispatch() error = %v, want did-you-mean suggestion", err)
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"models", "models", 0},
+		{"modles", "models", 2},
+		{"servr", "server", 1},
+		{"kitten", "sitting", 3},
+	}
+
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
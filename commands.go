@@ -0,0 +1,1842 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"atulm/cocli/client"
+	"atulm/cocli/clipboard"
+	"atulm/cocli/command"
+	"atulm/cocli/config"
+	"atulm/cocli/edits"
+	"atulm/cocli/git"
+	"atulm/cocli/input"
+	"atulm/cocli/ledger"
+	"atulm/cocli/models"
+	"atulm/cocli/session"
+	"atulm/cocli/tmux"
+)
+
+// app holds the state shared by the interactive loop and the slash commands
+type app struct {
+	cli        *client.Client
+	sessionMgr *session.Manager
+	input      *input.Editor
+	commands   *command.Registry
+	journal    *edits.Journal  // backups of edits applied in agent mode
+	project    *config.Project // the project config merged in, if any
+}
+
+// registerCommands builds the slash-command registry. /help, completion and
+// the unknown-command message are all generated from it.
+func (a *app) registerCommands() {
+	a.commands = command.NewRegistry()
+
+	a.commands.MustRegister(&command.Command{
+		Name: "help",
+		Help: "Show available commands",
+		Handler: func(args []string) error {
+			a.commands.PrintHelp(os.Stdout)
+			return nil
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:     "models",
+		Aliases:  []string{"list"},
+		Usage:    "[--refresh] [--vision] [--cheap]",
+		Help:     "List available models and switch between them; --vision and --cheap show only models that accept images or cost under 1x, --refresh fetches the list again",
+		Complete: command.FixedCompleter("--refresh", "--vision", "--cheap"),
+		Handler: func(args []string) error {
+			refresh := false
+			var flags []string
+			for _, arg := range args {
+				if arg == "--refresh" {
+					refresh = true
+				} else {
+					flags = append(flags, arg)
+				}
+			}
+			filter, err := models.ParseFilter(flags)
+			if err != nil {
+				return fmt.Errorf("usage: /models [--refresh] [--vision] [--cheap]: %w", err)
+			}
+			if refresh {
+				if _, err := a.cli.RefreshModels(); err != nil {
+					return fmt.Errorf("failed to refresh models: %w", err)
+				}
+			}
+			return promptForModelSelection(a.sessionMgr, a.input, filter)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:     "model",
+		Usage:    "[name|alias | info [name]]",
+		Help:     "Show the current model, switch by ID, name or configured alias, or show everything known about a model",
+		Complete: a.completeModel,
+		Handler: func(args []string) error {
+			return a.handleModelCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:     "compact",
+		Usage:    "[auto [on|off]]",
+		Help:     "Summarize the conversation into a new session to free context space, or set automatic summarizing",
+		Complete: a.completeCompact,
+		Handler: func(args []string) error {
+			return a.handleCompactCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:     "system",
+		Usage:    "[show | set <instructions> | clear]",
+		Help:     "Show or change the system prompt, keeping the conversation",
+		Complete: command.FixedCompleter("show", "set", "clear"),
+		Handler: func(args []string) error {
+			return a.handleSystemCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:     "context",
+		Usage:    "[tree | clear]",
+		Help:     "Tell the model the current repository's file tree, or remove it",
+		Complete: command.FixedCompleter("tree", "clear"),
+		Handler: func(args []string) error {
+			return a.handleContextCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:     "set",
+		Usage:    "var [name=value | name=]",
+		Help:     "List prompt variables, or set one for {{name}} in prompts (empty value removes it)",
+		Complete: command.FixedCompleter("var"),
+		Handler: func(args []string) error {
+			return a.handleSetCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:  "redo",
+		Usage: "[N]",
+		Help:  "Edit turn N's prompt and resend it in a forked conversation",
+		Handler: func(args []string) error {
+			return a.handleRedoCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name: "retry",
+		Help: "Resend the last prompt, e.g. after /model, keeping the old answer as a branch",
+		Handler: func(args []string) error {
+			return a.handleRetryCommand()
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:     "regen",
+		Usage:    "[model]",
+		Help:     "Answer the last prompt again with another model, keeping both answers in the transcript",
+		Complete: a.completeModelName,
+		Handler: func(args []string) error {
+			return a.handleRegenCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:     "history",
+		Usage:    "[N | search <terms> | run N | edit N]",
+		Help:     "List recent prompts from all saved conversations, search them, or send one again",
+		Complete: command.FixedCompleter("search", "run", "edit"),
+		Handler: func(args []string) error {
+			return a.handleHistoryCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:  "edit",
+		Usage: "[text]",
+		Help:  "Write a prompt in $EDITOR and send it when the editor exits",
+		Handler: func(args []string) error {
+			return a.handleEditCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:  "branch",
+		Usage: "[N]",
+		Help:  "List conversation branches saved by /redo, or switch to one",
+		Handler: func(args []string) error {
+			return a.handleBranchCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:     "resume",
+		Usage:    "[id]",
+		Help:     "List saved conversations, or reload one and continue it",
+		Complete: a.completeConversationID,
+		Handler: func(args []string) error {
+			return a.handleResumeCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:     "sessions",
+		Usage:    "[delete <id>]",
+		Help:     "List named sessions with their model, token usage and last activity, or delete a saved conversation",
+		Complete: command.FixedCompleter("delete"),
+		Handler: func(args []string) error {
+			return a.handleSessionsCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:     "session",
+		Usage:    "<new|open> <name>",
+		Help:     "Start a named session, or switch to one",
+		Complete: a.completeSession,
+		Handler: func(args []string) error {
+			return a.handleSessionCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:  "fork",
+		Usage: "[name]",
+		Help:  "Copy the conversation into a new named session and switch to it, leaving the original as it was",
+		Handler: func(args []string) error {
+			return a.handleForkCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:  "switch",
+		Usage: "[N]",
+		Help:  "List the sessions open in this run, or switch to one without recreating it",
+		Handler: func(args []string) error {
+			return a.handleSwitchCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:  "save",
+		Usage: "[path]",
+		Help:  "Export the conversation as a Markdown file",
+		Handler: func(args []string) error {
+			return a.handleSaveCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:  "title",
+		Usage: "[name]",
+		Help:  "Show or set the conversation's title, used by /resume, /sessions and /save",
+		Handler: func(args []string) error {
+			return a.handleTitleCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:  "export",
+		Usage: "[path]",
+		Help:  "Export the conversation as portable JSON, for /import",
+		Handler: func(args []string) error {
+			return a.handleExportCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:  "import",
+		Usage: "<path>",
+		Help:  "Continue a conversation exported with /export",
+		Handler: func(args []string) error {
+			return a.handleImportCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name: "usage",
+		Help: "Show messages, tokens, premium requests and streaming time for this session",
+		Handler: func(args []string) error {
+			usage := a.sessionMgr.Usage()
+			if len(usage) == 0 {
+				fmt.Println("No messages sent yet.")
+				return nil
+			}
+			return session.WriteUsageTable(os.Stdout, usage)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:     "cost",
+		Usage:    "[day|week|month]",
+		Help:     "Show premium requests and tokens used across all runs this day, week or month",
+		Complete: command.FixedCompleter("day", "week", "month"),
+		Handler: func(args []string) error {
+			return a.handleCostCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:     "copy",
+		Usage:    "[code [N]]",
+		Help:     "Copy the last reply, or its Nth code block, to the clipboard",
+		Complete: command.FixedCompleter("code"),
+		Handler: func(args []string) error {
+			return a.handleCopyCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:     "code",
+		Usage:    "[list | show N | save N <file>]",
+		Help:     "List the last reply's code blocks, print one as plain text, or save it to a file",
+		Complete: command.FixedCompleter("list", "show", "save"),
+		Handler: func(args []string) error {
+			return a.handleCodeCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:     "copycode",
+		Usage:    "[on|off]",
+		Help:     "Print code blocks as plain, unwrapped text for clean copying",
+		Complete: command.FixedCompleter("on", "off"),
+		Handler: func(args []string) error {
+			return a.handleCopyCodeCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:     "stats",
+		Usage:    "[on|off]",
+		Help:     "Show or toggle a line of timing and token stats after each reply",
+		Complete: command.FixedCompleter("on", "off"),
+		Handler: func(args []string) error {
+			return a.handleStatsCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:     "debug",
+		Usage:    "[on|off]",
+		Help:     "Show or toggle logging every raw session event to ~/.cocli/debug.log",
+		Complete: command.FixedCompleter("on", "off"),
+		Handler: func(args []string) error {
+			return a.handleDebugCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:     "tee",
+		Usage:    "[path|off]",
+		Help:     "Show or set a file the markdown of every reply is appended to, for notes",
+		Complete: command.FixedCompleter("off"),
+		Handler: func(args []string) error {
+			return a.handleTeeCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:     "theme",
+		Usage:    "[code style]",
+		Help:     "List syntax highlighting styles for code blocks, or preview and switch to one",
+		Complete: command.FixedCompleter(config.CodeStyleNames()...),
+		Handler: func(args []string) error {
+			return a.handleThemeCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:     "tools",
+		Usage:    "[auto|ask|off]",
+		Help:     "Show or change which tool calls the model may make without asking",
+		Complete: command.FixedCompleter(config.ToolModes...),
+		Handler: func(args []string) error {
+			return a.handleToolsCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:     "timeout",
+		Usage:    "[duration|off]",
+		Help:     "Show or change how long to wait for a reply, e.g. /timeout 30m",
+		Complete: command.FixedCompleter("off"),
+		Handler: func(args []string) error {
+			return a.handleTimeoutCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:     "tmux",
+		Usage:    "<capture [pane] | clear>",
+		Help:     "Attach a tmux pane's recent output to your next prompt",
+		Complete: command.FixedCompleter("capture", "clear"),
+		Handler: func(args []string) error {
+			return a.handleTmuxCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:     "agent",
+		Usage:    "[on|off]",
+		Help:     "Show or toggle agent mode, where file edits in replies can be applied",
+		Complete: command.FixedCompleter("on", "off"),
+		Handler: func(args []string) error {
+			return a.handleAgentCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name: "undo",
+		Help: "Restore the files changed by the last edits applied in agent mode",
+		Handler: func(args []string) error {
+			return a.handleUndoCommand()
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:     "diff",
+		Usage:    "[--staged] [prompt]",
+		Help:     "Send the working tree's git diff for review, or with your own prompt",
+		Complete: command.FixedCompleter("--staged"),
+		Handler: func(args []string) error {
+			return a.handleDiffCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:  "compare",
+		Usage: "<model,model[,...]> [prompt]",
+		Help:  "Send one prompt to 2-4 models at once and show their answers one after another, with latency and tokens",
+		Handler: func(args []string) error {
+			return a.handleCompareCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name: "status",
+		Help: "Show the model, server, config and instruction files, and MCP servers in use",
+		Handler: func(args []string) error {
+			a.handleStatusCommand()
+			return nil
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:     "auth",
+		Usage:    "[status]",
+		Help:     "Show who the copilot CLI is signed in as",
+		Complete: command.FixedCompleter("status"),
+		Handler: func(args []string) error {
+			return a.handleAuthCommand(args)
+		},
+	})
+
+	a.commands.MustRegister(&command.Command{
+		Name:     "server",
+		Usage:    "<start|stop|status|logs|install|uninstall|token|help>",
+		Help:     "Manage the background daemon",
+		Complete: command.FixedCompleter("start", "stop", "status", "logs", "install", "uninstall", "token", "help"),
+		Handler: func(args []string) error {
+			shouldExit, err := handleServerCommand(args, a.cli)
+			if err != nil {
+				return err
+			}
+			if shouldExit {
+				return command.ErrExit
+			}
+			return nil
+		},
+	})
+}
+
+// handleStatsCommand shows or toggles the stats line after each reply
+func (a *app) handleStatsCommand(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "on":
+			a.sessionMgr.SetShowStats(true)
+		case "off":
+			a.sessionMgr.SetShowStats(false)
+		default:
+			return fmt.Errorf("usage: /stats [on|off]")
+		}
+	}
+	if a.sessionMgr.ShowStats() {
+		fmt.Println("Reply stats are on: each reply ends with its time to first token, duration, tokens and tokens/sec.")
+	} else {
+		fmt.Println("Reply stats are off. Use /stats on to show them after each reply.")
+	}
+	return nil
+}
+
+// handleDebugCommand shows or toggles the debug log of session events
+func (a *app) handleDebugCommand(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "on":
+			path, err := debugLogPath()
+			if err != nil {
+				return err
+			}
+			if err := a.sessionMgr.StartDebugLog(path); err != nil {
+				return err
+			}
+		case "off":
+			if err := a.sessionMgr.StopDebugLog(); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("usage: /debug [on|off]")
+		}
+	}
+	if path := a.sessionMgr.DebugLogPath(); path != "" {
+		fmt.Printf("Debug logging is on: session events are appended to %s.\n", path)
+	} else {
+		fmt.Println("Debug logging is off. Use /debug on (or start cocli with --debug) to log session events.")
+	}
+	return nil
+}
+
+// handleTeeCommand shows, sets or stops the file replies are appended to
+func (a *app) handleTeeCommand(args []string) error {
+	switch {
+	case len(args) > 1:
+		return fmt.Errorf("usage: /tee [path|off]")
+	case len(args) == 1 && args[0] == "off":
+		if err := a.sessionMgr.StopTee(); err != nil {
+			return err
+		}
+	case len(args) == 1:
+		if err := a.sessionMgr.StartTee(config.ExpandHome(args[0])); err != nil {
+			return err
+		}
+	}
+	if path := a.sessionMgr.TeePath(); path != "" {
+		fmt.Printf("Replies are appended to %s.\n", path)
+	} else {
+		fmt.Println("Replies are not saved to a file. Use /tee <path> (or start cocli with --output <path>) to append them to one.")
+	}
+	return nil
+}
+
+// handleStatusCommand shows what this session runs with
+func (a *app) handleStatusCommand() {
+	line := func(label, format string, args ...any) {
+		fmt.Printf("%-14s%s\n", label+":", fmt.Sprintf(format, args...))
+	}
+	line("Model", "%s", a.sessionMgr.GetCurrentModel())
+	switch {
+	case a.cli.RemoteAddr() != "":
+		line("Server", "remote daemon at %s", a.cli.RemoteAddr())
+	case a.cli.IsUsingDaemon():
+		line("Server", "daemon on port %d", a.cli.DaemonPort())
+	default:
+		line("Server", "embedded")
+	}
+	if path, err := config.DefaultPath(); err == nil {
+		line("Config", "%s", path)
+	}
+	if a.project != nil {
+		sets := "nothing"
+		if len(a.project.Sets) > 0 {
+			sets = strings.Join(a.project.Sets, ", ")
+		}
+		line("Project", "%s (sets %s)", a.project.Path, sets)
+	} else {
+		line("Project", "none (no %s here or above)", config.ProjectFile)
+	}
+	if files := a.sessionMgr.InstructionFiles(); len(files) > 0 {
+		line("Instructions", "%s", strings.Join(files, ", "))
+	} else {
+		line("Instructions", "none")
+	}
+	if names := a.sessionMgr.MCPServers(); len(names) > 0 {
+		line("MCP servers", "%s", strings.Join(names, ", "))
+	} else {
+		line("MCP servers", "none")
+	}
+}
+
+// handleAuthCommand shows who the copilot CLI is signed in as
+func (a *app) handleAuthCommand(args []string) error {
+	if len(args) > 1 || (len(args) == 1 && args[0] != "status") {
+		return fmt.Errorf("usage: /auth [status]")
+	}
+	status, err := a.cli.AuthStatus()
+	if err != nil {
+		return err
+	}
+	printAuthStatus(status)
+	return nil
+}
+
+// printAuthStatus prints the account the copilot CLI is signed in as, or
+// how to sign it in
+func printAuthStatus(status *client.AuthStatus) {
+	if !status.Authenticated {
+		if status.Message != "" {
+			fmt.Printf("Not signed in: %s\n", status.Message)
+		} else {
+			fmt.Println("Not signed in.")
+		}
+		fmt.Println("Run `copilot` and sign in with /login, or export GH_TOKEN with a token that has Copilot access.")
+		return
+	}
+	host := strings.TrimPrefix(strings.TrimPrefix(status.Host, "https://"), "http://")
+	if host == "" {
+		host = "github.com"
+	}
+	account := "Signed in to " + host
+	if status.Login != "" {
+		account += " as " + status.Login
+	}
+	if status.AuthType != "" {
+		account += " (" + status.AuthType + ")"
+	}
+	fmt.Println(account)
+	if status.CLIVersion != "" {
+		fmt.Printf("Copilot CLI: %s\n", status.CLIVersion)
+	}
+	// The server doesn't report the Copilot plan
+	fmt.Println("Plan:        not reported by the copilot CLI; see https://github.com/settings/copilot")
+}
+
+// handleCopyCodeCommand shows or toggles copy-safe code blocks
+func (a *app) handleCopyCodeCommand(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "on":
+			a.sessionMgr.SetCopySafeCode(true)
+		case "off":
+			a.sessionMgr.SetCopySafeCode(false)
+		default:
+			return fmt.Errorf("usage: /copycode [on|off]")
+		}
+	}
+	if a.sessionMgr.CopySafeCode() {
+		fmt.Println("Copy-safe code blocks are on: code is printed as plain text without wrapping or colors.")
+	} else {
+		fmt.Println("Copy-safe code blocks are off. Use /copycode on to print code as plain text.")
+	}
+	return nil
+}
+
+// handleThemeCommand lists the code styles, or switches to one and shows a
+// sample in it
+func (a *app) handleThemeCommand(args []string) error {
+	if len(args) == 0 {
+		current := a.sessionMgr.CodeStyle()
+		fmt.Println("Code styles:")
+		for _, name := range config.CodeStyleNames() {
+			marker := " "
+			if name == current {
+				marker = "*"
+			}
+			fmt.Printf("%s %s\n", marker, name)
+		}
+		fmt.Println("Use /theme <style> to preview and switch.")
+		return nil
+	}
+	if err := config.CheckCodeStyle(args[0]); err != nil {
+		return err
+	}
+	if err := a.sessionMgr.SetCodeStyle(args[0]); err != nil {
+		return fmt.Errorf("failed to switch code style: %w", err)
+	}
+	fmt.Printf("Code style: %s\n", a.sessionMgr.CodeStyle())
+	a.sessionMgr.PreviewCodeStyle()
+	return nil
+}
+
+// handleToolsCommand shows or changes the tool mode for this run
+func (a *app) handleToolsCommand(args []string) error {
+	if len(args) > 0 {
+		mode, err := session.ParseToolMode(args[0])
+		if err != nil {
+			return err
+		}
+		a.sessionMgr.SetToolMode(mode)
+	}
+	switch a.sessionMgr.ToolMode() {
+	case session.ToolsAuto:
+		fmt.Println("Tools: auto. Every tool call is allowed without asking.")
+	case session.ToolsAsk:
+		fmt.Println("Tools: ask. Reads run freely; shell commands, file writes and other tools ask first.")
+	default:
+		fmt.Println("Tools: off. Tool calls that need permission are denied.")
+	}
+	return nil
+}
+
+// handleTimeoutCommand shows or changes the request timeout for this run
+func (a *app) handleTimeoutCommand(args []string) error {
+	if len(args) > 0 {
+		var d time.Duration
+		if args[0] != "off" {
+			var err error
+			if d, err = time.ParseDuration(args[0]); err != nil || d < 0 {
+				return fmt.Errorf("usage: /timeout [duration|off], e.g. /timeout 30m")
+			}
+		}
+		a.sessionMgr.SetRequestTimeout(d)
+	}
+	if d := a.sessionMgr.RequestTimeout(); d > 0 {
+		fmt.Printf("Replies are cancelled after %s. Use /timeout <duration> to change it, or /timeout off.\n", d)
+	} else {
+		fmt.Println("Replies have no time limit. Use /timeout <duration> to set one.")
+	}
+	return nil
+}
+
+// handleTmuxCommand captures a tmux pane as context for the next prompt, or
+// drops the captured context
+func (a *app) handleTmuxCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: /tmux <capture [pane] | clear>")
+	}
+
+	switch args[0] {
+	case "capture":
+		pane := tmux.LastPane
+		if len(args) > 1 {
+			pane = args[1]
+		}
+		text, err := tmux.Capture(pane, tmux.DefaultLines)
+		if err != nil {
+			return err
+		}
+		if text == "" {
+			fmt.Printf("Pane %s is empty; nothing attached.\n", pane)
+			return nil
+		}
+		a.sessionMgr.AttachContext("Output of tmux pane "+pane, text)
+		fmt.Printf("Captured %d lines from pane %s; they will be sent with your next prompt.\n", strings.Count(text, "\n")+1, pane)
+	case "clear":
+		a.sessionMgr.ClearContext()
+		fmt.Println("Attached context cleared.")
+	default:
+		return fmt.Errorf("unknown /tmux subcommand %q (use capture or clear)", args[0])
+	}
+	return nil
+}
+
+// handleAgentCommand shows or toggles agent mode
+func (a *app) handleAgentCommand(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "on", "off":
+			if err := a.sessionMgr.SetAgentMode(args[0] == "on"); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("usage: /agent [on|off]")
+		}
+	}
+	if a.sessionMgr.AgentMode() {
+		fmt.Println("Agent mode is on: edits the model proposes are shown after each reply and applied once you confirm them. /undo reverts the last ones applied.")
+	} else {
+		fmt.Println("Agent mode is off. Use /agent on to apply the file edits the model proposes.")
+	}
+	return nil
+}
+
+// handleUndoCommand restores the files changed by the last applied edits
+func (a *app) handleUndoCommand() error {
+	if a.journal == nil {
+		return edits.ErrNothingToUndo
+	}
+	paths, err := a.journal.Undo()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Restored %d %s:\n", len(paths), plural(len(paths), "file", "files"))
+	for _, path := range paths {
+		fmt.Printf("  %s\n", displayPath(path))
+	}
+	return nil
+}
+
+// reviewEdits offers the file edits in the last reply one file at a time
+// and applies the accepted ones as a batch /undo can revert
+func (a *app) reviewEdits() {
+	var proposed []edits.FileEdit
+	for _, block := range session.CodeBlocks(a.sessionMgr.LastReply()) {
+		if block.Lang != "diff" && block.Lang != "patch" {
+			continue
+		}
+		parsed, err := edits.Parse(block.Code)
+		if err != nil {
+			fmt.Printf("Skipping a diff in the reply: %v\n", err)
+			continue
+		}
+		proposed = append(proposed, parsed...)
+	}
+	if len(proposed) == 0 {
+		return
+	}
+
+	fmt.Printf("\nThe reply proposes %d file %s.\n", len(proposed), plural(len(proposed), "edit", "edits"))
+	// Accepted contents, so later edits to the same file build on them
+	accepted := map[string]edits.Change{}
+	var order []string
+	for _, e := range proposed {
+		change, err := proposedChange(e, accepted)
+		if err != nil {
+			fmt.Printf("Can't apply the edit to %s: %v\n", e.Path, err)
+			continue
+		}
+		a.sessionMgr.ShowDiff(e.Diff)
+		if !a.confirm(fmt.Sprintf("Apply this edit to %s? [y/N]: ", e.Path)) {
+			continue
+		}
+		if _, ok := accepted[e.Path]; !ok {
+			order = append(order, e.Path)
+		}
+		accepted[e.Path] = change
+	}
+	if len(order) == 0 {
+		fmt.Println("No edits applied.")
+		return
+	}
+
+	changes := make([]edits.Change, len(order))
+	for i, path := range order {
+		changes[i] = accepted[path]
+	}
+	if err := a.journal.Write(changes); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	fmt.Printf("Applied edits to %d %s. Use /undo to restore them.\n", len(changes), plural(len(changes), "file", "files"))
+}
+
+// proposedChange works out a file's contents after e, starting from an
+// edit already accepted for the file or else from the file on disk. Paths
+// outside the current directory are refused.
+func proposedChange(e edits.FileEdit, accepted map[string]edits.Change) (edits.Change, error) {
+	if !filepath.IsLocal(e.Path) {
+		return edits.Change{}, fmt.Errorf("the path is outside the current directory")
+	}
+	var content string
+	exists := true
+	if prev, ok := accepted[e.Path]; ok {
+		content, exists = prev.Content, !prev.Delete
+	} else {
+		data, err := os.ReadFile(e.Path)
+		if errors.Is(err, os.ErrNotExist) {
+			exists = false
+		} else if err != nil {
+			return edits.Change{}, err
+		}
+		content = string(data)
+	}
+	switch {
+	case e.Create && exists:
+		return edits.Change{}, fmt.Errorf("the file already exists")
+	case !e.Create && !exists:
+		return edits.Change{}, fmt.Errorf("no such file")
+	}
+	updated, err := e.Apply(content)
+	if err != nil {
+		return edits.Change{}, err
+	}
+	return edits.Change{Path: e.Path, Content: updated, Delete: e.Delete}, nil
+}
+
+// defaultReviewPrompt is sent by /diff when no prompt is given
+const defaultReviewPrompt = "Review these changes. Point out bugs, risky edits and missing tests, most important first, and suggest fixes."
+
+// handleDiffCommand attaches the working tree's changes and sends them with
+// a review prompt, or the user's own
+func (a *app) handleDiffCommand(args []string) error {
+	staged := len(args) > 0 && args[0] == "--staged"
+	if staged {
+		args = args[1:]
+	}
+	diff, err := git.Diff(staged)
+	if err != nil {
+		return err
+	}
+	if diff == "" {
+		if staged {
+			fmt.Println("No staged changes to review.")
+		} else {
+			fmt.Println("No unstaged changes to review (use /diff --staged for staged ones).")
+		}
+		return nil
+	}
+
+	label := "Output of git diff"
+	if staged {
+		label += " --staged"
+	}
+	a.sessionMgr.AttachContextItem(session.ContextItem{Label: label, Text: diff, Lang: "diff"})
+	fmt.Printf("Attached diff: %s (~%d tokens)\n", git.Stat(diff), session.EstimateTokens(diff))
+
+	prompt := strings.Join(args, " ")
+	if prompt == "" {
+		prompt = defaultReviewPrompt
+	}
+	return a.sessionMgr.Send(prompt)
+}
+
+// handleCompareCommand sends a prompt to several models in throwaway
+// sessions and prints their answers; the conversation is unchanged
+func (a *app) handleCompareCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: /compare <model,model[,...]> [prompt]")
+	}
+	list, err := a.sessionMgr.CompareModels(strings.Split(args[0], ","))
+	if err != nil {
+		return err
+	}
+	prompt := strings.Join(args[1:], " ")
+	if prompt == "" {
+		prompt, err = a.input.ReadLine("Prompt: ")
+		if errors.Is(err, io.EOF) || errors.Is(err, input.ErrInterrupted) {
+			fmt.Println("Cancelled")
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read the prompt: %w", err)
+		}
+		if prompt = strings.TrimSpace(prompt); prompt == "" {
+			fmt.Println("Cancelled")
+			return nil
+		}
+	}
+
+	ids := make([]string, len(list))
+	for i, info := range list {
+		ids[i] = info.ID
+	}
+	fmt.Printf("Asking %s...\n", strings.Join(ids, ", "))
+	results, err := a.sessionMgr.Compare(list, prompt)
+	if errors.Is(err, session.ErrPromptCancelled) {
+		fmt.Println("Cancelled")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	a.sessionMgr.PrintComparison(results)
+	return nil
+}
+
+// handleSystemCommand shows or changes the session's system prompt
+func (a *app) handleSystemCommand(args []string) error {
+	if len(args) == 0 || args[0] == "show" {
+		if prompt := a.sessionMgr.SystemPrompt(); prompt != "" {
+			fmt.Printf("System prompt:\n%s\n", prompt)
+		} else {
+			fmt.Println("No custom system prompt set. Use /system set <instructions>.")
+		}
+		return nil
+	}
+
+	switch args[0] {
+	case "set":
+		text := strings.Join(args[1:], " ")
+		if strings.TrimSpace(text) == "" {
+			return fmt.Errorf("usage: /system set <instructions>")
+		}
+		if err := a.sessionMgr.SetSystemPrompt(text); err != nil {
+			return err
+		}
+		fmt.Println("System prompt updated; the conversation was carried over.")
+	case "clear":
+		if err := a.sessionMgr.SetSystemPrompt(""); err != nil {
+			return err
+		}
+		fmt.Println("System prompt cleared; the conversation was carried over.")
+	default:
+		return fmt.Errorf("unknown /system subcommand %q (use show, set, or clear)", args[0])
+	}
+	return nil
+}
+
+// handleContextCommand shows, sets or clears the project context in the
+// system message
+func (a *app) handleContextCommand(args []string) error {
+	if len(args) == 0 {
+		if text := a.sessionMgr.ProjectContext(); text != "" {
+			fmt.Printf("Project context (~%d tokens):\n%s\n", session.EstimateTokens(text), text)
+		} else {
+			fmt.Println("No project context. Use /context tree to include the repository's file tree.")
+		}
+		return nil
+	}
+
+	switch args[0] {
+	case "tree":
+		root, err := git.Root()
+		if err != nil {
+			return err
+		}
+		files, err := git.Files(root)
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			return fmt.Errorf("no files found in %s", root)
+		}
+		tree := git.FileTree(files, git.DefaultTreeLines)
+		text := fmt.Sprintf("The user is working in the project at %s. Its files, leaving out those ignored by git:\n\n```\n%s\n```", root, tree)
+		if err := a.sessionMgr.SetProjectContext(text); err != nil {
+			return err
+		}
+		fmt.Printf("Added the file tree of %s (%d files, ~%d tokens) to the system message.\n", root, len(files), session.EstimateTokens(text))
+	case "clear":
+		if err := a.sessionMgr.SetProjectContext(""); err != nil {
+			return err
+		}
+		fmt.Println("Project context removed.")
+	default:
+		return fmt.Errorf("unknown /context subcommand %q (use tree or clear)", args[0])
+	}
+	return nil
+}
+
+// handleSetCommand lists or sets the variables filled into {{name}} in
+// prompts
+func (a *app) handleSetCommand(args []string) error {
+	if len(args) == 0 || args[0] != "var" {
+		return fmt.Errorf("usage: /set var [name=value]")
+	}
+	if len(args) == 1 {
+		vars := a.sessionMgr.Vars()
+		if len(vars) == 0 {
+			fmt.Println("No variables set. Use /set var name=value, then {{name}} in a prompt.")
+		} else {
+			names := make([]string, 0, len(vars))
+			for name := range vars {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			fmt.Println("Variables:")
+			for _, name := range names {
+				fmt.Printf("  %s = %s\n", name, vars[name])
+			}
+		}
+		fmt.Printf("Built-in: %s\n", strings.Join(session.BuiltinVars(), ", "))
+		return nil
+	}
+
+	name, value, ok := strings.Cut(strings.Join(args[1:], " "), "=")
+	if !ok {
+		return fmt.Errorf("usage: /set var name=value")
+	}
+	name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+	if err := a.sessionMgr.SetVar(name, value); err != nil {
+		return err
+	}
+	if value == "" {
+		fmt.Printf("Removed {{%s}}.\n", name)
+	} else {
+		fmt.Printf("{{%s}} = %s\n", name, value)
+	}
+	return nil
+}
+
+// handleRedoCommand lists the conversation's turns, or lets the user edit
+// turn N's prompt and resends it in a forked session
+func (a *app) handleRedoCommand(args []string) error {
+	turns := a.sessionMgr.Turns()
+	if len(turns) == 0 {
+		return fmt.Errorf("no turns to redo yet")
+	}
+	if len(args) == 0 {
+		fmt.Println("Turns:")
+		for i, turn := range turns {
+			fmt.Printf("  %d. %s\n", i+1, truncate(turn.Prompt, 70))
+		}
+		fmt.Println("\nUse /redo N to edit and resend a prompt.")
+		return nil
+	}
+
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 1 || n > len(turns) {
+		return fmt.Errorf("usage: /redo N, where N is between 1 and %d", len(turns))
+	}
+
+	prompt, err := a.input.EditLine("redo> ", turns[n-1].Prompt)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(prompt) == "" {
+		fmt.Println("Cancelled")
+		return nil
+	}
+
+	saved, err := a.sessionMgr.ForkAt(n)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Forked at turn %d; the original conversation is saved as branch %d (see /branch).\n", n, saved)
+	return a.sessionMgr.Send(prompt)
+}
+
+// handleRetryCommand resends the last prompt to the current model
+func (a *app) handleRetryCommand() error {
+	prompt, branch, err := a.sessionMgr.PrepareRetry()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Retrying with %s: %s\n", a.sessionMgr.GetCurrentModel(), truncate(prompt, 60))
+	if branch > 0 {
+		fmt.Printf("The previous answer is saved as branch %d (see /branch).\n", branch)
+	}
+	return a.sessionMgr.Send(prompt)
+}
+
+// handleRegenCommand answers the last prompt again with the named model, or
+// the current one, and prints the new answer
+func (a *app) handleRegenCommand(args []string) error {
+	list, err := a.sessionMgr.GetModels()
+	if err != nil {
+		return fmt.Errorf("failed to list models: %w", err)
+	}
+	name := strings.Join(args, " ")
+	if name == "" {
+		name = a.sessionMgr.GetCurrentModel()
+	}
+	model, err := models.Match(list, a.sessionMgr.ModelAliases(), name)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Regenerating with %s...\n", model.ID)
+	result, err := a.sessionMgr.Regenerate(model)
+	if err != nil {
+		return err
+	}
+	a.sessionMgr.PrintComparison([]session.Comparison{result})
+	return nil
+}
+
+// handleEditCommand opens $EDITOR, seeded with any arguments, and sends what
+// was saved as the prompt
+func (a *app) handleEditCommand(args []string) error {
+	prompt, err := input.Compose(strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+	if prompt == "" {
+		fmt.Println("Cancelled (empty prompt)")
+		return nil
+	}
+	fmt.Println(prompt)
+	return a.sessionMgr.Send(prompt)
+}
+
+// defaultHistoryCount is how many prompts /history lists
+const defaultHistoryCount = 20
+
+// handleHistoryCommand lists recent prompts across saved conversations,
+// searches them, or resends one as is or after editing it
+func (a *app) handleHistoryCommand(args []string) error {
+	const usage = "usage: /history [N | search <terms> | run N | edit N]"
+	entries, err := a.sessionMgr.PromptHistory()
+	if err != nil {
+		return fmt.Errorf("failed to read the prompt history: %w", err)
+	}
+	if len(entries) == 0 {
+		fmt.Println("No prompts in the history yet.")
+		return nil
+	}
+
+	if len(args) == 0 || (len(args) == 1 && args[0] != "search") {
+		count := defaultHistoryCount
+		if len(args) == 1 {
+			if count, err = strconv.Atoi(args[0]); err != nil || count < 1 {
+				return fmt.Errorf("%s", usage)
+			}
+		}
+		if count < len(entries) {
+			entries = entries[len(entries)-count:]
+		}
+		printHistory(entries)
+		fmt.Println("\nUse /history run N to send a prompt again, or /history edit N to edit it first.")
+		return nil
+	}
+
+	switch args[0] {
+	case "search":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: /history search <terms>")
+		}
+		found := session.SearchHistory(entries, args[1:])
+		if len(found) == 0 {
+			fmt.Printf("No prompts or replies mention %q.\n", strings.Join(args[1:], " "))
+			return nil
+		}
+		printHistory(found)
+		return nil
+	case "run", "edit":
+		if len(args) != 2 {
+			return fmt.Errorf("%s", usage)
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 || n > len(entries) {
+			return fmt.Errorf("no prompt %q in the history (it has %d)", args[1], len(entries))
+		}
+		prompt := entries[n-1].Prompt
+		if args[0] == "edit" {
+			if prompt, err = a.input.EditLine("> ", prompt); err != nil {
+				return err
+			}
+			if strings.TrimSpace(prompt) == "" {
+				fmt.Println("Cancelled")
+				return nil
+			}
+		} else {
+			fmt.Println(prompt)
+		}
+		a.input.AddHistory(prompt)
+		return a.sessionMgr.Send(prompt)
+	}
+	return fmt.Errorf("%s", usage)
+}
+
+// printHistory lists history entries with their numbers and times
+func printHistory(entries []session.HistoryEntry) {
+	for _, e := range entries {
+		fmt.Printf("%4d  %s  %s\n", e.N, e.At.Format("Jan 2 15:04"), truncate(strings.ReplaceAll(e.Prompt, "\n", " "), 60))
+	}
+}
+
+// maxListedConversations caps the /resume listing
+const maxListedConversations = 20
+
+// handleResumeCommand lists recent saved conversations or resumes one
+func (a *app) handleResumeCommand(args []string) error {
+	if len(args) == 0 {
+		conversations, err := a.sessionMgr.SavedConversations()
+		if err != nil {
+			return err
+		}
+		if len(conversations) == 0 {
+			fmt.Println("No saved conversations yet.")
+			return nil
+		}
+		fmt.Println("Saved conversations (most recent first):")
+		for i, c := range conversations {
+			if i == maxListedConversations {
+				fmt.Printf("  ... and %d older\n", len(conversations)-i)
+				break
+			}
+			current := "  "
+			if c.ID == a.sessionMgr.ConversationID() {
+				current = "* "
+			}
+			fmt.Printf("%s%s  %d turns on %s, updated %s", current, c.ID, c.TurnCount, c.Model, c.UpdatedAt.Format("Jan 2 15:04"))
+			if label := conversationLabel(&c); label != "" {
+				fmt.Printf(" (%s)", label)
+			}
+			fmt.Println()
+		}
+		fmt.Println("\nUse /resume <id> to continue one, or /sessions delete <id> to delete it.")
+		return nil
+	}
+
+	if err := a.sessionMgr.Resume(args[0]); err != nil {
+		return err
+	}
+	fmt.Printf("Resumed conversation %s on %s.\n", args[0], a.sessionMgr.GetCurrentModel())
+	return nil
+}
+
+// conversationLabel describes a saved conversation by its title, or else
+// its first prompt
+func conversationLabel(c *session.ConversationInfo) string {
+	if c.Title != "" {
+		return c.Title
+	}
+	return truncate(c.FirstPrompt, 40)
+}
+
+// handleSessionsCommand lists the named sessions, or deletes a saved
+// conversation
+func (a *app) handleSessionsCommand(args []string) error {
+	if len(args) > 0 {
+		if args[0] != "delete" || len(args) != 2 {
+			return fmt.Errorf("usage: /sessions [delete <id>]")
+		}
+		if err := a.sessionMgr.DeleteConversation(args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("Deleted conversation %s.\n", args[1])
+		return nil
+	}
+	sessions, err := a.sessionMgr.Sessions()
+	if err != nil {
+		return err
+	}
+	if len(sessions) == 0 {
+		fmt.Println("No named sessions. /session new <name> starts one.")
+		return nil
+	}
+	fmt.Println("Sessions (most recently active first):")
+	for _, s := range sessions {
+		current := "  "
+		if s.Current {
+			current = "* "
+		}
+		fmt.Printf("%s%s", current, s.Name)
+		if s.Title != "" {
+			fmt.Printf(": %s", s.Title)
+		}
+		fmt.Printf("  %d turns on %s", s.Turns, s.Model)
+		if s.TokenLimit > 0 {
+			fmt.Printf(", %d/%d tokens", s.Tokens, s.TokenLimit)
+		}
+		if !s.LastActivity.IsZero() {
+			fmt.Printf(", active %s", s.LastActivity.Format("Jan 2 15:04"))
+		}
+		if s.Parent != "" {
+			fmt.Printf(", forked from %s", s.Parent)
+		}
+		if s.Open && !s.Current {
+			fmt.Print(" (open)")
+		}
+		fmt.Println()
+	}
+	fmt.Println("\nUse /session open <name> to switch.")
+	return nil
+}
+
+// handleForkCommand copies the conversation into a new named session
+func (a *app) handleForkCommand(args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: /fork [name]")
+	}
+	parent := a.sessionMgr.SessionName()
+	name, branch, err := a.sessionMgr.Fork(strings.Join(args, ""))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Forked into session %s on %s.", name, a.sessionMgr.GetCurrentModel())
+	switch {
+	case parent != "":
+		fmt.Printf(" /session open %s goes back.\n", parent)
+	case branch > 0:
+		fmt.Printf(" The original is saved as branch %d (see /branch).\n", branch)
+	default:
+		fmt.Println()
+	}
+	return nil
+}
+
+// handleSessionCommand starts a named session or switches to one
+func (a *app) handleSessionCommand(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: /session <new|open> <name>")
+	}
+
+	var branch int
+	var err error
+	switch args[0] {
+	case "new":
+		branch, err = a.sessionMgr.NewSession(args[1])
+		if err == nil {
+			fmt.Printf("Started session %s on %s.\n", args[1], a.sessionMgr.GetCurrentModel())
+		}
+	case "open":
+		branch, err = a.sessionMgr.OpenSession(args[1])
+		if err == nil {
+			fmt.Printf("Switched to session %s on %s.\n", args[1], a.sessionMgr.GetCurrentModel())
+		}
+	default:
+		return fmt.Errorf("unknown /session subcommand %q (use new or open)", args[0])
+	}
+	if err != nil {
+		return err
+	}
+	if branch > 0 {
+		fmt.Printf("The previous conversation is saved as branch %d (see /branch).\n", branch)
+	}
+	return nil
+}
+
+// handleSwitchCommand lists the live sessions or switches to one
+func (a *app) handleSwitchCommand(args []string) error {
+	live := a.sessionMgr.LiveSessions()
+	if len(args) == 0 {
+		if len(live) == 0 {
+			fmt.Println("No open sessions. /session new <name> starts one.")
+			return nil
+		}
+		fmt.Println("Open sessions:")
+		for i, s := range live {
+			current := "  "
+			if s.Current {
+				current = "* "
+			}
+			fmt.Printf("%s%d. %s  %d turns on %s", current, i+1, s.Name, s.Turns, s.Model)
+			if s.TokenLimit > 0 {
+				fmt.Printf(", %d/%d tokens", s.Tokens, s.TokenLimit)
+			}
+			fmt.Println()
+		}
+		fmt.Println("\nUse /switch N to switch.")
+		return nil
+	}
+
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("usage: /switch [N]")
+	}
+	branch, err := a.sessionMgr.SwitchSession(n)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Switched to session %s on %s.\n", a.sessionMgr.SessionName(), a.sessionMgr.GetCurrentModel())
+	if branch > 0 {
+		fmt.Printf("The previous conversation is saved as branch %d (see /branch).\n", branch)
+	}
+	return nil
+}
+
+// completeSession completes /session's subcommand, then the names of
+// existing sessions for "open"
+func (a *app) completeSession(args []string) []string {
+	if len(args) == 1 {
+		return command.FixedCompleter("new", "open")(args)
+	}
+	if len(args) != 2 || args[0] != "open" {
+		return nil
+	}
+	sessions, _ := a.sessionMgr.Sessions()
+	var names []string
+	for _, s := range sessions {
+		if !s.Current {
+			names = append(names, s.Name)
+		}
+	}
+	return command.FixedCompleter(names...)(args[1:])
+}
+
+// handleModelCommand switches to the model args name, or shows the current
+// model and the configured aliases
+func (a *app) handleModelCommand(args []string) error {
+	if len(args) == 0 {
+		fmt.Printf("Current model: %s (%.2fx)\n", a.sessionMgr.GetCurrentModel(), a.sessionMgr.GetCurrentMultiplier())
+		aliases := a.sessionMgr.ModelAliases()
+		if len(aliases) > 0 {
+			fmt.Println("Aliases:")
+			for _, alias := range aliases.Names() {
+				fmt.Printf("  %-10s %s\n", alias, aliases[alias])
+			}
+		}
+		return nil
+	}
+	if args[0] == "info" {
+		return a.showModelInfo(strings.Join(args[1:], " "))
+	}
+	model, err := a.sessionMgr.SwitchModel(strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+	rememberModel(model.ID)
+	notice("Switched to: %s (%.2fx)\n\n", model.ID, models.Multiplier(model))
+	return nil
+}
+
+// showModelInfo prints everything known about the model name refers to, or
+// the current model
+func (a *app) showModelInfo(name string) error {
+	list, err := a.sessionMgr.GetModels()
+	if err != nil {
+		return fmt.Errorf("failed to list models: %w", err)
+	}
+	if name == "" {
+		name = a.sessionMgr.GetCurrentModel()
+	}
+	info, err := models.Match(list, a.sessionMgr.ModelAliases(), name)
+	if err != nil {
+		return err
+	}
+	fmt.Print(models.Details(info))
+	return nil
+}
+
+// completeModel completes /model and /model info with aliases and model IDs
+func (a *app) completeModel(args []string) []string {
+	switch {
+	case len(args) == 1:
+		matches := a.completeModelName(args)
+		if strings.HasPrefix("info", args[0]) {
+			matches = append([]string{"info"}, matches...)
+		}
+		return matches
+	case len(args) == 2 && args[0] == "info":
+		return a.completeModelName(args[1:])
+	}
+	return nil
+}
+
+// completeModelName completes a single model argument with aliases and
+// model IDs
+func (a *app) completeModelName(args []string) []string {
+	if len(args) != 1 {
+		return nil
+	}
+	list, err := a.sessionMgr.GetModels()
+	if err != nil {
+		list = nil
+	}
+	return models.Complete(list, a.sessionMgr.ModelAliases(), args[0])
+}
+
+// handleCopyCommand copies the last reply, or with "code [N]" one of its
+// code blocks, to the clipboard
+func (a *app) handleCopyCommand(args []string) error {
+	reply := a.sessionMgr.LastReply()
+	if reply == "" {
+		return fmt.Errorf("no reply to copy yet")
+	}
+	if len(args) == 0 {
+		return copyToClipboard(reply, "the last reply")
+	}
+	if args[0] != "code" || len(args) > 2 {
+		return fmt.Errorf("usage: /copy [code [N]]")
+	}
+
+	blocks := session.CodeBlocks(reply)
+	switch {
+	case len(blocks) == 0:
+		return fmt.Errorf("the last reply has no code blocks")
+	case len(args) == 1 && len(blocks) == 1:
+		return copyToClipboard(blocks[0].Code, "the code block")
+	case len(args) == 1:
+		printCodeBlocks(blocks)
+		fmt.Println("Use /copy code N to copy one.")
+		return nil
+	}
+
+	block, err := codeBlock(blocks, args[1])
+	if err != nil {
+		return err
+	}
+	return copyToClipboard(block.Code, fmt.Sprintf("code block %s", args[1]))
+}
+
+// handleCodeCommand lists the last reply's code blocks, prints one without
+// styling, or saves one to a file
+func (a *app) handleCodeCommand(args []string) error {
+	reply := a.sessionMgr.LastReply()
+	if reply == "" {
+		return fmt.Errorf("no reply yet")
+	}
+	blocks := session.CodeBlocks(reply)
+	if len(blocks) == 0 {
+		return fmt.Errorf("the last reply has no code blocks")
+	}
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+
+	switch {
+	case args[0] == "list" && len(args) == 1:
+		printCodeBlocks(blocks)
+		fmt.Println("Use /code show N to print one, or /code save N <file> to save it.")
+		return nil
+	case args[0] == "show" && len(args) == 2:
+		block, err := codeBlock(blocks, args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Println(block.Code)
+		return nil
+	case args[0] == "save" && len(args) == 3:
+		block, err := codeBlock(blocks, args[1])
+		if err != nil {
+			return err
+		}
+		path := args[2]
+		if !a.confirmOverwrite(path) {
+			fmt.Println("Not saved.")
+			return nil
+		}
+		if err := os.WriteFile(path, []byte(block.Code+"\n"), 0644); err != nil {
+			return fmt.Errorf("failed to save code block: %w", err)
+		}
+		fmt.Printf("Saved code block %s to %s\n", args[1], path)
+		return nil
+	}
+	return fmt.Errorf("usage: /code [list | show N | save N <file>]")
+}
+
+// confirmOverwrite asks before a file at path is replaced; it is true when
+// there is none
+func (a *app) confirmOverwrite(path string) bool {
+	if _, err := os.Stat(path); err != nil {
+		return true
+	}
+	return a.confirm(fmt.Sprintf("%s exists. Overwrite it? [y/N]: ", path))
+}
+
+// printCodeBlocks lists code blocks with their language, length and first
+// line
+func printCodeBlocks(blocks []session.CodeBlock) {
+	fmt.Printf("The last reply has %d code blocks:\n", len(blocks))
+	for i, block := range blocks {
+		lang := block.Lang
+		if lang == "" {
+			lang = "text"
+		}
+		lines := strings.Count(block.Code, "\n") + 1
+		fmt.Printf("  %d. %s (%d lines): %s\n", i+1, lang, lines, truncate(strings.SplitN(block.Code, "\n", 2)[0], 50))
+	}
+}
+
+// codeBlock returns the code block numbered arg, counting from 1
+func codeBlock(blocks []session.CodeBlock, arg string) (session.CodeBlock, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 || n > len(blocks) {
+		return session.CodeBlock{}, fmt.Errorf("no code block %q (the last reply has %d)", arg, len(blocks))
+	}
+	return blocks[n-1], nil
+}
+
+// copyToClipboard copies text and reports what was copied and how
+func copyToClipboard(text, what string) error {
+	method, err := clipboard.Copy(text)
+	if err != nil {
+		return fmt.Errorf("failed to copy %s: %w", what, err)
+	}
+	lines := strings.Count(strings.TrimSuffix(text, "\n"), "\n") + 1
+	fmt.Printf("Copied %s (%d lines) to the clipboard via %s.\n", what, lines, method)
+	return nil
+}
+
+// handleCostCommand totals the usage ledger for the current day, week or
+// month (the default)
+func (a *app) handleCostCommand(args []string) error {
+	period := ""
+	if len(args) > 0 {
+		period = args[0]
+	}
+	now := time.Now()
+	since, err := ledger.PeriodStart(period, now)
+	if err != nil {
+		return err
+	}
+	l, err := usageLedger()
+	if err != nil {
+		return err
+	}
+	entries, err := l.Entries(since)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Printf("No requests recorded since %s.\n", since.Format("Mon Jan 2"))
+		return nil
+	}
+	return ledger.Summarize(entries, since, now).Write(os.Stdout)
+}
+
+// handleSaveCommand writes the conversation as Markdown to args[0], or to
+// a timestamped file in the current directory
+func (a *app) handleSaveCommand(args []string) error {
+	if len(a.sessionMgr.Transcript()) == 0 {
+		return fmt.Errorf("nothing to save yet")
+	}
+	path := a.transcriptName(".md")
+	if len(args) > 0 {
+		path = config.ExpandHome(strings.Join(args, " "))
+	}
+	if !a.confirmOverwrite(path) {
+		fmt.Println("Not saved.")
+		return nil
+	}
+
+	if err := os.WriteFile(path, []byte(a.sessionMgr.MarkdownTranscript()), 0644); err != nil {
+		return fmt.Errorf("failed to save transcript: %w", err)
+	}
+	fmt.Printf("Saved %d turns to %s\n", len(a.sessionMgr.Transcript()), path)
+	return nil
+}
+
+// handleTitleCommand shows the conversation's title or replaces it
+func (a *app) handleTitleCommand(args []string) error {
+	if len(args) == 0 {
+		if title := a.sessionMgr.Title(); title != "" {
+			fmt.Println(title)
+		} else {
+			fmt.Println("No title yet; one is generated after the first reply, or use /title <name>.")
+		}
+		return nil
+	}
+	if err := a.sessionMgr.SetTitle(strings.Join(args, " ")); err != nil {
+		return err
+	}
+	fmt.Printf("Title set to %q.\n", a.sessionMgr.Title())
+	return nil
+}
+
+// transcriptName is the default file /save and /export write to: a
+// timestamp followed by the conversation's title, if it has one
+func (a *app) transcriptName(ext string) string {
+	name := "cocli-" + time.Now().Format("20060102-150405")
+	if slug := session.TitleSlug(a.sessionMgr.Title()); slug != "" {
+		name += "-" + slug
+	}
+	return name + ext
+}
+
+// handleCompactCommand compacts the conversation, or shows or sets whether
+// the oldest turns are summarized automatically near the token limit
+func (a *app) handleCompactCommand(args []string) error {
+	if len(args) == 0 {
+		fmt.Println("Compacting conversation...")
+		return a.sessionMgr.Compact()
+	}
+	if args[0] != "auto" || len(args) > 2 {
+		return fmt.Errorf("usage: /compact [auto [on|off]]")
+	}
+	if len(args) == 2 {
+		switch args[1] {
+		case "on":
+			a.sessionMgr.SetAutoSummarize(true)
+		case "off":
+			a.sessionMgr.SetAutoSummarize(false)
+		default:
+			return fmt.Errorf("usage: /compact auto [on|off]")
+		}
+	}
+	if a.sessionMgr.AutoSummarize() {
+		fmt.Println("The oldest turns are summarized when the context window is nearly full.")
+	} else {
+		fmt.Println("Automatic summarizing is off; use /compact when the context window fills up.")
+	}
+	return nil
+}
+
+// completeCompact completes /compact's arguments
+func (a *app) completeCompact(args []string) []string {
+	if len(args) == 1 {
+		return command.FixedCompleter("auto")(args)
+	}
+	if len(args) != 2 || args[0] != "auto" {
+		return nil
+	}
+	return command.FixedCompleter("on", "off")(args[1:])
+}
+
+// handleExportCommand writes the conversation as portable JSON
+func (a *app) handleExportCommand(args []string) error {
+	path := a.transcriptName(".json")
+	if len(args) > 0 {
+		path = config.ExpandHome(strings.Join(args, " "))
+	}
+	n, err := a.sessionMgr.Export(path)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Exported %d turns to %s\n", n, path)
+	return nil
+}
+
+// handleImportCommand continues an exported conversation
+func (a *app) handleImportCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: /import <path>")
+	}
+	path := config.ExpandHome(strings.Join(args, " "))
+	exported, err := a.sessionMgr.Import(path)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d turns on %s", len(exported.Turns), a.sessionMgr.GetCurrentModel())
+	if id := a.sessionMgr.ConversationID(); id != "" {
+		fmt.Printf(" as conversation %s", id)
+	}
+	fmt.Println(".")
+	return nil
+}
+
+// completeConversationID completes /resume's argument from the saved
+// conversations
+func (a *app) completeConversationID(args []string) []string {
+	conversations, _ := a.sessionMgr.SavedConversations()
+	ids := make([]string, len(conversations))
+	for i, c := range conversations {
+		ids[i] = c.ID
+	}
+	return command.FixedCompleter(ids...)(args)
+}
+
+// handleBranchCommand lists saved branches or switches to one
+func (a *app) handleBranchCommand(args []string) error {
+	branches := a.sessionMgr.Branches()
+	if len(args) == 0 {
+		if len(branches) == 0 {
+			fmt.Println("No saved branches. /redo N forks the conversation and saves the original here.")
+			return nil
+		}
+		fmt.Println("Saved branches:")
+		for _, b := range branches {
+			fmt.Printf("  %d. %d turns on %s, saved %s", b.Index, b.Turns, b.Model, b.SavedAt.Format("15:04:05"))
+			if b.LastPrompt != "" {
+				fmt.Printf(" (last: %s)", truncate(b.LastPrompt, 40))
+			}
+			fmt.Println()
+		}
+		fmt.Println("\nUse /branch N to switch; the current conversation is saved in its place.")
+		return nil
+	}
+
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("usage: /branch [N]")
+	}
+	if err := a.sessionMgr.SwitchBranch(n); err != nil {
+		return err
+	}
+	fmt.Printf("Switched to branch %d; the previous conversation is now branch %d.\n", n, n)
+	return nil
+}
+
+// truncate shortens s to at most max runes on a single line
+func truncate(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-3]) + "..."
+}
+
+// plural returns one when n is 1 and many otherwise
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+// displayPath shortens an absolute path under the current directory to a
+// relative one
+func displayPath(path string) string {
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, path); err == nil && filepath.IsLocal(rel) {
+			return rel
+		}
+	}
+	return path
+}
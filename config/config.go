@@ -0,0 +1,412 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	dirName  = ".cocli"
+	fileName = "config.json"
+)
+
+// Config is the user configuration loaded from ~/.cocli/config.json.
+// Every field is optional; zero values mean "use the built-in default".
+type Config struct {
+	// Model is the default model name or ID for new sessions
+	Model string `json:"model,omitempty"`
+	// ModelAliases maps short names usable wherever a model is named (e.g.
+	// "fast") to a model ID or name
+	ModelAliases map[string]string `json:"model_aliases,omitempty"`
+	// ModelsCacheTTL is how long the model list saved in models.json is
+	// reused by later runs; nil means the built-in default and "0s" fetches
+	// it on every start
+	ModelsCacheTTL *Duration `json:"models_cache_ttl,omitempty"`
+	// Prompt is the interactive prompt's template, such as
+	// "{model_short} {tokens_pct}%> "; "" keeps the built-in prompt
+	Prompt string `json:"prompt,omitempty"`
+	// SystemPrompt is added to the system message of every session
+	SystemPrompt string `json:"system_prompt,omitempty"`
+	// MCPServers are the MCP servers sessions may use, by name
+	MCPServers map[string]MCPServer `json:"mcp_servers,omitempty"`
+	// Daemon configures the background copilot server
+	Daemon DaemonConfig `json:"daemon"`
+	// Renderer configures markdown output
+	Renderer RendererConfig `json:"renderer"`
+	// Attachments controls which files may be attached to prompts
+	Attachments AttachmentsConfig `json:"attachments"`
+	// Shell controls the commands !(command) in a prompt may run
+	Shell ShellConfig `json:"shell"`
+	// Context configures context-window warnings and auto-compaction
+	Context ContextConfig `json:"context"`
+	// Session configures how responses are received
+	Session SessionConfig `json:"session"`
+	// History bounds the conversations saved in the sessions directory
+	History HistoryConfig `json:"history"`
+	// Notify configures notifications sent when a turn finishes
+	Notify NotifyConfig `json:"notify"`
+	// Log configures cocli's own warnings and diagnostics
+	Log LogConfig `json:"log"`
+
+	// Project is the project config merged over this one, if any
+	Project *Project `json:"-"`
+}
+
+// MCPServer configures an MCP server: a local command, or a remote server
+// at a URL
+type MCPServer struct {
+	// Type is "local" (the default), "stdio", "http" or "sse"
+	Type string `json:"type,omitempty"`
+	// Command, Args, Env and Cwd start a local server
+	Command string            `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	Cwd     string            `json:"cwd,omitempty"`
+	// URL and Headers reach a remote server
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Tools lists the server's tools the model may use; empty allows all
+	Tools []string `json:"tools,omitempty"`
+	// Timeout is in milliseconds; 0 keeps the server's default
+	Timeout int `json:"timeout,omitempty"`
+}
+
+// Remote reports whether the server is reached at a URL
+func (s MCPServer) Remote() bool {
+	return s.Type == "http" || s.Type == "sse"
+}
+
+// DaemonConfig configures the background copilot server
+type DaemonConfig struct {
+	// Port the daemon listens on
+	Port int `json:"port,omitempty"`
+	// StartTimeout is how long to wait for the daemon to become healthy
+	StartTimeout Duration `json:"start_timeout,omitempty"`
+	// HealthCheck selects how the daemon is probed; only "sdk-ping" is
+	// left, see ParseHealthCheck
+	HealthCheck string `json:"health_check,omitempty"`
+	// MaxRestarts is how many times a crashed daemon is restarted within
+	// RestartWindow; nil means the built-in default and 0 turns it off
+	MaxRestarts *int `json:"max_restarts,omitempty"`
+	// RestartWindow is the period MaxRestarts applies to
+	RestartWindow Duration `json:"restart_window,omitempty"`
+	// Remote is the host:port of a daemon on another machine to use instead
+	// of the local one
+	Remote string `json:"remote,omitempty"`
+	// Token authenticates with the Remote daemon; `cocli server token`
+	// prints it on the daemon's machine
+	Token string `json:"token,omitempty"`
+	// TLS secures connections to a remote daemon and, with Listen, lets
+	// `cocli server run` accept them
+	TLS DaemonTLSConfig `json:"tls"`
+}
+
+// DaemonTLSConfig configures TLS between cocli and a daemon on another
+// machine. The same keys serve both ends: on a client, CertFile and KeyFile
+// are its client certificate and CAFile verifies the daemon; on the daemon,
+// they are its server certificate and CAFile verifies clients.
+type DaemonTLSConfig struct {
+	// Enabled connects to Remote over TLS
+	Enabled bool `json:"enabled,omitempty"`
+	// CAFile is a PEM bundle of trusted CA certificates
+	CAFile string `json:"ca_file,omitempty"`
+	// CertFile and KeyFile are this side's PEM certificate and key
+	CertFile string `json:"cert_file,omitempty"`
+	KeyFile  string `json:"key_file,omitempty"`
+	// InsecureSkipVerify accepts any daemon certificate; for development only
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+	// Listen is the address `cocli server run` accepts TLS connections on
+	// (e.g. ":4443")
+	Listen string `json:"listen,omitempty"`
+}
+
+// RendererConfig configures markdown output
+type RendererConfig struct {
+	// Style is a glamour style name (e.g. "dark", "light")
+	Style string `json:"style,omitempty"`
+	// WordWrap is the column at which output is wrapped
+	WordWrap int `json:"word_wrap,omitempty"`
+	// CodeStyle is the syntax highlighting style for code blocks (e.g.
+	// "monokai", "github"); empty keeps the colors of Style
+	CodeStyle string `json:"code_style,omitempty"`
+	// CopyCode prints code blocks as plain, unwrapped text that copies
+	// cleanly from the terminal
+	CopyCode bool `json:"copy_code,omitempty"`
+	// Live prints replies as raw text while they stream and redraws each
+	// element formatted once it is complete
+	Live bool `json:"live,omitempty"`
+	// Callouts overrides the icon and color of "> [!NOTE]"-style callouts,
+	// keyed by kind (see CalloutKinds)
+	Callouts map[string]CalloutTheme `json:"callouts,omitempty"`
+}
+
+// AttachmentsConfig controls which files may be attached to prompts
+type AttachmentsConfig struct {
+	// Allow is a list of glob patterns; when non-empty, only matching paths may be attached
+	Allow []string `json:"allow,omitempty"`
+}
+
+// ShellConfig controls the commands !(command) in a prompt may run
+type ShellConfig struct {
+	// Allow lists commands that run without asking first; an entry matches
+	// the command itself and the command with more arguments (e.g. "git log")
+	Allow []string `json:"allow,omitempty"`
+	// MaxTokens caps the output of each command (default 2000)
+	MaxTokens int `json:"max_tokens,omitempty"`
+	// Timeout is how long a command may run (default 30s)
+	Timeout Duration `json:"timeout,omitempty"`
+}
+
+// ContextConfig configures context-window warnings and auto-compaction
+type ContextConfig struct {
+	// WarnAt lists usage percentages that trigger a warning (default 75, 90)
+	WarnAt []int `json:"warn_at,omitempty"`
+	// AutoCompactAt compacts the conversation before the next send once usage
+	// reaches this percentage; 0 disables it
+	AutoCompactAt int `json:"auto_compact_at,omitempty"`
+	// SummarizeBelow summarizes the oldest turns before the next send once
+	// fewer tokens than this are left; nil means the built-in default and 0
+	// disables it
+	SummarizeBelow *int `json:"summarize_below,omitempty"`
+}
+
+// SessionConfig configures how responses are received
+type SessionConfig struct {
+	// StallTimeout is how long a response may go without output before it is
+	// reported as stalled; nil means the built-in default and "0s" disables it
+	StallTimeout *Duration `json:"stall_timeout,omitempty"`
+	// RequestTimeout is how long to wait for a reply to finish; nil means
+	// the built-in default and "0s" waits indefinitely
+	RequestTimeout *Duration `json:"request_timeout,omitempty"`
+	// RetryAttempts is how many times a prompt is sent in all when its
+	// reply fails with a transient error; nil means the built-in default
+	// and 0 or 1 never retries
+	RetryAttempts *int `json:"retry_attempts,omitempty"`
+	// RetryDelay is the wait before the first retry, doubled before each
+	// later one; nil means the built-in default
+	RetryDelay *Duration `json:"retry_delay,omitempty"`
+	// MaxTurns archives the conversation and starts a new one after this
+	// many turns; 0 means no limit
+	MaxTurns int `json:"max_turns,omitempty"`
+	// MaxAge archives the conversation and starts a new one once its first
+	// turn is this old; 0 means no limit
+	MaxAge Duration `json:"max_age,omitempty"`
+	// MaxArchives is how many archived transcripts are kept (default 100)
+	MaxArchives int `json:"max_archives,omitempty"`
+	// Tools decides which tool calls the model may make: "ask" (the
+	// default) asks before shell commands and file writes, "auto" allows
+	// every call and "off" denies those that need permission
+	Tools string `json:"tools,omitempty"`
+}
+
+// HistoryConfig bounds the saved conversations, which are pruned on
+// startup and by `cocli sessions prune`
+type HistoryConfig struct {
+	// MaxSessions is how many conversations are kept, the most recently
+	// updated first; 0 means no limit
+	MaxSessions int `json:"max_sessions,omitempty"`
+	// MaxAge removes conversations not updated for this long; 0 means no
+	// limit
+	MaxAge Duration `json:"max_age,omitempty"`
+	// MaxSize caps the disk space they take, such as "200MB"; "" means no
+	// limit
+	MaxSize string `json:"max_size,omitempty"`
+}
+
+// ToolModes are the accepted values of session.tools
+var ToolModes = []string{"auto", "ask", "off"}
+
+// CheckToolMode reports whether s is one of ToolModes
+func CheckToolMode(s string) error {
+	for _, mode := range ToolModes {
+		if strings.EqualFold(s, mode) {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown tool mode %q (use %s)", s, strings.Join(ToolModes, ", "))
+}
+
+// NotifyConfig configures notifications sent when a turn finishes, so CI
+// pipelines and chat bots can react to long cocli jobs
+type NotifyConfig struct {
+	// Webhook is an http(s) URL that is POSTed a JSON summary of the turn
+	Webhook string `json:"webhook,omitempty"`
+	// Command is a shell command that receives the JSON summary on stdin
+	Command string `json:"command,omitempty"`
+	// MinDuration skips notifications for turns that finish faster than
+	// this; 0 notifies after every turn
+	MinDuration Duration `json:"min_duration,omitempty"`
+}
+
+// LogConfig configures cocli's log of warnings and diagnostics
+type LogConfig struct {
+	// Level is the lowest level logged: debug, info (the default), warn or
+	// error; --verbose means debug
+	Level string `json:"level,omitempty"`
+	// Format is "text" (the default) or "json"
+	Format string `json:"format,omitempty"`
+	// File receives the logs instead of stderr, which still shows warnings
+	// and errors
+	File string `json:"file,omitempty"`
+}
+
+// checkWebhook reports whether s is usable as a webhook URL
+func checkWebhook(s string) error {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid webhook URL %q (want an http or https URL)", s)
+	}
+	return nil
+}
+
+// CheckServerAddr reports whether s is a usable host:port daemon address
+func CheckServerAddr(s string) error {
+	host, port, err := net.SplitHostPort(s)
+	if err != nil || host == "" {
+		return fmt.Errorf("invalid daemon address %q (want host:port, e.g. devbox:4321)", s)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("invalid daemon address %q (port must be between 1 and 65535)", s)
+	}
+	return nil
+}
+
+// checkListenAddr reports whether s is a usable [host]:port listen address
+func checkListenAddr(s string) error {
+	_, port, err := net.SplitHostPort(s)
+	if err != nil {
+		return fmt.Errorf("invalid listen address %q (want [host]:port, e.g. :4443)", s)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("invalid listen address %q (port must be between 1 and 65535)", s)
+	}
+	return nil
+}
+
+// Duration is a time.Duration that reads and writes as a string like "30s".
+// It also accepts whole days, e.g. "7d".
+type Duration time.Duration
+
+// UnmarshalJSON parses a duration string
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := parseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// parseDuration parses a Go duration string or a whole number of days
+// such as "7d"
+func parseDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// MarshalJSON formats the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// ExpandHome replaces a leading "~/" in path with the user's home directory
+func ExpandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
+
+// DefaultDir returns the cocli config directory: $COCLI_CONFIG_DIR, or
+// ~/.cocli
+func DefaultDir() (string, error) {
+	if dir := os.Getenv(EnvConfigDir); dir != "" {
+		return filepath.Abs(dir)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, dirName), nil
+}
+
+// DefaultPath returns the path to the user config file
+func DefaultPath() (string, error) {
+	dir, err := DefaultDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fileName), nil
+}
+
+// Load reads and validates the config file at path. A missing file yields
+// an empty Config. Validation problems are returned as a *ValidationError.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Config{}, nil
+		}
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	if err := Validate(path, data); err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if err := checkMCPServers(cfg.MCPServers); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	cfg.resolveSecrets(path)
+	return &cfg, nil
+}
+
+// LoadDefault reads the config from the default path, merges the project
+// config found from the working directory over it (see FindProject), and
+// applies the COCLI_* environment overrides (see ApplyEnv)
+func LoadDefault() (*Config, error) {
+	path, err := DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if wd, err := os.Getwd(); err == nil {
+		if projectPath := FindProject(wd); projectPath != "" {
+			project, err := LoadProject(projectPath)
+			if err != nil {
+				return nil, err
+			}
+			cfg.MergeProject(projectPath, project)
+		}
+	}
+	if err := ApplyEnv(cfg, os.Getenv); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
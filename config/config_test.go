@@ -0,0 +1,175 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, `{
+  "model": "gpt-4.1",
+  "model_aliases": {"fast": "claude-haiku-4.5"},
+  "daemon": {"port": 5000, "start_timeout": "1m"},
+  "renderer": {"style": "light", "word_wrap": 120},
+  "attachments": {"allow": ["*.go"]},
+  "context": {"warn_at": [80], "auto_compact_at": 95, "summarize_below": 0},
+  "session": {"stall_timeout": "0s"}
+}`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Model != "gpt-4.1" {
+		t.Errorf("Model = %q, want %q", cfg.Model, "gpt-4.1")
+	}
+	if cfg.ModelAliases["fast"] != "claude-haiku-4.5" {
+		t.Errorf("ModelAliases = %v, want fast -> claude-haiku-4.5", cfg.ModelAliases)
+	}
+	if cfg.Daemon.Port != 5000 {
+		t.Errorf("Daemon.Port = %d, want 5000", cfg.Daemon.Port)
+	}
+	if time.Duration(cfg.Daemon.StartTimeout) != time.Minute {
+		t.Errorf("Daemon.StartTimeout = %v, want 1m", time.Duration(cfg.Daemon.StartTimeout))
+	}
+	if cfg.Renderer.Style != "light" || cfg.Renderer.WordWrap != 120 {
+		t.Errorf("Renderer = %+v, want light/120", cfg.Renderer)
+	}
+	if len(cfg.Attachments.Allow) != 1 || cfg.Attachments.Allow[0] != "*.go" {
+		t.Errorf("Attachments.Allow = %v, want [*.go]", cfg.Attachments.Allow)
+	}
+	if len(cfg.Context.WarnAt) != 1 || cfg.Context.WarnAt[0] != 80 || cfg.Context.AutoCompactAt != 95 {
+		t.Errorf("Context = %+v, want warn_at [80], auto_compact_at 95", cfg.Context)
+	}
+	if cfg.Context.SummarizeBelow == nil || *cfg.Context.SummarizeBelow != 0 {
+		t.Errorf("Context.SummarizeBelow = %v, want explicit 0", cfg.Context.SummarizeBelow)
+	}
+	if cfg.Session.StallTimeout == nil || *cfg.Session.StallTimeout != 0 {
+		t.Errorf("Session.StallTimeout = %v, want explicit 0s", cfg.Session.StallTimeout)
+	}
+}
+
+func TestLoad_Missing(t *testing.T) {
+	cfg, err := Load(filepath.Join(t.TempDir(), "config.json"))
+	if err != nil {
+		t.Fatalf("Load() error = %v, want nil for missing file", err)
+	}
+	if cfg.Model != "" || cfg.Daemon.Port != 0 {
+		t.Errorf("Load() = %+v, want empty config", cfg)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	path := writeConfig(t, `{"daemon": {"port": -1}}`)
+
+	_, err := Load(path)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Load() error = %v, want *ValidationError", err)
+	}
+	if !strings.Contains(err.Error(), path+":1:21:") {
+		t.Errorf("Error() = %q, want file:line:col prefix", err.Error())
+	}
+}
+
+func TestDuration_JSON(t *testing.T) {
+	var d Duration
+	if err := d.UnmarshalJSON([]byte(`"90s"`)); err != nil {
+		t.Fatalf("UnmarshalJSON() error = %v", err)
+	}
+	if time.Duration(d) != 90*time.Second {
+		t.Errorf("Duration = %v, want 90s", time.Duration(d))
+	}
+	data, err := d.MarshalJSON()
+	if err != nil || string(data) != `"1m30s"` {
+		t.Errorf("MarshalJSON() = %s, %v; want \"1m30s\"", data, err)
+	}
+}
+
+func TestDuration_Days(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: `"7d"`, want: 7 * 24 * time.Hour},
+		{in: `"0d"`, want: 0},
+		{in: `"1.5d"`, wantErr: true},
+		{in: `"-1d"`, wantErr: true},
+		{in: `"d"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		var d Duration
+		err := d.UnmarshalJSON([]byte(tt.in))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("UnmarshalJSON(%s) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && time.Duration(d) != tt.want {
+			t.Errorf("UnmarshalJSON(%s) = %v, want %v", tt.in, time.Duration(d), tt.want)
+		}
+	}
+}
+
+func TestDefaultPath(t *testing.T) {
+	path, err := DefaultPath()
+	if err != nil {
+		t.Fatalf("DefaultPath() error = %v", err)
+	}
+	if !strings.HasSuffix(path, filepath.Join(".cocli", "config.json")) {
+		t.Errorf("DefaultPath() = %q, want ~/.cocli/config.json", path)
+	}
+}
+
+func TestCheckServerAddr(t *testing.T) {
+	tests := []struct {
+		addr    string
+		wantErr bool
+	}{
+		{addr: "devbox:4321"},
+		{addr: "10.0.0.5:4500"},
+		{addr: "[::1]:4321"},
+		{addr: "devbox", wantErr: true},
+		{addr: ":4321", wantErr: true},
+		{addr: "devbox:0", wantErr: true},
+		{addr: "devbox:http", wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := CheckServerAddr(tt.addr); (err != nil) != tt.wantErr {
+			t.Errorf("CheckServerAddr(%q) error = %v, wantErr %v", tt.addr, err, tt.wantErr)
+		}
+	}
+}
+
+func TestExpandHome(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "~/notes/today.md", want: filepath.Join(home, "notes", "today.md")},
+		{path: "/etc/hosts", want: "/etc/hosts"},
+		{path: "relative/file", want: "relative/file"},
+		{path: "~other/file", want: "~other/file"},
+	}
+	for _, tt := range tests {
+		if got := ExpandHome(tt.path); got != tt.want {
+			t.Errorf("ExpandHome(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Environment variables cocli reads. Those with a config key override it;
+// command-line flags override both.
+const (
+	EnvModel          = "COCLI_MODEL"
+	EnvStyle          = "COCLI_STYLE"
+	EnvTheme          = "COCLI_THEME"
+	EnvWordWrap       = "COCLI_WORD_WRAP"
+	EnvPort           = "COCLI_PORT"
+	EnvDaemonPort     = "COCLI_DAEMON_PORT"
+	EnvServer         = "COCLI_SERVER"
+	EnvDaemonToken    = "COCLI_DAEMON_TOKEN"
+	EnvStartTimeout   = "COCLI_START_TIMEOUT"
+	EnvStallTimeout   = "COCLI_STALL_TIMEOUT"
+	EnvRequestTimeout = "COCLI_REQUEST_TIMEOUT"
+	EnvConfigDir      = "COCLI_CONFIG_DIR"
+	EnvCopilotCLIPath = "COPILOT_CLI_PATH"
+)
+
+// EnvVar describes an environment variable cocli reads
+type EnvVar struct {
+	Name string
+	// Key is the config key it overrides, "" when it has none
+	Key  string
+	Help string
+	// Secret is set for values that shouldn't be shown
+	Secret bool
+	// apply sets cfg from the value; nil for variables read where they
+	// are used, such as EnvConfigDir
+	apply func(cfg *Config, v string) error
+}
+
+// EnvVars lists every environment variable cocli reads. ApplyEnv applies
+// them in this order, so a later one wins over an earlier one for the same
+// key.
+var EnvVars = []EnvVar{
+	{Name: EnvModel, Key: "model", Help: "Model to start with (ID, name or alias)", apply: func(cfg *Config, v string) error {
+		cfg.Model = v
+		return nil
+	}},
+	{Name: EnvStyle, Key: "renderer.style", Help: "Renderer style, or a .json style file", apply: func(cfg *Config, v string) error {
+		if err := CheckStyle(v); err != nil {
+			return err
+		}
+		cfg.Renderer.Style = v
+		return nil
+	}},
+	{Name: EnvTheme, Key: "renderer.code_style", Help: "Syntax highlighting style for code blocks", apply: func(cfg *Config, v string) error {
+		if err := CheckCodeStyle(v); err != nil {
+			return err
+		}
+		cfg.Renderer.CodeStyle = v
+		return nil
+	}},
+	{Name: EnvWordWrap, Key: "renderer.word_wrap", Help: "Column to wrap replies at", apply: func(cfg *Config, v string) error {
+		n, err := envInt(v, 20, 1000)
+		if err != nil {
+			return err
+		}
+		cfg.Renderer.WordWrap = n
+		return nil
+	}},
+	{Name: EnvPort, Key: "daemon.port", Help: "Port of the local daemon (COCLI_DAEMON_PORT wins if both are set)", apply: applyDaemonPort},
+	{Name: EnvDaemonPort, Key: "daemon.port", Help: "Port of the local daemon", apply: applyDaemonPort},
+	{Name: EnvServer, Key: "daemon.remote", Help: "host:port of a daemon on another machine", apply: func(cfg *Config, v string) error {
+		if err := CheckServerAddr(v); err != nil {
+			return err
+		}
+		cfg.Daemon.Remote = v
+		return nil
+	}},
+	{Name: EnvDaemonToken, Key: "daemon.token", Help: "Token of a daemon on another machine", Secret: true, apply: func(cfg *Config, v string) error {
+		cfg.Daemon.Token = v
+		return nil
+	}},
+	{Name: EnvStartTimeout, Key: "daemon.start_timeout", Help: "How long to wait for the copilot server to start", apply: func(cfg *Config, v string) error {
+		d, err := envDuration(v)
+		if err != nil {
+			return err
+		}
+		cfg.Daemon.StartTimeout = d
+		return nil
+	}},
+	{Name: EnvStallTimeout, Key: "session.stall_timeout", Help: "Silence before a reply counts as stalled (0s turns it off)", apply: func(cfg *Config, v string) error {
+		d, err := envDuration(v)
+		if err != nil {
+			return err
+		}
+		cfg.Session.StallTimeout = &d
+		return nil
+	}},
+	{Name: EnvRequestTimeout, Key: "session.request_timeout", Help: "Longest a reply may take", apply: func(cfg *Config, v string) error {
+		d, err := envDuration(v)
+		if err != nil {
+			return err
+		}
+		cfg.Session.RequestTimeout = &d
+		return nil
+	}},
+	{Name: EnvConfigDir, Help: "Directory for the config file, sessions, logs and daemon state (default ~/.cocli)"},
+	{Name: EnvCopilotCLIPath, Help: "The copilot CLI executable (default: copilot from PATH)"},
+}
+
+// ApplyEnv overrides cfg with the environment variables in EnvVars that
+// are set, reading them with getenv. Values are checked like their config
+// keys, and cfg is left unchanged by a bad one.
+func ApplyEnv(cfg *Config, getenv func(string) string) error {
+	updated := *cfg
+	for _, env := range EnvVars {
+		v := getenv(env.Name)
+		if v == "" || env.apply == nil {
+			continue
+		}
+		if err := env.apply(&updated, v); err != nil {
+			return fmt.Errorf("%s: %v", env.Name, err)
+		}
+	}
+	*cfg = updated
+	return nil
+}
+
+// applyDaemonPort sets daemon.port from EnvPort or EnvDaemonPort
+func applyDaemonPort(cfg *Config, v string) error {
+	n, err := envInt(v, 1, 65535)
+	if err != nil {
+		return err
+	}
+	cfg.Daemon.Port = n
+	return nil
+}
+
+// envInt parses an integer within [min, max]
+func envInt(v string, min, max int) (int, error) {
+	n, err := strconv.Atoi(v)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("%q is not an integer between %d and %d", v, min, max)
+	}
+	return n, nil
+}
+
+// envDuration parses a duration such as "90s" or "2m"
+func envDuration(v string) (Duration, error) {
+	d, err := parseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", v)
+	}
+	return Duration(d), nil
+}
+
+// CopilotCLIPath returns the copilot CLI set with EnvCopilotCLIPath, or ""
+func CopilotCLIPath() string {
+	return os.Getenv(EnvCopilotCLIPath)
+}
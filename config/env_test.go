@@ -0,0 +1,117 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApplyEnv(t *testing.T) {
+	env := map[string]string{
+		EnvModel:          "gpt-4.1",
+		EnvStyle:          "light",
+		EnvTheme:          "monokai",
+		EnvWordWrap:       "100",
+		EnvDaemonPort:     "5000",
+		EnvServer:         "devbox:4321",
+		EnvDaemonToken:    "secret",
+		EnvStartTimeout:   "1m",
+		EnvStallTimeout:   "0s",
+		EnvRequestTimeout: "20m",
+	}
+	cfg := &Config{Model: "from-file", Daemon: DaemonConfig{Port: 4321}}
+	if err := ApplyEnv(cfg, func(k string) string { return env[k] }); err != nil {
+		t.Fatalf("ApplyEnv() error = %v", err)
+	}
+
+	if cfg.Model != "gpt-4.1" || cfg.Renderer.Style != "light" || cfg.Renderer.CodeStyle != "monokai" || cfg.Renderer.WordWrap != 100 {
+		t.Errorf("renderer = %q/%+v, want the env values", cfg.Model, cfg.Renderer)
+	}
+	if cfg.Daemon.Port != 5000 || cfg.Daemon.Remote != "devbox:4321" || cfg.Daemon.Token != "secret" || time.Duration(cfg.Daemon.StartTimeout) != time.Minute {
+		t.Errorf("daemon = %+v, want port 5000, remote devbox:4321, its token and a 1m start timeout", cfg.Daemon)
+	}
+	if cfg.Session.StallTimeout == nil || *cfg.Session.StallTimeout != 0 {
+		t.Errorf("stall timeout = %v, want 0s", cfg.Session.StallTimeout)
+	}
+	if cfg.Session.RequestTimeout == nil || time.Duration(*cfg.Session.RequestTimeout) != 20*time.Minute {
+		t.Errorf("request timeout = %v, want 20m", cfg.Session.RequestTimeout)
+	}
+}
+
+func TestApplyEnv_Unset(t *testing.T) {
+	cfg := &Config{Model: "from-file"}
+	if err := ApplyEnv(cfg, func(string) string { return "" }); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Model != "from-file" || cfg.Session.StallTimeout != nil {
+		t.Errorf("cfg = %+v, want the file's settings kept", cfg)
+	}
+}
+
+func TestApplyEnv_Invalid(t *testing.T) {
+	tests := map[string]string{
+		EnvWordWrap:       "wide",
+		EnvDaemonPort:     "70000",
+		EnvServer:         "devbox",
+		EnvStartTimeout:   "soon",
+		EnvStallTimeout:   "-",
+		EnvRequestTimeout: "later",
+		EnvStyle:          "neon",
+		EnvTheme:          "neon",
+		EnvPort:           "0",
+	}
+	for name, value := range tests {
+		err := ApplyEnv(&Config{}, func(k string) string {
+			if k == name {
+				return value
+			}
+			return ""
+		})
+		if err == nil || !strings.HasPrefix(err.Error(), name+":") {
+			t.Errorf("%s=%q: error = %v, want one naming the variable", name, value, err)
+		}
+	}
+}
+
+func TestApplyEnv_Precedence(t *testing.T) {
+	env := map[string]string{EnvPort: "5000", EnvDaemonPort: "6000"}
+	cfg := &Config{Daemon: DaemonConfig{Port: 4321}}
+	if err := ApplyEnv(cfg, func(k string) string { return env[k] }); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Daemon.Port != 6000 {
+		t.Errorf("port = %d, want COCLI_DAEMON_PORT to win over COCLI_PORT", cfg.Daemon.Port)
+	}
+
+	// A bad value leaves the config as it was, good values included
+	env = map[string]string{EnvModel: "gpt-4.1", EnvWordWrap: "wide"}
+	cfg = &Config{Model: "from-file"}
+	if err := ApplyEnv(cfg, func(k string) string { return env[k] }); err == nil || cfg.Model != "from-file" {
+		t.Errorf("ApplyEnv() = %v with model %q, want an error and the file's model", err, cfg.Model)
+	}
+}
+
+func TestEnvVars(t *testing.T) {
+	seen := map[string]bool{}
+	for _, env := range EnvVars {
+		if seen[env.Name] || env.Help == "" {
+			t.Errorf("%s: want each variable listed once, with help", env.Name)
+		}
+		seen[env.Name] = true
+		if (env.Key == "") != (env.apply == nil) {
+			t.Errorf("%s: key %q doesn't match whether it is applied", env.Name, env.Key)
+		}
+	}
+}
+
+func TestDefaultDir_Env(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(EnvConfigDir, dir)
+	if got, err := DefaultDir(); err != nil || got != dir {
+		t.Errorf("DefaultDir() = %q, %v; want %q", got, err, dir)
+	}
+	if got, _ := DefaultPath(); got != filepath.Join(dir, fileName) {
+		t.Errorf("DefaultPath() = %q, want the config file in %s", got, dir)
+	}
+}
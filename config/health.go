@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HealthCheckSDKPing sends the copilot server a ping request over the
+// daemon's private socket. It is the only health check: the socket reaches
+// the daemon even while the server behind it is down, so only an answer
+// from the server says it is up.
+const HealthCheckSDKPing = "sdk-ping"
+
+// legacyHealthChecks are strategies from before the daemon served its
+// server on a private socket; they are read as sdk-ping
+var legacyHealthChecks = []string{"tcp", "http"}
+
+// HealthCheck is a parsed daemon.health_check value
+type HealthCheck struct {
+	Strategy string
+	// Legacy is the dropped strategy the value named, if any
+	Legacy string
+}
+
+// ParseHealthCheck parses "sdk-ping", the default when s is empty. The
+// dropped "tcp" and "http <path>" strategies are accepted, so older configs
+// still load, and mean sdk-ping.
+func ParseHealthCheck(s string) (HealthCheck, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return HealthCheck{Strategy: HealthCheckSDKPing}, nil
+	}
+
+	switch fields[0] {
+	case HealthCheckSDKPing:
+		if len(fields) > 1 {
+			return HealthCheck{}, fmt.Errorf("%q takes no arguments", fields[0])
+		}
+		return HealthCheck{Strategy: HealthCheckSDKPing}, nil
+	case legacyHealthChecks[0], legacyHealthChecks[1]:
+		return HealthCheck{Strategy: HealthCheckSDKPing, Legacy: strings.Join(fields, " ")}, nil
+	}
+	return HealthCheck{}, fmt.Errorf("unknown strategy %q (use sdk-ping)", fields[0])
+}
+
+// String formats the health check the way it is written in config
+func (h HealthCheck) String() string {
+	return h.Strategy
+}
@@ -0,0 +1,29 @@
+package config
+
+import "testing"
+
+func TestParseHealthCheck(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    HealthCheck
+		wantErr bool
+	}{
+		{"", HealthCheck{Strategy: HealthCheckSDKPing}, false},
+		{"sdk-ping", HealthCheck{Strategy: HealthCheckSDKPing}, false},
+		{"tcp", HealthCheck{Strategy: HealthCheckSDKPing, Legacy: "tcp"}, false},
+		{"  http   /ready  ", HealthCheck{Strategy: HealthCheckSDKPing, Legacy: "http /ready"}, false},
+		{"sdk-ping now", HealthCheck{}, true},
+		{"udp", HealthCheck{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseHealthCheck(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHealthCheck(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseHealthCheck(%q) = %+v, want %+v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ProjectFile is the per-project config file, looked for in the working
+// directory and its parents
+const ProjectFile = ".cocli.json"
+
+// Project is a project config file merged over the user's config
+type Project struct {
+	Path string
+	// Sets names the keys it sets, such as "model" or "mcp_servers"
+	Sets []string
+}
+
+// projectSchema is the part of schema a project config may set. Settings
+// such as shell.allow or daemon.remote stay with the user, so a cloned
+// repository can't run commands unasked or send prompts elsewhere.
+var projectSchema = &field{kind: kindObject, fields: map[string]*field{
+	"model":         schema.fields["model"],
+	"system_prompt": schema.fields["system_prompt"],
+	"attachments":   schema.fields["attachments"],
+	"mcp_servers":   schema.fields["mcp_servers"],
+}}
+
+// FindProject returns the path of the ProjectFile in dir or the nearest of
+// its parents, or "" when there is none
+func FindProject(dir string) string {
+	for {
+		path := filepath.Join(dir, ProjectFile)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// LoadProject reads and validates the project config at path. Only the keys
+// in projectSchema are accepted.
+func LoadProject(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project config: %w", err)
+	}
+	if err := validate(path, data, projectSchema); err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if err := checkMCPServers(cfg.MCPServers); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// IsProjectFile reports whether path names a project config rather than
+// the user's config
+func IsProjectFile(path string) bool {
+	return filepath.Base(path) == ProjectFile
+}
+
+// MergeProject merges project, read from path, over cfg: its model replaces
+// cfg's, its system prompt and attachment patterns are added to cfg's, and
+// its MCP servers are added, replacing any of the same name
+func (cfg *Config) MergeProject(path string, project *Config) {
+	p := &Project{Path: path}
+	if project.Model != "" {
+		cfg.Model = project.Model
+		p.Sets = append(p.Sets, "model")
+	}
+	if project.SystemPrompt != "" {
+		if cfg.SystemPrompt != "" {
+			cfg.SystemPrompt += "\n\n"
+		}
+		cfg.SystemPrompt += project.SystemPrompt
+		p.Sets = append(p.Sets, "system_prompt")
+	}
+	if len(project.Attachments.Allow) > 0 {
+		cfg.Attachments.Allow = append(cfg.Attachments.Allow, project.Attachments.Allow...)
+		p.Sets = append(p.Sets, "attachments.allow")
+	}
+	if len(project.MCPServers) > 0 {
+		if cfg.MCPServers == nil {
+			cfg.MCPServers = map[string]MCPServer{}
+		}
+		for name, server := range project.MCPServers {
+			cfg.MCPServers[name] = server
+		}
+		p.Sets = append(p.Sets, "mcp_servers")
+	}
+	cfg.Project = p
+}
+
+// checkMCPServers checks that each server has what it is started or
+// reached with
+func checkMCPServers(servers map[string]MCPServer) error {
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		server := servers[name]
+		switch {
+		case server.Remote() && server.URL == "":
+			return fmt.Errorf("mcp_servers.%s: type %q needs a url", name, server.Type)
+		case !server.Remote() && server.Command == "":
+			return fmt.Errorf("mcp_servers.%s: a local server needs a command", name)
+		}
+	}
+	return nil
+}
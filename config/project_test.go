@@ -0,0 +1,98 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindProject(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "src", "pkg")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if got := FindProject(nested); got != "" {
+		t.Errorf("FindProject() = %q with no project file, want \"\"", got)
+	}
+
+	path := filepath.Join(root, ProjectFile)
+	if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := FindProject(nested); got != path {
+		t.Errorf("FindProject() = %q, want %q found in a parent", got, path)
+	}
+}
+
+func TestLoadProject(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "project settings", content: `{"model": "gpt-4.1", "system_prompt": "Use tabs.", "attachments": {"allow": ["src/**"]}, "mcp_servers": {"docs": {"type": "http", "url": "https://example.com/mcp"}}}`},
+		{name: "user-only setting", content: `{"shell": {"allow": ["rm -rf"]}}`, wantErr: `unknown key "shell"`},
+		{name: "bad server type", content: `{"mcp_servers": {"docs": {"type": "ftp"}}}`, wantErr: "unknown MCP server type"},
+		{name: "server without a command", content: `{"mcp_servers": {"tools": {"args": ["serve"]}}}`, wantErr: "mcp_servers.tools: a local server needs a command"},
+		{name: "remote server without a url", content: `{"mcp_servers": {"docs": {"type": "sse"}}}`, wantErr: `mcp_servers.docs: type "sse" needs a url`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ProjectFile)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadProject(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("LoadProject() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadProject() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := LoadProject(filepath.Join(t.TempDir(), ProjectFile)); err == nil {
+		t.Error("LoadProject() of a missing file: want an error")
+	}
+}
+
+func TestMergeProject(t *testing.T) {
+	cfg := &Config{
+		Model:        "claude-sonnet-4.5",
+		SystemPrompt: "Be brief.",
+		Attachments:  AttachmentsConfig{Allow: []string{"*.md"}},
+		MCPServers:   map[string]MCPServer{"docs": {Command: "docs-mcp"}, "git": {Command: "git-mcp"}},
+		Shell:        ShellConfig{Allow: []string{"ls"}},
+	}
+	project := &Config{
+		Model:        "gpt-4.1",
+		SystemPrompt: "Use tabs.",
+		Attachments:  AttachmentsConfig{Allow: []string{"src/**"}},
+		MCPServers:   map[string]MCPServer{"docs": {Type: "http", URL: "https://example.com/mcp"}},
+	}
+	cfg.MergeProject("/repo/.cocli.json", project)
+
+	if cfg.Model != "gpt-4.1" || cfg.SystemPrompt != "Be brief.\n\nUse tabs." {
+		t.Errorf("model/system prompt = %q/%q, want the project's model and both prompts", cfg.Model, cfg.SystemPrompt)
+	}
+	if !reflect.DeepEqual(cfg.Attachments.Allow, []string{"*.md", "src/**"}) {
+		t.Errorf("attachments.allow = %v, want both lists", cfg.Attachments.Allow)
+	}
+	if len(cfg.MCPServers) != 2 || cfg.MCPServers["docs"].URL == "" || cfg.MCPServers["git"].Command != "git-mcp" {
+		t.Errorf("mcp_servers = %+v, want the project's docs server and the user's git server", cfg.MCPServers)
+	}
+	if !reflect.DeepEqual(cfg.Shell.Allow, []string{"ls"}) {
+		t.Errorf("shell.allow = %v, want it untouched", cfg.Shell.Allow)
+	}
+	want := &Project{Path: "/repo/.cocli.json", Sets: []string{"model", "system_prompt", "attachments.allow", "mcp_servers"}}
+	if !reflect.DeepEqual(cfg.Project, want) {
+		t.Errorf("Project = %+v, want %+v", cfg.Project, want)
+	}
+}
@@ -0,0 +1,196 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"atulm/cocli/storage"
+)
+
+// SaveModel sets the top-level "model" key of the config file at path,
+// leaving the rest of the file as written. A missing file is created.
+func SaveModel(path, model string) error {
+	return update(path, func(data []byte) ([]byte, error) {
+		return setTopLevelString(data, "model", model)
+	})
+}
+
+// Get returns the value of key, a dotted path such as
+// "session.retry_attempts", as written in the config file at path. ok is
+// false when the file doesn't set it.
+func Get(path, key string) (value json.RawMessage, ok bool, err error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read config: %w", err)
+	}
+	value = data
+	for _, k := range strings.Split(key, ".") {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(value, &object); err != nil {
+			return nil, false, nil
+		}
+		if value, ok = object[k]; !ok {
+			return nil, false, nil
+		}
+	}
+	return value, true, nil
+}
+
+// Set sets key, a dotted path such as "daemon.port", to value in the config
+// file at path, leaving the rest of the file as written. value is JSON, or
+// a string when it isn't valid JSON. The result is validated before it is
+// written, so a bad key or value leaves the file unchanged. Credentials, such
+// as "daemon.token" and MCP servers' env vars and headers, go to the secret
+// store and the file gets a reference.
+func Set(path, key, value string) error {
+	encoded := []byte(value)
+	if !json.Valid(encoded) {
+		var err error
+		if encoded, err = json.Marshal(value); err != nil {
+			return err
+		}
+	}
+	var plain string
+	if secretKey.MatchString(key) && json.Unmarshal(encoded, &plain) == nil {
+		ref, err := storeSecret(path, key, plain)
+		if err != nil {
+			return err
+		}
+		if encoded, err = json.Marshal(ref); err != nil {
+			return err
+		}
+	}
+	keys := strings.Split(key, ".")
+	for _, k := range keys {
+		if k == "" {
+			return fmt.Errorf("invalid key %q", key)
+		}
+	}
+	return update(path, func(data []byte) ([]byte, error) {
+		updated, err := setPath(data, keys, encoded, 0)
+		if err != nil {
+			return nil, err
+		}
+		if err := Validate(path, updated); err != nil {
+			return nil, err
+		}
+		return updated, nil
+	})
+}
+
+// update rewrites the config file at path with change, under its lock and
+// keeping its permissions. A missing file is created.
+func update(path string, change func(data []byte) ([]byte, error)) error {
+	return storage.WithLock(path, func() error {
+		perm := os.FileMode(0644)
+		data, err := os.ReadFile(path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			data = nil
+		case err != nil:
+			return fmt.Errorf("failed to read config: %w", err)
+		default:
+			if info, err := os.Stat(path); err == nil {
+				perm = info.Mode().Perm()
+			}
+		}
+
+		updated, err := change(data)
+		if err != nil {
+			var invalid *ValidationError
+			if errors.As(err, &invalid) {
+				return err
+			}
+			return fmt.Errorf("failed to update %s: %w", path, err)
+		}
+		return storage.WriteFileAtomic(path, updated, perm)
+	})
+}
+
+// setTopLevelString sets key to value in the JSON object data (see setPath)
+func setTopLevelString(data []byte, key, value string) ([]byte, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return setPath(data, []string{key}, encoded, 0)
+}
+
+// setPath sets the member at keys, a path through nested objects, to the
+// JSON value in the object data. An existing value is replaced in place and
+// a new member goes at the top of its object, so the file's formatting and
+// key order survive. Missing objects on the way are added; empty data
+// becomes a new object. depth is how deeply data is nested, for indenting.
+func setPath(data []byte, keys []string, value []byte, depth int) ([]byte, error) {
+	indent := strings.Repeat("  ", depth+1)
+	if len(bytes.TrimSpace(data)) == 0 {
+		return []byte(fmt.Sprintf("{\n%s%q: %s\n}\n", indent, keys[0], nested(keys[1:], value))), nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("config is not a JSON object")
+	}
+	open := int(dec.InputOffset())
+	empty := true
+	for dec.More() {
+		empty = false
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+		if tok != keys[0] {
+			continue
+		}
+		// The value ends where the decoder stopped; it starts len(raw)
+		// bytes before that
+		end := int(dec.InputOffset())
+		start := end - len(raw)
+		replacement := value
+		if len(keys) > 1 {
+			if !bytes.HasPrefix(raw, []byte("{")) {
+				return nil, fmt.Errorf("%q is not an object", keys[0])
+			}
+			if replacement, err = setPath(raw, keys[1:], value, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		var out bytes.Buffer
+		out.Write(data[:start])
+		out.Write(replacement)
+		out.Write(data[end:])
+		return out.Bytes(), nil
+	}
+
+	// The new member goes first; an existing first member keeps its line
+	rest := data[open:]
+	member := fmt.Sprintf("\n%s%q: %s,", indent, keys[0], nested(keys[1:], value))
+	if empty {
+		member = fmt.Sprintf("\n%s%q: %s\n%s", indent, keys[0], nested(keys[1:], value), indent[2:])
+		rest = bytes.TrimLeft(rest, " \t\r\n")
+	}
+	var out bytes.Buffer
+	out.Write(data[:open])
+	out.WriteString(member)
+	out.Write(rest)
+	return out.Bytes(), nil
+}
+
+// nested wraps value in an object for each of keys
+func nested(keys []string, value []byte) []byte {
+	if len(keys) == 0 {
+		return value
+	}
+	return []byte(fmt.Sprintf("{%q: %s}", keys[0], nested(keys[1:], value)))
+}
@@ -0,0 +1,210 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"atulm/cocli/secrets"
+)
+
+func TestSetTopLevelString(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{name: "empty file", data: "", want: "{\n  \"model\": \"gpt-5\"\n}\n"},
+		{name: "empty object", data: "{}\n", want: "{\n  \"model\": \"gpt-5\"\n}\n"},
+		{
+			name: "replaces in place",
+			data: "{\n  \"daemon\": {\"port\": 4500},\n  \"model\":   \"gpt-4.1\",\n  \"renderer\": {}\n}\n",
+			want: "{\n  \"daemon\": {\"port\": 4500},\n  \"model\":   \"gpt-5\",\n  \"renderer\": {}\n}\n",
+		},
+		{
+			name: "nested model key untouched",
+			data: "{\n  \"x\": {\"model\": \"a\"}\n}",
+			want: "{\n  \"model\": \"gpt-5\",\n  \"x\": {\"model\": \"a\"}\n}",
+		},
+		{name: "not an object", data: "[1]", wantErr: true},
+		{name: "bad json", data: "{\"model\": ", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := setTopLevelString([]byte(tt.data), "model", "gpt-5")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("setTopLevelString() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if string(got) != tt.want {
+				t.Errorf("setTopLevelString() = %q, want %q", got, tt.want)
+			}
+			if !json.Valid(got) {
+				t.Errorf("result %q is not valid JSON", got)
+			}
+		})
+	}
+}
+
+func TestSaveModel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "config.json")
+	if err := SaveModel(path, "gpt-4.1"); err != nil {
+		t.Fatalf("SaveModel() error = %v", err)
+	}
+	if err := os.WriteFile(path, []byte("{\n  \"model\": \"gpt-4.1\",\n  \"renderer\": {\"style\": \"light\"}\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveModel(path, "claude-opus-4.5"); err != nil {
+		t.Fatalf("SaveModel() error = %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Model != "claude-opus-4.5" || cfg.Renderer.Style != "light" {
+		t.Errorf("config = %+v, want the new model and the rest kept", cfg)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want the file's 0600 kept", info.Mode().Perm())
+	}
+}
+
+func TestSetPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "replaces nested value in place",
+			data: "{\n  \"daemon\": {\n    \"port\": 4500\n  }\n}\n",
+			key:  "daemon.port",
+			want: "{\n  \"daemon\": {\n    \"port\": 5\n  }\n}\n",
+		},
+		{
+			name: "adds to existing object",
+			data: "{\n  \"daemon\": {\n    \"port\": 4500\n  }\n}\n",
+			key:  "daemon.token",
+			want: "{\n  \"daemon\": {\n    \"token\": 5,\n    \"port\": 4500\n  }\n}\n",
+		},
+		{
+			name: "adds to empty object",
+			data: "{\n  \"daemon\": {}\n}\n",
+			key:  "daemon.port",
+			want: "{\n  \"daemon\": {\n    \"port\": 5\n  }\n}\n",
+		},
+		{name: "adds missing objects", data: "", key: "session.retry_attempts", want: "{\n  \"session\": {\"retry_attempts\": 5}\n}\n"},
+		{name: "through a value", data: "{\"model\": \"gpt-5\"}", key: "model.name", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := setPath([]byte(tt.data), strings.Split(tt.key, "."), []byte("5"), 0)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("setPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && string(got) != tt.want {
+				t.Errorf("setPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetAndSet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := Set(path, "model", "gpt-4.1"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := Set(path, "session.retry_attempts", "5"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	if value, ok, err := Get(path, "model"); err != nil || !ok || string(value) != `"gpt-4.1"` {
+		t.Errorf("Get(model) = %s, %v, %v", value, ok, err)
+	}
+	if value, ok, err := Get(path, "session.retry_attempts"); err != nil || !ok || string(value) != "5" {
+		t.Errorf("Get(session.retry_attempts) = %s, %v, %v", value, ok, err)
+	}
+	if _, ok, err := Get(path, "daemon.port"); err != nil || ok {
+		t.Errorf("Get(daemon.port) = %v, %v, want it unset", ok, err)
+	}
+
+	before, _ := os.ReadFile(path)
+	for _, kv := range [][2]string{{"session.retry_attempts", "50"}, {"colour", "red"}} {
+		var invalid *ValidationError
+		if err := Set(path, kv[0], kv[1]); !errors.As(err, &invalid) {
+			t.Errorf("Set(%s, %s) error = %v, want a validation error", kv[0], kv[1], err)
+		}
+	}
+	if after, _ := os.ReadFile(path); string(after) != string(before) {
+		t.Errorf("config = %q after invalid sets, want it unchanged", after)
+	}
+}
+
+func TestSetSecret(t *testing.T) {
+	dir := t.TempDir()
+	store := secrets.NewFileStore(filepath.Join(dir, "secrets"))
+	secretStore = func(string) secrets.SecretStore { return store }
+	t.Cleanup(func() { secretStore = secrets.Durable })
+
+	path := filepath.Join(dir, "config.json")
+	if err := Set(path, "daemon.token", "s3cret"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), "s3cret") {
+		t.Errorf("config = %s, want the token kept out of it", data)
+	}
+	cfg, err := Load(path)
+	if err != nil || cfg.Daemon.Token != "s3cret" {
+		t.Fatalf("Load() = %+v, %v; want the stored token", cfg, err)
+	}
+
+	if err := Set(path, "mcp_servers.github", `{"type": "http", "url": "https://api.githubcopilot.com/mcp/"}`); err != nil {
+		t.Fatal(err)
+	}
+	key := "mcp_servers.github.headers.Authorization"
+	if err := Set(path, key, "Bearer ghp_x"); err != nil {
+		t.Fatalf("Set(%s) error = %v", key, err)
+	}
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), "ghp_x") {
+		t.Errorf("config = %s, want the header kept out of it", data)
+	}
+	if cfg, err := Load(path); err != nil || cfg.MCPServers["github"].Headers["Authorization"] != "Bearer ghp_x" {
+		t.Errorf("Load() = %+v, %v; want the stored header", cfg, err)
+	}
+
+	// A reference only resolves at its own key; elsewhere it drops its
+	// MCP server, not the whole config
+	if err := Set(path, "mcp_servers.github.headers.X-Token", SecretPrefix+secretName("daemon.token")); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = Load(path)
+	if err != nil || cfg.Daemon.Token != "s3cret" {
+		t.Fatalf("Load() = %+v, %v; want the rest of the config", cfg, err)
+	}
+	if _, ok := cfg.MCPServers["github"]; ok {
+		t.Error("Load() kept an MCP server with a misplaced secret")
+	}
+	if err := Set(path, "mcp_servers.github.headers.X-Token", "plain"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A missing secret, e.g. on a machine the config was copied to, drops
+	// the token and leaves the rest
+	store.Delete(secretName("daemon.token"))
+	cfg, err = Load(path)
+	if err != nil || cfg.Daemon.Token != "" || cfg.MCPServers["github"].Headers["X-Token"] != "plain" {
+		t.Errorf("Load() = %+v, %v; want the config without the token", cfg, err)
+	}
+}
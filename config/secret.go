@@ -0,0 +1,97 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"atulm/cocli/secrets"
+)
+
+// SecretPrefix marks a config value kept in the secret store: the file
+// holds "secret:<name>" and the value is stored under name
+const SecretPrefix = "secret:"
+
+// secretStore opens the secret store for a config directory; replaced in
+// tests
+var secretStore = secrets.Durable
+
+// secretKey matches the config keys whose values are credentials: the
+// daemon token and MCP servers' env vars and headers
+var secretKey = regexp.MustCompile(`^(daemon\.token|mcp_servers\.[^.]+\.(env|headers)\.[^.]+)$`)
+
+// unsafeSecretName matches what a secret store name can't contain
+var unsafeSecretName = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// secretName is the secret store name for a config key
+func secretName(key string) string {
+	return "config-" + unsafeSecretName.ReplaceAllString(strings.ReplaceAll(key, ".", "-"), "_")
+}
+
+// storeSecret stores value, for the credential key, in the secret store of
+// the user's config file at path and returns the reference to write in its
+// place. Other keys and values, and project configs, which are shared, are
+// returned as given.
+func storeSecret(path, key, value string) (string, error) {
+	if !secretKey.MatchString(key) || value == "" || strings.HasPrefix(value, SecretPrefix) || IsProjectFile(path) {
+		return value, nil
+	}
+	name := secretName(key)
+	if err := secretStore(filepath.Dir(path)).Set(name, value); err != nil {
+		return "", fmt.Errorf("failed to store %s: %w", key, err)
+	}
+	return SecretPrefix + name, nil
+}
+
+// resolveSecrets replaces the secret references in cfg, loaded from the
+// user's config file at path, with the stored values. A reference is only
+// honoured at the key it was written for. One that can't be resolved is
+// reported and drops what it belongs to, the daemon token or an MCP server,
+// rather than the whole config.
+func (cfg *Config) resolveSecrets(path string) {
+	var store secrets.SecretStore
+	resolve := func(key string, value *string) bool {
+		name, ok := strings.CutPrefix(*value, SecretPrefix)
+		if !ok {
+			return true
+		}
+		if name != secretName(key) {
+			slog.Warn("ignoring a secret that belongs to another setting", "config", path, "key", key, "secret", name)
+			return false
+		}
+		if store == nil {
+			store = secretStore(filepath.Dir(path))
+		}
+		stored, err := store.Get(name)
+		if err != nil {
+			slog.Warn("secret can't be read", "config", path, "key", key, "err", err, "fix", "cocli config set "+key+" <value>")
+			return false
+		}
+		*value = stored
+		return true
+	}
+
+	if !resolve("daemon.token", &cfg.Daemon.Token) {
+		cfg.Daemon.Token = ""
+	}
+	for server, s := range cfg.MCPServers {
+		ok := true
+		for _, values := range []struct {
+			field string
+			m     map[string]string
+		}{{"env", s.Env}, {"headers", s.Headers}} {
+			for k, v := range values.m {
+				if !resolve("mcp_servers."+server+"."+values.field+"."+k, &v) {
+					ok = false
+				}
+				values.m[k] = v
+			}
+		}
+		if !ok {
+			slog.Warn("MCP server disabled: one of its secrets is missing", "server", server)
+			delete(cfg.MCPServers, server)
+		}
+	}
+}
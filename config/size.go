@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// sizeUnits are the suffixes ParseSize accepts, largest first so "MB" isn't
+// read as "B"
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// ParseSize parses a disk size such as "200MB", "1.5GB" or "4096" (bytes).
+// Units are powers of 1024 and case insensitive; "" is 0.
+func ParseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	number, unit := s, int64(1)
+	upper := strings.ToUpper(s)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(upper, u.suffix) {
+			number, unit = strings.TrimSpace(s[:len(s)-len(u.suffix)]), u.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n < 0 || math.IsNaN(n) || math.IsInf(n, 0) {
+		return 0, fmt.Errorf("invalid size %q (use a number of bytes or a size like \"200MB\" or \"1GB\")", s)
+	}
+	return int64(n * float64(unit)), nil
+}
+
+// FormatSize formats a size in bytes the way ParseSize reads it, such as
+// "1.5MB" or "512B"
+func FormatSize(n int64) string {
+	for _, u := range sizeUnits {
+		if n >= u.bytes && u.bytes > 1 {
+			value := strconv.FormatFloat(float64(n)/float64(u.bytes), 'f', 1, 64)
+			return strings.TrimSuffix(value, ".0") + u.suffix
+		}
+	}
+	return strconv.FormatInt(n, 10) + "B"
+}
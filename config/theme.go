@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	chroma "github.com/alecthomas/chroma/v2/styles"
+	"github.com/charmbracelet/glamour/styles"
+)
+
+// AutoStyle picks the dark or light style to match the terminal's
+// background, and notty when output isn't a terminal
+const AutoStyle = styles.AutoStyle
+
+// StyleNames returns the built-in renderer styles, including AutoStyle
+func StyleNames() []string {
+	names := []string{AutoStyle}
+	for name := range styles.DefaultStyles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CheckStyle reports whether style is a built-in renderer style or the path
+// of a JSON style file
+func CheckStyle(style string) error {
+	if strings.HasSuffix(style, ".json") {
+		return nil
+	}
+	for _, name := range StyleNames() {
+		if style == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown style %q (want one of %s, or the path of a .json style file)", style, strings.Join(StyleNames(), ", "))
+}
+
+// DefaultCodeStyle keeps the code colors of the renderer style
+const DefaultCodeStyle = "default"
+
+// CodeStyleNames returns the syntax highlighting styles for code blocks,
+// including DefaultCodeStyle
+func CodeStyleNames() []string {
+	return append([]string{DefaultCodeStyle}, chroma.Names()...)
+}
+
+// CheckCodeStyle reports whether name is a syntax highlighting style
+func CheckCodeStyle(name string) error {
+	if name == DefaultCodeStyle {
+		return nil
+	}
+	if _, ok := chroma.Registry[name]; !ok {
+		return fmt.Errorf("unknown code style %q (see /theme for the list, e.g. monokai, github)", name)
+	}
+	return nil
+}
+
+// CalloutKinds are the GitHub-style callouts ("> [!NOTE]") that can be
+// themed under renderer.callouts
+var CalloutKinds = []string{"note", "tip", "important", "warning", "caution"}
+
+// CalloutTheme overrides how one kind of callout is drawn
+type CalloutTheme struct {
+	// Icon is shown before the callout title
+	Icon string `json:"icon,omitempty"`
+	// Color is an ANSI 256-color index ("75") or a hex color ("#5fafff")
+	Color string `json:"color,omitempty"`
+}
+
+// ParseColor converts a theme color, either an ANSI 256-color index or a
+// "#rrggbb" hex color, into an ANSI foreground escape sequence
+func ParseColor(s string) (string, error) {
+	if hex, ok := strings.CutPrefix(s, "#"); ok {
+		if len(hex) != 6 {
+			return "", fmt.Errorf("invalid color %q (use #rrggbb)", s)
+		}
+		rgb, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return "", fmt.Errorf("invalid color %q (use #rrggbb)", s)
+		}
+		return fmt.Sprintf("\x1b[38;2;%d;%d;%dm", rgb>>16, rgb>>8&0xff, rgb&0xff), nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n > 255 {
+		return "", fmt.Errorf("invalid color %q (use a 256-color index 0-255 or #rrggbb)", s)
+	}
+	return fmt.Sprintf("\x1b[38;5;%dm", n), nil
+}
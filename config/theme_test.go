@@ -0,0 +1,67 @@
+package config
+
+import "testing"
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "75", want: "\x1b[38;5;75m"},
+		{in: "0", want: "\x1b[38;5;0m"},
+		{in: "#5fafff", want: "\x1b[38;2;95;175;255m"},
+		{in: "#FF0000", want: "\x1b[38;2;255;0;0m"},
+		{in: "256", wantErr: true},
+		{in: "-1", wantErr: true},
+		{in: "blue", wantErr: true},
+		{in: "#fff", wantErr: true},
+		{in: "#gggggg", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseColor(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseColor(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseColor(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCheckStyle(t *testing.T) {
+	tests := []struct {
+		in      string
+		wantErr bool
+	}{
+		{in: "dark"},
+		{in: "light"},
+		{in: "notty"},
+		{in: "auto"},
+		{in: "dracula"},
+		{in: "~/.cocli/style.json"},
+		{in: "neon", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := CheckStyle(tt.in); (err != nil) != tt.wantErr {
+			t.Errorf("CheckStyle(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCheckCodeStyle(t *testing.T) {
+	for _, name := range []string{"default", "monokai", "github"} {
+		if err := CheckCodeStyle(name); err != nil {
+			t.Errorf("CheckCodeStyle(%q) error = %v", name, err)
+		}
+	}
+	if err := CheckCodeStyle("nope"); err == nil {
+		t.Error("CheckCodeStyle(nope) should fail")
+	}
+	if names := CodeStyleNames(); len(names) < 2 || names[0] != DefaultCodeStyle {
+		t.Errorf("CodeStyleNames() = %v, want default first", names)
+	}
+}
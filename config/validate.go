@@ -0,0 +1,515 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"atulm/cocli/logging"
+	"atulm/cocli/promptline"
+)
+
+// kind is the expected JSON type of a config field
+type kind int
+
+const (
+	kindObject kind = iota
+	kindString
+	kindInt
+	kindBool
+	kindDuration
+	kindGlobList
+	kindStringList
+	kindIntList
+)
+
+func (k kind) String() string {
+	switch k {
+	case kindObject:
+		return "an object"
+	case kindString:
+		return "a string"
+	case kindInt:
+		return "an integer"
+	case kindBool:
+		return "true or false"
+	case kindDuration:
+		return `a duration string like "30s" or "7d"`
+	case kindGlobList:
+		return "a list of glob patterns"
+	case kindStringList:
+		return "a list of strings"
+	case kindIntList:
+		return "a list of integers"
+	}
+	return "unknown"
+}
+
+// field describes one key in the config schema
+type field struct {
+	kind     kind
+	min, max int // inclusive range for kindInt and kindIntList elements; ignored when both are 0
+	fields   map[string]*field
+	// values, when set on a kindObject, accepts any key and validates each
+	// member against it instead of fields
+	values *field
+	// check validates a kindString value beyond its type
+	check func(string) error
+}
+
+// schema describes every key accepted in config.json
+var schema = &field{kind: kindObject, fields: map[string]*field{
+	"model":            {kind: kindString},
+	"model_aliases":    {kind: kindObject, values: &field{kind: kindString, check: checkAliasTarget}},
+	"models_cache_ttl": {kind: kindDuration},
+	"prompt":           {kind: kindString, check: promptline.Check},
+	"system_prompt":    {kind: kindString},
+	"mcp_servers": {kind: kindObject, values: &field{kind: kindObject, fields: map[string]*field{
+		"type":    {kind: kindString, check: checkMCPType},
+		"command": {kind: kindString},
+		"args":    {kind: kindStringList},
+		"env":     {kind: kindObject, values: &field{kind: kindString}},
+		"cwd":     {kind: kindString},
+		"url":     {kind: kindString},
+		"headers": {kind: kindObject, values: &field{kind: kindString}},
+		"tools":   {kind: kindStringList},
+		"timeout": {kind: kindInt, min: 0, max: 3600000},
+	}}},
+	"daemon": {kind: kindObject, fields: map[string]*field{
+		"port":          {kind: kindInt, min: 1, max: 65535},
+		"start_timeout": {kind: kindDuration},
+		"health_check": {kind: kindString, check: func(s string) error {
+			_, err := ParseHealthCheck(s)
+			return err
+		}},
+		"max_restarts":   {kind: kindInt, min: 0, max: 100},
+		"restart_window": {kind: kindDuration},
+		"remote":         {kind: kindString, check: CheckServerAddr},
+		"token":          {kind: kindString},
+		"tls": {kind: kindObject, fields: map[string]*field{
+			"enabled":              {kind: kindBool},
+			"ca_file":              {kind: kindString},
+			"cert_file":            {kind: kindString},
+			"key_file":             {kind: kindString},
+			"insecure_skip_verify": {kind: kindBool},
+			"listen":               {kind: kindString, check: checkListenAddr},
+		}},
+	}},
+	"renderer": {kind: kindObject, fields: map[string]*field{
+		"style":      {kind: kindString, check: CheckStyle},
+		"word_wrap":  {kind: kindInt, min: 20, max: 1000},
+		"code_style": {kind: kindString, check: CheckCodeStyle},
+		"copy_code":  {kind: kindBool},
+		"live":       {kind: kindBool},
+		"callouts":   calloutsSchema(),
+	}},
+	"attachments": {kind: kindObject, fields: map[string]*field{
+		"allow": {kind: kindGlobList},
+	}},
+	"shell": {kind: kindObject, fields: map[string]*field{
+		"allow":      {kind: kindStringList},
+		"max_tokens": {kind: kindInt, min: 1, max: 1000000},
+		"timeout":    {kind: kindDuration},
+	}},
+	"session": {kind: kindObject, fields: map[string]*field{
+		"stall_timeout":   {kind: kindDuration},
+		"request_timeout": {kind: kindDuration},
+		"retry_attempts":  {kind: kindInt, min: 0, max: 10},
+		"retry_delay":     {kind: kindDuration},
+		"max_turns":       {kind: kindInt, min: 0, max: 100000},
+		"max_age":         {kind: kindDuration},
+		"max_archives":    {kind: kindInt, min: 1, max: 100000},
+		"tools":           {kind: kindString, check: CheckToolMode},
+	}},
+	"history": {kind: kindObject, fields: map[string]*field{
+		"max_sessions": {kind: kindInt, min: 0, max: 1000000},
+		"max_age":      {kind: kindDuration},
+		"max_size": {kind: kindString, check: func(s string) error {
+			_, err := ParseSize(s)
+			return err
+		}},
+	}},
+	"context": {kind: kindObject, fields: map[string]*field{
+		"warn_at":         {kind: kindIntList, min: 1, max: 100},
+		"auto_compact_at": {kind: kindInt, min: 0, max: 100},
+		"summarize_below": {kind: kindInt, min: 0, max: 10000000},
+	}},
+	"notify": {kind: kindObject, fields: map[string]*field{
+		"webhook":      {kind: kindString, check: checkWebhook},
+		"command":      {kind: kindString},
+		"min_duration": {kind: kindDuration},
+	}},
+	"log": {kind: kindObject, fields: map[string]*field{
+		"level": {kind: kindString, check: func(s string) error {
+			_, err := logging.ParseLevel(s)
+			return err
+		}},
+		"format": {kind: kindString, check: logging.CheckFormat},
+		"file":   {kind: kindString},
+	}},
+}}
+
+// checkMCPType accepts the kinds of MCP server the copilot CLI can use
+func checkMCPType(s string) error {
+	switch s {
+	case "local", "stdio", "http", "sse":
+		return nil
+	}
+	return fmt.Errorf("unknown MCP server type %q (expected local, stdio, http or sse)", s)
+}
+
+// checkAliasTarget rejects model aliases that name no model
+func checkAliasTarget(s string) error {
+	if strings.TrimSpace(s) == "" {
+		return fmt.Errorf("alias must name a model")
+	}
+	return nil
+}
+
+// calloutsSchema accepts an icon and color for each callout kind
+func calloutsSchema() *field {
+	f := &field{kind: kindObject, fields: map[string]*field{}}
+	for _, k := range CalloutKinds {
+		f.fields[k] = &field{kind: kindObject, fields: map[string]*field{
+			"icon": {kind: kindString},
+			"color": {kind: kindString, check: func(s string) error {
+				_, err := ParseColor(s)
+				return err
+			}},
+		}}
+	}
+	return f
+}
+
+// Issue is a single problem found in a config file
+type Issue struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+// ValidationError lists every problem found in a config file
+type ValidationError struct {
+	File   string
+	Issues []Issue
+}
+
+// Error formats the issues one per line as file:line:col: message
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Issues)+1)
+	lines = append(lines, fmt.Sprintf("invalid config %s:", e.File))
+	for _, issue := range e.Issues {
+		lines = append(lines, fmt.Sprintf("  %s:%d:%d: %s", e.File, issue.Line, issue.Column, issue.Message))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Validate checks raw config JSON against the schema and returns a
+// *ValidationError describing every problem, or nil if the config is valid
+func Validate(file string, data []byte) error {
+	return validate(file, data, schema)
+}
+
+// validate checks raw config JSON against s (see Validate)
+func validate(file string, data []byte, s *field) error {
+	v := &validator{data: data, dec: json.NewDecoder(strings.NewReader(string(data)))}
+	v.dec.UseNumber()
+
+	if err := v.value("", s); err != nil {
+		v.syntaxError(err)
+	} else if _, err := v.dec.Token(); err != io.EOF {
+		v.issue(int(v.dec.InputOffset()), "unexpected content after the top-level object")
+	}
+
+	if len(v.issues) > 0 {
+		return &ValidationError{File: file, Issues: v.issues}
+	}
+	return nil
+}
+
+// validator walks the JSON token stream, checking each value against the
+// schema while tracking source positions
+type validator struct {
+	data   []byte
+	dec    *json.Decoder
+	issues []Issue
+}
+
+// issue records a problem at a byte offset
+func (v *validator) issue(offset int, format string, args ...interface{}) {
+	line, col := position(v.data, offset)
+	v.issues = append(v.issues, Issue{Line: line, Column: col, Message: fmt.Sprintf(format, args...)})
+}
+
+// syntaxError records a JSON parse error
+func (v *validator) syntaxError(err error) {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		v.issue(int(syntaxErr.Offset), "invalid JSON: %v", err)
+		return
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		v.issue(len(v.data), "invalid JSON: unexpected end of file")
+		return
+	}
+	v.issue(int(v.dec.InputOffset()), "invalid JSON: %v", err)
+}
+
+// nextOffset returns the offset of the next token, skipping whitespace and
+// the separators the decoder has not consumed yet
+func (v *validator) nextOffset() int {
+	offset := int(v.dec.InputOffset())
+	for offset < len(v.data) && strings.IndexByte(" \t\r\n,:", v.data[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+// value validates the next JSON value at path against f
+func (v *validator) value(path string, f *field) error {
+	name := path
+	if name == "" {
+		name = "config"
+	}
+	start := v.nextOffset()
+	tok, err := v.dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		if t == '{' {
+			if f.kind != kindObject {
+				v.issue(start, "%s: expected %s, got an object", name, f.kind)
+				return v.skipOpened()
+			}
+			return v.object(path, f)
+		}
+		switch f.kind {
+		case kindGlobList:
+			return v.globList(path)
+		case kindStringList:
+			return v.stringList(path)
+		case kindIntList:
+			return v.intList(path, f)
+		}
+		v.issue(start, "%s: expected %s, got a list", name, f.kind)
+		return v.skipOpened()
+	case nil:
+		// null leaves the built-in default in place
+		return nil
+	case string:
+		switch f.kind {
+		case kindString:
+			if f.check != nil {
+				if err := f.check(t); err != nil {
+					v.issue(start, "%s: %v", name, err)
+				}
+			}
+		case kindDuration:
+			if _, err := parseDuration(t); err != nil {
+				v.issue(start, "%s: invalid duration %q (use a value like \"30s\", \"2m\" or \"7d\")", name, t)
+			}
+		default:
+			v.issue(start, "%s: expected %s, got a string", name, f.kind)
+		}
+	case json.Number:
+		if f.kind != kindInt {
+			v.issue(start, "%s: expected %s, got a number", name, f.kind)
+			return nil
+		}
+		v.integer(start, name, t, f)
+	case bool:
+		if f.kind != kindBool {
+			v.issue(start, "%s: expected %s, got a boolean", name, f.kind)
+		}
+	}
+	return nil
+}
+
+// object validates the members of an object whose '{' has been consumed
+func (v *validator) object(path string, f *field) error {
+	for v.dec.More() {
+		keyStart := v.nextOffset()
+		tok, err := v.dec.Token()
+		if err != nil {
+			return err
+		}
+		key := tok.(string)
+		child := key
+		if path != "" {
+			child = path + "." + key
+		}
+
+		childField, ok := f.fields[key]
+		if f.values != nil {
+			childField, ok = f.values, true
+		}
+		if !ok {
+			v.issue(keyStart, "unknown key %q%s", child, knownKeys(f))
+			if err := v.skipValue(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := v.value(child, childField); err != nil {
+			return err
+		}
+	}
+	_, err := v.dec.Token() // closing '}'
+	return err
+}
+
+// globList validates a list of glob patterns whose '[' has been consumed
+func (v *validator) globList(path string) error {
+	for i := 0; v.dec.More(); i++ {
+		start := v.nextOffset()
+		tok, err := v.dec.Token()
+		if err != nil {
+			return err
+		}
+		elem := fmt.Sprintf("%s[%d]", path, i)
+		switch t := tok.(type) {
+		case string:
+			if _, err := filepath.Match(t, ""); err != nil {
+				v.issue(start, "%s: bad glob pattern %q: %v", elem, t, err)
+			}
+		case json.Delim:
+			v.issue(start, "%s: expected a glob pattern string", elem)
+			if err := v.skipOpened(); err != nil {
+				return err
+			}
+		default:
+			v.issue(start, "%s: expected a glob pattern string", elem)
+		}
+	}
+	_, err := v.dec.Token() // closing ']'
+	return err
+}
+
+// stringList validates a list of strings whose '[' has been consumed
+func (v *validator) stringList(path string) error {
+	for i := 0; v.dec.More(); i++ {
+		start := v.nextOffset()
+		tok, err := v.dec.Token()
+		if err != nil {
+			return err
+		}
+		elem := fmt.Sprintf("%s[%d]", path, i)
+		switch tok.(type) {
+		case string:
+		case json.Delim:
+			v.issue(start, "%s: expected a string", elem)
+			if err := v.skipOpened(); err != nil {
+				return err
+			}
+		default:
+			v.issue(start, "%s: expected a string", elem)
+		}
+	}
+	_, err := v.dec.Token() // closing ']'
+	return err
+}
+
+// intList validates a list of integers whose '[' has been consumed, applying
+// f's range to each element
+func (v *validator) intList(path string, f *field) error {
+	for i := 0; v.dec.More(); i++ {
+		start := v.nextOffset()
+		tok, err := v.dec.Token()
+		if err != nil {
+			return err
+		}
+		elem := fmt.Sprintf("%s[%d]", path, i)
+		switch t := tok.(type) {
+		case json.Number:
+			v.integer(start, elem, t, f)
+		case json.Delim:
+			v.issue(start, "%s: expected an integer", elem)
+			if err := v.skipOpened(); err != nil {
+				return err
+			}
+		default:
+			v.issue(start, "%s: expected an integer", elem)
+		}
+	}
+	_, err := v.dec.Token() // closing ']'
+	return err
+}
+
+// integer checks that a number is an integer within f's range
+func (v *validator) integer(start int, name string, t json.Number, f *field) {
+	n, err := t.Int64()
+	if err != nil {
+		v.issue(start, "%s: expected an integer, got %s", name, t)
+		return
+	}
+	if (f.min != 0 || f.max != 0) && (n < int64(f.min) || n > int64(f.max)) {
+		v.issue(start, "%s: %d is out of range (%d-%d)", name, n, f.min, f.max)
+	}
+}
+
+// skipValue consumes the next value, whatever its type
+func (v *validator) skipValue() error {
+	tok, err := v.dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); ok && (d == '{' || d == '[') {
+		return v.skipOpened()
+	}
+	return nil
+}
+
+// skipOpened consumes tokens until the container that was just opened closes
+func (v *validator) skipOpened() error {
+	for depth := 1; depth > 0; {
+		tok, err := v.dec.Token()
+		if err != nil {
+			return err
+		}
+		if d, ok := tok.(json.Delim); ok {
+			if d == '{' || d == '[' {
+				depth++
+			} else {
+				depth--
+			}
+		}
+	}
+	return nil
+}
+
+// knownKeys lists the keys accepted in an object, for unknown-key messages
+func knownKeys(f *field) string {
+	keys := make([]string, 0, len(f.fields))
+	for key := range f.fields {
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+	sort.Strings(keys)
+	return " (expected one of: " + strings.Join(keys, ", ") + ")"
+}
+
+// position converts a byte offset into a 1-based line and column
+func position(data []byte, offset int) (line, col int) {
+	if offset > len(data) {
+		offset = len(data)
+	}
+	line, col = 1, 1
+	for _, b := range data[:offset] {
+		if b == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+	}
+	return line, col
+}
@@ -0,0 +1,253 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidate_Valid(t *testing.T) {
+	data := `{
+  "model": "claude-sonnet-4.5",
+  "prompt": "{model_short} {tokens_pct}%> ",
+  "model_aliases": {"fast": "claude-haiku-4.5", "smart": "Claude Opus 4.5"},
+  "daemon": {"port": 4321, "start_timeout": "45s", "remote": "devbox:4443", "tls": {"enabled": true, "ca_file": "~/.cocli/ca.pem", "listen": ":4443"}},
+  "renderer": {"style": "dark", "word_wrap": 100, "copy_code": true, "callouts": {"warning": {"icon": "!", "color": "#ffaf00"}}},
+  "attachments": {"allow": ["*.go", "docs/**"]},
+  "shell": {"allow": ["git log", "git status"], "max_tokens": 1000, "timeout": "10s"},
+  "session": {"retry_attempts": 5, "retry_delay": "2s", "max_turns": 200, "max_age": "7d", "max_archives": 50, "tools": "auto"},
+  "history": {"max_sessions": 500, "max_age": "90d", "max_size": "200MB"},
+  "notify": {"webhook": "https://hooks.example.com/cocli", "command": "notify-send cocli", "min_duration": "2m"},
+  "log": {"level": "debug", "format": "json", "file": "~/.cocli/cocli.log"}
+}`
+	if err := Validate("config.json", []byte(data)); err != nil {
+		t.Errorf("Validate() error = %v, want nil", err)
+	}
+}
+
+func TestValidate_Empty(t *testing.T) {
+	if err := Validate("config.json", []byte("{}")); err != nil {
+		t.Errorf("Validate() error = %v, want nil", err)
+	}
+}
+
+func TestValidate_Issues(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		wantLine int
+		wantCol  int
+		wantMsg  string
+	}{
+		{
+			name:     "unknown key",
+			data:     "{\n  \"modle\": \"x\"\n}",
+			wantLine: 2, wantCol: 3,
+			wantMsg: `unknown key "modle"`,
+		},
+		{
+			name:     "unknown nested key",
+			data:     "{\n  \"daemon\": {\n    \"prot\": 1\n  }\n}",
+			wantLine: 3, wantCol: 5,
+			wantMsg: `unknown key "daemon.prot" (expected one of: health_check, max_restarts, port, remote, restart_window, start_timeout, tls, token)`,
+		},
+		{
+			name:     "wrong type",
+			data:     "{\n  \"daemon\": {\"port\": \"4321\"}\n}",
+			wantLine: 2, wantCol: 22,
+			wantMsg: "daemon.port: expected an integer, got a string",
+		},
+		{
+			name:     "bad TLS listen address",
+			data:     "{\"daemon\": {\"tls\": {\"listen\": \"4443\"}}}",
+			wantLine: 1, wantCol: 31,
+			wantMsg: `daemon.tls.listen: invalid listen address "4443"`,
+		},
+		{
+			name:     "port out of range",
+			data:     "{\"daemon\": {\"port\": 70000}}",
+			wantLine: 1, wantCol: 21,
+			wantMsg: "daemon.port: 70000 is out of range (1-65535)",
+		},
+		{
+			name:     "non-integer port",
+			data:     "{\"daemon\": {\"port\": 43.5}}",
+			wantLine: 1, wantCol: 21,
+			wantMsg: "daemon.port: expected an integer, got 43.5",
+		},
+		{
+			name:     "bad duration",
+			data:     "{\"daemon\": {\"start_timeout\": \"soon\"}}",
+			wantLine: 1, wantCol: 30,
+			wantMsg: `daemon.start_timeout: invalid duration "soon"`,
+		},
+		{
+			name:     "bad day duration",
+			data:     "{\"session\": {\"max_age\": \"1.5d\"}}",
+			wantLine: 1, wantCol: 25,
+			wantMsg: `session.max_age: invalid duration "1.5d"`,
+		},
+		{
+			name:     "bad webhook",
+			data:     "{\"notify\": {\"webhook\": \"hooks.example.com\"}}",
+			wantLine: 1, wantCol: 24,
+			wantMsg: `notify.webhook: invalid webhook URL "hooks.example.com" (want an http or https URL)`,
+		},
+		{
+			name:     "bad callout color",
+			data:     "{\"renderer\": {\"callouts\": {\"note\": {\"color\": \"blue\"}}}}",
+			wantLine: 1, wantCol: 46,
+			wantMsg: `renderer.callouts.note.color: invalid color "blue"`,
+		},
+		{
+			name:     "bad health check",
+			data:     "{\"daemon\": {\"health_check\": \"udp\"}}",
+			wantLine: 1, wantCol: 29,
+			wantMsg: `daemon.health_check: unknown strategy "udp"`,
+		},
+		{
+			name:     "bad history size",
+			data:     "{\"history\": {\"max_size\": \"lots\"}}",
+			wantLine: 1, wantCol: 26,
+			wantMsg: `history.max_size: invalid size "lots"`,
+		},
+		{
+			name:     "warn threshold out of range",
+			data:     "{\"context\": {\"warn_at\": [75, 120]}}",
+			wantLine: 1, wantCol: 30,
+			wantMsg: "context.warn_at[1]: 120 is out of range (1-100)",
+		},
+		{
+			name:     "bad glob",
+			data:     "{\"attachments\": {\"allow\": [\"*.go\", \"[abc\"]}}",
+			wantLine: 1, wantCol: 36,
+			wantMsg: `attachments.allow[1]: bad glob pattern "[abc"`,
+		},
+		{
+			name:     "shell allow not a string",
+			data:     "{\"shell\": {\"allow\": [\"git log\", 3]}}",
+			wantLine: 1, wantCol: 33,
+			wantMsg: "shell.allow[1]: expected a string",
+		},
+		{
+			name:     "unknown tool mode",
+			data:     "{\"session\": {\"tools\": \"always\"}}",
+			wantLine: 1, wantCol: 23,
+			wantMsg: `session.tools: unknown tool mode "always" (use auto, ask, off)`,
+		},
+		{
+			name:     "unknown style",
+			data:     "{\"renderer\": {\"style\": \"neon\"}}",
+			wantLine: 1, wantCol: 24,
+			wantMsg: `renderer.style: unknown style "neon"`,
+		},
+		{
+			name:     "alias not a string",
+			data:     "{\"model_aliases\": {\"fast\": 4}}",
+			wantLine: 1, wantCol: 28,
+			wantMsg: "model_aliases.fast: expected a string, got a number",
+		},
+		{
+			name:     "empty alias",
+			data:     "{\"model_aliases\": {\"fast\": \" \"}}",
+			wantLine: 1, wantCol: 28,
+			wantMsg: "model_aliases.fast: alias must name a model",
+		},
+		{
+			name:     "unknown prompt placeholder",
+			data:     "{\"prompt\": \"{branch} > \"}",
+			wantLine: 1, wantCol: 12,
+			wantMsg: "prompt: unknown placeholder {branch}",
+		},
+		{
+			name:     "unknown log format",
+			data:     "{\"log\": {\"format\": \"xml\"}}",
+			wantLine: 1, wantCol: 20,
+			wantMsg: `log.format: unknown log format "xml"`,
+		},
+		{
+			name:     "object where scalar expected",
+			data:     "{\"model\": {\"id\": \"x\"}}",
+			wantLine: 1, wantCol: 11,
+			wantMsg: "model: expected a string, got an object",
+		},
+		{
+			name:     "not an object",
+			data:     "[1, 2]",
+			wantLine: 1, wantCol: 1,
+			wantMsg: "config: expected an object, got a list",
+		},
+		{
+			name:     "syntax error",
+			data:     "{\n  \"model\": \"x\",\n}",
+			wantLine: 2, wantCol: 16,
+			wantMsg: "invalid JSON",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate("config.json", []byte(tt.data))
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Validate() error = %v, want *ValidationError", err)
+			}
+			if len(verr.Issues) == 0 {
+				t.Fatal("Validate() returned no issues")
+			}
+			issue := verr.Issues[0]
+			if !strings.Contains(issue.Message, tt.wantMsg) {
+				t.Errorf("Message = %q, want it to contain %q", issue.Message, tt.wantMsg)
+			}
+			if issue.Line != tt.wantLine || issue.Column != tt.wantCol {
+				t.Errorf("position = %d:%d, want %d:%d", issue.Line, issue.Column, tt.wantLine, tt.wantCol)
+			}
+		})
+	}
+}
+
+func TestValidate_ReportsAllIssues(t *testing.T) {
+	data := `{
+  "bogus": true,
+  "daemon": {"port": 0},
+  "renderer": {"word_wrap": "wide"}
+}`
+	err := Validate("config.json", []byte(data))
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Validate() error = %v, want *ValidationError", err)
+	}
+	if len(verr.Issues) != 3 {
+		t.Errorf("Issues = %d, want 3: %v", len(verr.Issues), verr.Issues)
+	}
+}
+
+func TestValidationError_Format(t *testing.T) {
+	err := &ValidationError{
+		File:   "/home/u/.cocli/config.json",
+		Issues: []Issue{{Line: 3, Column: 5, Message: "daemon.port: 0 is out of range (1-65535)"}},
+	}
+	want := "/home/u/.cocli/config.json:3:5: daemon.port: 0 is out of range (1-65535)"
+	if !strings.Contains(err.Error(), want) {
+		t.Errorf("Error() = %q, want it to contain %q", err.Error(), want)
+	}
+}
+
+func TestPosition(t *testing.T) {
+	data := []byte("ab\ncd\n")
+	tests := []struct {
+		offset, line, col int
+	}{
+		{0, 1, 1},
+		{1, 1, 2},
+		{3, 2, 1},
+		{4, 2, 2},
+		{100, 3, 1},
+	}
+	for _, tt := range tests {
+		line, col := position(data, tt.offset)
+		if line != tt.line || col != tt.col {
+			t.Errorf("position(%d) = %d:%d, want %d:%d", tt.offset, line, col, tt.line, tt.col)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package edits
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"atulm/cocli/storage"
+)
+
+// manifestFile lists the files a batch changed, in its backup directory
+const manifestFile = "manifest.json"
+
+// maxBatches is how many batches are kept for Undo; older backups are
+// removed
+const maxBatches = 50
+
+// ErrNothingToUndo is returned by Undo when no batch is left
+var ErrNothingToUndo = errors.New("no applied edits to undo")
+
+// Change is the new state of one file
+type Change struct {
+	// Path is the file to change
+	Path string
+	// Content is the file's new contents, unless Delete is set
+	Content string
+	Delete  bool
+}
+
+// Journal writes batches of changes to the working tree, keeping a backup
+// of every file a batch touches so the latest batch can be undone
+type Journal struct {
+	dir string
+}
+
+// backupEntry records how to restore one file
+type backupEntry struct {
+	// Path is the file's absolute path
+	Path string `json:"path"`
+	// Backup is the backup copy's name in the batch directory, or "" if the
+	// file didn't exist before
+	Backup string      `json:"backup,omitempty"`
+	Mode   os.FileMode `json:"mode,omitempty"`
+}
+
+// NewJournal keeps backups under dir, one directory per batch
+func NewJournal(dir string) *Journal {
+	return &Journal{dir: dir}
+}
+
+// Write backs up the files changes touch, then applies the changes. If a
+// change fails, the files already changed are restored.
+func (j *Journal) Write(changes []Change) (err error) {
+	batch := filepath.Join(j.dir, strconv.FormatInt(time.Now().UnixNano(), 10))
+	if err := os.MkdirAll(batch, 0700); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = os.RemoveAll(batch)
+		}
+	}()
+
+	var entries []backupEntry
+	for i, c := range changes {
+		abs, err := filepath.Abs(c.Path)
+		if err != nil {
+			return err
+		}
+		entry := backupEntry{Path: abs}
+		if data, err := os.ReadFile(abs); err == nil {
+			info, err := os.Stat(abs)
+			if err != nil {
+				return err
+			}
+			entry.Backup, entry.Mode = strconv.Itoa(i), info.Mode().Perm()
+			if err := os.WriteFile(filepath.Join(batch, entry.Backup), data, 0600); err != nil {
+				return fmt.Errorf("failed to back up %s: %w", c.Path, err)
+			}
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		entries = append(entries, entry)
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(batch, manifestFile), data, 0600); err != nil {
+		return fmt.Errorf("failed to write backup manifest: %w", err)
+	}
+
+	for i, c := range changes {
+		if err := apply(entries[i], c); err != nil {
+			// Put back what this batch already changed
+			_ = restore(batch, entries[:i+1])
+			return err
+		}
+	}
+	j.prune()
+	return nil
+}
+
+// prune removes the oldest batches beyond maxBatches
+func (j *Journal) prune() {
+	batches, err := j.batches()
+	if err != nil {
+		return
+	}
+	for len(batches) > maxBatches {
+		_ = os.RemoveAll(filepath.Join(j.dir, batches[0]))
+		batches = batches[1:]
+	}
+}
+
+// apply writes one change, keeping the file's permissions
+func apply(entry backupEntry, c Change) error {
+	if c.Delete {
+		if err := os.Remove(entry.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to delete %s: %w", c.Path, err)
+		}
+		return nil
+	}
+	mode := entry.Mode
+	if mode == 0 {
+		mode = 0644
+	}
+	if err := storage.WriteFileAtomic(entry.Path, []byte(c.Content), mode); err != nil {
+		return fmt.Errorf("failed to write %s: %w", c.Path, err)
+	}
+	return nil
+}
+
+// Undo restores the files changed by the latest batch and forgets it,
+// returning the restored paths
+func (j *Journal) Undo() ([]string, error) {
+	batches, err := j.batches()
+	if err != nil {
+		return nil, err
+	}
+	if len(batches) == 0 {
+		return nil, ErrNothingToUndo
+	}
+	batch := filepath.Join(j.dir, batches[len(batches)-1])
+	data, err := os.ReadFile(filepath.Join(batch, manifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup manifest: %w", err)
+	}
+	var entries []backupEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("invalid backup manifest %s: %w", batch, err)
+	}
+	if err := restore(batch, entries); err != nil {
+		return nil, err
+	}
+	paths := make([]string, len(entries))
+	for i, e := range entries {
+		paths[i] = e.Path
+	}
+	return paths, os.RemoveAll(batch)
+}
+
+// Pending returns how many batches can be undone
+func (j *Journal) Pending() int {
+	batches, _ := j.batches()
+	return len(batches)
+}
+
+// batches lists the batch directories, oldest first
+func (j *Journal) batches() ([]string, error) {
+	dirs, err := os.ReadDir(j.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, d := range dirs {
+		if _, err := strconv.ParseInt(d.Name(), 10, 64); d.IsDir() && err == nil {
+			names = append(names, d.Name())
+		}
+	}
+	sort.Slice(names, func(a, b int) bool {
+		x, _ := strconv.ParseInt(names[a], 10, 64)
+		y, _ := strconv.ParseInt(names[b], 10, 64)
+		return x < y
+	})
+	return names, nil
+}
+
+// restore puts each file back as it was before the batch
+func restore(batch string, entries []backupEntry) error {
+	var firstErr error
+	for _, e := range entries {
+		var err error
+		if e.Backup == "" {
+			if err = os.Remove(e.Path); errors.Is(err, os.ErrNotExist) {
+				err = nil
+			}
+		} else {
+			var data []byte
+			if data, err = os.ReadFile(filepath.Join(batch, e.Backup)); err == nil {
+				err = storage.WriteFileAtomic(e.Path, data, e.Mode)
+			}
+		}
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to restore %s: %w", e.Path, err)
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,70 @@
+package edits
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJournal_WriteAndUndo(t *testing.T) {
+	dir := t.TempDir()
+	edited := filepath.Join(dir, "a.txt")
+	removed := filepath.Join(dir, "b.txt")
+	created := filepath.Join(dir, "sub", "c.txt")
+	if err := os.WriteFile(edited, []byte("old a\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(removed, []byte("old b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	j := NewJournal(filepath.Join(dir, "backups"))
+	err := j.Write([]Change{
+		{Path: edited, Content: "new a\n"},
+		{Path: removed, Delete: true},
+		{Path: created, Content: "new c\n"},
+	})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	assertFile(t, edited, "new a\n")
+	assertFile(t, created, "new c\n")
+	if _, err := os.Stat(removed); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("%s still exists", removed)
+	}
+	if info, _ := os.Stat(edited); info.Mode().Perm() != 0755 {
+		t.Errorf("mode = %v, want 0755 kept", info.Mode().Perm())
+	}
+	if n := j.Pending(); n != 1 {
+		t.Errorf("Pending() = %d, want 1", n)
+	}
+
+	paths, err := j.Undo()
+	if err != nil {
+		t.Fatalf("Undo() error = %v", err)
+	}
+	if len(paths) != 3 {
+		t.Errorf("Undo() restored %q, want 3 files", paths)
+	}
+	assertFile(t, edited, "old a\n")
+	assertFile(t, removed, "old b\n")
+	if _, err := os.Stat(created); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("%s was created by the batch and should be gone", created)
+	}
+
+	if _, err := j.Undo(); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("Undo() error = %v, want ErrNothingToUndo", err)
+	}
+}
+
+func assertFile(t *testing.T, path, want string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile(%s) error = %v", path, err)
+	}
+	if string(data) != want {
+		t.Errorf("%s = %q, want %q", path, data, want)
+	}
+}
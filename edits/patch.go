@@ -0,0 +1,205 @@
+// Package edits parses the file edits a model proposes as unified diffs,
+// applies them to the working tree and keeps backups so they can be undone.
+package edits
+
+import (
+	"fmt"
+	"strings"
+)
+
+// devNull stands for a missing file in a diff header
+const devNull = "/dev/null"
+
+// FileEdit is the change a diff makes to one file
+type FileEdit struct {
+	// Path is the file the edit applies to
+	Path string
+	// Create and Delete are set when the diff adds or removes the file
+	Create bool
+	Delete bool
+	// Diff is the file's part of the diff, headers included
+	Diff  string
+	hunks []hunk
+}
+
+// hunk is one @@ section of a diff
+type hunk struct {
+	oldStart int // 1-based line the hunk starts at in the old file, 0 if unknown
+	lines    []string
+}
+
+// Parse splits a unified diff into the edits it makes to each file. Line
+// counts in hunk headers are ignored, since models often get them wrong.
+func Parse(diff string) ([]FileEdit, error) {
+	var edits []FileEdit
+	var cur *FileEdit
+	var diffText strings.Builder
+	lines := strings.Split(strings.TrimRight(diff, "\n"), "\n")
+	finish := func() {
+		if cur != nil {
+			cur.Diff = strings.TrimRight(diffText.String(), "\n")
+			edits = append(edits, *cur)
+			cur = nil
+		}
+		diffText.Reset()
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			finish()
+			oldPath, newPath := headerPath(line[4:]), headerPath(lines[i+1][4:])
+			cur = &FileEdit{Path: newPath, Create: oldPath == devNull, Delete: newPath == devNull}
+			if cur.Delete {
+				cur.Path = oldPath
+			}
+			if cur.Path == devNull || cur.Path == "" {
+				return nil, fmt.Errorf("diff header names no file: %q", line)
+			}
+			diffText.WriteString(line + "\n" + lines[i+1] + "\n")
+			i++
+		case cur == nil:
+			// Text before the first file header, such as "diff --git" lines
+		case strings.HasPrefix(line, "@@"):
+			cur.hunks = append(cur.hunks, hunk{oldStart: hunkStart(line)})
+			diffText.WriteString(line + "\n")
+		case len(cur.hunks) == 0:
+			// "index" and mode lines between the headers and the first hunk
+		case strings.HasPrefix(line, "diff --git "):
+			finish()
+		case strings.HasPrefix(line, `\`):
+			// "\ No newline at end of file"
+			diffText.WriteString(line + "\n")
+		default:
+			h := &cur.hunks[len(cur.hunks)-1]
+			if line == "" {
+				line = " " // blank context lines often lose their space
+			}
+			if c := line[0]; c != ' ' && c != '+' && c != '-' {
+				return nil, fmt.Errorf("%s: unexpected line in hunk: %q", cur.Path, line)
+			}
+			h.lines = append(h.lines, line)
+			diffText.WriteString(line + "\n")
+		}
+	}
+	finish()
+
+	if len(edits) == 0 {
+		return nil, fmt.Errorf("no file changes found in the diff")
+	}
+	for _, e := range edits {
+		if len(e.hunks) == 0 && !e.Delete {
+			return nil, fmt.Errorf("%s: the diff has no hunks", e.Path)
+		}
+	}
+	return edits, nil
+}
+
+// headerPath extracts the path from a ---/+++ header, dropping a trailing
+// timestamp and git's a/ and b/ prefixes
+func headerPath(s string) string {
+	s, _, _ = strings.Cut(s, "\t")
+	s = strings.TrimSpace(s)
+	if s == devNull {
+		return s
+	}
+	if rest, ok := strings.CutPrefix(s, "a/"); ok {
+		return rest
+	}
+	if rest, ok := strings.CutPrefix(s, "b/"); ok {
+		return rest
+	}
+	return s
+}
+
+// hunkStart reads the old start line from a "@@ -l,s +l,s @@" header
+func hunkStart(header string) int {
+	var start int
+	if _, err := fmt.Sscanf(header, "@@ -%d", &start); err != nil {
+		return 0
+	}
+	return start
+}
+
+// Apply returns content with the edit applied. Each hunk is looked for
+// near the line its header names, then anywhere after the previous hunk;
+// trailing whitespace is ignored if there is no exact match.
+func (e FileEdit) Apply(content string) (string, error) {
+	if e.Delete {
+		return "", nil
+	}
+	var lines []string
+	if content != "" {
+		lines = strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	}
+	endsWithNewline := content == "" || strings.HasSuffix(content, "\n")
+
+	next := 0 // hunks apply in order, so each starts after the previous one
+	for n, h := range e.hunks {
+		var old, repl []string
+		for _, l := range h.lines {
+			switch l[0] {
+			case ' ':
+				old = append(old, l[1:])
+				repl = append(repl, l[1:])
+			case '-':
+				old = append(old, l[1:])
+			case '+':
+				repl = append(repl, l[1:])
+			}
+		}
+		at := findLines(lines, old, next, h.oldStart-1)
+		if at < 0 {
+			return "", fmt.Errorf("%s: hunk %d does not match the file", e.Path, n+1)
+		}
+		lines = append(lines[:at], append(repl, lines[at+len(old):]...)...)
+		next = at + len(repl)
+	}
+
+	out := strings.Join(lines, "\n")
+	if endsWithNewline && len(lines) > 0 {
+		out += "\n"
+	}
+	return out, nil
+}
+
+// findLines returns where want occurs in lines at or after from, preferring
+// the occurrence closest to hint, or -1
+func findLines(lines, want []string, from, hint int) int {
+	if len(want) == 0 {
+		// A pure insertion goes where the header says
+		return min(max(hint+1, from), len(lines))
+	}
+	for _, match := range []func(a, b string) bool{
+		func(a, b string) bool { return a == b },
+		func(a, b string) bool { return strings.TrimRight(a, " \t\r") == strings.TrimRight(b, " \t\r") },
+	} {
+		best := -1
+		for i := from; i+len(want) <= len(lines); i++ {
+			if matchAt(lines, want, i, match) && (best < 0 || abs(i-hint) < abs(best-hint)) {
+				best = i
+			}
+		}
+		if best >= 0 {
+			return best
+		}
+	}
+	return -1
+}
+
+func matchAt(lines, want []string, at int, match func(a, b string) bool) bool {
+	for j, w := range want {
+		if !match(lines[at+j], w) {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,111 @@
+package edits
+
+import (
+	"strings"
+	"testing"
+)
+
+const sampleDiff = `Some text before.
+diff --git a/greet.go b/greet.go
+index 83db48f..bf269f4 100644
+--- a/greet.go
++++ b/greet.go
+@@ -1,5 +1,5 @@
+ package main
+
+ func greet() string {
+-	return "hello"
++	return "hello, world"
+ }
+--- /dev/null
++++ b/NOTES.md
+@@ -0,0 +1,2 @@
++# Notes
++Remember the milk.
+--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-gone`
+
+func TestParse(t *testing.T) {
+	edits, err := Parse(sampleDiff)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(edits) != 3 {
+		t.Fatalf("Parse() = %d edits, want 3", len(edits))
+	}
+	tests := []struct {
+		path           string
+		create, delete bool
+	}{
+		{"greet.go", false, false},
+		{"NOTES.md", true, false},
+		{"old.txt", false, true},
+	}
+	for i, tt := range tests {
+		e := edits[i]
+		if e.Path != tt.path || e.Create != tt.create || e.Delete != tt.delete {
+			t.Errorf("edit %d = %s create=%v delete=%v, want %s create=%v delete=%v", i, e.Path, e.Create, e.Delete, tt.path, tt.create, tt.delete)
+		}
+	}
+	if !strings.HasPrefix(edits[0].Diff, "--- a/greet.go\n+++ b/greet.go\n@@") || strings.Contains(edits[0].Diff, "NOTES") {
+		t.Errorf("edit 0 Diff = %q", edits[0].Diff)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, diff := range []string{
+		"no diff here",
+		"--- a/x\n+++ b/x\n",
+		"--- a/x\n+++ b/x\n@@ -1 +1 @@\n*bad\n",
+	} {
+		if _, err := Parse(diff); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", diff)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	edits, err := Parse(sampleDiff)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := "package main\n\nfunc greet() string {\n\treturn \"hello\"\n}\n"
+	got, err := edits[0].Apply(old)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if want := "package main\n\nfunc greet() string {\n\treturn \"hello, world\"\n}\n"; got != want {
+		t.Errorf("Apply() = %q, want %q", got, want)
+	}
+
+	// The hunk is found even when the file has moved on
+	moved := "// Copyright\n\n" + old
+	if got, err := edits[0].Apply(moved); err != nil || !strings.Contains(got, "hello, world") {
+		t.Errorf("Apply() on a shifted file = %q, %v", got, err)
+	}
+
+	if got, err := edits[1].Apply(""); err != nil || got != "# Notes\nRemember the milk.\n" {
+		t.Errorf("Apply() of a new file = %q, %v", got, err)
+	}
+
+	if _, err := edits[0].Apply("package other\n"); err == nil {
+		t.Error("Apply() to a file that doesn't match succeeded")
+	}
+}
+
+func TestApply_TrailingWhitespace(t *testing.T) {
+	edits, err := Parse("--- a/x.txt\n+++ b/x.txt\n@@ -2,2 +2,2 @@\n b\n-c\n+C\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := edits[0].Apply("a\nb  \nc\nd")
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if want := "a\nb\nC\nd"; got != want {
+		t.Errorf("Apply() = %q, want %q", got, want)
+	}
+}
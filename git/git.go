@@ -0,0 +1,88 @@
+// Package git reads local changes from the working tree so they can be
+// attached to prompts.
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ErrNotRepo is returned outside a git repository
+var ErrNotRepo = errors.New("not inside a git repository")
+
+// runGit runs the git command and returns its output; tests replace it
+var runGit = func(args ...string) ([]byte, error) {
+	out, err := exec.Command("git", args...).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+		msg := strings.TrimSpace(string(exitErr.Stderr))
+		if strings.Contains(msg, "not a git repository") {
+			return nil, ErrNotRepo
+		}
+		return nil, errors.New(msg)
+	}
+	return out, err
+}
+
+// Diff returns the working tree's unstaged changes, or with staged the
+// changes staged for the next commit, as a unified diff. It is empty when
+// there are none.
+func Diff(staged bool) (string, error) {
+	args := []string{"diff", "--no-color", "--no-ext-diff"}
+	if staged {
+		args = append(args, "--staged")
+	}
+	out, err := runGit(args...)
+	if err != nil {
+		if errors.Is(err, ErrNotRepo) {
+			return "", err
+		}
+		return "", fmt.Errorf("git diff failed: %w", err)
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
+
+// Branch returns the current branch, or "" outside a repository
+func Branch() string {
+	out, err := runGit("rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// DiffStat summarizes a unified diff
+type DiffStat struct {
+	Files, Added, Removed int
+}
+
+// Stat counts the files and lines a unified diff changes
+func Stat(diff string) DiffStat {
+	var s DiffStat
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			s.Files++
+		case strings.HasPrefix(line, "+++ "), strings.HasPrefix(line, "--- "):
+		case strings.HasPrefix(line, "+"):
+			s.Added++
+		case strings.HasPrefix(line, "-"):
+			s.Removed++
+		}
+	}
+	return s
+}
+
+func (s DiffStat) String() string {
+	return fmt.Sprintf("%s, +%d -%d", fileCount(s.Files), s.Added, s.Removed)
+}
+
+// fileCount returns "1 file" or "n files"
+func fileCount(n int) string {
+	if n == 1 {
+		return "1 file"
+	}
+	return fmt.Sprintf("%d files", n)
+}
@@ -0,0 +1,64 @@
+package git
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	orig := runGit
+	t.Cleanup(func() { runGit = orig })
+	var gotArgs []string
+	runGit = func(args ...string) ([]byte, error) {
+		gotArgs = args
+		return []byte("diff --git a/x b/x\n+y\n\n"), nil
+	}
+
+	got, err := Diff(true)
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	if got != "diff --git a/x b/x\n+y" {
+		t.Errorf("Diff() = %q, want trailing newlines trimmed", got)
+	}
+	want := []string{"diff", "--no-color", "--no-ext-diff", "--staged"}
+	if !reflect.DeepEqual(gotArgs, want) {
+		t.Errorf("git args = %q, want %q", gotArgs, want)
+	}
+
+	runGit = func(args ...string) ([]byte, error) { return nil, ErrNotRepo }
+	if _, err := Diff(false); !errors.Is(err, ErrNotRepo) {
+		t.Errorf("Diff() error = %v, want ErrNotRepo", err)
+	}
+	if got := Branch(); got != "" {
+		t.Errorf("Branch() = %q outside a repository, want empty", got)
+	}
+}
+
+func TestStat(t *testing.T) {
+	diff := `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,3 @@
+ package main
+-var x = 1
++var x = 2
++var y = 3
+diff --git a/README.md b/README.md
+--- a/README.md
++++ b/README.md
+@@ -1 +1 @@
+-old
++new`
+	got := Stat(diff)
+	if want := (DiffStat{Files: 2, Added: 3, Removed: 2}); got != want {
+		t.Errorf("Stat() = %+v, want %+v", got, want)
+	}
+	if s := got.String(); s != "2 files, +3 -2" {
+		t.Errorf("String() = %q", s)
+	}
+	if s := (DiffStat{Files: 1, Added: 1}).String(); s != "1 file, +1 -0" {
+		t.Errorf("String() = %q", s)
+	}
+}
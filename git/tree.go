@@ -0,0 +1,129 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// DefaultTreeLines caps the lines FileTree writes
+const DefaultTreeLines = 300
+
+// Root returns the top directory of the current repository
+func Root() (string, error) {
+	out, err := runGit("rev-parse", "--show-toplevel")
+	if err != nil {
+		if errors.Is(err, ErrNotRepo) {
+			return "", err
+		}
+		return "", fmt.Errorf("git rev-parse failed: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Files lists the files of the repository at root, tracked or not, leaving
+// out those ignored by .gitignore. Paths are relative to root and use
+// forward slashes.
+func Files(root string) ([]string, error) {
+	out, err := runGit("-C", root, "ls-files", "--cached", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, fmt.Errorf("git ls-files failed: %w", err)
+	}
+	var files []string
+	seen := map[string]bool{}
+	for _, line := range strings.Split(string(out), "\n") {
+		if line != "" && !seen[line] {
+			seen[line] = true
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// dirNode is a directory in a file tree
+type dirNode struct {
+	dirs  map[string]*dirNode
+	files []string
+	count int // files in this directory and below
+}
+
+func newDirNode() *dirNode {
+	return &dirNode{dirs: map[string]*dirNode{}}
+}
+
+// FileTree draws files as an indented tree, directories first, in at most
+// maxLines lines (DefaultTreeLines if 0). When the whole tree doesn't fit,
+// directories below the deepest level that does are shown with their file
+// count instead of their contents.
+func FileTree(files []string, maxLines int) string {
+	if maxLines <= 0 {
+		maxLines = DefaultTreeLines
+	}
+	root := newDirNode()
+	depth := 0
+	for _, f := range files {
+		parts := strings.Split(path.Clean(f), "/")
+		depth = max(depth, len(parts)-1)
+		node := root
+		node.count++
+		for _, dir := range parts[:len(parts)-1] {
+			child, ok := node.dirs[dir]
+			if !ok {
+				child = newDirNode()
+				node.dirs[dir] = child
+			}
+			child.count++
+			node = child
+		}
+		node.files = append(node.files, parts[len(parts)-1])
+	}
+
+	// Show as many levels as fit; below them, directories are summarized
+	levels := depth + 1
+	for levels > 1 && treeLines(root, levels) > maxLines {
+		levels--
+	}
+	var lines []string
+	drawTree(root, levels, "", &lines)
+	if len(lines) > maxLines {
+		more := len(lines) - maxLines
+		lines = append(lines[:maxLines], fmt.Sprintf("... (%d more)", more))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// treeLines counts the lines drawTree writes for node showing levels levels
+func treeLines(node *dirNode, levels int) int {
+	n := len(node.files) + len(node.dirs)
+	if levels > 1 {
+		for _, child := range node.dirs {
+			n += treeLines(child, levels-1)
+		}
+	}
+	return n
+}
+
+// drawTree appends node's contents to lines, levels levels deep
+func drawTree(node *dirNode, levels int, indent string, lines *[]string) {
+	names := make([]string, 0, len(node.dirs))
+	for name := range node.dirs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		child := node.dirs[name]
+		if levels <= 1 {
+			*lines = append(*lines, fmt.Sprintf("%s%s/ (%s)", indent, name, fileCount(child.count)))
+			continue
+		}
+		*lines = append(*lines, indent+name+"/")
+		drawTree(child, levels-1, indent+"  ", lines)
+	}
+	files := append([]string(nil), node.files...)
+	sort.Strings(files)
+	for _, f := range files {
+		*lines = append(*lines, indent+f)
+	}
+}
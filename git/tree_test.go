@@ -0,0 +1,59 @@
+package git
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFileTree(t *testing.T) {
+	files := []string{"main.go", "go.mod", "session/session.go", "session/blocks.go", "session/testdata/a.md", "git/git.go"}
+
+	want := `git/
+  git.go
+session/
+  testdata/
+    a.md
+  blocks.go
+  session.go
+go.mod
+main.go`
+	if got := FileTree(files, 0); got != want {
+		t.Errorf("FileTree() =\n%s\nwant\n%s", got, want)
+	}
+
+	// Deeper levels are summarized when the whole tree doesn't fit
+	want = `git/ (1 file)
+session/ (3 files)
+go.mod
+main.go`
+	if got := FileTree(files, 5); got != want {
+		t.Errorf("FileTree(5) =\n%s\nwant\n%s", got, want)
+	}
+
+	want = `a
+b
+... (2 more)`
+	if got := FileTree([]string{"a", "b", "c", "d"}, 2); got != want {
+		t.Errorf("FileTree(2) =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFiles(t *testing.T) {
+	orig := runGit
+	t.Cleanup(func() { runGit = orig })
+	var gotArgs []string
+	runGit = func(args ...string) ([]byte, error) {
+		gotArgs = args
+		return []byte("a.go\nb/c.go\na.go\n"), nil
+	}
+	got, err := Files("/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a.go", "b/c.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Files() = %q, want %q", got, want)
+	}
+	if want := []string{"-C", "/repo", "ls-files", "--cached", "--others", "--exclude-standard"}; !reflect.DeepEqual(gotArgs, want) {
+		t.Errorf("git args = %q, want %q", gotArgs, want)
+	}
+}
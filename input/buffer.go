@@ -0,0 +1,192 @@
+package input
+
+import (
+	"unicode"
+)
+
+// maxKillRingSize is how many killed strings the kill ring remembers
+const maxKillRingSize = 16
+
+// KillRing stores text removed by kill commands so it can be yanked back
+type KillRing struct {
+	entries []string
+	// index of the entry the next yank-pop rotates to
+	yankIndex int
+}
+
+// Kill records killed text. When merge is true the text is joined to the most
+// recent entry (consecutive kills accumulate, as in Emacs); prepend controls
+// which side it is joined on for backward kills.
+func (k *KillRing) Kill(text string, merge, prepend bool) {
+	if text == "" {
+		return
+	}
+	if merge && len(k.entries) > 0 {
+		last := len(k.entries) - 1
+		if prepend {
+			k.entries[last] = text + k.entries[last]
+		} else {
+			k.entries[last] += text
+		}
+	} else {
+		k.entries = append(k.entries, text)
+		if len(k.entries) > maxKillRingSize {
+			k.entries = k.entries[1:]
+		}
+	}
+	k.yankIndex = len(k.entries) - 1
+}
+
+// Yank returns the most recently killed text
+func (k *KillRing) Yank() (string, bool) {
+	if len(k.entries) == 0 {
+		return "", false
+	}
+	k.yankIndex = len(k.entries) - 1
+	return k.entries[k.yankIndex], true
+}
+
+// YankPop rotates to the previous kill and returns it
+func (k *KillRing) YankPop() (string, bool) {
+	if len(k.entries) == 0 {
+		return "", false
+	}
+	k.yankIndex--
+	if k.yankIndex < 0 {
+		k.yankIndex = len(k.entries) - 1
+	}
+	return k.entries[k.yankIndex], true
+}
+
+// lineBuffer holds the line being edited and the cursor position (in runes)
+type lineBuffer struct {
+	runes []rune
+	pos   int
+}
+
+// String returns the buffer contents
+func (b *lineBuffer) String() string {
+	return string(b.runes)
+}
+
+// set replaces the buffer contents and moves the cursor to the end
+func (b *lineBuffer) set(s string) {
+	b.runes = []rune(s)
+	b.pos = len(b.runes)
+}
+
+// insert inserts text at the cursor
+func (b *lineBuffer) insert(text []rune) {
+	tail := append([]rune(nil), b.runes[b.pos:]...)
+	b.runes = append(append(b.runes[:b.pos], text...), tail...)
+	b.pos += len(text)
+}
+
+// remove deletes runes in [from, to) and returns them
+func (b *lineBuffer) remove(from, to int) string {
+	removed := string(b.runes[from:to])
+	b.runes = append(b.runes[:from], b.runes[to:]...)
+	if b.pos > to {
+		b.pos -= to - from
+	} else if b.pos > from {
+		b.pos = from
+	}
+	return removed
+}
+
+// backspace deletes the rune before the cursor
+func (b *lineBuffer) backspace() {
+	if b.pos > 0 {
+		b.remove(b.pos-1, b.pos)
+	}
+}
+
+// deleteChar deletes the rune under the cursor
+func (b *lineBuffer) deleteChar() {
+	if b.pos < len(b.runes) {
+		b.remove(b.pos, b.pos+1)
+	}
+}
+
+func (b *lineBuffer) left() {
+	if b.pos > 0 {
+		b.pos--
+	}
+}
+
+func (b *lineBuffer) right() {
+	if b.pos < len(b.runes) {
+		b.pos++
+	}
+}
+
+func (b *lineBuffer) home() { b.pos = 0 }
+
+func (b *lineBuffer) end() { b.pos = len(b.runes) }
+
+// isWordRune reports whether r is part of a word for Alt-style word motions
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// wordStartBefore returns the start of the word before the cursor, where
+// words are runs of runes for which inWord is true
+func (b *lineBuffer) wordStartBefore(inWord func(rune) bool) int {
+	i := b.pos
+	for i > 0 && !inWord(b.runes[i-1]) {
+		i--
+	}
+	for i > 0 && inWord(b.runes[i-1]) {
+		i--
+	}
+	return i
+}
+
+// wordEndAfter returns the end of the word after the cursor
+func (b *lineBuffer) wordEndAfter(inWord func(rune) bool) int {
+	i := b.pos
+	for i < len(b.runes) && !inWord(b.runes[i]) {
+		i++
+	}
+	for i < len(b.runes) && inWord(b.runes[i]) {
+		i++
+	}
+	return i
+}
+
+// wordLeft moves the cursor to the start of the previous word (Alt+B)
+func (b *lineBuffer) wordLeft() {
+	b.pos = b.wordStartBefore(isWordRune)
+}
+
+// wordRight moves the cursor to the end of the next word (Alt+F)
+func (b *lineBuffer) wordRight() {
+	b.pos = b.wordEndAfter(isWordRune)
+}
+
+// killToStart removes text from the start of the line to the cursor (Ctrl+U)
+func (b *lineBuffer) killToStart() string {
+	return b.remove(0, b.pos)
+}
+
+// killToEnd removes text from the cursor to the end of the line (Ctrl+K)
+func (b *lineBuffer) killToEnd() string {
+	return b.remove(b.pos, len(b.runes))
+}
+
+// killWhitespaceWordBack removes the whitespace-delimited word before the
+// cursor (Ctrl+W, like unix-word-rubout)
+func (b *lineBuffer) killWhitespaceWordBack() string {
+	start := b.wordStartBefore(func(r rune) bool { return !unicode.IsSpace(r) })
+	return b.remove(start, b.pos)
+}
+
+// killWordBack removes the word before the cursor (Alt+Backspace)
+func (b *lineBuffer) killWordBack() string {
+	return b.remove(b.wordStartBefore(isWordRune), b.pos)
+}
+
+// killWordForward removes the word after the cursor (Alt+D)
+func (b *lineBuffer) killWordForward() string {
+	return b.remove(b.pos, b.wordEndAfter(isWordRune))
+}
@@ -0,0 +1,160 @@
+package input
+
+import (
+	"testing"
+)
+
+// newBuffer creates a buffer holding s with the cursor at pos
+func newBuffer(s string, pos int) *lineBuffer {
+	b := &lineBuffer{}
+	b.set(s)
+	b.pos = pos
+	return b
+}
+
+func TestLineBuffer_Insert(t *testing.T) {
+	b := newBuffer("hello world", 5)
+	b.insert([]rune(","))
+
+	if got := b.String(); got != "hello, world" {
+		t.Errorf("String() = %q, want %q", got, "hello, world")
+	}
+	if b.pos != 6 {
+		t.Errorf("pos = %d, want 6", b.pos)
+	}
+}
+
+func TestLineBuffer_BackspaceDelete(t *testing.T) {
+	b := newBuffer("abc", 1)
+	b.backspace()
+	if b.String() != "bc" || b.pos != 0 {
+		t.Errorf("backspace: got %q pos %d, want %q pos 0", b.String(), b.pos, "bc")
+	}
+
+	b.backspace() // at start: no-op
+	if b.String() != "bc" {
+		t.Errorf("backspace at start changed buffer to %q", b.String())
+	}
+
+	b.deleteChar()
+	if b.String() != "c" || b.pos != 0 {
+		t.Errorf("deleteChar: got %q pos %d, want %q pos 0", b.String(), b.pos, "c")
+	}
+}
+
+func TestLineBuffer_WordMotion(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		pos   int
+		move  func(b *lineBuffer)
+		wantP int
+	}{
+		{name: "left from end", text: "foo bar", pos: 7, move: (*lineBuffer).wordLeft, wantP: 4},
+		{name: "left skips punctuation", text: "foo.bar()", pos: 9, move: (*lineBuffer).wordLeft, wantP: 4},
+		{name: "left at start", text: "foo", pos: 0, move: (*lineBuffer).wordLeft, wantP: 0},
+		{name: "right from start", text: "foo bar", pos: 0, move: (*lineBuffer).wordRight, wantP: 3},
+		{name: "right from space", text: "foo bar", pos: 3, move: (*lineBuffer).wordRight, wantP: 7},
+		{name: "right at end", text: "foo", pos: 3, move: (*lineBuffer).wordRight, wantP: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBuffer(tt.text, tt.pos)
+			tt.move(b)
+			if b.pos != tt.wantP {
+				t.Errorf("pos = %d, want %d", b.pos, tt.wantP)
+			}
+		})
+	}
+}
+
+func TestLineBuffer_Kills(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		pos        int
+		kill       func(b *lineBuffer) string
+		wantKilled string
+		wantText   string
+		wantPos    int
+	}{
+		{name: "ctrl+u", text: "hello world", pos: 6, kill: (*lineBuffer).killToStart, wantKilled: "hello ", wantText: "world", wantPos: 0},
+		{name: "ctrl+k", text: "hello world", pos: 5, kill: (*lineBuffer).killToEnd, wantKilled: " world", wantText: "hello", wantPos: 5},
+		{name: "ctrl+w", text: "git diff HEAD~1", pos: 15, kill: (*lineBuffer).killWhitespaceWordBack, wantKilled: "HEAD~1", wantText: "git diff ", wantPos: 9},
+		{name: "ctrl+w trailing space", text: "foo bar  ", pos: 9, kill: (*lineBuffer).killWhitespaceWordBack, wantKilled: "bar  ", wantText: "foo ", wantPos: 4},
+		{name: "alt+backspace", text: "path/to/file", pos: 12, kill: (*lineBuffer).killWordBack, wantKilled: "file", wantText: "path/to/", wantPos: 8},
+		{name: "alt+d", text: "foo bar baz", pos: 3, kill: (*lineBuffer).killWordForward, wantKilled: " bar", wantText: "foo baz", wantPos: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBuffer(tt.text, tt.pos)
+			killed := tt.kill(b)
+			if killed != tt.wantKilled {
+				t.Errorf("killed = %q, want %q", killed, tt.wantKilled)
+			}
+			if b.String() != tt.wantText {
+				t.Errorf("text = %q, want %q", b.String(), tt.wantText)
+			}
+			if b.pos != tt.wantPos {
+				t.Errorf("pos = %d, want %d", b.pos, tt.wantPos)
+			}
+		})
+	}
+}
+
+func TestLineBuffer_Unicode(t *testing.T) {
+	b := newBuffer("héllo wörld", 11)
+	b.wordLeft()
+	if b.pos != 6 {
+		t.Errorf("wordLeft pos = %d, want 6", b.pos)
+	}
+	if killed := b.killToStart(); killed != "héllo " {
+		t.Errorf("killToStart = %q, want %q", killed, "héllo ")
+	}
+}
+
+func TestKillRing(t *testing.T) {
+	var k KillRing
+
+	if _, ok := k.Yank(); ok {
+		t.Error("Yank() on empty ring should report false")
+	}
+
+	k.Kill("one", false, false)
+	k.Kill("two", false, false)
+	if got, _ := k.Yank(); got != "two" {
+		t.Errorf("Yank() = %q, want %q", got, "two")
+	}
+	if got, _ := k.YankPop(); got != "one" {
+		t.Errorf("YankPop() = %q, want %q", got, "one")
+	}
+	if got, _ := k.YankPop(); got != "two" {
+		t.Errorf("YankPop() should wrap around, got %q", got)
+	}
+}
+
+func TestKillRing_Merge(t *testing.T) {
+	var k KillRing
+	k.Kill("world", false, true)
+	k.Kill("hello ", true, true)
+	k.Kill("!", true, false)
+
+	if got, _ := k.Yank(); got != "hello world!" {
+		t.Errorf("Yank() = %q, want merged %q", got, "hello world!")
+	}
+	if len(k.entries) != 1 {
+		t.Errorf("entries = %d, want 1", len(k.entries))
+	}
+}
+
+func TestKillRing_Bounded(t *testing.T) {
+	var k KillRing
+	for i := 0; i < maxKillRingSize+5; i++ {
+		k.Kill("x", false, false)
+	}
+	if len(k.entries) != maxKillRingSize {
+		t.Errorf("entries = %d, want %d", len(k.entries), maxKillRingSize)
+	}
+}
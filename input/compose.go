@@ -0,0 +1,60 @@
+package input
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// EditorCommand returns the user's editor command: $VISUAL, then $EDITOR,
+// falling back to notepad on Windows and vi elsewhere. The value may carry
+// arguments, e.g. "code --wait".
+func EditorCommand() []string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(name)); len(fields) > 0 {
+			return fields
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return []string{"notepad"}
+	}
+	return []string{"vi"}
+}
+
+// Compose opens the user's editor on a temporary Markdown file seeded with
+// initial, waits for the editor to exit and returns what was saved, with
+// surrounding whitespace trimmed. The editor takes over the terminal, so
+// this must not be called while ReadLine is running.
+func Compose(initial string) (string, error) {
+	f, err := os.CreateTemp("", "cocli-prompt-*.md")
+	if err != nil {
+		return "", err
+	}
+	path := f.Name()
+	defer os.Remove(path)
+
+	if initial != "" {
+		_, err = f.WriteString(initial + "\n")
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	args := EditorCommand()
+	cmd := exec.Command(args[0], append(args[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %s: %w", args[0], err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,62 @@
+package input
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestEditorCommand(t *testing.T) {
+	fallback := []string{"vi"}
+	if runtime.GOOS == "windows" {
+		fallback = []string{"notepad"}
+	}
+	tests := []struct {
+		name   string
+		visual string
+		editor string
+		want   []string
+	}{
+		{name: "visual first", visual: "code --wait", editor: "nano", want: []string{"code", "--wait"}},
+		{name: "editor", editor: "nano", want: []string{"nano"}},
+		{name: "blank values ignored", visual: "  ", want: fallback},
+		{name: "fallback", want: fallback},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("VISUAL", tt.visual)
+			t.Setenv("EDITOR", tt.editor)
+			if got := EditorCommand(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("EditorCommand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompose(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the editor")
+	}
+	// The "editor" appends a line to the file it is given
+	script := filepath.Join(t.TempDir(), "editor.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nprintf 'second line\\n\\n' >> \"$1\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", script)
+
+	got, err := Compose("first line")
+	if err != nil {
+		t.Fatalf("Compose() error = %v", err)
+	}
+	if want := "first line\nsecond line"; got != want {
+		t.Errorf("Compose() = %q, want %q", got, want)
+	}
+
+	t.Setenv("EDITOR", "false")
+	if _, err := Compose(""); err == nil {
+		t.Error("Compose() with a failing editor: want an error")
+	}
+}
@@ -0,0 +1,331 @@
+package input
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+	"golang.org/x/term"
+)
+
+// defaultWidth is used when the terminal width cannot be determined
+const defaultWidth = 80
+
+// ErrInterrupted is returned by ReadLine when the user presses Ctrl+C
+var ErrInterrupted = errors.New("interrupted")
+
+// ansiRegex matches ANSI escape sequences, which take no space on screen
+var ansiRegex = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+// Editor reads lines of input with Emacs-style editing when attached to a
+// terminal, and falls back to plain line reading otherwise.
+//
+// Supported keys: Left/Right, Home/End, Ctrl+A/E/B/F, Alt+B/F (word
+// motion), Up/Down and Ctrl+P/N (history), Ctrl+U/K/W and Alt+Backspace/Alt+D
+// (kill), Ctrl+Y/Alt+Y (yank, yank-pop), Ctrl+L (clear), Tab (complete,
+// see SetCompleter), Ctrl+D (EOF on an empty line) and Ctrl+C (interrupt).
+type Editor struct {
+	in         *os.File
+	reader     *bufio.Reader
+	out        io.Writer
+	isTerminal bool
+	killRing   KillRing
+	history    History
+
+	// completer offers completions for Tab; nil leaves Tab unbound
+	completer Completer
+
+	// cursorRow is the screen row of the cursor relative to the prompt's first row
+	cursorRow int
+}
+
+// NewEditor creates an editor reading from in and echoing to out
+func NewEditor(in *os.File, out io.Writer) *Editor {
+	return &Editor{
+		in:         in,
+		reader:     bufio.NewReader(in),
+		out:        out,
+		isTerminal: term.IsTerminal(int(in.Fd())),
+	}
+}
+
+// Completer returns the candidates for the last word of line, the text
+// before the cursor. Each candidate replaces that word.
+type Completer func(line string) []string
+
+// SetCompleter makes Tab complete the word before the cursor with c
+func (e *Editor) SetCompleter(c Completer) {
+	e.completer = c
+}
+
+// IsTerminal reports whether the editor is attached to an interactive terminal
+func (e *Editor) IsTerminal() bool {
+	return e.isTerminal
+}
+
+// AddHistory records line so Up/Down can recall it in later reads
+func (e *Editor) AddHistory(line string) {
+	e.history.Add(line)
+}
+
+// ReadLine displays prompt and reads a line of input without the trailing
+// newline. It returns io.EOF at end of input and ErrInterrupted on Ctrl+C.
+func (e *Editor) ReadLine(prompt string) (string, error) {
+	return e.EditLine(prompt, "")
+}
+
+// EditLine is like ReadLine but starts with text already in the buffer for
+// the user to edit. Without a terminal, an empty reply keeps text unchanged.
+func (e *Editor) EditLine(prompt, text string) (string, error) {
+	if !e.isTerminal {
+		return e.readPlainDefault(prompt, text)
+	}
+
+	fd := int(e.in.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return e.readPlainDefault(prompt, text)
+	}
+	defer term.Restore(fd, state)
+
+	return e.edit(prompt, text)
+}
+
+// readPlainDefault reads a plain line, showing and defaulting to text
+func (e *Editor) readPlainDefault(prompt, text string) (string, error) {
+	if text == "" {
+		return e.readPlain(prompt)
+	}
+	fmt.Fprintf(e.out, "Current: %s\n", text)
+	line, err := e.readPlain(prompt)
+	if err != nil || line != "" {
+		return line, err
+	}
+	return text, nil
+}
+
+// readPlain reads a line without editing support (pipes, dumb terminals)
+func (e *Editor) readPlain(prompt string) (string, error) {
+	fmt.Fprint(e.out, prompt)
+	line, err := e.reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// edit runs the interactive editing loop; the terminal must be in raw mode
+func (e *Editor) edit(prompt, text string) (string, error) {
+	var buf lineBuffer
+	buf.set(text)
+	e.history.reset()
+	e.cursorRow = 0
+	e.refresh(prompt, &buf)
+
+	var last keyKind
+	yankStart, yankLen := 0, 0
+
+	for {
+		k, err := readKey(e.reader)
+		if err != nil {
+			e.finish(prompt, &buf)
+			return "", err
+		}
+
+		// Consecutive kills accumulate into one kill-ring entry
+		merge := isKill(last)
+
+		switch k.kind {
+		case keyRune:
+			buf.insert([]rune{k.r})
+		case keyEnter:
+			e.finish(prompt, &buf)
+			return buf.String(), nil
+		case keyInterrupt:
+			e.finish(prompt, &buf)
+			return "", ErrInterrupted
+		case keyEOF:
+			if len(buf.runes) == 0 {
+				e.finish(prompt, &buf)
+				return "", io.EOF
+			}
+			buf.deleteChar()
+		case keyBackspace:
+			buf.backspace()
+		case keyDelete:
+			buf.deleteChar()
+		case keyLeft:
+			buf.left()
+		case keyRight:
+			buf.right()
+		case keyUp:
+			if line, ok := e.history.Prev(buf.String()); ok {
+				buf.set(line)
+			}
+		case keyDown:
+			if line, ok := e.history.Next(); ok {
+				buf.set(line)
+			}
+		case keyHome:
+			buf.home()
+		case keyEnd:
+			buf.end()
+		case keyWordLeft:
+			buf.wordLeft()
+		case keyWordRight:
+			buf.wordRight()
+		case keyKillToStart:
+			e.killRing.Kill(buf.killToStart(), merge, true)
+		case keyKillToEnd:
+			e.killRing.Kill(buf.killToEnd(), merge, false)
+		case keyKillWhitespaceWord:
+			e.killRing.Kill(buf.killWhitespaceWordBack(), merge, true)
+		case keyKillWordBack:
+			e.killRing.Kill(buf.killWordBack(), merge, true)
+		case keyKillWordForward:
+			e.killRing.Kill(buf.killWordForward(), merge, false)
+		case keyYank:
+			if text, ok := e.killRing.Yank(); ok {
+				yankStart = buf.pos
+				buf.insert([]rune(text))
+				yankLen = buf.pos - yankStart
+			}
+		case keyYankPop:
+			// Only valid directly after a yank: replace the yanked text
+			if last != keyYank && last != keyYankPop {
+				break
+			}
+			if text, ok := e.killRing.YankPop(); ok {
+				buf.remove(yankStart, yankStart+yankLen)
+				buf.pos = yankStart
+				buf.insert([]rune(text))
+				yankLen = buf.pos - yankStart
+			}
+		case keyClear:
+			fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+			e.cursorRow = 0
+		case keyTab:
+			e.complete(prompt, &buf)
+		default:
+			// Unbound keys are ignored
+		}
+
+		last = k.kind
+		e.refresh(prompt, &buf)
+	}
+}
+
+// complete replaces the word before the cursor with its only candidate, or
+// extends it to the candidates' longest common prefix, listing them when
+// that adds nothing
+func (e *Editor) complete(prompt string, buf *lineBuffer) {
+	if e.completer == nil {
+		return
+	}
+	before := string(buf.runes[:buf.pos])
+	candidates := e.completer(before)
+	if len(candidates) == 0 {
+		return
+	}
+	start := buf.pos - len([]rune(before[strings.LastIndexAny(before, " \t")+1:]))
+	word := string(buf.runes[start:buf.pos])
+
+	replacement := candidates[0] + " "
+	if len(candidates) > 1 {
+		replacement = candidates[0]
+		for _, c := range candidates[1:] {
+			replacement = commonPrefix(replacement, c)
+		}
+		if len(replacement) <= len(word) || !strings.HasPrefix(replacement, word) {
+			pos := buf.pos
+			e.finish(prompt, buf)
+			fmt.Fprintf(e.out, "%s\r\n", strings.Join(candidates, "  "))
+			buf.pos = pos
+			return
+		}
+	}
+	buf.remove(start, buf.pos)
+	buf.insert([]rune(replacement))
+}
+
+// commonPrefix returns the longest prefix a and b share
+func commonPrefix(a, b string) string {
+	ar, br := []rune(a), []rune(b)
+	n := 0
+	for n < len(ar) && n < len(br) && ar[n] == br[n] {
+		n++
+	}
+	return string(ar[:n])
+}
+
+// isKill reports whether a key kind adds to the kill ring
+func isKill(kind keyKind) bool {
+	switch kind {
+	case keyKillToStart, keyKillToEnd, keyKillWhitespaceWord, keyKillWordBack, keyKillWordForward:
+		return true
+	}
+	return false
+}
+
+// width returns the terminal width in columns
+func (e *Editor) width() int {
+	if w, _, err := term.GetSize(int(e.in.Fd())); err == nil && w > 0 {
+		return w
+	}
+	return defaultWidth
+}
+
+// refresh redraws the prompt and buffer, handling lines that wrap across
+// several terminal rows
+func (e *Editor) refresh(prompt string, buf *lineBuffer) {
+	cols := e.width()
+	promptWidth := visibleWidth(prompt)
+	total := promptWidth + runewidth.StringWidth(buf.String())
+	cursor := promptWidth + runewidth.StringWidth(string(buf.runes[:buf.pos]))
+
+	var out strings.Builder
+	// Return to the prompt's first row and clear everything below it
+	if e.cursorRow > 0 {
+		fmt.Fprintf(&out, "\x1b[%dA", e.cursorRow)
+	}
+	out.WriteString("\r\x1b[J")
+	out.WriteString(prompt)
+	out.WriteString(buf.String())
+
+	// Terminals defer wrapping at the right margin; force it so the cursor
+	// arithmetic below holds
+	endRow := total / cols
+	if total > 0 && total%cols == 0 {
+		out.WriteString("\r\n")
+	}
+
+	cursorRow, cursorCol := cursor/cols, cursor%cols
+	if up := endRow - cursorRow; up > 0 {
+		fmt.Fprintf(&out, "\x1b[%dA", up)
+	}
+	out.WriteString("\r")
+	if cursorCol > 0 {
+		fmt.Fprintf(&out, "\x1b[%dC", cursorCol)
+	}
+	e.cursorRow = cursorRow
+
+	fmt.Fprint(e.out, out.String())
+}
+
+// finish moves the cursor past the end of the input and starts a new line
+func (e *Editor) finish(prompt string, buf *lineBuffer) {
+	buf.end()
+	e.refresh(prompt, buf)
+	fmt.Fprint(e.out, "\r\n")
+	e.cursorRow = 0
+}
+
+// visibleWidth returns the number of terminal columns s occupies
+func visibleWidth(s string) int {
+	return runewidth.StringWidth(ansiRegex.ReplaceAllString(s, ""))
+}
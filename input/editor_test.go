@@ -0,0 +1,245 @@
+package input
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// newTestEditor creates an editor that reads keystrokes from input without a terminal
+func newTestEditor(input string) (*Editor, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	return &Editor{
+		reader: bufio.NewReader(strings.NewReader(input)),
+		out:    out,
+	}, out
+}
+
+func TestEditor_Edit(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "plain", input: "hello\r", want: "hello"},
+		{name: "backspace", input: "helx\x7flo\r", want: "hello"},
+		{name: "insert mid-line", input: "hllo\x01\x1b[Ce\r", want: "hello"},
+		{name: "ctrl+u", input: "wrong\x15right\r", want: "right"},
+		{name: "ctrl+k", input: "keep drop\x1bb\x0b\r", want: "keep "},
+		{name: "ctrl+w", input: "explain this error\x17change\r", want: "explain this change"},
+		{name: "alt+backspace", input: "foo.bar\x1b\x7fbaz\r", want: "foo.baz"},
+		{name: "alt+b alt+f", input: "one three\x1bbtwo \x1bf!\r", want: "one two three!"},
+		{name: "kill and yank", input: "world hello\x17\x01\x19 \r", want: "hello world "},
+		{name: "consecutive kills merge", input: "a b c\x17\x17\x19\r", want: "a b c"},
+		{name: "yank pop", input: "first\x15second\x15\x19\x1by\r", want: "first"},
+		{name: "ctrl+d deletes", input: "abc\x01\x04\r", want: "bc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, _ := newTestEditor(tt.input)
+			got, err := e.edit("> ", "")
+			if err != nil {
+				t.Fatalf("edit() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("edit() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEditor_Edit_Interrupt(t *testing.T) {
+	e, _ := newTestEditor("abc\x03")
+	if _, err := e.edit("> ", ""); !errors.Is(err, ErrInterrupted) {
+		t.Errorf("edit() error = %v, want ErrInterrupted", err)
+	}
+}
+
+func TestEditor_Edit_EOF(t *testing.T) {
+	e, _ := newTestEditor("\x04")
+	if _, err := e.edit("> ", ""); !errors.Is(err, io.EOF) {
+		t.Errorf("edit() error = %v, want io.EOF", err)
+	}
+}
+
+func TestEditor_Edit_InitialText(t *testing.T) {
+	// Cursor starts at the end of the prefilled text
+	e, _ := newTestEditor(" world\r")
+	got, err := e.edit("> ", "hello")
+	if err != nil {
+		t.Fatalf("edit() error = %v", err)
+	}
+	if got != "hello world" {
+		t.Errorf("edit() = %q, want %q", got, "hello world")
+	}
+}
+
+func TestEditor_History(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "up recalls newest", input: "\x1b[A\r", want: "second"},
+		{name: "up twice", input: "\x1b[A\x1b[A\r", want: "first"},
+		{name: "ctrl+p ctrl+n", input: "\x10\x10\x0e\r", want: "second"},
+		{name: "down restores draft", input: "dra\x1b[A\x1b[Bft\r", want: "draft"},
+		{name: "recalled line can be edited", input: "\x1b[A\x01re\r", want: "resecond"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, _ := newTestEditor(tt.input)
+			e.AddHistory("first")
+			e.AddHistory("second")
+			got, err := e.edit("> ", "")
+			if err != nil {
+				t.Fatalf("edit() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("edit() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEditor_ReadPlainDefault(t *testing.T) {
+	e, out := newTestEditor("\nreplacement\n")
+
+	got, err := e.readPlainDefault("> ", "original")
+	if err != nil || got != "original" {
+		t.Errorf("readPlainDefault() on empty reply = %q, %v; want %q", got, err, "original")
+	}
+	if !strings.Contains(out.String(), "Current: original") {
+		t.Errorf("output = %q, want the current text shown", out.String())
+	}
+
+	got, err = e.readPlainDefault("> ", "original")
+	if err != nil || got != "replacement" {
+		t.Errorf("readPlainDefault() = %q, %v; want %q", got, err, "replacement")
+	}
+}
+
+func TestEditor_KillRingPersistsAcrossLines(t *testing.T) {
+	e, _ := newTestEditor("saved text\x15\r\x19\r")
+	if _, err := e.edit("> ", ""); err != nil {
+		t.Fatalf("edit() error = %v", err)
+	}
+	got, err := e.edit("> ", "")
+	if err != nil {
+		t.Fatalf("edit() error = %v", err)
+	}
+	if got != "saved text" {
+		t.Errorf("second line = %q, want text yanked from previous line", got)
+	}
+}
+
+func TestEditor_ReadPlain(t *testing.T) {
+	e, out := newTestEditor("first line\r\nsecond")
+
+	got, err := e.readPlain("> ")
+	if err != nil || got != "first line" {
+		t.Errorf("readPlain() = %q, %v; want %q", got, err, "first line")
+	}
+	if out.String() != "> " {
+		t.Errorf("prompt output = %q, want %q", out.String(), "> ")
+	}
+
+	// Last line without trailing newline is still returned
+	got, err = e.readPlain("> ")
+	if err != nil || got != "second" {
+		t.Errorf("readPlain() = %q, %v; want %q", got, err, "second")
+	}
+
+	if _, err := e.readPlain("> "); !errors.Is(err, io.EOF) {
+		t.Errorf("readPlain() at end error = %v, want io.EOF", err)
+	}
+}
+
+func TestEditor_Refresh_Wrapping(t *testing.T) {
+	e, out := newTestEditor("")
+	buf := newBuffer(strings.Repeat("x", 100), 10)
+
+	e.refresh("> ", buf)
+
+	// 102 columns on an 80-column terminal: cursor ends up on the first row
+	if e.cursorRow != 0 {
+		t.Errorf("cursorRow = %d, want 0", e.cursorRow)
+	}
+	if !strings.Contains(out.String(), "\x1b[1A") {
+		t.Errorf("refresh should move up from the wrapped row, got %q", out.String())
+	}
+
+	buf.end()
+	e.refresh("> ", buf)
+	if e.cursorRow != 1 {
+		t.Errorf("cursorRow = %d, want 1 after moving to end", e.cursorRow)
+	}
+}
+
+func TestVisibleWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{s: "> ", want: 2},
+		{s: "\x1b[33m[model]\x1b[0m > ", want: 10},
+		{s: "日本", want: 4},
+	}
+
+	for _, tt := range tests {
+		if got := visibleWidth(tt.s); got != tt.want {
+			t.Errorf("visibleWidth(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestEditor_Edit_Complete(t *testing.T) {
+	completer := func(line string) []string {
+		var matches []string
+		for _, c := range []string{"/model", "/models", "/mcp"} {
+			if strings.HasPrefix(c, line) {
+				matches = append(matches, c)
+			}
+		}
+		return matches
+	}
+	tests := []struct {
+		name     string
+		input    string
+		want     string
+		wantList bool
+	}{
+		{name: "only candidate", input: "/mc\tlist\r", want: "/mcp list"},
+		{name: "common prefix", input: "/mod\t\r", want: "/model"},
+		{name: "lists when ambiguous", input: "/m\t\r", want: "/m", wantList: true},
+		{name: "no candidates", input: "hello\t\r", want: "hello"},
+		{name: "completes before the cursor", input: "/mc x\x02\x02\t\r", want: "/mcp  x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, out := newTestEditor(tt.input)
+			e.SetCompleter(completer)
+			got, err := e.edit("> ", "")
+			if err != nil {
+				t.Fatalf("edit() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("edit() = %q, want %q", got, tt.want)
+			}
+			if listed := strings.Contains(out.String(), "/model  /models  /mcp"); listed != tt.wantList {
+				t.Errorf("candidates listed = %v, want %v", listed, tt.wantList)
+			}
+		})
+	}
+
+	// Without a completer Tab does nothing
+	e, _ := newTestEditor("/m\t\r")
+	if got, _ := e.edit("> ", ""); got != "/m" {
+		t.Errorf("edit() without a completer = %q, want %q", got, "/m")
+	}
+}
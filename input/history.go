@@ -0,0 +1,59 @@
+package input
+
+import "strings"
+
+// maxHistorySize is how many lines the input history remembers
+const maxHistorySize = 500
+
+// History stores previously entered lines for Up/Down navigation. Blank
+// lines and immediate repeats are not recorded.
+type History struct {
+	entries []string
+	// pos is the entry being shown; len(entries) means the line being typed
+	pos int
+	// draft holds the line being typed while older entries are shown
+	draft string
+}
+
+// Add records an entered line and resets navigation to the newest entry
+func (h *History) Add(line string) {
+	if strings.TrimSpace(line) != "" && (len(h.entries) == 0 || h.entries[len(h.entries)-1] != line) {
+		h.entries = append(h.entries, line)
+		if len(h.entries) > maxHistorySize {
+			h.entries = h.entries[1:]
+		}
+	}
+	h.reset()
+}
+
+// reset starts navigation from the line being typed
+func (h *History) reset() {
+	h.pos = len(h.entries)
+	h.draft = ""
+}
+
+// Prev returns the entry before the one shown. current is the buffer's
+// contents, kept as the draft when leaving the line being typed.
+func (h *History) Prev(current string) (string, bool) {
+	if h.pos == 0 {
+		return "", false
+	}
+	if h.pos == len(h.entries) {
+		h.draft = current
+	}
+	h.pos--
+	return h.entries[h.pos], true
+}
+
+// Next returns the entry after the one shown, or the draft once past the
+// newest entry
+func (h *History) Next() (string, bool) {
+	if h.pos >= len(h.entries) {
+		return "", false
+	}
+	h.pos++
+	if h.pos == len(h.entries) {
+		return h.draft, true
+	}
+	return h.entries[h.pos], true
+}
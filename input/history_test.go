@@ -0,0 +1,50 @@
+package input
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestHistory_Navigation(t *testing.T) {
+	var h History
+	h.Add("first")
+	h.Add("second")
+
+	steps := []struct {
+		name   string
+		move   func() (string, bool)
+		want   string
+		wantOK bool
+	}{
+		{name: "up", move: func() (string, bool) { return h.Prev("draft") }, want: "second", wantOK: true},
+		{name: "up again", move: func() (string, bool) { return h.Prev("second") }, want: "first", wantOK: true},
+		{name: "up at oldest", move: func() (string, bool) { return h.Prev("first") }, wantOK: false},
+		{name: "down", move: h.Next, want: "second", wantOK: true},
+		{name: "down to draft", move: h.Next, want: "draft", wantOK: true},
+		{name: "down at draft", move: h.Next, wantOK: false},
+	}
+	for _, s := range steps {
+		got, ok := s.move()
+		if ok != s.wantOK || (ok && got != s.want) {
+			t.Errorf("%s: got %q, %v; want %q, %v", s.name, got, ok, s.want, s.wantOK)
+		}
+	}
+}
+
+func TestHistory_Add(t *testing.T) {
+	var h History
+	h.Add("same")
+	h.Add("same")
+	h.Add("   ")
+	h.Add("")
+	if len(h.entries) != 1 {
+		t.Errorf("entries = %q, want blanks and repeats skipped", h.entries)
+	}
+
+	for i := 0; i < maxHistorySize+10; i++ {
+		h.Add(fmt.Sprint(i))
+	}
+	if len(h.entries) != maxHistorySize || h.entries[0] != "10" {
+		t.Errorf("kept %d entries starting at %q, want the newest %d", len(h.entries), h.entries[0], maxHistorySize)
+	}
+}
@@ -0,0 +1,196 @@
+package input
+
+import (
+	"bufio"
+)
+
+// keyKind identifies an editing action decoded from terminal input
+type keyKind int
+
+const (
+	keyRune keyKind = iota
+	keyEnter
+	keyBackspace
+	keyDelete
+	keyLeft
+	keyRight
+	keyUp
+	keyDown
+	keyHome
+	keyEnd
+	keyWordLeft
+	keyWordRight
+	keyKillToStart
+	keyKillToEnd
+	keyKillWhitespaceWord
+	keyKillWordBack
+	keyKillWordForward
+	keyYank
+	keyYankPop
+	keyTab
+	keyClear
+	keyInterrupt
+	keyEOF
+	keyEscape
+	keyUnknown
+)
+
+// key is a single decoded keypress
+type key struct {
+	kind keyKind
+	r    rune
+}
+
+// Control characters
+const (
+	ctrlA     = 0x01
+	ctrlB     = 0x02
+	ctrlC     = 0x03
+	ctrlD     = 0x04
+	ctrlE     = 0x05
+	ctrlF     = 0x06
+	ctrlH     = 0x08
+	tab       = 0x09
+	ctrlJ     = 0x0a
+	ctrlK     = 0x0b
+	ctrlL     = 0x0c
+	ctrlM     = 0x0d
+	ctrlN     = 0x0e
+	ctrlP     = 0x10
+	ctrlU     = 0x15
+	ctrlW     = 0x17
+	ctrlY     = 0x19
+	escape    = 0x1b
+	backspace = 0x7f
+)
+
+// readKey decodes the next keypress from r. Escape sequences are recognised
+// only when their bytes are already buffered, so a lone Esc never blocks.
+func readKey(r *bufio.Reader) (key, error) {
+	c, _, err := r.ReadRune()
+	if err != nil {
+		return key{}, err
+	}
+
+	switch c {
+	case ctrlA:
+		return key{kind: keyHome}, nil
+	case ctrlB:
+		return key{kind: keyLeft}, nil
+	case ctrlC:
+		return key{kind: keyInterrupt}, nil
+	case ctrlD:
+		return key{kind: keyEOF}, nil
+	case ctrlE:
+		return key{kind: keyEnd}, nil
+	case ctrlF:
+		return key{kind: keyRight}, nil
+	case ctrlH, backspace:
+		return key{kind: keyBackspace}, nil
+	case tab:
+		return key{kind: keyTab}, nil
+	case ctrlJ, ctrlM:
+		return key{kind: keyEnter}, nil
+	case ctrlK:
+		return key{kind: keyKillToEnd}, nil
+	case ctrlL:
+		return key{kind: keyClear}, nil
+	case ctrlN:
+		return key{kind: keyDown}, nil
+	case ctrlP:
+		return key{kind: keyUp}, nil
+	case ctrlU:
+		return key{kind: keyKillToStart}, nil
+	case ctrlW:
+		return key{kind: keyKillWhitespaceWord}, nil
+	case ctrlY:
+		return key{kind: keyYank}, nil
+	case escape:
+		return readEscape(r)
+	}
+
+	if c < 0x20 {
+		return key{kind: keyUnknown}, nil
+	}
+	return key{kind: keyRune, r: c}, nil
+}
+
+// readEscape decodes the bytes following an Esc: Alt+key combinations and
+// CSI/SS3 cursor sequences
+func readEscape(r *bufio.Reader) (key, error) {
+	if r.Buffered() == 0 {
+		return key{kind: keyEscape}, nil
+	}
+	c, _, err := r.ReadRune()
+	if err != nil {
+		return key{}, err
+	}
+
+	switch c {
+	case 'b', 'B':
+		return key{kind: keyWordLeft}, nil
+	case 'f', 'F':
+		return key{kind: keyWordRight}, nil
+	case 'd', 'D':
+		return key{kind: keyKillWordForward}, nil
+	case 'y', 'Y':
+		return key{kind: keyYankPop}, nil
+	case backspace, ctrlH:
+		return key{kind: keyKillWordBack}, nil
+	case '[', 'O':
+		return readCSI(r)
+	}
+	return key{kind: keyUnknown}, nil
+}
+
+// readCSI decodes a cursor-key sequence after "Esc [" or "Esc O"
+func readCSI(r *bufio.Reader) (key, error) {
+	var params []byte
+	for r.Buffered() > 0 {
+		c, err := r.ReadByte()
+		if err != nil {
+			return key{}, err
+		}
+		if c >= '0' && c <= '9' || c == ';' {
+			params = append(params, c)
+			continue
+		}
+
+		switch c {
+		case 'A':
+			return key{kind: keyUp}, nil
+		case 'B':
+			return key{kind: keyDown}, nil
+		case 'C':
+			if isModified(params) {
+				return key{kind: keyWordRight}, nil
+			}
+			return key{kind: keyRight}, nil
+		case 'D':
+			if isModified(params) {
+				return key{kind: keyWordLeft}, nil
+			}
+			return key{kind: keyLeft}, nil
+		case 'H':
+			return key{kind: keyHome}, nil
+		case 'F':
+			return key{kind: keyEnd}, nil
+		case '~':
+			switch string(params) {
+			case "1", "7":
+				return key{kind: keyHome}, nil
+			case "4", "8":
+				return key{kind: keyEnd}, nil
+			case "3":
+				return key{kind: keyDelete}, nil
+			}
+		}
+		return key{kind: keyUnknown}, nil
+	}
+	return key{kind: keyUnknown}, nil
+}
+
+// isModified reports whether CSI params carry an Alt/Ctrl modifier (e.g. "1;5")
+func isModified(params []byte) bool {
+	return len(params) > 2 && params[0] == '1' && params[1] == ';'
+}
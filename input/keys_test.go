@@ -0,0 +1,65 @@
+package input
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestReadKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  keyKind
+	}{
+		{name: "rune", input: "a", want: keyRune},
+		{name: "enter", input: "\r", want: keyEnter},
+		{name: "backspace", input: "\x7f", want: keyBackspace},
+		{name: "ctrl+a", input: "\x01", want: keyHome},
+		{name: "ctrl+e", input: "\x05", want: keyEnd},
+		{name: "ctrl+u", input: "\x15", want: keyKillToStart},
+		{name: "ctrl+k", input: "\x0b", want: keyKillToEnd},
+		{name: "ctrl+w", input: "\x17", want: keyKillWhitespaceWord},
+		{name: "ctrl+y", input: "\x19", want: keyYank},
+		{name: "ctrl+c", input: "\x03", want: keyInterrupt},
+		{name: "ctrl+d", input: "\x04", want: keyEOF},
+		{name: "alt+b", input: "\x1bb", want: keyWordLeft},
+		{name: "alt+f", input: "\x1bf", want: keyWordRight},
+		{name: "alt+d", input: "\x1bd", want: keyKillWordForward},
+		{name: "alt+y", input: "\x1by", want: keyYankPop},
+		{name: "alt+backspace", input: "\x1b\x7f", want: keyKillWordBack},
+		{name: "up", input: "\x1b[A", want: keyUp},
+		{name: "down", input: "\x1b[B", want: keyDown},
+		{name: "right", input: "\x1b[C", want: keyRight},
+		{name: "left", input: "\x1b[D", want: keyLeft},
+		{name: "ctrl+left", input: "\x1b[1;5D", want: keyWordLeft},
+		{name: "alt+right", input: "\x1b[1;3C", want: keyWordRight},
+		{name: "home", input: "\x1b[H", want: keyHome},
+		{name: "end ss3", input: "\x1bOF", want: keyEnd},
+		{name: "home tilde", input: "\x1b[1~", want: keyHome},
+		{name: "delete", input: "\x1b[3~", want: keyDelete},
+		{name: "lone escape", input: "\x1b", want: keyEscape},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := readKey(bufio.NewReader(strings.NewReader(tt.input)))
+			if err != nil {
+				t.Fatalf("readKey() error = %v", err)
+			}
+			if k.kind != tt.want {
+				t.Errorf("readKey(%q) = %v, want %v", tt.input, k.kind, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadKey_Unicode(t *testing.T) {
+	k, err := readKey(bufio.NewReader(strings.NewReader("é")))
+	if err != nil {
+		t.Fatalf("readKey() error = %v", err)
+	}
+	if k.kind != keyRune || k.r != 'é' {
+		t.Errorf("readKey() = %+v, want rune 'é'", k)
+	}
+}
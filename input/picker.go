@@ -0,0 +1,168 @@
+package input
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+	"golang.org/x/term"
+)
+
+// pickerRows is how many items the picker shows at once
+const pickerRows = 10
+
+// ErrNoPicker is returned by Pick when the input can't show the picker, as
+// with pipes and dumb terminals
+var ErrNoPicker = errors.New("the picker needs an interactive terminal")
+
+// Pick lets the user choose one of items: typing narrows the list to fuzzy
+// matches, Up/Down (or Ctrl+P/N) move the selection and Enter chooses it.
+// The list starts at selected. It returns the chosen item's index, or
+// ErrInterrupted when the user presses Esc or Ctrl+C.
+func (e *Editor) Pick(prompt string, items []string, selected int) (int, error) {
+	if !e.isTerminal || os.Getenv("TERM") == "dumb" {
+		return -1, ErrNoPicker
+	}
+	fd := int(e.in.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return -1, ErrNoPicker
+	}
+	defer term.Restore(fd, state)
+
+	return e.pick(prompt, items, selected)
+}
+
+// pick runs the picker loop; the terminal must be in raw mode
+func (e *Editor) pick(prompt string, items []string, selected int) (int, error) {
+	var query lineBuffer
+	matches := fuzzyFilter(items, "")
+	cur, top := min(max(selected, 0), len(items)-1), 0
+
+	for {
+		if cur < top {
+			top = cur
+		} else if cur >= top+pickerRows {
+			top = cur - pickerRows + 1
+		}
+		e.drawPicker(prompt, query.String(), items, matches, cur, top)
+
+		k, err := readKey(e.reader)
+		if err != nil {
+			e.closePicker(prompt, "")
+			return -1, err
+		}
+		refilter := true
+		switch k.kind {
+		case keyRune:
+			query.insert([]rune{k.r})
+		case keyBackspace:
+			query.backspace()
+		case keyKillToStart, keyKillWhitespaceWord:
+			query.set("")
+		case keyUp:
+			cur, refilter = max(cur-1, 0), false
+		case keyDown:
+			cur, refilter = max(min(cur+1, len(matches)-1), 0), false
+		case keyEnter:
+			if len(matches) > 0 {
+				e.closePicker(prompt, items[matches[cur]])
+				return matches[cur], nil
+			}
+			refilter = false
+		case keyInterrupt, keyEscape, keyEOF:
+			e.closePicker(prompt, "")
+			return -1, ErrInterrupted
+		default:
+			refilter = false
+		}
+		if refilter {
+			matches, cur, top = fuzzyFilter(items, query.String()), 0, 0
+		}
+	}
+}
+
+// drawPicker redraws the query line and the visible part of the list below
+// it, leaving the cursor after the query
+func (e *Editor) drawPicker(prompt, query string, items []string, matches []int, cur, top int) {
+	cols := e.width()
+	var out strings.Builder
+	out.WriteString("\r\x1b[J")
+	out.WriteString(prompt)
+	out.WriteString(query)
+
+	rows := 0
+	for i := top; i < len(matches) && i < top+pickerRows; i++ {
+		out.WriteString("\r\n")
+		if i == cur {
+			out.WriteString("\x1b[7m" + runewidth.Truncate("> "+items[matches[i]], cols-1, "…") + "\x1b[0m")
+		} else {
+			out.WriteString(runewidth.Truncate("  "+items[matches[i]], cols-1, "…"))
+		}
+		rows++
+	}
+	switch {
+	case len(matches) == 0:
+		out.WriteString("\r\n  (no matches)")
+		rows++
+	case len(matches) > pickerRows || len(matches) < len(items):
+		fmt.Fprintf(&out, "\r\n  (%d of %d)", len(matches), len(items))
+		rows++
+	}
+
+	fmt.Fprintf(&out, "\x1b[%dA\r", rows)
+	if col := visibleWidth(prompt) + runewidth.StringWidth(query); col > 0 {
+		fmt.Fprintf(&out, "\x1b[%dC", col)
+	}
+	fmt.Fprint(e.out, out.String())
+}
+
+// closePicker clears the list and leaves the prompt with the choice, if any
+func (e *Editor) closePicker(prompt, choice string) {
+	fmt.Fprintf(e.out, "\r\x1b[J%s%s\r\n", prompt, choice)
+}
+
+// fuzzyFilter returns the indexes of the items query fuzzily matches, best
+// match first
+func fuzzyFilter(items []string, query string) []int {
+	type match struct{ index, score int }
+	var found []match
+	for i, item := range items {
+		if score, ok := fuzzyScore(item, query); ok {
+			found = append(found, match{i, score})
+		}
+	}
+	sort.SliceStable(found, func(a, b int) bool { return found[a].score < found[b].score })
+	indexes := make([]int, len(found))
+	for i, m := range found {
+		indexes[i] = m.index
+	}
+	return indexes
+}
+
+// fuzzyScore reports whether the runes of query appear in text in order,
+// ignoring case, and scores the match: lower is better, favouring matches
+// that start early and run together
+func fuzzyScore(text, query string) (int, bool) {
+	t := []rune(strings.ToLower(text))
+	score, last := 0, -1
+	for _, r := range strings.ToLower(query) {
+		i := last + 1
+		for i < len(t) && t[i] != r {
+			i++
+		}
+		if i == len(t) {
+			return 0, false
+		}
+		if last < 0 {
+			score += i
+		} else {
+			score += i - last - 1
+		}
+		last = i
+	}
+	return score, true
+}
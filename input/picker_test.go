@@ -0,0 +1,82 @@
+package input
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+var pickerItems = []string{
+	"Claude Haiku 4.5 (ID: claude-haiku-4.5)",
+	"Claude Sonnet 4.5 (ID: claude-sonnet-4.5)",
+	"GPT-4.1 (ID: gpt-4.1)",
+}
+
+func TestEditor_Pick(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		selected int
+		want     int
+		wantErr  error
+	}{
+		{name: "enter keeps selection", input: "\r", selected: 1, want: 1},
+		{name: "down", input: "\x1b[B\r", want: 1},
+		{name: "down stops at last", input: "\x1b[B\x1b[B\x1b[B\r", want: 2},
+		{name: "up stops at first", input: "\x1b[A\r", want: 0},
+		{name: "filter", input: "gpt\r", want: 2},
+		{name: "fuzzy filter", input: "snt\r", want: 1},
+		{name: "filter then move", input: "claude\x1b[B\r", want: 1},
+		{name: "backspace widens filter", input: "gptx\x7f\r", want: 2},
+		{name: "enter without matches is ignored", input: "zzz\r\x15\r", selected: 2, want: 0},
+		{name: "escape", input: "\x1b", wantErr: ErrInterrupted},
+		{name: "ctrl+c", input: "son\x03", wantErr: ErrInterrupted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, _ := newTestEditor(tt.input)
+			got, err := e.pick("Model: ", pickerItems, tt.selected)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("pick() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("pick() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("pick() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEditor_Pick_NoTerminal(t *testing.T) {
+	e, _ := newTestEditor("\r")
+	if _, err := e.Pick("Model: ", pickerItems, 0); !errors.Is(err, ErrNoPicker) {
+		t.Errorf("Pick() error = %v, want ErrNoPicker", err)
+	}
+}
+
+func TestFuzzyFilter(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{query: "", want: []int{0, 1, 2}},
+		{query: "4.5", want: []int{0, 1}},
+		{query: "SONNET", want: []int{1}},
+		{query: "gpt", want: []int{2}},
+		{query: "4.1", want: []int{2}},
+		// Earlier matches with fewer gaps rank first
+		{query: "id", want: []int{2, 0, 1}},
+		{query: "xyz", want: []int{}},
+	}
+	for _, tt := range tests {
+		if got := fuzzyFilter(pickerItems, tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("fuzzyFilter(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package input
+
+import (
+	"bytes"
+	"io"
+	"unicode/utf8"
+)
+
+// MaxPipedBytes caps how much piped stdin is read as prompt context
+const MaxPipedBytes = 1 << 20
+
+// ReadPiped reads r (e.g. stdin from `git diff | cocli ...`) up to limit
+// bytes. When the input is longer it is cut at the last line break before
+// the limit and truncated is true. Trailing newlines are dropped.
+func ReadPiped(r io.Reader, limit int) (text string, truncated bool, err error) {
+	data, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return "", false, err
+	}
+	if len(data) > limit {
+		truncated = true
+		data = data[:limit]
+		if i := bytes.LastIndexByte(data, '\n'); i > 0 {
+			data = data[:i]
+		}
+		// Don't leave half a character at the cut
+		for len(data) > 0 && !utf8.Valid(data) {
+			data = data[:len(data)-1]
+		}
+	}
+	return string(bytes.TrimRight(data, "\r\n")), truncated, nil
+}
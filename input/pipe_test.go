@@ -0,0 +1,34 @@
+package input
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadPiped(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		limit         int
+		want          string
+		wantTruncated bool
+	}{
+		{name: "fits", input: "diff --git a/x b/x\n+new\n", limit: 100, want: "diff --git a/x b/x\n+new"},
+		{name: "exactly at limit", input: "abcd", limit: 4, want: "abcd"},
+		{name: "cut at line break", input: "one\ntwo\nthree\n", limit: 10, want: "one\ntwo", wantTruncated: true},
+		{name: "one long line", input: "abcdefgh", limit: 5, want: "abcde", wantTruncated: true},
+		{name: "no split rune", input: "aé", limit: 2, want: "a", wantTruncated: true},
+		{name: "empty", input: "", limit: 10, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated, err := ReadPiped(strings.NewReader(tt.input), tt.limit)
+			if err != nil {
+				t.Fatalf("ReadPiped() error = %v", err)
+			}
+			if got != tt.want || truncated != tt.wantTruncated {
+				t.Errorf("ReadPiped() = %q, %v; want %q, %v", got, truncated, tt.want, tt.wantTruncated)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+// Package ledger keeps a running record of every request cocli sends, across
+// runs, so premium request usage can be added up by day, week or month.
+package ledger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"atulm/cocli/storage"
+)
+
+// FileName is the ledger's file name under the cocli directory
+const FileName = "usage.jsonl"
+
+// Entry records one request
+type Entry struct {
+	Time         time.Time `json:"time"`
+	Model        string    `json:"model"`
+	Multiplier   float64   `json:"multiplier"`
+	InputTokens  int64     `json:"input_tokens,omitempty"`
+	OutputTokens int64     `json:"output_tokens,omitempty"`
+	// RateLimited counts the times the API throttled the request
+	RateLimited int `json:"rate_limited,omitempty"`
+}
+
+// Ledger is an append-only JSON Lines file of entries. Writes hold the
+// file's lock, so concurrent cocli processes don't interleave lines.
+type Ledger struct {
+	path string
+}
+
+// New returns a ledger stored at path
+func New(path string) *Ledger {
+	return &Ledger{path: path}
+}
+
+// Path returns the ledger file's path
+func (l *Ledger) Path() string {
+	return l.path
+}
+
+// Append adds e to the ledger
+func (l *Ledger) Append(e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return fmt.Errorf("failed to create usage ledger directory: %w", err)
+	}
+	return storage.WithLock(l.path, func() error {
+		f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to open usage ledger: %w", err)
+		}
+		if _, err := f.Write(append(line, '\n')); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to write usage ledger: %w", err)
+		}
+		return f.Close()
+	})
+}
+
+// Entries returns the entries recorded at or after since, oldest first. A
+// missing ledger has no entries; lines that don't parse are skipped.
+func (l *Ledger) Entries(since time.Time) ([]Entry, error) {
+	data, err := storage.LockedRead(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage ledger: %w", err)
+	}
+
+	var entries []Entry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		if !e.Time.Before(since) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// PeriodStart returns the start of the calendar day, week (from Monday) or
+// month containing now; "" means month
+func PeriodStart(period string, now time.Time) (time.Time, error) {
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	switch period {
+	case "day", "today":
+		return today, nil
+	case "week":
+		sinceMonday := (int(today.Weekday()) + 6) % 7
+		return today.AddDate(0, 0, -sinceMonday), nil
+	case "", "month":
+		return time.Date(y, m, 1, 0, 0, 0, 0, now.Location()), nil
+	}
+	return time.Time{}, fmt.Errorf("unknown period %q (want day, week or month)", period)
+}
+
+// ModelTotal adds up one model's entries
+type ModelTotal struct {
+	Model           string
+	Requests        int
+	PremiumRequests float64
+	InputTokens     int64
+	OutputTokens    int64
+	RateLimited     int
+}
+
+func (t *ModelTotal) add(e Entry) {
+	t.Requests++
+	t.PremiumRequests += e.Multiplier
+	t.InputTokens += e.InputTokens
+	t.OutputTokens += e.OutputTokens
+	t.RateLimited += e.RateLimited
+}
+
+// Summary totals the entries of a period
+type Summary struct {
+	Since, Until time.Time
+	// Models is sorted by premium requests, highest first
+	Models []ModelTotal
+	Total  ModelTotal
+	// LastRateLimited is the time of the latest throttled request
+	LastRateLimited time.Time
+}
+
+// Summarize totals entries for the period from since until now
+func Summarize(entries []Entry, since, now time.Time) Summary {
+	s := Summary{Since: since, Until: now, Total: ModelTotal{Model: "Total"}}
+	index := map[string]int{}
+	for _, e := range entries {
+		i, ok := index[e.Model]
+		if !ok {
+			i = len(s.Models)
+			index[e.Model] = i
+			s.Models = append(s.Models, ModelTotal{Model: e.Model})
+		}
+		s.Models[i].add(e)
+		s.Total.add(e)
+		if e.RateLimited > 0 && e.Time.After(s.LastRateLimited) {
+			s.LastRateLimited = e.Time
+		}
+	}
+	sort.SliceStable(s.Models, func(i, j int) bool {
+		return s.Models[i].PremiumRequests > s.Models[j].PremiumRequests
+	})
+	return s
+}
+
+// PerDay returns the average premium requests per day over the period; a
+// period shorter than a day counts as one day
+func (s Summary) PerDay() float64 {
+	days := s.Until.Sub(s.Since).Hours() / 24
+	if days < 1 {
+		days = 1
+	}
+	return s.Total.PremiumRequests / days
+}
+
+// Write prints s as a table with a total row and the daily burn rate
+func (s Summary) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Model\tRequests\tPremium\tInput\tOutput")
+	row := func(t ModelTotal) {
+		fmt.Fprintf(tw, "%s\t%d\t%.2f\t%d\t%d\n", t.Model, t.Requests, t.PremiumRequests, t.InputTokens, t.OutputTokens)
+	}
+	for _, t := range s.Models {
+		row(t)
+	}
+	row(s.Total)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "\n%.2f premium requests since %s (%.2f/day)\n", s.Total.PremiumRequests, s.Since.Format("Mon Jan 2"), s.PerDay()); err != nil {
+		return err
+	}
+	if s.Total.RateLimited == 0 {
+		return nil
+	}
+	_, err := fmt.Fprintf(w, "Rate limited %d times, most recently %s\n", s.Total.RateLimited, s.LastRateLimited.Format("Mon Jan 2 15:04"))
+	return err
+}
@@ -0,0 +1,141 @@
+package ledger
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLedger_AppendAndEntries(t *testing.T) {
+	l := New(filepath.Join(t.TempDir(), "nested", FileName))
+	if entries, err := l.Entries(time.Time{}); err != nil || len(entries) != 0 {
+		t.Fatalf("Entries() on a missing ledger = %v, %v; want none", entries, err)
+	}
+
+	base := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	for i, model := range []string{"gpt-5", "gpt-4.1", "gpt-5"} {
+		e := Entry{Time: base.Add(time.Duration(i) * time.Hour), Model: model, Multiplier: 1, InputTokens: 10}
+		if err := l.Append(e); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+	// A torn line from a crash is skipped
+	f, err := os.OpenFile(l.Path(), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"time": "2026-03`)
+	f.Close()
+
+	entries, err := l.Entries(base.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Entries() error = %v", err)
+	}
+	if len(entries) != 2 || entries[0].Model != "gpt-4.1" || entries[1].Model != "gpt-5" {
+		t.Errorf("Entries() = %+v, want the last two", entries)
+	}
+}
+
+func TestPeriodStart(t *testing.T) {
+	// A Thursday afternoon
+	now := time.Date(2026, 3, 12, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		period  string
+		want    time.Time
+		wantErr bool
+	}{
+		{period: "day", want: time.Date(2026, 3, 12, 0, 0, 0, 0, time.UTC)},
+		{period: "week", want: time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)},
+		{period: "month", want: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{period: "", want: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{period: "year", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.period, func(t *testing.T) {
+			got, err := PeriodStart(tt.period, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PeriodStart() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("PeriodStart() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Sunday belongs to the week that started on Monday
+	sunday := time.Date(2026, 3, 15, 9, 0, 0, 0, time.UTC)
+	if got, _ := PeriodStart("week", sunday); !got.Equal(time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("PeriodStart(week, Sunday) = %v, want the Monday before", got)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	since := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	now := since.Add(4 * 24 * time.Hour)
+	entries := []Entry{
+		{Model: "gpt-4.1", Multiplier: 0, InputTokens: 100, OutputTokens: 10},
+		{Model: "claude-opus-4.5", Multiplier: 3, InputTokens: 200, OutputTokens: 20},
+		{Model: "gpt-4.1", Multiplier: 0, InputTokens: 50, OutputTokens: 5},
+		{Model: "claude-opus-4.5", Multiplier: 3},
+	}
+
+	s := Summarize(entries, since, now)
+	if len(s.Models) != 2 || s.Models[0].Model != "claude-opus-4.5" {
+		t.Fatalf("Models = %+v, want opus first", s.Models)
+	}
+	if s.Models[1].Requests != 2 || s.Models[1].InputTokens != 150 {
+		t.Errorf("gpt-4.1 = %+v, want 2 requests and 150 input tokens", s.Models[1])
+	}
+	if s.Total.Requests != 4 || s.Total.PremiumRequests != 6 {
+		t.Errorf("Total = %+v, want 4 requests costing 6", s.Total)
+	}
+	if s.PerDay() != 1.5 {
+		t.Errorf("PerDay() = %v, want 1.5", s.PerDay())
+	}
+
+	var buf bytes.Buffer
+	if err := s.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); !strings.Contains(out, "Total") || !strings.Contains(out, "6.00 premium requests since Sun Mar 1 (1.50/day)") {
+		t.Errorf("Write() = %q, want a total row and the burn rate", out)
+	}
+}
+
+func TestSummarize_RateLimited(t *testing.T) {
+	since := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{Time: since.Add(time.Hour), Model: "gpt-5", Multiplier: 1, RateLimited: 2},
+		{Time: since.Add(2 * time.Hour), Model: "gpt-5", Multiplier: 1},
+		{Time: since.Add(30 * time.Minute), Model: "gpt-4.1", RateLimited: 1},
+	}
+	s := Summarize(entries, since, since.Add(24*time.Hour))
+	if s.Total.RateLimited != 3 || !s.LastRateLimited.Equal(since.Add(time.Hour)) {
+		t.Errorf("rate limited %d times, last at %v; want 3, the latest throttled request", s.Total.RateLimited, s.LastRateLimited)
+	}
+	var buf bytes.Buffer
+	if err := s.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "Rate limited 3 times, most recently Sun Mar 1 01:00") {
+		t.Errorf("Write() = %q, want the rate limit hits", buf.String())
+	}
+
+	s = Summarize(entries[1:2], since, since.Add(24*time.Hour))
+	buf.Reset()
+	s.Write(&buf)
+	if strings.Contains(buf.String(), "Rate limited") {
+		t.Errorf("Write() = %q without rate limits", buf.String())
+	}
+}
+
+func TestSummary_PerDayShortPeriod(t *testing.T) {
+	since := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	s := Summarize([]Entry{{Model: "gpt-5", Multiplier: 1}}, since, since.Add(time.Hour))
+	if s.PerDay() != 1 {
+		t.Errorf("PerDay() = %v, want a short period counted as one day", s.PerDay())
+	}
+}
@@ -0,0 +1,233 @@
+// Package logging sets up the slog logger cocli's packages share. Libraries
+// log through slog's default logger, so programs embedding them decide where
+// their logs go; cocli itself installs one with Setup.
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// Levels are the accepted names of log levels
+var Levels = []string{"debug", "info", "warn", "error"}
+
+// Formats are the accepted log formats. "text" is written for people: on a
+// terminal as "Warning: message: error", in a file as slog's key=value
+// lines.
+var Formats = []string{"text", "json"}
+
+// Options configure the logger
+type Options struct {
+	// Level is the lowest level logged
+	Level slog.Level
+	// JSON writes one JSON object per record instead of text
+	JSON bool
+	// File, if set, receives the logs instead of stderr; warnings and
+	// errors are still shown on stderr
+	File string
+}
+
+// ParseLevel returns the level named by one of Levels; "" is info
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q (use %s)", s, strings.Join(Levels, ", "))
+}
+
+// CheckFormat reports whether s is one of Formats
+func CheckFormat(s string) error {
+	for _, f := range Formats {
+		if s == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown log format %q (use %s)", s, strings.Join(Formats, ", "))
+}
+
+// Setup makes a logger from opts slog's default, and returns a function
+// that closes its file, if any
+func Setup(opts Options) (func() error, error) {
+	handler, closeFile, err := NewHandler(os.Stderr, opts)
+	if err != nil {
+		return nil, err
+	}
+	slog.SetDefault(slog.New(handler))
+	return closeFile, nil
+}
+
+// NewHandler returns a handler writing to stderr, or to opts.File and the
+// warnings and errors also to stderr
+func NewHandler(stderr io.Writer, opts Options) (slog.Handler, func() error, error) {
+	if opts.File == "" {
+		return newHandler(stderr, opts, true), func() error { return nil }, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(opts.File), 0700); err != nil {
+		return nil, nil, fmt.Errorf("failed to open the log file: %w", err)
+	}
+	f, err := os.OpenFile(opts.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open the log file: %w", err)
+	}
+	console := newConsoleHandler(stderr, max(opts.Level, slog.LevelWarn))
+	return teeHandler{newHandler(f, opts, false), console}, f.Close, nil
+}
+
+// newHandler returns a handler for w; a terminal gets the console format
+// unless JSON is asked for
+func newHandler(w io.Writer, opts Options, console bool) slog.Handler {
+	switch {
+	case opts.JSON:
+		return slog.NewJSONHandler(w, &slog.HandlerOptions{Level: opts.Level})
+	case console:
+		return newConsoleHandler(w, opts.Level)
+	default:
+		return slog.NewTextHandler(w, &slog.HandlerOptions{Level: opts.Level})
+	}
+}
+
+// consoleHandler writes records the way cocli has always shown them:
+// "Warning: message: error key=value", with no time or level for info
+type consoleHandler struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	level  slog.Level
+	attrs  []slog.Attr
+	prefix string // group names joined by dots, ending in a dot
+
+	// eol ends each line; a terminal gets \r\n, since a warning may
+	// interrupt the prompt while it is in raw mode
+	eol string
+}
+
+func newConsoleHandler(w io.Writer, level slog.Level) *consoleHandler {
+	eol := "\n"
+	if f, ok := w.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		eol = "\r\n"
+	}
+	return &consoleHandler{mu: &sync.Mutex{}, w: w, level: level, eol: eol}
+}
+
+func (h *consoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *consoleHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	switch {
+	case r.Level >= slog.LevelError:
+		b.WriteString("Error: ")
+	case r.Level >= slog.LevelWarn:
+		b.WriteString("Warning: ")
+	case r.Level < slog.LevelInfo:
+		b.WriteString("debug: ")
+	}
+	b.WriteString(r.Message)
+
+	var errText string
+	var rest []string
+	add := func(prefix string, a slog.Attr) {
+		if a.Equal(slog.Attr{}) {
+			return
+		}
+		if a.Key == "err" && prefix == "" {
+			errText = a.Value.String()
+			return
+		}
+		rest = append(rest, prefix+a.Key+"="+a.Value.String())
+	}
+	for _, a := range h.attrs {
+		add("", a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		add(h.prefix, a)
+		return true
+	})
+	if errText != "" {
+		b.WriteString(": " + errText)
+	}
+	for _, kv := range rest {
+		b.WriteString(" " + kv)
+	}
+	b.WriteString(h.eol)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+func (h *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.attrs = append([]slog.Attr(nil), h.attrs...)
+	for _, a := range attrs {
+		a.Key = h.prefix + a.Key
+		c.attrs = append(c.attrs, a)
+	}
+	return &c
+}
+
+func (h *consoleHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	c := *h
+	c.prefix = h.prefix + name + "."
+	return &c
+}
+
+// teeHandler sends each record to every handler that takes its level
+type teeHandler []slog.Handler
+
+func (t teeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range t {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t teeHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range t {
+		if h.Enabled(ctx, r.Level) {
+			if err := h.Handle(ctx, r.Clone()); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	out := make(teeHandler, len(t))
+	for i, h := range t {
+		out[i] = h.WithAttrs(attrs)
+	}
+	return out
+}
+
+func (t teeHandler) WithGroup(name string) slog.Handler {
+	out := make(teeHandler, len(t))
+	for i, h := range t {
+		out[i] = h.WithGroup(name)
+	}
+	return out
+}
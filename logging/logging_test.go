@@ -0,0 +1,105 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConsoleHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(l *slog.Logger)
+		want string
+	}{
+		{name: "info", log: func(l *slog.Logger) { l.Info("Fetching available models") }, want: "Fetching available models\n"},
+		{name: "warning with error", log: func(l *slog.Logger) {
+			l.Warn("conversation not saved", "err", errors.New("disk full"), "id", "abc")
+		}, want: "Warning: conversation not saved: disk full id=abc\n"},
+		{name: "error", log: func(l *slog.Logger) { l.Error("failed") }, want: "Error: failed\n"},
+		{name: "attrs and groups", log: func(l *slog.Logger) {
+			l.With("port", 4321).WithGroup("req").Info("connected", "n", 2)
+		}, want: "connected port=4321 req.n=2\n"},
+		{name: "debug hidden", log: func(l *slog.Logger) { l.Debug("noise") }, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			h, _, err := NewHandler(&buf, Options{Level: slog.LevelInfo})
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.log(slog.New(h))
+			if buf.String() != tt.want {
+				t.Errorf("logged %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestNewHandler_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	h, _, err := NewHandler(&buf, Options{Level: slog.LevelDebug, JSON: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slog.New(h).Debug("session created", "model", "gpt-4.1")
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("not JSON: %q", buf.String())
+	}
+	if record["level"] != "DEBUG" || record["msg"] != "session created" || record["model"] != "gpt-4.1" {
+		t.Errorf("record = %v", record)
+	}
+}
+
+func TestNewHandler_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "cocli.log")
+	var stderr bytes.Buffer
+	h, closeFile, err := NewHandler(&stderr, Options{Level: slog.LevelInfo, File: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := slog.New(h)
+	l.Info("Fetching available models")
+	l.Warn("daemon did not respond", "err", errors.New("timeout"))
+	if err := closeFile(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 2 || !strings.Contains(string(data), "level=WARN") {
+		t.Errorf("file = %q, want both records as text", data)
+	}
+	if stderr.String() != "Warning: daemon did not respond: timeout\n" {
+		t.Errorf("stderr = %q, want only the warning", stderr.String())
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    slog.Level
+		wantErr bool
+	}{
+		{in: "", want: slog.LevelInfo},
+		{in: "debug", want: slog.LevelDebug},
+		{in: "WARN", want: slog.LevelWarn},
+		{in: "error", want: slog.LevelError},
+		{in: "loud", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLevel(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseLevel(%q) = %v, %v", tt.in, got, err)
+		}
+	}
+}
@@ -1,57 +1,204 @@
 package main
 
 import (
-	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"atulm/cocli/api"
 	"atulm/cocli/client"
+	"atulm/cocli/command"
+	"atulm/cocli/config"
+	"atulm/cocli/edits"
+	"atulm/cocli/git"
+	"atulm/cocli/input"
+	"atulm/cocli/ledger"
+	"atulm/cocli/logging"
+	"atulm/cocli/models"
+	"atulm/cocli/notify"
+	"atulm/cocli/promptline"
+	"atulm/cocli/secrets"
 	"atulm/cocli/server"
 	"atulm/cocli/session"
+
+	copilot "github.com/github/copilot-sdk/go"
+	"golang.org/x/term"
 )
 
+// globalFlags are the options given before the other arguments
+type globalFlags struct {
+	// style is the renderer style chosen with --style
+	style string
+	// noColor writes replies as plain text (--no-color)
+	noColor bool
+	// server is the host:port of a remote daemon (--server)
+	server string
+	// verbose logs debug diagnostics (--verbose)
+	verbose bool
+	// debug logs every session event to ~/.cocli/debug.log (--debug)
+	debug bool
+	// output is a file every reply's markdown is appended to (--output)
+	output string
+	// quiet prints only replies, warnings and errors (--quiet)
+	quiet bool
+}
+
+// globals holds the global flags of this run
+var globals globalFlags
+
 func main() {
+	// The SDK runs cocli as its "CLI" to reach a daemon through a private
+	// socket; see server.BridgeEnv
+	if path := os.Getenv(server.BridgeEnv); path != "" {
+		if err := server.Bridge(path, os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	// `cocli --style light ...` overrides renderer.style for this run,
+	// `cocli --no-color ...` writes plain text, `cocli --server host:port`
+	// uses a daemon on another machine, `cocli --debug` logs session events,
+	// `cocli --output notes.md` appends replies to a file, `cocli --quiet`
+	// leaves out banners and notices and `cocli --verbose` logs debug
+	// diagnostics
+	args, err := globals.cut(os.Args[1:], false)
+	if err != nil {
+		exitWithError(err)
+	}
+	sub, args := findSubcommand(args)
+	// Other than a prompt, a subcommand's arguments may mix in global flags
+	if args, err = globals.cut(args, !sub.prompt); err != nil {
+		exitWithError(err)
+	}
+	// Logs go to stderr until the config says otherwise
+	setupLogging(&config.Config{})
+	os.Exit(sub.run(args))
+}
+
+// runChat runs the interactive session, first sending the prompt in args if
+// there is one, and returns the process exit code
+func runChat(args []string) int {
+	// Validate the config file up front so mistakes are reported before connecting
+	cfg, err := loadConfig()
+	if err != nil {
+		exitWithError(err)
+	}
+
 	// Create client first (handles daemon connection)
-	cli, err := client.NewClient()
+	cli, err := newClient(cfg)
 	if err != nil {
-		log.Fatal(err)
+		exitWithError(err)
 	}
-	defer cli.Stop()
 
 	// Create session manager with client
-	sessionMgr, err := session.NewManager(cli)
+	sessionMgr, err := newSessionManager(cli, cfg)
 	if err != nil {
-		log.Fatal(err)
+		cli.Stop()
+		exitWithError(err)
+	}
+	// On a daemon the session outlives this run, for the next one to reattach to
+	defer sessionMgr.Detach()
+
+	followTerminalWidth(sessionMgr, cfg)
+	sessionMgr.SetAutoTitle(true)
+
+	// Explain a daemon crash nobody has seen yet
+	if dm, err := server.DefaultDaemonManager(); err == nil && cli.RemoteAddr() == "" {
+		if crash, _ := dm.TakeUnreportedCrash(); crash != nil {
+			slog.Warn("the background daemon crashed since it was last used", "reason", crash.Reason(), "pid", crash.PID)
+			printCrashReport(crash)
+			fmt.Println()
+		}
 	}
 
 	// Display connection mode
-	if cli.IsUsingDaemon() {
-		fmt.Printf("Connected to daemon on port %d\n", server.DefaultPort)
+	if addr := cli.RemoteAddr(); addr != "" {
+		notice("Connected to remote daemon at %s\n", addr)
+	} else if cli.IsUsingDaemon() {
+		notice("Connected to daemon on port %d\n", cli.DaemonPort())
+		superviseDaemon(cfg)
 	} else {
-		fmt.Println("Using embedded server (consider: /server start)")
+		notice("Using embedded server (consider: /server start)\n")
+	}
+	if cfg.Project != nil {
+		notice("Using project config %s\n", cfg.Project.Path)
+	}
+	reattachSession(sessionMgr, cli)
+	// Ended by Close on a clean exit, so a panic or kill is noticed next time
+	crashed, err := sessionMgr.TrackRun()
+	if err != nil {
+		slog.Warn("crash recovery is off for this run", "err", err)
 	}
 
-	// Handle Ctrl+C
+	// Handle Ctrl+C: cancel a response in progress, otherwise quit
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 	go func() {
-		<-sigChan
-		fmt.Println("\nBye")
-		os.Exit(0)
+		for sig := range sigChan {
+			if sig == os.Interrupt && (sessionMgr.Abort() || interrupt()) {
+				continue
+			}
+			fmt.Println("\nBye")
+			sessionMgr.Close()
+			os.Exit(0)
+		}
 	}()
 
-	reader := bufio.NewReader(os.Stdin)
+	editor := input.NewEditor(os.Stdin, os.Stdout)
+
+	a := &app{cli: cli, sessionMgr: sessionMgr, input: editor, project: cfg.Project}
+	a.registerCommands()
+	editor.SetCompleter(a.commands.Complete)
+	sessionMgr.AddPromptStage(sessionMgr.SizeGuard(a.chooseOversizeAction))
+	sessionMgr.SetShellPolicy(shellPolicy(cfg), a.confirmCommand)
+	sessionMgr.SetToolApprover(a.approveTool)
+	if dir, err := config.DefaultDir(); err == nil {
+		a.journal = edits.NewJournal(filepath.Join(dir, "backups"))
+	}
+	sessionMgr.OnTurnComplete(func(report session.TurnReport) {
+		if report.Status == session.TurnOK && sessionMgr.AgentMode() && a.journal != nil {
+			a.reviewEdits()
+		}
+	})
 
 	// Check if a prompt was provided as a command-line argument
 	var initialPrompt string
-	if len(os.Args) > 1 {
-		initialPrompt = strings.Join(os.Args[1:], " ")
+	if len(args) > 0 {
+		initialPrompt = strings.Join(args, " ")
+		// `git diff | cocli "explain this change"` sends the diff along
+		if !editor.IsTerminal() {
+			if err := attachPipedInput(sessionMgr); err != nil {
+				exitWithError(err)
+			}
+		}
+	}
+
+	if crashed != nil && initialPrompt == "" && editor.IsTerminal() {
+		a.offerRecovery(crashed)
+	}
+	// Pruned after the offer so a declined conversation stays for /resume
+	var keep []string
+	if crashed != nil {
+		keep = append(keep, crashed.ID)
 	}
+	pruneHistory(sessionMgr, cfg, keep...)
+
+	colorPrompt := editor.IsTerminal() && !plainOutput()
 
 	// Interactive loop
 	for {
@@ -63,108 +210,1151 @@ func main() {
 			prompt = initialPrompt
 			initialPrompt = "" // Clear it so we only use it once
 		} else {
-			// Display prompt with tokens and multiplier if available
-			if sessionMgr.HasTokenLimit() {
-				fmt.Printf("[%s | %.2fx | %d/%d tokens] > ", sessionMgr.GetCurrentModel(), sessionMgr.GetCurrentMultiplier(), sessionMgr.GetTokensLeft(), sessionMgr.GetTokenLimit())
-			} else {
-				fmt.Printf("[%s | %.2fx] > ", sessionMgr.GetCurrentModel(), sessionMgr.GetCurrentMultiplier())
-			}
-			prompt, err = reader.ReadString('\n')
+			prompt, err = editor.ReadLine(promptLine(sessionMgr, cfg.Prompt, colorPrompt))
 			if err != nil {
-				log.Fatal(err)
+				if errors.Is(err, io.EOF) || errors.Is(err, input.ErrInterrupted) {
+					fmt.Println("Bye")
+					sessionMgr.Close()
+					return 0
+				}
+				slog.Error("failed to read the prompt", "err", err)
+				sessionMgr.Close()
+				os.Exit(1)
 			}
 			prompt = strings.TrimSpace(prompt)
 		}
+		editor.AddHistory(prompt)
 
 		// Handle slash commands
-		if strings.HasPrefix(prompt, "/") {
-			if prompt == "/models" || prompt == "/list" {
-				if err := promptForModelSelection(sessionMgr, reader); err != nil {
-					fmt.Printf("Error: %v\n", err)
-				}
-			} else if strings.HasPrefix(prompt, "/server") {
-				shouldExit, err := handleServerCommand(prompt, cli.IsUsingDaemon())
-				if err != nil {
-					fmt.Printf("Error: %v\n", err)
-				}
-				if shouldExit {
+		if command.IsCommand(prompt) {
+			if err := a.commands.Dispatch(prompt); err != nil {
+				if errors.Is(err, command.ErrExit) {
 					fmt.Println("Bye")
-					return
+					sessionMgr.Close()
+					return 0
 				}
-			} else {
-				fmt.Println("Unknown command. Available: /models, /list, /server")
+				fmt.Printf("Error: %v\n", err)
 			}
 			continue
 		}
 
 		// Send prompt if not empty
 		if prompt != "" {
-			if err := sessionMgr.Send(prompt); err != nil {
-				log.Fatal(err)
+			err := sessionMgr.Send(prompt)
+			for question := retryQuestion(err, sessionMgr); question != "" && a.confirm(question); question = retryQuestion(err, sessionMgr) {
+				err = sessionMgr.Send(prompt)
+			}
+			if err != nil {
+				if errors.Is(err, session.ErrPromptCancelled) ||
+					errors.Is(err, session.ErrResponseCancelled) ||
+					errors.Is(err, session.ErrResponseStalled) ||
+					errors.Is(err, session.ErrRequestTimedOut) {
+					fmt.Println("Cancelled")
+					continue
+				}
+				// The conversation is intact; the prompt can be sent again
+				fmt.Printf("Error: %v\n", cli.Diagnose(err))
 			}
 		}
 	}
 }
 
-func promptForModelSelection(sessionMgr *session.Manager, reader *bufio.Reader) error {
-	models, err := sessionMgr.GetModels()
+// reattachSession continues the session the previous run left on the
+// daemon, if any, and keeps the current one for the next run
+func reattachSession(sessionMgr *session.Manager, cli *client.Client) {
+	if !cli.IsUsingDaemon() {
+		return
+	}
+	dir, err := config.DefaultDir()
+	if err != nil {
+		return
+	}
+	sessionMgr.SetHandlePath(filepath.Join(dir, "daemon-session.json"))
+	h, err := sessionMgr.Reattach()
 	if err != nil {
-		return fmt.Errorf("failed to list models: %w", err)
+		slog.Warn("starting a new session", "err", err)
+		return
 	}
-	if len(models) == 0 {
-		return fmt.Errorf("no models available from server")
+	if h != nil {
+		notice("Reattached to the previous session on %s, last used %s\n",
+			sessionMgr.GetCurrentModel(), h.UpdatedAt.Format("Jan 2 15:04"))
 	}
+}
 
-	if err := sessionMgr.DisplayModels(); err != nil {
-		return fmt.Errorf("failed to display models: %w", err)
+// promptLine fills in the prompt template from the config, or shows the
+// model, its multiplier and the tokens left, if known. With color the tokens
+// turn yellow past a context warning threshold and red past the highest one.
+func promptLine(sessionMgr *session.Manager, template string, color bool) string {
+	v := promptline.Values{
+		Model:         sessionMgr.GetCurrentModel(),
+		ModelShort:    models.ShortName(sessionMgr.GetCurrentModel()),
+		Multiplier:    sessionMgr.GetCurrentMultiplier(),
+		Session:       sessionMgr.SessionName(),
+		RateLimitWait: sessionMgr.RateLimitWait(),
+	}
+	if sessionMgr.HasTokenLimit() {
+		v.TokensLeft, v.TokenLimit = sessionMgr.GetTokensLeft(), sessionMgr.GetTokenLimit()
+	}
+	if promptline.Uses(template, "git_branch") {
+		v.GitBranch = git.Branch()
 	}
+	if color {
+		switch sessionMgr.ContextLevel() {
+		case session.ContextWarning:
+			v.Highlight = func(s string) string { return "\x1b[33m" + s + "\x1b[0m" }
+		case session.ContextCritical:
+			v.Highlight = func(s string) string { return "\x1b[1;31m" + s + "\x1b[0m" }
+		}
+	}
+	return promptline.Format(template, v)
+}
 
-	fmt.Printf("Enter model number (current: %s, press Enter to skip): ", sessionMgr.GetCurrentModel())
-	modelInput, _ := reader.ReadString('\n')
-	modelInput = strings.TrimSpace(modelInput)
+// retryQuestion asks whether to resend a prompt whose reply failed with
+// err, or returns "" if err is not worth retrying
+func retryQuestion(err error, sessionMgr *session.Manager) string {
+	switch {
+	case errors.Is(err, session.ErrResponseStalled):
+		return "The response stalled. Retry? [y/N]: "
+	case errors.Is(err, session.ErrRequestTimedOut):
+		return fmt.Sprintf("No reply within %s (see /timeout). Retry? [y/N]: ", sessionMgr.RequestTimeout())
+	default:
+		return ""
+	}
+}
 
-	if modelInput == "" {
+// exitWithError prints a startup error (including any context and suggested
+// fix it carries) to stderr and exits with a non-zero status
+func exitWithError(err error) {
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	os.Exit(1)
+}
+
+// minAutoWrap is the narrowest terminal width used for wrapping; narrower
+// terminals keep the default
+const minAutoWrap = 20
+
+// wordWrap returns the configured wrap column or, when none is set, the
+// terminal's width (0, the renderer's default, if stdout isn't a terminal)
+func wordWrap(cfg *config.Config) int {
+	if cfg.Renderer.WordWrap > 0 {
+		return cfg.Renderer.WordWrap
+	}
+	return terminalWidth()
+}
+
+// terminalWidth returns stdout's terminal width, or 0 if unknown
+func terminalWidth() int {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width < minAutoWrap {
+		return 0
+	}
+	return width
+}
+
+// terminalSize returns stdout's terminal size, or zeros if unknown
+func terminalSize() (int, int) {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		return 0, 0
+	}
+	return width, height
+}
+
+// followTerminalWidth re-wraps output at the new width when the terminal is
+// resized, unless the config fixes the wrap column
+func followTerminalWidth(sessionMgr *session.Manager, cfg *config.Config) {
+	if cfg.Renderer.WordWrap > 0 {
+		return
+	}
+	watchResize(func() {
+		if width := terminalWidth(); width > 0 {
+			sessionMgr.SetWordWrap(width)
+		}
+	})
+}
+
+// cut removes the global flags --style <name>, --server <addr>, --output
+// <path> (each also as --flag=value), --no-color, --debug, --quiet and
+// --verbose from args and sets them on g. Only leading flags are taken unless anywhere is set; a
+// "--" ends the flags either way.
+func (g *globalFlags) cut(args []string, anywhere bool) ([]string, error) {
+	var rest []string
+	for len(args) > 0 {
+		if args[0] == "--" {
+			return append(rest, args[1:]...), nil
+		}
+		n, err := g.parse(args)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			if !anywhere {
+				return args, nil
+			}
+			rest, n = append(rest, args[0]), 1
+		}
+		args = args[n:]
+	}
+	return rest, nil
+}
+
+// parse sets the global flag at the start of args on g and returns how many
+// arguments it took, 0 if args doesn't start with one
+func (g *globalFlags) parse(args []string) (int, error) {
+	switch {
+	case args[0] == "--no-color":
+		g.noColor = true
+		return 1, nil
+	case args[0] == "--debug":
+		g.debug = true
+		return 1, nil
+	case args[0] == "--verbose":
+		g.verbose = true
+		return 1, nil
+	case args[0] == "--quiet":
+		g.quiet = true
+		return 1, nil
+	case args[0] == "--server" || strings.HasPrefix(args[0], "--server="):
+		n := 2
+		if value, ok := strings.CutPrefix(args[0], "--server="); ok {
+			g.server, n = value, 1
+		} else if len(args) < 2 {
+			return 0, fmt.Errorf("--server needs a host:port value")
+		} else {
+			g.server = args[1]
+		}
+		if err := config.CheckServerAddr(g.server); err != nil {
+			return 0, fmt.Errorf("--server: %w", err)
+		}
+		return n, nil
+	case args[0] == "--output" || strings.HasPrefix(args[0], "--output="):
+		if value, ok := strings.CutPrefix(args[0], "--output="); ok {
+			g.output = value
+			return 1, nil
+		}
+		if len(args) < 2 {
+			return 0, fmt.Errorf("--output needs a file path")
+		}
+		g.output = args[1]
+		return 2, nil
+	case args[0] == "--style" || strings.HasPrefix(args[0], "--style="):
+		n := 2
+		if value, ok := strings.CutPrefix(args[0], "--style="); ok {
+			g.style, n = value, 1
+		} else if len(args) < 2 {
+			return 0, fmt.Errorf("--style needs a value (%s, or a .json style file)", strings.Join(config.StyleNames(), ", "))
+		} else {
+			g.style = args[1]
+		}
+		if err := config.CheckStyle(g.style); err != nil {
+			return 0, fmt.Errorf("--style: %w", err)
+		}
+		return n, nil
+	}
+	return 0, nil
+}
+
+// notice prints an informational message, such as a banner, unless --quiet
+// was given
+func notice(format string, args ...any) {
+	if !globals.quiet {
+		fmt.Printf(format, args...)
+	}
+}
+
+// plainOutput reports whether replies should be plain text: with
+// --no-color, or when NO_COLOR is set (https://no-color.org)
+func plainOutput() bool {
+	return globals.noColor || os.Getenv("NO_COLOR") != ""
+}
+
+// loadConfig loads the config and applies the command-line overrides
+func loadConfig() (*config.Config, error) {
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return nil, err
+	}
+	if globals.style != "" {
+		cfg.Renderer.Style = globals.style
+	}
+	if globals.server != "" {
+		cfg.Daemon.Remote = globals.server
+	}
+	if err := setupLogging(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// setupLogging sends the log where the config says, at debug level with
+// --verbose. A log file is written unbuffered, so it is left for the exit
+// to close.
+func setupLogging(cfg *config.Config) error {
+	level, err := logging.ParseLevel(cfg.Log.Level)
+	if err != nil {
+		return err
+	}
+	if globals.verbose {
+		level = slog.LevelDebug
+	} else if globals.quiet {
+		level = max(level, slog.LevelWarn)
+	}
+	_, err = logging.Setup(logging.Options{
+		Level: level,
+		JSON:  cfg.Log.Format == "json",
+		File:  config.ExpandHome(cfg.Log.File),
+	})
+	return err
+}
+
+// newClient connects to the remote daemon when one is configured, and
+// otherwise to the local daemon or an embedded server
+func newClient(cfg *config.Config) (*client.Client, error) {
+	var cli *client.Client
+	var err error
+	if cfg.Daemon.Remote != "" {
+		var tlsOpts *server.TLSOptions
+		if cfg.Daemon.TLS.Enabled {
+			opts := daemonTLS(cfg)
+			tlsOpts = &opts
+		}
+		cli, err = client.NewRemoteClient(cfg.Daemon.Remote, tlsOpts, cfg.Daemon.Token)
+	} else {
+		cli, err = client.NewClient()
+	}
+	if err != nil {
+		return nil, err
+	}
+	cli.SetQuiet(globals.quiet)
+	if dir, err := config.DefaultDir(); err == nil {
+		ttl := client.DefaultModelsCacheTTL
+		if cfg.ModelsCacheTTL != nil {
+			ttl = time.Duration(*cfg.ModelsCacheTTL)
+		}
+		cli.SetModelsCache(filepath.Join(dir, client.ModelsCacheFile), ttl)
+	}
+	return cli, nil
+}
+
+// daemonTLS returns the daemon.tls certificate settings
+func daemonTLS(cfg *config.Config) server.TLSOptions {
+	return server.TLSOptions{
+		CertFile:           cfg.Daemon.TLS.CertFile,
+		KeyFile:            cfg.Daemon.TLS.KeyFile,
+		CAFile:             cfg.Daemon.TLS.CAFile,
+		InsecureSkipVerify: cfg.Daemon.TLS.InsecureSkipVerify,
+	}
+}
+
+// debugLogPath is where --debug and /debug log session events
+func debugLogPath() (string, error) {
+	dir, err := config.DefaultDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "debug.log"), nil
+}
+
+// newSessionManager creates the session manager and applies the config
+func newSessionManager(cli *client.Client, cfg *config.Config) (*session.Manager, error) {
+	rendererOpts := []session.RendererOption{
+		session.WithStyle(cfg.Renderer.Style),
+		session.WithWordWrap(wordWrap(cfg)),
+		session.WithCodeStyle(cfg.Renderer.CodeStyle),
+	}
+	if plainOutput() {
+		rendererOpts = append(rendererOpts, session.WithPlainText())
+	} else if cfg.Renderer.Live && term.IsTerminal(int(os.Stdout.Fd())) {
+		rendererOpts = append(rendererOpts, session.WithLiveRender(terminalSize))
+	}
+	sessionMgr, err := session.NewManager(cli,
+		session.WithModel(cfg.Model),
+		session.WithModelAliases(cfg.ModelAliases),
+		session.WithInstructions(cfg.SystemPrompt),
+		session.WithMCPServers(mcpServers(cfg)),
+		session.WithProjectRoot(projectRoot()),
+		session.WithRendererOptions(rendererOpts...),
+	)
+	if err != nil {
+		return nil, err
+	}
+	sessionMgr.SetContextPolicy(contextPolicy(cfg))
+	sessionMgr.SetSessionPolicy(sessionPolicy(cfg))
+	if dir, err := historyDir(); err == nil {
+		sessionMgr.SetHistoryDir(dir)
+	}
+	sessionMgr.SetCopySafeCode(cfg.Renderer.CopyCode)
+	sessionMgr.SetProgress(os.Stderr, term.IsTerminal(int(os.Stderr.Fd())) && !plainOutput())
+	sessionMgr.SetCalloutStyles(calloutStyles(cfg))
+	if cfg.Session.StallTimeout != nil {
+		sessionMgr.SetStallTimeout(time.Duration(*cfg.Session.StallTimeout))
+	}
+	if cfg.Session.RequestTimeout != nil {
+		sessionMgr.SetRequestTimeout(time.Duration(*cfg.Session.RequestTimeout))
+	}
+	sessionMgr.SetRetryPolicy(retryPolicy(cfg))
+	sessionMgr.SetQuiet(globals.quiet)
+	sessionMgr.AddPromptStage(sessionMgr.AttachImages())
+	sessionMgr.AddPromptStage(sessionMgr.AttachFiles(session.FilePolicy{Allow: cfg.Attachments.Allow}))
+	sessionMgr.SetShellPolicy(shellPolicy(cfg), nil)
+	sessionMgr.SetToolMode(toolMode(cfg))
+	if globals.debug {
+		if path, err := debugLogPath(); err != nil {
+			slog.Warn("session events are not logged", "err", err)
+		} else if err := sessionMgr.StartDebugLog(path); err != nil {
+			slog.Warn("session events are not logged", "err", err)
+		}
+	}
+	if globals.output != "" {
+		if err := sessionMgr.StartTee(config.ExpandHome(globals.output)); err != nil {
+			sessionMgr.Close()
+			return nil, err
+		}
+	}
+	notifyOnTurnComplete(sessionMgr, cfg)
+	if l, err := usageLedger(); err == nil {
+		recordRequests(sessionMgr, l)
+	}
+	return sessionMgr, nil
+}
+
+// runPrint sends one prompt, streams the answer to stdout and returns the
+// process exit code: 0 on success, 1 on failure, 2 for a missing prompt
+// and 130 when cancelled with Ctrl+C. args starts with -p/--print and/or
+// --json; without a prompt after them, the prompt is read from stdin.
+func runPrint(args []string) int {
+	q := question{usage: "usage: cocli -p [--json] <prompt> (or pipe the prompt on stdin)"}
+	for len(args) > 0 && (args[0] == "-p" || args[0] == "--print" || args[0] == "--json") {
+		q.json = q.json || args[0] == "--json"
+		args = args[1:]
+	}
+	q.prompt = strings.Join(args, " ")
+	// -p keeps the exit codes it had before ask told failures apart
+	code := answerOnce(q)
+	if code > exitUsage && code != exitCancelled {
+		return exitFailed
+	}
+	return code
+}
+
+// question is a prompt answered without the interactive loop
+type question struct {
+	prompt string
+	// model overrides the configured model; it may be an alias
+	model string
+	// json streams events instead of rendered markdown
+	json bool
+	// usage is printed when there is no prompt
+	usage string
+	// after, if set, is given the session once the answer is in, and returns
+	// the exit code. The answer is then shown on stderr.
+	after func(sessionMgr *session.Manager) int
+}
+
+// answerOnce sends q, or the prompt piped on stdin when q has none, streams
+// the answer to stdout and returns the exit code for how it went (see
+// exitOK). Piped input given with a prompt is attached to it.
+func answerOnce(q question) int {
+	// Only the answer goes to stdout; connection messages go to stderr
+	out := os.Stdout
+	os.Stdout = os.Stderr
+
+	// fail reports an error on stderr, and on stdout as an error event in
+	// JSON mode
+	fail := func(code int, err error) int {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if q.json {
+			json.NewEncoder(out).Encode(session.JSONEvent{Type: session.EventError, Error: err.Error()})
+		}
+		return code
+	}
+
+	prompt := strings.TrimSpace(q.prompt)
+	fromArgs := prompt != ""
+	if !fromArgs && !term.IsTerminal(int(os.Stdin.Fd())) {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fail(exitFailed, err)
+		}
+		prompt = strings.TrimSpace(string(data))
+	}
+	if prompt == "" {
+		fmt.Fprintln(os.Stderr, q.usage)
+		return exitUsage
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return fail(exitConfig, err)
+	}
+	cli, err := newClient(cfg)
+	if err != nil {
+		return fail(exitCode(err, exitUnavailable), err)
+	}
+	defer cli.Stop()
+	if q.model != "" {
+		list, err := cli.GetModels()
+		if err != nil {
+			err = cli.Diagnose(err)
+			return fail(exitCode(err, exitUnavailable), err)
+		}
+		model, err := models.Match(list, cfg.ModelAliases, q.model)
+		if err != nil {
+			return fail(exitUsage, err)
+		}
+		cfg.Model = model.ID
+	}
+	sessionMgr, err := newSessionManager(cli, cfg)
+	if err != nil {
+		err = cli.Diagnose(err)
+		return fail(exitCode(err, exitUnavailable), err)
+	}
+	if q.json {
+		sessionMgr.SetJSONOutput(out)
+	}
+
+	// Ctrl+C cancels the response; a second one quits
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+	go func() {
+		for sig := range sigChan {
+			if sig == os.Interrupt && sessionMgr.Abort() {
+				continue
+			}
+			os.Exit(exitCancelled)
+		}
+	}()
+
+	if fromArgs && !term.IsTerminal(int(os.Stdin.Fd())) {
+		if err := attachPipedInput(sessionMgr); err != nil {
+			return fail(exitFailed, err)
+		}
+	}
+
+	// In JSON mode stdout carries only the events, so anything else the
+	// session prints stays on stderr
+	if !q.json && q.after == nil {
+		os.Stdout = out
+	}
+	err = sessionMgr.Send(prompt)
+	switch {
+	case err == nil && q.after != nil:
+		os.Stdout = out
+		return q.after(sessionMgr)
+	case err == nil:
+		return exitOK
+	case errors.Is(err, session.ErrResponseCancelled):
+		sessionMgr.WriteJSONError(err)
+		return exitCancelled
+	default:
+		err = cli.Diagnose(err)
+		return fail(exitCode(err, exitFailed), err)
+	}
+}
+
+// pipedInputWarnPercent is the share of the available context above which
+// piped input gets a size warning
+const pipedInputWarnPercent = 50
+
+// attachPipedInput reads stdin and attaches it to the first prompt as
+// context, reporting its size on stderr
+func attachPipedInput(sessionMgr *session.Manager) error {
+	text, truncated, err := input.ReadPiped(os.Stdin, input.MaxPipedBytes)
+	if err != nil {
+		return fmt.Errorf("failed to read stdin: %w", err)
+	}
+	if strings.TrimSpace(text) == "" {
 		return nil
 	}
+	sessionMgr.AttachContext("Piped input", text)
 
-	var modelIdx int
-	_, err = fmt.Sscanf(modelInput, "%d", &modelIdx)
-	if err != nil || modelIdx <= 0 || modelIdx > len(models) {
-		return fmt.Errorf("invalid model selection")
+	tokens := session.EstimateTokens(text)
+	fmt.Fprintf(os.Stderr, "Attached %d lines from stdin (~%d tokens).\n", strings.Count(text, "\n")+1, tokens)
+	if truncated {
+		slog.Warn("stdin was too long; only the beginning was attached", "limit_kib", input.MaxPipedBytes/1024)
+	}
+	if available := sessionMgr.AvailableTokens(); available > 0 && tokens*100 > available*pipedInputWarnPercent {
+		slog.Warn("the piped input fills much of the context window", "tokens", tokens, "available", available, "percent", tokens*100/available)
+	}
+	return nil
+}
+
+// notifyOnTurnComplete sends a summary of every turn that takes at least
+// notify.min_duration to the configured webhook and command
+func notifyOnTurnComplete(sessionMgr *session.Manager, cfg *config.Config) {
+	notifier := &notify.Notifier{Webhook: cfg.Notify.Webhook, Command: cfg.Notify.Command}
+	if !notifier.Enabled() {
+		return
+	}
+	minDuration := time.Duration(cfg.Notify.MinDuration)
+	sessionMgr.OnTurnComplete(func(report session.TurnReport) {
+		if report.Duration() < minDuration {
+			return
+		}
+		if err := notifier.Notify(report); err != nil {
+			slog.Warn("failed to send completion notification", "err", err)
+		}
+	})
+}
+
+// usageLedger returns the ledger of requests across runs, under ~/.cocli
+func usageLedger() (*ledger.Ledger, error) {
+	dir, err := config.DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+	return ledger.New(filepath.Join(dir, ledger.FileName)), nil
+}
+
+// recordRequests appends every request the session sends to the ledger
+func recordRequests(sessionMgr *session.Manager, l *ledger.Ledger) {
+	sessionMgr.OnRequest(func(u session.ModelUsage) {
+		err := l.Append(ledger.Entry{
+			Time:         time.Now(),
+			Model:        u.Model,
+			Multiplier:   u.PremiumRequests,
+			InputTokens:  u.InputTokens,
+			OutputTokens: u.OutputTokens,
+			RateLimited:  u.RateLimits,
+		})
+		if err != nil {
+			slog.Warn("request not recorded in the usage ledger", "err", err)
+		}
+	})
+}
+
+// contextPolicy builds the session's context-usage policy from the config
+func contextPolicy(cfg *config.Config) session.ContextPolicy {
+	policy := session.DefaultContextPolicy()
+	if len(cfg.Context.WarnAt) > 0 {
+		policy.WarnAt = cfg.Context.WarnAt
+	}
+	policy.AutoCompactAt = cfg.Context.AutoCompactAt
+	if cfg.Context.SummarizeBelow != nil {
+		policy.SummarizeBelow = int64(*cfg.Context.SummarizeBelow)
+	}
+	return policy
+}
+
+// calloutStyles converts the callout theme in the config. Colors were
+// checked when the config was validated.
+func calloutStyles(cfg *config.Config) map[string]session.CalloutStyle {
+	styles := make(map[string]session.CalloutStyle, len(cfg.Renderer.Callouts))
+	for kind, theme := range cfg.Renderer.Callouts {
+		style := session.CalloutStyle{Icon: theme.Icon}
+		if theme.Color != "" {
+			style.Color, _ = config.ParseColor(theme.Color)
+		}
+		styles[kind] = style
+	}
+	return styles
+}
+
+// sessionPolicy builds the conversation lifetime policy from the config;
+// archived transcripts go to ~/.cocli/archive
+func sessionPolicy(cfg *config.Config) session.SessionPolicy {
+	policy := session.SessionPolicy{
+		MaxTurns:    cfg.Session.MaxTurns,
+		MaxAge:      time.Duration(cfg.Session.MaxAge),
+		MaxArchives: cfg.Session.MaxArchives,
+	}
+	if dir, err := config.DefaultDir(); err == nil {
+		policy.ArchiveDir = filepath.Join(dir, "archive")
+	}
+	return policy
+}
+
+// historyDir is where conversations are saved, ~/.cocli/sessions
+func historyDir() (string, error) {
+	dir, err := config.DefaultDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "sessions"), nil
+}
+
+// retentionPolicy builds the limits on saved conversations from the config
+func retentionPolicy(cfg *config.Config) session.RetentionPolicy {
+	// max_size was checked when the config was loaded
+	maxBytes, _ := config.ParseSize(cfg.History.MaxSize)
+	return session.RetentionPolicy{
+		MaxSessions: cfg.History.MaxSessions,
+		MaxAge:      time.Duration(cfg.History.MaxAge),
+		MaxBytes:    maxBytes,
+	}
+}
+
+// pruneHistory removes the saved conversations beyond the history limits,
+// apart from those in keep
+func pruneHistory(sessionMgr *session.Manager, cfg *config.Config, keep ...string) {
+	result, err := sessionMgr.PruneHistory(retentionPolicy(cfg), keep...)
+	if err != nil {
+		slog.Warn("old conversations not pruned", "err", err)
+		return
+	}
+	if n := len(result.Removed); n > 0 {
+		notice("Removed %d old conversations (%s) beyond the history limits\n", n, config.FormatSize(result.Freed))
+	}
+}
+
+// retryPolicy builds the policy for retrying transient failures from the
+// config
+func retryPolicy(cfg *config.Config) session.RetryPolicy {
+	policy := session.DefaultRetryPolicy()
+	if cfg.Session.RetryAttempts != nil {
+		policy.Attempts = *cfg.Session.RetryAttempts
+	}
+	if cfg.Session.RetryDelay != nil {
+		policy.Delay = time.Duration(*cfg.Session.RetryDelay)
+	}
+	return policy
+}
+
+// shellPolicy converts the shell settings in the config
+func shellPolicy(cfg *config.Config) session.ShellPolicy {
+	return session.ShellPolicy{
+		Allow:     cfg.Shell.Allow,
+		MaxTokens: int64(cfg.Shell.MaxTokens),
+		Timeout:   time.Duration(cfg.Shell.Timeout),
+	}
+}
+
+// toolMode returns the configured tool mode, ToolsAsk by default. The value
+// was checked when the config was validated.
+func toolMode(cfg *config.Config) session.ToolMode {
+	if mode, err := session.ParseToolMode(cfg.Session.Tools); err == nil {
+		return mode
 	}
+	return session.ToolsAsk
+}
 
-	model := models[modelIdx-1]
-	multiplier := 0.0
-	if model.Billing != nil {
-		multiplier = model.Billing.Multiplier
+// projectRoot returns the root of the repository cocli runs in, or the
+// working directory outside one
+func projectRoot() string {
+	if root, err := git.Root(); err == nil {
+		return root
 	}
+	wd, _ := os.Getwd()
+	return wd
+}
+
+// mcpServers converts the configured MCP servers to the SDK's form
+func mcpServers(cfg *config.Config) map[string]copilot.MCPServerConfig {
+	if len(cfg.MCPServers) == 0 {
+		return nil
+	}
+	servers := make(map[string]copilot.MCPServerConfig, len(cfg.MCPServers))
+	for name, s := range cfg.MCPServers {
+		tools := s.Tools
+		if len(tools) == 0 {
+			tools = []string{"*"}
+		}
+		server := copilot.MCPServerConfig{"tools": tools}
+		if s.Timeout > 0 {
+			server["timeout"] = s.Timeout
+		}
+		if s.Remote() {
+			server["type"] = s.Type
+			server["url"] = s.URL
+			if len(s.Headers) > 0 {
+				server["headers"] = s.Headers
+			}
+		} else {
+			server["type"] = "local"
+			if s.Type != "" {
+				server["type"] = s.Type
+			}
+			server["command"] = s.Command
+			server["args"] = append([]string{}, s.Args...)
+			if len(s.Env) > 0 {
+				server["env"] = s.Env
+			}
+			if s.Cwd != "" {
+				server["cwd"] = s.Cwd
+			}
+		}
+		servers[name] = server
+	}
+	return servers
+}
+
+// runServerForeground runs the copilot server in the foreground until
+// SIGINT/SIGTERM and returns the process exit code
+func runServerForeground(args []string) int {
+	fs := flag.NewFlagSet("cocli server run", flag.ContinueOnError)
+	readyAddr := fs.String("ready-addr", "", "serve /readyz and /livez probes on this address (e.g. :8080)")
+	readyFile := fs.String("ready-file", "", "create this file while the server is healthy")
+	// TLS for clients on other machines defaults to the daemon.tls settings
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	tlsOpts := daemonTLS(cfg)
+	tlsListen := fs.String("tls-listen", cfg.Daemon.TLS.Listen, "accept TLS connections from other machines on this address (e.g. :4443)")
+	// /server start runs the daemon as `cocli server run --managed`
+	managed := fs.Bool("managed", false, "run as the daemon started by /server start")
+	socket := fs.String("socket", "", "private unix socket serving the copilot server to this user (default: one in the daemon's state directory)")
+	port := fs.Int("port", 0, "port for clients on other machines (default: daemon.port, or the user's port)")
+	fs.StringVar(&tlsOpts.CertFile, "tls-cert", tlsOpts.CertFile, "PEM certificate for --tls-listen")
+	fs.StringVar(&tlsOpts.KeyFile, "tls-key", tlsOpts.KeyFile, "PEM key for --tls-cert")
+	fs.StringVar(&tlsOpts.CAFile, "tls-client-ca", tlsOpts.CAFile, "require client certificates signed by this PEM CA bundle")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	dm, err := server.DefaultDaemonManager()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err = dm.RunForeground(ctx, server.ForegroundOptions{
+		ReadyAddr: *readyAddr,
+		ReadyFile: *readyFile,
+		Stderr:    os.Stderr,
+		TLSAddr:   *tlsListen,
+		TLS:       tlsOpts,
+		Managed:   *managed,
+		Socket:    *socket,
+		Port:      *port,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runServerToken prints the running daemon's token and returns the process
+// exit code
+func runServerToken() int {
+	dm, err := server.DefaultDaemonManager()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	token := dm.Token()
+	if token == "" || !dm.IsRunning() {
+		fmt.Fprintln(os.Stderr, "Error: no running daemon with a token; start one with /server start or cocli server run")
+		return 1
+	}
+	fmt.Println(token)
+	return 0
+}
+
+// apiKeySecret names serve-api's key in the secret store
+const apiKeySecret = "serve-api-key"
+
+// runServeAPI serves the OpenAI-compatible API until SIGINT/SIGTERM and
+// returns the process exit code
+func runServeAPI(args []string) int {
+	fs := flag.NewFlagSet("cocli serve-api", flag.ContinueOnError)
+	host := fs.String("host", "127.0.0.1", "address to listen on; anyone who can reach it and has the key can use your Copilot account")
+	port := fs.Int("port", 8080, "port to listen on")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	cli, err := newClient(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer cli.Stop()
+
+	// A new key each start, kept in the secret store while the server runs
+	// so the owner's tools can read it
+	key, err := api.NewAPIKey()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if dir, err := config.DefaultDir(); err == nil {
+		store := secrets.Default(dir)
+		if err := store.Set(apiKeySecret, key); err != nil {
+			slog.Warn("the API key is not in the secret store", "err", err)
+		} else {
+			defer store.Delete(apiKeySecret)
+		}
+	}
+
+	addr := net.JoinHostPort(*host, strconv.Itoa(*port))
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           api.NewServer(api.NewClientBackend(cli), cfg.Model, key),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("Serving the OpenAI-compatible API on http://%s/v1 (Ctrl+C to stop)\n", addr)
+	fmt.Printf("API key (send as \"Authorization: Bearer <key>\"; it changes on every start): %s\n", key)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runStdio serves the JSON-RPC protocol on stdin/stdout until stdin closes
+// and returns the process exit code
+func runStdio() int {
+	// stdout carries the protocol; anything else printed (including by the
+	// client) goes to stderr
+	out := os.Stdout
+	os.Stdout = os.Stderr
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	cli, err := newClient(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer cli.Stop()
+
+	srv := api.NewRPCServer(api.NewClientBackend(cli), cfg.Model, out)
+	if err := srv.Serve(os.Stdin); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runConfigValidate validates the config file at args[0] (or the default
+// path) and returns the process exit code
+func runConfigValidate(args []string) int {
+	var path string
+	if len(args) > 0 {
+		path = args[0]
+	} else {
+		var err error
+		if path, err = config.DefaultPath(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		fmt.Printf("%s: not found (built-in defaults are used)\n", path)
+		return 0
+	}
+	load := config.Load
+	if config.IsProjectFile(path) {
+		load = config.LoadProject
+	}
+	if _, err := load(path); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("%s: OK\n", path)
+	return 0
+}
+
+// promptForModelSelection lets the user pick a model matching filter from a
+// fuzzy-filtered list, or from a numbered menu when the terminal can't show
+// one, and switches to it
+func promptForModelSelection(sessionMgr *session.Manager, editor *input.Editor, filter models.Filter) error {
+	model, err := pickModel(sessionMgr, editor, filter)
+	if errors.Is(err, input.ErrNoPicker) {
+		model, err = pickModelByNumber(sessionMgr, editor, filter)
+	}
+	if err != nil || model == nil {
+		return err
+	}
+	multiplier := models.Multiplier(*model)
 
 	if err := sessionMgr.SetModel(model.ID, multiplier); err != nil {
 		return fmt.Errorf("failed to switch model: %w", err)
 	}
 
-	fmt.Printf("Switched to: %s (%.2fx)\n\n", model.ID, multiplier)
+	rememberModel(model.ID)
+	notice("Switched to: %s (%.2fx)\n\n", model.ID, multiplier)
 	return nil
 }
 
-// handleServerCommand handles /server subcommands
+// pickModel shows the models matching filter in the picker. It returns nil
+// if the user cancels or keeps the current model.
+func pickModel(sessionMgr *session.Manager, editor *input.Editor, filter models.Filter) (*copilot.ModelInfo, error) {
+	list, err := sessionMgr.GetModels()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models: %w", err)
+	}
+	list = filter.Apply(list)
+	if len(list) == 0 {
+		return nil, fmt.Errorf("no models match %s", filter)
+	}
+
+	items := make([]string, len(list))
+	current := 0
+	for i, info := range list {
+		items[i] = models.Describe(info)
+		if info.ID == sessionMgr.GetCurrentModel() {
+			current = i
+		}
+	}
+	idx, err := editor.Pick("Model (type to filter, Esc to skip): ", items, current)
+	if errors.Is(err, input.ErrInterrupted) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if list[idx].ID == sessionMgr.GetCurrentModel() {
+		return nil, nil
+	}
+	return &list[idx], nil
+}
+
+// pickModelByNumber prints the models matching filter and reads the number
+// of one. It returns nil if the user skips.
+func pickModelByNumber(sessionMgr *session.Manager, editor *input.Editor, filter models.Filter) (*copilot.ModelInfo, error) {
+	list, err := sessionMgr.DisplayModels(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models: %w", err)
+	}
+
+	modelInput, _ := editor.ReadLine(fmt.Sprintf("Enter model number (current: %s, press Enter to skip): ", sessionMgr.GetCurrentModel()))
+	modelInput = strings.TrimSpace(modelInput)
+
+	if modelInput == "" {
+		return nil, nil
+	}
+
+	var modelIdx int
+	_, err = fmt.Sscanf(modelInput, "%d", &modelIdx)
+	if err != nil || modelIdx <= 0 || modelIdx > len(list) {
+		return nil, fmt.Errorf("invalid model selection")
+	}
+	return &list[modelIdx-1], nil
+}
+
+// rememberModel saves model in the config file so the next run starts with it
+func rememberModel(model string) {
+	path, err := config.DefaultPath()
+	if err == nil {
+		err = config.SaveModel(path, model)
+	}
+	if err != nil {
+		slog.Warn("failed to save the model as the default", "err", err)
+	}
+}
+
+// confirm asks a yes/no question, defaulting to no
+func (a *app) confirm(question string) bool {
+	answer, err := a.input.ReadLine(question)
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// offerRecovery offers to resume the conversation of a run that crashed or
+// was killed
+func (a *app) offerRecovery(c *session.ConversationInfo) {
+	fmt.Printf("cocli didn't exit cleanly last time. Its conversation %s has %d turns on %s, updated %s", c.ID, c.TurnCount, c.Model, c.UpdatedAt.Format("Jan 2 15:04"))
+	if label := conversationLabel(c); label != "" {
+		fmt.Printf(" (%s)", label)
+	}
+	fmt.Println(".")
+	if !a.confirm("Resume it? [y/N]: ") {
+		fmt.Printf("Use /resume %s to continue it later.\n", c.ID)
+		return
+	}
+	if err := a.sessionMgr.Resume(c.ID); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	fmt.Printf("Resumed conversation %s on %s.\n", c.ID, a.sessionMgr.GetCurrentModel())
+}
+
+// confirmCommand asks before running a !(command) from a prompt
+func (a *app) confirmCommand(command string) bool {
+	return a.confirm(fmt.Sprintf("Run `%s` and send its output? [y/N]: ", command))
+}
+
+// approveTool asks whether the model may run a tool that could change
+// something
+func (a *app) approveTool(req session.ToolRequest) bool {
+	fmt.Printf("The model wants to run a %s tool: %s\n", req.Kind, req.Summary)
+	if req.Intention != "" {
+		fmt.Printf("  (%s)\n", req.Intention)
+	}
+	return a.confirm("Allow it? [y/N]: ")
+}
+
+// chooseOversizeAction warns that a prompt won't fit in the remaining context
+// window and asks the user how to proceed
+func (a *app) chooseOversizeAction(estimated, available int64) (session.OversizeAction, error) {
+	slog.Warn("this prompt doesn't fit in the context window", "tokens", estimated, "remaining", available)
+	fmt.Println("  [h] truncate head (keep the end)")
+	fmt.Println("  [t] truncate tail (keep the beginning)")
+	fmt.Println("  [s] summarize it first")
+	fmt.Println("  [f] attach it as a file")
+	fmt.Println("  [y] send anyway")
+	fmt.Println("  [n] cancel")
+	choice, err := a.input.ReadLine("Choice [n]: ")
+	if err != nil {
+		return session.OversizeCancel, err
+	}
+	switch strings.ToLower(strings.TrimSpace(choice)) {
+	case "h":
+		return session.OversizeTruncateHead, nil
+	case "t":
+		return session.OversizeTruncateTail, nil
+	case "s":
+		return session.OversizeSummarize, nil
+	case "f":
+		return session.OversizeAttachFile, nil
+	case "y":
+		return session.OversizeSend, nil
+	default:
+		return session.OversizeCancel, nil
+	}
+}
+
+// handleServerCommand handles /server subcommands, and `cocli server`
+// ones when cli is nil
 // Returns (shouldExit, error) - shouldExit is true when daemon is stopped and we were using it
-func handleServerCommand(cmd string, usingDaemon bool) (bool, error) {
-	parts := strings.Fields(cmd)
-	if len(parts) < 2 {
+func handleServerCommand(args []string, cli *client.Client) (bool, error) {
+	if len(args) < 1 {
 		printServerHelp()
 		return false, nil
 	}
+	// /server manages this machine's daemon, not a remote one
+	usingDaemon := cli != nil && cli.IsUsingDaemon() && cli.RemoteAddr() == ""
+	inSession := cli != nil
 
 	dm, err := server.DefaultDaemonManager()
 	if err != nil {
 		return false, fmt.Errorf("failed to initialize daemon manager: %w", err)
 	}
 
-	switch parts[1] {
+	switch args[0] {
 	case "start":
 		err := dm.Start()
-		if err == nil && !usingDaemon {
+		if err == nil && inSession && !usingDaemon {
 			fmt.Println("\nNote: This session is using an embedded server.")
 			fmt.Println("Restart the CLI to connect to the daemon.")
 		}
@@ -177,7 +1367,33 @@ func handleServerCommand(cmd string, usingDaemon bool) (bool, error) {
 		// Exit CLI if we were connected to the daemon we just stopped
 		return usingDaemon, nil
 	case "status":
-		return false, printServerStatus(dm)
+		return false, printServerStatus(dm, cli)
+	case "logs":
+		return false, printServerLogs(dm, args[1:])
+	case "install":
+		path, err := dm.InstallService()
+		if err != nil {
+			return false, err
+		}
+		fmt.Printf("Installed %s; the daemon now starts when you log in.\n", path)
+		if inSession && !usingDaemon {
+			fmt.Println("Restart the CLI to connect to it.")
+		}
+		return false, nil
+	case "uninstall":
+		path, err := dm.UninstallService()
+		if err != nil {
+			return false, err
+		}
+		fmt.Printf("Removed %s and stopped the daemon it ran.\n", path)
+		return usingDaemon, nil
+	case "token":
+		token := dm.Token()
+		if token == "" || !dm.IsRunning() {
+			return false, server.ErrDaemonNotRunning
+		}
+		fmt.Println(token)
+		return false, nil
 	case "help":
 		printServerHelp()
 		return false, nil
@@ -187,37 +1403,167 @@ func handleServerCommand(cmd string, usingDaemon bool) (bool, error) {
 	}
 }
 
-// printServerStatus displays the current daemon status
-func printServerStatus(dm *server.DaemonManager) error {
-	status, err := dm.Status()
+// printServerStatus displays the current daemon status, including what the
+// running daemon reports about itself and cli's model cache
+func printServerStatus(dm *server.DaemonManager, cli *client.Client) error {
+	status, err := dm.Inspect()
 	if err != nil {
 		return err
 	}
 
 	if status.Running {
 		fmt.Printf("Daemon: running\n")
-		fmt.Printf("  PID:     %d\n", status.PID)
-		fmt.Printf("  Port:    %d\n", status.Port)
-		fmt.Printf("  Uptime:  %s\n", formatDuration(status.Uptime))
+		fmt.Printf("  PID:      %d\n", status.PID)
+		fmt.Printf("  Port:     %d\n", status.Port)
+		fmt.Printf("  Uptime:   %s\n", formatDuration(status.Uptime))
+		if status.Version != "" {
+			fmt.Printf("  Version:  %s\n", status.Version)
+		}
+		if status.CLIPath != "" {
+			fmt.Printf("  Binary:   %s\n", status.CLIPath)
+		}
+		if status.Clients >= 0 {
+			fmt.Printf("  Clients:  %d connected\n", status.Clients)
+		}
+		fmt.Printf("  Health:   ok (%s)\n", status.HealthLatency.Round(time.Millisecond))
+		// `cocli server status` has no connection, nor models, of its own
+		if cli != nil {
+			if age, ok := cli.ModelsCacheAge(); ok {
+				fmt.Printf("  Models:   cached %s ago\n", formatDuration(age))
+			} else {
+				fmt.Println("  Models:   not fetched yet")
+			}
+		}
+		if status.TLSAddr != "" {
+			fmt.Printf("  TLS:      %s\n", status.TLSAddr)
+		}
+		if status.Restarts > 0 {
+			fmt.Printf("  Restarts: %d (after crashes)\n", status.Restarts)
+		}
 	} else {
 		fmt.Println("Daemon: not running")
-		fmt.Println("\nStart the daemon with: /server start")
+		if status.LastCrash != nil {
+			printCrashReport(status.LastCrash)
+			if path := dm.LogPath(); path != "" {
+				fmt.Printf("  Log:      %s\n", path)
+			}
+		}
+		fmt.Println("\nStart the daemon with: /server start (or cocli server start)")
 	}
 	return nil
 }
 
+// printCrashReport shows why the daemon last exited and its final stderr lines
+func printCrashReport(crash *server.CrashReport) {
+	fmt.Printf("  Crashed: %s (PID %d, %s)\n", crash.Reason(), crash.PID, crash.ExitedAt.Format("2006-01-02 15:04:05"))
+	if len(crash.Stderr) > 0 {
+		fmt.Println("  Last output:")
+		for _, line := range crash.Stderr {
+			fmt.Printf("    %s\n", line)
+		}
+	}
+}
+
+// defaultLogLines is how many lines /server logs shows without -n
+const defaultLogLines = 50
+
+// printServerLogs prints the tail of the daemon log and, with -f, follows
+// it until Ctrl+C
+func printServerLogs(dm *server.DaemonManager, args []string) error {
+	fs := flag.NewFlagSet("/server logs", flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	follow := fs.Bool("f", false, "keep printing new output until Ctrl+C")
+	n := fs.Int("n", defaultLogLines, "number of lines to show")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	lines, err := dm.Logs(*n)
+	if err != nil && !(*follow && errors.Is(err, server.ErrNoLog) && dm.LogPath() != "") {
+		return err
+	}
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+	if !*follow {
+		return nil
+	}
+
+	fmt.Printf("Following %s; press Ctrl+C to stop.\n", dm.LogPath())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	onInterrupt.Store(&cancel)
+	defer onInterrupt.Store(nil)
+	return dm.FollowLogs(ctx, os.Stdout)
+}
+
+// onInterrupt, when set, is called on Ctrl+C instead of quitting, e.g. to
+// stop following the daemon log
+var onInterrupt atomic.Pointer[context.CancelFunc]
+
+// interrupt runs and clears onInterrupt, reporting whether it was set
+func interrupt() bool {
+	if cancel := onInterrupt.Swap(nil); cancel != nil {
+		(*cancel)()
+		return true
+	}
+	return false
+}
+
+// superviseDaemon restarts the daemon if it crashes while this session is
+// connected to it, within the configured restart budget
+func superviseDaemon(cfg *config.Config) {
+	dm, err := server.DefaultDaemonManager()
+	if err != nil {
+		return
+	}
+	policy := server.DefaultRestartPolicy()
+	if cfg.Daemon.MaxRestarts != nil {
+		policy.MaxRestarts = *cfg.Daemon.MaxRestarts
+	}
+	if cfg.Daemon.RestartWindow > 0 {
+		policy.Window = time.Duration(cfg.Daemon.RestartWindow)
+	}
+	go server.NewSupervisor(dm, policy, reportRestart).Run(context.Background())
+}
+
+// reportRestart tells the user the daemon died and whether it is back. It
+// may interrupt the prompt, so it starts on a new line.
+func reportRestart(e server.RestartEvent) {
+	const fix = "restart cocli and /resume the conversation"
+	fmt.Fprint(os.Stderr, "\r\n")
+	switch {
+	case e.Err != nil:
+		slog.Warn("the background daemon died and was not restarted", "reason", e.Crash.Reason(), "restarts", e.Restarts, "err", e.Err, "fix", "check /server logs, then /server start")
+	case e.Crash != nil:
+		slog.Warn("the background daemon died and was restarted; this session lost its connection", "reason", e.Crash.Reason(), "pid", e.PID, "restarts", e.Restarts, "fix", fix)
+	default:
+		slog.Warn("the background daemon was restarted after a crash; this session lost its connection", "pid", e.PID, "restarts", e.Restarts, "fix", fix)
+	}
+}
+
 // printServerHelp displays help for server commands
 func printServerHelp() {
-	fmt.Println("Usage: /server <command>")
+	fmt.Println("Usage: /server <command>, or cocli server <command> from a shell")
 	fmt.Println("")
 	fmt.Println("Commands:")
-	fmt.Printf("  start   Start the background daemon (port %d)\n", server.DefaultPort)
-	fmt.Println("  stop    Stop the daemon")
-	fmt.Println("  status  Show daemon status")
-	fmt.Println("  help    Show this help")
+	fmt.Printf("  start      Start the background daemon (port %d)\n", server.UserPort())
+	fmt.Println("  stop       Stop the daemon")
+	fmt.Println("  status     Show daemon status")
+	fmt.Printf("  logs       Show the last lines of the daemon log (-n N, default %d; -f to follow)\n", defaultLogLines)
+	fmt.Println("  install    Run the daemon on login (systemd user unit or launchd agent)")
+	fmt.Println("  uninstall  Remove that service and stop its daemon")
+	fmt.Println("  token      Print the token clients on other machines present")
+	fmt.Println("  help       Show this help")
 	fmt.Println("")
 	fmt.Println("When the daemon is running, cocli will connect to it")
 	fmt.Println("instead of starting a new server, making startup faster.")
+	fmt.Println("")
+	fmt.Println("To run the server in the foreground (e.g. in a container), use:")
+	fmt.Println("  cocli server run [--ready-addr :8080] [--ready-file /tmp/cocli-ready]")
 }
 
 // formatDuration formats a duration in a human-readable way
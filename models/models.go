@@ -0,0 +1,315 @@
+// Package models resolves the model names users type — IDs, display names
+// and configured aliases — against the models the server offers.
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+// Aliases maps short names (e.g. "fast") to a model ID or name
+type Aliases map[string]string
+
+// Expand returns the model an alias stands for, matching the alias case
+// insensitively, or name unchanged if it isn't an alias
+func (a Aliases) Expand(name string) string {
+	if target, ok := a[name]; ok {
+		return target
+	}
+	for alias, target := range a {
+		if strings.EqualFold(alias, name) {
+			return target
+		}
+	}
+	return name
+}
+
+// Names returns the aliases in sorted order
+func (a Aliases) Names() []string {
+	names := make([]string, 0, len(a))
+	for alias := range a {
+		names = append(names, alias)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Find looks up a model by its ID or, case insensitively, its name
+func Find(list []copilot.ModelInfo, name string) (copilot.ModelInfo, bool) {
+	for _, info := range list {
+		if info.ID == name || strings.EqualFold(info.Name, name) {
+			return info, true
+		}
+	}
+	return copilot.ModelInfo{}, false
+}
+
+// Resolve expands name if it is an alias and finds the model it refers to
+func Resolve(list []copilot.ModelInfo, aliases Aliases, name string) (copilot.ModelInfo, error) {
+	target := aliases.Expand(name)
+	if info, ok := Find(list, target); ok {
+		return info, nil
+	}
+	if target != name {
+		return copilot.ModelInfo{}, fmt.Errorf("alias %q refers to unknown model %q", name, target)
+	}
+	return copilot.ModelInfo{}, fmt.Errorf("unknown model %q", name)
+}
+
+// Match resolves name like Resolve, falling back to the model whose ID or
+// name starts with name, then to the model whose ID or name contains every
+// word of name. Either fallback must find exactly one model.
+func Match(list []copilot.ModelInfo, aliases Aliases, name string) (copilot.ModelInfo, error) {
+	info, err := Resolve(list, aliases, name)
+	if err == nil || aliases.Expand(name) != name {
+		return info, err
+	}
+	query := strings.ToLower(strings.TrimSpace(name))
+	if query == "" {
+		return copilot.ModelInfo{}, err
+	}
+	for _, match := range []func(text string) bool{
+		func(text string) bool { return strings.HasPrefix(text, query) },
+		func(text string) bool {
+			for _, word := range strings.FieldsFunc(query, isSeparator) {
+				if !strings.Contains(text, word) {
+					return false
+				}
+			}
+			return true
+		},
+	} {
+		var found []copilot.ModelInfo
+		for _, info := range list {
+			if match(strings.ToLower(info.ID)) || match(strings.ToLower(info.Name)) {
+				found = append(found, info)
+			}
+		}
+		switch len(found) {
+		case 0:
+			continue
+		case 1:
+			return found[0], nil
+		}
+		ids := make([]string, len(found))
+		for i, info := range found {
+			ids[i] = info.ID
+		}
+		return copilot.ModelInfo{}, fmt.Errorf("%q matches several models: %s", name, strings.Join(ids, ", "))
+	}
+	return copilot.ModelInfo{}, err
+}
+
+// isSeparator splits a model query into words
+func isSeparator(r rune) bool {
+	return r == ' ' || r == '-' || r == '_'
+}
+
+// ShortName abbreviates a model ID or display name for tight spaces such as
+// the prompt: "claude-sonnet-4.5" and "Claude Sonnet 4.5" become
+// "sonnet-4.5". The "claude-" family prefix and "-preview" suffixes are
+// dropped; other names are only lowercased.
+func ShortName(model string) string {
+	s := strings.ToLower(strings.Join(strings.Fields(model), "-"))
+	s = strings.TrimPrefix(s, "claude-")
+	return strings.TrimSuffix(s, "-preview")
+}
+
+// Multiplier returns a model's premium request multiplier, or 0 if it has
+// no billing information
+func Multiplier(info copilot.ModelInfo) float64 {
+	if info.Billing == nil {
+		return 0
+	}
+	return info.Billing.Multiplier
+}
+
+// Cheapest returns the model with the lowest multiplier, the first of them
+// on a tie. Models without billing information are skipped.
+func Cheapest(list []copilot.ModelInfo) (copilot.ModelInfo, bool) {
+	var cheapest copilot.ModelInfo
+	found := false
+	for _, info := range list {
+		if info.Billing == nil {
+			continue
+		}
+		if !found || info.Billing.Multiplier < cheapest.Billing.Multiplier {
+			cheapest, found = info, true
+		}
+	}
+	return cheapest, found
+}
+
+// Complete returns the aliases and model IDs starting with prefix, aliases
+// first
+func Complete(list []copilot.ModelInfo, aliases Aliases, prefix string) []string {
+	var matches []string
+	for _, alias := range aliases.Names() {
+		if strings.HasPrefix(alias, prefix) {
+			matches = append(matches, alias)
+		}
+	}
+	for _, info := range list {
+		if strings.HasPrefix(info.ID, prefix) {
+			matches = append(matches, info.ID)
+		}
+	}
+	return matches
+}
+
+// CheapMultiplier is the premium request multiplier cheap models are billed
+// below
+const CheapMultiplier = 1.0
+
+// Filter narrows a model list to the models with every set property
+type Filter struct {
+	// Vision keeps models that accept images
+	Vision bool
+	// Cheap keeps models billed below CheapMultiplier
+	Cheap bool
+}
+
+// ParseFilter reads --vision and --cheap flags
+func ParseFilter(flags []string) (Filter, error) {
+	var f Filter
+	for _, flag := range flags {
+		switch flag {
+		case "--vision":
+			f.Vision = true
+		case "--cheap":
+			f.Cheap = true
+		default:
+			return Filter{}, fmt.Errorf("unknown filter %q (use --vision or --cheap)", flag)
+		}
+	}
+	return f, nil
+}
+
+// Match reports whether info has every property the filter asks for
+func (f Filter) Match(info copilot.ModelInfo) bool {
+	if f.Vision && !info.Capabilities.Supports.Vision {
+		return false
+	}
+	if f.Cheap && Multiplier(info) >= CheapMultiplier {
+		return false
+	}
+	return true
+}
+
+// Apply returns the models in list that match the filter, in order
+func (f Filter) Apply(list []copilot.ModelInfo) []copilot.ModelInfo {
+	var matches []copilot.ModelInfo
+	for _, info := range list {
+		if f.Match(info) {
+			matches = append(matches, info)
+		}
+	}
+	return matches
+}
+
+// String describes the filter as the flags that set it
+func (f Filter) String() string {
+	var flags []string
+	if f.Vision {
+		flags = append(flags, "--vision")
+	}
+	if f.Cheap {
+		flags = append(flags, "--cheap")
+	}
+	return strings.Join(flags, " ")
+}
+
+// Badges describes a model's capabilities, such as "vision" and
+// "128k context"
+func Badges(info copilot.ModelInfo) []string {
+	var badges []string
+	if info.Capabilities.Supports.Vision {
+		badges = append(badges, "vision")
+	}
+	if n := info.Capabilities.Limits.MaxContextWindowTokens; n > 0 {
+		badges = append(badges, formatTokens(n)+" context")
+	}
+	return badges
+}
+
+// Describe summarizes a model in one line: its name, ID, multiplier and
+// badges
+func Describe(info copilot.ModelInfo) string {
+	line := fmt.Sprintf("%s (ID: %s)", info.Name, info.ID)
+	if info.Billing != nil {
+		line += fmt.Sprintf(" (%.2fx)", info.Billing.Multiplier)
+	}
+	if badges := Badges(info); len(badges) > 0 {
+		line += " [" + strings.Join(badges, ", ") + "]"
+	}
+	return line
+}
+
+// Details describes everything known about a model, one "Label: value"
+// line per property
+func Details(info copilot.ModelInfo) string {
+	var b strings.Builder
+	line := func(label, value string) {
+		fmt.Fprintf(&b, "%-16s %s\n", label+":", value)
+	}
+	line("ID", info.ID)
+	line("Name", info.Name)
+	if info.Billing != nil {
+		line("Multiplier", fmt.Sprintf("%.2fx", info.Billing.Multiplier))
+	} else {
+		line("Multiplier", "unknown")
+	}
+
+	limits := info.Capabilities.Limits
+	if limits.MaxContextWindowTokens > 0 {
+		line("Context window", fmt.Sprintf("%d tokens", limits.MaxContextWindowTokens))
+	}
+	if limits.MaxPromptTokens != nil {
+		line("Max prompt", fmt.Sprintf("%d tokens", *limits.MaxPromptTokens))
+	}
+
+	if !info.Capabilities.Supports.Vision {
+		line("Vision", "no")
+	} else if v := limits.Vision; v == nil {
+		line("Vision", "yes")
+	} else {
+		var parts []string
+		if len(v.SupportedMediaTypes) > 0 {
+			parts = append(parts, strings.Join(v.SupportedMediaTypes, ", "))
+		}
+		if v.MaxPromptImages > 0 {
+			parts = append(parts, fmt.Sprintf("up to %d per prompt", v.MaxPromptImages))
+		}
+		if v.MaxPromptImageSize > 0 {
+			parts = append(parts, fmt.Sprintf("%d KiB each", v.MaxPromptImageSize>>10))
+		}
+		value := "yes"
+		if len(parts) > 0 {
+			value += " (" + strings.Join(parts, "; ") + ")"
+		}
+		line("Vision", value)
+	}
+
+	if info.Policy != nil {
+		line("Policy", info.Policy.State)
+		if info.Policy.Terms != "" {
+			line("Terms", info.Policy.Terms)
+		}
+	}
+	return b.String()
+}
+
+// formatTokens abbreviates a token count, such as 128k or 1M
+func formatTokens(n int) string {
+	switch {
+	case n >= 1_000_000 && n%100_000 == 0:
+		return strconv.FormatFloat(float64(n)/1_000_000, 'f', -1, 64) + "M"
+	case n >= 1000:
+		return strconv.Itoa((n+500)/1000) + "k"
+	}
+	return strconv.Itoa(n)
+}
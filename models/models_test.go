@@ -0,0 +1,247 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+var testModels = []copilot.ModelInfo{
+	{ID: "claude-haiku-4.5", Name: "Claude Haiku 4.5", Billing: &copilot.ModelBilling{Multiplier: 0.33}},
+	{ID: "claude-opus-4.5", Name: "Claude Opus 4.5", Billing: &copilot.ModelBilling{Multiplier: 3}},
+	{ID: "gpt-4.1", Name: "GPT-4.1"},
+}
+
+var testAliases = Aliases{
+	"fast":  "claude-haiku-4.5",
+	"smart": "Claude Opus 4.5",
+	"old":   "gpt-3",
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantID  string
+		wantErr string
+	}{
+		{name: "id", input: "gpt-4.1", wantID: "gpt-4.1"},
+		{name: "display name", input: "claude opus 4.5", wantID: "claude-opus-4.5"},
+		{name: "alias to id", input: "fast", wantID: "claude-haiku-4.5"},
+		{name: "alias to name", input: "smart", wantID: "claude-opus-4.5"},
+		{name: "alias any case", input: "FAST", wantID: "claude-haiku-4.5"},
+		{name: "alias to unknown model", input: "old", wantErr: `alias "old" refers to unknown model "gpt-3"`},
+		{name: "unknown", input: "nope", wantErr: `unknown model "nope"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Resolve(testModels, testAliases, tt.input)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Resolve() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			if got.ID != tt.wantID {
+				t.Errorf("Resolve() = %q, want %q", got.ID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	list := append(testModels, copilot.ModelInfo{ID: "claude-sonnet-4.5", Name: "Claude Sonnet 4.5"})
+	tests := []struct {
+		name    string
+		input   string
+		wantID  string
+		wantErr string
+	}{
+		{name: "exact id", input: "gpt-4.1", wantID: "gpt-4.1"},
+		{name: "alias", input: "fast", wantID: "claude-haiku-4.5"},
+		{name: "id prefix", input: "claude-o", wantID: "claude-opus-4.5"},
+		{name: "name prefix", input: "GPT", wantID: "gpt-4.1"},
+		{name: "word", input: "sonnet", wantID: "claude-sonnet-4.5"},
+		{name: "words in any order", input: "opus claude", wantID: "claude-opus-4.5"},
+		{name: "ambiguous prefix", input: "claude", wantErr: `"claude" matches several models: claude-haiku-4.5, claude-opus-4.5, claude-sonnet-4.5`},
+		{name: "ambiguous word", input: "4.5", wantErr: "matches several models"},
+		{name: "broken alias is not guessed", input: "old", wantErr: `alias "old" refers to unknown model "gpt-3"`},
+		{name: "no match", input: "llama", wantErr: `unknown model "llama"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Match(list, testAliases, tt.input)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Match() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Match() error = %v", err)
+			}
+			if got.ID != tt.wantID {
+				t.Errorf("Match() = %q, want %q", got.ID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestMultiplier(t *testing.T) {
+	if got := Multiplier(testModels[1]); got != 3 {
+		t.Errorf("Multiplier(opus) = %v, want 3", got)
+	}
+	if got := Multiplier(testModels[2]); got != 0 {
+		t.Errorf("Multiplier(no billing) = %v, want 0", got)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{prefix: "", want: []string{"fast", "old", "smart", "claude-haiku-4.5", "claude-opus-4.5", "gpt-4.1"}},
+		{prefix: "s", want: []string{"smart"}},
+		{prefix: "claude-o", want: []string{"claude-opus-4.5"}},
+		{prefix: "x", want: nil},
+	}
+	for _, tt := range tests {
+		if got := Complete(testModels, testAliases, tt.prefix); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Complete(%q) = %v, want %v", tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	vision := copilot.ModelInfo{ID: "gpt-4.1", Billing: &copilot.ModelBilling{Multiplier: 1}}
+	vision.Capabilities.Supports.Vision = true
+	list := append([]copilot.ModelInfo{vision}, testModels...)
+
+	tests := []struct {
+		flags   []string
+		want    []string
+		wantErr bool
+	}{
+		{flags: nil, want: []string{"gpt-4.1", "claude-haiku-4.5", "claude-opus-4.5", "gpt-4.1"}},
+		{flags: []string{"--vision"}, want: []string{"gpt-4.1"}},
+		{flags: []string{"--cheap"}, want: []string{"claude-haiku-4.5", "gpt-4.1"}},
+		{flags: []string{"--vision", "--cheap"}, want: nil},
+		{flags: []string{"--fast"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		f, err := ParseFilter(tt.flags)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseFilter(%v) expected error", tt.flags)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("ParseFilter(%v) error = %v", tt.flags, err)
+		}
+		if got := strings.Join(tt.flags, " "); f.String() != got {
+			t.Errorf("Filter.String() = %q, want %q", f.String(), got)
+		}
+		var ids []string
+		for _, info := range f.Apply(list) {
+			ids = append(ids, info.ID)
+		}
+		if !reflect.DeepEqual(ids, tt.want) {
+			t.Errorf("Apply(%v) = %v, want %v", tt.flags, ids, tt.want)
+		}
+	}
+}
+
+func TestBadges(t *testing.T) {
+	tests := []struct {
+		vision  bool
+		context int
+		want    []string
+	}{
+		{vision: true, context: 128000, want: []string{"vision", "128k context"}},
+		{context: 200000, want: []string{"200k context"}},
+		{context: 1000000, want: []string{"1M context"}},
+		{context: 64500, want: []string{"65k context"}},
+		{want: nil},
+	}
+	for _, tt := range tests {
+		var info copilot.ModelInfo
+		info.Capabilities.Supports.Vision = tt.vision
+		info.Capabilities.Limits.MaxContextWindowTokens = tt.context
+		if got := Badges(info); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Badges(vision=%v, context=%d) = %v, want %v", tt.vision, tt.context, got, tt.want)
+		}
+	}
+}
+
+func TestDetails(t *testing.T) {
+	maxPrompt := 64000
+	info := copilot.ModelInfo{
+		ID:      "gpt-4.1",
+		Name:    "GPT-4.1",
+		Billing: &copilot.ModelBilling{Multiplier: 0},
+		Policy:  &copilot.ModelPolicy{State: "enabled", Terms: "Enable access to GPT-4.1"},
+	}
+	info.Capabilities.Supports.Vision = true
+	info.Capabilities.Limits.MaxContextWindowTokens = 128000
+	info.Capabilities.Limits.MaxPromptTokens = &maxPrompt
+	info.Capabilities.Limits.Vision = &copilot.ModelVisionLimits{
+		SupportedMediaTypes: []string{"image/png", "image/jpeg"},
+		MaxPromptImages:     1,
+		MaxPromptImageSize:  3 << 20,
+	}
+
+	want := `ID:              gpt-4.1
+Name:            GPT-4.1
+Multiplier:      0.00x
+Context window:  128000 tokens
+Max prompt:      64000 tokens
+Vision:          yes (image/png, image/jpeg; up to 1 per prompt; 3072 KiB each)
+Policy:          enabled
+Terms:           Enable access to GPT-4.1
+`
+	if got := Details(info); got != want {
+		t.Errorf("Details() =\n%s\nwant\n%s", got, want)
+	}
+
+	bare := Details(copilot.ModelInfo{ID: "x", Name: "X"})
+	for _, line := range []string{"Multiplier:      unknown\n", "Vision:          no\n"} {
+		if !strings.Contains(bare, line) {
+			t.Errorf("Details() = %q, want it to contain %q", bare, line)
+		}
+	}
+	if strings.Contains(bare, "Policy") || strings.Contains(bare, "Context") {
+		t.Errorf("Details() shows unknown properties: %q", bare)
+	}
+}
+
+func TestShortName(t *testing.T) {
+	tests := map[string]string{
+		"claude-sonnet-4.5":      "sonnet-4.5",
+		"Claude Sonnet 4.5":      "sonnet-4.5",
+		"gpt-4.1":                "gpt-4.1",
+		"gemini-3-pro-preview":   "gemini-3-pro",
+		"  Claude  Haiku  4.5  ": "haiku-4.5",
+	}
+	for model, want := range tests {
+		if got := ShortName(model); got != want {
+			t.Errorf("ShortName(%q) = %q, want %q", model, got, want)
+		}
+	}
+}
+
+func TestCheapest(t *testing.T) {
+	got, ok := Cheapest(testModels)
+	if !ok || got.ID != "claude-haiku-4.5" {
+		t.Errorf("Cheapest() = %q, %v, want claude-haiku-4.5", got.ID, ok)
+	}
+	if _, ok := Cheapest([]copilot.ModelInfo{{ID: "gpt-4.1"}}); ok {
+		t.Error("Cheapest() found a model without billing information")
+	}
+}
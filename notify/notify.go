@@ -0,0 +1,102 @@
+// Package notify tells other programs (CI pipelines, chat bots) that a
+// cocli run has finished, by POSTing a JSON summary to a webhook or piping
+// it to a command.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// DefaultTimeout bounds how long a notification may take
+const DefaultTimeout = 10 * time.Second
+
+// Notifier delivers run summaries. Either or both of Webhook and Command
+// may be set; a zero Notifier does nothing.
+type Notifier struct {
+	// Webhook is an http(s) URL the summary is POSTed to as JSON
+	Webhook string
+	// Command is a shell command that receives the summary on stdin
+	Command string
+	// Timeout bounds each delivery; 0 means DefaultTimeout
+	Timeout time.Duration
+}
+
+// Enabled reports whether the notifier has anywhere to deliver to
+func (n *Notifier) Enabled() bool {
+	return n.Webhook != "" || n.Command != ""
+}
+
+// Notify sends summary, marshalled as JSON, to the webhook and the command.
+// It returns the first delivery error after trying both.
+func (n *Notifier) Notify(summary interface{}) error {
+	if !n.Enabled() {
+		return nil
+	}
+	body, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+
+	timeout := n.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var firstErr error
+	if n.Webhook != "" {
+		firstErr = n.post(ctx, body)
+	}
+	if n.Command != "" {
+		if err := n.run(ctx, body); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// post sends body to the webhook
+func (n *Notifier) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.Webhook, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "cocli")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook: %s returned %s", n.Webhook, resp.Status)
+	}
+	return nil
+}
+
+// run pipes body to the command through the platform shell
+func (n *Notifier) run(ctx context.Context, body []byte) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", n.Command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", n.Command)
+	}
+	cmd.Stdin = bytes.NewReader(body)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if len(out) > 0 {
+			return fmt.Errorf("notify command: %w: %s", err, bytes.TrimSpace(out))
+		}
+		return fmt.Errorf("notify command: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+type summary struct {
+	Status string `json:"status"`
+}
+
+func TestNotify_Webhook(t *testing.T) {
+	var got summary
+	var contentType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer srv.Close()
+
+	n := &Notifier{Webhook: srv.URL}
+	if err := n.Notify(summary{Status: "ok"}); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if got.Status != "ok" || contentType != "application/json" {
+		t.Errorf("webhook got %+v as %q, want the JSON summary", got, contentType)
+	}
+}
+
+func TestNotify_WebhookError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	n := &Notifier{Webhook: srv.URL}
+	if err := n.Notify(summary{}); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("Notify() error = %v, want the status reported", err)
+	}
+}
+
+func TestNotify_Command(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	out := filepath.Join(t.TempDir(), "summary.json")
+	n := &Notifier{Command: "cat > " + out}
+	if err := n.Notify(summary{Status: "error"}); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"status":"error"}` {
+		t.Errorf("command stdin = %s, want the JSON summary", data)
+	}
+
+	n = &Notifier{Command: "echo broken >&2; exit 3"}
+	if err := n.Notify(summary{}); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("Notify() error = %v, want the command's output", err)
+	}
+}
+
+func TestNotify_Disabled(t *testing.T) {
+	var n Notifier
+	if n.Enabled() || n.Notify(summary{}) != nil {
+		t.Error("a zero Notifier should do nothing")
+	}
+}
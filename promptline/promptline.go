@@ -0,0 +1,166 @@
+// Package promptline formats the interactive prompt, either in the built-in
+// "[model | multiplier | tokens] > " layout or from a template with
+// {placeholders} set in the config.
+package promptline
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Placeholders lists what a template may contain, each standing for:
+//
+//	{model}        the current model's ID
+//	{model_short}  the model's short name, such as "sonnet-4.5"
+//	{multiplier}   its premium request multiplier, such as "1.00"
+//	{tokens}       tokens left of the context window, such as "96000/128000"
+//	{tokens_left}  tokens left of the context window
+//	{token_limit}  size of the context window
+//	{tokens_pct}   percentage of the context window used
+//	{session}      the session name, "" for unnamed sessions
+//	{git_branch}   the current git branch, "" outside a repository
+//	{rate_limit}   time left of a rate limit, such as "42s", "" when none
+var Placeholders = []string{
+	"model", "model_short", "multiplier",
+	"tokens", "tokens_left", "token_limit", "tokens_pct",
+	"session", "git_branch", "rate_limit",
+}
+
+// placeholderRegex matches a {placeholder} in a template
+var placeholderRegex = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// Values are what the placeholders stand for
+type Values struct {
+	Model      string
+	ModelShort string
+	Multiplier float64
+	// TokensLeft and TokenLimit describe the context window; TokenLimit is
+	// 0 until the server reports it, and the token placeholders show "?"
+	TokensLeft int64
+	TokenLimit int64
+	Session    string
+	GitBranch  string
+	// RateLimitWait is how long until the API takes prompts again after
+	// throttling them, 0 when it isn't
+	RateLimitWait time.Duration
+	// Highlight, if set, wraps the token placeholders, such as to color
+	// them as the context window fills up
+	Highlight func(string) string
+}
+
+// Check reports whether template uses only known placeholders
+func Check(template string) error {
+	for _, m := range placeholderRegex.FindAllStringSubmatch(template, -1) {
+		if !known(m[1]) {
+			return fmt.Errorf("unknown placeholder {%s} (use %s)", m[1], list())
+		}
+	}
+	return nil
+}
+
+// Uses reports whether template contains {name}, so values that are costly
+// to find, such as the git branch, are only found when shown
+func Uses(template, name string) bool {
+	return strings.Contains(template, "{"+name+"}")
+}
+
+// Format fills in template, or lays out the built-in prompt when template
+// is "". Unknown placeholders are left as they are.
+func Format(template string, v Values) string {
+	if template == "" {
+		return builtin(v)
+	}
+	return placeholderRegex.ReplaceAllStringFunc(template, func(p string) string {
+		name := p[1 : len(p)-1]
+		switch name {
+		case "model":
+			return v.Model
+		case "model_short":
+			return v.ModelShort
+		case "multiplier":
+			return fmt.Sprintf("%.2f", v.Multiplier)
+		case "tokens":
+			return v.highlight(v.tokens("%d/%d", v.TokensLeft, v.TokenLimit))
+		case "tokens_left":
+			return v.highlight(v.tokens("%d", v.TokensLeft))
+		case "token_limit":
+			return v.highlight(v.tokens("%d", v.TokenLimit))
+		case "tokens_pct":
+			if v.TokenLimit <= 0 {
+				return v.highlight("?")
+			}
+			used := v.TokenLimit - v.TokensLeft
+			return v.highlight(strconv.FormatInt(used*100/v.TokenLimit, 10))
+		case "session":
+			return v.Session
+		case "git_branch":
+			return v.GitBranch
+		case "rate_limit":
+			return v.rateLimit()
+		}
+		return p
+	})
+}
+
+// builtin is the prompt used without a template, such as
+// "work [claude-sonnet-4.5 | 1.00x | 96000/128000 tokens] > ", with
+// "| rate limited 42s" before the "]" while throttled
+func builtin(v Values) string {
+	parts := []string{v.Model, fmt.Sprintf("%.2fx", v.Multiplier)}
+	if v.TokenLimit > 0 {
+		parts = append(parts, v.highlight(fmt.Sprintf("%d/%d tokens", v.TokensLeft, v.TokenLimit)))
+	}
+	if wait := v.rateLimit(); wait != "" {
+		parts = append(parts, "rate limited "+wait)
+	}
+	line := "[" + strings.Join(parts, " | ") + "] > "
+	if v.Session != "" {
+		line = v.Session + " " + line
+	}
+	return line
+}
+
+// rateLimit formats the rate limit's wait to the second, rounding up so
+// it never shows 0s while still throttled
+func (v Values) rateLimit() string {
+	if v.RateLimitWait <= 0 {
+		return ""
+	}
+	return ((v.RateLimitWait + time.Second - 1) / time.Second * time.Second).String()
+}
+
+// tokens formats token counts, or "?" while the limit is unknown
+func (v Values) tokens(format string, args ...any) string {
+	if v.TokenLimit <= 0 {
+		return "?"
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+func (v Values) highlight(s string) string {
+	if v.Highlight == nil {
+		return s
+	}
+	return v.Highlight(s)
+}
+
+func known(name string) bool {
+	for _, p := range Placeholders {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// list names the placeholders for error messages
+func list() string {
+	names := make([]string, len(Placeholders))
+	for i, p := range Placeholders {
+		names[i] = "{" + p + "}"
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,67 @@
+package promptline
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormat(t *testing.T) {
+	v := Values{
+		Model:      "claude-sonnet-4.5",
+		ModelShort: "sonnet-4.5",
+		Multiplier: 1,
+		TokensLeft: 96000,
+		TokenLimit: 128000,
+		Session:    "work",
+		GitBranch:  "main",
+	}
+	unknown := v
+	unknown.TokensLeft, unknown.TokenLimit, unknown.Session = 0, 0, ""
+	limited := v
+	limited.RateLimitWait = 41*time.Second + 300*time.Millisecond
+
+	tests := []struct {
+		name     string
+		template string
+		v        Values
+		want     string
+	}{
+		{name: "builtin", v: v, want: "work [claude-sonnet-4.5 | 1.00x | 96000/128000 tokens] > "},
+		{name: "builtin without tokens", v: unknown, want: "[claude-sonnet-4.5 | 1.00x] > "},
+		{name: "short", template: "{model_short} {tokens_pct}%> ", v: v, want: "sonnet-4.5 25%> "},
+		{name: "everything", template: "{session}@{git_branch} {model} {multiplier}x {tokens} {tokens_left} {token_limit} > ", v: v,
+			want: "work@main claude-sonnet-4.5 1.00x 96000/128000 96000 128000 > "},
+		{name: "tokens unknown", template: "{tokens} {tokens_pct}% > ", v: unknown, want: "? ?% > "},
+		{name: "unknown placeholder kept", template: "{nope} > ", v: v, want: "{nope} > "},
+		{name: "builtin rate limited", v: limited, want: "work [claude-sonnet-4.5 | 1.00x | 96000/128000 tokens | rate limited 42s] > "},
+		{name: "rate limit", template: "{model_short} {rate_limit}> ", v: limited, want: "sonnet-4.5 42s> "},
+		{name: "no rate limit", template: "{model_short} {rate_limit}> ", v: v, want: "sonnet-4.5 > "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Format(tt.template, tt.v); got != tt.want {
+				t.Errorf("Format(%q) = %q, want %q", tt.template, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormat_Highlight(t *testing.T) {
+	v := Values{Model: "gpt-4.1", TokensLeft: 5, TokenLimit: 100, Highlight: func(s string) string { return "<" + s + ">" }}
+	if got := Format("{model} {tokens_pct}%", v); got != "gpt-4.1 <95>%" {
+		t.Errorf("Format() = %q, want only the tokens highlighted", got)
+	}
+	if got := Format("", v); got != "[gpt-4.1 | 0.00x | <5/100 tokens>] > " {
+		t.Errorf("Format() = %q, want the builtin tokens highlighted", got)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if err := Check("{model_short} {tokens_pct}%> "); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+	if err := Check("{model} {branch} > "); err == nil || !strings.Contains(err.Error(), "{branch}") {
+		t.Errorf("Check() error = %v, want the unknown placeholder named", err)
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !unix
+
+package main
+
+// watchResize does nothing: without SIGWINCH the width is only read at
+// startup
+func watchResize(fn func()) {}
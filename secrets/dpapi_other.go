@@ -0,0 +1,9 @@
+//go:build !windows
+
+package secrets
+
+// newPlatformStore returns nil: only Windows has a platform store beyond
+// the keychain commands handled in Default
+func newPlatformStore(configDir string) SecretStore {
+	return nil
+}
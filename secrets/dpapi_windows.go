@@ -0,0 +1,68 @@
+//go:build windows
+
+package secrets
+
+import (
+	"path/filepath"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// DPAPIStore encrypts secrets with the Windows Data Protection API (bound to
+// the current user) and keeps the ciphertext in files
+type DPAPIStore struct {
+	files *FileStore
+}
+
+// newPlatformStore returns the DPAPI store on Windows
+func newPlatformStore(configDir string) SecretStore {
+	return &DPAPIStore{files: NewFileStore(filepath.Join(configDir, "secrets-dpapi"))}
+}
+
+// Get decrypts and returns the secret stored under key
+func (s *DPAPIStore) Get(key string) (string, error) {
+	sealed, err := s.files.Get(key)
+	if err != nil {
+		return "", err
+	}
+	in := blob([]byte(sealed))
+	var out windows.DataBlob
+	if err := windows.CryptUnprotectData(in, nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out); err != nil {
+		return "", err
+	}
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(out.Data)))
+	return string(unsafe.Slice(out.Data, out.Size)), nil
+}
+
+// Set encrypts value and stores it under key
+func (s *DPAPIStore) Set(key, value string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	in := blob([]byte(value))
+	var out windows.DataBlob
+	if err := windows.CryptProtectData(in, nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out); err != nil {
+		return err
+	}
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(out.Data)))
+	return s.files.Set(key, string(unsafe.Slice(out.Data, out.Size)))
+}
+
+// Delete removes the secret stored under key
+func (s *DPAPIStore) Delete(key string) error {
+	return s.files.Delete(key)
+}
+
+// Name returns the backend name
+func (s *DPAPIStore) Name() string {
+	return "dpapi"
+}
+
+// blob wraps data in a DPAPI DataBlob
+func blob(data []byte) *windows.DataBlob {
+	if len(data) == 0 {
+		return &windows.DataBlob{}
+	}
+	return &windows.DataBlob{Size: uint32(len(data)), Data: &data[0]}
+}
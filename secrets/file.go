@@ -0,0 +1,67 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+
+	"atulm/cocli/storage"
+)
+
+// FileStore keeps each secret in its own 0600 file inside a 0700 directory.
+// It is the fallback when no OS keychain is available.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a FileStore rooted at dir
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+func (s *FileStore) path(key string) string {
+	return filepath.Join(s.dir, key)
+}
+
+// Get returns the secret stored under key
+func (s *FileStore) Get(key string) (string, error) {
+	if err := checkKey(key); err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(s.path(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Set stores value under key with owner-only permissions
+func (s *FileStore) Set(key, value string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return err
+	}
+
+	return storage.LockedWrite(s.path(key), []byte(value), 0600)
+}
+
+// Delete removes the secret stored under key
+func (s *FileStore) Delete(key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	err := os.Remove(s.path(key))
+	if err != nil && os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Name returns the backend name
+func (s *FileStore) Name() string {
+	return "file"
+}
@@ -0,0 +1,87 @@
+package secrets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileStore_SetGet(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "secrets"))
+
+	if err := store.Set("daemon-token", "s3cret"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	got, err := store.Get("daemon-token")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got != "s3cret" {
+		t.Errorf("Get() = %q, want %q", got, "s3cret")
+	}
+}
+
+func TestFileStore_Permissions(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "secrets")
+	store := NewFileStore(dir)
+	if err := store.Set("token", "value"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "token"))
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("file mode = %o, want 0600", perm)
+	}
+	dirInfo, err := os.Stat(dir)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if perm := dirInfo.Mode().Perm(); perm != 0700 {
+		t.Errorf("dir mode = %o, want 0700", perm)
+	}
+}
+
+func TestFileStore_Overwrite(t *testing.T) {
+	store := NewFileStore(t.TempDir())
+	_ = store.Set("token", "old")
+	_ = store.Set("token", "new")
+
+	if got, _ := store.Get("token"); got != "new" {
+		t.Errorf("Get() = %q, want %q", got, "new")
+	}
+}
+
+func TestFileStore_NotFound(t *testing.T) {
+	store := NewFileStore(t.TempDir())
+	if _, err := store.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFileStore_Delete(t *testing.T) {
+	store := NewFileStore(t.TempDir())
+	_ = store.Set("token", "value")
+
+	if err := store.Delete("token"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get("token"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Delete error = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete("token"); err != nil {
+		t.Errorf("Delete() of missing key error = %v, want nil", err)
+	}
+}
+
+func TestFileStore_InvalidKey(t *testing.T) {
+	store := NewFileStore(t.TempDir())
+	for _, key := range []string{"", "../escape", "a/b", "has space"} {
+		if err := store.Set(key, "x"); err == nil {
+			t.Errorf("Set(%q) expected error for invalid key", key)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package secrets
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// KeychainStore stores secrets in the macOS login keychain via security(1)
+type KeychainStore struct {
+	run runner
+}
+
+// NewKeychainStore creates a store backed by the security command
+func NewKeychainStore() *KeychainStore {
+	return &KeychainStore{run: execRunner}
+}
+
+// Get returns the secret stored under key
+func (s *KeychainStore) Get(key string) (string, error) {
+	if err := checkKey(key); err != nil {
+		return "", err
+	}
+	out, err := s.run("", "security", "find-generic-password", "-s", service, "-a", key, "-w")
+	if err != nil {
+		return "", ErrNotFound
+	}
+	return strings.TrimSuffix(out, "\n"), nil
+}
+
+// Set stores value under key, updating any existing item. The command goes
+// to security's interactive mode on stdin, with the value hex-encoded, so
+// the secret never appears in the process list.
+func (s *KeychainStore) Set(key, value string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	command := fmt.Sprintf("add-generic-password -U -s %s -a %s -X %s\n", service, key, hex.EncodeToString([]byte(value)))
+	_, err := s.run(command, "security", "-i")
+	return err
+}
+
+// Delete removes the secret stored under key
+func (s *KeychainStore) Delete(key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	if _, err := s.Get(key); err != nil {
+		return nil
+	}
+	_, err := s.run("", "security", "delete-generic-password", "-s", service, "-a", key)
+	return err
+}
+
+// Name returns the backend name
+func (s *KeychainStore) Name() string {
+	return "keychain"
+}
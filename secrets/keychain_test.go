@@ -0,0 +1,51 @@
+package secrets
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestKeychainStore_Commands(t *testing.T) {
+	var calls [][]string
+	var stdins []string
+	store := &KeychainStore{run: func(stdin, name string, args ...string) (string, error) {
+		calls = append(calls, append([]string{name}, args...))
+		stdins = append(stdins, stdin)
+		if args[0] == "find-generic-password" {
+			return "s3cret\n", nil
+		}
+		return "", nil
+	}}
+
+	if err := store.Set("token", "s3cret"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	got, err := store.Get("token")
+	if err != nil || got != "s3cret" {
+		t.Errorf("Get() = %q, %v; want %q", got, err, "s3cret")
+	}
+
+	// The secret goes on stdin, hex-encoded, never on the command line
+	if set := strings.Join(calls[0], " "); set != "security -i" {
+		t.Errorf("Set() ran %q, want security's interactive mode", set)
+	}
+	want := "add-generic-password -U -s " + service + " -a token -X " + hex.EncodeToString([]byte("s3cret")) + "\n"
+	if stdins[0] != want {
+		t.Errorf("Set() stdin = %q, want %q", stdins[0], want)
+	}
+}
+
+func TestKeychainStore_NotFound(t *testing.T) {
+	store := &KeychainStore{run: func(stdin, name string, args ...string) (string, error) {
+		return "", errors.New("The specified item could not be found in the keychain.")
+	}}
+
+	if _, err := store.Get("token"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() error = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete("token"); err != nil {
+		t.Errorf("Delete() of missing item error = %v, want nil", err)
+	}
+}
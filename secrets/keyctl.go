@@ -0,0 +1,70 @@
+package secrets
+
+import (
+	"strings"
+)
+
+// KeyctlStore stores secrets in the Linux kernel user keyring via keyctl(1).
+// Keys live for the user's login session, so they do not survive a reboot.
+type KeyctlStore struct {
+	run runner
+}
+
+// NewKeyctlStore creates a store backed by the keyctl command
+func NewKeyctlStore() *KeyctlStore {
+	return &KeyctlStore{run: execRunner}
+}
+
+// description is the keyring description for key
+func (s *KeyctlStore) description(key string) string {
+	return service + ":" + key
+}
+
+// find returns the keyring serial number for key
+func (s *KeyctlStore) find(key string) (string, error) {
+	out, err := s.run("", "keyctl", "search", "@u", "user", s.description(key))
+	if err != nil {
+		return "", ErrNotFound
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// Get returns the secret stored under key
+func (s *KeyctlStore) Get(key string) (string, error) {
+	if err := checkKey(key); err != nil {
+		return "", err
+	}
+	id, err := s.find(key)
+	if err != nil {
+		return "", err
+	}
+	return s.run("", "keyctl", "pipe", id)
+}
+
+// Set stores value under key. The value is passed on stdin so it never
+// appears in the process list.
+func (s *KeyctlStore) Set(key, value string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	_, err := s.run(value, "keyctl", "padd", "user", s.description(key), "@u")
+	return err
+}
+
+// Delete removes the secret stored under key
+func (s *KeyctlStore) Delete(key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	id, err := s.find(key)
+	if err != nil {
+		return nil
+	}
+	_, err = s.run("", "keyctl", "unlink", id, "@u")
+	return err
+}
+
+// Name returns the backend name
+func (s *KeyctlStore) Name() string {
+	return "keyctl"
+}
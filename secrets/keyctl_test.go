@@ -0,0 +1,84 @@
+package secrets
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeKeyring emulates keyctl and security(1) by recording commands
+type fakeKeyring struct {
+	values map[string]string
+	calls  []string
+	fail   bool
+}
+
+func newFakeKeyring() *fakeKeyring {
+	return &fakeKeyring{values: make(map[string]string)}
+}
+
+func (f *fakeKeyring) run(stdin string, name string, args ...string) (string, error) {
+	f.calls = append(f.calls, name+" "+strings.Join(args, " "))
+	if f.fail {
+		return "", errors.New("keyring unavailable")
+	}
+
+	switch {
+	case name == "keyctl" && args[0] == "padd":
+		f.values[args[2]] = stdin
+		return "1\n", nil
+	case name == "keyctl" && args[0] == "search":
+		if _, ok := f.values[args[3]]; ok {
+			return args[3] + "\n", nil
+		}
+		return "", errors.New("not found")
+	case name == "keyctl" && args[0] == "pipe":
+		return f.values[args[1]], nil
+	case name == "keyctl" && args[0] == "unlink":
+		delete(f.values, args[1])
+		return "", nil
+	}
+	return "", errors.New("unexpected command")
+}
+
+func TestKeyctlStore_RoundTrip(t *testing.T) {
+	fake := newFakeKeyring()
+	store := &KeyctlStore{run: fake.run}
+
+	if err := store.Set("daemon-token", "s3cret"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	got, err := store.Get("daemon-token")
+	if err != nil || got != "s3cret" {
+		t.Errorf("Get() = %q, %v; want %q", got, err, "s3cret")
+	}
+
+	if err := store.Delete("daemon-token"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get("daemon-token"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Delete error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestKeyctlStore_SecretNotOnCommandLine(t *testing.T) {
+	fake := newFakeKeyring()
+	store := &KeyctlStore{run: fake.run}
+	_ = store.Set("token", "do-not-leak")
+
+	for _, call := range fake.calls {
+		if strings.Contains(call, "do-not-leak") {
+			t.Errorf("secret passed as an argument: %q", call)
+		}
+	}
+}
+
+func TestKeyctlStore_Namespaced(t *testing.T) {
+	fake := newFakeKeyring()
+	store := &KeyctlStore{run: fake.run}
+	_ = store.Set("token", "x")
+
+	if _, ok := fake.values["cocli:token"]; !ok {
+		t.Errorf("key should be stored as cocli:token, got %v", fake.values)
+	}
+}
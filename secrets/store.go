@@ -0,0 +1,174 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// service namespaces cocli's entries in the OS keychain
+const service = "cocli"
+
+// ErrNotFound is returned when no secret is stored under a key
+var ErrNotFound = errors.New("secret not found")
+
+// validKey restricts keys to names that are safe as file names and keychain accounts
+var validKey = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// SecretStore stores small secrets (auth tokens, credentials, keys) outside
+// cocli's plain JSON state files
+type SecretStore interface {
+	// Get returns the secret stored under key, or ErrNotFound
+	Get(key string) (string, error)
+	// Set stores value under key, replacing any existing value
+	Set(key, value string) error
+	// Delete removes the secret under key; deleting a missing key is not an error
+	Delete(key string) error
+	// Name describes the backend (e.g. "keychain", "file")
+	Name() string
+}
+
+// runner executes an external command with the given stdin and returns its stdout
+type runner func(stdin string, name string, args ...string) (string, error)
+
+// execRunner runs commands with os/exec
+func execRunner(stdin string, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("%s: %w: %s", name, err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return string(out), nil
+}
+
+// Default returns the best store for this platform: the OS keychain
+// (Keychain on macOS, keyctl on Linux, DPAPI on Windows) backed by a 0600
+// file store under configDir for when the keychain is unavailable.
+func Default(configDir string) SecretStore {
+	files := NewFileStore(filepath.Join(configDir, "secrets"))
+
+	var primary SecretStore
+	switch runtime.GOOS {
+	case "darwin":
+		if _, err := exec.LookPath("security"); err == nil {
+			primary = NewKeychainStore()
+		}
+	case "linux":
+		if _, err := exec.LookPath("keyctl"); err == nil {
+			primary = NewKeyctlStore()
+		}
+	default:
+		primary = newPlatformStore(configDir)
+	}
+
+	if primary == nil {
+		return files
+	}
+	return &fallbackStore{primary: primary, fallback: files}
+}
+
+// Durable returns a store for secrets that must outlive the login session,
+// such as credentials in the config. It is Default except on Linux, where
+// the kernel keyring is emptied by a reboot: there the 0600 file store holds
+// the durable copy and the keyring, when available, caches it.
+func Durable(configDir string) SecretStore {
+	if runtime.GOOS != "linux" {
+		return Default(configDir)
+	}
+	files := NewFileStore(filepath.Join(configDir, "secrets"))
+	if _, err := exec.LookPath("keyctl"); err != nil {
+		return files
+	}
+	return &cachedStore{durable: files, cache: NewKeyctlStore()}
+}
+
+// checkKey validates a secret key
+func checkKey(key string) error {
+	if !validKey.MatchString(key) {
+		return fmt.Errorf("invalid secret key %q", key)
+	}
+	return nil
+}
+
+// fallbackStore uses primary and falls back to the file store when the
+// keychain is unusable (e.g. no session keyring in a container)
+type fallbackStore struct {
+	primary  SecretStore
+	fallback SecretStore
+}
+
+func (s *fallbackStore) Get(key string) (string, error) {
+	value, err := s.primary.Get(key)
+	if err == nil {
+		return value, nil
+	}
+	return s.fallback.Get(key)
+}
+
+func (s *fallbackStore) Set(key, value string) error {
+	if err := s.primary.Set(key, value); err != nil {
+		return s.fallback.Set(key, value)
+	}
+	// Don't leave a stale copy behind from an earlier fallback write
+	_ = s.fallback.Delete(key)
+	return nil
+}
+
+func (s *fallbackStore) Delete(key string) error {
+	primaryErr := s.primary.Delete(key)
+	if err := s.fallback.Delete(key); err != nil {
+		return err
+	}
+	return primaryErr
+}
+
+func (s *fallbackStore) Name() string {
+	return s.primary.Name() + " (file fallback)"
+}
+
+// cachedStore keeps secrets in durable and copies them into cache, which
+// may lose them at any time
+type cachedStore struct {
+	durable SecretStore
+	cache   SecretStore
+}
+
+func (s *cachedStore) Get(key string) (string, error) {
+	if value, err := s.cache.Get(key); err == nil {
+		return value, nil
+	}
+	value, err := s.durable.Get(key)
+	if err != nil {
+		return "", err
+	}
+	_ = s.cache.Set(key, value)
+	return value, nil
+}
+
+func (s *cachedStore) Set(key, value string) error {
+	if err := s.durable.Set(key, value); err != nil {
+		return err
+	}
+	_ = s.cache.Set(key, value)
+	return nil
+}
+
+func (s *cachedStore) Delete(key string) error {
+	_ = s.cache.Delete(key)
+	return s.durable.Delete(key)
+}
+
+func (s *cachedStore) Name() string {
+	return s.durable.Name() + " (" + s.cache.Name() + " cache)"
+}
@@ -0,0 +1,82 @@
+package secrets
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFallbackStore_UsesPrimary(t *testing.T) {
+	fake := newFakeKeyring()
+	files := NewFileStore(t.TempDir())
+	store := &fallbackStore{primary: &KeyctlStore{run: fake.run}, fallback: files}
+
+	if err := store.Set("token", "value"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if _, err := files.Get("token"); !errors.Is(err, ErrNotFound) {
+		t.Error("secret should not be written to the file fallback when the keychain works")
+	}
+	if got, _ := store.Get("token"); got != "value" {
+		t.Errorf("Get() = %q, want %q", got, "value")
+	}
+}
+
+func TestFallbackStore_FallsBack(t *testing.T) {
+	fake := newFakeKeyring()
+	fake.fail = true
+	files := NewFileStore(t.TempDir())
+	store := &fallbackStore{primary: &KeyctlStore{run: fake.run}, fallback: files}
+
+	if err := store.Set("token", "value"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if got, _ := files.Get("token"); got != "value" {
+		t.Errorf("file fallback = %q, want %q", got, "value")
+	}
+	if got, _ := store.Get("token"); got != "value" {
+		t.Errorf("Get() = %q, want %q", got, "value")
+	}
+	if err := store.Delete("token"); err != nil {
+		t.Errorf("Delete() error = %v", err)
+	}
+	if _, err := store.Get("token"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Delete error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestDefault_ReturnsStore(t *testing.T) {
+	store := Default(t.TempDir())
+	if store == nil || store.Name() == "" {
+		t.Fatal("Default() should always return a usable store")
+	}
+}
+
+func TestCachedStore_SurvivesLostCache(t *testing.T) {
+	fake := newFakeKeyring()
+	files := NewFileStore(t.TempDir())
+	store := &cachedStore{durable: files, cache: &KeyctlStore{run: fake.run}}
+
+	if err := store.Set("token", "value"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if got, _ := files.Get("token"); got != "value" {
+		t.Errorf("durable copy = %q, want %q", got, "value")
+	}
+
+	// A reboot empties the keyring; the file still has the secret, and
+	// the next read caches it again
+	fake.values = make(map[string]string)
+	if got, err := store.Get("token"); err != nil || got != "value" {
+		t.Errorf("Get() after losing the cache = %q, %v; want %q", got, err, "value")
+	}
+	if len(fake.values) != 1 {
+		t.Error("Get() should cache the secret again")
+	}
+
+	if err := store.Delete("token"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get("token"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Delete error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,251 @@
+package server
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"atulm/cocli/storage"
+)
+
+// The daemon's port can be reached by anyone on the machine, so it is
+// served by an auth gate that forwards to the copilot server only after the
+// client presents the daemon's token:
+//
+//	gate:   COCLI-AUTH
+//	client: <token>
+//	gate:   OK (or DENIED, then closes)
+//
+// after which the connection carries the SDK protocol unchanged. Instead
+// of the token, a client may send "STATS <token>" to be told how many
+// clients are connected ("STATS <n>"), after which the gate closes.
+const (
+	authGreeting = "COCLI-AUTH"
+	authOK       = "OK"
+	authDenied   = "DENIED"
+	statsRequest = "STATS"
+
+	// authTimeout bounds each step of the handshake
+	authTimeout = 5 * time.Second
+
+	// tokenFileName passes the token to a daemon started by /server start
+	tokenFileName = "token"
+)
+
+var (
+	// ErrAuthRequired is returned when a daemon wants a token and none was
+	// given
+	ErrAuthRequired = errors.New("the daemon requires a token")
+
+	// ErrAuthDenied is returned when the daemon rejected the token
+	ErrAuthDenied = errors.New("the daemon rejected the token")
+)
+
+// newToken returns a random daemon token
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate daemon token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Gate accepts connections on ln until it is closed and forwards those that
+// present token to a connection opened with dial
+func Gate(ln net.Listener, token string, dial func() (net.Conn, error)) {
+	newGate(token).serve(ln, dial)
+}
+
+// gate checks tokens and counts the clients it forwards; one gate may
+// serve several listeners
+type gate struct {
+	token   string
+	clients atomic.Int64
+}
+
+func newGate(token string) *gate {
+	return &gate{token: token}
+}
+
+// serve forwards authenticated connections on ln until it is closed
+func (g *gate) serve(ln net.Listener, dial func() (net.Conn, error)) {
+	forward(ln, g.check, g.counted(dial))
+}
+
+// servePrivate forwards every connection on ln, which only the daemon's
+// owner can reach (see ListenPrivate), without asking for the token
+func (g *gate) servePrivate(ln net.Listener, dial func() (net.Conn, error)) {
+	forward(ln, nil, g.counted(dial))
+}
+
+// counted wraps dial so the connections it opens count as clients
+func (g *gate) counted(dial func() (net.Conn, error)) func() (net.Conn, error) {
+	return func() (net.Conn, error) {
+		conn, err := dial()
+		if err != nil {
+			return nil, err
+		}
+		g.clients.Add(1)
+		return &countedConn{Conn: conn, done: func() { g.clients.Add(-1) }}, nil
+	}
+}
+
+// check runs the gate's side of the handshake on conn. A stats request is
+// answered and then rejected so the connection is closed.
+func (g *gate) check(conn net.Conn) error {
+	_ = conn.SetDeadline(time.Now().Add(authTimeout))
+	defer conn.SetDeadline(time.Time{})
+	if _, err := fmt.Fprintf(conn, "%s\n", authGreeting); err != nil {
+		return err
+	}
+	got, err := readLine(conn)
+	if err != nil {
+		return err
+	}
+	token, stats := strings.CutPrefix(got, statsRequest+" ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(g.token)) != 1 {
+		fmt.Fprintf(conn, "%s\n", authDenied)
+		return ErrAuthDenied
+	}
+	if stats {
+		fmt.Fprintf(conn, "%s %d\n", statsRequest, g.clients.Load())
+		return errStatsServed
+	}
+	_, err = fmt.Fprintf(conn, "%s\n", authOK)
+	return err
+}
+
+// errStatsServed ends a connection that asked for stats
+var errStatsServed = errors.New("stats served")
+
+// countedConn calls done once when it is closed
+type countedConn struct {
+	net.Conn
+	once sync.Once
+	done func()
+}
+
+func (c *countedConn) Close() error {
+	c.once.Do(c.done)
+	return c.Conn.Close()
+}
+
+// ConnectedClients asks the gate on conn how many clients it is forwarding
+func ConnectedClients(conn net.Conn, token string) (int, error) {
+	_ = conn.SetDeadline(time.Now().Add(authTimeout))
+	defer conn.SetDeadline(time.Time{})
+	if greeting, err := readLine(conn); err != nil || greeting != authGreeting {
+		return 0, fmt.Errorf("no auth greeting from the daemon")
+	}
+	if _, err := fmt.Fprintf(conn, "%s %s\n", statsRequest, token); err != nil {
+		return 0, err
+	}
+	reply, err := readLine(conn)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := strings.CutPrefix(reply, statsRequest+" ")
+	if !ok {
+		return 0, ErrAuthDenied
+	}
+	return strconv.Atoi(n)
+}
+
+// Authenticate runs the client's side of the handshake on a connection to
+// the daemon's gate
+func Authenticate(conn net.Conn, token string) error {
+	_ = conn.SetDeadline(time.Now().Add(authTimeout))
+	defer conn.SetDeadline(time.Time{})
+	greeting, err := readLine(conn)
+	if err != nil {
+		return fmt.Errorf("no auth greeting from the daemon: %w", err)
+	}
+	if greeting != authGreeting {
+		return fmt.Errorf("unexpected greeting %q from the daemon", greeting)
+	}
+	if token == "" {
+		return ErrAuthRequired
+	}
+	if _, err := fmt.Fprintf(conn, "%s\n", token); err != nil {
+		return err
+	}
+	reply, err := readLine(conn)
+	if err != nil || reply != authOK {
+		return ErrAuthDenied
+	}
+	return nil
+}
+
+// RequiresAuth reports whether the daemon greets conn with the auth
+// handshake within timeout. It consumes the greeting.
+func RequiresAuth(conn net.Conn, timeout time.Duration) bool {
+	_ = conn.SetReadDeadline(time.Now().Add(timeout))
+	defer conn.SetReadDeadline(time.Time{})
+	greeting, err := readLine(conn)
+	return err == nil && greeting == authGreeting
+}
+
+// readLine reads one short line a byte at a time, so nothing after it is
+// consumed from conn
+func readLine(conn net.Conn) (string, error) {
+	var b strings.Builder
+	buf := make([]byte, 1)
+	for b.Len() < 256 {
+		if _, err := conn.Read(buf); err != nil {
+			return "", err
+		}
+		if buf[0] == '\n' {
+			return b.String(), nil
+		}
+		b.WriteByte(buf[0])
+	}
+	return "", errors.New("auth line too long")
+}
+
+// readTokenFile reads and removes the token left by /server start for the
+// daemon it launched
+func readTokenFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(path)
+	defer f.Close()
+	token, err := bufio.NewReader(f).ReadString('\n')
+	if token = strings.TrimSpace(token); token == "" {
+		return "", fmt.Errorf("empty token file %s: %v", path, err)
+	}
+	return token, nil
+}
+
+// tokenPath is where /server start leaves the token for the daemon it
+// launches, or "" without a state directory
+func (d *DaemonManager) tokenPath() string {
+	if d.crashPath == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(d.crashPath), tokenFileName)
+}
+
+// writeTokenFile leaves token for a daemon about to be launched
+func (d *DaemonManager) writeTokenFile(token string) error {
+	path := d.tokenPath()
+	if path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return storage.WriteFileAtomic(path, []byte(token+"\n"), 0600)
+}
@@ -0,0 +1,269 @@
+package server
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"atulm/cocli/secrets"
+)
+
+// startGate serves an echo server behind a gate for token, returning the
+// gate's address
+func startGate(t *testing.T, token string) string {
+	t.Helper()
+	target := startEcho(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go Gate(ln, token, func() (net.Conn, error) { return net.Dial("tcp", target) })
+	return ln.Addr().String()
+}
+
+func TestGate(t *testing.T) {
+	addr := startGate(t, "s3cret")
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "valid token", token: "s3cret"},
+		{name: "wrong token", token: "guess", wantErr: ErrAuthDenied},
+		{name: "no token", token: "", wantErr: ErrAuthRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			err = Authenticate(conn, tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Authenticate() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			// The connection now reaches the server
+			conn.Write([]byte("ping\n"))
+			got, err := readLine(conn)
+			if err != nil || got != "ping" {
+				t.Errorf("echo = %q, %v; want ping", got, err)
+			}
+		})
+	}
+}
+
+func TestRequiresAuth(t *testing.T) {
+	conn, err := net.Dial("tcp", startGate(t, "s3cret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if !RequiresAuth(conn, time.Second) {
+		t.Error("RequiresAuth(gate) = false, want true")
+	}
+
+	plain, err := net.Dial("tcp", startEcho(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plain.Close()
+	if RequiresAuth(plain, 100*time.Millisecond) {
+		t.Error("RequiresAuth(plain server) = true, want false")
+	}
+}
+
+func TestTokenFile(t *testing.T) {
+	dm := newCrashTestManager(t, &MockConfigStore{}, NewMockProcessManager())
+	if err := dm.writeTokenFile("s3cret"); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(dm.tokenPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("token file mode = %o, want 0600", perm)
+	}
+	if got, err := readTokenFile(dm.tokenPath()); err != nil || got != "s3cret" {
+		t.Errorf("readTokenFile() = %q, %v; want the token", got, err)
+	}
+	if _, err := os.Stat(dm.tokenPath()); !os.IsNotExist(err) {
+		t.Error("token file should be removed once read")
+	}
+}
+
+func TestFileConfigStore_SavePrivate(t *testing.T) {
+	store := NewFileConfigStore(filepath.Join(t.TempDir(), "daemon"))
+	if err := store.Save(&DaemonConfig{PID: 1, Port: 4321, Token: "s3cret"}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(store.GetPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("server.json mode = %o, want 0600", perm)
+	}
+	data, err := os.ReadFile(store.GetPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "s3cret") {
+		t.Errorf("server.json = %s, want the token kept out of it", data)
+	}
+	loaded, err := store.Load()
+	if err != nil || loaded.Token != "" || loaded.TokenRef == "" {
+		t.Fatalf("Load() = %+v, %v; want only the token's reference", loaded, err)
+	}
+	if token, err := store.Token(loaded); err != nil || token != "s3cret" {
+		t.Errorf("Token() = %q, %v; want the token kept", token, err)
+	}
+
+	if err := store.Delete(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.secrets.Get(store.tokenKey); !errors.Is(err, secrets.ErrNotFound) {
+		t.Errorf("token left in the secret store after Delete: %v", err)
+	}
+}
+
+func TestDaemonManager_Start_Token(t *testing.T) {
+	store := &MockConfigStore{}
+	procMgr := NewMockProcessManager()
+	procMgr.startPID = 200
+	dm := newCrashTestManager(t, store, procMgr)
+
+	if err := dm.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if store.config == nil || store.config.Token == "" || store.config.Socket == "" {
+		t.Fatalf("daemon config = %+v, want a token and a private socket", store.config)
+	}
+	if dm.Token() != store.config.Token {
+		t.Errorf("Token() = %q, want the saved token", dm.Token())
+	}
+	// The token file is left for the launched daemon to pick up
+	if got, err := readTokenFile(dm.tokenPath()); err != nil || got != store.config.Token {
+		t.Errorf("token file = %q, %v; want the saved token", got, err)
+	}
+}
+
+func TestConnectedClients(t *testing.T) {
+	addr := startGate(t, "s3cret")
+	stats := func(token string) (int, error) {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		return ConnectedClients(conn, token)
+	}
+	if n, err := stats("s3cret"); err != nil || n != 0 {
+		t.Fatalf("ConnectedClients() = %d, %v; want 0 before any client", n, err)
+	}
+
+	client, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Authenticate(client, "s3cret"); err != nil {
+		t.Fatal(err)
+	}
+	// The gate counts the client once it has dialed the server
+	deadline := time.Now().Add(time.Second)
+	for n, _ := stats("s3cret"); n != 1; n, _ = stats("s3cret") {
+		if time.Now().After(deadline) {
+			t.Fatalf("ConnectedClients() = %d, want 1 with a client connected", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := stats("guess"); !errors.Is(err, ErrAuthDenied) {
+		t.Errorf("ConnectedClients() with a wrong token: error = %v, want ErrAuthDenied", err)
+	}
+
+	client.Close()
+	deadline = time.Now().Add(time.Second)
+	for n, _ := stats("s3cret"); n != 0; n, _ = stats("s3cret") {
+		if time.Now().After(deadline) {
+			t.Fatalf("ConnectedClients() = %d, want 0 once the client left", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// countingStore counts the lookups in a secret store
+type countingStore struct {
+	secrets.SecretStore
+	gets int
+}
+
+func (s *countingStore) Get(name string) (string, error) {
+	s.gets++
+	return s.SecretStore.Get(name)
+}
+
+func TestDaemonManager_TokenLookedUpOnce(t *testing.T) {
+	store := NewFileConfigStore(filepath.Join(t.TempDir(), "daemon"))
+	counted := &countingStore{SecretStore: store.secrets}
+	store.secrets = counted
+	started := time.Now()
+	if err := store.Save(&DaemonConfig{PID: 7, Port: 4321, StartedAt: started, Token: "s3cret"}); err != nil {
+		t.Fatal(err)
+	}
+	dm := NewDaemonManager(store, NewMockProcessManager(), &MockHealthChecker{}, &MockCLIFinder{})
+
+	for range 3 {
+		if got := dm.Token(); got != "s3cret" {
+			t.Fatalf("Token() = %q, want the saved token", got)
+		}
+	}
+	if counted.gets != 1 {
+		t.Errorf("secret store read %d times, want once", counted.gets)
+	}
+
+	// A restarted daemon has a new token
+	if err := store.Save(&DaemonConfig{PID: 8, Port: 4321, StartedAt: started.Add(time.Second), Token: "n3w"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := dm.Token(); got != "n3w" || counted.gets != 2 {
+		t.Errorf("Token() = %q after %d reads, want the new token read once more", got, counted.gets)
+	}
+}
+
+func TestDaemonManager_Inspect(t *testing.T) {
+	gateAddr := startGate(t, "s3cret")
+	_, portStr, _ := net.SplitHostPort(gateAddr)
+	port, _ := strconv.Atoi(portStr)
+	procMgr := NewMockProcessManager()
+	procMgr.runningPIDs[7] = true
+	store := &MockConfigStore{config: &DaemonConfig{
+		PID: 7, Port: port, StartedAt: time.Now(), Token: "s3cret", CLIPath: "/usr/bin/copilot",
+	}}
+	dm := NewDaemonManager(store, procMgr,
+		&MockHealthChecker{healthy: true, info: &ServerInfo{Version: "1.2.3", ProtocolVersion: 2}},
+		&MockCLIFinder{path: "/usr/bin/copilot"},
+	)
+
+	status, err := dm.Inspect()
+	if err != nil {
+		t.Fatalf("Inspect() error = %v", err)
+	}
+	if status.Version != "1.2.3" || status.CLIPath != "/usr/bin/copilot" || status.Clients != 0 {
+		t.Errorf("Inspect() = %+v, want version, binary and 0 clients", status)
+	}
+
+	store.config.Token = ""
+	if status, _ := dm.Inspect(); status.Clients != -1 {
+		t.Errorf("Inspect() without a token: Clients = %d, want -1 (unknown)", status.Clients)
+	}
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// BridgeEnv, when set in cocli's environment, names a unix socket and makes
+// cocli relay its stdin and stdout to it instead of running. The copilot SDK
+// only reaches servers over TCP or a process's stdio, so clients give it
+// cocli as the "CLI" to reach the daemon through an owner-only socket.
+const BridgeEnv = "COCLI_BRIDGE"
+
+// socketFileName is the daemon's owner-only socket in its state directory
+const socketFileName = "server.sock"
+
+// Bridge relays stdin and stdout to the unix socket at path until either
+// side closes
+func Bridge(path string, stdin io.Reader, stdout io.Writer) error {
+	conn, err := net.DialTimeout("unix", path, healthCheckTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", path, err)
+	}
+	defer conn.Close()
+	done := make(chan error, 2)
+	go func() {
+		_, err := io.Copy(conn, stdin)
+		done <- err
+	}()
+	go func() {
+		_, err := io.Copy(stdout, conn)
+		done <- err
+	}()
+	return <-done
+}
+
+// ListenPrivate listens on a unix socket at path that only this user can
+// connect to: its directory is made 0700 and the socket 0600. A socket left
+// by an earlier process is replaced.
+func ListenPrivate(path string) (net.Listener, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(dir, 0700); err != nil {
+		return nil, err
+	}
+	_ = os.Remove(path)
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// socketPath is where the daemon serves the copilot server to its owner: in
+// its state directory, or a new private temporary directory without one
+func (d *DaemonManager) socketPath() (string, error) {
+	if d.crashPath != "" {
+		return filepath.Join(filepath.Dir(d.crashPath), socketFileName), nil
+	}
+	dir, err := os.MkdirTemp("", "cocli-daemon-")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, socketFileName), nil
+}
+
+// rpcCall sends one JSON-RPC request on conn and decodes the result into
+// result, for health checks that don't need a full SDK client
+func rpcCall(conn net.Conn, method string, params, result any, timeout time.Duration) error {
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+	defer conn.SetDeadline(time.Time{})
+	body, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": "1", "method": method, "params": params})
+	if err != nil {
+		return err
+	}
+	if err := writeMessage(conn, body); err != nil {
+		return err
+	}
+	br := bufio.NewReader(conn)
+	for {
+		body, err := readMessage(br)
+		if err != nil {
+			return err
+		}
+		var reply struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Result json.RawMessage `json:"result"`
+			Error  *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(body, &reply); err != nil {
+			return err
+		}
+		// Skip notifications meant for every client
+		if reply.Method != "" || string(reply.ID) != `"1"` {
+			continue
+		}
+		if reply.Error != nil {
+			return fmt.Errorf("%s failed: %s", method, reply.Error.Message)
+		}
+		if result == nil {
+			return nil
+		}
+		return json.Unmarshal(reply.Result, result)
+	}
+}
@@ -3,13 +3,20 @@ package server
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
+
+	"atulm/cocli/config"
+	"atulm/cocli/secrets"
+	"atulm/cocli/storage"
 )
 
 const (
-	configDirName  = ".cocli"
+	daemonDirName  = "daemon"
 	configFileName = "server.json"
 )
 
@@ -21,12 +28,49 @@ type DaemonConfig struct {
 	PID       int       `json:"pid"`
 	Port      int       `json:"port"`
 	StartedAt time.Time `json:"started_at"`
+	// Restarts counts automatic restarts after crashes (see Supervisor)
+	Restarts int `json:"restarts,omitempty"`
+	// TLSAddr is where a foreground server accepts TLS connections from
+	// other machines, if it does
+	TLSAddr string `json:"tls_addr,omitempty"`
+	// Token must be presented to the auth gate on Port, which forwards to
+	// the copilot server (see Gate). FileConfigStore keeps it in the secret
+	// store and only TokenRef, its key there, in the file, and Load leaves
+	// it unset (see ConfigStore.Token); daemons started by older versions of
+	// cocli wrote it to the file.
+	Token    string `json:"token,omitempty"`
+	TokenRef string `json:"token_ref,omitempty"`
+	// Socket is the unix socket that serves the copilot server to the
+	// daemon's owner without a token (see ListenPrivate)
+	Socket string `json:"socket,omitempty"`
+	// ServerPort is where daemons started by older versions of cocli ran
+	// the copilot server, on plain TCP
+	ServerPort int `json:"server_port,omitempty"`
+	// CLIPath is the copilot CLI the daemon runs
+	CLIPath string `json:"cli_path,omitempty"`
+	// Process identifies the daemon process beyond its PID, which the OS
+	// may give to another process once the daemon is gone
+	Process *ProcessIdentity `json:"process,omitempty"`
+}
+
+// serverAddr is where the copilot server itself can be reached, for net.Dial
+func (c *DaemonConfig) serverAddr() (network, addr string) {
+	if c.Socket != "" {
+		return "unix", c.Socket
+	}
+	port := c.Port
+	if c.ServerPort > 0 {
+		port = c.ServerPort
+	}
+	return "tcp", net.JoinHostPort("localhost", strconv.Itoa(port))
 }
 
 // ConfigStore interface for config file operations
 type ConfigStore interface {
-	// Load reads the daemon config from storage
+	// Load reads the daemon config from storage, without its token
 	Load() (*DaemonConfig, error)
+	// Token returns the token of a loaded config, looking up its TokenRef
+	Token(config *DaemonConfig) (string, error)
 	// Save persists the daemon config to storage
 	Save(config *DaemonConfig) error
 	// Delete removes the config from storage
@@ -38,21 +82,43 @@ type ConfigStore interface {
 // FileConfigStore implements ConfigStore using the filesystem
 type FileConfigStore struct {
 	configDir string
+	// secrets holds the daemon token under tokenKey
+	secrets  secrets.SecretStore
+	tokenKey string
 }
 
-// NewFileConfigStore creates a ConfigStore with a custom config directory
+// NewFileConfigStore creates a ConfigStore with a custom config directory,
+// keeping the token in files under it
 func NewFileConfigStore(configDir string) *FileConfigStore {
-	return &FileConfigStore{configDir: configDir}
+	return &FileConfigStore{
+		configDir: configDir,
+		secrets:   secrets.NewFileStore(filepath.Join(configDir, "secrets")),
+		tokenKey:  "daemon-token",
+	}
 }
 
-// DefaultConfigStore returns a ConfigStore using ~/.cocli
+// DefaultConfigStore returns a ConfigStore using ~/.cocli/daemon/<user>,
+// namespaced by user so a shared home directory doesn't mix daemons
 func DefaultConfigStore() (*FileConfigStore, error) {
-	home, err := os.UserHomeDir()
+	dir, err := config.DefaultDir()
 	if err != nil {
 		return nil, err
 	}
-	configDir := filepath.Join(home, configDirName)
-	return &FileConfigStore{configDir: configDir}, nil
+	return &FileConfigStore{
+		configDir: filepath.Join(dir, daemonDirName, UserNamespace()),
+		secrets:   secrets.Default(dir),
+		tokenKey:  "daemon-token-" + UserNamespace(),
+	}, nil
+}
+
+// DefaultLogDir returns ~/.cocli/logs/<user>, where the daemon's output is
+// logged
+func DefaultLogDir() (string, error) {
+	dir, err := config.DefaultDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, logDirName, UserNamespace()), nil
 }
 
 // GetPath returns the full path to the config file
@@ -60,11 +126,13 @@ func (s *FileConfigStore) GetPath() string {
 	return filepath.Join(s.configDir, configFileName)
 }
 
-// Load reads the daemon config from the file
+// Load reads the daemon config from the file. It is called often, e.g. by
+// the Supervisor, so the token is left in the secret store until Token asks
+// for it.
 func (s *FileConfigStore) Load() (*DaemonConfig, error) {
 	path := s.GetPath()
 
-	data, err := os.ReadFile(path)
+	data, err := storage.LockedRead(path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, ErrConfigNotFound
@@ -76,33 +144,54 @@ func (s *FileConfigStore) Load() (*DaemonConfig, error) {
 	if err := json.Unmarshal(data, &config); err != nil {
 		return nil, err
 	}
-
 	return &config, nil
 }
 
-// Save persists the daemon config to the file
+// Token returns config's token from the secret store, or from the file for
+// daemons started by older versions of cocli
+func (s *FileConfigStore) Token(config *DaemonConfig) (string, error) {
+	if config.Token != "" || config.TokenRef == "" {
+		return config.Token, nil
+	}
+	return s.secrets.Get(config.TokenRef)
+}
+
+// Save persists the daemon config to the file. The write holds the file's
+// lock and replaces it atomically, so concurrent cocli processes never see a
+// partial file. The token goes to the secret store, and the file is still
+// readable by its owner only.
 func (s *FileConfigStore) Save(config *DaemonConfig) error {
 	// Ensure config directory exists
-	if err := os.MkdirAll(s.configDir, 0755); err != nil {
+	if err := os.MkdirAll(s.configDir, 0700); err != nil {
 		return err
 	}
 
-	data, err := json.MarshalIndent(config, "", "  ")
+	saved := *config
+	if saved.Token != "" {
+		if err := s.secrets.Set(s.tokenKey, saved.Token); err != nil {
+			return fmt.Errorf("failed to store the daemon token: %w", err)
+		}
+		saved.Token, saved.TokenRef = "", s.tokenKey
+	}
+	data, err := json.MarshalIndent(&saved, "", "  ")
 	if err != nil {
 		return err
 	}
 
 	path := s.GetPath()
-	return os.WriteFile(path, data, 0644)
+	return storage.LockedWrite(path, data, 0600)
 }
 
-// Delete removes the config file
+// Delete removes the config file and the token
 func (s *FileConfigStore) Delete() error {
+	_ = s.secrets.Delete(s.tokenKey)
 	path := s.GetPath()
-	err := os.Remove(path)
-	if err != nil && os.IsNotExist(err) {
-		// File doesn't exist, that's fine
-		return nil
-	}
-	return err
+	return storage.WithLock(path, func() error {
+		err := os.Remove(path)
+		if err != nil && os.IsNotExist(err) {
+			// File doesn't exist, that's fine
+			return nil
+		}
+		return err
+	})
 }
@@ -2,6 +2,7 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -91,17 +92,7 @@ func TestFileConfigStore_Load_InvalidJSON(t *testing.T) {
 func isJSONError(err error) bool {
 	var syntaxErr *json.SyntaxError
 	var typeErr *json.UnmarshalTypeError
-	if err == nil {
-		return false
-	}
-	// Check if it's any kind of JSON error
-	return json.Unmarshal([]byte("invalid"), nil) != nil &&
-		(err.Error() != "" && (contains(err.Error(), "invalid") ||
-			contains(err.Error(), "unexpected") ||
-			contains(err.Error(), "cannot unmarshal")))
-	_ = syntaxErr
-	_ = typeErr
-	return true
+	return errors.As(err, &syntaxErr) || errors.As(err, &typeErr)
 }
 
 func contains(s, substr string) bool {
@@ -218,4 +209,8 @@ func TestDefaultConfigStore(t *testing.T) {
 	if !contains(path, "server.json") {
 		t.Errorf("GetPath() = %q, want path containing server.json", path)
 	}
+	// State is namespaced per user
+	if !contains(path, UserNamespace()) {
+		t.Errorf("GetPath() = %q, want path containing user namespace %q", path, UserNamespace())
+	}
 }
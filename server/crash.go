@@ -0,0 +1,231 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"atulm/cocli/storage"
+)
+
+const (
+	logFileName   = "server.log"
+	crashFileName = "server-crash.json"
+
+	// crashTailLines is how many trailing stderr lines a crash report keeps
+	crashTailLines = 20
+
+	// maxTailBytes bounds how much of the log is read to find the tail
+	maxTailBytes = 64 * 1024
+)
+
+// CrashReport records how the daemon last exited unexpectedly
+type CrashReport struct {
+	PID int `json:"pid"`
+	// ExitCode is the process exit code, or -1 when it is unknown or the
+	// process was killed by a signal
+	ExitCode int       `json:"exit_code"`
+	Signal   string    `json:"signal,omitempty"`
+	ExitedAt time.Time `json:"exited_at"`
+	// Stderr holds the last lines the daemon wrote to its log
+	Stderr []string `json:"stderr,omitempty"`
+	// Reported is set once the crash has been shown at startup
+	Reported bool `json:"reported"`
+}
+
+// Reason describes how the daemon exited
+func (c *CrashReport) Reason() string {
+	switch {
+	case c.Signal != "":
+		return fmt.Sprintf("killed by signal: %s", c.Signal)
+	case c.ExitCode >= 0:
+		return fmt.Sprintf("exited with code %d", c.ExitCode)
+	default:
+		return "exited unexpectedly (exit status unknown; no cocli process was watching it)"
+	}
+}
+
+// newCrashReport builds a report for pid from its exit state (nil when the
+// exit was only noticed later) and the tail of the daemon log
+func newCrashReport(pid int, state *os.ProcessState, logPath string) *CrashReport {
+	report := &CrashReport{
+		PID:      pid,
+		ExitCode: -1,
+		ExitedAt: time.Now(),
+	}
+	if state != nil {
+		report.ExitCode = state.ExitCode()
+		if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			report.Signal = ws.Signal().String()
+		}
+	}
+	if logPath != "" {
+		report.Stderr, _ = tailLines(logPath, crashTailLines)
+	}
+	return report
+}
+
+// SetStateDir sets the directory holding the crash report, and the daemon
+// logs in its logs subdirectory unless SetLogDir says otherwise. Logging
+// and crash capture are disabled until a state directory is set.
+func (d *DaemonManager) SetStateDir(dir string) {
+	if dir == "" {
+		d.logPath, d.crashPath = "", ""
+		return
+	}
+	d.logPath = filepath.Join(dir, logDirName, logFileName)
+	d.crashPath = filepath.Join(dir, crashFileName)
+}
+
+// SetLogDir sets the directory the daemon logs are written to
+func (d *DaemonManager) SetLogDir(dir string) {
+	d.logPath = filepath.Join(dir, logFileName)
+}
+
+// LogPath returns the file the daemon's output is written to, or "" when
+// logging is disabled
+func (d *DaemonManager) LogPath() string {
+	return d.logPath
+}
+
+// LastCrash returns the most recent crash report, or nil if the daemon has
+// not crashed since it was last started
+func (d *DaemonManager) LastCrash() (*CrashReport, error) {
+	if d.crashPath == "" {
+		return nil, nil
+	}
+	data, err := storage.LockedRead(d.crashPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var report CrashReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// TakeUnreportedCrash returns a crash that has not been shown at startup yet
+// and marks it as shown. It stays available to LastCrash for /server status.
+func (d *DaemonManager) TakeUnreportedCrash() (*CrashReport, error) {
+	report, err := d.LastCrash()
+	if err != nil || report == nil || report.Reported {
+		return nil, err
+	}
+	report.Reported = true
+	if err := d.saveCrash(report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// saveCrash persists a crash report
+func (d *DaemonManager) saveCrash(report *CrashReport) error {
+	if d.crashPath == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return storage.LockedWrite(d.crashPath, data, 0644)
+}
+
+// clearCrash removes the crash report, e.g. after a successful start
+func (d *DaemonManager) clearCrash() {
+	if d.crashPath == "" {
+		return
+	}
+	_ = storage.WithLock(d.crashPath, func() error {
+		return os.Remove(d.crashPath)
+	})
+}
+
+// recordCrash saves a crash report for pid and removes the stale config
+func (d *DaemonManager) recordCrash(pid int, state *os.ProcessState) {
+	_ = d.saveCrash(newCrashReport(pid, state, d.logPath))
+	_ = d.config.Delete()
+}
+
+// handleExit is called when a daemon started by this process exits. Exits
+// caused by Stop, here or in another cocli process, are not crashes: Stop
+// marks the PID or removes the config before the process goes away.
+func (d *DaemonManager) handleExit(pid int, state *os.ProcessState) {
+	d.mu.Lock()
+	stopping := d.stopping[pid]
+	delete(d.stopping, pid)
+	d.mu.Unlock()
+	if stopping {
+		return
+	}
+
+	config, err := d.config.Load()
+	if err != nil || config.PID != pid {
+		return
+	}
+	d.recordCrash(pid, state)
+}
+
+// markStopping records that pid is being stopped deliberately
+func (d *DaemonManager) markStopping(pid int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stopping == nil {
+		d.stopping = make(map[int]bool)
+	}
+	d.stopping[pid] = true
+}
+
+// stderrTail returns the last lines of the daemon log for error messages
+func (d *DaemonManager) stderrTail() []string {
+	if d.logPath == "" {
+		return nil
+	}
+	lines, _ := tailLines(d.logPath, crashTailLines)
+	return lines
+}
+
+// tailLines returns up to n non-empty trailing lines of the file at path
+func tailLines(path string, n int) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	offset := info.Size() - maxTailBytes
+	if offset < 0 {
+		offset = 0
+	}
+	data := make([]byte, info.Size()-offset)
+	if _, err := f.ReadAt(data, offset); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimRight(line, "\r"); strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	// The first line may be cut in half when the read started mid-file
+	if offset > 0 && len(lines) > 0 {
+		lines = lines[1:]
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, nil
+}
@@ -0,0 +1,217 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newCrashTestManager(t *testing.T, configStore *MockConfigStore, procMgr *MockProcessManager) *DaemonManager {
+	t.Helper()
+	dm := NewDaemonManager(
+		configStore,
+		procMgr,
+		&MockHealthChecker{healthy: true},
+		&MockCLIFinder{path: "/usr/bin/copilot"},
+	)
+	dm.SetStateDir(t.TempDir())
+	return dm
+}
+
+func TestCrashReport_Reason(t *testing.T) {
+	tests := []struct {
+		name   string
+		report CrashReport
+		want   string
+	}{
+		{"exit code", CrashReport{ExitCode: 3}, "exited with code 3"},
+		{"signal", CrashReport{ExitCode: -1, Signal: "killed"}, "killed by signal: killed"},
+		{"unknown", CrashReport{ExitCode: -1}, "exit status unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.report.Reason(); !strings.Contains(got, tt.want) {
+				t.Errorf("Reason() = %q, want it to contain %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTailLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.log")
+	var lines []string
+	for i := 0; i < 30; i++ {
+		lines = append(lines, strings.Repeat("x", i%3)+"line")
+	}
+	content := strings.Join(lines, "\n") + "\n\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := tailLines(path, 5)
+	if err != nil {
+		t.Fatalf("tailLines() error = %v", err)
+	}
+	if len(got) != 5 {
+		t.Fatalf("tailLines() returned %d lines, want 5", len(got))
+	}
+	if got[4] != lines[29] {
+		t.Errorf("last line = %q, want %q", got[4], lines[29])
+	}
+}
+
+func TestDaemonManager_Status_RecordsCrash(t *testing.T) {
+	configStore := &MockConfigStore{
+		config: &DaemonConfig{PID: 12345, Port: 4321, StartedAt: time.Now()},
+	}
+	dm := newCrashTestManager(t, configStore, NewMockProcessManager())
+	if err := os.MkdirAll(filepath.Dir(dm.LogPath()), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dm.LogPath(), []byte("fatal: out of memory\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := dm.Status()
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if status.Running {
+		t.Fatal("Status().Running = true, want false")
+	}
+	if status.LastCrash == nil {
+		t.Fatal("Status().LastCrash = nil, want crash report")
+	}
+	if status.LastCrash.PID != 12345 {
+		t.Errorf("LastCrash.PID = %d, want 12345", status.LastCrash.PID)
+	}
+	if len(status.LastCrash.Stderr) != 1 || status.LastCrash.Stderr[0] != "fatal: out of memory" {
+		t.Errorf("LastCrash.Stderr = %q, want the log tail", status.LastCrash.Stderr)
+	}
+	if !configStore.DeleteCalled {
+		t.Error("Expected stale config to be deleted")
+	}
+
+	// The report survives later status checks
+	status, err = dm.Status()
+	if err != nil {
+		t.Fatalf("second Status() error = %v", err)
+	}
+	if status.LastCrash == nil {
+		t.Error("second Status().LastCrash = nil, want crash report")
+	}
+}
+
+func TestDaemonManager_TakeUnreportedCrash(t *testing.T) {
+	dm := newCrashTestManager(t, &MockConfigStore{}, NewMockProcessManager())
+	if err := dm.saveCrash(&CrashReport{PID: 1, ExitCode: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := dm.TakeUnreportedCrash()
+	if err != nil {
+		t.Fatalf("TakeUnreportedCrash() error = %v", err)
+	}
+	if first == nil {
+		t.Fatal("TakeUnreportedCrash() = nil, want report")
+	}
+
+	second, err := dm.TakeUnreportedCrash()
+	if err != nil {
+		t.Fatalf("second TakeUnreportedCrash() error = %v", err)
+	}
+	if second != nil {
+		t.Error("second TakeUnreportedCrash() returned the report again")
+	}
+
+	if last, _ := dm.LastCrash(); last == nil {
+		t.Error("LastCrash() = nil after reporting, want report kept for /server status")
+	}
+}
+
+func TestDaemonManager_Start_ClearsCrash(t *testing.T) {
+	procMgr := NewMockProcessManager()
+	procMgr.startPID = 12345
+	dm := newCrashTestManager(t, &MockConfigStore{}, procMgr)
+	if err := dm.saveCrash(&CrashReport{PID: 1, ExitCode: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := dm.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if last, _ := dm.LastCrash(); last != nil {
+		t.Errorf("LastCrash() = %+v after successful start, want nil", last)
+	}
+	if _, err := os.Stat(dm.LogPath()); err != nil {
+		t.Errorf("daemon log not created: %v", err)
+	}
+}
+
+func TestDaemonManager_HandleExit(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    *DaemonConfig
+		stopping  bool
+		wantCrash bool
+	}{
+		{"unexpected exit", &DaemonConfig{PID: 42}, false, true},
+		{"stopped here", &DaemonConfig{PID: 42}, true, false},
+		{"stopped elsewhere", nil, false, false},
+		{"replaced by newer daemon", &DaemonConfig{PID: 43}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dm := newCrashTestManager(t, &MockConfigStore{config: tt.config}, NewMockProcessManager())
+			if tt.stopping {
+				dm.markStopping(42)
+			}
+
+			dm.handleExit(42, nil)
+
+			last, err := dm.LastCrash()
+			if err != nil {
+				t.Fatalf("LastCrash() error = %v", err)
+			}
+			if (last != nil) != tt.wantCrash {
+				t.Errorf("crash recorded = %v, want %v", last != nil, tt.wantCrash)
+			}
+		})
+	}
+}
+
+func TestOSProcessManager_OnExit(t *testing.T) {
+	exited := make(chan int, 1)
+	pm := &OSProcessManager{
+		OnExit: func(pid int, state *os.ProcessState) {
+			exited <- state.ExitCode()
+		},
+	}
+
+	logPath := filepath.Join(t.TempDir(), "server.log")
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logFile.Close()
+
+	if _, err := pm.StartProcess("sh", []string{"-c", "echo boom >&2; exit 3"}, nil, logFile); err != nil {
+		t.Fatalf("StartProcess() error = %v", err)
+	}
+
+	select {
+	case code := <-exited:
+		if code != 3 {
+			t.Errorf("exit code = %d, want 3", code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnExit was not called")
+	}
+
+	report := newCrashReport(1, nil, logPath)
+	if len(report.Stderr) != 1 || report.Stderr[0] != "boom" {
+		t.Errorf("Stderr = %q, want [boom]", report.Stderr)
+	}
+}
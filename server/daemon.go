@@ -6,16 +6,24 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
+	"strconv"
+	"sync"
 	"syscall"
 	"time"
+
+	"atulm/cocli/config"
+	"atulm/cocli/storage"
 )
 
 const (
-	// DefaultPort is the default port for the daemon
+	// DefaultPort is the base daemon port; each user's default is derived
+	// from it by UserPort
 	DefaultPort = 4321
 
 	// startTimeout is how long to wait for daemon to start
@@ -26,12 +34,19 @@ const (
 
 	// healthCheckTimeout is how long to wait for health check
 	healthCheckTimeout = 5 * time.Second
+
+	// startLockName serializes daemon starts between cocli processes
+	startLockName = "start"
 )
 
 var (
 	// ErrDaemonAlreadyRunning is returned when trying to start an already running daemon
 	ErrDaemonAlreadyRunning = errors.New("daemon is already running")
 
+	// ErrDaemonStarting is returned when another process is already starting
+	// the daemon
+	ErrDaemonStarting = errors.New("daemon is already starting")
+
 	// ErrDaemonNotRunning is returned when trying to stop a daemon that isn't running
 	ErrDaemonNotRunning = errors.New("daemon is not running")
 
@@ -40,6 +55,9 @@ var (
 
 	// ErrStartTimeout is returned when daemon fails to start within timeout
 	ErrStartTimeout = errors.New("daemon failed to start within timeout")
+
+	// ErrDaemonExited is returned when the daemon process exits during startup
+	ErrDaemonExited = errors.New("daemon process exited during startup")
 )
 
 // DaemonStatus represents current daemon state
@@ -49,12 +67,31 @@ type DaemonStatus struct {
 	Port      int
 	StartedAt time.Time
 	Uptime    time.Duration
+	// Restarts counts automatic restarts after crashes
+	Restarts int
+	// TLSAddr is where the daemon accepts TLS connections, if it does
+	TLSAddr string
+	// CLIPath is the copilot CLI the daemon runs
+	CLIPath string
+	// HealthLatency is how long the health check took
+	HealthLatency time.Duration
+	// Version is the copilot CLI version and Clients counts the clients
+	// connected through the auth gate (-1 when unknown); only Inspect
+	// fills them in
+	Version string
+	Clients int
+	// LastCrash is set when the daemon is not running because it crashed
+	LastCrash *CrashReport
 }
 
 // ProcessManager interface for process operations
 type ProcessManager interface {
-	// IsRunning checks if a process with the given PID is running
-	IsRunning(pid int) bool
+	// IsRunning checks if a process with the given PID is running and, when
+	// id is not nil, that it is the process id describes rather than a later
+	// one given the same PID
+	IsRunning(pid int, id *ProcessIdentity) bool
+	// Identify describes the running process with the given PID
+	Identify(pid int) (*ProcessIdentity, error)
 	// Kill terminates a process with the given PID
 	Kill(pid int) error
 	// StartProcess starts a new process and returns its PID
@@ -63,8 +100,11 @@ type ProcessManager interface {
 
 // HealthChecker interface for server health checks
 type HealthChecker interface {
-	// Ping checks if the server at host:port is responding
-	Ping(host string, port int, timeout time.Duration) error
+	// Ping checks if the server at addr (a unix socket path or host:port,
+	// as for net.Dial) is responding
+	Ping(network, addr string, timeout time.Duration) error
+	// Inspect asks the server at addr about itself
+	Inspect(network, addr string, timeout time.Duration) (*ServerInfo, error)
 }
 
 // CLIFinder interface for locating the copilot CLI
@@ -81,6 +121,24 @@ type DaemonManager struct {
 	cliFinder    CLIFinder
 	port         int
 	startTimeout time.Duration // Configurable for testing
+
+	// portSet is true when port was configured, so a busy port is an error
+	// rather than skipped
+	portSet bool
+
+	// logPath captures the daemon's output and crashPath holds the last
+	// crash report; both are empty when crash capture is disabled
+	logPath   string
+	crashPath string
+
+	mu       sync.Mutex
+	stopping map[int]bool // PIDs being stopped deliberately
+
+	// token caches the token of the daemon started at tokenStarted by
+	// tokenPID, so the secret store is asked once per daemon
+	token        string
+	tokenPID     int
+	tokenStarted time.Time
 }
 
 // NewDaemonManager creates a DaemonManager with the given dependencies
@@ -102,19 +160,41 @@ func NewDaemonManager(
 
 // DefaultDaemonManager creates a DaemonManager with default implementations
 func DefaultDaemonManager() (*DaemonManager, error) {
-	config, err := DefaultConfigStore()
+	store, err := DefaultConfigStore()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create config store: %w", err)
 	}
 
-	return &DaemonManager{
-		config:       config,
-		process:      &OSProcessManager{},
-		health:       &SDKHealthChecker{},
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return nil, err
+	}
+	health, err := NewHealthChecker(cfg.Daemon.HealthCheck)
+	if err != nil {
+		return nil, err
+	}
+
+	process := &OSProcessManager{}
+	d := &DaemonManager{
+		config:       store,
+		process:      process,
+		health:       health,
 		cliFinder:    &EnvCLIFinder{},
-		port:         DefaultPort,
+		port:         UserPort(),
 		startTimeout: startTimeout,
-	}, nil
+	}
+	if cfg.Daemon.Port > 0 {
+		d.port, d.portSet = cfg.Daemon.Port, true
+	}
+	if cfg.Daemon.StartTimeout > 0 {
+		d.startTimeout = time.Duration(cfg.Daemon.StartTimeout)
+	}
+	process.OnExit = d.handleExit
+	d.SetStateDir(filepath.Dir(store.GetPath()))
+	if logDir, err := DefaultLogDir(); err == nil {
+		d.SetLogDir(logDir)
+	}
+	return d, nil
 }
 
 // SetStartTimeout sets the start timeout (useful for testing)
@@ -124,6 +204,20 @@ func (d *DaemonManager) SetStartTimeout(timeout time.Duration) {
 
 // Start starts the daemon
 func (d *DaemonManager) Start() error {
+	return d.start(os.Stdout, 0)
+}
+
+// start starts the daemon, reporting progress to out. restarts is recorded
+// in the daemon config: how many times the supervisor has restarted it.
+func (d *DaemonManager) start(out io.Writer, restarts int) error {
+	// Only one process starts the daemon at a time; without this two shells
+	// could both find it stopped and launch one each
+	lock, err := d.lockStart()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
 	// Check if already running
 	if d.IsRunning() {
 		return ErrDaemonAlreadyRunning
@@ -135,33 +229,72 @@ func (d *DaemonManager) Start() error {
 	// Find CLI
 	cliPath, err := d.cliFinder.FindCLI()
 	if err != nil {
-		return fmt.Errorf("%w: %v", ErrCLINotFound, err)
+		return NewCLINotFoundError(fmt.Errorf("%w: %v", ErrCLINotFound, err))
 	}
 
-	fmt.Printf("Starting daemon on port %d...\n", d.port)
+	d.pickPort()
+	fmt.Fprintf(out, "Starting daemon on port %d...\n", d.port)
 
-	// Start process with --server mode (matches SDK's CLI invocation)
-	args := []string{
-		"--server",
-		"--port", fmt.Sprintf("%d", d.port),
-		"--no-auto-update",
-		"--log-level", "error", // Only log errors to reduce noise
+	// The daemon is `cocli server run --managed`: it runs the copilot
+	// server on its stdio, serves it to this user on a private socket and
+	// to others on d.port through an auth gate that requires the token
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+	socket, err := d.socketPath()
+	if err != nil {
+		return fmt.Errorf("failed to pick a socket for the copilot server: %w", err)
 	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the cocli binary: %w", err)
+	}
+	if err := d.writeTokenFile(token); err != nil {
+		return fmt.Errorf("failed to pass the daemon token: %w", err)
+	}
+	args := []string{"server", "run", "--managed", "--socket", socket, "--port", strconv.Itoa(d.port)}
 
-	// Discard stdout/stderr to avoid noise from server health check messages
-	pid, err := d.process.StartProcess(cliPath, args, io.Discard, io.Discard)
+	// Capture the daemon's output in its log so startup failures and
+	// crashes can be explained later (/server logs)
+	logFile, closeLog, err := d.openLog()
+	if err != nil {
+		return fmt.Errorf("failed to open daemon log: %w", err)
+	}
+	pid, err := d.process.StartProcess(exe, args, logFile, logFile)
+	closeLog()
 	if err != nil {
 		return fmt.Errorf("failed to start daemon process: %w", err)
 	}
+	// Recorded so a later process given the same PID isn't taken for the
+	// daemon
+	identity, _ := d.process.Identify(pid)
 
 	// Wait for health check with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), d.startTimeout)
 	defer cancel()
 
-	if err := d.waitForHealthy(ctx); err != nil {
+	if err := d.waitForHealthy(ctx, pid, socket); err != nil {
 		// Kill the process if health check fails
+		d.markStopping(pid)
 		_ = d.process.Kill(pid)
-		return fmt.Errorf("daemon started but health check failed: %w", err)
+		context := []ContextItem{
+			{Label: "copilot CLI", Value: cliPath},
+			{Label: "port", Value: fmt.Sprintf("%d", d.port)},
+			{Label: "config", Value: d.config.GetPath()},
+		}
+		if d.logPath != "" {
+			context = append(context, ContextItem{Label: "log", Value: d.logPath})
+		}
+		for _, line := range d.stderrTail() {
+			context = append(context, ContextItem{Label: "stderr", Value: line})
+		}
+		return &ActionableError{
+			Err:     fmt.Errorf("daemon started but health check failed: %w", err),
+			Context: context,
+			Fix: fmt.Sprintf("Check that port %d is free (e.g. `lsof -i :%d`) and that `%s --server --stdio` starts on its own, then retry /server start.",
+				d.port, d.port, cliPath),
+		}
 	}
 
 	// Save config
@@ -169,6 +302,12 @@ func (d *DaemonManager) Start() error {
 		PID:       pid,
 		Port:      d.port,
 		StartedAt: time.Now(),
+		Restarts:  restarts,
+		Token:     token,
+		CLIPath:   cliPath,
+		Process:   identity,
+		// The health checks and local clients use the private socket
+		Socket: socket,
 	}
 	if err := d.config.Save(config); err != nil {
 		// Kill the process if we can't save config
@@ -176,12 +315,53 @@ func (d *DaemonManager) Start() error {
 		return fmt.Errorf("failed to save daemon config: %w", err)
 	}
 
-	fmt.Printf("Daemon started (PID: %d)\n", pid)
+	d.clearCrash()
+
+	fmt.Fprintf(out, "Daemon started (PID: %d)\n", pid)
 	return nil
 }
 
-// waitForHealthy waits for the daemon to become healthy
-func (d *DaemonManager) waitForHealthy(ctx context.Context) error {
+// lockStart takes the start lock without waiting, returning
+// ErrDaemonStarting if another process holds it. Without a state directory
+// there is nothing to lock and it returns a nil lock.
+func (d *DaemonManager) lockStart() (*storage.FileLock, error) {
+	if d.crashPath == "" {
+		return nil, nil
+	}
+	lock, err := storage.TryLock(filepath.Join(filepath.Dir(d.crashPath), startLockName))
+	if errors.Is(err, storage.ErrLocked) {
+		return nil, ErrDaemonStarting
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock daemon start: %w", err)
+	}
+	return lock, nil
+}
+
+// waitForStart blocks until no other process is starting the daemon
+func (d *DaemonManager) waitForStart() {
+	if d.crashPath == "" {
+		return
+	}
+	if lock, err := storage.Lock(filepath.Join(filepath.Dir(d.crashPath), startLockName)); err == nil {
+		lock.Unlock()
+	}
+}
+
+// serverArgs returns the copilot CLI arguments for running the server on
+// its stdio
+func serverArgs() []string {
+	return []string{
+		"--server",
+		"--stdio",
+		"--no-auto-update",
+		"--log-level", "error", // Only log errors to reduce noise
+	}
+}
+
+// waitForHealthy waits for the copilot server on socket to become healthy,
+// failing early if the daemon process exits
+func (d *DaemonManager) waitForHealthy(ctx context.Context, pid int, socket string) error {
 	ticker := time.NewTicker(500 * time.Millisecond)
 	defer ticker.Stop()
 
@@ -190,9 +370,12 @@ func (d *DaemonManager) waitForHealthy(ctx context.Context) error {
 		case <-ctx.Done():
 			return ErrStartTimeout
 		case <-ticker.C:
-			if err := d.health.Ping("localhost", d.port, healthCheckTimeout); err == nil {
+			if err := d.health.Ping("unix", socket, healthCheckTimeout); err == nil {
 				return nil
 			}
+			if !d.process.IsRunning(pid, nil) {
+				return ErrDaemonExited
+			}
 		}
 	}
 }
@@ -208,24 +391,28 @@ func (d *DaemonManager) Stop() error {
 	}
 
 	// Check if process is actually running
-	if !d.process.IsRunning(config.PID) {
-		// Process already dead, just clean up config
-		_ = d.config.Delete()
+	if !d.process.IsRunning(config.PID, config.Process) {
+		// Process died on its own; record why and clean up config
+		d.recordCrash(config.PID, nil)
 		return ErrDaemonNotRunning
 	}
 
 	fmt.Printf("Stopping daemon (PID: %d)...\n", config.PID)
+	d.markStopping(config.PID)
+
+	// Delete config before killing so a cocli process watching the daemon
+	// doesn't mistake the exit for a crash
+	if err := d.config.Delete(); err != nil {
+		return fmt.Errorf("failed to delete config: %w", err)
+	}
 
 	// Kill the process
 	if err := d.process.Kill(config.PID); err != nil {
+		// Still running; keep tracking it
+		_ = d.config.Save(config)
 		return fmt.Errorf("failed to stop daemon: %w", err)
 	}
 
-	// Delete config
-	if err := d.config.Delete(); err != nil {
-		return fmt.Errorf("failed to delete config: %w", err)
-	}
-
 	fmt.Println("Daemon stopped")
 	return nil
 }
@@ -235,25 +422,26 @@ func (d *DaemonManager) Status() (*DaemonStatus, error) {
 	config, err := d.config.Load()
 	if err != nil {
 		if errors.Is(err, ErrConfigNotFound) {
-			return &DaemonStatus{Running: false}, nil
+			return d.notRunningStatus(), nil
 		}
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
-	// Check if process is running and healthy
-	running := d.process.IsRunning(config.PID)
-	if running {
-		// Verify with health check
-		if err := d.health.Ping("localhost", config.Port, healthCheckTimeout); err != nil {
-			running = false
-		}
+	// A recorded daemon whose process is gone died without being stopped
+	if !d.process.IsRunning(config.PID, config.Process) {
+		d.recordCrash(config.PID, nil)
+		return d.notRunningStatus(), nil
 	}
 
-	if !running {
+	// Verify with health check
+	network, addr := config.serverAddr()
+	pingStart := time.Now()
+	if err := d.health.Ping(network, addr, healthCheckTimeout); err != nil {
 		// Clean up stale config
 		_ = d.config.Delete()
-		return &DaemonStatus{Running: false}, nil
+		return d.notRunningStatus(), nil
 	}
+	latency := time.Since(pingStart)
 
 	return &DaemonStatus{
 		Running:   true,
@@ -261,9 +449,48 @@ func (d *DaemonManager) Status() (*DaemonStatus, error) {
 		Port:      config.Port,
 		StartedAt: config.StartedAt,
 		Uptime:    time.Since(config.StartedAt),
+		Restarts:  config.Restarts,
+		TLSAddr:   config.TLSAddr,
+		CLIPath:   config.CLIPath,
+
+		HealthLatency: latency,
+		Clients:       -1,
 	}, nil
 }
 
+// Inspect returns Status along with what the running daemon reports about
+// itself. It is slower than Status, so it is meant for showing to the user.
+func (d *DaemonManager) Inspect() (*DaemonStatus, error) {
+	status, err := d.Status()
+	if err != nil || !status.Running {
+		return status, err
+	}
+	config, err := d.config.Load()
+	if err != nil {
+		return status, nil
+	}
+	network, addr := config.serverAddr()
+	if info, err := d.health.Inspect(network, addr, healthCheckTimeout); err == nil {
+		status.Version = info.Version
+	}
+	if token := d.tokenOf(config); token != "" {
+		addr := net.JoinHostPort("localhost", strconv.Itoa(config.Port))
+		if conn, err := net.DialTimeout("tcp", addr, healthCheckTimeout); err == nil {
+			if n, err := ConnectedClients(conn, token); err == nil {
+				status.Clients = n
+			}
+			conn.Close()
+		}
+	}
+	return status, nil
+}
+
+// notRunningStatus reports a stopped daemon along with its last crash, if any
+func (d *DaemonManager) notRunningStatus() *DaemonStatus {
+	crash, _ := d.LastCrash()
+	return &DaemonStatus{Running: false, LastCrash: crash}
+}
+
 // IsRunning returns true if the daemon is running and healthy
 func (d *DaemonManager) IsRunning() bool {
 	status, err := d.Status()
@@ -273,6 +500,24 @@ func (d *DaemonManager) IsRunning() bool {
 	return status.Running
 }
 
+// Port returns the port the daemon is started on
+func (d *DaemonManager) Port() int {
+	return d.port
+}
+
+// SetPort sets the port the daemon is started on, as if configured
+func (d *DaemonManager) SetPort(port int) {
+	d.port, d.portSet = port, true
+}
+
+// pickPort moves a default port that another program (or user) holds to
+// the next free one
+func (d *DaemonManager) pickPort() {
+	if !d.portSet {
+		d.port = nextFreePort(d.port)
+	}
+}
+
 // GetPort returns the daemon port if running
 func (d *DaemonManager) GetPort() (int, error) {
 	status, err := d.Status()
@@ -285,13 +530,60 @@ func (d *DaemonManager) GetPort() (int, error) {
 	return status.Port, nil
 }
 
+// Token returns the token clients present to the running daemon, or "" if
+// it has none
+func (d *DaemonManager) Token() string {
+	config, err := d.config.Load()
+	if err != nil {
+		return ""
+	}
+	return d.tokenOf(config)
+}
+
+// tokenOf returns config's token, asking the config store only the first
+// time for each daemon
+func (d *DaemonManager) tokenOf(config *DaemonConfig) string {
+	if config.Token != "" {
+		return config.Token
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if config.PID == d.tokenPID && config.StartedAt.Equal(d.tokenStarted) {
+		return d.token
+	}
+	token, err := d.config.Token(config)
+	if err != nil {
+		// Without the token only the private socket can be used
+		slog.Debug("daemon token not found", "key", config.TokenRef, "err", err)
+	}
+	d.token, d.tokenPID, d.tokenStarted = token, config.PID, config.StartedAt
+	return token
+}
+
+// Socket returns the private socket of the running daemon, which its owner
+// connects to without a token, or "" for daemons started by older versions
+// of cocli
+func (d *DaemonManager) Socket() string {
+	config, err := d.config.Load()
+	if err != nil {
+		return ""
+	}
+	return config.Socket
+}
+
 // --- Default Implementations ---
 
 // OSProcessManager implements ProcessManager using os/exec
-type OSProcessManager struct{}
+type OSProcessManager struct {
+	// OnExit, if set, is called with the exit state of each process started
+	// by StartProcess that exits while this process is still alive
+	OnExit func(pid int, state *os.ProcessState)
+}
 
-// IsRunning checks if a process with the given PID is running
-func (p *OSProcessManager) IsRunning(pid int) bool {
+// IsRunning checks if a process with the given PID is running. A process
+// that doesn't match id, when given, has reused the PID and doesn't count;
+// where the OS can't identify processes the PID alone decides.
+func (p *OSProcessManager) IsRunning(pid int, id *ProcessIdentity) bool {
 	if pid <= 0 {
 		return false
 	}
@@ -300,8 +592,22 @@ func (p *OSProcessManager) IsRunning(pid int) bool {
 		return false
 	}
 	// On Unix, FindProcess always succeeds, so we send signal 0 to check
-	err = process.Signal(syscall.Signal(0))
-	return err == nil
+	if err := process.Signal(syscall.Signal(0)); err != nil {
+		return false
+	}
+	if id == nil {
+		return true
+	}
+	current, err := processIdentity(pid)
+	if err != nil {
+		return errors.Is(err, errIdentityUnsupported)
+	}
+	return id.matches(current)
+}
+
+// Identify describes the running process with the given PID
+func (p *OSProcessManager) Identify(pid int) (*ProcessIdentity, error) {
+	return processIdentity(pid)
 }
 
 // Kill terminates a process with the given PID
@@ -352,35 +658,25 @@ func (p *OSProcessManager) StartProcess(name string, args []string, stdout, stde
 		return 0, err
 	}
 
-	// Don't wait for the process - let it run in background
+	// Don't wait for the process - let it run in background, reporting its
+	// exit if anyone is listening
 	go func() {
 		_ = cmd.Wait()
+		if p.OnExit != nil && cmd.ProcessState != nil {
+			p.OnExit(cmd.Process.Pid, cmd.ProcessState)
+		}
 	}()
 
 	return cmd.Process.Pid, nil
 }
 
-// SDKHealthChecker implements HealthChecker using a simple TCP connection check
-type SDKHealthChecker struct{}
-
-// Ping checks if the server at host:port is responding by attempting a TCP connection
-func (h *SDKHealthChecker) Ping(host string, port int, timeout time.Duration) error {
-	addr := fmt.Sprintf("%s:%d", host, port)
-	conn, err := net.DialTimeout("tcp", addr, timeout)
-	if err != nil {
-		return err
-	}
-	conn.Close()
-	return nil
-}
-
 // EnvCLIFinder implements CLIFinder checking env then PATH
 type EnvCLIFinder struct{}
 
 // FindCLI returns the path to the copilot CLI executable
 func (f *EnvCLIFinder) FindCLI() (string, error) {
 	// Check environment variable first
-	if path := os.Getenv("COPILOT_CLI_PATH"); path != "" {
+	if path := config.CopilotCLIPath(); path != "" {
 		if _, err := os.Stat(path); err == nil {
 			return path, nil
 		}
@@ -4,8 +4,11 @@ import (
 	"errors"
 	"io"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
+
+	"atulm/cocli/storage"
 )
 
 // --- Mock Implementations ---
@@ -31,6 +34,10 @@ func (m *MockConfigStore) Load() (*DaemonConfig, error) {
 	return m.config, nil
 }
 
+func (m *MockConfigStore) Token(config *DaemonConfig) (string, error) {
+	return config.Token, nil
+}
+
 func (m *MockConfigStore) Save(config *DaemonConfig) error {
 	m.SaveCalled = true
 	m.SavedConfig = config
@@ -57,6 +64,9 @@ func (m *MockConfigStore) GetPath() string {
 // MockProcessManager implements ProcessManager for testing
 type MockProcessManager struct {
 	runningPIDs map[int]bool
+	// identities describes running processes; those without one match any
+	// identity
+	identities  map[int]*ProcessIdentity
 	killErr     error
 	startPID    int
 	startErr    error
@@ -67,11 +77,22 @@ type MockProcessManager struct {
 func NewMockProcessManager() *MockProcessManager {
 	return &MockProcessManager{
 		runningPIDs: make(map[int]bool),
+		identities:  make(map[int]*ProcessIdentity),
 	}
 }
 
-func (m *MockProcessManager) IsRunning(pid int) bool {
-	return m.runningPIDs[pid]
+func (m *MockProcessManager) IsRunning(pid int, id *ProcessIdentity) bool {
+	if !m.runningPIDs[pid] {
+		return false
+	}
+	return id == nil || m.identities[pid] == nil || id.matches(m.identities[pid])
+}
+
+func (m *MockProcessManager) Identify(pid int) (*ProcessIdentity, error) {
+	if id := m.identities[pid]; id != nil {
+		return id, nil
+	}
+	return nil, errIdentityUnsupported
 }
 
 func (m *MockProcessManager) Kill(pid int) error {
@@ -96,9 +117,10 @@ func (m *MockProcessManager) StartProcess(name string, args []string, stdout, st
 type MockHealthChecker struct {
 	healthy bool
 	pingErr error
+	info    *ServerInfo
 }
 
-func (m *MockHealthChecker) Ping(host string, port int, timeout time.Duration) error {
+func (m *MockHealthChecker) Ping(network, addr string, timeout time.Duration) error {
 	if !m.healthy {
 		if m.pingErr != nil {
 			return m.pingErr
@@ -108,6 +130,13 @@ func (m *MockHealthChecker) Ping(host string, port int, timeout time.Duration) e
 	return nil
 }
 
+func (m *MockHealthChecker) Inspect(network, addr string, timeout time.Duration) (*ServerInfo, error) {
+	if m.info == nil {
+		return nil, errors.New("inspect not supported")
+	}
+	return m.info, nil
+}
+
 // MockCLIFinder implements CLIFinder for testing
 type MockCLIFinder struct {
 	path string
@@ -311,6 +340,29 @@ func TestDaemonManager_Start_AlreadyRunning(t *testing.T) {
 	}
 }
 
+func TestDaemonManager_Start_AlreadyStarting(t *testing.T) {
+	procMgr := NewMockProcessManager()
+	procMgr.startPID = 12345
+	dm := newCrashTestManager(t, &MockConfigStore{}, procMgr)
+
+	// Another process is partway through /server start
+	lock, err := storage.TryLock(filepath.Join(filepath.Dir(dm.crashPath), startLockName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dm.Start(); !errors.Is(err, ErrDaemonStarting) {
+		t.Errorf("Start() error = %v, want ErrDaemonStarting", err)
+	}
+	if procMgr.StartCalled {
+		t.Error("Start() launched a daemon while another start held the lock")
+	}
+
+	lock.Unlock()
+	if err := dm.Start(); err != nil {
+		t.Errorf("Start() after the other start finished: error = %v", err)
+	}
+}
+
 func TestDaemonManager_Start_CLINotFound(t *testing.T) {
 	dm := NewDaemonManager(
 		&MockConfigStore{},
@@ -369,6 +421,8 @@ func TestDaemonManager_Start_Success(t *testing.T) {
 	configStore := &MockConfigStore{}
 	procMgr := NewMockProcessManager()
 	procMgr.startPID = 12345
+	identity := &ProcessIdentity{StartTime: time.Now(), Name: "cocli"}
+	procMgr.identities[12345] = identity
 
 	dm := NewDaemonManager(
 		configStore,
@@ -395,6 +449,9 @@ func TestDaemonManager_Start_Success(t *testing.T) {
 	if configStore.SavedConfig.Port != DefaultPort {
 		t.Errorf("SavedConfig.Port = %d, want %d", configStore.SavedConfig.Port, DefaultPort)
 	}
+	if configStore.SavedConfig.Process != identity {
+		t.Errorf("SavedConfig.Process = %+v, want the daemon's identity", configStore.SavedConfig.Process)
+	}
 }
 
 func TestDaemonManager_Stop_NotRunning(t *testing.T) {
@@ -495,10 +552,10 @@ func TestDaemonManager_Stop_Success(t *testing.T) {
 func TestOSProcessManager_IsRunning_InvalidPID(t *testing.T) {
 	pm := &OSProcessManager{}
 
-	if pm.IsRunning(0) {
+	if pm.IsRunning(0, nil) {
 		t.Error("IsRunning(0) = true, want false")
 	}
-	if pm.IsRunning(-1) {
+	if pm.IsRunning(-1, nil) {
 		t.Error("IsRunning(-1) = true, want false")
 	}
 }
@@ -508,7 +565,7 @@ func TestOSProcessManager_IsRunning_CurrentProcess(t *testing.T) {
 
 	// Current process should be running
 	pid := currentPID()
-	if !pm.IsRunning(pid) {
+	if !pm.IsRunning(pid, nil) {
 		t.Errorf("IsRunning(%d) = false, want true for current process", pid)
 	}
 }
@@ -517,7 +574,7 @@ func TestOSProcessManager_IsRunning_NonExistentPID(t *testing.T) {
 	pm := &OSProcessManager{}
 
 	// Very high PID unlikely to exist
-	if pm.IsRunning(999999999) {
+	if pm.IsRunning(999999999, nil) {
 		t.Error("IsRunning(999999999) = true, want false for non-existent PID")
 	}
 }
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"atulm/cocli/config"
+)
+
+// ContextItem is a single labelled piece of environment context attached to an error
+type ContextItem struct {
+	Label string
+	Value string
+}
+
+// ActionableError wraps a startup failure with the environment that was
+// consulted (paths, env vars) and a suggested fix, so the user can act on it
+// without reading the source.
+type ActionableError struct {
+	Err     error
+	Context []ContextItem
+	Fix     string
+}
+
+// Error formats the wrapped error followed by its context and suggested fix
+func (e *ActionableError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Err.Error())
+	for _, item := range e.Context {
+		fmt.Fprintf(&b, "\n  %-20s %s", item.Label+":", item.Value)
+	}
+	if e.Fix != "" {
+		fmt.Fprintf(&b, "\n\nSuggested fix: %s", e.Fix)
+	}
+	return b.String()
+}
+
+// Unwrap returns the underlying error so errors.Is/As keep working
+func (e *ActionableError) Unwrap() error {
+	return e.Err
+}
+
+// NewCLINotFoundError returns an actionable error for a missing copilot CLI
+func NewCLINotFoundError(err error) *ActionableError {
+	return &ActionableError{
+		Err:     err,
+		Context: CLIEnvContext(),
+		Fix: "Install the GitHub Copilot CLI (https://docs.github.com/en/copilot/how-tos/set-up/install-copilot-cli) " +
+			"or set COPILOT_CLI_PATH to the copilot executable.",
+	}
+}
+
+// NewAuthError returns an actionable error for an unauthenticated copilot CLI
+func NewAuthError(err error, statusMessage string) *ActionableError {
+	context := []ContextItem{
+		{Label: "GH_TOKEN", Value: EnvPresence("GH_TOKEN")},
+		{Label: "GITHUB_TOKEN", Value: EnvPresence("GITHUB_TOKEN")},
+	}
+	if statusMessage != "" {
+		context = append(context, ContextItem{Label: "auth status", Value: statusMessage})
+	}
+	return &ActionableError{
+		Err:     err,
+		Context: context,
+		Fix:     "Run `copilot` and sign in with /login, or export GH_TOKEN with a token that has Copilot access.",
+	}
+}
+
+// NewOfflineError returns an actionable error for a failure to reach GitHub
+func NewOfflineError(err error) *ActionableError {
+	return &ActionableError{
+		Err: err,
+		Context: []ContextItem{
+			{Label: "HTTPS_PROXY", Value: EnvPresence("HTTPS_PROXY")},
+			{Label: "NO_PROXY", Value: EnvValue("NO_PROXY")},
+		},
+		Fix: "Check your network connection; Copilot needs to reach api.github.com. " +
+			"Behind a proxy, export HTTPS_PROXY before starting cocli (and the daemon).",
+	}
+}
+
+// NewDaemonDownError returns an actionable error for a local daemon that
+// stopped answering and couldn't be reconnected to
+func NewDaemonDownError(err error, port int) *ActionableError {
+	return &ActionableError{
+		Err:     fmt.Errorf("the cocli daemon on port %d is not responding: %w", port, err),
+		Context: []ContextItem{{Label: "daemon", Value: fmt.Sprintf("localhost:%d", port)}},
+		Fix:     "Check it with /server status and /server logs, then restart it with /server start. The conversation can be continued with /resume.",
+	}
+}
+
+// offlineSigns are fragments of the errors seen when the network or DNS is down
+var offlineSigns = []string{
+	"no such host", "network is unreachable", "no route to host",
+	"temporary failure in name resolution", "server misbehaving",
+	"dial tcp: lookup", "getaddrinfo",
+}
+
+// IsOffline reports whether err comes from having no network connection
+func IsOffline(err error) bool {
+	if err == nil {
+		return false
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) || errors.Is(err, syscall.ENETUNREACH) || errors.Is(err, syscall.EHOSTUNREACH) {
+		return true
+	}
+	return containsAny(err.Error(), offlineSigns)
+}
+
+// authSigns are fragments of the errors the CLI fails with when it is signed
+// out or its token was revoked
+var authSigns = []string{
+	"not authenticated", "unauthenticated", "unauthorized", "status 401",
+	"bad credentials", "authentication required", "login required", "not logged in",
+	"token expired", "invalid token",
+}
+
+// IsAuthFailure reports whether err comes from the copilot CLI not being
+// signed in to GitHub
+func IsAuthFailure(err error) bool {
+	return err != nil && !errors.Is(err, ErrAuthRequired) && !errors.Is(err, ErrAuthDenied) &&
+		containsAny(err.Error(), authSigns)
+}
+
+func containsAny(s string, fragments []string) bool {
+	s = strings.ToLower(s)
+	for _, f := range fragments {
+		if strings.Contains(s, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// CLIEnvContext describes where the copilot CLI was looked for
+func CLIEnvContext() []ContextItem {
+	context := []ContextItem{{Label: config.EnvCopilotCLIPath, Value: EnvValue(config.EnvCopilotCLIPath)}}
+	if path, err := exec.LookPath("copilot"); err == nil {
+		context = append(context, ContextItem{Label: "copilot in PATH", Value: path})
+	} else {
+		context = append(context, ContextItem{Label: "copilot in PATH", Value: "not found"})
+	}
+	return append(context, ContextItem{Label: "PATH", Value: EnvValue("PATH")})
+}
+
+// EnvValue returns the value of an env var, or a marker when it is unset,
+// for error context
+func EnvValue(name string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return "(not set)"
+}
+
+// EnvPresence reports whether a secret env var is set without revealing it
+func EnvPresence(name string) string {
+	if os.Getenv(name) != "" {
+		return "set"
+	}
+	return "(not set)"
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestActionableError_Error(t *testing.T) {
+	err := &ActionableError{
+		Err: errors.New("failed to start client"),
+		Context: []ContextItem{
+			{Label: "COPILOT_CLI_PATH", Value: "(not set)"},
+			{Label: "port", Value: "4321"},
+		},
+		Fix: "Install the copilot CLI.",
+	}
+
+	msg := err.Error()
+	for _, want := range []string{
+		"failed to start client",
+		"COPILOT_CLI_PATH:",
+		"(not set)",
+		"port:",
+		"4321",
+		"Suggested fix: Install the copilot CLI.",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("Error() = %q, want it to contain %q", msg, want)
+		}
+	}
+}
+
+func TestActionableError_NoFix(t *testing.T) {
+	err := &ActionableError{Err: errors.New("boom")}
+	if got := err.Error(); got != "boom" {
+		t.Errorf("Error() = %q, want %q", got, "boom")
+	}
+}
+
+func TestActionableError_Unwrap(t *testing.T) {
+	err := NewCLINotFoundError(errors.Join(ErrCLINotFound, errors.New("not in PATH")))
+	if !errors.Is(err, ErrCLINotFound) {
+		t.Error("errors.Is(err, ErrCLINotFound) = false, want true")
+	}
+}
+
+func TestNewCLINotFoundError_Context(t *testing.T) {
+	t.Setenv("COPILOT_CLI_PATH", "/opt/copilot")
+	err := NewCLINotFoundError(ErrCLINotFound)
+
+	msg := err.Error()
+	if !strings.Contains(msg, "/opt/copilot") {
+		t.Errorf("Error() = %q, want it to mention COPILOT_CLI_PATH value", msg)
+	}
+	if !strings.Contains(msg, "copilot in PATH:") {
+		t.Errorf("Error() = %q, want it to mention the PATH lookup", msg)
+	}
+}
+
+func TestNewAuthError_HidesTokens(t *testing.T) {
+	t.Setenv("GH_TOKEN", "secret-token")
+	t.Setenv("GITHUB_TOKEN", "")
+	err := NewAuthError(errors.New("not authenticated"), "no credentials")
+
+	msg := err.Error()
+	if strings.Contains(msg, "secret-token") {
+		t.Errorf("Error() = %q, must not reveal token values", msg)
+	}
+	if !strings.Contains(msg, "no credentials") {
+		t.Errorf("Error() = %q, want auth status message", msg)
+	}
+	if !strings.Contains(msg, "/login") {
+		t.Errorf("Error() = %q, want login hint", msg)
+	}
+}
+
+func TestErrorClassification(t *testing.T) {
+	tests := []struct {
+		err         error
+		offline     bool
+		authFailure bool
+	}{
+		{err: &net.DNSError{Err: "no such host", Name: "api.github.com"}, offline: true},
+		{err: fmt.Errorf("dial: %w", syscall.ENETUNREACH), offline: true},
+		{err: errors.New("request failed: dial tcp: lookup api.githubcopilot.com: Temporary failure in name resolution"), offline: true},
+		{err: errors.New("session error: Not authenticated. Please log in."), authFailure: true},
+		{err: errors.New("API error: status 401: Bad credentials"), authFailure: true},
+		{err: fmt.Errorf("handshake: %w", ErrAuthDenied)},
+		{err: errors.New("connection refused")},
+		{err: nil},
+	}
+	for _, tt := range tests {
+		if got := IsOffline(tt.err); got != tt.offline {
+			t.Errorf("IsOffline(%v) = %v, want %v", tt.err, got, tt.offline)
+		}
+		if got := IsAuthFailure(tt.err); got != tt.authFailure {
+			t.Errorf("IsAuthFailure(%v) = %v, want %v", tt.err, got, tt.authFailure)
+		}
+	}
+}
+
+func TestNewDaemonDownError(t *testing.T) {
+	err := NewDaemonDownError(errors.New("broken pipe"), 4321)
+	msg := err.Error()
+	for _, want := range []string{"port 4321", "broken pipe", "/server start"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("Error() = %q, want it to contain %q", msg, want)
+		}
+	}
+}
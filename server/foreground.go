@@ -0,0 +1,302 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"atulm/cocli/storage"
+)
+
+// defaultCheckInterval is how often a ready foreground server is re-checked
+const defaultCheckInterval = 5 * time.Second
+
+// ForegroundOptions configures RunForeground
+type ForegroundOptions struct {
+	// ReadyAddr, if set, serves /readyz and /livez probes on this address
+	// (e.g. ":8080")
+	ReadyAddr string
+	// ReadyFile, if set, exists only while the server passes health checks
+	ReadyFile string
+	// Stderr receives the copilot server's log output; its stdout carries
+	// the protocol
+	Stderr io.Writer
+	// CheckInterval is the delay between health checks once the server is
+	// ready; it defaults to 5s
+	CheckInterval time.Duration
+	// TLSAddr, if set, accepts TLS connections from other machines on this
+	// address (e.g. ":4443") and forwards them to the server
+	TLSAddr string
+	// TLS holds the certificate for TLSAddr and, optionally, the CA that
+	// client certificates must be signed by
+	TLS TLSOptions
+	// Managed is set for the daemon launched by /server start, which
+	// passes the token and records the daemon in its state itself
+	Managed bool
+	// Socket is the private unix socket serving the copilot server to this
+	// user; one in the state directory is used when it is empty
+	Socket string
+	// Port, if set, replaces the daemon's port for clients on other
+	// machines
+	Port int
+}
+
+// RunForeground runs the copilot server attached to this process until ctx
+// is cancelled or the server exits, for containers and process supervisors.
+// Once the server passes its health check it is reported ready through the
+// optional probe endpoint and readiness file, and local cocli sessions can
+// connect to it as they would to a background daemon.
+func (d *DaemonManager) RunForeground(ctx context.Context, opts ForegroundOptions) error {
+	if !opts.Managed {
+		if d.IsRunning() {
+			return ErrDaemonAlreadyRunning
+		}
+		_ = d.config.Delete()
+	}
+
+	cliPath, err := d.cliFinder.FindCLI()
+	if err != nil {
+		return NewCLINotFoundError(fmt.Errorf("%w: %v", ErrCLINotFound, err))
+	}
+	if opts.Port > 0 {
+		d.SetPort(opts.Port)
+	}
+	d.pickPort()
+
+	var token string
+	if opts.Managed {
+		token, err = readTokenFile(d.tokenPath())
+	} else {
+		token, err = newToken()
+	}
+	if err != nil {
+		return err
+	}
+	socket := opts.Socket
+	if socket == "" {
+		if socket, err = d.socketPath(); err != nil {
+			return fmt.Errorf("failed to pick a socket for the copilot server: %w", err)
+		}
+		if d.crashPath == "" {
+			defer os.RemoveAll(filepath.Dir(socket))
+		}
+	}
+
+	// The server runs on this process's pipes, which nobody else can reach
+	cmd := exec.Command(cliPath, serverArgs()...)
+	cmd.Stderr = opts.Stderr
+	serverIn, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to start copilot server: %w", err)
+	}
+	serverOut, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to start copilot server: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start copilot server: %w", err)
+	}
+	stdio := newMux(serverIn)
+	go stdio.Run(serverOut)
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	clients := newGate(token)
+	// This user reaches the server on a private socket; anyone else only
+	// through the gate
+	private, err := ListenPrivate(socket)
+	if err != nil {
+		stopForeground(cmd, exited)
+		return fmt.Errorf("failed to listen on %s: %w", socket, err)
+	}
+	defer os.Remove(socket)
+	defer private.Close()
+	go clients.servePrivate(private, stdio.Dial)
+
+	gate, err := net.Listen("tcp", net.JoinHostPort("localhost", strconv.Itoa(d.port)))
+	if err != nil {
+		stopForeground(cmd, exited)
+		return fmt.Errorf("failed to listen on port %d: %w", d.port, err)
+	}
+	defer gate.Close()
+	go clients.serve(gate, stdio.Dial)
+
+	probes := &readiness{file: opts.ReadyFile}
+	if opts.ReadyAddr != "" {
+		ln, err := net.Listen("tcp", opts.ReadyAddr)
+		if err != nil {
+			stopForeground(cmd, exited)
+			return fmt.Errorf("failed to listen for readiness probes: %w", err)
+		}
+		srv := &http.Server{Handler: probes, ReadHeaderTimeout: healthCheckTimeout}
+		go srv.Serve(ln)
+		defer srv.Close()
+		slog.Info("Serving readiness probes (/readyz, /livez)", "addr", ln.Addr().String())
+	}
+
+	if opts.TLSAddr != "" {
+		tlsConfig, err := opts.TLS.ServerConfig()
+		if err != nil {
+			stopForeground(cmd, exited)
+			return err
+		}
+		ln, err := tls.Listen("tcp", opts.TLSAddr, tlsConfig)
+		if err != nil {
+			stopForeground(cmd, exited)
+			return fmt.Errorf("failed to listen for TLS connections: %w", err)
+		}
+		defer ln.Close()
+		go clients.serve(ln, stdio.Dial)
+		slog.Info("Accepting TLS connections", "addr", ln.Addr().String())
+	}
+
+	probes.setAlive(true)
+	defer probes.setReady(false)
+
+	// Let local cocli sessions find the server like a background daemon
+	if !opts.Managed {
+		identity, _ := d.process.Identify(os.Getpid())
+		config := &DaemonConfig{
+			PID:       os.Getpid(),
+			Port:      d.port,
+			StartedAt: time.Now(),
+			TLSAddr:   opts.TLSAddr,
+			Token:     token,
+			Socket:    socket,
+			CLIPath:   cliPath,
+			Process:   identity,
+		}
+		if err := d.config.Save(config); err != nil {
+			_ = cmd.Process.Kill()
+			return fmt.Errorf("failed to save daemon config: %w", err)
+		}
+		defer d.config.Delete()
+	}
+
+	slog.Info("Running copilot server in the foreground", "port", d.port, "pid", cmd.Process.Pid)
+
+	interval := opts.CheckInterval
+	if interval <= 0 {
+		interval = defaultCheckInterval
+	}
+	// Poll quickly until the first successful check, then back off
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	deadline := time.After(d.startTimeout)
+	everReady := false
+
+	for {
+		select {
+		case err := <-exited:
+			probes.setAlive(false)
+			if err != nil {
+				return fmt.Errorf("copilot server exited: %w", err)
+			}
+			return errors.New("copilot server exited")
+		case <-ctx.Done():
+			probes.setReady(false)
+			slog.Info("Stopping copilot server...")
+			stopForeground(cmd, exited)
+			return nil
+		case <-deadline:
+			if !everReady {
+				stopForeground(cmd, exited)
+				return ErrStartTimeout
+			}
+		case <-ticker.C:
+			healthy := d.health.Ping("unix", socket, healthCheckTimeout) == nil
+			probes.setReady(healthy)
+			if healthy && !everReady {
+				everReady = true
+				ticker.Reset(interval)
+				slog.Info("Copilot server is ready")
+			}
+		}
+	}
+}
+
+// stopForeground asks the server to exit and kills it if it doesn't
+func stopForeground(cmd *exec.Cmd, exited <-chan error) {
+	_ = cmd.Process.Signal(syscall.SIGTERM)
+	select {
+	case <-exited:
+	case <-time.After(stopTimeout):
+		_ = cmd.Process.Kill()
+		<-exited
+	}
+}
+
+// readiness tracks probe state for a foreground server and serves it over
+// HTTP: /livez while the server process runs, /readyz while it is healthy
+type readiness struct {
+	mu    sync.Mutex
+	alive bool
+	ready bool
+	file  string
+}
+
+func (r *readiness) setAlive(alive bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.alive = alive
+	if !alive {
+		r.setReadyLocked(false)
+	}
+}
+
+func (r *readiness) setReady(ready bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.setReadyLocked(ready)
+}
+
+// setReadyLocked updates readiness and the readiness file; r.mu must be held
+func (r *readiness) setReadyLocked(ready bool) {
+	if ready == r.ready {
+		return
+	}
+	r.ready = ready
+	if r.file == "" {
+		return
+	}
+	if ready {
+		_ = storage.WriteFileAtomic(r.file, []byte(time.Now().Format(time.RFC3339)+"\n"), 0644)
+	} else {
+		_ = os.Remove(r.file)
+	}
+}
+
+// ServeHTTP answers Kubernetes-style probes
+func (r *readiness) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	alive, ready := r.alive, r.ready
+	r.mu.Unlock()
+
+	var ok bool
+	switch req.URL.Path {
+	case "/livez":
+		ok = alive
+	case "/readyz":
+		ok = ready
+	default:
+		http.NotFound(w, req)
+		return
+	}
+	if !ok {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeServerCLI writes a script standing in for the copilot CLI that runs
+// until it is signalled
+func fakeServerCLI(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "copilot")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\nexec sleep 30\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadiness_Probes(t *testing.T) {
+	readyFile := filepath.Join(t.TempDir(), "ready")
+	r := &readiness{file: readyFile}
+
+	probe := func(path string) int {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+
+	if got := probe("/livez"); got != http.StatusServiceUnavailable {
+		t.Errorf("/livez before start = %d, want 503", got)
+	}
+
+	r.setAlive(true)
+	if got := probe("/livez"); got != http.StatusOK {
+		t.Errorf("/livez while alive = %d, want 200", got)
+	}
+	if got := probe("/readyz"); got != http.StatusServiceUnavailable {
+		t.Errorf("/readyz before healthy = %d, want 503", got)
+	}
+
+	r.setReady(true)
+	if got := probe("/readyz"); got != http.StatusOK {
+		t.Errorf("/readyz while healthy = %d, want 200", got)
+	}
+	if _, err := os.Stat(readyFile); err != nil {
+		t.Errorf("ready file missing while healthy: %v", err)
+	}
+
+	r.setAlive(false)
+	if got := probe("/readyz"); got != http.StatusServiceUnavailable {
+		t.Errorf("/readyz after exit = %d, want 503", got)
+	}
+	if _, err := os.Stat(readyFile); !os.IsNotExist(err) {
+		t.Errorf("ready file still present after exit: %v", err)
+	}
+
+	if got := probe("/other"); got != http.StatusNotFound {
+		t.Errorf("/other = %d, want 404", got)
+	}
+}
+
+func TestDaemonManager_RunForeground(t *testing.T) {
+	configStore := &MockConfigStore{}
+	dm := NewDaemonManager(
+		configStore,
+		NewMockProcessManager(),
+		&MockHealthChecker{healthy: true},
+		&MockCLIFinder{path: fakeServerCLI(t)},
+	)
+	readyFile := filepath.Join(t.TempDir(), "ready")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- dm.RunForeground(ctx, ForegroundOptions{ReadyFile: readyFile})
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(readyFile); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			cancel()
+			t.Fatal("ready file was not created")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("RunForeground() error = %v, want nil after cancel", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("RunForeground() did not return after cancel")
+	}
+
+	if _, err := os.Stat(readyFile); !os.IsNotExist(err) {
+		t.Errorf("ready file still present after shutdown: %v", err)
+	}
+	if configStore.SavedConfig == nil || configStore.SavedConfig.Port != DefaultPort {
+		t.Errorf("SavedConfig = %+v, want config for port %d", configStore.SavedConfig, DefaultPort)
+	}
+	if !configStore.DeleteCalled || configStore.config != nil {
+		t.Error("Expected config to be deleted on shutdown")
+	}
+}
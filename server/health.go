@@ -0,0 +1,59 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	"atulm/cocli/config"
+)
+
+// NewHealthChecker returns the HealthChecker for a daemon.health_check
+// config value. Every value checks the server with a ping: the daemon's
+// socket accepts connections while the server behind it is still starting,
+// so anything short of an answer from the server proves nothing.
+func NewHealthChecker(spec string) (HealthChecker, error) {
+	hc, err := config.ParseHealthCheck(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid health check %q: %w", spec, err)
+	}
+	if hc.Legacy != "" {
+		slog.Warn("daemon.health_check is no longer used; the daemon is checked with sdk-ping", "value", hc.Legacy)
+	}
+	return &SDKHealthChecker{}, nil
+}
+
+// ServerInfo is what a copilot server reports about itself
+type ServerInfo struct {
+	Version         string
+	ProtocolVersion int
+}
+
+// SDKHealthChecker implements HealthChecker by sending the server a ping
+// request, which proves it speaks the protocol
+type SDKHealthChecker struct{}
+
+// Ping connects to the server at addr and pings it
+func (h *SDKHealthChecker) Ping(network, addr string, timeout time.Duration) error {
+	conn, err := net.DialTimeout(network, addr, timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return rpcCall(conn, "ping", map[string]any{"message": "cocli health check"}, nil, timeout)
+}
+
+// Inspect connects to the server at addr and asks for its status
+func (h *SDKHealthChecker) Inspect(network, addr string, timeout time.Duration) (*ServerInfo, error) {
+	conn, err := net.DialTimeout(network, addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var info ServerInfo
+	if err := rpcCall(conn, "status.get", map[string]any{}, &info, timeout); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewHealthChecker(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr bool
+	}{
+		{"", false},
+		{"sdk-ping", false},
+		// Dropped strategies still load, as a ping
+		{"tcp", false},
+		{"http /healthz", false},
+		{"carrier-pigeon", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := NewHealthChecker(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewHealthChecker(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if _, ok := got.(*SDKHealthChecker); !tt.wantErr && !ok {
+				t.Errorf("NewHealthChecker(%q) = %T, want *SDKHealthChecker", tt.spec, got)
+			}
+		})
+	}
+}
+
+func TestSDKHealthChecker_ServerNotAnswering(t *testing.T) {
+	// The daemon's socket is up but the server behind the mux never replies
+	serverOut, _ := io.Pipe()
+	m := newMux(io.Discard)
+	go m.Run(serverOut)
+	dir, err := os.MkdirTemp("", "cocli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, socketFileName)
+	ln, err := ListenPrivate(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go newGate("s3cret").servePrivate(ln, m.Dial)
+
+	if err := (&SDKHealthChecker{}).Ping("unix", path, 200*time.Millisecond); err == nil {
+		t.Error("Ping() = nil while the server isn't answering, want an error")
+	}
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// logDirName is the directory under the state directory holding the
+	// daemon logs
+	logDirName = "logs"
+
+	// maxLogFiles is how many daemon logs are kept: the current one plus
+	// those of earlier starts, named server.log.1 (newest) and up
+	maxLogFiles = 5
+
+	// followInterval is how often a followed log is checked for new output
+	followInterval = 250 * time.Millisecond
+)
+
+// ErrNoLog is returned when daemon logging is disabled or the daemon has
+// not been started yet
+var ErrNoLog = errors.New("no daemon log")
+
+// Logs returns the last n non-empty lines of the daemon log
+func (d *DaemonManager) Logs(n int) ([]string, error) {
+	if d.logPath == "" {
+		return nil, ErrNoLog
+	}
+	lines, err := tailLines(d.logPath, n)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w at %s; start the daemon with /server start", ErrNoLog, d.logPath)
+	}
+	return lines, err
+}
+
+// FollowLogs writes output appended to the daemon log to w until ctx is
+// done. When a new daemon start replaces the log, the new one is followed
+// from its beginning.
+func (d *DaemonManager) FollowLogs(ctx context.Context, w io.Writer) error {
+	if d.logPath == "" {
+		return ErrNoLog
+	}
+	follower := newLogFollower(d.logPath)
+	ticker := time.NewTicker(followInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := follower.poll(w); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// logFollower tracks how much of a log has been seen
+type logFollower struct {
+	path    string
+	current os.FileInfo
+	offset  int64
+}
+
+// newLogFollower starts following the file at path from its current end.
+// The file may not exist yet.
+func newLogFollower(path string) *logFollower {
+	f := &logFollower{path: path}
+	if info, err := os.Stat(path); err == nil {
+		f.current, f.offset = info, info.Size()
+	}
+	return f
+}
+
+// poll writes what was appended to the log since the last poll to w
+func (f *logFollower) poll(w io.Writer) error {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return nil // rotated away; wait for the new log
+	}
+	// A replaced or truncated log is read from the start
+	if f.current == nil || !os.SameFile(f.current, info) || info.Size() < f.offset {
+		f.offset = 0
+	}
+	f.current = info
+	if info.Size() == f.offset {
+		return nil
+	}
+	n, err := copyRange(w, f.path, f.offset, info.Size())
+	f.offset += n
+	return err
+}
+
+// copyRange writes bytes [from, to) of the file at path to w
+func copyRange(w io.Writer, path string, from, to int64) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return io.Copy(w, io.NewSectionReader(f, from, to-from))
+}
+
+// rotateLogs shifts path to path.1, path.1 to path.2 and so on, dropping
+// the oldest so that at most keep files remain including a new path
+func rotateLogs(path string, keep int) error {
+	if keep < 2 {
+		return removeIfExists(path)
+	}
+	if err := removeIfExists(fmt.Sprintf("%s.%d", path, keep-1)); err != nil {
+		return err
+	}
+	for i := keep - 2; i >= 0; i-- {
+		from := path
+		if i > 0 {
+			from = fmt.Sprintf("%s.%d", path, i)
+		}
+		err := os.Rename(from, fmt.Sprintf("%s.%d", path, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func removeIfExists(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// openLog rotates the daemon logs and opens a fresh one for the new
+// process's output. It returns io.Discard when logging is disabled.
+func (d *DaemonManager) openLog() (io.Writer, func(), error) {
+	if d.logPath == "" {
+		return io.Discard, func() {}, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(d.logPath), 0755); err != nil {
+		return nil, nil, err
+	}
+	if err := rotateLogs(d.logPath, maxLogFiles); err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Create(d.logPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, func() { f.Close() }, nil
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotateLogs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.log")
+	for i := 1; i <= 4; i++ {
+		if err := rotateLogs(path, 3); err != nil {
+			t.Fatalf("rotateLogs() error = %v", err)
+		}
+		if err := os.WriteFile(path, []byte{byte('0' + i)}, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := map[string]string{"": "4", ".1": "3", ".2": "2"}
+	for suffix, content := range want {
+		data, err := os.ReadFile(path + suffix)
+		if err != nil || string(data) != content {
+			t.Errorf("server.log%s = %q, %v; want %q", suffix, data, err, content)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("server.log.3 exists, want at most 3 logs kept")
+	}
+}
+
+func TestDaemonManager_Start_RotatesLog(t *testing.T) {
+	procMgr := NewMockProcessManager()
+	procMgr.startPID = 12345
+	dm := newCrashTestManager(t, &MockConfigStore{}, procMgr)
+	if err := os.MkdirAll(filepath.Dir(dm.LogPath()), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dm.LogPath(), []byte("previous run\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := dm.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if data, err := os.ReadFile(dm.LogPath() + ".1"); err != nil || string(data) != "previous run\n" {
+		t.Errorf("server.log.1 = %q, %v; want the previous run's log", data, err)
+	}
+	if !strings.Contains(dm.LogPath(), filepath.Join(logDirName, logFileName)) {
+		t.Errorf("LogPath() = %q, want it under %s", dm.LogPath(), logDirName)
+	}
+}
+
+func TestDaemonManager_Logs(t *testing.T) {
+	dm := newCrashTestManager(t, &MockConfigStore{}, NewMockProcessManager())
+	if _, err := dm.Logs(10); !errors.Is(err, ErrNoLog) {
+		t.Errorf("Logs() before any start: error = %v, want ErrNoLog", err)
+	}
+
+	os.MkdirAll(filepath.Dir(dm.LogPath()), 0755)
+	os.WriteFile(dm.LogPath(), []byte("one\ntwo\n\nthree\n"), 0644)
+	lines, err := dm.Logs(2)
+	if err != nil || strings.Join(lines, ",") != "two,three" {
+		t.Errorf("Logs(2) = %q, %v; want the last two lines", lines, err)
+	}
+}
+
+func TestLogFollower(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.log")
+	os.WriteFile(path, []byte("old\n"), 0644)
+	follower := newLogFollower(path)
+
+	var out strings.Builder
+	poll := func(want string) {
+		t.Helper()
+		if err := follower.poll(&out); err != nil {
+			t.Fatalf("poll() error = %v", err)
+		}
+		if out.String() != want {
+			t.Errorf("followed output = %q, want %q", out.String(), want)
+		}
+	}
+
+	poll("")
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString("new\n")
+	f.Close()
+	poll("new\n")
+
+	// While rotated away nothing is read; the new log is read from its start
+	os.Rename(path, path+".1")
+	poll("new\n")
+	os.WriteFile(path, []byte("restarted\n"), 0644)
+	poll("new\nrestarted\n")
+}
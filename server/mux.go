@@ -0,0 +1,343 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The copilot server is run on stdio, which only the daemon can reach, and
+// speaks JSON-RPC with Content-Length framing. A mux shares that one stream
+// among the daemon's clients: their request IDs are rewritten so replies
+// find their way back, and what the server sends about a session (events,
+// tool calls, permission requests) goes to the client that created or last
+// used it. Anything about no particular session goes to every client.
+
+const (
+	// maxMessageSize bounds a single JSON-RPC message
+	maxMessageSize = 64 << 20
+
+	// muxQueueSize is how many messages may wait for a client to read
+	// them; a client that falls further behind is dropped
+	muxQueueSize = 256
+
+	// muxSendTimeout is how long a client may take to accept a message
+	muxSendTimeout = 30 * time.Second
+)
+
+// errServerGone is returned by Dial once the copilot server's output ended
+var errServerGone = errors.New("copilot server exited")
+
+// mux multiplexes client connections onto the copilot server's stdio
+type mux struct {
+	// wmu serializes writes to the server
+	wmu    sync.Mutex
+	server io.Writer
+
+	mu      sync.Mutex
+	closed  bool
+	nextID  int64
+	clients map[*muxClient]bool
+	// pending maps the IDs of requests forwarded to the server to the
+	// client that sent them
+	pending map[string]pendingCall
+	// owners maps session IDs to the client using them
+	owners map[string]*muxClient
+}
+
+// pendingCall is a client request waiting for the server's reply
+type pendingCall struct {
+	client *muxClient
+	id     json.RawMessage
+}
+
+// muxClient is one client connection served by a mux. Messages to it wait
+// in its own queue, so a slow client doesn't hold up the others.
+type muxClient struct {
+	conn      net.Conn
+	out       chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newMuxClient returns a client on conn and starts writing its queue
+func newMuxClient(conn net.Conn) *muxClient {
+	c := &muxClient{conn: conn, out: make(chan []byte, muxQueueSize), done: make(chan struct{})}
+	go c.writeQueue()
+	return c
+}
+
+// rpcMessage is the part of a JSON-RPC message the mux routes by
+type rpcMessage struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+}
+
+// sessionOf returns the session ID in a message's params or result, or ""
+func sessionOf(raw json.RawMessage) string {
+	var v struct {
+		SessionID string `json:"sessionId"`
+	}
+	_ = json.Unmarshal(raw, &v)
+	return v.SessionID
+}
+
+// newMux returns a mux writing to the server on w. Run must be called with
+// the server's output.
+func newMux(w io.Writer) *mux {
+	return &mux{
+		server:  w,
+		clients: make(map[*muxClient]bool),
+		pending: make(map[string]pendingCall),
+		owners:  make(map[string]*muxClient),
+	}
+}
+
+// Dial returns a new client connection to the server
+func (m *mux) Dial() (net.Conn, error) {
+	local, remote := net.Pipe()
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil, errServerGone
+	}
+	c := newMuxClient(remote)
+	m.clients[c] = true
+	m.mu.Unlock()
+	go m.serveClient(c)
+	return local, nil
+}
+
+// Run routes the server's messages on r to the clients until r ends, then
+// closes them
+func (m *mux) Run(r io.Reader) {
+	br := bufio.NewReader(r)
+	for {
+		body, err := readMessage(br)
+		if err != nil {
+			break
+		}
+		m.fromServer(body)
+	}
+	m.mu.Lock()
+	m.closed = true
+	clients := m.clients
+	m.clients = make(map[*muxClient]bool)
+	m.mu.Unlock()
+	for c := range clients {
+		c.close()
+	}
+}
+
+// serveClient forwards c's messages to the server until c closes
+func (m *mux) serveClient(c *muxClient) {
+	defer m.drop(c)
+	br := bufio.NewReader(c.conn)
+	for {
+		body, err := readMessage(br)
+		if err != nil {
+			return
+		}
+		if err := m.fromClient(c, body); err != nil {
+			return
+		}
+	}
+}
+
+// drop forgets c and the sessions it was using; the sessions stay on the
+// server for a later client to resume
+func (m *mux) drop(c *muxClient) {
+	c.close()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.clients, c)
+	for id, owner := range m.owners {
+		if owner == c {
+			delete(m.owners, id)
+		}
+	}
+	for id, call := range m.pending {
+		if call.client == c {
+			delete(m.pending, id)
+		}
+	}
+}
+
+// fromClient forwards a message from c to the server
+func (m *mux) fromClient(c *muxClient, body []byte) error {
+	var msg rpcMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return fmt.Errorf("invalid message from client: %w", err)
+	}
+	if msg.Method != "" && len(msg.ID) > 0 {
+		m.mu.Lock()
+		if session := sessionOf(msg.Params); session != "" {
+			m.owners[session] = c
+		}
+		m.nextID++
+		id := json.RawMessage(strconv.Quote("mux-" + strconv.FormatInt(m.nextID, 10)))
+		m.pending[string(id)] = pendingCall{client: c, id: msg.ID}
+		m.mu.Unlock()
+
+		var err error
+		if body, err = withID(body, id); err != nil {
+			return err
+		}
+	}
+	// Notifications, and replies to the server's requests, pass unchanged
+	m.wmu.Lock()
+	defer m.wmu.Unlock()
+	return writeMessage(m.server, body)
+}
+
+// fromServer routes a message from the server to the clients it concerns
+func (m *mux) fromServer(body []byte) {
+	var msg rpcMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return
+	}
+
+	if msg.Method == "" && len(msg.ID) > 0 {
+		// A reply to a client's request
+		m.mu.Lock()
+		call, ok := m.pending[string(msg.ID)]
+		delete(m.pending, string(msg.ID))
+		if session := sessionOf(msg.Result); ok && session != "" {
+			m.owners[session] = call.client
+		}
+		m.mu.Unlock()
+		if !ok {
+			return
+		}
+		if body, err := withID(body, call.id); err == nil {
+			call.client.send(body)
+		}
+		return
+	}
+
+	session := sessionOf(msg.Params)
+	m.mu.Lock()
+	var targets []*muxClient
+	if owner, ok := m.owners[session]; ok {
+		targets = append(targets, owner)
+	} else if session == "" {
+		for c := range m.clients {
+			targets = append(targets, c)
+		}
+	}
+	m.mu.Unlock()
+
+	if len(targets) == 0 && len(msg.ID) > 0 {
+		// Nobody can answer a request about a session no client is using
+		reply, _ := json.Marshal(map[string]any{
+			"jsonrpc": "2.0",
+			"id":      msg.ID,
+			"error":   map[string]any{"code": -32603, "message": "no client is attached to session " + session},
+		})
+		m.wmu.Lock()
+		_ = writeMessage(m.server, reply)
+		m.wmu.Unlock()
+		return
+	}
+	for _, c := range targets {
+		c.send(body)
+	}
+}
+
+// send queues a message for the client without waiting. A client whose
+// queue is full has stopped reading and is closed.
+func (c *muxClient) send(body []byte) {
+	select {
+	case c.out <- body:
+	case <-c.done:
+	default:
+		slog.Warn("dropping a daemon client that stopped reading", "queued", len(c.out))
+		c.close()
+	}
+}
+
+// writeQueue writes the queued messages to the client until it is closed,
+// closing it if a write fails or the client stops reading
+func (c *muxClient) writeQueue() {
+	for {
+		select {
+		case body := <-c.out:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(muxSendTimeout))
+			if err := writeMessage(c.conn, body); err != nil {
+				c.close()
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// close closes the client's connection and stops its writer
+func (c *muxClient) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+// withID returns the message body with its ID replaced
+func withID(body []byte, id json.RawMessage) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, err
+	}
+	fields["id"] = id
+	return json.Marshal(fields)
+}
+
+// readMessage reads one Content-Length framed message
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			if length < 0 {
+				// Stray blank line between messages
+				continue
+			}
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			n, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || n < 0 || n > maxMessageSize {
+				return nil, fmt.Errorf("invalid Content-Length %q", value)
+			}
+			length = n
+		}
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// writeMessage writes one Content-Length framed message
+func writeMessage(w io.Writer, body []byte) error {
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err := w.Write(body)
+	return err
+}
@@ -0,0 +1,242 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeCopilot stands in for a copilot server on stdio: it creates numbered
+// sessions, answers ping and status.get, and passes on the replies to its
+// own requests
+type fakeCopilot struct {
+	mu       sync.Mutex
+	w        io.Writer
+	sessions int
+	replies  chan map[string]any
+}
+
+// startMux runs a mux in front of a fakeCopilot
+func startMux(t *testing.T) (*mux, *fakeCopilot) {
+	t.Helper()
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	t.Cleanup(func() { inW.Close(); outW.Close() })
+	fake := &fakeCopilot{w: outW, replies: make(chan map[string]any, 10)}
+	m := newMux(inW)
+	go m.Run(outR)
+	go fake.serve(inR)
+	return m, fake
+}
+
+func (f *fakeCopilot) serve(r io.Reader) {
+	br := bufio.NewReader(r)
+	for {
+		body, err := readMessage(br)
+		if err != nil {
+			return
+		}
+		var msg map[string]any
+		json.Unmarshal(body, &msg)
+		switch msg["method"] {
+		case "session.create":
+			f.mu.Lock()
+			f.sessions++
+			id := fmt.Sprintf("s%d", f.sessions)
+			f.mu.Unlock()
+			f.send(map[string]any{"id": msg["id"], "result": map[string]any{"sessionId": id}})
+		case "ping":
+			f.send(map[string]any{"id": msg["id"], "result": map[string]any{"message": "pong"}})
+		case "status.get":
+			f.send(map[string]any{"id": msg["id"], "result": map[string]any{"version": "1.2.3", "protocolVersion": 2}})
+		case nil:
+			f.replies <- msg
+		}
+	}
+}
+
+// send writes a message from the server
+func (f *fakeCopilot) send(msg map[string]any) {
+	msg["jsonrpc"] = "2.0"
+	body, _ := json.Marshal(msg)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	writeMessage(f.w, body)
+}
+
+// rpcClient is one connection to the mux, speaking raw JSON-RPC
+type rpcClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dialMux(t *testing.T, m *mux) *rpcClient {
+	t.Helper()
+	conn, err := m.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &rpcClient{t: t, conn: conn, r: bufio.NewReader(conn)}
+}
+
+func (c *rpcClient) send(msg map[string]any) {
+	c.t.Helper()
+	msg["jsonrpc"] = "2.0"
+	body, _ := json.Marshal(msg)
+	if err := writeMessage(c.conn, body); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// read returns the next message, or nil if none arrives soon
+func (c *rpcClient) read() map[string]any {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	body, err := readMessage(c.r)
+	if err != nil {
+		return nil
+	}
+	var msg map[string]any
+	json.Unmarshal(body, &msg)
+	return msg
+}
+
+func TestMux_RoutesBySession(t *testing.T) {
+	m, fake := startMux(t)
+	a, b := dialMux(t, m), dialMux(t, m)
+
+	// Both clients use the same request ID; each gets its own reply
+	a.send(map[string]any{"id": "1", "method": "session.create", "params": map[string]any{}})
+	replyA := a.read()
+	b.send(map[string]any{"id": "1", "method": "session.create", "params": map[string]any{}})
+	replyB := b.read()
+	if replyA["id"] != "1" || replyB["id"] != "1" {
+		t.Fatalf("replies = %v, %v; want the clients' own IDs", replyA, replyB)
+	}
+	sessionA := replyA["result"].(map[string]any)["sessionId"]
+	sessionB := replyB["result"].(map[string]any)["sessionId"]
+	if sessionA == sessionB {
+		t.Fatalf("both clients got session %v", sessionA)
+	}
+
+	// Session events go to the session's client only
+	fake.send(map[string]any{"method": "session.event", "params": map[string]any{"sessionId": sessionB}})
+	if msg := b.read(); msg["method"] != "session.event" {
+		t.Errorf("client b got %v, want its session's event", msg)
+	}
+	if msg := a.read(); msg != nil {
+		t.Errorf("client a got %v, want nothing", msg)
+	}
+
+	// Requests about a session reach its client, whose reply reaches the
+	// server
+	fake.send(map[string]any{"id": 7, "method": "tool.call", "params": map[string]any{"sessionId": sessionA}})
+	call := a.read()
+	if call["method"] != "tool.call" {
+		t.Fatalf("client a got %v, want the tool call", call)
+	}
+	a.send(map[string]any{"id": call["id"], "result": map[string]any{"ok": true}})
+	if reply := <-fake.replies; reply["id"] != float64(7) {
+		t.Errorf("server got %v, want the reply to its request", reply)
+	}
+
+	// Anything about no session goes to everyone
+	fake.send(map[string]any{"method": "models.changed", "params": map[string]any{}})
+	if a.read() == nil || b.read() == nil {
+		t.Error("a broadcast didn't reach both clients")
+	}
+}
+
+func TestMux_StalledClient(t *testing.T) {
+	m, fake := startMux(t)
+	stalled, reader := dialMux(t, m), dialMux(t, m)
+
+	// One client never reads; the other still gets every message, and the
+	// stalled one is dropped once its queue is full
+	for i := range muxQueueSize + 2 {
+		fake.send(map[string]any{"method": "models.changed", "params": map[string]any{"n": i}})
+		if msg := reader.read(); msg == nil {
+			t.Fatalf("message %d didn't reach the reading client", i)
+		}
+	}
+	stalled.conn.SetReadDeadline(time.Now().Add(time.Second))
+	for {
+		if _, err := readMessage(stalled.r); err != nil {
+			if err == io.EOF || errors.Is(err, io.ErrClosedPipe) {
+				return
+			}
+			t.Fatalf("stalled client: %v, want its connection closed", err)
+		}
+	}
+}
+
+func TestMux_DetachedSession(t *testing.T) {
+	m, fake := startMux(t)
+	a := dialMux(t, m)
+	a.send(map[string]any{"id": "1", "method": "session.create", "params": map[string]any{}})
+	session := a.read()["result"].(map[string]any)["sessionId"]
+	a.conn.Close()
+
+	// Once its client is gone the server is told nobody can answer
+	deadline := time.Now().Add(time.Second)
+	for {
+		fake.send(map[string]any{"id": 9, "method": "permission.request", "params": map[string]any{"sessionId": session}})
+		select {
+		case reply := <-fake.replies:
+			if reply["error"] == nil {
+				t.Fatalf("server got %v, want an error", reply)
+			}
+			return
+		case <-time.After(50 * time.Millisecond):
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the server's request was never answered")
+		}
+	}
+}
+
+func TestMux_HealthCheckOverPrivateSocket(t *testing.T) {
+	m, _ := startMux(t)
+	// Unix socket paths are short; t.TempDir can be too long on macOS
+	dir, err := os.MkdirTemp("", "cocli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "private", socketFileName)
+	ln, err := ListenPrivate(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go newGate("s3cret").servePrivate(ln, m.Dial)
+
+	for file, want := range map[string]os.FileMode{path: 0600, filepath.Dir(path): 0700} {
+		info, err := os.Stat(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != want {
+			t.Errorf("%s mode = %o, want %o", file, info.Mode().Perm(), want)
+		}
+	}
+
+	h := &SDKHealthChecker{}
+	if err := h.Ping("unix", path, time.Second); err != nil {
+		t.Errorf("Ping() error = %v", err)
+	}
+	info, err := h.Inspect("unix", path, time.Second)
+	if err != nil || info.Version != "1.2.3" || info.ProtocolVersion != 2 {
+		t.Errorf("Inspect() = %+v, %v; want the server's version", info, err)
+	}
+}
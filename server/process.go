@@ -0,0 +1,33 @@
+package server
+
+import (
+	"errors"
+	"time"
+)
+
+// errIdentityUnsupported is returned by processIdentity where the OS can't
+// be asked about a process
+var errIdentityUnsupported = errors.New("process identity is not supported on this platform")
+
+// startTimeTolerance absorbs the jitter in how some kernels report start
+// times; a process reusing a PID starts much later than that
+const startTimeTolerance = time.Second
+
+// ProcessIdentity tells a process apart from a later one that is given the
+// same PID, e.g. after a reboot
+type ProcessIdentity struct {
+	// StartTime is when the process started
+	StartTime time.Time `json:"start_time"`
+	// Name is the process name the OS reports: its executable's base name,
+	// possibly truncated
+	Name string `json:"name"`
+}
+
+// matches reports whether other describes the same process
+func (id *ProcessIdentity) matches(other *ProcessIdentity) bool {
+	if id.Name != other.Name {
+		return false
+	}
+	diff := id.StartTime.Sub(other.StartTime)
+	return diff < startTimeTolerance && diff > -startTimeTolerance
+}
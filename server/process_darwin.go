@@ -0,0 +1,30 @@
+//go:build darwin
+
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// processIdentity asks the kernel for pid's name and start time
+func processIdentity(pid int) (*ProcessIdentity, error) {
+	info, err := unix.SysctlKinfoProc("kern.proc.pid", pid)
+	if err != nil {
+		return nil, err
+	}
+	proc := info.Proc
+	// A missing process comes back zeroed rather than as an error
+	if int(proc.P_pid) != pid {
+		return nil, fmt.Errorf("no process %d", pid)
+	}
+	name := proc.P_comm[:]
+	if i := bytes.IndexByte(name, 0); i >= 0 {
+		name = name[:i]
+	}
+	start := time.Unix(int64(proc.P_starttime.Sec), int64(proc.P_starttime.Usec)*1000)
+	return &ProcessIdentity{StartTime: start, Name: string(name)}, nil
+}
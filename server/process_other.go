@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+
+package server
+
+// processIdentity is not supported here, so daemons are recognized by PID
+// alone
+func processIdentity(pid int) (*ProcessIdentity, error) {
+	return nil, errIdentityUnsupported
+}
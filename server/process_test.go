@@ -0,0 +1,68 @@
+package server
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestProcessIdentity_Matches(t *testing.T) {
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	id := &ProcessIdentity{StartTime: start, Name: "cocli"}
+	tests := []struct {
+		name  string
+		other ProcessIdentity
+		want  bool
+	}{
+		{"same", ProcessIdentity{StartTime: start, Name: "cocli"}, true},
+		{"start time jitter", ProcessIdentity{StartTime: start.Add(-500 * time.Millisecond), Name: "cocli"}, true},
+		{"started later", ProcessIdentity{StartTime: start.Add(time.Hour), Name: "cocli"}, false},
+		{"other program", ProcessIdentity{StartTime: start, Name: "bash"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := id.matches(&tt.other); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOSProcessManager_IsRunning_Identity(t *testing.T) {
+	pm := &OSProcessManager{}
+	pid := os.Getpid()
+	id, err := pm.Identify(pid)
+	if errors.Is(err, errIdentityUnsupported) {
+		t.Skip("process identity is not supported on this platform")
+	}
+	if err != nil {
+		t.Fatalf("Identify() error = %v", err)
+	}
+	if id.Name == "" || time.Since(id.StartTime) < 0 || time.Since(id.StartTime) > time.Hour {
+		t.Errorf("Identify() = %+v, want this test binary started recently", id)
+	}
+
+	if !pm.IsRunning(pid, id) {
+		t.Error("IsRunning() with this process's identity = false, want true")
+	}
+	reused := &ProcessIdentity{StartTime: id.StartTime.Add(-24 * time.Hour), Name: id.Name}
+	if pm.IsRunning(pid, reused) {
+		t.Error("IsRunning() with an earlier process's identity = true, want false")
+	}
+}
+
+func TestDaemonManager_Status_PIDReused(t *testing.T) {
+	procMgr := NewMockProcessManager()
+	procMgr.runningPIDs[7] = true
+	procMgr.identities[7] = &ProcessIdentity{StartTime: time.Now(), Name: "bash"}
+	store := &MockConfigStore{config: &DaemonConfig{
+		PID: 7, Port: 4321, StartedAt: time.Now().Add(-48 * time.Hour),
+		Process: &ProcessIdentity{StartTime: time.Now().Add(-48 * time.Hour), Name: "cocli"},
+	}}
+	dm := newCrashTestManager(t, store, procMgr)
+
+	if dm.IsRunning() {
+		t.Error("IsRunning() = true for a daemon whose PID now belongs to another process")
+	}
+}
@@ -0,0 +1,296 @@
+package server
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"atulm/cocli/config"
+)
+
+const (
+	// systemdUnitName names the systemd user unit on Linux
+	systemdUnitName = "cocli-daemon.service"
+
+	// launchdLabel names the launchd agent on macOS
+	launchdLabel = "com.cocli.daemon"
+)
+
+// ErrServiceNotInstalled is returned by UninstallService when there is no
+// service to remove
+var ErrServiceNotInstalled = errors.New("daemon service is not installed")
+
+// runCommand runs a service manager command; replaced in tests
+var runCommand = func(name string, args ...string) error {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%s %s: %w: %s", name, strings.Join(args, " "), err, msg)
+		}
+		return fmt.Errorf("%s %s: %w", name, strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// service is a login service definition for one platform
+type service struct {
+	// path is where the definition is installed
+	path    string
+	content string
+	// start and stop are the commands that load and unload it; cleanup
+	// runs once it is removed
+	start   [][]string
+	stop    [][]string
+	cleanup [][]string
+}
+
+// serviceParams are the inputs to a service definition
+type serviceParams struct {
+	goos string
+	home string
+	// configHome is $XDG_CONFIG_HOME, if set
+	configHome string
+	uid        int
+	// exe is the cocli binary and cliPath the copilot CLI it should run
+	exe     string
+	cliPath string
+	logDir  string
+	// cocliDir is the cocli config directory when COCLI_CONFIG_DIR moves it
+	cocliDir string
+}
+
+// environment returns the variables the service runs the daemon with, as
+// NAME=value
+func (p serviceParams) environment() []string {
+	env := []string{config.EnvCopilotCLIPath + "=" + p.cliPath}
+	if p.cocliDir != "" {
+		env = append(env, config.EnvConfigDir+"="+p.cocliDir)
+	}
+	return env
+}
+
+// serviceFor builds the service that runs `cocli server run` on login: a
+// systemd user unit on Linux or a launchd agent on macOS
+func serviceFor(p serviceParams) (*service, error) {
+	switch p.goos {
+	case "linux":
+		dir := p.configHome
+		if dir == "" {
+			dir = filepath.Join(p.home, ".config")
+		}
+		return &service{
+			path:    filepath.Join(dir, "systemd", "user", systemdUnitName),
+			content: systemdUnit(p),
+			start: [][]string{
+				{"systemctl", "--user", "daemon-reload"},
+				{"systemctl", "--user", "enable", "--now", systemdUnitName},
+			},
+			stop: [][]string{
+				{"systemctl", "--user", "disable", "--now", systemdUnitName},
+			},
+			cleanup: [][]string{
+				{"systemctl", "--user", "daemon-reload"},
+			},
+		}, nil
+	case "darwin":
+		path := filepath.Join(p.home, "Library", "LaunchAgents", launchdLabel+".plist")
+		domain := "gui/" + strconv.Itoa(p.uid)
+		return &service{
+			path:    path,
+			content: launchdPlist(p),
+			start:   [][]string{{"launchctl", "bootstrap", domain, path}},
+			stop:    [][]string{{"launchctl", "bootout", domain, path}},
+		}, nil
+	default:
+		return nil, fmt.Errorf("daemon services are not supported on %s; run `cocli server run` under your own process supervisor", p.goos)
+	}
+}
+
+// systemdUnit renders the systemd user unit. systemd restarts the server
+// if it fails; a clean stop (e.g. on logout) stays stopped.
+func systemdUnit(p serviceParams) string {
+	var env strings.Builder
+	for _, v := range p.environment() {
+		fmt.Fprintf(&env, "Environment=%s\n", systemdQuote(v))
+	}
+	return fmt.Sprintf(`[Unit]
+Description=cocli copilot daemon
+
+[Service]
+ExecStart=%s server run
+%sRestart=on-failure
+RestartSec=5
+
+[Install]
+WantedBy=default.target
+`, systemdQuote(p.exe), env.String())
+}
+
+// systemdQuote quotes s as one word of a systemd unit setting
+func systemdQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "%", "%%")
+	return `"` + s + `"`
+}
+
+// launchdPlist renders the launchd agent, which keeps the server running
+// until it exits cleanly
+func launchdPlist(p serviceParams) string {
+	log := filepath.Join(p.logDir, "service.log")
+	var env strings.Builder
+	for _, v := range p.environment() {
+		name, value, _ := strings.Cut(v, "=")
+		fmt.Fprintf(&env, "\t\t<key>%s</key>\n\t\t<string>%s</string>\n", name, xmlEscape(value))
+	}
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>%s</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>%s</string>
+		<string>server</string>
+		<string>run</string>
+	</array>
+	<key>EnvironmentVariables</key>
+	<dict>
+%s	</dict>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<dict>
+		<key>SuccessfulExit</key>
+		<false/>
+	</dict>
+	<key>StandardOutPath</key>
+	<string>%s</string>
+	<key>StandardErrorPath</key>
+	<string>%s</string>
+</dict>
+</plist>
+`, launchdLabel, xmlEscape(p.exe), env.String(), xmlEscape(log), xmlEscape(log))
+}
+
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// currentService describes the service for this platform and user
+func (d *DaemonManager) currentService() (*service, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the cocli binary: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	cliPath, err := d.cliFinder.FindCLI()
+	if err != nil {
+		return nil, NewCLINotFoundError(fmt.Errorf("%w: %v", ErrCLINotFound, err))
+	}
+	// A daemon started on login must keep its state where this one does
+	var cocliDir string
+	if os.Getenv(config.EnvConfigDir) != "" {
+		if cocliDir, err = config.DefaultDir(); err != nil {
+			return nil, err
+		}
+	}
+	logDir := filepath.Dir(d.logPath)
+	if d.logPath == "" {
+		logDir = os.TempDir()
+	}
+	return serviceFor(serviceParams{
+		goos:       runtime.GOOS,
+		home:       home,
+		configHome: os.Getenv("XDG_CONFIG_HOME"),
+		uid:        os.Getuid(),
+		exe:        exe,
+		cliPath:    cliPath,
+		logDir:     logDir,
+		cocliDir:   cocliDir,
+	})
+}
+
+// InstallService installs and starts a login service that runs the daemon
+// in the foreground (`cocli server run`), so it is running whenever you are
+// logged in. It returns the file written.
+func (d *DaemonManager) InstallService() (string, error) {
+	svc, err := d.currentService()
+	if err != nil {
+		return "", err
+	}
+	return svc.path, d.installService(svc)
+}
+
+func (d *DaemonManager) installService(svc *service) error {
+	// Reinstalling replaces a loaded service
+	if _, err := os.Stat(svc.path); err == nil {
+		_ = runAll(svc.stop)
+	}
+	if d.IsRunning() {
+		return fmt.Errorf("the daemon is already running; stop it with /server stop so the service can start its own")
+	}
+	if err := os.MkdirAll(filepath.Dir(svc.path), 0755); err != nil {
+		return err
+	}
+	if d.logPath != "" {
+		if err := os.MkdirAll(filepath.Dir(d.logPath), 0755); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(svc.path, []byte(svc.content), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", svc.path, err)
+	}
+	if err := runAll(svc.start); err != nil {
+		return fmt.Errorf("wrote %s but could not start it: %w", svc.path, err)
+	}
+	return nil
+}
+
+// UninstallService stops the login service and removes it. It returns the
+// file removed.
+func (d *DaemonManager) UninstallService() (string, error) {
+	svc, err := d.currentService()
+	if err != nil {
+		return "", err
+	}
+	return svc.path, uninstallService(svc)
+}
+
+func uninstallService(svc *service) error {
+	if _, err := os.Stat(svc.path); errors.Is(err, os.ErrNotExist) {
+		return ErrServiceNotInstalled
+	}
+	// A service that isn't loaded fails to stop; remove it regardless
+	_ = runAll(svc.stop)
+	if err := os.Remove(svc.path); err != nil {
+		return err
+	}
+	return runAll(svc.cleanup)
+}
+
+// runAll runs commands in order, stopping at the first failure
+func runAll(cmds [][]string) error {
+	for _, cmd := range cmds {
+		if err := runCommand(cmd[0], cmd[1:]...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
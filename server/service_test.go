@@ -0,0 +1,164 @@
+package server
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeCommands records service manager commands instead of running them
+func fakeCommands(t *testing.T, fail string) *[]string {
+	t.Helper()
+	var ran []string
+	orig := runCommand
+	runCommand = func(name string, args ...string) error {
+		cmd := strings.Join(append([]string{name}, args...), " ")
+		ran = append(ran, cmd)
+		if fail != "" && strings.Contains(cmd, fail) {
+			return errors.New("failed")
+		}
+		return nil
+	}
+	t.Cleanup(func() { runCommand = orig })
+	return &ran
+}
+
+func TestServiceFor(t *testing.T) {
+	params := serviceParams{
+		home:    "/home/ann",
+		uid:     501,
+		exe:     "/opt/my tools/cocli",
+		cliPath: "/usr/bin/copilot",
+		logDir:  "/home/ann/.cocli/logs/ann",
+	}
+	tests := []struct {
+		name        string
+		goos        string
+		configHome  string
+		cocliDir    string
+		wantPath    string
+		wantContent []string
+		wantStart   string
+	}{
+		{
+			name:        "systemd",
+			goos:        "linux",
+			wantPath:    "/home/ann/.config/systemd/user/cocli-daemon.service",
+			wantContent: []string{`ExecStart="/opt/my tools/cocli" server run`, `Environment="COPILOT_CLI_PATH=/usr/bin/copilot"`, "Restart=on-failure", "WantedBy=default.target"},
+			wantStart:   "systemctl --user enable --now cocli-daemon.service",
+		},
+		{
+			name:       "systemd with XDG_CONFIG_HOME",
+			goos:       "linux",
+			configHome: "/xdg",
+			wantPath:   "/xdg/systemd/user/cocli-daemon.service",
+			wantStart:  "systemctl --user enable --now cocli-daemon.service",
+		},
+		{
+			name:        "systemd with COCLI_CONFIG_DIR",
+			goos:        "linux",
+			cocliDir:    "/data/cocli",
+			wantPath:    "/home/ann/.config/systemd/user/cocli-daemon.service",
+			wantContent: []string{`Environment="COPILOT_CLI_PATH=/usr/bin/copilot"`, `Environment="COCLI_CONFIG_DIR=/data/cocli"`},
+			wantStart:   "systemctl --user enable --now cocli-daemon.service",
+		},
+		{
+			name:        "launchd with COCLI_CONFIG_DIR",
+			goos:        "darwin",
+			cocliDir:    "/data/cocli",
+			wantPath:    "/home/ann/Library/LaunchAgents/com.cocli.daemon.plist",
+			wantContent: []string{"<key>COPILOT_CLI_PATH</key>\n\t\t<string>/usr/bin/copilot</string>", "<key>COCLI_CONFIG_DIR</key>\n\t\t<string>/data/cocli</string>"},
+			wantStart:   "launchctl bootstrap gui/501 /home/ann/Library/LaunchAgents/com.cocli.daemon.plist",
+		},
+		{
+			name:        "launchd",
+			goos:        "darwin",
+			wantPath:    "/home/ann/Library/LaunchAgents/com.cocli.daemon.plist",
+			wantContent: []string{"<string>/opt/my tools/cocli</string>", "<string>run</string>", "<key>COPILOT_CLI_PATH</key>", "/home/ann/.cocli/logs/ann/service.log"},
+			wantStart:   "launchctl bootstrap gui/501 /home/ann/Library/LaunchAgents/com.cocli.daemon.plist",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := params
+			p.goos, p.configHome, p.cocliDir = tt.goos, tt.configHome, tt.cocliDir
+			svc, err := serviceFor(p)
+			if err != nil {
+				t.Fatalf("serviceFor() error = %v", err)
+			}
+			if svc.path != filepath.FromSlash(tt.wantPath) {
+				t.Errorf("path = %q, want %q", svc.path, tt.wantPath)
+			}
+			for _, want := range tt.wantContent {
+				if !strings.Contains(svc.content, want) {
+					t.Errorf("content missing %q:\n%s", want, svc.content)
+				}
+			}
+			last := svc.start[len(svc.start)-1]
+			if got := strings.Join(last, " "); got != filepath.FromSlash(tt.wantStart) {
+				t.Errorf("start command = %q, want %q", got, tt.wantStart)
+			}
+		})
+	}
+
+	if _, err := serviceFor(serviceParams{goos: "windows"}); err == nil {
+		t.Error("serviceFor(windows) should fail")
+	}
+}
+
+func TestSystemdQuote(t *testing.T) {
+	if got, want := systemdQuote(`/a "b"/100%\x`), `"/a \"b\"/100%%\\x"`; got != want {
+		t.Errorf("systemdQuote() = %s, want %s", got, want)
+	}
+}
+
+func TestInstallAndUninstallService(t *testing.T) {
+	dm := newCrashTestManager(t, &MockConfigStore{}, NewMockProcessManager())
+	svc := &service{
+		path:    filepath.Join(t.TempDir(), "user", "cocli-daemon.service"),
+		content: "[Service]\n",
+		start:   [][]string{{"svcctl", "start"}},
+		stop:    [][]string{{"svcctl", "stop"}},
+		cleanup: [][]string{{"svcctl", "reload"}},
+	}
+	ran := fakeCommands(t, "")
+
+	if err := dm.installService(svc); err != nil {
+		t.Fatalf("installService() error = %v", err)
+	}
+	if data, err := os.ReadFile(svc.path); err != nil || string(data) != svc.content {
+		t.Errorf("service file = %q, %v; want the definition", data, err)
+	}
+	if err := uninstallService(svc); err != nil {
+		t.Fatalf("uninstallService() error = %v", err)
+	}
+	if _, err := os.Stat(svc.path); !os.IsNotExist(err) {
+		t.Error("service file should be removed")
+	}
+	if got := strings.Join(*ran, ", "); got != "svcctl start, svcctl stop, svcctl reload" {
+		t.Errorf("commands = %q, want start, then stop and reload", got)
+	}
+
+	if err := uninstallService(svc); !errors.Is(err, ErrServiceNotInstalled) {
+		t.Errorf("uninstallService() twice: error = %v, want ErrServiceNotInstalled", err)
+	}
+}
+
+func TestInstallService_Errors(t *testing.T) {
+	svc := &service{path: filepath.Join(t.TempDir(), "x.service"), start: [][]string{{"svcctl", "start"}}}
+
+	fakeCommands(t, "start")
+	dm := newCrashTestManager(t, &MockConfigStore{}, NewMockProcessManager())
+	if err := dm.installService(svc); err == nil || !strings.Contains(err.Error(), "could not start") {
+		t.Errorf("installService() error = %v, want a start failure", err)
+	}
+
+	procMgr := NewMockProcessManager()
+	procMgr.runningPIDs[7] = true
+	running := newCrashTestManager(t, &MockConfigStore{config: &DaemonConfig{PID: 7, Port: 4321}}, procMgr)
+	if err := running.installService(svc); err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Errorf("installService() with a running daemon: error = %v, want it refused", err)
+	}
+}
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"atulm/cocli/storage"
+)
+
+const (
+	// DefaultMaxRestarts is how many crash restarts are allowed per window
+	// unless configured otherwise
+	DefaultMaxRestarts = 3
+
+	// DefaultRestartWindow is the period MaxRestarts applies to
+	DefaultRestartWindow = 10 * time.Minute
+
+	// superviseInterval is how often the supervisor checks the daemon
+	superviseInterval = 2 * time.Second
+
+	// supervisorLockName serializes restarts between cocli processes
+	supervisorLockName = "supervisor"
+)
+
+// ErrRestartBudget is reported when the daemon crashed again after using up
+// its restarts for the window
+var ErrRestartBudget = errors.New("restart budget exhausted")
+
+// RestartPolicy bounds how often a crashed daemon is restarted
+type RestartPolicy struct {
+	// MaxRestarts is how many restarts are allowed within Window; 0 turns
+	// automatic restarts off
+	MaxRestarts int
+	Window      time.Duration
+}
+
+// DefaultRestartPolicy returns the built-in restart policy
+func DefaultRestartPolicy() RestartPolicy {
+	return RestartPolicy{MaxRestarts: DefaultMaxRestarts, Window: DefaultRestartWindow}
+}
+
+// RestartEvent reports a daemon that died unexpectedly and what was done
+// about it
+type RestartEvent struct {
+	// Crash describes how the daemon died; nil when another cocli process
+	// noticed and restarted it first
+	Crash *CrashReport
+	// PID is the new daemon's PID, or 0 if it was not restarted
+	PID int
+	// Restarts counts the daemon's automatic restarts, including this one
+	Restarts int
+	// Err is why the daemon was not restarted
+	Err error
+}
+
+// Supervisor watches a daemon started by any cocli process and restarts it
+// when it dies without being stopped. Every cocli process connected to the
+// daemon may run one; they take turns through a lock, and the others report
+// the new daemon they find.
+type Supervisor struct {
+	d      *DaemonManager
+	policy RestartPolicy
+	notify func(RestartEvent)
+	now    func() time.Time
+
+	lastPID      int         // daemon seen running at the last check, or 0
+	lastRestarts int         // its restart count
+	restarts     []time.Time // restarts made by this supervisor
+}
+
+// NewSupervisor creates a supervisor for d that calls notify whenever the
+// daemon dies unexpectedly
+func NewSupervisor(d *DaemonManager, policy RestartPolicy, notify func(RestartEvent)) *Supervisor {
+	return &Supervisor{d: d, policy: policy, notify: notify, now: time.Now}
+}
+
+// Run checks the daemon until ctx is done
+func (s *Supervisor) Run(ctx context.Context) {
+	ticker := time.NewTicker(superviseInterval)
+	defer ticker.Stop()
+	s.check()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.check()
+		}
+	}
+}
+
+// check looks at the daemon once, restarting it if it died since the last
+// check
+func (s *Supervisor) check() {
+	config, err := s.d.config.Load()
+	if err == nil && s.d.process.IsRunning(config.PID, config.Process) {
+		if s.lastPID != 0 && config.PID != s.lastPID {
+			// Another cocli process restarted it
+			s.notify(RestartEvent{PID: config.PID, Restarts: config.Restarts})
+		}
+		s.lastPID, s.lastRestarts = config.PID, config.Restarts
+		return
+	}
+	if s.lastPID == 0 {
+		return // not running when we started watching
+	}
+	if err != nil && !errors.Is(err, ErrConfigNotFound) {
+		return
+	}
+	if err == nil {
+		s.d.recordCrash(config.PID, nil)
+	}
+
+	// A deliberate stop removes the config without leaving a crash report
+	crash, _ := s.d.LastCrash()
+	pid := s.lastPID
+	s.lastPID = 0
+	if crash == nil || crash.PID != pid {
+		return
+	}
+	s.notify(s.restart(crash))
+}
+
+// restart starts a new daemon if the policy allows
+func (s *Supervisor) restart(crash *CrashReport) RestartEvent {
+	event := RestartEvent{Crash: crash, Restarts: s.lastRestarts}
+	if s.policy.MaxRestarts <= 0 {
+		event.Err = fmt.Errorf("automatic restarts are off")
+		return event
+	}
+	now := s.now()
+	recent := s.restarts[:0]
+	for _, t := range s.restarts {
+		if now.Sub(t) < s.policy.Window {
+			recent = append(recent, t)
+		}
+	}
+	s.restarts = recent
+	if len(s.restarts) >= s.policy.MaxRestarts {
+		event.Err = fmt.Errorf("%w: %d restarts in %s", ErrRestartBudget, len(s.restarts), s.policy.Window)
+		return event
+	}
+
+	err := s.withLock(func() error {
+		if s.d.IsRunning() {
+			return nil // another cocli process got there first
+		}
+		s.restarts = append(s.restarts, now)
+		err := s.d.start(io.Discard, s.lastRestarts+1)
+		if errors.Is(err, ErrDaemonStarting) {
+			// A /server start is already bringing it back
+			s.d.waitForStart()
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		event.Err = err
+		return event
+	}
+	if config, err := s.d.config.Load(); err == nil {
+		event.PID, event.Restarts = config.PID, config.Restarts
+		s.lastPID, s.lastRestarts = config.PID, config.Restarts
+	}
+	return event
+}
+
+// withLock runs fn holding the lock shared by all supervisors, when the
+// daemon has a state directory to keep it in
+func (s *Supervisor) withLock(fn func() error) error {
+	if s.d.crashPath == "" {
+		return fn()
+	}
+	return storage.WithLock(filepath.Join(filepath.Dir(s.d.crashPath), supervisorLockName), fn)
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// newSupervisedDaemon returns a manager whose daemon (PID 100) is running
+// and a supervisor that has seen it, recording the events it reports
+func newSupervisedDaemon(t *testing.T, policy RestartPolicy) (*Supervisor, *MockProcessManager, *MockConfigStore, *[]RestartEvent) {
+	t.Helper()
+	procMgr := NewMockProcessManager()
+	procMgr.runningPIDs[100] = true
+	store := &MockConfigStore{config: &DaemonConfig{PID: 100, Port: 4321, StartedAt: time.Now()}}
+	dm := newCrashTestManager(t, store, procMgr)
+
+	var events []RestartEvent
+	s := NewSupervisor(dm, policy, func(e RestartEvent) {
+		events = append(events, e)
+	})
+	s.check()
+	if len(events) != 0 || s.lastPID != 100 {
+		t.Fatalf("first check: events %+v, lastPID %d; want the running daemon seen quietly", events, s.lastPID)
+	}
+	return s, procMgr, store, &events
+}
+
+func TestSupervisor_RestartsCrashedDaemon(t *testing.T) {
+	s, procMgr, store, events := newSupervisedDaemon(t, DefaultRestartPolicy())
+
+	delete(procMgr.runningPIDs, 100)
+	procMgr.startPID = 200
+	s.check()
+
+	if len(*events) != 1 {
+		t.Fatalf("events = %+v, want one restart", *events)
+	}
+	e := (*events)[0]
+	if e.Err != nil || e.PID != 200 || e.Restarts != 1 || e.Crash == nil || e.Crash.PID != 100 {
+		t.Errorf("event = %+v, want PID 100's crash and restart 1 as PID 200", e)
+	}
+	if store.config == nil || store.config.PID != 200 || store.config.Restarts != 1 {
+		t.Errorf("daemon config = %+v, want the restarted daemon recorded", store.config)
+	}
+
+	s.check()
+	if len(*events) != 1 {
+		t.Errorf("events = %+v, want nothing more while the new daemon runs", *events)
+	}
+}
+
+func TestSupervisor_IgnoresDeliberateStop(t *testing.T) {
+	s, procMgr, _, events := newSupervisedDaemon(t, DefaultRestartPolicy())
+
+	if err := s.d.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	s.check()
+	if len(*events) != 0 || procMgr.StartCalled {
+		t.Errorf("events = %+v, started = %v; want a stopped daemon left alone", *events, procMgr.StartCalled)
+	}
+}
+
+func TestSupervisor_RestartBudget(t *testing.T) {
+	s, procMgr, _, events := newSupervisedDaemon(t, RestartPolicy{MaxRestarts: 1, Window: time.Hour})
+
+	delete(procMgr.runningPIDs, 100)
+	procMgr.startPID = 200
+	s.check()
+	delete(procMgr.runningPIDs, 200)
+	procMgr.startPID = 300
+	s.check()
+
+	if len(*events) != 2 {
+		t.Fatalf("events = %+v, want a restart then a refusal", *events)
+	}
+	if e := (*events)[1]; !errors.Is(e.Err, ErrRestartBudget) || e.PID != 0 || e.Crash == nil || e.Crash.PID != 200 {
+		t.Errorf("second event = %+v, want PID 200's crash and ErrRestartBudget", e)
+	}
+
+	// The budget frees up once the window has passed
+	s.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	s.lastPID = 200
+	s.d.saveCrash(&CrashReport{PID: 200})
+	s.check()
+	if e := (*events)[2]; e.Err != nil || e.PID != 300 {
+		t.Errorf("event after the window = %+v, want a restart as PID 300", e)
+	}
+}
+
+func TestSupervisor_RestartedElsewhere(t *testing.T) {
+	s, procMgr, store, events := newSupervisedDaemon(t, DefaultRestartPolicy())
+
+	delete(procMgr.runningPIDs, 100)
+	procMgr.runningPIDs[300] = true
+	store.config = &DaemonConfig{PID: 300, Port: 4321, Restarts: 1}
+	s.check()
+
+	if len(*events) != 1 || (*events)[0].PID != 300 || (*events)[0].Crash != nil || procMgr.StartCalled {
+		t.Errorf("events = %+v, want the other process's restart reported without starting another", *events)
+	}
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+
+	"atulm/cocli/config"
+)
+
+// TLSOptions configures TLS for daemon connections from other machines. The
+// copilot SDK doesn't speak TLS, so it is terminated by cocli on both ends:
+// `cocli server run` accepts TLS connections and forwards them to the
+// server, and clients tunnel through a private local socket.
+type TLSOptions struct {
+	// CertFile and KeyFile are this side's certificate: the daemon's when
+	// serving, or the client certificate presented to the daemon
+	CertFile string
+	KeyFile  string
+	// CAFile verifies the other side: the daemon's certificate when
+	// connecting (the system roots when empty), or client certificates
+	// when serving (which are then required)
+	CAFile string
+	// InsecureSkipVerify accepts any daemon certificate; for development only
+	InsecureSkipVerify bool
+}
+
+// ServerConfig returns the TLS config for accepting daemon connections
+func (o TLSOptions) ServerConfig() (*tls.Config, error) {
+	if o.CertFile == "" || o.KeyFile == "" {
+		return nil, errors.New("serving TLS needs a certificate and key")
+	}
+	cert, err := tls.LoadX509KeyPair(config.ExpandHome(o.CertFile), config.ExpandHome(o.KeyFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	cfg := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if o.CAFile != "" {
+		pool, err := loadCertPool(o.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
+
+// ClientConfig returns the TLS config for connecting to the daemon on host
+func (o TLSOptions) ClientConfig(host string) (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: o.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if o.CAFile != "" {
+		pool, err := loadCertPool(o.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = pool
+	}
+	if o.CertFile != "" || o.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.ExpandHome(o.CertFile), config.ExpandHome(o.KeyFile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// loadCertPool reads a PEM bundle of CA certificates
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(config.ExpandHome(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM certificates in CA bundle %s", path)
+	}
+	return pool, nil
+}
+
+// Forward accepts connections on ln until it is closed, copying each in
+// both directions to a connection opened with dial
+func Forward(ln net.Listener, dial func() (net.Conn, error)) {
+	forward(ln, nil, dial)
+}
+
+// forward is Forward with check run on each accepted connection (after its
+// TLS handshake, if any); connections it rejects are dropped before the
+// target is dialed
+func forward(ln net.Listener, check func(net.Conn) error, dial func() (net.Conn, error)) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			if tc, ok := conn.(*tls.Conn); ok {
+				if err := tc.Handshake(); err != nil {
+					return
+				}
+			}
+			if check != nil {
+				if err := check(conn); err != nil {
+					return
+				}
+			}
+			target, err := dial()
+			if err != nil {
+				return
+			}
+			defer target.Close()
+			pipe(conn, target)
+		}()
+	}
+}
+
+// pipe copies between a and b until either side closes
+func pipe(a, b net.Conn) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	copyAndClose := func(dst, src net.Conn) {
+		defer wg.Done()
+		_, _ = io.Copy(dst, src)
+		// Unblock the other direction
+		dst.Close()
+		src.Close()
+	}
+	go copyAndClose(a, b)
+	go copyAndClose(b, a)
+	wg.Wait()
+}
@@ -0,0 +1,193 @@
+package server
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA issues certificates for TLS tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	dir  string
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	ca := &testCA{cert: cert, key: key, dir: t.TempDir()}
+	writePEM(t, filepath.Join(ca.dir, "ca.pem"), "CERTIFICATE", der)
+	return ca
+}
+
+// issue writes a certificate and key for 127.0.0.1 signed by the CA and
+// returns their paths
+func (ca *testCA) issue(t *testing.T, name string, usage x509.ExtKeyUsage) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPath, keyPath := filepath.Join(ca.dir, name+".pem"), filepath.Join(ca.dir, name+"-key.pem")
+	writePEM(t, certPath, "CERTIFICATE", der)
+	writePEM(t, keyPath, "EC PRIVATE KEY", keyDER)
+	return certPath, keyPath
+}
+
+func writePEM(t *testing.T, path, typ string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// startEcho serves connections that echo one line back
+func startEcho(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				line, _ := bufio.NewReader(conn).ReadString('\n')
+				conn.Write([]byte(line))
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// startTLSForward forwards TLS connections to target, returning the TLS
+// address
+func startTLSForward(t *testing.T, opts TLSOptions, target string) string {
+	t.Helper()
+	cfg, err := opts.ServerConfig()
+	if err != nil {
+		t.Fatalf("ServerConfig() error = %v", err)
+	}
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go Forward(ln, func() (net.Conn, error) { return net.Dial("tcp", target) })
+	return ln.Addr().String()
+}
+
+// roundTrip sends a line through addr over TLS and returns the reply
+func roundTrip(addr string, opts TLSOptions) (string, error) {
+	cfg, err := opts.ClientConfig("127.0.0.1")
+	if err != nil {
+		return "", err
+	}
+	conn, err := tls.Dial("tcp", addr, cfg)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("ping\n")); err != nil {
+		return "", err
+	}
+	return bufio.NewReader(conn).ReadString('\n')
+}
+
+func TestForward_TLS(t *testing.T) {
+	ca := newTestCA(t)
+	serverCert, serverKey := ca.issue(t, "daemon", x509.ExtKeyUsageServerAuth)
+	addr := startTLSForward(t, TLSOptions{CertFile: serverCert, KeyFile: serverKey}, startEcho(t))
+	caFile := filepath.Join(ca.dir, "ca.pem")
+
+	if got, err := roundTrip(addr, TLSOptions{CAFile: caFile}); err != nil || got != "ping\n" {
+		t.Errorf("trusted round trip = %q, %v; want the echo", got, err)
+	}
+	if got, err := roundTrip(addr, TLSOptions{InsecureSkipVerify: true}); err != nil || got != "ping\n" {
+		t.Errorf("skip-verify round trip = %q, %v; want the echo", got, err)
+	}
+	if _, err := roundTrip(addr, TLSOptions{}); err == nil {
+		t.Error("round trip without the CA should fail verification")
+	}
+}
+
+func TestForward_ClientCertificates(t *testing.T) {
+	ca := newTestCA(t)
+	caFile := filepath.Join(ca.dir, "ca.pem")
+	serverCert, serverKey := ca.issue(t, "daemon", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.issue(t, "laptop", x509.ExtKeyUsageClientAuth)
+	addr := startTLSForward(t, TLSOptions{CertFile: serverCert, KeyFile: serverKey, CAFile: caFile}, startEcho(t))
+
+	if got, err := roundTrip(addr, TLSOptions{CAFile: caFile, CertFile: clientCert, KeyFile: clientKey}); err != nil || got != "ping\n" {
+		t.Errorf("round trip with a client certificate = %q, %v; want the echo", got, err)
+	}
+	if got, err := roundTrip(addr, TLSOptions{CAFile: caFile}); err == nil {
+		t.Errorf("round trip without a client certificate = %q, want it rejected", got)
+	}
+}
+
+func TestTLSOptions_Errors(t *testing.T) {
+	if _, err := (TLSOptions{}).ServerConfig(); err == nil {
+		t.Error("ServerConfig() without a certificate should fail")
+	}
+	bad := filepath.Join(t.TempDir(), "bad.pem")
+	if err := os.WriteFile(bad, []byte("not pem"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (TLSOptions{CAFile: bad}).ClientConfig("devbox"); err == nil {
+		t.Error("ClientConfig() with a bad CA bundle should fail")
+	}
+	if _, err := (TLSOptions{CertFile: bad, KeyFile: bad}).ClientConfig("devbox"); err == nil {
+		t.Error("ClientConfig() with a bad client certificate should fail")
+	}
+}
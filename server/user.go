@@ -0,0 +1,84 @@
+package server
+
+import (
+	"hash/fnv"
+	"net"
+	"os"
+	"os/user"
+	"regexp"
+	"strconv"
+)
+
+// userPortRange is how many ports, starting at DefaultPort, per-user
+// default ports are spread over
+const userPortRange = 1000
+
+// unsafeNameChars matches characters not allowed in a namespace directory
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// UserNamespace returns a name identifying the current user, safe to use as
+// a directory name. Daemon state is kept per user so users sharing a host
+// (or a home directory) don't overwrite each other's server.json.
+func UserNamespace() string {
+	name := ""
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	if name == "" {
+		name = os.Getenv("USER")
+	}
+	if name == "" {
+		name = os.Getenv("USERNAME")
+	}
+	if name != "" {
+		return unsafeNameChars.ReplaceAllString(name, "_")
+	}
+	// Fall back to the numeric UID (-1 on Windows, where a name is always found)
+	return "uid-" + strconv.Itoa(os.Getuid())
+}
+
+// UserPort returns the current user's default daemon port. It is derived
+// from the UID, so users on a shared host get stable, distinct ports unless
+// their UIDs are userPortRange apart; where there are no UIDs (Windows) it
+// is derived from UserNamespace. A port that turns out to be taken is
+// skipped when the daemon starts (see nextFreePort).
+func UserPort() int {
+	if uid := os.Getuid(); uid >= 0 {
+		return uidPort(uid)
+	}
+	return namespacePort(UserNamespace())
+}
+
+// uidPort maps a UID onto [DefaultPort, DefaultPort+userPortRange)
+func uidPort(uid int) int {
+	return DefaultPort + uid%userPortRange
+}
+
+// namespacePort maps a namespace onto [DefaultPort, DefaultPort+userPortRange)
+// by hashing it; different namespaces can share a port
+func namespacePort(namespace string) int {
+	h := fnv.New32a()
+	h.Write([]byte(namespace))
+	return DefaultPort + int(h.Sum32()%userPortRange)
+}
+
+// portProbes is how many ports nextFreePort tries
+const portProbes = 20
+
+// nextFreePort returns port if it can be listened on, or else the first of
+// the following ports that can, wrapping around within the user range. port is
+// returned when none of them is free, for the caller's listen to report.
+func nextFreePort(port int) int {
+	for i := 0; i < portProbes; i++ {
+		p := port + i
+		if port < DefaultPort+userPortRange && p >= DefaultPort+userPortRange {
+			p -= userPortRange
+		}
+		ln, err := net.Listen("tcp", net.JoinHostPort("localhost", strconv.Itoa(p)))
+		if err == nil {
+			ln.Close()
+			return p
+		}
+	}
+	return port
+}
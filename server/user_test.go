@@ -0,0 +1,78 @@
+package server
+
+import (
+	"net"
+	"os"
+	"regexp"
+	"testing"
+)
+
+func TestUserNamespace_SafeForPaths(t *testing.T) {
+	ns := UserNamespace()
+	if ns == "" {
+		t.Fatal("UserNamespace() is empty")
+	}
+	if !regexp.MustCompile(`^[A-Za-z0-9._-]+$`).MatchString(ns) {
+		t.Errorf("UserNamespace() = %q, want only path-safe characters", ns)
+	}
+}
+
+func TestNamespacePort(t *testing.T) {
+	for _, ns := range []string{"alice", "bob", "uid-1000", "DOMAIN_carol"} {
+		port := namespacePort(ns)
+		if port < DefaultPort || port >= DefaultPort+userPortRange {
+			t.Errorf("namespacePort(%q) = %d, want within [%d, %d)", ns, port, DefaultPort, DefaultPort+userPortRange)
+		}
+		if again := namespacePort(ns); again != port {
+			t.Errorf("namespacePort(%q) not stable: %d then %d", ns, port, again)
+		}
+	}
+
+	if namespacePort("alice") == namespacePort("bob") {
+		t.Error("namespacePort gave alice and bob the same port")
+	}
+}
+
+func TestUIDPort(t *testing.T) {
+	tests := []struct {
+		uid  int
+		want int
+	}{
+		{uid: 0, want: DefaultPort},
+		{uid: 501, want: DefaultPort + 501},
+		{uid: 1000, want: DefaultPort},
+		{uid: 1001, want: DefaultPort + 1},
+	}
+	for _, tt := range tests {
+		if got := uidPort(tt.uid); got != tt.want {
+			t.Errorf("uidPort(%d) = %d, want %d", tt.uid, got, tt.want)
+		}
+	}
+}
+
+func TestUserPort(t *testing.T) {
+	want := namespacePort(UserNamespace())
+	if uid := os.Getuid(); uid >= 0 {
+		want = uidPort(uid)
+	}
+	if got := UserPort(); got != want {
+		t.Errorf("UserPort() = %d, want %d", got, want)
+	}
+}
+
+func TestNextFreePort(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	busy := ln.Addr().(*net.TCPAddr).Port
+	if got := nextFreePort(busy); got == busy {
+		t.Errorf("nextFreePort(%d) = %d, want another port", busy, got)
+	}
+
+	ln.Close()
+	if got := nextFreePort(busy); got != busy {
+		t.Errorf("nextFreePort(%d) = %d once it is free, want it", busy, got)
+	}
+}
@@ -0,0 +1,46 @@
+package session
+
+import "fmt"
+
+// agentInstructions are added to the system message in agent mode, so edits
+// come back in a form cocli can apply
+const agentInstructions = `Agent mode is on: the user can apply file edits you propose to their working tree.
+When you change files, give each change as a unified diff in a fenced code block tagged "diff", with "--- a/<path>" and "+++ b/<path>" headers using paths relative to the current directory, "--- /dev/null" for new files and "+++ /dev/null" for deleted ones.
+Include three lines of unchanged context around each change and copy context lines exactly. Put all the changes to one file in one diff. Don't use diff blocks for anything that isn't meant to be applied.`
+
+// AgentMode reports whether the model is asked to propose file edits as
+// diffs the user can apply
+func (m *Manager) AgentMode() bool {
+	return m.agentMode
+}
+
+// SetAgentMode turns agent mode on or off. It changes the system message,
+// so like SetSystemPrompt it recreates the session, carrying the
+// conversation over.
+func (m *Manager) SetAgentMode(on bool) error {
+	if on == m.agentMode {
+		return nil
+	}
+	m.agentMode = on
+	if err := m.recreateSession(); err != nil {
+		m.agentMode = !on
+		return err
+	}
+	return nil
+}
+
+// ShowDiff prints a unified diff in the colors replies use for diffs
+func (m *Manager) ShowDiff(diff string) {
+	fence := fenceFor(diff)
+	m.PrintMarkdown(fence + "diff\n" + diff + "\n" + fence + "\n")
+}
+
+// PrintMarkdown renders markdown outside a reply
+func (m *Manager) PrintMarkdown(markdown string) {
+	if m.renderer == nil {
+		fmt.Println(markdown)
+		return
+	}
+	m.renderer.renderContent(markdown)
+	m.renderer.output("\n")
+}
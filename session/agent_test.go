@@ -0,0 +1,40 @@
+package session
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSetAgentMode(t *testing.T) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	mgr.turns = []Turn{{Prompt: "q1", Response: "a1"}}
+	mgr.tokenLimit = 100000
+
+	if err := mgr.SetAgentMode(true); err != nil {
+		t.Fatalf("SetAgentMode() error = %v", err)
+	}
+	if !mgr.AgentMode() || !strings.Contains(mgr.systemMessage(), agentInstructions) {
+		t.Error("agent mode is on but the system message lacks its instructions")
+	}
+	if len(mgr.Turns()) != 1 {
+		t.Errorf("Turns() = %d, want the conversation kept", len(mgr.Turns()))
+	}
+
+	if err := mgr.SetAgentMode(false); err != nil {
+		t.Fatalf("SetAgentMode() error = %v", err)
+	}
+	if strings.Contains(mgr.systemMessage(), agentInstructions) {
+		t.Error("agent mode is off but the system message still has its instructions")
+	}
+}
+
+func TestShowDiff(t *testing.T) {
+	var out bytes.Buffer
+	m := &Manager{}
+	m.renderer, _ = NewStreamingMarkdownRenderer(WithWriter(&out), WithPlainText())
+	m.ShowDiff("--- a/x\n+++ b/x\n@@ -1 +1 @@\n-old\n+new")
+	if want := "```diff\n--- a/x\n+++ b/x\n@@ -1 +1 @@\n-old\n+new\n```\n\n"; out.String() != want {
+		t.Errorf("ShowDiff() output = %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,182 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"atulm/cocli/storage"
+)
+
+// DefaultMaxArchives is how many archived transcripts are kept when the
+// policy doesn't say
+const DefaultMaxArchives = 100
+
+// archiveTimeFormat names archive files so they sort oldest first
+const archiveTimeFormat = "20060102-150405.000000000"
+
+// SessionPolicy bounds how long a conversation lives. Once it reaches a
+// limit, it is archived and the next prompt starts a fresh conversation,
+// keeping memory bounded when cocli is left running for days.
+type SessionPolicy struct {
+	// MaxTurns is the number of turns after which the conversation is
+	// archived; 0 means no limit
+	MaxTurns int
+	// MaxAge is how long after its first turn the conversation is archived;
+	// 0 means no limit
+	MaxAge time.Duration
+	// ArchiveDir receives the transcripts; when empty, conversations are
+	// still rolled over but not saved
+	ArchiveDir string
+	// MaxArchives is how many transcripts ArchiveDir keeps; the oldest are
+	// removed first. 0 means DefaultMaxArchives.
+	MaxArchives int
+}
+
+// Archive is the saved transcript of a finished conversation
+type Archive struct {
+	Title     string    `json:"title,omitempty"`
+	Model     string    `json:"model"`
+	StartedAt time.Time `json:"started_at"`
+	EndedAt   time.Time `json:"ended_at"`
+	// Reason says which limit ended the conversation
+	Reason string `json:"reason"`
+	// Summary is the compacted part of the conversation, if any
+	Summary string `json:"summary,omitempty"`
+	// TurnCount includes turns that were compacted into Summary
+	TurnCount int    `json:"turn_count"`
+	Turns     []Turn `json:"turns"`
+}
+
+// SetSessionPolicy replaces the conversation lifetime policy
+func (m *Manager) SetSessionPolicy(p SessionPolicy) {
+	m.sessionPolicy = p
+}
+
+// sessionLimitReached reports which limit, if any, the conversation has hit
+func (m *Manager) sessionLimitReached(now time.Time) string {
+	p := m.sessionPolicy
+	if p.MaxTurns > 0 && m.turnCount >= p.MaxTurns {
+		return fmt.Sprintf("reached %d turns", p.MaxTurns)
+	}
+	if p.MaxAge > 0 && !m.startedAt.IsZero() && now.Sub(m.startedAt) >= p.MaxAge {
+		return fmt.Sprintf("older than %s", p.MaxAge)
+	}
+	return ""
+}
+
+// maybeArchive archives the conversation and starts a new one once it has
+// reached a limit of the session policy
+func (m *Manager) maybeArchive() error {
+	reason := m.sessionLimitReached(time.Now())
+	if reason == "" {
+		return nil
+	}
+
+	path, err := m.archive(reason)
+	if err != nil {
+		return err
+	}
+	m.resetConversation()
+	if err := m.Create(m.currentModel); err != nil {
+		return err
+	}
+
+	if path != "" {
+		m.notice("The conversation %s; it was archived to %s and a new one started.\n", reason, path)
+	} else {
+		m.notice("The conversation %s; a new one started.\n", reason)
+	}
+	return nil
+}
+
+// resetConversation forgets the current conversation's history
+func (m *Manager) resetConversation() {
+	m.turns = nil
+	m.recorded = nil
+	m.contextSummary = ""
+	m.turnCount = 0
+	m.startedAt = time.Time{}
+	m.conversationID = ""
+	m.title = ""
+}
+
+// archive writes the current conversation to the archive directory and
+// prunes old transcripts. It returns the file written, or "" when archiving
+// is disabled.
+func (m *Manager) archive(reason string) (string, error) {
+	dir := m.sessionPolicy.ArchiveDir
+	if dir == "" {
+		return "", nil
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	now := time.Now()
+	started := m.startedAt
+	if started.IsZero() {
+		started = now
+	}
+	data, err := json.MarshalIndent(&Archive{
+		Title:     m.Title(),
+		Model:     m.currentModel,
+		StartedAt: started,
+		EndedAt:   now,
+		Reason:    reason,
+		Summary:   m.contextSummary,
+		TurnCount: m.turnCount,
+		Turns:     m.turns,
+	}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	name := started.UTC().Format(archiveTimeFormat)
+	if slug := TitleSlug(m.Title()); slug != "" {
+		name += "-" + slug
+	}
+	path := filepath.Join(dir, name+".json")
+	if err := storage.WriteFileAtomic(path, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to archive conversation: %w", err)
+	}
+
+	keep := m.sessionPolicy.MaxArchives
+	if keep <= 0 {
+		keep = DefaultMaxArchives
+	}
+	if err := pruneArchives(dir, keep); err != nil {
+		slog.Warn("failed to remove old archives", "err", err)
+	}
+	return path, nil
+}
+
+// pruneArchives removes the oldest transcripts in dir beyond keep
+func pruneArchives(dir string, keep int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+			names = append(names, e.Name())
+		}
+	}
+	if len(names) <= keep {
+		return nil
+	}
+	// Names start with timestamps, so they sort oldest first
+	sort.Strings(names)
+	for _, name := range names[:len(names)-keep] {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package session
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionLimitReached(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name      string
+		policy    SessionPolicy
+		turnCount int
+		startedAt time.Time
+		want      string
+	}{
+		{name: "no limits", turnCount: 500, startedAt: now.Add(-30 * 24 * time.Hour)},
+		{name: "under max turns", policy: SessionPolicy{MaxTurns: 3}, turnCount: 2},
+		{name: "max turns", policy: SessionPolicy{MaxTurns: 3}, turnCount: 3, want: "reached 3 turns"},
+		{name: "under max age", policy: SessionPolicy{MaxAge: time.Hour}, startedAt: now.Add(-time.Minute)},
+		{name: "max age", policy: SessionPolicy{MaxAge: time.Hour}, startedAt: now.Add(-2 * time.Hour), want: "older than 1h0m0s"},
+		{name: "no turns yet", policy: SessionPolicy{MaxAge: time.Hour}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr := createTestManager(&mockSDKClient{})
+			mgr.SetSessionPolicy(tt.policy)
+			mgr.turnCount = tt.turnCount
+			mgr.startedAt = tt.startedAt
+			if got := mgr.sessionLimitReached(now); got != tt.want {
+				t.Errorf("sessionLimitReached() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordTurn_TracksLifetime(t *testing.T) {
+	mgr := createTestManager(&mockSDKClient{})
+	mgr.recordTurn("one", nil)
+	first := mgr.startedAt
+	mgr.recordTurn("two", nil)
+
+	if mgr.turnCount != 2 {
+		t.Errorf("turnCount = %d, want 2", mgr.turnCount)
+	}
+	if first.IsZero() || !mgr.startedAt.Equal(first) {
+		t.Errorf("startedAt = %v, want the first turn's time %v", mgr.startedAt, first)
+	}
+}
+
+func TestMaybeArchive(t *testing.T) {
+	dir := t.TempDir()
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	mgr.SetSessionPolicy(SessionPolicy{MaxTurns: 2, ArchiveDir: dir})
+	mgr.recordTurn("one", nil)
+	if err := mgr.maybeArchive(); err != nil {
+		t.Fatalf("maybeArchive() error = %v", err)
+	}
+	if len(mgr.Turns()) != 1 {
+		t.Fatal("conversation was archived before reaching its limit")
+	}
+
+	mgr.recordTurn("two", nil)
+	mgr.contextSummary = "earlier"
+	out := captureOutput(func() {
+		if err := mgr.maybeArchive(); err != nil {
+			t.Fatalf("maybeArchive() error = %v", err)
+		}
+	})
+	if !strings.Contains(out, "reached 2 turns") {
+		t.Errorf("output %q, want the limit that was reached", out)
+	}
+	if len(mgr.Turns()) != 0 || mgr.turnCount != 0 || mgr.contextSummary != "" || !mgr.startedAt.IsZero() {
+		t.Error("conversation was not reset after archiving")
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("archive files = %v, want 1", files)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var archived Archive
+	if err := json.Unmarshal(data, &archived); err != nil {
+		t.Fatalf("archive is not valid JSON: %v", err)
+	}
+	if archived.TurnCount != 2 || len(archived.Turns) != 2 || archived.Turns[1].Prompt != "two" || archived.Summary != "earlier" {
+		t.Errorf("archive = %+v, want both turns and the summary", archived)
+	}
+}
+
+func TestMaybeArchive_NoArchiveDir(t *testing.T) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	mgr.SetSessionPolicy(SessionPolicy{MaxTurns: 1})
+	mgr.recordTurn("one", nil)
+
+	out := captureOutput(func() {
+		if err := mgr.maybeArchive(); err != nil {
+			t.Fatalf("maybeArchive() error = %v", err)
+		}
+	})
+	if len(mgr.Turns()) != 0 || strings.Contains(out, "archived") {
+		t.Errorf("want a new conversation without an archive, got %d turns and output %q", len(mgr.Turns()), out)
+	}
+}
+
+func TestPruneArchives(t *testing.T) {
+	dir := t.TempDir()
+	names := []string{"20250101-000000.json", "20250102-000000.json", "20250103-000000.json", "notes.txt"}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := pruneArchives(dir, 2); err != nil {
+		t.Fatalf("pruneArchives() error = %v", err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	var left []string
+	for _, e := range entries {
+		left = append(left, e.Name())
+	}
+	want := "20250102-000000.json,20250103-000000.json,notes.txt"
+	if got := strings.Join(left, ","); got != want {
+		t.Errorf("remaining = %s, want %s", got, want)
+	}
+}
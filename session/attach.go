@@ -0,0 +1,59 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ContextItem is text (e.g. a captured terminal pane) attached to the next
+// prompt
+type ContextItem struct {
+	Label string
+	Text  string
+	// Lang is the fence's language tag, if known
+	Lang string
+}
+
+// AttachContext queues text to be sent with the next prompt
+func (m *Manager) AttachContext(label, text string) {
+	m.pendingContext = append(m.pendingContext, ContextItem{Label: label, Text: text})
+}
+
+// AttachContextItem queues an item, such as a fenced diff, to be sent with
+// the next prompt
+func (m *Manager) AttachContextItem(item ContextItem) {
+	m.pendingContext = append(m.pendingContext, item)
+}
+
+// ClearContext drops the context queued for the next prompt
+func (m *Manager) ClearContext() {
+	m.pendingContext = nil
+}
+
+// withContext appends the context items to the prompt text as fenced blocks
+func withContext(text string, items []ContextItem) string {
+	if len(items) == 0 {
+		return text
+	}
+	var b strings.Builder
+	b.WriteString(text)
+	for _, item := range items {
+		fence := fenceFor(item.Text)
+		fmt.Fprintf(&b, "\n\n%s:\n%s%s\n%s\n%s", item.Label, fence, item.Lang, item.Text, fence)
+	}
+	return b.String()
+}
+
+// fenceFor returns a backtick fence longer than any run of backticks in text
+func fenceFor(text string) string {
+	longest, run := 0, 0
+	for _, r := range text {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
@@ -0,0 +1,51 @@
+package session
+
+import "testing"
+
+func TestWithContext(t *testing.T) {
+	tests := []struct {
+		name  string
+		items []ContextItem
+		want  string
+	}{
+		{name: "none", want: "why?"},
+		{
+			name:  "pane",
+			items: []ContextItem{{Label: "tmux pane {last}", Text: "FAIL"}},
+			want:  "why?\n\ntmux pane {last}:\n```\nFAIL\n```",
+		},
+		{
+			name:  "text with a fence",
+			items: []ContextItem{{Label: "log", Text: "```go\nx\n```"}},
+			want:  "why?\n\nlog:\n````\n```go\nx\n```\n````",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := withContext("why?", tt.items); got != tt.want {
+				t.Errorf("withContext() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAttachContext_SentOnce(t *testing.T) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	mgr.AttachContext("tmux pane 1", "panic: boom")
+
+	if err := mgr.Send("explain"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	sess := mgr.session.(*mockSession)
+	if want := "explain\n\ntmux pane 1:\n```\npanic: boom\n```"; sess.lastOptions.Prompt != want {
+		t.Errorf("sent prompt = %q, want %q", sess.lastOptions.Prompt, want)
+	}
+
+	// The context is used up by the prompt it was sent with
+	if err := mgr.Send("thanks"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if sess.lastOptions.Prompt != "thanks" {
+		t.Errorf("second prompt = %q, want no context", sess.lastOptions.Prompt)
+	}
+}
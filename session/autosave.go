@@ -0,0 +1,159 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"atulm/cocli/storage"
+)
+
+// runsDir holds a marker for each running cocli under the history
+// directory. A run holds its marker's lock until Close, so a marker whose
+// lock is free was left by a run that crashed or was killed.
+const runsDir = "running"
+
+// runMarker records what a run was doing, for the next run to recover
+type runMarker struct {
+	StartedAt time.Time `json:"started_at"`
+	// ConversationID is the conversation the run last saved
+	ConversationID string `json:"conversation_id,omitempty"`
+}
+
+// run is this process's marker
+type run struct {
+	path   string
+	lock   *storage.FileLock
+	marker runMarker
+}
+
+// TrackRun marks this run as in progress until Close, so a later run can
+// tell if it ended without Close. It returns the conversation of the latest
+// earlier run that ended that way, or nil if there is none to recover.
+func (m *Manager) TrackRun() (*ConversationInfo, error) {
+	if m.historyDir == "" || m.run != nil {
+		return nil, nil
+	}
+	dir := filepath.Join(m.historyDir, runsDir)
+	crashed := crashedRuns(dir)
+
+	started := time.Now()
+	path := filepath.Join(dir, fmt.Sprintf("%d-%d.json", os.Getpid(), started.UnixNano()))
+	lock, err := storage.TryLock(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to track this run: %w", err)
+	}
+	m.run = &run{path: path, lock: lock, marker: runMarker{StartedAt: started}}
+	if err := m.writeRunMarker(); err != nil {
+		m.Close()
+		return nil, err
+	}
+
+	// The latest crash wins; its conversation is offered if it still exists
+	var latest *runMarker
+	for i := range crashed {
+		if crashed[i].ConversationID != "" && (latest == nil || crashed[i].StartedAt.After(latest.StartedAt)) {
+			latest = &crashed[i]
+		}
+	}
+	if latest == nil || latest.ConversationID == m.conversationID {
+		return nil, nil
+	}
+	saved, err := loadConversation(m.historyDir, latest.ConversationID)
+	if err != nil {
+		return nil, nil
+	}
+	info := ConversationInfo{
+		ID:        saved.ID,
+		Name:      saved.Name,
+		Title:     saved.Title,
+		Model:     saved.Model,
+		UpdatedAt: saved.UpdatedAt,
+		TurnCount: saved.TurnCount,
+	}
+	if len(saved.Turns) > 0 {
+		info.FirstPrompt = saved.Turns[0].Prompt
+	}
+	return &info, nil
+}
+
+// crashedRuns reads and removes the markers in dir that no running cocli
+// holds
+func crashedRuns(dir string) []runMarker {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var crashed []runMarker
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		lock, err := storage.TryLock(path)
+		if err != nil {
+			continue // still running
+		}
+		var marker runMarker
+		if data, err := os.ReadFile(path); err == nil && json.Unmarshal(data, &marker) == nil {
+			crashed = append(crashed, marker)
+		}
+		os.Remove(path)
+		lock.Unlock()
+		os.Remove(path + ".lock")
+	}
+	return crashed
+}
+
+// writeRunMarker records the current conversation in this run's marker
+func (m *Manager) writeRunMarker() error {
+	m.run.marker.ConversationID = m.conversationID
+	data, err := json.Marshal(&m.run.marker)
+	if err != nil {
+		return err
+	}
+	if err := storage.WriteFileAtomic(m.run.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to track this run: %w", err)
+	}
+	return nil
+}
+
+// noteRun updates this run's marker when the conversation it is in changed
+func (m *Manager) noteRun() {
+	if m.run == nil || m.run.marker.ConversationID == m.conversationID {
+		return
+	}
+	if err := m.writeRunMarker(); err != nil {
+		slog.Warn("run marker not updated", "err", err)
+	}
+}
+
+// Close ends the run started by TrackRun, so the next run doesn't take it
+// for a crash, and closes the debug log and tee file. The client's
+// lifecycle is managed separately.
+func (m *Manager) Close() []error {
+	var errs []error
+	if err := m.StopDebugLog(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := m.StopTee(); err != nil {
+		errs = append(errs, err)
+	}
+	if m.run == nil {
+		return errs
+	}
+	if err := os.Remove(m.run.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		errs = append(errs, err)
+	}
+	if err := m.run.lock.Unlock(); err != nil {
+		errs = append(errs, err)
+	}
+	os.Remove(m.run.path + ".lock")
+	m.run = nil
+	return errs
+}
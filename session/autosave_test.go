@@ -0,0 +1,106 @@
+package session
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTrackRun(t *testing.T) {
+	dir := t.TempDir()
+
+	if info, err := createTestManager(&mockSDKClient{}).TrackRun(); info != nil || err != nil {
+		t.Errorf("TrackRun() without a history directory = %+v, %v", info, err)
+	}
+
+	// A run that is still going isn't taken for a crash
+	first := createTestManagerWithSession(&mockSDKClient{})
+	first.SetHistoryDir(dir)
+	if info, err := first.TrackRun(); info != nil || err != nil {
+		t.Fatalf("TrackRun() of the first run = %+v, %v", info, err)
+	}
+	if err := first.Send("question"); err != nil {
+		t.Fatal(err)
+	}
+	running := createTestManagerWithSession(&mockSDKClient{})
+	running.SetHistoryDir(dir)
+	if info, err := running.TrackRun(); info != nil || err != nil {
+		t.Errorf("TrackRun() beside a running run = %+v, %v", info, err)
+	}
+	running.Close()
+
+	// The first run dies without Close
+	first.run.lock.Unlock()
+	first.run = nil
+
+	second := createTestManagerWithSession(&mockSDKClient{})
+	second.SetHistoryDir(dir)
+	info, err := second.TrackRun()
+	if err != nil {
+		t.Fatalf("TrackRun() error = %v", err)
+	}
+	if info == nil || info.ID != first.ConversationID() || info.TurnCount != 1 || info.FirstPrompt != "question" {
+		t.Fatalf("TrackRun() = %+v, want the crashed run's conversation %s", info, first.ConversationID())
+	}
+	if errs := second.Close(); len(errs) != 0 {
+		t.Errorf("Close() = %v", errs)
+	}
+
+	// The crash is offered once, and a clean exit is not a crash
+	third := createTestManagerWithSession(&mockSDKClient{})
+	third.SetHistoryDir(dir)
+	if info, err := third.TrackRun(); info != nil || err != nil {
+		t.Errorf("TrackRun() after a clean exit = %+v, %v", info, err)
+	}
+	third.Close()
+}
+
+func TestSend_SavesPendingPrompt(t *testing.T) {
+	dir := t.TempDir()
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	mgr.SetHistoryDir(dir)
+	if err := mgr.Send("first"); err != nil {
+		t.Fatal(err)
+	}
+	mgr.session.(*mockSession).err = errors.New("quota exceeded")
+	if err := mgr.Send("second"); err == nil {
+		t.Fatal("Send() should fail")
+	}
+
+	saved, err := loadConversation(dir, mgr.ConversationID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.Turns) != 1 || saved.Pending == nil || saved.Pending.Prompt != "second" {
+		t.Fatalf("saved turns %+v, pending %+v; want the failed prompt pending", saved.Turns, saved.Pending)
+	}
+
+	// Resuming offers the prompt to /retry
+	other := createTestManagerWithSession(&mockSDKClient{})
+	other.SetHistoryDir(dir)
+	if err := other.Resume(saved.ID); err != nil {
+		t.Fatal(err)
+	}
+	if other.LastPrompt() != "second" {
+		t.Errorf("LastPrompt() = %q, want the pending prompt", other.LastPrompt())
+	}
+
+	// A finished reply clears it
+	if err := other.Send("third"); err != nil {
+		t.Fatal(err)
+	}
+	if saved, _ := loadConversation(dir, saved.ID); saved.Pending != nil {
+		t.Errorf("pending = %+v after a finished reply", saved.Pending)
+	}
+}
+
+func TestPartialReply(t *testing.T) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	resp := mgr.beginResponse()
+	mgr.addPartial("Hello, ")
+	mgr.addPartial("wor")
+	mgr.endResponse(resp)
+	mgr.addPartial("ignored")
+	if got := mgr.partialReply(resp); got != "Hello, wor" {
+		t.Errorf("partialReply() = %q, want the streamed text", got)
+	}
+}
@@ -0,0 +1,146 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"strings"
+	"syscall"
+	"time"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+// RetryPolicy decides how often a prompt whose reply failed with a
+// transient error, such as a reset connection or a 5xx from the API, is
+// sent again before Send gives up
+type RetryPolicy struct {
+	// Attempts is how many times a prompt is sent in all; 1 or less never
+	// retries
+	Attempts int
+	// Delay is the wait before the first retry. It doubles before each
+	// later one, up to MaxDelay, and is jittered so many clients hit by the
+	// same outage don't retry in step.
+	Delay    time.Duration
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy tries each prompt up to three times, a second and then
+// two seconds apart
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{Attempts: 3, Delay: time.Second, MaxDelay: 30 * time.Second}
+}
+
+// SetRetryPolicy sets how Send retries transient failures
+func (m *Manager) SetRetryPolicy(p RetryPolicy) {
+	m.retryPolicy = p
+}
+
+// backoff returns the wait before retry n, counting from 1: the delay
+// doubled n-1 times and capped, of which the second half is random
+func (p RetryPolicy) backoff(n int) time.Duration {
+	d := p.Delay
+	for i := 1; i < n && (p.MaxDelay <= 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int64N(int64(d/2)+1))
+}
+
+// transientErrors are fragments of error messages that a later attempt may
+// not see: dropped connections, interrupted streams and server-side 5xx
+var transientErrors = []string{
+	"connection reset", "broken pipe", "unexpected eof",
+	"stream interrupted", "stream error", "stream closed",
+	"internal server error", "bad gateway", "service unavailable", "gateway timeout",
+	"status 500", "status 502", "status 503", "status 504",
+	"server overloaded", "overloaded_error",
+}
+
+// isTransient reports whether err is a failure worth sending the prompt
+// again for. Cancellations, stalls and timeouts are the user's to retry.
+func isTransient(err error) bool {
+	switch {
+	case err == nil,
+		errors.Is(err, ErrResponseCancelled),
+		errors.Is(err, ErrResponseStalled),
+		errors.Is(err, ErrRequestTimedOut),
+		errors.Is(err, ErrPromptCancelled):
+		return false
+	case errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.EPIPE),
+		errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range transientErrors {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// exchangeWithRetry sends p like exchange, sending it again after a lost
+// connection is restored, or after a transient failure or a rate limit
+// while the retry policy allows
+func (m *Manager) exchangeWithRetry(p *Prompt) (reply *copilot.SessionEvent, partial string, err error) {
+	reconnected := false
+	for attempt := 1; ; attempt++ {
+		reply, partial, err = m.exchange(p)
+		if err == nil {
+			m.rateLimitedUntil = time.Time{}
+			return reply, partial, nil
+		}
+		if errors.Is(err, ErrResponseCancelled) {
+			return reply, partial, err
+		}
+		// A lost connection is restored and the prompt sent once more
+		if !reconnected && m.reconnectIfLost() {
+			reconnected = true
+			continue
+		}
+
+		wait := m.retryPolicy.backoff(attempt)
+		var limit *RateLimitError
+		limited := errors.As(err, &limit)
+		if limited {
+			m.turnRateLimits++
+			wait = max(wait, limit.Wait)
+			m.rateLimitedUntil = time.Now().Add(wait)
+		}
+		if !(limited || isTransient(err)) || attempt >= m.retryPolicy.Attempts {
+			return reply, partial, err
+		}
+
+		status := fmt.Sprintf("%v; retrying (%d/%d)…", err, attempt+1, m.retryPolicy.Attempts)
+		if limited {
+			status = fmt.Sprintf("Rate limited; retrying (%d/%d) in %s (Ctrl+C to stop waiting)…", attempt+1, m.retryPolicy.Attempts, wait.Round(time.Second))
+		}
+		if m.jsonOut != nil {
+			slog.Warn("retrying", "err", err, "attempt", attempt+1, "of", m.retryPolicy.Attempts, "wait", wait)
+		} else {
+			m.endOutput()
+			m.notice("%s\n", status)
+		}
+		if !m.pause(wait) {
+			return nil, partial, ErrResponseCancelled
+		}
+	}
+}
+
+// endOutput finishes any reply text rendered before a failure, so what
+// follows starts on a line of its own
+func (m *Manager) endOutput() {
+	if m.renderer != nil {
+		m.renderer.Flush()
+	}
+	fmt.Println()
+}
@@ -0,0 +1,127 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+// flakySession fails with each of failures in turn, then replies
+type flakySession struct {
+	mockSession
+	failures []error
+}
+
+func (s *flakySession) SendAndWait(options copilot.MessageOptions, timeout time.Duration) (*copilot.SessionEvent, error) {
+	s.sendCount++
+	if len(s.failures) > 0 {
+		err := s.failures[0]
+		s.failures = s.failures[1:]
+		return nil, err
+	}
+	content := "answer"
+	return &copilot.SessionEvent{Type: "assistant.message", Data: copilot.Data{Content: &content}}, nil
+}
+
+// noSleep records the waits between retries instead of sleeping
+func noSleep(t *testing.T) *[]time.Duration {
+	var waits []time.Duration
+	old := sleep
+	sleep = func(d time.Duration, abort <-chan struct{}) bool {
+		waits = append(waits, d)
+		return true
+	}
+	t.Cleanup(func() { sleep = old })
+	return &waits
+}
+
+func TestSend_RetriesTransientErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  []error
+		wantErr   bool
+		wantSends int
+	}{
+		{name: "recovers", failures: []error{errors.New("read: connection reset by peer"), fmt.Errorf("API error: status 503")}, wantSends: 3},
+		{name: "gives up", failures: []error{syscall.ECONNRESET, io.ErrUnexpectedEOF, errors.New("502 Bad Gateway")}, wantErr: true, wantSends: 3},
+		{name: "not transient", failures: []error{errors.New("model not available")}, wantErr: true, wantSends: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			waits := noSleep(t)
+			mgr := createTestManager(&mockSDKClient{})
+			sess := &flakySession{failures: tt.failures}
+			mgr.session = sess
+
+			var err error
+			out := captureOutput(func() { err = mgr.Send("question") })
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if sess.sendCount != tt.wantSends || len(*waits) != tt.wantSends-1 {
+				t.Errorf("%d sends, %d waits; want %d sends", sess.sendCount, len(*waits), tt.wantSends)
+			}
+			if tt.wantSends > 1 && !strings.Contains(out, "retrying (2/3)…") {
+				t.Errorf("output = %q, want a retrying notice", out)
+			}
+			if !tt.wantErr && mgr.LastReply() != "answer" {
+				t.Errorf("LastReply() = %q, want the reply after retrying", mgr.LastReply())
+			}
+		})
+	}
+}
+
+func TestSend_RetryPolicyOff(t *testing.T) {
+	noSleep(t)
+	mgr := createTestManager(&mockSDKClient{})
+	mgr.SetRetryPolicy(RetryPolicy{Attempts: 1})
+	sess := &flakySession{failures: []error{syscall.ECONNRESET}}
+	mgr.session = sess
+	if err := mgr.Send("question"); err == nil || sess.sendCount != 1 {
+		t.Errorf("Send() = %v after %d sends, want the failure without retrying", err, sess.sendCount)
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{Delay: time.Second, MaxDelay: 5 * time.Second}
+	for n, base := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		for range 20 {
+			if d := p.backoff(n + 1); d < base/2 || d > base {
+				t.Fatalf("backoff(%d) = %s, want between %s and %s", n+1, d, base/2, base)
+			}
+		}
+	}
+	if d := (RetryPolicy{}).backoff(1); d != 0 {
+		t.Errorf("backoff() without a delay = %s, want 0", d)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: fmt.Errorf("failed to send message: %w", syscall.ECONNRESET), want: true},
+		{err: fmt.Errorf("failed to send message: %w", io.ErrUnexpectedEOF), want: true},
+		{err: errors.New("write: broken pipe"), want: true},
+		{err: errors.New("upstream returned status 504"), want: true},
+		{err: errors.New("Service Unavailable"), want: true},
+		{err: errors.New("stream interrupted"), want: true},
+		{err: errors.New("session error: rate limited"), want: false},
+		{err: fmt.Errorf("%w: connection reset", ErrRequestTimedOut), want: false},
+		{err: ErrResponseCancelled, want: false},
+		{err: ErrResponseStalled, want: false},
+		{err: nil, want: false},
+	}
+	for _, tt := range tests {
+		if got := isTransient(tt.err); got != tt.want {
+			t.Errorf("isTransient(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
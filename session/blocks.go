@@ -0,0 +1,142 @@
+package session
+
+import (
+	"regexp"
+	"strings"
+)
+
+// listItemRegex matches bullet and ordered list items at any nesting depth
+var listItemRegex = regexp.MustCompile(`^\s*([-*+]|\d{1,9}[.)])(\s|$)`)
+
+// isListItem reports whether line starts a list item
+func isListItem(line string) bool {
+	return listItemRegex.MatchString(line)
+}
+
+// isTableRow reports whether line is a pipe table row
+func isTableRow(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "|")
+}
+
+// tableSeparatorCellRegex matches one cell of a table's delimiter row,
+// e.g. "---" or ":--:"
+var tableSeparatorCellRegex = regexp.MustCompile(`^:?-+:?$`)
+
+// isTableSeparator reports whether line is the delimiter row under a table
+// header, e.g. "|---|:--:|" or "--- | ---"
+func isTableSeparator(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	if !strings.Contains(trimmed, "|") {
+		return false
+	}
+	trimmed = strings.TrimSuffix(strings.TrimPrefix(trimmed, "|"), "|")
+	for _, cell := range strings.Split(trimmed, "|") {
+		if !tableSeparatorCellRegex.MatchString(strings.TrimSpace(cell)) {
+			return false
+		}
+	}
+	return true
+}
+
+// isIndented reports whether line continues a list item's content
+func isIndented(line string) bool {
+	return strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+}
+
+// pendingBlockStart returns the offset at which a list, table or blockquote
+// that may still grow begins, or -1 if the content doesn't end in one. These
+// blocks are held back until a line that can't belong to them arrives, so
+// they are rendered in one piece with consistent indentation and column
+// widths instead of fragment by fragment.
+//
+// A line with a pipe may be the header of a table without outer pipes, so
+// it is held until the next line shows whether a delimiter row follows. A
+// table ends at the first line that isn't a row, and a blockquote (which
+// may be a callout) at the first line that doesn't start with ">". A list ends at an
+// unindented line that follows a blank line and isn't a list item; until
+// then, later lines may be continuations or more items. Only complete lines
+// are considered: the partial last line can't end anything yet.
+func pendingBlockStart(content string) int {
+	const (
+		none = iota
+		list
+		tableHeader
+		table
+		quote
+	)
+	state, start := none, -1
+	blankSeen := false
+	fence := ""
+
+	offset := 0
+	complete := content[:strings.LastIndex(content, "\n")+1]
+	for _, line := range strings.SplitAfter(complete, "\n") {
+		if line == "" {
+			break // nothing after the last newline
+		}
+		lineStart := offset
+		offset += len(line)
+		line = strings.TrimRight(line, "\r\n")
+		trimmed := strings.TrimSpace(line)
+
+		// Lines inside fenced code can't start or end a block
+		if fence != "" {
+			if trimmed == fence {
+				fence = ""
+			}
+			continue
+		}
+
+		switch state {
+		case tableHeader:
+			if isTableSeparator(line) {
+				state = table
+				continue
+			}
+			state = none
+		case table:
+			// Rows of a table without outer pipes only contain one
+			if isTableRow(line) || (trimmed != "" && strings.Contains(line, "|")) {
+				continue
+			}
+			state = none
+		case quote:
+			if strings.HasPrefix(trimmed, ">") {
+				continue
+			}
+			state = none
+		case list:
+			switch {
+			case trimmed == "":
+				blankSeen = true
+				continue
+			case isListItem(line), isIndented(line), !blankSeen:
+				// Another item, a continuation, or a lazy continuation
+				blankSeen = false
+				if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+					fence = trimmed[:3]
+				}
+				continue
+			}
+			state = none
+		}
+
+		switch {
+		case isTableRow(line):
+			state, start = table, lineStart
+		case isListItem(line):
+			state, start, blankSeen = list, lineStart, false
+		case strings.HasPrefix(trimmed, ">"):
+			state, start = quote, lineStart
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			fence = trimmed[:3]
+		case strings.Contains(line, "|"):
+			state, start = tableHeader, lineStart
+		}
+	}
+
+	if state == none {
+		return -1
+	}
+	return start
+}
@@ -0,0 +1,121 @@
+package session
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPendingBlockStart(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    int
+	}{
+		{name: "prose", content: "Hello.\nWorld.\n", want: -1},
+		{name: "open list", content: "Intro\n- a\n- b\n", want: 6},
+		{name: "nested list", content: "- a\n  - b\n    - c\n", want: 0},
+		{name: "list with blank line may continue", content: "- a\n\n", want: 0},
+		{name: "loose list continues", content: "- a\n\n- b\n", want: 0},
+		{name: "lazy continuation", content: "- a\nmore text\n", want: 0},
+		{name: "list ended by paragraph", content: "- a\n\nAfter\n", want: -1},
+		{name: "partial line doesn't end a list", content: "- a\n\nAft", want: 0},
+		{name: "open table", content: "Text\n| a | b |\n|---|---|\n", want: 5},
+		{name: "table ended", content: "| a | b |\n|---|---|\nAfter\n", want: -1},
+		{name: "table then list", content: "| a |\n- x\n", want: 6},
+		{name: "possible header without outer pipes", content: "Text\nName | Size\n", want: 5},
+		{name: "open table without outer pipes", content: "Name | Size\n--- | :---:\na | 1\n", want: 0},
+		{name: "table without outer pipes ended", content: "Name | Size\n--- | ---\na | 1\n\n", want: -1},
+		{name: "pipe in prose", content: "Use a | b.\nMore text\n", want: -1},
+		{name: "open blockquote", content: "Text\n> [!NOTE]\n> body\n", want: 5},
+		{name: "blockquote ended", content: "> quote\nAfter\n", want: -1},
+		{name: "list items inside code", content: "```\n- a\n```\n", want: -1},
+		{name: "code inside list item", content: "- a\n  ```\n\nx\n  ```\n", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pendingBlockStart(tt.content); got != tt.want {
+				t.Errorf("pendingBlockStart(%q) = %d, want %d", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamedNestedListRendersWhole(t *testing.T) {
+	list := "- Fruits\n  - Apple\n  - Banana\n    - Cavendish\n- Vegetables\n  1. Carrot\n  2. Leek\n"
+
+	r, buf := createTestRenderer(t)
+	for i := 0; i < len(list); i += 3 {
+		end := i + 3
+		if end > len(list) {
+			end = len(list)
+		}
+		r.ProcessDelta(list[i:end])
+	}
+	if buf.Len() != 0 {
+		t.Errorf("list rendered before it was complete: %q", stripANSI(buf.String()))
+	}
+	r.Flush()
+
+	whole, err := r.glamourRenderer.Render(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stripANSI(buf.String()), stripANSI(strings.TrimSuffix(whole, "\n")); got != want {
+		t.Errorf("streamed list = %q, want it rendered in one piece as %q", got, want)
+	}
+}
+
+func TestIsTableSeparator(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{"|---|---|", true},
+		{"| :--- | ---: | :-: |", true},
+		{"--- | ---", true},
+		{"---", false},
+		{"| a | b |", false},
+		{"|--|x|", false},
+	}
+	for _, tt := range tests {
+		if got := isTableSeparator(tt.line); got != tt.want {
+			t.Errorf("isTableSeparator(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestStreamedTableRendersWhenComplete(t *testing.T) {
+	r, buf := createTestRenderer(t)
+
+	streamDeltas(r, []string{"| Name | Size |\n", "|------|------|\n", "| a | 1 |\n", "| longer name | 22 |\n"})
+	if buf.Len() != 0 {
+		t.Errorf("table rendered before it was complete: %q", stripANSI(buf.String()))
+	}
+
+	r.ProcessDelta("\nDone.\n")
+	output := stripANSI(buf.String())
+	if !strings.Contains(output, "longer name") {
+		t.Errorf("table not rendered once complete: %q", output)
+	}
+	if strings.Contains(output, "|---") {
+		t.Errorf("table rows rendered as text instead of a table: %q", output)
+	}
+}
+
+func TestStreamedTableWithoutOuterPipesRendersWhole(t *testing.T) {
+	r, buf := createTestRenderer(t)
+
+	streamDeltas(r, []string{"Sizes:\n\nName ", "| Size\n", "---|", "---\na | 1\n", "longer name | 22\n"})
+	if got := stripANSI(buf.String()); strings.Contains(got, "Name") {
+		t.Errorf("table header rendered before the table was complete: %q", got)
+	}
+
+	r.ProcessDelta("\nDone.\n")
+	output := stripANSI(buf.String())
+	if !strings.Contains(output, "longer name") {
+		t.Errorf("table not rendered once complete: %q", output)
+	}
+	if strings.Contains(output, "---") {
+		t.Errorf("table rows rendered as text instead of a table: %q", output)
+	}
+}
@@ -0,0 +1,153 @@
+package session
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// branch is a saved line of conversation that can be switched back to. Its
+// SDK session stays alive with its event handlers registered.
+type branch struct {
+	session        SessionInterface
+	model          string
+	multiplier     float64
+	turns          []Turn
+	recorded       []Turn
+	contextSummary string
+	currentTokens  int64
+	tokenLimit     int64
+	turnCount      int
+	startedAt      time.Time
+	conversationID string
+	sessionName    string
+	title          string
+	parentSession  string
+	systemPrompt   string
+	lastReply      string
+	lastPrompt     string
+	lastPromptTurn int
+	savedAt        time.Time
+	// stale is set when the connection session belonged to was lost; the
+	// conversation gets a new session when it is switched back to
+	stale bool
+}
+
+// BranchInfo describes a saved branch for display
+type BranchInfo struct {
+	// Index identifies the branch for SwitchBranch (1-based)
+	Index      int
+	Model      string
+	Turns      int
+	LastPrompt string
+	SavedAt    time.Time
+}
+
+// saveBranch captures the current conversation
+func (m *Manager) saveBranch() branch {
+	return branch{
+		session:        m.session,
+		model:          m.currentModel,
+		multiplier:     m.currentMultiplier,
+		turns:          m.turns,
+		recorded:       m.recorded,
+		contextSummary: m.contextSummary,
+		currentTokens:  m.currentTokens,
+		tokenLimit:     m.tokenLimit,
+		turnCount:      m.turnCount,
+		startedAt:      m.startedAt,
+		conversationID: m.conversationID,
+		sessionName:    m.sessionName,
+		title:          m.Title(),
+		parentSession:  m.parentSession,
+		systemPrompt:   m.systemPrompt,
+		lastReply:      m.lastReply,
+		lastPrompt:     m.lastPrompt,
+		lastPromptTurn: m.lastPromptTurn,
+		savedAt:        time.Now(),
+	}
+}
+
+// restoreBranch makes a saved conversation current
+func (m *Manager) restoreBranch(b branch) {
+	m.session = b.session
+	m.currentModel = b.model
+	m.currentMultiplier = b.multiplier
+	m.turns = b.turns
+	m.recorded = b.recorded
+	m.contextSummary = b.contextSummary
+	m.currentTokens = b.currentTokens
+	m.tokenLimit = b.tokenLimit
+	m.turnCount = b.turnCount
+	m.startedAt = b.startedAt
+	m.conversationID = b.conversationID
+	m.sessionName = b.sessionName
+	m.title = b.title
+	m.parentSession = b.parentSession
+	m.systemPrompt = b.systemPrompt
+	m.lastReply = b.lastReply
+	m.lastPrompt = b.lastPrompt
+	m.lastPromptTurn = b.lastPromptTurn
+	m.warnedAt = 0
+	// Nothing half-rendered carries over from the conversation left behind
+	if m.renderer != nil {
+		m.renderer.Reset()
+	}
+	if b.stale {
+		if err := m.Create(m.currentModel); err != nil {
+			slog.Warn("failed to recreate the session", "err", err)
+		}
+	}
+}
+
+// ForkAt saves the current conversation as a branch and starts a new session
+// that carries turns before turn n (1-based), ready for an edited version of
+// turn n's prompt. It returns the index of the saved branch.
+func (m *Manager) ForkAt(n int) (int, error) {
+	if m.session == nil {
+		return 0, fmt.Errorf("no active session")
+	}
+	if n < 1 || n > len(m.turns) {
+		return 0, fmt.Errorf("no turn %d (this conversation has %d)", n, len(m.turns))
+	}
+
+	saved := m.saveBranch()
+	m.turns = append([]Turn(nil), saved.turns[:n-1]...)
+	dropped := len(saved.turns) - len(m.turns)
+	m.turnCount -= dropped
+	m.recorded = append([]Turn(nil), saved.recorded[:max(0, len(saved.recorded)-dropped)]...)
+	// The fork is saved as a conversation of its own
+	m.conversationID = ""
+	if err := m.Create(m.currentModel); err != nil {
+		m.restoreBranch(saved)
+		return 0, err
+	}
+
+	m.branches = append(m.branches, saved)
+	return len(m.branches), nil
+}
+
+// Branches lists the saved branches
+func (m *Manager) Branches() []BranchInfo {
+	infos := make([]BranchInfo, len(m.branches))
+	for i, b := range m.branches {
+		info := BranchInfo{Index: i + 1, Model: b.model, Turns: len(b.turns), SavedAt: b.savedAt}
+		if len(b.turns) > 0 {
+			info.LastPrompt = b.turns[len(b.turns)-1].Prompt
+		}
+		infos[i] = info
+	}
+	return infos
+}
+
+// SwitchBranch makes saved branch i (1-based) current; the conversation that
+// was current takes its place in the list
+func (m *Manager) SwitchBranch(i int) error {
+	if i < 1 || i > len(m.branches) {
+		return fmt.Errorf("no branch %d", i)
+	}
+	current := m.saveBranch()
+	m.restoreBranch(m.branches[i-1])
+	m.branches[i-1] = current
+	return nil
+}
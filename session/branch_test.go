@@ -0,0 +1,77 @@
+package session
+
+import "testing"
+
+func threeTurnManager() *Manager {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	mgr.turns = []Turn{
+		{Prompt: "one", Response: "1"},
+		{Prompt: "two", Response: "2"},
+		{Prompt: "three", Response: "3"},
+	}
+	mgr.currentTokens = 300
+	mgr.tokenLimit = 1000
+	return mgr
+}
+
+func TestForkAt(t *testing.T) {
+	mgr := threeTurnManager()
+
+	saved, err := mgr.ForkAt(2)
+	if err != nil {
+		t.Fatalf("ForkAt() error = %v", err)
+	}
+	if saved != 1 {
+		t.Errorf("ForkAt() = %d, want branch 1", saved)
+	}
+
+	turns := mgr.Turns()
+	if len(turns) != 1 || turns[0].Prompt != "one" {
+		t.Errorf("Turns() after fork = %+v, want only turn one", turns)
+	}
+	if mgr.currentTokens != 0 {
+		t.Errorf("currentTokens = %d, want reset for the new session", mgr.currentTokens)
+	}
+
+	branches := mgr.Branches()
+	if len(branches) != 1 {
+		t.Fatalf("Branches() = %d, want 1", len(branches))
+	}
+	if branches[0].Turns != 3 || branches[0].LastPrompt != "three" {
+		t.Errorf("saved branch = %+v, want the original 3 turns", branches[0])
+	}
+}
+
+func TestForkAt_InvalidTurn(t *testing.T) {
+	mgr := threeTurnManager()
+	for _, n := range []int{0, 4} {
+		if _, err := mgr.ForkAt(n); err == nil {
+			t.Errorf("ForkAt(%d) error = nil, want error", n)
+		}
+	}
+	if len(mgr.Branches()) != 0 {
+		t.Error("failed fork saved a branch")
+	}
+}
+
+func TestSwitchBranch(t *testing.T) {
+	mgr := threeTurnManager()
+	if _, err := mgr.ForkAt(1); err != nil {
+		t.Fatalf("ForkAt() error = %v", err)
+	}
+	mgr.turns = append(mgr.turns, Turn{Prompt: "edited"})
+
+	if err := mgr.SwitchBranch(1); err != nil {
+		t.Fatalf("SwitchBranch() error = %v", err)
+	}
+	if len(mgr.Turns()) != 3 || mgr.currentTokens != 300 {
+		t.Errorf("after switch: %d turns, %d tokens; want the original branch back", len(mgr.Turns()), mgr.currentTokens)
+	}
+	if b := mgr.Branches()[0]; b.Turns != 1 || b.LastPrompt != "edited" {
+		t.Errorf("branch 1 = %+v, want the forked conversation saved in its place", b)
+	}
+
+	if err := mgr.SwitchBranch(2); err == nil {
+		t.Error("SwitchBranch(2) error = nil, want error")
+	}
+}
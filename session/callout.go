@@ -0,0 +1,144 @@
+package session
+
+import (
+	"regexp"
+	"strings"
+)
+
+// CalloutStyle is how one kind of callout is drawn
+type CalloutStyle struct {
+	Icon string
+	// Color is an ANSI foreground escape sequence
+	Color string
+}
+
+// DefaultCalloutStyles are the built-in callout styles, keyed by kind
+func DefaultCalloutStyles() map[string]CalloutStyle {
+	return map[string]CalloutStyle{
+		"note":      {Icon: "ℹ", Color: "\x1b[38;5;75m"},
+		"tip":       {Icon: "✓", Color: "\x1b[38;5;42m"},
+		"important": {Icon: "!", Color: "\x1b[38;5;141m"},
+		"warning":   {Icon: "⚠", Color: "\x1b[38;5;214m"},
+		"caution":   {Icon: "✖", Color: "\x1b[38;5;203m"},
+	}
+}
+
+// calloutRegex matches the first line of a callout, e.g. "> [!NOTE]" or
+// "> [!WARNING] Custom title"
+var calloutRegex = regexp.MustCompile(`(?i)^\s*>\s*\[!(note|tip|important|warning|caution)\]\s*(.*)$`)
+
+// trailingPaddingRegex matches the padding glamour adds to the end of lines
+var trailingPaddingRegex = regexp.MustCompile(`(\s|\x1b\[[0-9;]*m)+$`)
+
+// callout is a callout found in markdown
+type callout struct {
+	kind  string
+	title string
+	body  string
+}
+
+// proseSegment is a run of markdown or a callout
+type proseSegment struct {
+	text    string
+	callout *callout
+}
+
+// splitCallouts separates callouts from the surrounding markdown. A callout
+// runs until the first line that doesn't continue the blockquote.
+func splitCallouts(content string) []proseSegment {
+	var (
+		segments []proseSegment
+		text     strings.Builder
+		current  *callout
+		body     strings.Builder
+	)
+	flushText := func() {
+		if text.Len() > 0 {
+			segments = append(segments, proseSegment{text: text.String()})
+			text.Reset()
+		}
+	}
+	flushCallout := func() {
+		current.body = body.String()
+		segments = append(segments, proseSegment{callout: current})
+		current = nil
+		body.Reset()
+	}
+
+	for _, line := range strings.SplitAfter(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if current != nil {
+			if strings.HasPrefix(trimmed, ">") {
+				body.WriteString(strings.TrimPrefix(strings.TrimPrefix(trimmed, ">"), " ") + "\n")
+				continue
+			}
+			flushCallout()
+		}
+		if m := calloutRegex.FindStringSubmatch(trimmed); m != nil {
+			flushText()
+			current = &callout{kind: strings.ToLower(m[1]), title: strings.TrimSpace(m[2])}
+			continue
+		}
+		text.WriteString(line)
+	}
+
+	if current != nil {
+		flushCallout()
+	}
+	flushText()
+	return segments
+}
+
+// SetCalloutStyles overrides callout styles by kind; kinds not given keep
+// their current style
+func (r *StreamingMarkdownRenderer) SetCalloutStyles(styles map[string]CalloutStyle) {
+	if r.callouts == nil {
+		r.callouts = DefaultCalloutStyles()
+	}
+	for kind, style := range styles {
+		current := r.callouts[kind]
+		if style.Icon != "" {
+			current.Icon = style.Icon
+		}
+		if style.Color != "" {
+			current.Color = style.Color
+		}
+		r.callouts[kind] = current
+	}
+}
+
+// renderCallout draws a callout as a coloured title line followed by its
+// rendered body behind a coloured bar
+func (r *StreamingMarkdownRenderer) renderCallout(c *callout) string {
+	style := r.callouts[c.kind]
+	title := c.title
+	if title == "" {
+		title = strings.ToUpper(c.kind[:1]) + c.kind[1:]
+	}
+	bar := "  " + style.Color + "│" + ansiReset + " "
+
+	var out strings.Builder
+	out.WriteString("\n")
+	out.WriteString("  " + style.Color + "\x1b[1m" + style.Icon + " " + title + ansiReset + "\n")
+
+	if strings.TrimSpace(c.body) != "" {
+		lines := strings.Split(r.renderMarkdown(c.body), "\n")
+		// Drop the blank lines glamour puts around blocks
+		for len(lines) > 0 && isBlankRendered(lines[0]) {
+			lines = lines[1:]
+		}
+		for len(lines) > 0 && isBlankRendered(lines[len(lines)-1]) {
+			lines = lines[:len(lines)-1]
+		}
+		for _, line := range lines {
+			out.WriteString(bar + trailingPaddingRegex.ReplaceAllString(line, ansiReset) + "\n")
+		}
+	}
+	out.WriteString("\n")
+	return out.String()
+}
+
+// isBlankRendered reports whether a rendered line holds only padding
+func isBlankRendered(line string) bool {
+	return trailingPaddingRegex.ReplaceAllString(line, "") == ""
+}
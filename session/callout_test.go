@@ -0,0 +1,67 @@
+package session
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitCallouts(t *testing.T) {
+	content := "Intro\n> [!WARNING] Heads up\n> Don't **do** this.\n>\n> Really.\nAfter\n> plain quote\n"
+	segs := splitCallouts(content)
+	if len(segs) != 3 {
+		t.Fatalf("splitCallouts() = %d segments, want 3: %+v", len(segs), segs)
+	}
+	if segs[0].text != "Intro\n" {
+		t.Errorf("segment 0 = %q, want the intro", segs[0].text)
+	}
+	c := segs[1].callout
+	if c == nil || c.kind != "warning" || c.title != "Heads up" || c.body != "Don't **do** this.\n\nReally.\n" {
+		t.Errorf("segment 1 = %+v, want the warning callout", c)
+	}
+	if segs[2].text != "After\n> plain quote\n" {
+		t.Errorf("segment 2 = %q, want the rest, with the plain quote left alone", segs[2].text)
+	}
+}
+
+func TestSplitCallouts_CaseInsensitive(t *testing.T) {
+	segs := splitCallouts("> [!note]\n> hi\n")
+	if len(segs) != 1 || segs[0].callout == nil || segs[0].callout.kind != "note" {
+		t.Errorf("splitCallouts() = %+v, want one note callout", segs)
+	}
+}
+
+func TestRenderCallout(t *testing.T) {
+	r, buf := createTestRenderer(t)
+
+	streamDeltas(r, []string{"> [!TIP]\n", "> Use `go vet`.\n", "\nDone.\n"})
+	r.Flush()
+
+	output := buf.String()
+	style := DefaultCalloutStyles()["tip"]
+	if !strings.Contains(output, style.Color+"\x1b[1m"+style.Icon+" Tip") {
+		t.Errorf("callout title not styled: %q", output)
+	}
+	if !strings.Contains(output, style.Color+"│") || !containsText(output, "go vet") {
+		t.Errorf("callout body not rendered behind the bar: %q", output)
+	}
+	if containsText(output, "[!TIP]") {
+		t.Errorf("callout marker should not be printed: %q", stripANSI(output))
+	}
+	if !containsText(output, "Done.") {
+		t.Errorf("text after the callout missing: %q", stripANSI(output))
+	}
+}
+
+func TestSetCalloutStyles(t *testing.T) {
+	r, buf := createTestRenderer(t)
+	r.SetCalloutStyles(map[string]CalloutStyle{"note": {Icon: "N"}})
+
+	r.ProcessDelta("> [!NOTE] Custom\n> body\n")
+	r.Flush()
+
+	output := buf.String()
+	want := DefaultCalloutStyles()["note"].Color + "\x1b[1mN Custom"
+	if !strings.Contains(output, want) {
+		t.Errorf("output = %q, want the custom icon with the default color", output)
+	}
+}
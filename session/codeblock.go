@@ -0,0 +1,84 @@
+package session
+
+import "strings"
+
+// markdownSegment is a run of markdown or a single fenced code block
+type markdownSegment struct {
+	// text is the segment as written, fences included
+	text string
+	code bool
+	// lang is the code block's lower-cased language, if any
+	lang string
+	// body is the code block's contents without its fences
+	body string
+}
+
+// splitCodeBlocks separates fenced code blocks from the surrounding markdown
+// so they can be rendered specially. An unclosed block runs to the end of
+// content.
+func splitCodeBlocks(content string) []markdownSegment {
+	var (
+		segments []markdownSegment
+		prose    strings.Builder
+		block    markdownSegment
+		body     strings.Builder
+		marker   string // fence of the open code block, if any
+	)
+	flushProse := func() {
+		if prose.Len() > 0 {
+			segments = append(segments, markdownSegment{text: prose.String()})
+			prose.Reset()
+		}
+	}
+	flushBlock := func() {
+		block.body = body.String()
+		segments = append(segments, block)
+		body.Reset()
+	}
+
+	for _, line := range strings.SplitAfter(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case marker == "":
+			if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+				flushProse()
+				marker = trimmed[:3]
+				block = markdownSegment{text: line, code: true, lang: fenceLanguage(trimmed)}
+				continue
+			}
+			prose.WriteString(line)
+		case trimmed == marker:
+			marker = ""
+			block.text += line
+			flushBlock()
+		default:
+			block.text += line
+			body.WriteString(line)
+		}
+	}
+
+	if marker != "" {
+		flushBlock()
+	}
+	flushProse()
+	return segments
+}
+
+// fenceLanguage returns the lower-cased language of a code fence line
+func fenceLanguage(fence string) string {
+	fields := strings.Fields(fence[3:])
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToLower(fields[0])
+}
+
+// renderPlainCode prints a code block exactly as written, without colours,
+// indentation, padding or wrapping, so selecting it in the terminal copies
+// clean code
+func renderPlainCode(body string) string {
+	if body != "" && !strings.HasSuffix(body, "\n") {
+		body += "\n"
+	}
+	return "\n" + body + "\n"
+}
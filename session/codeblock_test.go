@@ -0,0 +1,89 @@
+package session
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitCodeBlocks(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []markdownSegment
+	}{
+		{
+			name:    "prose only",
+			content: "text\n\nmore\n",
+			want:    []markdownSegment{{text: "text\n\nmore\n"}},
+		},
+		{
+			name:    "code between prose",
+			content: "before\n```Diff\n-a\n+b\n```\nafter\n",
+			want: []markdownSegment{
+				{text: "before\n"},
+				{text: "```Diff\n-a\n+b\n```\n", code: true, lang: "diff", body: "-a\n+b\n"},
+				{text: "after\n"},
+			},
+		},
+		{
+			name:    "tildes without language",
+			content: "~~~\nx\n~~~\n",
+			want:    []markdownSegment{{text: "~~~\nx\n~~~\n", code: true, body: "x\n"}},
+		},
+		{
+			name:    "unclosed block",
+			content: "```go\nx := 1\n",
+			want:    []markdownSegment{{text: "```go\nx := 1\n", code: true, lang: "go", body: "x := 1\n"}},
+		},
+		{
+			name:    "other fence inside a block",
+			content: "~~~md\n```diff\n+x\n```\n~~~\n",
+			want: []markdownSegment{{
+				text: "~~~md\n```diff\n+x\n```\n~~~\n", code: true, lang: "md", body: "```diff\n+x\n```\n",
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitCodeBlocks(tt.content)
+			if len(got) != len(tt.want) {
+				t.Fatalf("splitCodeBlocks() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("segment %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCopySafeCodeBlock(t *testing.T) {
+	r, buf := createTestRenderer(t)
+	r.SetCopySafe(true)
+
+	long := "x := \"" + strings.Repeat("a", 120) + "\""
+	streamDeltas(r, []string{"Run this:\n\n", "```go\n", long + "\n", "\tfmt.Println(x)\n```\n\n"})
+	r.Flush()
+
+	output := buf.String()
+	want := "\n" + long + "\n\tfmt.Println(x)\n\n"
+	if !strings.Contains(output, want) {
+		t.Errorf("code block not printed verbatim:\ngot  %q\nwant it to contain %q", output, want)
+	}
+	if !containsText(output, "Run this:") {
+		t.Errorf("prose missing: %q", stripANSI(output))
+	}
+}
+
+func TestCopySafeAppliesToDiffs(t *testing.T) {
+	r, buf := createTestRenderer(t)
+	r.SetCopySafe(true)
+
+	r.ProcessDelta("```diff\n-a\n+b\n```\n")
+	r.Flush()
+
+	if output := buf.String(); output != "\n-a\n+b\n" {
+		t.Errorf("output = %q, want the plain diff", output)
+	}
+}
@@ -0,0 +1,110 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/glamour/ansi"
+	"github.com/charmbracelet/glamour/styles"
+	"github.com/muesli/termenv"
+	"golang.org/x/term"
+)
+
+// DefaultCodeStyle keeps the code colors of the renderer style
+const DefaultCodeStyle = "default"
+
+// codeStylePreview is rendered by /theme to show a code style
+const codeStylePreview = "```go\n// greet says hello\nfunc greet(name string) string {\n\tif name == \"\" {\n\t\treturn \"Hello, world!\" // fallback\n\t}\n\treturn fmt.Sprintf(\"Hello, %s (%d)\", name, 42)\n}\n```\n"
+
+// WithCodeStyle sets the chroma style code blocks are highlighted with
+// (e.g. "monokai", "github"); "" or DefaultCodeStyle keeps the colors of
+// the renderer style
+func WithCodeStyle(name string) RendererOption {
+	return func(r *StreamingMarkdownRenderer) {
+		if name != DefaultCodeStyle {
+			r.codeStyle = name
+		}
+	}
+}
+
+// SetCodeStyle switches the chroma style code blocks are highlighted with,
+// rebuilding the glamour renderer. On error the current style is kept. It
+// has no effect with WithGlamourRenderer or in plain text mode.
+func (r *StreamingMarkdownRenderer) SetCodeStyle(name string) error {
+	if name == DefaultCodeStyle {
+		name = ""
+	}
+	if !r.ownGlamour {
+		r.codeStyle = name
+		return nil
+	}
+	old := r.codeStyle
+	r.codeStyle = name
+	gr, err := r.newGlamour(r.wordWrap)
+	if err != nil {
+		r.codeStyle = old
+		return err
+	}
+	r.glamourRenderer = gr
+	return nil
+}
+
+// CodeStyle returns the chroma style code blocks are highlighted with, or
+// DefaultCodeStyle
+func (r *StreamingMarkdownRenderer) CodeStyle() string {
+	if r.codeStyle == "" {
+		return DefaultCodeStyle
+	}
+	return r.codeStyle
+}
+
+// PreviewCodeStyle renders a short code sample in the current code style
+func (r *StreamingMarkdownRenderer) PreviewCodeStyle() {
+	r.renderContent(codeStylePreview)
+	r.output("\n")
+}
+
+// glamourStyle returns the option selecting the renderer style, with code
+// blocks highlighted in the code style if one is set
+func (r *StreamingMarkdownRenderer) glamourStyle() (glamour.TermRendererOption, error) {
+	if r.codeStyle == "" {
+		return glamour.WithStylePath(r.style), nil
+	}
+	cfg, err := loadStyle(r.style)
+	if err != nil {
+		return nil, err
+	}
+	// A chroma palette in the style takes precedence over the theme
+	cfg.CodeBlock.Chroma = nil
+	cfg.CodeBlock.Theme = r.codeStyle
+	return glamour.WithStyles(cfg), nil
+}
+
+// loadStyle resolves a glamour style name or JSON style file the way
+// glamour.WithStylePath does
+func loadStyle(style string) (ansi.StyleConfig, error) {
+	if style == styles.AutoStyle {
+		switch {
+		case !term.IsTerminal(int(os.Stdout.Fd())):
+			return styles.NoTTYStyleConfig, nil
+		case termenv.HasDarkBackground():
+			return styles.DarkStyleConfig, nil
+		default:
+			return styles.LightStyleConfig, nil
+		}
+	}
+	if cfg, ok := styles.DefaultStyles[style]; ok {
+		return *cfg, nil
+	}
+	data, err := os.ReadFile(style)
+	if err != nil {
+		return ansi.StyleConfig{}, fmt.Errorf("failed to read style %s: %w", style, err)
+	}
+	var cfg ansi.StyleConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return ansi.StyleConfig{}, fmt.Errorf("invalid style file %s: %w", style, err)
+	}
+	return cfg, nil
+}
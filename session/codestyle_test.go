@@ -0,0 +1,61 @@
+package session
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// renderCode renders a Go code block with r and returns the output
+func renderCode(r *StreamingMarkdownRenderer, buf *bytes.Buffer) string {
+	buf.Reset()
+	r.renderContent("```go\nfunc main() {}\n```\n")
+	return buf.String()
+}
+
+func TestCodeStyle(t *testing.T) {
+	buf := &bytes.Buffer{}
+	r, err := NewStreamingMarkdownRenderer(WithWriter(buf), WithCodeStyle("monokai"))
+	if err != nil {
+		t.Fatalf("NewStreamingMarkdownRenderer() error = %v", err)
+	}
+	if got := r.CodeStyle(); got != "monokai" {
+		t.Errorf("CodeStyle() = %q, want monokai", got)
+	}
+	monokai := renderCode(r, buf)
+	if !strings.Contains(stripANSI(monokai), "func main()") {
+		t.Fatalf("code not rendered: %q", monokai)
+	}
+
+	if err := r.SetCodeStyle("github"); err != nil {
+		t.Fatalf("SetCodeStyle() error = %v", err)
+	}
+	if renderCode(r, buf) == monokai {
+		t.Error("code rendered the same after switching styles")
+	}
+
+	if err := r.SetCodeStyle(DefaultCodeStyle); err != nil {
+		t.Fatalf("SetCodeStyle(default) error = %v", err)
+	}
+	base, baseBuf := createTestRenderer(t)
+	if got, want := renderCode(r, buf), renderCode(base, baseBuf); got != want || r.CodeStyle() != DefaultCodeStyle {
+		t.Errorf("default code style rendered %q, want the style's own colors %q", got, want)
+	}
+}
+
+func TestSetCodeStyle_KeepsStyleOnError(t *testing.T) {
+	bad := filepath.Join(t.TempDir(), "bad.json")
+	if err := os.WriteFile(bad, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	r, _ := createTestRenderer(t)
+	r.style = bad
+	if err := r.SetCodeStyle("monokai"); err == nil {
+		t.Error("SetCodeStyle() with an invalid style file should fail")
+	}
+	if got := r.CodeStyle(); got != DefaultCodeStyle {
+		t.Errorf("CodeStyle() after a failed switch = %q, want %q", got, DefaultCodeStyle)
+	}
+}
@@ -0,0 +1,190 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	copilot "github.com/github/copilot-sdk/go"
+
+	"atulm/cocli/client"
+	"atulm/cocli/models"
+)
+
+const (
+	// MinCompareModels and MaxCompareModels bound how many models one
+	// comparison asks
+	MinCompareModels = 2
+	MaxCompareModels = 4
+)
+
+// Comparison is one model's answer to a compared prompt
+type Comparison struct {
+	Model        string
+	Reply        string
+	Latency      time.Duration
+	InputTokens  int64
+	OutputTokens int64
+	// Err is set if the model didn't answer
+	Err error
+}
+
+// throwawaySession is a session used for one prompt and then destroyed
+type throwawaySession interface {
+	SessionInterface
+	Destroy() error
+}
+
+// newThrowawaySession creates a session for one prompt; replaced in tests
+var newThrowawaySession = func(cli *client.Client, cfg *copilot.SessionConfig) (throwawaySession, error) {
+	sess, err := cli.CreateSession(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if sess == nil {
+		return nil, fmt.Errorf("no session available")
+	}
+	return sess, nil
+}
+
+// CompareModels resolves the models a comparison asks: 2 to 4 distinct
+// models, each named by ID, name, alias or unambiguous part of one
+func (m *Manager) CompareModels(names []string) ([]copilot.ModelInfo, error) {
+	if len(names) < MinCompareModels || len(names) > MaxCompareModels {
+		return nil, fmt.Errorf("compare %d to %d models, not %d", MinCompareModels, MaxCompareModels, len(names))
+	}
+	list, err := m.client.GetModels()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models: %w", err)
+	}
+	var chosen []copilot.ModelInfo
+	for _, name := range names {
+		info, err := models.Match(list, m.aliases, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range chosen {
+			if c.ID == info.ID {
+				return nil, fmt.Errorf("%s is listed twice", info.ID)
+			}
+		}
+		chosen = append(chosen, info)
+	}
+	return chosen, nil
+}
+
+// Compare sends text to each model at once, each in a throwaway session
+// with the current system instructions but not the conversation, and
+// returns the answers in the order of list. The conversation is left as it
+// was; the requests count toward usage.
+func (m *Manager) Compare(list []copilot.ModelInfo, text string) ([]Comparison, error) {
+	p, err := m.preparePrompt(text)
+	if err != nil {
+		return nil, err
+	}
+	defer p.done()
+
+	results := make([]Comparison, len(list))
+	var wg sync.WaitGroup
+	for i, info := range list {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = m.ask(info.ID, m.instructions(), p)
+		}()
+	}
+	wg.Wait()
+
+	for i, r := range results {
+		if r.Err == nil {
+			m.addUsage(ModelUsage{
+				Model:           r.Model,
+				Messages:        1,
+				InputTokens:     r.InputTokens,
+				OutputTokens:    r.OutputTokens,
+				PremiumRequests: models.Multiplier(list[i]),
+				Streaming:       r.Latency,
+			})
+		}
+	}
+	return results, nil
+}
+
+// ask sends a prepared prompt to model in a throwaway session with the
+// given system message
+func (m *Manager) ask(model, system string, p *Prompt) Comparison {
+	result := Comparison{Model: model}
+	sess, err := newThrowawaySession(m.client, &copilot.SessionConfig{
+		Model: model,
+		SystemMessage: &copilot.SystemMessageConfig{
+			Mode:    "append",
+			Content: system,
+		},
+	})
+	if err != nil {
+		result.Err = fmt.Errorf("failed to create session: %w", err)
+		return result
+	}
+	defer sess.Destroy()
+
+	var mu sync.Mutex
+	unsubscribe := sess.On(func(event copilot.SessionEvent) {
+		if event.Type != copilot.AssistantUsage {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if event.Data.InputTokens != nil {
+			result.InputTokens += int64(*event.Data.InputTokens)
+		}
+		if event.Data.OutputTokens != nil {
+			result.OutputTokens += int64(*event.Data.OutputTokens)
+		}
+	})
+	defer unsubscribe()
+
+	start := time.Now()
+	event, err := sess.SendAndWait(copilot.MessageOptions{Prompt: p.Text, Attachments: p.Attachments}, m.sdkTimeout())
+	mu.Lock()
+	defer mu.Unlock()
+	result.Latency = time.Since(start)
+	switch {
+	case err != nil:
+		result.Err = err
+	case event == nil || event.Data.Content == nil:
+		result.Err = fmt.Errorf("model returned no reply")
+	default:
+		result.Reply = *event.Data.Content
+	}
+	return result
+}
+
+// PrintComparison prints each answer under a header naming the model, how
+// long it took and the tokens it used
+func (m *Manager) PrintComparison(results []Comparison) {
+	for _, r := range results {
+		header := comparisonHeader(r)
+		if m.renderer != nil && !m.renderer.plain {
+			header = toolStyle + header + ansiReset
+		}
+		fmt.Printf("\n%s\n\n", header)
+		if r.Err != nil {
+			fmt.Printf("Error: %v\n", r.Err)
+			continue
+		}
+		m.PrintMarkdown(r.Reply)
+	}
+}
+
+// comparisonHeader describes one answer, such as
+// "── gpt-4.1 · 3.2s · 1200 in / 350 out tokens ──"
+func comparisonHeader(r Comparison) string {
+	parts := []string{r.Model, r.Latency.Round(100 * time.Millisecond).String()}
+	if r.Err != nil {
+		parts = append(parts, "failed")
+	} else if r.InputTokens > 0 || r.OutputTokens > 0 {
+		parts = append(parts, fmt.Sprintf("%d in / %d out tokens", r.InputTokens, r.OutputTokens))
+	}
+	return "── " + strings.Join(parts, " · ") + " ──"
+}
@@ -0,0 +1,151 @@
+package session
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	copilot "github.com/github/copilot-sdk/go"
+
+	"atulm/cocli/client"
+)
+
+// compareSession answers with a reply naming its model and reports usage
+type compareSession struct {
+	mockSession
+	handler copilot.SessionEventHandler
+}
+
+func (s *compareSession) On(handler copilot.SessionEventHandler) func() {
+	s.handler = handler
+	return func() {}
+}
+
+func (s *compareSession) SendAndWait(options copilot.MessageOptions, timeout time.Duration) (*copilot.SessionEvent, error) {
+	in, out := 100.0, 20.0
+	s.handler(copilot.SessionEvent{Type: copilot.AssistantUsage, Data: copilot.Data{InputTokens: &in, OutputTokens: &out}})
+	return s.mockSession.SendAndWait(options, timeout)
+}
+
+func (s *compareSession) Destroy() error { return nil }
+
+func TestCompareModels(t *testing.T) {
+	list := []copilot.ModelInfo{
+		{ID: "claude-haiku-4.5", Name: "Claude Haiku 4.5"},
+		{ID: "claude-sonnet-4.5", Name: "Claude Sonnet 4.5"},
+		{ID: "gpt-4.1", Name: "GPT-4.1"},
+	}
+	tests := []struct {
+		names   []string
+		want    []string
+		wantErr string
+	}{
+		{names: []string{"haiku", "gpt-4.1"}, want: []string{"claude-haiku-4.5", "gpt-4.1"}},
+		{names: []string{"gpt"}, wantErr: "compare 2 to 4 models, not 1"},
+		{names: []string{"a", "b", "c", "d", "e"}, wantErr: "not 5"},
+		{names: []string{"gpt", "gpt-4.1"}, wantErr: "gpt-4.1 is listed twice"},
+		{names: []string{"haiku", "llama"}, wantErr: `unknown model "llama"`},
+	}
+	for _, tt := range tests {
+		mgr := createTestManager(&mockSDKClient{models: list})
+		got, err := mgr.CompareModels(tt.names)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CompareModels(%v) error = %v, want %q", tt.names, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("CompareModels(%v) error = %v", tt.names, err)
+		}
+		var ids []string
+		for _, info := range got {
+			ids = append(ids, info.ID)
+		}
+		if strings.Join(ids, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("CompareModels(%v) = %v, want %v", tt.names, ids, tt.want)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	var mu sync.Mutex
+	var configs []*copilot.SessionConfig
+	orig := newThrowawaySession
+	newThrowawaySession = func(_ *client.Client, cfg *copilot.SessionConfig) (throwawaySession, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		configs = append(configs, cfg)
+		if cfg.Model == "broken" {
+			return nil, errors.New("no such model")
+		}
+		return &compareSession{mockSession: mockSession{reply: "answer from " + cfg.Model}}, nil
+	}
+	defer func() { newThrowawaySession = orig }()
+
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	mgr.systemPrompt = "Be brief."
+	list := []copilot.ModelInfo{
+		{ID: "gpt-4.1", Billing: &copilot.ModelBilling{Multiplier: 0}},
+		{ID: "broken"},
+		{ID: "claude-sonnet-4.5", Billing: &copilot.ModelBilling{Multiplier: 1}},
+	}
+	results, err := mgr.Compare(list, "hello")
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+
+	if len(configs) != 3 {
+		t.Fatalf("created %d sessions, want 3", len(configs))
+	}
+	for _, cfg := range configs {
+		if !strings.Contains(cfg.SystemMessage.Content, "Be brief.") {
+			t.Errorf("session for %s is missing the system prompt", cfg.Model)
+		}
+	}
+	if results[0].Reply != "answer from gpt-4.1" || results[2].Reply != "answer from claude-sonnet-4.5" {
+		t.Errorf("replies out of order: %+v", results)
+	}
+	if results[0].InputTokens != 100 || results[0].OutputTokens != 20 {
+		t.Errorf("tokens = %d/%d, want 100/20", results[0].InputTokens, results[0].OutputTokens)
+	}
+	if results[1].Err == nil || !strings.Contains(results[1].Err.Error(), "no such model") {
+		t.Errorf("broken model error = %v", results[1].Err)
+	}
+
+	// Only the models that answered count toward usage
+	usage := mgr.Usage()
+	if len(usage) != 2 || usage[1].Model != "claude-sonnet-4.5" || usage[1].PremiumRequests != 1 {
+		t.Errorf("usage = %+v", usage)
+	}
+	if mgr.session.(*mockSession).sendCount != 0 {
+		t.Error("Compare() sent to the conversation's session")
+	}
+}
+
+func TestComparisonHeader(t *testing.T) {
+	tests := []struct {
+		r    Comparison
+		want string
+	}{
+		{
+			r:    Comparison{Model: "gpt-4.1", Latency: 3240 * time.Millisecond, InputTokens: 1200, OutputTokens: 350},
+			want: "── gpt-4.1 · 3.2s · 1200 in / 350 out tokens ──",
+		},
+		{
+			r:    Comparison{Model: "gpt-4.1", Latency: time.Second},
+			want: "── gpt-4.1 · 1s ──",
+		},
+		{
+			r:    Comparison{Model: "broken", Err: errors.New("boom")},
+			want: "── broken · 0s · failed ──",
+		},
+	}
+	for _, tt := range tests {
+		if got := comparisonHeader(tt.r); got != tt.want {
+			t.Errorf("comparisonHeader() = %q, want %q", got, tt.want)
+		}
+	}
+}
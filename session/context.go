@@ -0,0 +1,252 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+// compactPrompt asks the model to summarize the conversation so far
+const compactPrompt = "Summarize our conversation so far so it can be continued in a new session. " +
+	"Keep decisions, open questions, file names, code identifiers, and any code that is still being worked on. " +
+	"Reply with the summary only."
+
+// summarizeTurnsPrompt asks the model to summarize the oldest turns, which
+// follow it in the same message
+const summarizeTurnsPrompt = "Summarize this earlier part of our conversation so it can be continued without it. " +
+	"Keep decisions, open questions, file names, code identifiers, and any code that is still being worked on. " +
+	"Reply with the summary only."
+
+// ErrNothingToCompact is returned by Compact when the session has no usage yet
+var ErrNothingToCompact = errors.New("nothing to compact yet")
+
+const (
+	// DefaultSummarizeBelow is how few tokens may be left in the context
+	// window before the oldest turns are summarized
+	DefaultSummarizeBelow = 20000
+
+	// KeepRecentTurns is how many of the latest turns summarizing leaves as
+	// they are
+	KeepRecentTurns = 4
+)
+
+// ContextPolicy configures context-window usage warnings and auto-compaction
+type ContextPolicy struct {
+	// WarnAt lists usage percentages that print a warning when first crossed
+	WarnAt []int
+	// AutoCompactAt is the usage percentage at or above which the
+	// conversation is compacted before the next send; 0 disables it
+	AutoCompactAt int
+	// SummarizeBelow is the number of tokens left below which the oldest
+	// turns are summarized before the next send; 0 disables it
+	SummarizeBelow int64
+}
+
+// DefaultContextPolicy warns at 75% and 90%, summarizes the oldest turns
+// once fewer than DefaultSummarizeBelow tokens are left and never compacts
+// the whole conversation automatically
+func DefaultContextPolicy() ContextPolicy {
+	return ContextPolicy{WarnAt: []int{75, 90}, SummarizeBelow: DefaultSummarizeBelow}
+}
+
+// SetAutoSummarize turns summarizing the oldest turns near the token limit
+// on or off for this run
+func (m *Manager) SetAutoSummarize(on bool) {
+	m.summarizeOff = !on
+}
+
+// AutoSummarize reports whether the oldest turns are summarized near the
+// token limit
+func (m *Manager) AutoSummarize() bool {
+	return !m.summarizeOff && m.contextPolicy.SummarizeBelow > 0
+}
+
+// SetContextPolicy replaces the context usage policy
+func (m *Manager) SetContextPolicy(p ContextPolicy) {
+	p.WarnAt = append([]int(nil), p.WarnAt...)
+	sort.Ints(p.WarnAt)
+	m.contextPolicy = p
+}
+
+// ContextUsagePercent returns how full the context window is, or -1 when
+// the token limit is not known yet
+func (m *Manager) ContextUsagePercent() int {
+	if !m.HasTokenLimit() {
+		return -1
+	}
+	return int(m.currentTokens * 100 / m.tokenLimit)
+}
+
+// ContextLevel is how full the context window is compared to the warning
+// thresholds
+type ContextLevel int
+
+const (
+	// ContextOK is below every warning threshold, or usage isn't known
+	ContextOK ContextLevel = iota
+	// ContextWarning is past a warning threshold but not the highest
+	ContextWarning
+	// ContextCritical is past the highest warning threshold
+	ContextCritical
+)
+
+// ContextLevel reports how full the context window is compared to the
+// policy's warning thresholds
+func (m *Manager) ContextLevel() ContextLevel {
+	usage := m.ContextUsagePercent()
+	warnAt := m.contextPolicy.WarnAt
+	switch {
+	case usage < 0 || len(warnAt) == 0 || usage < warnAt[0]:
+		return ContextOK
+	case usage < warnAt[len(warnAt)-1]:
+		return ContextWarning
+	default:
+		return ContextCritical
+	}
+}
+
+// checkContextUsage warns once per threshold as usage climbs
+func (m *Manager) checkContextUsage() {
+	usage := m.ContextUsagePercent()
+	if usage < 0 {
+		return
+	}
+
+	crossed := 0
+	for _, threshold := range m.contextPolicy.WarnAt {
+		if usage >= threshold {
+			crossed = threshold
+		}
+	}
+	if crossed <= m.warnedAt {
+		return
+	}
+	m.warnedAt = crossed
+
+	full := fmt.Sprintf("%d%%", usage)
+	tokens := fmt.Sprintf("%d/%d", m.currentTokens, m.tokenLimit)
+	if auto := m.contextPolicy.AutoCompactAt; auto > 0 && usage >= auto {
+		slog.Warn("the context window is filling up; the conversation will be compacted before the next message", "full", full, "tokens", tokens)
+	} else {
+		slog.Warn("the context window is filling up; use /compact to summarize the conversation and free space", "full", full, "tokens", tokens)
+	}
+}
+
+// maybeAutoCompact compacts before a send once usage reaches the policy's
+// auto-compaction threshold
+func (m *Manager) maybeAutoCompact() error {
+	auto := m.contextPolicy.AutoCompactAt
+	usage := m.ContextUsagePercent()
+	if auto <= 0 || usage < auto {
+		return nil
+	}
+	m.notice("Context window is %d%% full; compacting the conversation first...\n", usage)
+	return m.Compact()
+}
+
+// maybeSummarize summarizes the oldest turns before a send once fewer
+// tokens are left than the policy allows. The latest turns are kept, so
+// there is nothing to do until the conversation is longer than them.
+func (m *Manager) maybeSummarize() error {
+	left := m.GetTokensLeft()
+	if !m.AutoSummarize() || !m.HasTokenLimit() || left >= m.contextPolicy.SummarizeBelow {
+		return nil
+	}
+	n := len(m.turns) - KeepRecentTurns
+	if n <= 0 {
+		return nil
+	}
+	m.notice("Only %d tokens of context left; summarizing the %d oldest turns first (/compact auto off to stop)...\n", left, n)
+	return m.SummarizeOldest(n)
+}
+
+// SummarizeOldest asks the model to summarize the oldest n turns of the
+// context, together with any earlier summary, then replaces the session with
+// a new one that carries the summary and the remaining turns
+func (m *Manager) SummarizeOldest(n int) error {
+	if m.session == nil {
+		return fmt.Errorf("no active session")
+	}
+	if n <= 0 || n > len(m.turns) {
+		return fmt.Errorf("can't summarize %d of %d turns", n, len(m.turns))
+	}
+
+	var text strings.Builder
+	text.WriteString(summarizeTurnsPrompt)
+	if m.contextSummary != "" {
+		fmt.Fprintf(&text, "\n\nSummary of what came before:\n\n%s", m.contextSummary)
+	}
+	fmt.Fprintf(&text, "\n\nTranscript:\n\n%s", transcript(m.turns[:n]))
+	result := m.ask(m.currentModel, m.instructions(), &Prompt{Text: text.String()})
+	if result.Err != nil {
+		return fmt.Errorf("failed to summarize conversation: %w", result.Err)
+	}
+	if result.Reply == "" {
+		return fmt.Errorf("failed to summarize conversation: model returned no summary")
+	}
+	m.addUsage(ModelUsage{
+		Model:           m.currentModel,
+		Messages:        1,
+		InputTokens:     result.InputTokens,
+		OutputTokens:    result.OutputTokens,
+		PremiumRequests: m.currentMultiplier,
+		Streaming:       result.Latency,
+	})
+
+	before := m.currentTokens
+	previousSummary, previousTurns := m.contextSummary, m.turns
+	m.contextSummary = result.Reply
+	m.turns = slices.Clone(m.turns[n:])
+	if err := m.Create(m.currentModel); err != nil {
+		m.contextSummary, m.turns = previousSummary, previousTurns
+		return err
+	}
+	m.notice("Summarized %d turns (was %d tokens).\n", n, before)
+	return nil
+}
+
+// Compact asks the model to summarize the conversation, then replaces the
+// session with a new one on the same model whose system message carries the
+// summary, freeing the context window
+func (m *Manager) Compact() error {
+	if m.session == nil {
+		return fmt.Errorf("no active session")
+	}
+	if m.currentTokens == 0 {
+		return ErrNothingToCompact
+	}
+
+	summary, err := m.summarizeConversation()
+	if err != nil {
+		return err
+	}
+
+	before := m.currentTokens
+	m.contextSummary = summary
+	m.turns = nil
+	if err := m.Create(m.currentModel); err != nil {
+		return err
+	}
+	m.notice("Compacted conversation (was %d tokens).\n", before)
+	return nil
+}
+
+// summarizeConversation asks the current session for a summary of itself
+func (m *Manager) summarizeConversation() (string, error) {
+	// Don't stream the summary to the screen
+	m.muted = true
+	event, err := m.session.SendAndWait(copilot.MessageOptions{Prompt: compactPrompt}, m.sdkTimeout())
+	m.muted = false
+	if err != nil {
+		return "", fmt.Errorf("failed to summarize conversation: %w", err)
+	}
+	if event == nil || event.Data.Content == nil || *event.Data.Content == "" {
+		return "", fmt.Errorf("failed to summarize conversation: model returned no summary")
+	}
+	return *event.Data.Content, nil
+}
@@ -0,0 +1,229 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	copilot "github.com/github/copilot-sdk/go"
+
+	"atulm/cocli/client"
+)
+
+func TestContextUsagePercent(t *testing.T) {
+	mgr := createTestManager(&mockSDKClient{})
+	if got := mgr.ContextUsagePercent(); got != -1 {
+		t.Errorf("ContextUsagePercent() with unknown limit = %d, want -1", got)
+	}
+
+	mgr.tokenLimit = 1000
+	mgr.currentTokens = 755
+	if got := mgr.ContextUsagePercent(); got != 75 {
+		t.Errorf("ContextUsagePercent() = %d, want 75", got)
+	}
+}
+
+func TestContextLevel(t *testing.T) {
+	tests := []struct {
+		warnAt []int
+		limit  int64
+		tokens int64
+		want   ContextLevel
+	}{
+		{warnAt: []int{80, 95}, limit: 0, tokens: 0, want: ContextOK},
+		{warnAt: []int{80, 95}, limit: 100, tokens: 79, want: ContextOK},
+		{warnAt: []int{80, 95}, limit: 100, tokens: 80, want: ContextWarning},
+		{warnAt: []int{80, 95}, limit: 100, tokens: 96, want: ContextCritical},
+		{warnAt: []int{90}, limit: 100, tokens: 90, want: ContextCritical},
+		{warnAt: nil, limit: 100, tokens: 99, want: ContextOK},
+	}
+	for _, tt := range tests {
+		mgr := createTestManager(&mockSDKClient{})
+		mgr.SetContextPolicy(ContextPolicy{WarnAt: tt.warnAt})
+		mgr.tokenLimit, mgr.currentTokens = tt.limit, tt.tokens
+		if got := mgr.ContextLevel(); got != tt.want {
+			t.Errorf("ContextLevel() at %d/%d with %v = %d, want %d", tt.tokens, tt.limit, tt.warnAt, got, tt.want)
+		}
+	}
+}
+
+func TestCheckContextUsage_WarnsOncePerThreshold(t *testing.T) {
+	mgr := createTestManager(&mockSDKClient{})
+	mgr.tokenLimit = 100
+
+	steps := []struct {
+		tokens   int64
+		wantWarn bool
+	}{
+		{50, false},
+		{76, true},  // crosses 75
+		{80, false}, // still under 90, already warned
+		{91, true},  // crosses 90
+		{95, false},
+	}
+	for _, step := range steps {
+		mgr.currentTokens = step.tokens
+		out := captureLog(mgr.checkContextUsage)
+		if warned := strings.Contains(out, "level=WARN"); warned != step.wantWarn {
+			t.Errorf("at %d tokens: warned = %v, want %v (output %q)", step.tokens, warned, step.wantWarn, out)
+		}
+	}
+}
+
+func TestCheckContextUsage_MentionsAutoCompact(t *testing.T) {
+	mgr := createTestManager(&mockSDKClient{})
+	mgr.SetContextPolicy(ContextPolicy{WarnAt: []int{90, 50}, AutoCompactAt: 80})
+	mgr.tokenLimit = 100
+
+	mgr.currentTokens = 60
+	if out := captureLog(mgr.checkContextUsage); !strings.Contains(out, "/compact") {
+		t.Errorf("below auto-compact threshold: output %q, want /compact hint", out)
+	}
+
+	mgr.currentTokens = 92
+	if out := captureLog(mgr.checkContextUsage); !strings.Contains(out, "compacted before the next message") {
+		t.Errorf("above auto-compact threshold: output %q, want auto-compact notice", out)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	sess := mgr.session.(*mockSession)
+	sess.reply = "We were writing a ring buffer in Go."
+	mgr.tokenLimit = 100
+	mgr.currentTokens = 95
+	mgr.warnedAt = 90
+
+	captureOutput(func() {
+		if err := mgr.Compact(); err != nil {
+			t.Fatalf("Compact() error = %v", err)
+		}
+	})
+
+	if len(sess.sent) != 1 || sess.sent[0] != compactPrompt {
+		t.Errorf("sent = %q, want the compaction prompt", sess.sent)
+	}
+	if mgr.currentTokens != 0 || mgr.warnedAt != 0 {
+		t.Errorf("after Compact: tokens = %d, warnedAt = %d; want both reset", mgr.currentTokens, mgr.warnedAt)
+	}
+	if !strings.Contains(mgr.systemMessage(), sess.reply) {
+		t.Errorf("systemMessage() = %q, want it to carry the summary", mgr.systemMessage())
+	}
+
+	// Switching models starts over without the summary
+	if err := mgr.SetModel("gpt-4.1", 1); err != nil {
+		t.Fatalf("SetModel() error = %v", err)
+	}
+	if strings.Contains(mgr.systemMessage(), sess.reply) {
+		t.Error("systemMessage() still carries the summary after switching models")
+	}
+}
+
+func TestCompact_Errors(t *testing.T) {
+	mgr := createTestManager(&mockSDKClient{})
+	if err := mgr.Compact(); err == nil {
+		t.Error("Compact() without a session error = nil, want error")
+	}
+
+	mgr = createTestManagerWithSession(&mockSDKClient{})
+	if err := mgr.Compact(); !errors.Is(err, ErrNothingToCompact) {
+		t.Errorf("Compact() on empty session error = %v, want ErrNothingToCompact", err)
+	}
+
+	mgr.currentTokens = 10
+	if err := mgr.Compact(); err == nil || !strings.Contains(err.Error(), "no summary") {
+		t.Errorf("Compact() with empty reply error = %v, want no-summary error", err)
+	}
+}
+
+func TestSend_AutoCompacts(t *testing.T) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	sess := mgr.session.(*mockSession)
+	sess.reply = "summary"
+	mgr.SetContextPolicy(ContextPolicy{AutoCompactAt: 90})
+	mgr.tokenLimit = 100
+	mgr.currentTokens = 92
+
+	captureOutput(func() {
+		if err := mgr.Send("next question"); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	})
+
+	if len(sess.sent) != 2 || sess.sent[0] != compactPrompt || sess.sent[1] != "next question" {
+		t.Errorf("sent = %q, want compaction prompt then the question", sess.sent)
+	}
+}
+
+func TestSend_SummarizesOldestTurns(t *testing.T) {
+	var asked string
+	orig := newThrowawaySession
+	newThrowawaySession = func(_ *client.Client, cfg *copilot.SessionConfig) (throwawaySession, error) {
+		return &summarySession{asked: &asked}, nil
+	}
+	defer func() { newThrowawaySession = orig }()
+
+	newManager := func() *Manager {
+		mgr := createTestManagerWithSession(&mockSDKClient{})
+		for i := 1; i <= 6; i++ {
+			turn := Turn{Prompt: fmt.Sprintf("question %d", i), Response: fmt.Sprintf("answer %d", i)}
+			mgr.turns = append(mgr.turns, turn)
+			mgr.recorded = append(mgr.recorded, turn)
+		}
+		mgr.contextSummary = "earlier summary"
+		mgr.tokenLimit = 128000
+		mgr.currentTokens = 120000
+		return mgr
+	}
+
+	mgr := newManager()
+	captureOutput(func() {
+		if err := mgr.Send("next question"); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	})
+	if !strings.Contains(asked, "earlier summary") || !strings.Contains(asked, "question 2") || strings.Contains(asked, "question 3") {
+		t.Errorf("summary request = %q, want the earlier summary and the 2 oldest turns", asked)
+	}
+	if mgr.contextSummary != "the gist" {
+		t.Errorf("contextSummary = %q, want the model's summary", mgr.contextSummary)
+	}
+	if len(mgr.turns) != KeepRecentTurns+1 || mgr.turns[0].Prompt != "question 3" {
+		t.Errorf("turns = %+v, want the latest %d turns and the new one", mgr.turns, KeepRecentTurns)
+	}
+	if len(mgr.Transcript()) != 7 {
+		t.Errorf("Transcript() has %d turns, want all 7 kept", len(mgr.Transcript()))
+	}
+	if usage := mgr.Usage(); len(usage) != 1 || usage[0].Messages != 2 {
+		t.Errorf("usage = %+v, want the summary request counted", usage)
+	}
+
+	// Opted out, or with room left, nothing is summarized
+	for _, setup := range []func(*Manager){
+		func(m *Manager) { m.SetAutoSummarize(false) },
+		func(m *Manager) { m.currentTokens = 1000 },
+		func(m *Manager) { m.SetContextPolicy(ContextPolicy{}) },
+	} {
+		asked = ""
+		mgr := newManager()
+		setup(mgr)
+		captureOutput(func() { mgr.Send("next question") })
+		if asked != "" || len(mgr.turns) != 7 {
+			t.Errorf("%d turns left in context, want all 6 kept", len(mgr.turns)-1)
+		}
+	}
+}
+
+// summarySession answers a summary request with a fixed summary
+type summarySession struct {
+	compareSession
+	asked *string
+}
+
+func (s *summarySession) SendAndWait(options copilot.MessageOptions, timeout time.Duration) (*copilot.SessionEvent, error) {
+	*s.asked = options.Prompt
+	s.reply = "the gist"
+	return s.compareSession.SendAndWait(options, timeout)
+}
@@ -0,0 +1,77 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+// debugLog appends every session event to a file, for diagnosing streaming,
+// token and tool event issues. Events arrive on the SDK's goroutine while
+// the log is turned on and off from the prompt, hence the lock.
+type debugLog struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// StartDebugLog appends every session event from now on to path, one line
+// per event with the time it was received, its type and the event as JSON
+func (m *Manager) StartDebugLog(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to open the debug log: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open the debug log: %w", err)
+	}
+	fmt.Fprintf(f, "# debug log started %s, model %s\n", time.Now().Format(time.RFC3339), m.currentModel)
+
+	m.debug.mu.Lock()
+	old := m.debug.file
+	m.debug.path, m.debug.file = path, f
+	m.debug.mu.Unlock()
+	if old != nil {
+		old.Close()
+	}
+	return nil
+}
+
+// StopDebugLog stops logging session events
+func (m *Manager) StopDebugLog() error {
+	m.debug.mu.Lock()
+	f := m.debug.file
+	m.debug.path, m.debug.file = "", nil
+	m.debug.mu.Unlock()
+	if f == nil {
+		return nil
+	}
+	return f.Close()
+}
+
+// DebugLogPath returns the file session events are logged to, or "" when
+// the debug log is off
+func (m *Manager) DebugLogPath() string {
+	m.debug.mu.Lock()
+	defer m.debug.mu.Unlock()
+	return m.debug.path
+}
+
+// logEvent appends event to the debug log, if it is on
+func (m *Manager) logEvent(event copilot.SessionEvent) {
+	m.debug.mu.Lock()
+	defer m.debug.mu.Unlock()
+	if m.debug.file == nil {
+		return
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		data = []byte(fmt.Sprintf("%q", err.Error()))
+	}
+	fmt.Fprintf(m.debug.file, "%s %s %s\n", time.Now().Format(time.RFC3339Nano), event.Type, data)
+}
@@ -0,0 +1,51 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+func TestDebugLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "debug.log")
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	delta := "Hel"
+	tokens := 1200.0
+
+	mgr.handleEvent(copilot.SessionEvent{Type: copilot.AssistantMessageDelta, Data: copilot.Data{DeltaContent: &delta}})
+	if err := mgr.StartDebugLog(path); err != nil {
+		t.Fatalf("StartDebugLog() error = %v", err)
+	}
+	if mgr.DebugLogPath() != path {
+		t.Errorf("DebugLogPath() = %q, want %q", mgr.DebugLogPath(), path)
+	}
+	captureOutput(func() {
+		mgr.handleEvent(copilot.SessionEvent{Type: copilot.AssistantMessageDelta, Data: copilot.Data{DeltaContent: &delta}})
+		mgr.handleEvent(copilot.SessionEvent{Type: copilot.SessionUsageInfo, Data: copilot.Data{CurrentTokens: &tokens}})
+	})
+	if err := mgr.StopDebugLog(); err != nil {
+		t.Fatalf("StopDebugLog() error = %v", err)
+	}
+	mgr.handleEvent(copilot.SessionEvent{Type: copilot.SessionIdle})
+	if mgr.DebugLogPath() != "" {
+		t.Errorf("DebugLogPath() = %q after StopDebugLog", mgr.DebugLogPath())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "# debug log started") {
+		t.Fatalf("log = %q, want a header and the two events while on", data)
+	}
+	if !strings.Contains(lines[1], " assistant.message_delta {") || !strings.Contains(lines[1], `"deltaContent":"Hel"`) {
+		t.Errorf("delta line = %q", lines[1])
+	}
+	if !strings.Contains(lines[2], `"currentTokens":1200`) {
+		t.Errorf("usage line = %q", lines[2])
+	}
+}
@@ -0,0 +1,54 @@
+package session
+
+import (
+	"strings"
+)
+
+// diffLanguages are the code fence languages rendered as diffs
+var diffLanguages = map[string]bool{"diff": true, "patch": true, "udiff": true}
+
+// ANSI styles for diff lines, matching the 256-colour palette of the dark
+// glamour theme
+const (
+	diffAdded   = "\x1b[38;5;42m"
+	diffRemoved = "\x1b[38;5;203m"
+	diffHunk    = "\x1b[1;38;5;75m"
+	diffHeader  = "\x1b[1;38;5;252m"
+	diffContext = "\x1b[38;5;252m"
+	diffMeta    = "\x1b[38;5;243m"
+	ansiReset   = "\x1b[0m"
+)
+
+// diffIndent matches the margin glamour puts around code blocks
+const diffIndent = "  "
+
+// renderDiff colours a unified diff: additions green, removals red, hunk
+// headers and file headers each in their own style
+func renderDiff(body string) string {
+	var out strings.Builder
+	out.WriteString("\n")
+	inHunk := false
+	for _, line := range strings.Split(strings.TrimSuffix(body, "\n"), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		style := diffContext
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			style = diffHunk
+			inHunk = true
+		case strings.HasPrefix(line, "diff "), strings.HasPrefix(line, "index "):
+			style = diffHeader
+			inHunk = false
+		case !inHunk && (strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "+++ ")):
+			style = diffHeader
+		case strings.HasPrefix(line, "+"):
+			style = diffAdded
+		case strings.HasPrefix(line, "-"):
+			style = diffRemoved
+		case strings.HasPrefix(line, `\`):
+			style = diffMeta
+		}
+		out.WriteString(diffIndent + style + line + ansiReset + "\n")
+	}
+	out.WriteString("\n")
+	return out.String()
+}
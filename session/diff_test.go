@@ -0,0 +1,54 @@
+package session
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderDiff_LineStyles(t *testing.T) {
+	body := "diff --git a/x.go b/x.go\n--- a/x.go\n+++ b/x.go\n@@ -1,3 +1,3 @@ func main()\n ctx\n-old\n+new\n--- removed dashes\n\\ No newline at end of file\n"
+	lines := strings.Split(strings.Trim(renderDiff(body), "\n"), "\n")
+
+	want := []struct {
+		style, text string
+	}{
+		{diffHeader, "diff --git a/x.go b/x.go"},
+		{diffHeader, "--- a/x.go"},
+		{diffHeader, "+++ b/x.go"},
+		{diffHunk, "@@ -1,3 +1,3 @@ func main()"},
+		{diffContext, " ctx"},
+		{diffRemoved, "-old"},
+		{diffAdded, "+new"},
+		{diffRemoved, "--- removed dashes"},
+		{diffMeta, `\ No newline at end of file`},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("renderDiff() produced %d lines, want %d: %q", len(lines), len(want), lines)
+	}
+	for i, w := range want {
+		if expected := diffIndent + w.style + w.text + ansiReset; lines[i] != expected {
+			t.Errorf("line %d = %q, want %q", i, lines[i], expected)
+		}
+	}
+}
+
+func TestRenderDiffCodeBlock(t *testing.T) {
+	r, buf := createTestRenderer(t)
+
+	streamDeltas(r, []string{"Here is the fix:\n\n", "```diff\n-return nil\n", "+return err\n```\n\n", "Done.\n"})
+	r.Flush()
+
+	output := buf.String()
+	if !strings.Contains(output, diffRemoved+"-return nil"+ansiReset) {
+		t.Errorf("removed line not coloured red: %q", output)
+	}
+	if !strings.Contains(output, diffAdded+"+return err"+ansiReset) {
+		t.Errorf("added line not coloured green: %q", output)
+	}
+	if !containsText(output, "Here is the fix:") || !containsText(output, "Done.") {
+		t.Errorf("surrounding prose missing: %q", stripANSI(output))
+	}
+	if containsText(output, "```") {
+		t.Errorf("fence markers should not be printed: %q", stripANSI(output))
+	}
+}
@@ -0,0 +1,53 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// exportTimeFormat is how timestamps appear in exported transcripts
+const exportTimeFormat = "2006-01-02 15:04:05"
+
+// Transcript returns every turn of the current conversation, including
+// turns that were compacted away
+func (m *Manager) Transcript() []Turn {
+	return append([]Turn(nil), m.recorded...)
+}
+
+// MarkdownTranscript formats the conversation as a Markdown document, with
+// each turn's time and model and the assistant's raw markdown
+func (m *Manager) MarkdownTranscript() string {
+	return markdownTranscript(m.Title(), m.recorded, time.Now())
+}
+
+// markdownTranscript formats turns as a Markdown document exported at now,
+// headed by title if it isn't ""
+func markdownTranscript(title string, turns []Turn, now time.Time) string {
+	if title == "" {
+		title = "cocli conversation"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	if len(turns) > 0 {
+		fmt.Fprintf(&b, "- Started: %s\n", turns[0].At.Format(exportTimeFormat))
+	}
+	fmt.Fprintf(&b, "- Exported: %s\n", now.Format(exportTimeFormat))
+	fmt.Fprintf(&b, "- Turns: %d\n", len(turns))
+
+	for i, turn := range turns {
+		fmt.Fprintf(&b, "\n---\n\n## Turn %d · %s", i+1, turn.At.Format(exportTimeFormat))
+		if turn.Model != "" {
+			fmt.Fprintf(&b, " · %s", turn.Model)
+		}
+		fmt.Fprintf(&b, "\n\n### User\n\n%s\n\n### Assistant", strings.TrimSpace(turn.Prompt))
+		if len(turn.Alternatives) > 0 && turn.Model != "" {
+			fmt.Fprintf(&b, " · %s", turn.Model)
+		}
+		fmt.Fprintf(&b, "\n\n%s\n", strings.TrimSpace(turn.Response))
+		for _, alt := range turn.Alternatives {
+			fmt.Fprintf(&b, "\n### Assistant · %s (regenerated)\n\n%s\n", alt.Model, strings.TrimSpace(alt.Response))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,75 @@
+package session
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMarkdownTranscript(t *testing.T) {
+	at := time.Date(2025, 3, 1, 9, 30, 0, 0, time.UTC)
+	turns := []Turn{
+		{Prompt: "What is Go?", Response: "A **language**.\n", At: at, Model: "claude-sonnet-4.5"},
+		{Prompt: "Example?", Response: "```go\nfmt.Println(1)\n```", At: at.Add(time.Minute), Model: "gpt-4.1"},
+	}
+
+	got := markdownTranscript("", turns, at.Add(time.Hour))
+	want := `# cocli conversation
+
+- Started: 2025-03-01 09:30:00
+- Exported: 2025-03-01 10:30:00
+- Turns: 2
+
+---
+
+## Turn 1 · 2025-03-01 09:30:00 · claude-sonnet-4.5
+
+### User
+
+What is Go?
+
+### Assistant
+
+A **language**.
+
+---
+
+## Turn 2 · 2025-03-01 09:31:00 · gpt-4.1
+
+### User
+
+Example?
+
+### Assistant
+
+` + "```go\nfmt.Println(1)\n```\n"
+	if got != want {
+		t.Errorf("markdownTranscript() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestTranscript_KeepsCompactedTurns(t *testing.T) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	mgr.session.(*mockSession).reply = "summary"
+	for _, prompt := range []string{"one", "two"} {
+		if err := mgr.Send(prompt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mgr.currentTokens = 100
+	if err := mgr.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	mgr.Send("three")
+
+	transcript := mgr.Transcript()
+	if len(transcript) != 3 || len(mgr.Turns()) != 1 {
+		t.Fatalf("transcript has %d turns and history %d, want 3 and 1", len(transcript), len(mgr.Turns()))
+	}
+	if transcript[0].Model != mgr.GetCurrentModel() {
+		t.Errorf("turn model = %q, want %q", transcript[0].Model, mgr.GetCurrentModel())
+	}
+	if md := mgr.MarkdownTranscript(); !strings.Contains(md, "## Turn 3") {
+		t.Errorf("markdown = %q, want all three turns", md)
+	}
+}
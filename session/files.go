@@ -0,0 +1,177 @@
+package session
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"atulm/cocli/config"
+)
+
+// DefaultMaxFileBytes caps the size of a single @file attachment
+const DefaultMaxFileBytes = 256 << 10
+
+// fileRefRegex matches @path tokens at the start of the prompt or after
+// whitespace, so e-mail addresses are left alone
+var fileRefRegex = regexp.MustCompile(`(^|\s)@([^\s@]+)`)
+
+// FilePolicy limits what @file tokens may attach
+type FilePolicy struct {
+	// Allow lists glob patterns; when non-empty, only matching paths may be
+	// attached. Patterns without a slash match the file name, and a
+	// trailing "/**" matches everything under a directory.
+	Allow []string
+	// MaxFileBytes caps each file; 0 means DefaultMaxFileBytes
+	MaxFileBytes int
+	// MaxTokens caps the estimated tokens of all files in a prompt; 0 means
+	// half of the available context window
+	MaxTokens int64
+}
+
+// fileLanguages maps file extensions to fence language tags
+var fileLanguages = map[string]string{
+	".go": "go", ".py": "python", ".js": "javascript", ".jsx": "jsx",
+	".ts": "typescript", ".tsx": "tsx", ".rs": "rust", ".java": "java",
+	".kt": "kotlin", ".swift": "swift", ".c": "c", ".h": "c",
+	".cc": "cpp", ".cpp": "cpp", ".hpp": "cpp", ".cs": "csharp",
+	".rb": "ruby", ".php": "php", ".lua": "lua", ".sh": "bash",
+	".bash": "bash", ".zsh": "zsh", ".ps1": "powershell", ".sql": "sql",
+	".html": "html", ".css": "css", ".scss": "scss", ".json": "json",
+	".yaml": "yaml", ".yml": "yaml", ".toml": "toml", ".xml": "xml",
+	".md": "markdown", ".proto": "protobuf", ".tf": "hcl",
+	".diff": "diff", ".patch": "diff",
+}
+
+// fileNameLanguages maps well-known file names to fence language tags
+var fileNameLanguages = map[string]string{
+	"Makefile":   "make",
+	"Dockerfile": "dockerfile",
+	"go.mod":     "go",
+}
+
+// fileLanguage infers a fence language tag from a file's name
+func fileLanguage(path string) string {
+	base := filepath.Base(path)
+	if lang, ok := fileNameLanguages[base]; ok {
+		return lang
+	}
+	return fileLanguages[strings.ToLower(filepath.Ext(base))]
+}
+
+// AttachFiles returns a prompt stage that expands @path tokens into fenced
+// code blocks appended to the prompt, enforcing policy, and reports what
+// was attached. Tokens that don't name a file are sent as typed.
+func (m *Manager) AttachFiles(policy FilePolicy) PromptStage {
+	return func(p *Prompt) error {
+		items, err := m.expandFileRefs(p, policy)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			m.notice("Attached %s (%d lines, ~%d tokens)\n", item.Label, strings.Count(item.Text, "\n")+1, EstimateTokens(item.Text))
+		}
+		p.Text = withContext(p.Text, items)
+		return nil
+	}
+}
+
+// expandFileRefs reads the files named by @path tokens in p, replacing each
+// token with the quoted path
+func (m *Manager) expandFileRefs(p *Prompt, policy FilePolicy) ([]ContextItem, error) {
+	maxBytes := policy.MaxFileBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxFileBytes
+	}
+
+	var items []ContextItem
+	seen := map[string]bool{}
+	var firstErr error
+	p.Text = fileRefRegex.ReplaceAllStringFunc(p.Text, func(match string) string {
+		sub := fileRefRegex.FindStringSubmatch(match)
+		lead, ref := sub[1], strings.TrimRight(sub[2], ",.;:!?)")
+		trailing := sub[2][len(ref):]
+
+		path := config.ExpandHome(ref)
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			return match
+		}
+		if firstErr != nil {
+			return match
+		}
+		if !allowedFile(policy.Allow, path) {
+			firstErr = fmt.Errorf("@%s is not allowed by attachments.allow", ref)
+			return match
+		}
+		if !seen[path] {
+			seen[path] = true
+			text, err := readTextFile(path, maxBytes)
+			if err != nil {
+				firstErr = fmt.Errorf("@%s: %w", ref, err)
+				return match
+			}
+			items = append(items, ContextItem{Label: ref, Text: text, Lang: fileLanguage(path)})
+		}
+		return lead + "`" + ref + "`" + trailing
+	})
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	budget := policy.MaxTokens
+	if budget <= 0 {
+		budget = m.AvailableTokens() / 2
+	}
+	var total int64
+	for _, item := range items {
+		total += EstimateTokens(item.Text)
+	}
+	if budget > 0 && total > budget {
+		return nil, fmt.Errorf("attached files are ~%d tokens, over the budget of %d", total, budget)
+	}
+	return items, nil
+}
+
+// readTextFile reads a text file of at most maxBytes
+func readTextFile(path string, maxBytes int) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxBytes {
+		return "", fmt.Errorf("file is %d KiB, over the %d KiB limit", len(data)>>10, maxBytes>>10)
+	}
+	if bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data) {
+		return "", fmt.Errorf("not a text file")
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// allowedFile reports whether path matches one of the allow patterns; an
+// empty list allows everything
+func allowedFile(allow []string, path string) bool {
+	if len(allow) == 0 {
+		return true
+	}
+	path = filepath.ToSlash(filepath.Clean(path))
+	for _, pattern := range allow {
+		if dir, ok := strings.CutSuffix(pattern, "/**"); ok {
+			if strings.HasPrefix(path, dir+"/") {
+				return true
+			}
+			continue
+		}
+		name := path
+		if !strings.Contains(pattern, "/") {
+			name = filepath.Base(path)
+		}
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileLanguage(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"main.go", "go"},
+		{"web/App.TSX", "tsx"},
+		{"deploy/Dockerfile", "dockerfile"},
+		{"notes.txt", ""},
+		{"LICENSE", ""},
+	}
+	for _, tt := range tests {
+		if got := fileLanguage(tt.path); got != tt.want {
+			t.Errorf("fileLanguage(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestAllowedFile(t *testing.T) {
+	tests := []struct {
+		name  string
+		allow []string
+		path  string
+		want  bool
+	}{
+		{name: "no list", path: "secret.env", want: true},
+		{name: "name glob", allow: []string{"*.go"}, path: "session/files.go", want: true},
+		{name: "name glob miss", allow: []string{"*.go"}, path: ".env", want: false},
+		{name: "path glob", allow: []string{"docs/*.md"}, path: "docs/a.md", want: true},
+		{name: "path glob deeper", allow: []string{"docs/*.md"}, path: "docs/x/a.md", want: false},
+		{name: "dir", allow: []string{"internal/**"}, path: "internal/x/y.txt", want: true},
+		{name: "dir miss", allow: []string{"internal/**"}, path: "internalize.go", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := allowedFile(tt.allow, tt.path); got != tt.want {
+				t.Errorf("allowedFile(%v, %q) = %v, want %v", tt.allow, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAttachFiles(t *testing.T) {
+	dir := t.TempDir()
+	goFile := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(goFile, []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	envFile := filepath.Join(dir, ".env")
+	if err := os.WriteFile(envFile, []byte("TOKEN=x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	binFile := filepath.Join(dir, "a.bin")
+	if err := os.WriteFile(binFile, []byte{0, 1, 2}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		text    string
+		policy  FilePolicy
+		want    string
+		wantErr string
+	}{
+		{
+			name: "expands",
+			text: "review @" + goFile + ", please",
+			want: "review `" + goFile + "`, please\n\n" + goFile + ":\n```go\npackage main\n```",
+		},
+		{
+			name: "attached once",
+			text: "@" + goFile + " vs @" + goFile,
+			want: "`" + goFile + "` vs `" + goFile + "`\n\n" + goFile + ":\n```go\npackage main\n```",
+		},
+		{name: "not a file", text: "ping @alice and me@example.com", want: "ping @alice and me@example.com"},
+		{name: "not allowed", text: "@" + envFile, policy: FilePolicy{Allow: []string{"*.go"}}, wantErr: "not allowed"},
+		{name: "binary", text: "@" + binFile, wantErr: "not a text file"},
+		{name: "too big", text: "@" + goFile, policy: FilePolicy{MaxFileBytes: 4}, wantErr: "over the 0 KiB limit"},
+		{name: "over budget", text: "@" + goFile, policy: FilePolicy{MaxTokens: 1}, wantErr: "over the budget"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr := createTestManagerWithSession(&mockSDKClient{})
+			p := &Prompt{Text: tt.text}
+			var err error
+			out := captureOutput(func() { err = mgr.AttachFiles(tt.policy)(p) })
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if p.Text != tt.want {
+				t.Errorf("prompt = %q, want %q", p.Text, tt.want)
+			}
+			if strings.Contains(tt.want, "```go") && !strings.Contains(out, "Attached "+goFile+" (1 lines") {
+				t.Errorf("output = %q, want the attachment reported", out)
+			}
+		})
+	}
+}
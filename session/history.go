@@ -0,0 +1,65 @@
+package session
+
+import (
+	"strings"
+	"time"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+// Turn is one prompt and the assistant's reply
+type Turn struct {
+	Prompt   string    `json:"prompt"`
+	Response string    `json:"response"`
+	At       time.Time `json:"at"`
+	// Model answered the turn
+	Model string `json:"model,omitempty"`
+	// Alternatives are other models' answers to the prompt, from /regen.
+	// They aren't part of the conversation the model sees.
+	Alternatives []Alternative `json:"alternatives,omitempty"`
+}
+
+// Alternative is another model's answer to a turn's prompt
+type Alternative struct {
+	Model    string    `json:"model"`
+	Response string    `json:"response"`
+	At       time.Time `json:"at"`
+}
+
+// Turns returns the conversation's turns since it was last compacted
+func (m *Manager) Turns() []Turn {
+	return append([]Turn(nil), m.turns...)
+}
+
+// recordTurn appends a completed exchange to the history
+func (m *Manager) recordTurn(prompt string, reply *copilot.SessionEvent) {
+	turn := Turn{Prompt: prompt, At: time.Now(), Model: m.currentModel}
+	if reply != nil && reply.Data.Content != nil {
+		turn.Response = *reply.Data.Content
+	}
+	if turn.Response != "" {
+		m.lastReply = turn.Response
+		m.teeReply(turn.Response)
+	}
+	m.turns = append(m.turns, turn)
+	m.recorded = append(m.recorded, turn)
+	m.turnCount++
+	if m.startedAt.IsZero() {
+		m.startedAt = turn.At
+	}
+}
+
+// transcript formats turns as plain text for carrying into a new session
+func transcript(turns []Turn) string {
+	var b strings.Builder
+	for i, turn := range turns {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		b.WriteString("User: ")
+		b.WriteString(turn.Prompt)
+		b.WriteString("\n\nAssistant: ")
+		b.WriteString(turn.Response)
+	}
+	return b.String()
+}
@@ -0,0 +1,122 @@
+package session
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	copilot "github.com/github/copilot-sdk/go"
+
+	"atulm/cocli/config"
+	"atulm/cocli/models"
+)
+
+// DefaultMaxImageBytes caps an image when the model doesn't report a limit
+const DefaultMaxImageBytes = 20 << 20
+
+// imageRefRegex matches @image:path tokens at the start of the prompt or
+// after whitespace
+var imageRefRegex = regexp.MustCompile(`(^|\s)@image:(\S+)`)
+
+// imageTypes are the image formats that can be attached, by the media type
+// their contents are detected as
+var imageTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+// AttachImages returns a prompt stage that attaches the images named by
+// @image:path tokens, after checking that the current model accepts images
+// of that type, size and number. The server reads each image from its path,
+// so it must be on the same machine.
+func (m *Manager) AttachImages() PromptStage {
+	return func(p *Prompt) error {
+		matches := imageRefRegex.FindAllStringSubmatch(p.Text, -1)
+		if len(matches) == 0 {
+			return nil
+		}
+		if m.client != nil && m.client.RemoteAddr() != "" {
+			return fmt.Errorf("images can't be attached when using a remote daemon")
+		}
+		limits, err := m.visionLimits()
+		if err != nil {
+			return err
+		}
+		if limits.MaxPromptImages > 0 && len(matches) > limits.MaxPromptImages {
+			return fmt.Errorf("%s accepts at most %d images per prompt", m.currentModel, limits.MaxPromptImages)
+		}
+
+		var firstErr error
+		p.Text = imageRefRegex.ReplaceAllStringFunc(p.Text, func(match string) string {
+			sub := imageRefRegex.FindStringSubmatch(match)
+			lead, ref := sub[1], sub[2]
+			if firstErr != nil {
+				return match
+			}
+			att, err := imageAttachment(config.ExpandHome(ref), limits)
+			if err != nil {
+				firstErr = fmt.Errorf("@image:%s: %w", ref, err)
+				return match
+			}
+			p.Attachments = append(p.Attachments, att)
+			m.notice("Attached image %s\n", ref)
+			return lead + "`" + att.DisplayName + "`"
+		})
+		return firstErr
+	}
+}
+
+// visionLimits returns the current model's image limits, or an error if it
+// doesn't accept images. A model missing from the list is assumed to accept
+// them, leaving the server to decide.
+func (m *Manager) visionLimits() (copilot.ModelVisionLimits, error) {
+	list, err := m.client.GetModels()
+	if err != nil {
+		return copilot.ModelVisionLimits{}, nil
+	}
+	info, ok := models.Find(list, m.currentModel)
+	if !ok {
+		return copilot.ModelVisionLimits{}, nil
+	}
+	if !info.Capabilities.Supports.Vision {
+		return copilot.ModelVisionLimits{}, fmt.Errorf("%s doesn't accept images; switch to a model that does with /model", info.Name)
+	}
+	if info.Capabilities.Limits.Vision == nil {
+		return copilot.ModelVisionLimits{}, nil
+	}
+	return *info.Capabilities.Limits.Vision, nil
+}
+
+// imageAttachment checks that path is an image the model accepts and
+// returns the attachment for it
+func imageAttachment(path string, limits copilot.ModelVisionLimits) (copilot.Attachment, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return copilot.Attachment{}, err
+	}
+	data, err := os.ReadFile(abs)
+	if err != nil {
+		return copilot.Attachment{}, err
+	}
+
+	maxBytes := limits.MaxPromptImageSize
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxImageBytes
+	}
+	if len(data) > maxBytes {
+		return copilot.Attachment{}, fmt.Errorf("image is %d KiB, over the %d KiB limit", len(data)>>10, maxBytes>>10)
+	}
+
+	mediaType := http.DetectContentType(data)
+	if !slices.Contains(imageTypes, mediaType) {
+		return copilot.Attachment{}, fmt.Errorf("not a PNG, JPEG, GIF or WebP image")
+	}
+	if accepted := limits.SupportedMediaTypes; len(accepted) > 0 && !slices.ContainsFunc(accepted, func(t string) bool {
+		return strings.EqualFold(t, mediaType)
+	}) {
+		return copilot.Attachment{}, fmt.Errorf("the model doesn't accept %s images (it takes %s)", mediaType, strings.Join(accepted, ", "))
+	}
+
+	return copilot.Attachment{Type: copilot.File, Path: abs, DisplayName: filepath.Base(abs)}, nil
+}
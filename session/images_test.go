@@ -0,0 +1,121 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+// pngHeader is enough of a PNG for its type to be detected
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func visionModel(vision bool, limits *copilot.ModelVisionLimits) copilot.ModelInfo {
+	info := copilot.ModelInfo{ID: "gpt-4.1", Name: "GPT-4.1"}
+	info.Capabilities.Supports.Vision = vision
+	info.Capabilities.Limits.Vision = limits
+	return info
+}
+
+func TestAttachImages(t *testing.T) {
+	dir := t.TempDir()
+	png := filepath.Join(dir, "shot.png")
+	if err := os.WriteFile(png, pngHeader, 0644); err != nil {
+		t.Fatal(err)
+	}
+	text := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(text, []byte("just text"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		model    copilot.ModelInfo
+		prompt   string
+		wantText string
+		wantErr  string
+		attached int
+	}{
+		{
+			name:     "no image tokens",
+			model:    visionModel(false, nil),
+			prompt:   "hello @alice",
+			wantText: "hello @alice",
+		},
+		{
+			name:    "missing file",
+			model:   visionModel(true, nil),
+			prompt:  "what is in @image:" + png + "?",
+			wantErr: "no such file",
+		},
+		{
+			name:     "attaches image",
+			model:    visionModel(true, nil),
+			prompt:   "describe @image:" + png,
+			wantText: "describe `shot.png`",
+			attached: 1,
+		},
+		{
+			name:    "model without vision",
+			model:   visionModel(false, nil),
+			prompt:  "describe @image:" + png,
+			wantErr: "doesn't accept images",
+		},
+		{
+			name:    "not an image",
+			model:   visionModel(true, nil),
+			prompt:  "@image:" + text,
+			wantErr: "not a PNG",
+		},
+		{
+			name:    "media type not supported",
+			model:   visionModel(true, &copilot.ModelVisionLimits{SupportedMediaTypes: []string{"image/jpeg"}}),
+			prompt:  "@image:" + png,
+			wantErr: "doesn't accept image/png",
+		},
+		{
+			name:    "too large",
+			model:   visionModel(true, &copilot.ModelVisionLimits{MaxPromptImageSize: 4}),
+			prompt:  "@image:" + png,
+			wantErr: "limit",
+		},
+		{
+			name:    "too many images",
+			model:   visionModel(true, &copilot.ModelVisionLimits{MaxPromptImages: 1}),
+			prompt:  "@image:" + png + " @image:" + png,
+			wantErr: "at most 1 images",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr := createTestManager(&mockSDKClient{models: []copilot.ModelInfo{tt.model}})
+			mgr.currentModel = tt.model.ID
+			p := &Prompt{Text: tt.prompt}
+			err := mgr.AttachImages()(p)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if p.Text != tt.wantText {
+				t.Errorf("text = %q, want %q", p.Text, tt.wantText)
+			}
+			if len(p.Attachments) != tt.attached {
+				t.Fatalf("got %d attachments, want %d", len(p.Attachments), tt.attached)
+			}
+			if tt.attached > 0 {
+				att := p.Attachments[0]
+				if att.Path != png || att.DisplayName != "shot.png" || att.Type != copilot.File {
+					t.Errorf("attachment = %+v", att)
+				}
+			}
+		})
+	}
+}
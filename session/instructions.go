@@ -0,0 +1,68 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// instructionFiles hold a project's conventions for the model, relative to
+// its root. Every one that exists is added to the system message, in this
+// order.
+var instructionFiles = []string{
+	".cocli/instructions.md",
+	".github/copilot-instructions.md",
+}
+
+// maxInstructionTokens is the most of one instruction file added to the
+// system message
+const maxInstructionTokens = 8000
+
+// WithProjectRoot reads the project's instruction files (such as
+// .cocli/instructions.md) from dir whenever a session is created; ""
+// reads none
+func WithProjectRoot(dir string) ManagerOption {
+	return func(o *managerOptions) {
+		o.projectRoot = dir
+	}
+}
+
+// InstructionFiles returns the instruction files added to the current
+// session's system message, relative to the project root
+func (m *Manager) InstructionFiles() []string {
+	return m.instructionFiles
+}
+
+// loadInstructions reads the project's instruction files, so each session
+// starts with them as they are now
+func (m *Manager) loadInstructions() {
+	m.instructionFiles, m.projectInstructions = nil, ""
+	if m.projectRoot == "" {
+		return
+	}
+	var parts []string
+	for _, name := range instructionFiles {
+		data, err := os.ReadFile(filepath.Join(m.projectRoot, filepath.FromSlash(name)))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			slog.Warn("project instructions not read", "file", name, "err", err)
+			continue
+		}
+		text := strings.TrimSpace(string(data))
+		if text == "" {
+			continue
+		}
+		if EstimateTokens(text) > maxInstructionTokens {
+			slog.Warn("project instructions cut short", "file", name, "tokens", EstimateTokens(text), "max", maxInstructionTokens)
+			text = truncateTail(text, maxInstructionTokens)
+		}
+		m.instructionFiles = append(m.instructionFiles, name)
+		parts = append(parts, fmt.Sprintf("Project instructions from %s:\n\n%s", name, text))
+	}
+	m.projectInstructions = strings.Join(parts, "\n\n")
+}
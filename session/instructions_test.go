@@ -0,0 +1,70 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCreate_ReadsInstructionFiles(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(".github/copilot-instructions.md", "Use tabs.\n")
+
+	sdk := &mockSDKClient{}
+	mgr := createTestManager(sdk)
+	mgr.projectRoot = root
+	if err := mgr.Create("gpt-4.1"); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if !reflect.DeepEqual(mgr.InstructionFiles(), []string{".github/copilot-instructions.md"}) {
+		t.Errorf("InstructionFiles() = %v, want the copilot instructions", mgr.InstructionFiles())
+	}
+	if msg := sdk.created.SystemMessage.Content; !strings.Contains(msg, "Project instructions from .github/copilot-instructions.md:\n\nUse tabs.") {
+		t.Errorf("system message = %q, want the instructions in it", msg)
+	}
+
+	// The next session picks up a new file, before the other one
+	write(".cocli/instructions.md", "Wrap errors with %w.")
+	if err := mgr.Create("gpt-4.1"); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	msg := sdk.created.SystemMessage.Content
+	if i, j := strings.Index(msg, "Wrap errors"), strings.Index(msg, "Use tabs."); i < 0 || j < i {
+		t.Errorf("system message = %q, want .cocli/instructions.md and then the copilot instructions", msg)
+	}
+}
+
+func TestLoadInstructions(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, ".cocli"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	huge := strings.Repeat("Follow the style guide. ", maxInstructionTokens)
+	if err := os.WriteFile(filepath.Join(root, ".cocli", "instructions.md"), []byte(huge), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr := createTestManager(&mockSDKClient{})
+	mgr.projectRoot = root
+	mgr.loadInstructions()
+	if tokens := EstimateTokens(mgr.projectInstructions); tokens > maxInstructionTokens+100 {
+		t.Errorf("instructions are ~%d tokens, want them cut to about %d", tokens, maxInstructionTokens)
+	}
+
+	mgr.projectRoot = ""
+	mgr.loadInstructions()
+	if mgr.projectInstructions != "" || mgr.InstructionFiles() != nil {
+		t.Errorf("instructions = %q from %v, want none without a project root", mgr.projectInstructions, mgr.InstructionFiles())
+	}
+}
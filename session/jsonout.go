@@ -0,0 +1,98 @@
+package session
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+// Event types written in JSON output mode
+const (
+	EventMessageDelta    = "message_delta"
+	EventMessageComplete = "message_complete"
+	EventUsage           = "usage"
+	EventError           = "error"
+)
+
+// JSONEvent is one line of JSON output mode
+type JSONEvent struct {
+	Type  string `json:"type"`
+	Model string `json:"model,omitempty"`
+	// Text is the streamed fragment of a message_delta
+	Text string `json:"text,omitempty"`
+	// Content is the full reply of a message_complete
+	Content string `json:"content,omitempty"`
+	// Token counts of a usage event
+	InputTokens   *int64 `json:"input_tokens,omitempty"`
+	OutputTokens  *int64 `json:"output_tokens,omitempty"`
+	ContextTokens *int64 `json:"context_tokens,omitempty"`
+	TokenLimit    *int64 `json:"token_limit,omitempty"`
+	// Error is the message of an error event
+	Error string `json:"error,omitempty"`
+}
+
+// jsonWriter writes JSON events one per line
+type jsonWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func (w *jsonWriter) write(event JSONEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_ = w.enc.Encode(event)
+}
+
+// SetJSONOutput switches output to newline-delimited JSON events on w in
+// place of rendered markdown; nil switches back
+func (m *Manager) SetJSONOutput(w io.Writer) {
+	if w == nil {
+		m.jsonOut = nil
+		return
+	}
+	m.jsonOut = &jsonWriter{enc: json.NewEncoder(w)}
+}
+
+// WriteJSONError reports err as an error event in JSON output mode
+func (m *Manager) WriteJSONError(err error) {
+	if m.jsonOut != nil {
+		m.jsonOut.write(JSONEvent{Type: EventError, Model: m.currentModel, Error: err.Error()})
+	}
+}
+
+// writeJSONEvent converts a session event to JSON output
+func (m *Manager) writeJSONEvent(event copilot.SessionEvent) {
+	switch event.Type {
+	case copilot.AssistantMessageDelta:
+		if event.Data.DeltaContent != nil {
+			m.jsonOut.write(JSONEvent{Type: EventMessageDelta, Text: *event.Data.DeltaContent})
+		}
+	case copilot.AssistantMessage:
+		content := ""
+		if event.Data.Content != nil {
+			content = *event.Data.Content
+		}
+		m.jsonOut.write(JSONEvent{Type: EventMessageComplete, Model: m.currentModel, Content: content})
+	case copilot.AssistantUsage:
+		usage := JSONEvent{Type: EventUsage, Model: m.currentModel}
+		if event.Data.InputTokens != nil {
+			n := int64(*event.Data.InputTokens)
+			usage.InputTokens = &n
+		}
+		if event.Data.OutputTokens != nil {
+			n := int64(*event.Data.OutputTokens)
+			usage.OutputTokens = &n
+		}
+		if m.currentTokens > 0 {
+			n := m.currentTokens
+			usage.ContextTokens = &n
+		}
+		if m.tokenLimit > 0 {
+			n := m.tokenLimit
+			usage.TokenLimit = &n
+		}
+		m.jsonOut.write(usage)
+	}
+}
@@ -0,0 +1,60 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+func TestJSONOutput(t *testing.T) {
+	var out bytes.Buffer
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	mgr.SetJSONOutput(&out)
+	mgr.tokenLimit = 1000
+
+	delta, content := "Hel", "Hello"
+	in, outTokens := 12.0, 3.0
+	events := []copilot.SessionEvent{
+		{Type: copilot.AssistantMessageDelta, Data: copilot.Data{DeltaContent: &delta}},
+		{Type: copilot.AssistantMessage, Data: copilot.Data{Content: &content}},
+		{Type: copilot.AssistantUsage, Data: copilot.Data{InputTokens: &in, OutputTokens: &outTokens}},
+		{Type: copilot.SessionIdle},
+	}
+	stdout := captureOutput(func() {
+		for _, e := range events {
+			mgr.renderEvent(e)
+		}
+		mgr.WriteJSONError(errors.New("boom"))
+	})
+	if stdout != "" {
+		t.Errorf("JSON mode wrote %q to the terminal", stdout)
+	}
+
+	var got []JSONEvent
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var e JSONEvent
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", line, err)
+		}
+		got = append(got, e)
+	}
+	if len(got) != 4 {
+		t.Fatalf("got %d events, want delta, complete, usage and error: %s", len(got), out.String())
+	}
+	if got[0].Type != EventMessageDelta || got[0].Text != "Hel" {
+		t.Errorf("event 0 = %+v, want the delta", got[0])
+	}
+	if got[1].Type != EventMessageComplete || got[1].Content != "Hello" {
+		t.Errorf("event 1 = %+v, want the complete message", got[1])
+	}
+	if u := got[2]; u.Type != EventUsage || *u.InputTokens != 12 || *u.OutputTokens != 3 || *u.TokenLimit != 1000 || u.ContextTokens != nil {
+		t.Errorf("event 2 = %+v, want usage", u)
+	}
+	if got[3].Type != EventError || got[3].Error != "boom" {
+		t.Errorf("event 3 = %+v, want the error", got[3])
+	}
+}
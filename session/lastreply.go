@@ -0,0 +1,28 @@
+package session
+
+import "strings"
+
+// CodeBlock is a fenced code block of a reply
+type CodeBlock struct {
+	// Lang is the block's lower-cased language, if any
+	Lang string
+	// Code is the block's contents without its fences
+	Code string
+}
+
+// LastReply returns the raw markdown of the most recent assistant message,
+// or "" before the first reply
+func (m *Manager) LastReply() string {
+	return m.lastReply
+}
+
+// CodeBlocks returns the fenced code blocks of markdown in order
+func CodeBlocks(markdown string) []CodeBlock {
+	var blocks []CodeBlock
+	for _, seg := range splitCodeBlocks(markdown) {
+		if seg.code {
+			blocks = append(blocks, CodeBlock{Lang: seg.lang, Code: strings.TrimSuffix(seg.body, "\n")})
+		}
+	}
+	return blocks
+}
@@ -0,0 +1,56 @@
+package session
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCodeBlocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     []CodeBlock
+	}{
+		{name: "none", markdown: "just prose\n"},
+		{
+			name:     "two blocks",
+			markdown: "Run:\n\n```bash\ngo test ./...\n```\n\nThen:\n\n~~~Go\nfunc main() {}\n\n// done\n~~~\n",
+			want: []CodeBlock{
+				{Lang: "bash", Code: "go test ./..."},
+				{Lang: "go", Code: "func main() {}\n\n// done"},
+			},
+		},
+		{name: "unclosed", markdown: "```\nx := 1\n", want: []CodeBlock{{Code: "x := 1"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CodeBlocks(tt.markdown); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CodeBlocks() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLastReply(t *testing.T) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	if mgr.LastReply() != "" {
+		t.Errorf("LastReply() = %q before any reply, want empty", mgr.LastReply())
+	}
+
+	sess := mgr.session.(*mockSession)
+	sess.reply = "first"
+	mgr.Send("one")
+	sess.reply = "**second**"
+	mgr.Send("two")
+	if mgr.LastReply() != "**second**" {
+		t.Errorf("LastReply() = %q, want the latest raw markdown", mgr.LastReply())
+	}
+
+	// A model switch starts a new conversation but keeps the reply to copy
+	if err := mgr.SetModel("gpt-5", 1); err != nil {
+		t.Fatal(err)
+	}
+	if mgr.LastReply() != "**second**" {
+		t.Errorf("LastReply() after a model switch = %q, want it kept", mgr.LastReply())
+	}
+}
@@ -0,0 +1,96 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// defaultLiveHeight is the terminal height assumed when it can't be read
+const defaultLiveHeight = 24
+
+// WithLiveRender prints text as it streams and re-renders each element in
+// place once it is complete, instead of waiting for the element before
+// printing anything. size returns the terminal's width and height; zero
+// values fall back to the wrap column and 24 rows. It has no effect with
+// WithPlainText.
+func WithLiveRender(size func() (width, height int)) RendererOption {
+	return func(r *StreamingMarkdownRenderer) {
+		r.live = true
+		r.liveSize = size
+	}
+}
+
+// terminalSize returns the terminal's size for live rendering
+func (r *StreamingMarkdownRenderer) terminalSize() (int, int) {
+	width, height := 0, 0
+	if r.liveSize != nil {
+		width, height = r.liveSize()
+	}
+	if width <= 0 {
+		width = r.WordWrap()
+	}
+	if height <= 0 {
+		height = defaultLiveHeight
+	}
+	return width, height
+}
+
+// showPreview prints the part of the buffer not yet shown as raw text. The
+// preview starts on the row below the rendered output, which (like glamour's
+// output) ends without a newline. It never grows taller than the terminal,
+// so all of it can still be reached to be erased; the rest waits for the
+// rendered element.
+func (r *StreamingMarkdownRenderer) showPreview() {
+	pending := previewText(r.buffer.String())
+	if !strings.HasPrefix(pending, r.preview) || len(pending) == len(r.preview) {
+		return
+	}
+	width, height := r.terminalSize()
+	if previewRows(pending, width) >= height {
+		return
+	}
+	if r.preview == "" {
+		r.output("\n")
+	}
+	r.output(pending[len(r.preview):])
+	r.preview = pending
+}
+
+// clearPreview erases the raw preview so the rendered element replaces it,
+// leaving the cursor on the row the preview started below
+func (r *StreamingMarkdownRenderer) clearPreview() {
+	if r.preview == "" {
+		return
+	}
+	width, _ := r.terminalSize()
+	// The cursor is on the preview's last row
+	seq := "\r"
+	if rows := previewRows(r.preview, width); rows > 1 {
+		seq += fmt.Sprintf("\x1b[%dA", rows-1)
+	}
+	r.output(seq + "\x1b[J\x1b[1A")
+	r.preview = ""
+}
+
+// previewText is s as printed in the preview: tabs become spaces and
+// carriage returns are dropped, so its width on screen is known
+func previewText(s string) string {
+	s = strings.ReplaceAll(s, "\r", "")
+	return strings.ReplaceAll(s, "\t", "    ")
+}
+
+// previewRows returns how many terminal rows s takes up at width columns
+func previewRows(s string, width int) int {
+	rows := 0
+	for _, line := range strings.Split(s, "\n") {
+		w := runewidth.StringWidth(line)
+		if w == 0 {
+			rows++
+			continue
+		}
+		rows += (w + width - 1) / width
+	}
+	return rows
+}
@@ -0,0 +1,102 @@
+package session
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPreviewRows(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"", 1},
+		{"hello", 1},
+		{"hello\n", 2},
+		{"a\nb\nc", 3},
+		{strings.Repeat("x", 10), 1},
+		{strings.Repeat("x", 11), 2},
+		{strings.Repeat("x", 25) + "\nok", 4},
+		{"日本語日本", 1},
+		{"日本語日本語", 2},
+	}
+	for _, tt := range tests {
+		if got := previewRows(tt.s, 10); got != tt.want {
+			t.Errorf("previewRows(%q, 10) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func newLiveRenderer(t *testing.T, width, height int) (*StreamingMarkdownRenderer, *bytes.Buffer) {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	r, err := NewStreamingMarkdownRenderer(WithWriter(buf), WithLiveRender(func() (int, int) { return width, height }))
+	if err != nil {
+		t.Fatalf("NewStreamingMarkdownRenderer() error = %v", err)
+	}
+	return r, buf
+}
+
+func TestLiveRender(t *testing.T) {
+	r, buf := newLiveRenderer(t, 80, 24)
+
+	r.ProcessDelta("Hello ")
+	r.ProcessDelta("wor")
+	if got := buf.String(); got != "\nHello wor" {
+		t.Fatalf("preview = %q, want the raw text as it streams", got)
+	}
+
+	buf.Reset()
+	r.ProcessDelta("ld.\n\nNext\tpart")
+	out := buf.String()
+	clear := strings.Index(out, "\r\x1b[J\x1b[1A")
+	if clear != 0 {
+		t.Fatalf("output = %q, want the preview erased first", out)
+	}
+	if !strings.Contains(stripANSI(out), "Hello world.") {
+		t.Errorf("output = %q, want the paragraph rendered in its place", out)
+	}
+	if !strings.HasSuffix(out, "\nNext    part") {
+		t.Errorf("output = %q, want the rest previewed with tabs expanded", out)
+	}
+
+	buf.Reset()
+	r.Flush()
+	if out := buf.String(); !strings.HasPrefix(out, "\r\x1b[J\x1b[1A") || !strings.Contains(stripANSI(out), "Next") {
+		t.Errorf("Flush() output = %q, want the preview replaced by the rendered text", out)
+	}
+}
+
+func TestLiveRender_MultiRowPreview(t *testing.T) {
+	r, buf := newLiveRenderer(t, 10, 24)
+	r.ProcessDelta("```\none\n" + strings.Repeat("x", 15))
+	buf.Reset()
+	r.ProcessDelta("\n```\n")
+	// "```", "one" and 15 x's wrapped onto two rows
+	if out := buf.String(); !strings.HasPrefix(out, "\r\x1b[3A\x1b[J\x1b[1A") {
+		t.Errorf("output = %q, want the cursor moved up over the whole preview", out)
+	}
+}
+
+func TestLiveRender_PreviewFitsTerminal(t *testing.T) {
+	r, buf := newLiveRenderer(t, 80, 4)
+	r.ProcessDelta("```\na\nb\n")
+	shown := buf.String()
+	r.ProcessDelta("c\nd\n")
+	if buf.String() != shown {
+		t.Errorf("preview grew to %q, want it kept within the terminal's height", buf.String())
+	}
+}
+
+func TestLiveRender_Plain(t *testing.T) {
+	buf := &bytes.Buffer{}
+	r, err := NewStreamingMarkdownRenderer(WithWriter(buf), WithPlainText(), WithLiveRender(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.ProcessDelta("Hello")
+	if buf.Len() != 0 {
+		t.Errorf("plain text mode previewed %q", buf.String())
+	}
+}
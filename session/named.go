@@ -0,0 +1,314 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// sessionNameRegex matches names that are easy to type and safe to store
+var sessionNameRegex = regexp.MustCompile(`^[0-9A-Za-z][0-9A-Za-z._-]*$`)
+
+// SessionInfo describes a named session for display
+type SessionInfo struct {
+	Name         string
+	Title        string
+	Model        string
+	Turns        int
+	Tokens       int64
+	TokenLimit   int64
+	LastActivity time.Time
+	// Parent is the session this one was forked from, if any
+	Parent string
+	// Open is true when the session is live in this process; the others
+	// are reloaded from the history directory by OpenSession
+	Open    bool
+	Current bool
+}
+
+// SessionName returns the name of the current session, or "" if it has none
+func (m *Manager) SessionName() string {
+	return m.sessionName
+}
+
+// NewSession sets the current conversation aside and starts an empty one
+// called name on the current model. It returns the branch index an unnamed
+// conversation was saved as, or 0; named ones stay open under their name.
+func (m *Manager) NewSession(name string) (int, error) {
+	if !sessionNameRegex.MatchString(name) {
+		return 0, fmt.Errorf("invalid session name %q (use letters, digits, '.', '_' and '-')", name)
+	}
+	if m.session == nil {
+		return 0, fmt.Errorf("no active session")
+	}
+	exists, err := m.sessionExists(name)
+	if err != nil {
+		return 0, err
+	}
+	if exists {
+		return 0, fmt.Errorf("session %q already exists", name)
+	}
+
+	previous := m.saveBranch()
+	m.resetConversation()
+	m.sessionName, m.parentSession = name, ""
+	m.startedAt = time.Now()
+	if err := m.Create(m.currentModel); err != nil {
+		m.restoreBranch(previous)
+		return 0, err
+	}
+	// Saved before its first turn, so the name is taken across runs
+	if err := m.saveConversation(); err != nil {
+		slog.Warn("new session not saved", "err", err)
+	}
+	return m.setAside(previous), nil
+}
+
+// Fork copies the current conversation into a new session called name and
+// switches to it, so a different direction can be explored while the
+// original is left as it was. Without a name, one is made up from the
+// current session's. It returns the fork's name and the branch index an
+// unnamed original was saved as, or 0; named ones stay open.
+func (m *Manager) Fork(name string) (string, int, error) {
+	if m.session == nil {
+		return "", 0, fmt.Errorf("no active session")
+	}
+	if name == "" {
+		var err error
+		if name, err = m.forkName(); err != nil {
+			return "", 0, err
+		}
+	}
+	if !sessionNameRegex.MatchString(name) {
+		return "", 0, fmt.Errorf("invalid session name %q (use letters, digits, '.', '_' and '-')", name)
+	}
+	exists, err := m.sessionExists(name)
+	if err != nil {
+		return "", 0, err
+	}
+	if exists {
+		return "", 0, fmt.Errorf("session %q already exists", name)
+	}
+
+	previous := m.saveBranch()
+	m.turns = append([]Turn(nil), previous.turns...)
+	m.recorded = append([]Turn(nil), previous.recorded...)
+	m.parentSession = previous.sessionName
+	if m.parentSession == "" {
+		m.parentSession = previous.conversationID
+	}
+	m.sessionName = name
+	m.conversationID = ""
+	m.startedAt = time.Now()
+	if err := m.Create(m.currentModel); err != nil {
+		m.restoreBranch(previous)
+		return "", 0, err
+	}
+	if err := m.saveConversation(); err != nil {
+		slog.Warn("forked session not saved", "err", err)
+	}
+	return name, m.setAside(previous), nil
+}
+
+// forkName makes up an unused name for a fork of the current session
+func (m *Manager) forkName() (string, error) {
+	base := m.sessionName
+	if base == "" {
+		base = "fork"
+	}
+	for n := 1; ; n++ {
+		name := fmt.Sprintf("%s-%d", base, n)
+		exists, err := m.sessionExists(name)
+		if err != nil || !exists {
+			return name, err
+		}
+	}
+}
+
+// OpenSession switches to the named session, reloading it from the history
+// directory unless it is already open. The current conversation is set
+// aside as in NewSession.
+func (m *Manager) OpenSession(name string) (int, error) {
+	if name == m.sessionName {
+		return 0, fmt.Errorf("already in session %q", name)
+	}
+
+	previous := m.saveBranch()
+	if b, ok := m.openSessions[name]; ok {
+		delete(m.openSessions, name)
+		m.restoreBranch(b)
+		return m.setAside(previous), nil
+	}
+
+	id, err := m.savedSessionID(name)
+	if errors.Is(err, errNoSession) {
+		return 0, fmt.Errorf("no session %q", name)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err := m.Resume(id); err != nil {
+		return 0, err
+	}
+	return m.setAside(previous), nil
+}
+
+// Sessions lists the named sessions, open and saved, most recently active
+// first
+func (m *Manager) Sessions() ([]SessionInfo, error) {
+	var infos []SessionInfo
+	seen := make(map[string]bool)
+	add := func(info SessionInfo) {
+		if !seen[info.Name] {
+			seen[info.Name] = true
+			infos = append(infos, info)
+		}
+	}
+
+	for _, info := range m.LiveSessions() {
+		add(info)
+	}
+
+	saved, err := m.SavedConversations()
+	if err != nil {
+		return nil, err
+	}
+	// Most recent first, so a name's latest conversation wins
+	for _, c := range saved {
+		if c.Name != "" {
+			add(SessionInfo{
+				Name:         c.Name,
+				Title:        c.Title,
+				Parent:       c.Parent,
+				Model:        c.Model,
+				Turns:        c.TurnCount,
+				Tokens:       c.Tokens,
+				TokenLimit:   c.TokenLimit,
+				LastActivity: c.UpdatedAt,
+			})
+		}
+	}
+
+	sort.SliceStable(infos, func(i, j int) bool {
+		return infos[i].LastActivity.After(infos[j].LastActivity)
+	})
+	return infos, nil
+}
+
+// LiveSessions lists the named sessions open in this process, including the
+// current one, sorted by name. SwitchSession takes an index into it.
+func (m *Manager) LiveSessions() []SessionInfo {
+	var infos []SessionInfo
+	if m.sessionName != "" {
+		infos = append(infos, m.currentSessionInfo())
+	}
+	for name, b := range m.openSessions {
+		infos = append(infos, openSessionInfo(name, b))
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// SwitchSession makes live session i (1-based, as listed by LiveSessions)
+// current without recreating it. It returns the branch index an unnamed
+// conversation was saved as, or 0.
+func (m *Manager) SwitchSession(i int) (int, error) {
+	live := m.LiveSessions()
+	if i < 1 || i > len(live) {
+		return 0, fmt.Errorf("no session %d", i)
+	}
+	return m.OpenSession(live[i-1].Name)
+}
+
+// currentSessionInfo describes the current conversation
+func (m *Manager) currentSessionInfo() SessionInfo {
+	return SessionInfo{
+		Name:         m.sessionName,
+		Title:        m.Title(),
+		Parent:       m.parentSession,
+		Model:        m.currentModel,
+		Turns:        m.turnCount,
+		Tokens:       m.currentTokens,
+		TokenLimit:   m.tokenLimit,
+		LastActivity: lastActivity(m.turns, m.startedAt),
+		Open:         true,
+		Current:      true,
+	}
+}
+
+// openSessionInfo describes a named session that was set aside
+func openSessionInfo(name string, b branch) SessionInfo {
+	return SessionInfo{
+		Name:         name,
+		Title:        b.title,
+		Parent:       b.parentSession,
+		Model:        b.model,
+		Turns:        b.turnCount,
+		Tokens:       b.currentTokens,
+		TokenLimit:   b.tokenLimit,
+		LastActivity: lastActivity(b.turns, b.startedAt),
+		Open:         true,
+	}
+}
+
+// setAside keeps a conversation that was switched away from: named ones
+// stay open under their name, unnamed ones with turns become a branch. It
+// returns the branch index, or 0.
+func (m *Manager) setAside(b branch) int {
+	if b.sessionName != "" {
+		if m.openSessions == nil {
+			m.openSessions = make(map[string]branch)
+		}
+		m.openSessions[b.sessionName] = b
+		return 0
+	}
+	if b.turnCount == 0 {
+		return 0
+	}
+	m.branches = append(m.branches, b)
+	return len(m.branches)
+}
+
+// sessionExists reports whether name is taken by an open or saved session
+func (m *Manager) sessionExists(name string) (bool, error) {
+	if _, ok := m.openSessions[name]; ok || name == m.sessionName {
+		return true, nil
+	}
+	_, err := m.savedSessionID(name)
+	if errors.Is(err, errNoSession) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// errNoSession is returned by savedSessionID when no saved conversation
+// has the name
+var errNoSession = errors.New("no such session")
+
+// savedSessionID returns the ID of the latest saved conversation called name
+func (m *Manager) savedSessionID(name string) (string, error) {
+	saved, err := m.SavedConversations()
+	if err != nil {
+		return "", err
+	}
+	for _, c := range saved {
+		if c.Name == name {
+			return c.ID, nil
+		}
+	}
+	return "", errNoSession
+}
+
+// lastActivity is the time of the last turn, or when the conversation
+// started if it has none
+func lastActivity(turns []Turn, startedAt time.Time) time.Time {
+	if len(turns) > 0 {
+		return turns[len(turns)-1].At
+	}
+	return startedAt
+}
@@ -0,0 +1,205 @@
+package session
+
+import "testing"
+
+func TestNamedSessions(t *testing.T) {
+	dir := t.TempDir()
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	mgr.SetHistoryDir(dir)
+	if err := mgr.Send("unnamed question"); err != nil {
+		t.Fatal(err)
+	}
+
+	branch, err := mgr.NewSession("work")
+	if err != nil {
+		t.Fatalf("NewSession() error = %v", err)
+	}
+	if branch != 1 || mgr.SessionName() != "work" || len(mgr.Turns()) != 0 {
+		t.Errorf("NewSession() = branch %d, name %q, %d turns; want the unnamed conversation kept as branch 1 and an empty session", branch, mgr.SessionName(), len(mgr.Turns()))
+	}
+	if err := mgr.Send("work question"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.NewSession("work"); err == nil {
+		t.Error("NewSession() with a taken name should fail")
+	}
+
+	if branch, err := mgr.NewSession("home"); err != nil || branch != 0 {
+		t.Fatalf("NewSession(home) = %d, %v; want the named session kept open", branch, err)
+	}
+	if _, err := mgr.OpenSession("work"); err != nil {
+		t.Fatalf("OpenSession() error = %v", err)
+	}
+	if turns := mgr.Turns(); len(turns) != 1 || turns[0].Prompt != "work question" {
+		t.Errorf("Turns() = %+v, want the work session back", turns)
+	}
+
+	infos, err := mgr.Sessions()
+	if err != nil {
+		t.Fatalf("Sessions() error = %v", err)
+	}
+	if len(infos) != 2 {
+		t.Fatalf("Sessions() = %+v, want work and home", infos)
+	}
+	for _, info := range infos {
+		if !info.Open || info.Current != (info.Name == "work") {
+			t.Errorf("session %+v, want both open and work current", info)
+		}
+	}
+	if _, err := mgr.OpenSession("work"); err == nil {
+		t.Error("OpenSession() of the current session should fail")
+	}
+	if _, err := mgr.OpenSession("missing"); err == nil {
+		t.Error("OpenSession() of an unknown session should fail")
+	}
+}
+
+func TestOpenSession_FromDisk(t *testing.T) {
+	dir := t.TempDir()
+	first := createTestManagerWithSession(&mockSDKClient{})
+	first.SetHistoryDir(dir)
+	if _, err := first.NewSession("notes"); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Send("remember this"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A later run finds it in the history directory
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	mgr.SetHistoryDir(dir)
+	infos, err := mgr.Sessions()
+	if err != nil || len(infos) != 1 || infos[0].Name != "notes" || infos[0].Open || infos[0].Turns != 1 {
+		t.Fatalf("Sessions() = %+v, %v; want the saved session", infos, err)
+	}
+	if _, err := mgr.NewSession("notes"); err == nil {
+		t.Error("NewSession() should not reuse a saved session's name")
+	}
+	if _, err := mgr.OpenSession("notes"); err != nil {
+		t.Fatalf("OpenSession() error = %v", err)
+	}
+	if mgr.SessionName() != "notes" || mgr.ConversationID() != first.ConversationID() {
+		t.Errorf("opened %q (%s), want notes continuing %s", mgr.SessionName(), mgr.ConversationID(), first.ConversationID())
+	}
+}
+
+func TestNewSession_InvalidName(t *testing.T) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	for _, name := range []string{"", "two words", "../x", "-flag"} {
+		if _, err := mgr.NewSession(name); err == nil {
+			t.Errorf("NewSession(%q) should fail", name)
+		}
+	}
+}
+
+func TestSwitchSession(t *testing.T) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	if _, err := mgr.NewSession("api"); err != nil {
+		t.Fatal(err)
+	}
+	mgr.currentTokens, mgr.tokenLimit = 500, 1000
+	mgr.lastReply = "api answer"
+	if _, err := mgr.NewSession("web"); err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.SetModel("gpt-4.1", 1); err != nil {
+		t.Fatal(err)
+	}
+	mgr.currentTokens, mgr.tokenLimit = 20, 2000
+
+	live := mgr.LiveSessions()
+	if len(live) != 2 || live[0].Name != "api" || live[1].Name != "web" || !live[1].Current {
+		t.Fatalf("LiveSessions() = %+v, want api then the current web", live)
+	}
+	if _, err := mgr.SwitchSession(1); err != nil {
+		t.Fatalf("SwitchSession() error = %v", err)
+	}
+	if mgr.SessionName() != "api" || mgr.GetCurrentModel() != "Claude Sonnet 4.5" || mgr.currentTokens != 500 || mgr.LastReply() != "api answer" {
+		t.Errorf("after switch: %q on %q with %d tokens, last reply %q; want api's own state", mgr.SessionName(), mgr.GetCurrentModel(), mgr.currentTokens, mgr.LastReply())
+	}
+
+	if _, err := mgr.SwitchSession(2); err != nil {
+		t.Fatal(err)
+	}
+	if mgr.GetCurrentModel() != "gpt-4.1" || mgr.GetTokenLimit() != 2000 {
+		t.Errorf("back on web: %q with limit %d, want gpt-4.1 and 2000", mgr.GetCurrentModel(), mgr.GetTokenLimit())
+	}
+	for _, i := range []int{0, 3} {
+		if _, err := mgr.SwitchSession(i); err == nil {
+			t.Errorf("SwitchSession(%d) should fail", i)
+		}
+	}
+}
+
+func TestFork(t *testing.T) {
+	dir := t.TempDir()
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	mgr.SetHistoryDir(dir)
+	if _, err := mgr.NewSession("main"); err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.Send("shared question"); err != nil {
+		t.Fatal(err)
+	}
+
+	name, branch, err := mgr.Fork("")
+	if err != nil {
+		t.Fatalf("Fork() error = %v", err)
+	}
+	if name != "main-1" || branch != 0 || mgr.SessionName() != "main-1" {
+		t.Errorf("Fork() = %q, %d; want main-1 with main kept open", name, branch)
+	}
+	if turns := mgr.Turns(); len(turns) != 1 || turns[0].Prompt != "shared question" {
+		t.Errorf("fork turns = %+v, want the conversation copied", turns)
+	}
+	if err := mgr.Send("fork only"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := mgr.Fork("main"); err == nil {
+		t.Error("Fork() with a taken name should fail")
+	}
+
+	// A later run sees the fork's parent, and the original is unchanged
+	later := createTestManagerWithSession(&mockSDKClient{})
+	later.SetHistoryDir(dir)
+	infos, err := later.Sessions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parents := map[string]string{}
+	for _, info := range infos {
+		parents[info.Name] = info.Parent
+	}
+	if p, ok := parents["main-1"]; !ok || p != "main" || parents["main"] != "" {
+		t.Errorf("Sessions() = %+v, want main-1 forked from main", infos)
+	}
+	if _, err := mgr.OpenSession("main"); err != nil {
+		t.Fatal(err)
+	}
+	if turns := mgr.Turns(); len(turns) != 1 {
+		t.Errorf("original has %d turns after the fork went on, want 1", len(turns))
+	}
+}
+
+func TestFork_Unnamed(t *testing.T) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	mgr.SetHistoryDir(t.TempDir())
+	if err := mgr.Send("question"); err != nil {
+		t.Fatal(err)
+	}
+	id := mgr.ConversationID()
+
+	name, branch, err := mgr.Fork("idea")
+	if err != nil {
+		t.Fatalf("Fork() error = %v", err)
+	}
+	if name != "idea" || branch != 1 {
+		t.Errorf("Fork() = %q, %d; want idea with the original saved as branch 1", name, branch)
+	}
+	if info := mgr.LiveSessions()[0]; info.Parent != id || id == "" {
+		t.Errorf("fork parent = %q, want the original's ID %q", info.Parent, id)
+	}
+	if _, _, err := mgr.Fork("bad name"); err == nil {
+		t.Error("Fork() with an invalid name should fail")
+	}
+}
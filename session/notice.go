@@ -0,0 +1,16 @@
+package session
+
+import "fmt"
+
+// SetQuiet suppresses notices, such as attachments, retries, compaction and
+// stats lines, so only replies, warnings and errors are printed (--quiet)
+func (m *Manager) SetQuiet(quiet bool) {
+	m.quiet = quiet
+}
+
+// notice prints an informational message unless the manager is quiet
+func (m *Manager) notice(format string, args ...any) {
+	if !m.quiet {
+		fmt.Printf(format, args...)
+	}
+}
@@ -0,0 +1,122 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"atulm/cocli/models"
+)
+
+// ExportVersion is the version of the format Export writes; Import reads
+// this version and older ones
+const ExportVersion = 1
+
+// ExportedConversation is a conversation in the portable format of /export
+// and /import, documented in README.md
+type ExportedConversation struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	// Name is set for named sessions (/session new)
+	Name         string    `json:"name,omitempty"`
+	Title        string    `json:"title,omitempty"`
+	Model        string    `json:"model"`
+	SystemPrompt string    `json:"system_prompt,omitempty"`
+	StartedAt    time.Time `json:"started_at"`
+	// Turns is the whole transcript, including turns compacted away
+	Turns []Turn `json:"turns"`
+	// Tokens and TokenLimit are the context window usage when exported
+	Tokens     int64 `json:"tokens,omitempty"`
+	TokenLimit int64 `json:"token_limit,omitempty"`
+}
+
+// Export writes the current conversation to path as portable JSON and
+// returns how many turns it wrote
+func (m *Manager) Export(path string) (int, error) {
+	if len(m.recorded) == 0 {
+		return 0, fmt.Errorf("nothing to export yet")
+	}
+	data, err := json.MarshalIndent(&ExportedConversation{
+		Version:      ExportVersion,
+		ExportedAt:   time.Now(),
+		Name:         m.sessionName,
+		Title:        m.Title(),
+		Model:        m.currentModel,
+		SystemPrompt: m.systemPrompt,
+		StartedAt:    m.startedAt,
+		Turns:        m.recorded,
+		Tokens:       m.currentTokens,
+		TokenLimit:   m.tokenLimit,
+	}, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return 0, fmt.Errorf("failed to export conversation: %w", err)
+	}
+	return len(m.recorded), nil
+}
+
+// Import reads a conversation written by Export and replays it into a new
+// session, like Resume, saving it to the history under a new ID. Its name is
+// kept unless a session already has it, and its model unless the model
+// isn't available here.
+func (m *Manager) Import(path string) (*ExportedConversation, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var exported ExportedConversation
+	if err := json.Unmarshal(data, &exported); err != nil {
+		return nil, fmt.Errorf("%s is not an exported conversation: %w", path, err)
+	}
+	switch {
+	case exported.Version < 1:
+		return nil, fmt.Errorf("%s is not an exported conversation: no version", path)
+	case exported.Version > ExportVersion:
+		return nil, fmt.Errorf("%s was exported by a newer cocli (format version %d)", path, exported.Version)
+	case len(exported.Turns) == 0:
+		return nil, fmt.Errorf("%s has no turns to import", path)
+	}
+
+	if exported.Name != "" {
+		taken, err := m.sessionExists(exported.Name)
+		if err != nil || taken || !sessionNameRegex.MatchString(exported.Name) {
+			exported.Name = ""
+		}
+	}
+	model := exported.Model
+	if list, err := m.client.GetModels(); err == nil {
+		if info, ok := models.Find(list, model); ok {
+			model = info.ID
+		} else {
+			model = ""
+		}
+	}
+	if model == "" {
+		model = m.currentModel
+	}
+	startedAt := exported.StartedAt
+	if startedAt.IsZero() {
+		startedAt = exported.Turns[0].At
+	}
+
+	err = m.replay(&SavedConversation{
+		Name:         exported.Name,
+		Title:        exported.Title,
+		Model:        model,
+		SystemPrompt: exported.SystemPrompt,
+		StartedAt:    startedAt,
+		TurnCount:    len(exported.Turns),
+		Turns:        exported.Turns,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := m.saveConversation(); err != nil {
+		slog.Warn("imported conversation not saved", "err", err)
+	}
+	return &exported, nil
+}
@@ -0,0 +1,97 @@
+package session
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportImport(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "shared.json")
+
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	if _, err := mgr.Export(path); err == nil {
+		t.Error("Export() of an empty conversation should fail")
+	}
+	mgr.SetSystemPrompt("Be brief.")
+	mgr.sessionName = "work"
+	mgr.currentTokens, mgr.tokenLimit = 1200, 128000
+	for _, prompt := range []string{"first", "second"} {
+		if err := mgr.Send(prompt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n, err := mgr.Export(path)
+	if err != nil || n != 2 {
+		t.Fatalf("Export() = %d, %v; want 2 turns", n, err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exported map[string]any
+	if err := json.Unmarshal(data, &exported); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"version", "exported_at", "name", "model", "system_prompt", "started_at", "turns", "tokens", "token_limit"} {
+		if _, ok := exported[key]; !ok {
+			t.Errorf("export is missing %q", key)
+		}
+	}
+
+	other := createTestManagerWithSession(&mockSDKClient{})
+	other.SetHistoryDir(t.TempDir())
+	got, err := other.Import(path)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if got.Name != "work" || other.SessionName() != "work" {
+		t.Errorf("imported name %q, session %q; want work", got.Name, other.SessionName())
+	}
+	if turns := other.Transcript(); len(turns) != 2 || turns[1].Prompt != "second" {
+		t.Errorf("Transcript() = %+v, want the two exported turns", turns)
+	}
+	if other.SystemPrompt() != "Be brief." {
+		t.Errorf("SystemPrompt() = %q, want the exported one", other.SystemPrompt())
+	}
+	if other.ConversationID() == "" {
+		t.Error("the imported conversation wasn't saved to the history")
+	}
+
+	// Importing again keeps the turns but not the name, which is now taken
+	if got, err := other.Import(path); err != nil || got.Name != "" {
+		t.Errorf("Import() again = %+v, %v; want it unnamed", got, err)
+	}
+}
+
+func TestImport_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "not json", content: "# notes", wantErr: "not an exported conversation"},
+		{name: "no version", content: `{"turns": [{"prompt": "hi"}]}`, wantErr: "no version"},
+		{name: "newer version", content: `{"version": 99, "turns": [{"prompt": "hi"}]}`, wantErr: "newer cocli"},
+		{name: "no turns", content: `{"version": 1, "turns": []}`, wantErr: "no turns"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".json")
+			os.WriteFile(path, []byte(tt.content), 0600)
+			mgr := createTestManagerWithSession(&mockSDKClient{})
+			if _, err := mgr.Import(path); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Import() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	if _, err := mgr.Import(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("Import() of a missing file should fail")
+	}
+}
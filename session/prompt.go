@@ -0,0 +1,69 @@
+package session
+
+import (
+	"errors"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+// charsPerToken is the rough characters-per-token ratio used for estimates
+const charsPerToken = 4
+
+// ErrPromptCancelled is returned by Send when a pipeline stage cancels the prompt
+var ErrPromptCancelled = errors.New("prompt cancelled")
+
+// Prompt is a message being prepared for sending
+type Prompt struct {
+	Text        string
+	Attachments []copilot.Attachment
+
+	// cleanup runs after the prompt has been sent (e.g. removing temp files)
+	cleanup []func()
+}
+
+// OnSent registers a function to run once the prompt has been sent
+func (p *Prompt) OnSent(fn func()) {
+	p.cleanup = append(p.cleanup, fn)
+}
+
+// PromptStage transforms a prompt before it is sent. Returning an error
+// aborts the send; return ErrPromptCancelled when the user chose to cancel.
+type PromptStage func(p *Prompt) error
+
+// EstimateTokens returns a rough token count for text
+func EstimateTokens(text string) int64 {
+	return int64((len(text) + charsPerToken - 1) / charsPerToken)
+}
+
+// AddPromptStage appends a stage to the prompt pre-processing pipeline.
+// Stages run in the order they were added.
+func (m *Manager) AddPromptStage(stage PromptStage) {
+	m.promptStages = append(m.promptStages, stage)
+}
+
+// preparePrompt fills in {{variables}} and !(command) output, adds any
+// attached context to the prompt text and runs the pre-processing pipeline
+// over it. The context is used up once the pipeline lets the prompt through.
+func (m *Manager) preparePrompt(text string) (*Prompt, error) {
+	text, err := m.runShellCommands(m.expandVars(text))
+	if err != nil {
+		return nil, err
+	}
+	p := &Prompt{Text: withContext(text, m.pendingContext)}
+	for _, stage := range m.promptStages {
+		if err := stage(p); err != nil {
+			p.done()
+			return nil, err
+		}
+	}
+	m.pendingContext = nil
+	return p, nil
+}
+
+// done runs the prompt's cleanup functions
+func (p *Prompt) done() {
+	for _, fn := range p.cleanup {
+		fn()
+	}
+	p.cleanup = nil
+}
@@ -0,0 +1,82 @@
+package session
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int64
+	}{
+		{text: "", want: 0},
+		{text: "abc", want: 1},
+		{text: "abcd", want: 1},
+		{text: "abcde", want: 2},
+		{text: string(make([]byte, 4000)), want: 1000},
+	}
+
+	for _, tt := range tests {
+		if got := EstimateTokens(tt.text); got != tt.want {
+			t.Errorf("EstimateTokens(len %d) = %d, want %d", len(tt.text), got, tt.want)
+		}
+	}
+}
+
+func TestPromptPipeline_Order(t *testing.T) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	mgr.AddPromptStage(func(p *Prompt) error {
+		p.Text += " one"
+		return nil
+	})
+	mgr.AddPromptStage(func(p *Prompt) error {
+		p.Text += " two"
+		return nil
+	})
+
+	if err := mgr.Send("zero"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	sess := mgr.session.(*mockSession)
+	if sess.lastOptions.Prompt != "zero one two" {
+		t.Errorf("sent prompt = %q, want %q", sess.lastOptions.Prompt, "zero one two")
+	}
+}
+
+func TestPromptPipeline_Cancel(t *testing.T) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	cleaned := false
+	mgr.AddPromptStage(func(p *Prompt) error {
+		p.OnSent(func() { cleaned = true })
+		return ErrPromptCancelled
+	})
+
+	err := mgr.Send("hello")
+	if !errors.Is(err, ErrPromptCancelled) {
+		t.Errorf("Send() error = %v, want ErrPromptCancelled", err)
+	}
+	if mgr.session.(*mockSession).sendCount != 0 {
+		t.Error("cancelled prompt should not be sent")
+	}
+	if !cleaned {
+		t.Error("cleanup should run when a stage aborts the send")
+	}
+}
+
+func TestPromptPipeline_CleanupAfterSend(t *testing.T) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	cleaned := false
+	mgr.AddPromptStage(func(p *Prompt) error {
+		p.OnSent(func() { cleaned = true })
+		return nil
+	})
+
+	if err := mgr.Send("hello"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if !cleaned {
+		t.Error("cleanup should run after the prompt is sent")
+	}
+}
@@ -0,0 +1,87 @@
+package session
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HistoryEntry is a past prompt from the saved conversations
+type HistoryEntry struct {
+	// N numbers the entry from 1, oldest first, so numbers stay put as new
+	// prompts are added
+	N              int
+	Prompt         string
+	Response       string
+	At             time.Time
+	ConversationID string
+}
+
+// PromptHistory returns the prompts of every saved conversation, oldest
+// first. Without a history directory only the current conversation's
+// prompts are returned.
+func (m *Manager) PromptHistory() ([]HistoryEntry, error) {
+	var entries []HistoryEntry
+	if m.historyDir == "" {
+		for _, turn := range m.turns {
+			entries = append(entries, HistoryEntry{Prompt: turn.Prompt, Response: turn.Response, At: turn.At})
+		}
+		return numberEntries(entries), nil
+	}
+
+	files, err := os.ReadDir(m.historyDir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	for _, f := range files {
+		id, ok := strings.CutSuffix(f.Name(), ".json")
+		if f.IsDir() || !ok {
+			continue
+		}
+		saved, err := loadConversation(m.historyDir, id)
+		if err != nil {
+			continue // skip files that aren't conversations
+		}
+		for _, turn := range saved.Turns {
+			entries = append(entries, HistoryEntry{
+				Prompt:         turn.Prompt,
+				Response:       turn.Response,
+				At:             turn.At,
+				ConversationID: id,
+			})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].At.Before(entries[j].At)
+	})
+	return numberEntries(entries), nil
+}
+
+func numberEntries(entries []HistoryEntry) []HistoryEntry {
+	for i := range entries {
+		entries[i].N = i + 1
+	}
+	return entries
+}
+
+// SearchHistory returns the entries whose prompt or reply contains every
+// term, ignoring case
+func SearchHistory(entries []HistoryEntry, terms []string) []HistoryEntry {
+	var found []HistoryEntry
+	for _, e := range entries {
+		text := strings.ToLower(e.Prompt + "\n" + e.Response)
+		match := true
+		for _, term := range terms {
+			if !strings.Contains(text, strings.ToLower(term)) {
+				match = false
+				break
+			}
+		}
+		if match {
+			found = append(found, e)
+		}
+	}
+	return found
+}
@@ -0,0 +1,83 @@
+package session
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPromptHistory(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	write := func(id string, turns ...Turn) {
+		data, err := json.Marshal(&SavedConversation{ID: id, Turns: turns})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, id+".json"), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("first", Turn{Prompt: "explain goroutines", Response: "They are cheap threads", At: base},
+		Turn{Prompt: "and channels?", Response: "Typed pipes", At: base.Add(2 * time.Minute)})
+	write("second", Turn{Prompt: "fix my Makefile", Response: "Use tabs", At: base.Add(time.Minute)})
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr := createTestManager(&mockSDKClient{})
+	mgr.SetHistoryDir(dir)
+	entries, err := mgr.PromptHistory()
+	if err != nil {
+		t.Fatalf("PromptHistory() error = %v", err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Prompt)
+	}
+	want := []string{"explain goroutines", "fix my Makefile", "and channels?"}
+	if len(got) != len(want) {
+		t.Fatalf("PromptHistory() = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] || entries[i].N != i+1 {
+			t.Errorf("entry %d = %d %q, want %d %q", i, entries[i].N, got[i], i+1, want[i])
+		}
+	}
+	if entries[1].ConversationID != "second" {
+		t.Errorf("entry 2 conversation = %q, want second", entries[1].ConversationID)
+	}
+
+	tests := []struct {
+		terms []string
+		want  []int
+	}{
+		{[]string{"GOROUTINES"}, []int{1}},
+		{[]string{"tabs"}, []int{2}},
+		{[]string{"and", "pipes"}, []int{3}},
+		{[]string{"makefile", "pipes"}, nil},
+	}
+	for _, tt := range tests {
+		found := SearchHistory(entries, tt.terms)
+		var ns []int
+		for _, e := range found {
+			ns = append(ns, e.N)
+		}
+		if len(ns) != len(tt.want) || (len(ns) > 0 && ns[0] != tt.want[0]) {
+			t.Errorf("SearchHistory(%q) = %v, want %v", tt.terms, ns, tt.want)
+		}
+	}
+}
+
+func TestPromptHistory_WithoutHistoryDir(t *testing.T) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	if err := mgr.Send("hello"); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := mgr.PromptHistory()
+	if err != nil || len(entries) != 1 || entries[0].Prompt != "hello" || entries[0].N != 1 {
+		t.Errorf("PromptHistory() = %+v, %v; want the current conversation's prompt", entries, err)
+	}
+}
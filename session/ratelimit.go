@@ -0,0 +1,154 @@
+package session
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+// RateLimitError is returned by Send when the API throttled the prompt and
+// the retry policy ran out before it was allowed through
+type RateLimitError struct {
+	// Wait is how long the API asked to wait, 0 if it didn't say
+	Wait time.Duration
+	Err  error
+}
+
+func (e *RateLimitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+// rateLimitSigns are fragments of the errors the API throttles with
+var rateLimitSigns = []string{"rate limit", "rate_limit", "ratelimit", "too many requests", "429", "throttl"}
+
+// isRateLimit reports whether an error message or type is a rate limit
+func isRateLimit(s string) bool {
+	s = strings.ToLower(s)
+	for _, sign := range rateLimitSigns {
+		if strings.Contains(s, sign) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryAfterRegex finds the wait in messages such as "retry after 30
+// seconds", "Retry-After: 12" or "try again in 1m30s"
+var retryAfterRegex = regexp.MustCompile(`(?i)(?:retry[- ]after|try again in|retry in|resets? in)[:\s]*([0-9][0-9hms.]*)\s*([a-z]*)`)
+
+// parseRetryAfter returns the wait a rate limit message asks for, or 0.
+// A bare number is in seconds, as in the Retry-After header.
+func parseRetryAfter(msg string) time.Duration {
+	m := retryAfterRegex.FindStringSubmatch(msg)
+	if m == nil {
+		return 0
+	}
+	value := strings.TrimRight(m[1], ".") // the end of the sentence
+	if d, err := time.ParseDuration(value); err == nil {
+		return d
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	unit := time.Second
+	switch u := strings.ToLower(m[2]); {
+	case strings.HasPrefix(u, "ms"), strings.HasPrefix(u, "milli"):
+		unit = time.Millisecond
+	case strings.HasPrefix(u, "m"):
+		unit = time.Minute
+	case strings.HasPrefix(u, "h"):
+		unit = time.Hour
+	}
+	return time.Duration(n * float64(unit))
+}
+
+// noteRateLimit records a session error event that throttled the reply
+// being received, for exchange to report. The error SendAndWait returns
+// only carries the message, not the error type.
+func (m *Manager) noteRateLimit(event copilot.SessionEvent) {
+	var kind, msg string
+	if event.Data.ErrorType != nil {
+		kind = *event.Data.ErrorType
+	}
+	if event.Data.Message != nil {
+		msg = *event.Data.Message
+	}
+	if !isRateLimit(kind) && !isRateLimit(msg) {
+		return
+	}
+	m.respMu.Lock()
+	defer m.respMu.Unlock()
+	if m.resp != nil {
+		m.resp.rateLimited = true
+		m.resp.retryAfter = parseRetryAfter(msg)
+	}
+}
+
+// asRateLimit returns err as a *RateLimitError if the reply resp was
+// receiving was throttled
+func (m *Manager) asRateLimit(resp *response, err error) error {
+	m.respMu.Lock()
+	limited, wait := resp.rateLimited, resp.retryAfter
+	m.respMu.Unlock()
+	if !limited && !isRateLimit(err.Error()) {
+		return err
+	}
+	if wait == 0 {
+		wait = parseRetryAfter(err.Error())
+	}
+	return &RateLimitError{Wait: wait, Err: err}
+}
+
+// RateLimitWait returns how long until the API is expected to accept
+// prompts again after throttling them, or 0
+func (m *Manager) RateLimitWait() time.Duration {
+	if wait := time.Until(m.rateLimitedUntil); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// waitForRateLimit holds a prompt until the rate limit is expected to be
+// over, unless the user cancels the wait
+func (m *Manager) waitForRateLimit() error {
+	wait := m.RateLimitWait()
+	if wait <= 0 {
+		return nil
+	}
+	m.notice("Rate limited; sending in %s (Ctrl+C to cancel)…\n", wait.Round(time.Second))
+	if !m.pause(wait) {
+		return ErrPromptCancelled
+	}
+	return nil
+}
+
+// pause waits for d unless Abort is called first, and reports whether the
+// wait ran its course
+func (m *Manager) pause(d time.Duration) bool {
+	resp := &response{abort: make(chan struct{})}
+	m.respMu.Lock()
+	m.resp = resp
+	m.respMu.Unlock()
+	defer m.endResponse(resp)
+	return sleep(d, resp.abort)
+}
+
+// sleep waits between retries, or until abort is closed; replaced in tests
+var sleep = func(d time.Duration, abort <-chan struct{}) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-abort:
+		return false
+	}
+}
@@ -0,0 +1,120 @@
+package session
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want time.Duration
+	}{
+		{msg: "rate limit exceeded, retry after 30 seconds", want: 30 * time.Second},
+		{msg: "429 Too Many Requests; Retry-After: 12", want: 12 * time.Second},
+		{msg: "You have been rate limited. Please try again in 1m30s.", want: 90 * time.Second},
+		{msg: "quota resets in 2 minutes", want: 2 * time.Minute},
+		{msg: "retry after 500ms", want: 500 * time.Millisecond},
+		{msg: "rate limited", want: 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.msg); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestSend_WaitsOutRateLimit(t *testing.T) {
+	waits := noSleep(t)
+	mgr := createTestManager(&mockSDKClient{})
+	var usage []ModelUsage
+	mgr.OnRequest(func(u ModelUsage) { usage = append(usage, u) })
+	sess := &flakySession{failures: []error{errors.New("session error: rate limit exceeded, retry after 20 seconds")}}
+	mgr.session = sess
+
+	var err error
+	out := captureOutput(func() { err = mgr.Send("question") })
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if sess.sendCount != 2 || len(*waits) != 1 || (*waits)[0] < 20*time.Second {
+		t.Errorf("%d sends, waits %v; want one retry after the 20s asked for", sess.sendCount, *waits)
+	}
+	if !strings.Contains(out, "Rate limited; retrying (2/3) in 20s") {
+		t.Errorf("output = %q, want the wait shown", out)
+	}
+	if len(usage) != 1 || usage[0].RateLimits != 1 {
+		t.Errorf("usage = %+v, want the rate limit recorded", usage)
+	}
+	if mgr.RateLimitWait() != 0 {
+		t.Errorf("RateLimitWait() = %s after the prompt went through", mgr.RateLimitWait())
+	}
+}
+
+func TestSend_RateLimitExhausted(t *testing.T) {
+	waits := noSleep(t)
+	mgr := createTestManager(&mockSDKClient{})
+	mgr.SetRetryPolicy(RetryPolicy{Attempts: 1})
+	mgr.session = &flakySession{failures: []error{errors.New("session error: 429 Too Many Requests; Retry-After: 60")}}
+
+	err := mgr.Send("question")
+	var limit *RateLimitError
+	if !errors.As(err, &limit) || limit.Wait != time.Minute {
+		t.Fatalf("Send() error = %v, want a RateLimitError asking for a minute", err)
+	}
+	if wait := mgr.RateLimitWait(); wait <= 55*time.Second || wait > time.Minute {
+		t.Errorf("RateLimitWait() = %s, want about a minute", wait)
+	}
+
+	// The next prompt waits for the limit to pass before it is sent
+	out := captureOutput(func() { err = mgr.Send("again") })
+	if err != nil || len(*waits) != 1 || (*waits)[0] <= 55*time.Second {
+		t.Errorf("Send() = %v after waits %v, want it held for the rest of the minute", err, *waits)
+	}
+	if !strings.Contains(out, "Rate limited; sending in") {
+		t.Errorf("output = %q, want the wait announced", out)
+	}
+}
+
+func TestSend_RateLimitWaitCancelled(t *testing.T) {
+	old := sleep
+	sleep = func(time.Duration, <-chan struct{}) bool { return false }
+	t.Cleanup(func() { sleep = old })
+
+	mgr := createTestManager(&mockSDKClient{})
+	sess := &flakySession{}
+	mgr.session = sess
+	mgr.rateLimitedUntil = time.Now().Add(time.Minute)
+	captureOutput(func() {
+		if err := mgr.Send("question"); !errors.Is(err, ErrPromptCancelled) {
+			t.Errorf("Send() error = %v, want the prompt cancelled", err)
+		}
+	})
+	if sess.sendCount != 0 {
+		t.Errorf("sendCount = %d, want nothing sent", sess.sendCount)
+	}
+}
+
+func TestNoteRateLimit(t *testing.T) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	resp := mgr.beginResponse()
+	kind, msg := "rate_limited", "Slow down. Try again in 45s."
+	mgr.handleEvent(copilot.SessionEvent{Type: copilot.SessionError, Data: copilot.Data{ErrorType: &kind, Message: &msg}})
+	mgr.endResponse(resp)
+
+	err := mgr.asRateLimit(resp, errors.New("failed to send message: session error: Slow down."))
+	var limit *RateLimitError
+	if !errors.As(err, &limit) || limit.Wait != 45*time.Second {
+		t.Errorf("asRateLimit() = %#v, want a rate limit of 45s", err)
+	}
+
+	other := mgr.beginResponse()
+	mgr.endResponse(other)
+	if err := mgr.asRateLimit(other, errors.New("model not available")); errors.As(err, &limit) {
+		t.Errorf("asRateLimit() = %v, want other errors unchanged", err)
+	}
+}
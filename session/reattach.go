@@ -0,0 +1,179 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	copilot "github.com/github/copilot-sdk/go"
+
+	"atulm/cocli/client"
+	"atulm/cocli/storage"
+)
+
+// SessionHandle identifies the daemon session a run left alive, so the next
+// run can reattach to it instead of starting over
+type SessionHandle struct {
+	SessionID string `json:"session_id"`
+	// Daemon is the host:port of the daemon that holds the session
+	Daemon string `json:"daemon"`
+	Model  string `json:"model"`
+	// ConversationID is the saved conversation the session belongs to, ""
+	// before its first turn
+	ConversationID string    `json:"conversation_id,omitempty"`
+	Tokens         int64     `json:"tokens,omitempty"`
+	TokenLimit     int64     `json:"token_limit,omitempty"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// resumeSession reattaches to a session kept alive on the server; replaced
+// in tests
+var resumeSession = func(cli *client.Client, id string, cfg *copilot.ResumeSessionConfig) (SessionInterface, error) {
+	sess, err := cli.ResumeSession(id, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if sess == nil {
+		return nil, fmt.Errorf("no session available")
+	}
+	return &copilotSession{sess}, nil
+}
+
+// ID returns the SDK's ID of the session
+func (s *copilotSession) ID() string {
+	return s.SessionID
+}
+
+// SetHandlePath sets the file the current session's handle is kept in, so a
+// later run can Reattach to it; "" (the default) turns reattaching off. Set
+// it only when connected to a daemon, as other servers end with the run.
+func (m *Manager) SetHandlePath(path string) {
+	m.handlePath = path
+}
+
+// Reattach resumes the session a previous run left on the daemon, with its
+// model, context and token counts, and reloads its conversation from the
+// history. It returns nil when there is no session to reattach to. When the
+// session can't be resumed its handle is discarded and the current session
+// is kept.
+func (m *Manager) Reattach() (*SessionHandle, error) {
+	if m.handlePath == "" {
+		return nil, nil
+	}
+	h, err := readHandle(m.handlePath)
+	if err != nil || h == nil {
+		return nil, err
+	}
+	if h.Daemon != m.daemonAddr() {
+		return nil, nil
+	}
+
+	sess, err := resumeSession(m.client, h.SessionID, &copilot.ResumeSessionConfig{
+		Streaming:           true,
+		MCPServers:          m.mcpServers,
+		OnPermissionRequest: m.handlePermission,
+	})
+	if err != nil {
+		os.Remove(m.handlePath)
+		return nil, fmt.Errorf("could not reattach to session %s: %w", h.SessionID, err)
+	}
+
+	// The session this manager started with is no longer needed
+	if s, ok := m.session.(interface{ Destroy() error }); ok {
+		s.Destroy()
+	}
+	m.session = sess
+	m.setupEventHandlers()
+
+	if m.historyDir != "" && h.ConversationID != "" {
+		if saved, err := loadConversation(m.historyDir, h.ConversationID); err == nil {
+			m.adopt(saved)
+			m.turns = append([]Turn(nil), saved.Turns...)
+			m.recorded = append([]Turn(nil), saved.Turns...)
+		}
+	}
+	m.currentModel = h.Model
+	m.currentMultiplier = m.modelMultiplier(h.Model)
+	m.currentTokens, m.tokenLimit = h.Tokens, h.TokenLimit
+	m.warnedAt = 0
+	return h, nil
+}
+
+// Detach stops the client. With a handle path set the current session is
+// left alive on the daemon and its handle saved, for the next run to
+// Reattach to; otherwise it is the same as stopping the client.
+func (m *Manager) Detach() []error {
+	id := m.sessionID()
+	if m.handlePath == "" || id == "" {
+		return m.client.Stop()
+	}
+	if err := m.writeHandle(); err != nil {
+		slog.Warn("session handle not saved", "err", err)
+	}
+	return m.client.Detach(id)
+}
+
+// sessionID returns the SDK's ID of the current session, or "" if unknown
+func (m *Manager) sessionID() string {
+	if s, ok := m.session.(interface{ ID() string }); ok {
+		return s.ID()
+	}
+	return ""
+}
+
+// daemonAddr identifies the daemon the client is connected to
+func (m *Manager) daemonAddr() string {
+	if addr := m.client.RemoteAddr(); addr != "" {
+		return addr
+	}
+	return "localhost:" + strconv.Itoa(m.client.DaemonPort())
+}
+
+// writeHandle saves the current session's handle, if a handle path is set
+func (m *Manager) writeHandle() error {
+	id := m.sessionID()
+	if m.handlePath == "" || id == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(&SessionHandle{
+		SessionID:      id,
+		Daemon:         m.daemonAddr(),
+		Model:          m.currentModel,
+		ConversationID: m.conversationID,
+		Tokens:         m.currentTokens,
+		TokenLimit:     m.tokenLimit,
+		UpdatedAt:      time.Now(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.handlePath), 0700); err != nil {
+		return fmt.Errorf("failed to save session handle: %w", err)
+	}
+	if err := storage.WriteFileAtomic(m.handlePath, data, 0600); err != nil {
+		return fmt.Errorf("failed to save session handle: %w", err)
+	}
+	return nil
+}
+
+// readHandle reads a session handle, returning nil if there is none
+func readHandle(path string) (*SessionHandle, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var h SessionHandle
+	if err := json.Unmarshal(data, &h); err != nil || h.SessionID == "" {
+		os.Remove(path)
+		return nil, fmt.Errorf("session handle %s is corrupt", path)
+	}
+	return &h, nil
+}
@@ -0,0 +1,115 @@
+package session
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	copilot "github.com/github/copilot-sdk/go"
+
+	"atulm/cocli/client"
+)
+
+// idSession is a mock session with an SDK session ID
+type idSession struct {
+	mockSession
+	id string
+}
+
+func (s *idSession) ID() string { return s.id }
+
+func TestReattach(t *testing.T) {
+	dir := t.TempDir()
+	handle := filepath.Join(dir, "daemon-session.json")
+
+	first := createTestManager(&mockSDKClient{})
+	first.session = &idSession{id: "sess-1"}
+	first.SetHistoryDir(dir)
+	first.SetHandlePath(handle)
+	first.currentModel = "gpt-4.1"
+	if err := first.Send("question"); err != nil {
+		t.Fatal(err)
+	}
+
+	var resumed string
+	orig := resumeSession
+	resumeSession = func(_ *client.Client, id string, cfg *copilot.ResumeSessionConfig) (SessionInterface, error) {
+		if !cfg.Streaming || cfg.OnPermissionRequest == nil {
+			t.Errorf("resumed with %+v, want streaming and a permission handler", cfg)
+		}
+		resumed = id
+		return &idSession{id: id}, nil
+	}
+	defer func() { resumeSession = orig }()
+
+	second := createTestManagerWithSession(&mockSDKClient{})
+	second.SetHistoryDir(dir)
+	second.SetHandlePath(handle)
+	h, err := second.Reattach()
+	if err != nil {
+		t.Fatalf("Reattach() error = %v", err)
+	}
+	if h == nil || resumed != "sess-1" || second.sessionID() != "sess-1" {
+		t.Fatalf("Reattach() = %+v, resumed %q; want sess-1", h, resumed)
+	}
+	if second.GetCurrentModel() != "gpt-4.1" || second.ConversationID() != first.ConversationID() {
+		t.Errorf("model %q, conversation %q; want gpt-4.1 and %q", second.GetCurrentModel(), second.ConversationID(), first.ConversationID())
+	}
+	if turns := second.Turns(); len(turns) != 1 || turns[0].Prompt != "question" {
+		t.Errorf("Turns() = %+v, want the previous conversation", turns)
+	}
+}
+
+func TestReattach_NothingToResume(t *testing.T) {
+	dir := t.TempDir()
+	handle := filepath.Join(dir, "daemon-session.json")
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+
+	// No handle path, then no handle yet
+	if h, err := mgr.Reattach(); h != nil || err != nil {
+		t.Errorf("Reattach() without a handle path = %+v, %v", h, err)
+	}
+	mgr.SetHandlePath(handle)
+	if h, err := mgr.Reattach(); h != nil || err != nil {
+		t.Errorf("Reattach() without a handle = %+v, %v", h, err)
+	}
+
+	// A handle from another daemon is left alone
+	os.WriteFile(handle, []byte(`{"session_id": "sess-1", "daemon": "other:4321"}`), 0600)
+	if h, err := mgr.Reattach(); h != nil || err != nil {
+		t.Errorf("Reattach() with another daemon's handle = %+v, %v", h, err)
+	}
+	if _, err := os.Stat(handle); err != nil {
+		t.Errorf("another daemon's handle was removed: %v", err)
+	}
+}
+
+func TestReattach_Failure(t *testing.T) {
+	handle := filepath.Join(t.TempDir(), "daemon-session.json")
+	orig := resumeSession
+	resumeSession = func(*client.Client, string, *copilot.ResumeSessionConfig) (SessionInterface, error) {
+		return nil, errors.New("session not found")
+	}
+	defer func() { resumeSession = orig }()
+
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	mgr.SetHandlePath(handle)
+	current := mgr.session
+
+	os.WriteFile(handle, []byte(`{"session_id": "sess-1", "daemon": "localhost:0"}`), 0600)
+	if _, err := mgr.Reattach(); err == nil {
+		t.Error("Reattach() of a session the daemon lost should fail")
+	}
+	if mgr.session != current {
+		t.Error("Reattach() replaced the session after failing")
+	}
+	if _, err := os.Stat(handle); !os.IsNotExist(err) {
+		t.Error("the stale handle was kept")
+	}
+
+	os.WriteFile(handle, []byte(`not json`), 0600)
+	if _, err := mgr.Reattach(); err == nil {
+		t.Error("Reattach() of a corrupt handle should fail")
+	}
+}
@@ -0,0 +1,57 @@
+package session
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	copilot "github.com/github/copilot-sdk/go"
+
+	"atulm/cocli/models"
+)
+
+// Regenerate sends the latest turn's prompt to model in a fresh session
+// that carries the conversation before that turn, and keeps the answer
+// next to the original one, tagged with its model. The conversation goes on
+// from the original answer.
+func (m *Manager) Regenerate(model copilot.ModelInfo) (Comparison, error) {
+	if len(m.recorded) == 0 {
+		return Comparison{}, fmt.Errorf("no answer to regenerate yet")
+	}
+	last := len(m.recorded) - 1
+	turn := m.recorded[last]
+
+	// The latest turn is also the end of the live conversation unless it
+	// was compacted away
+	prior := m.turns
+	inTurns := len(m.turns) > 0 && sameTurn(m.turns[len(m.turns)-1], turn)
+	if inTurns {
+		prior = m.turns[:len(m.turns)-1]
+	}
+
+	result := m.ask(model.ID, m.systemMessageWith(prior), &Prompt{Text: turn.Prompt})
+	if result.Err != nil {
+		return result, result.Err
+	}
+	m.addUsage(ModelUsage{
+		Model:           model.ID,
+		Messages:        1,
+		InputTokens:     result.InputTokens,
+		OutputTokens:    result.OutputTokens,
+		PremiumRequests: models.Multiplier(model),
+		Streaming:       result.Latency,
+	})
+
+	alt := Alternative{Model: model.ID, Response: result.Reply, At: time.Now()}
+	m.recorded[last].Alternatives = append(slices.Clone(turn.Alternatives), alt)
+	if inTurns {
+		t := &m.turns[len(m.turns)-1]
+		t.Alternatives = append(slices.Clone(t.Alternatives), alt)
+	}
+	return result, nil
+}
+
+// sameTurn reports whether a and b record the same exchange
+func sameTurn(a, b Turn) bool {
+	return a.At.Equal(b.At) && a.Prompt == b.Prompt
+}
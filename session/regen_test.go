@@ -0,0 +1,76 @@
+package session
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	copilot "github.com/github/copilot-sdk/go"
+
+	"atulm/cocli/client"
+)
+
+func TestRegenerate(t *testing.T) {
+	var system string
+	orig := newThrowawaySession
+	newThrowawaySession = func(_ *client.Client, cfg *copilot.SessionConfig) (throwawaySession, error) {
+		system = cfg.SystemMessage.Content
+		return &compareSession{mockSession: mockSession{reply: "answer from " + cfg.Model}}, nil
+	}
+	defer func() { newThrowawaySession = orig }()
+
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	if _, err := mgr.Regenerate(copilot.ModelInfo{ID: "gpt-4.1"}); err == nil {
+		t.Fatal("Regenerate() before any answer should fail")
+	}
+
+	mgr.currentModel = "claude-sonnet-4.5"
+	mgr.session.(*mockSession).reply = "first"
+	for _, prompt := range []string{"q1", "q2"} {
+		if err := mgr.Send(prompt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := mgr.Regenerate(copilot.ModelInfo{ID: "gpt-4.1", Billing: &copilot.ModelBilling{Multiplier: 1}})
+	if err != nil {
+		t.Fatalf("Regenerate() error = %v", err)
+	}
+	if result.Reply != "answer from gpt-4.1" || result.Model != "gpt-4.1" {
+		t.Errorf("Regenerate() = %+v", result)
+	}
+	// The fresh session carries the conversation before the regenerated turn
+	if !strings.Contains(system, "User: q1") || strings.Contains(system, "User: q2") {
+		t.Errorf("system message = %q, want only the earlier turn", system)
+	}
+
+	for name, turns := range map[string][]Turn{"Turns": mgr.Turns(), "Transcript": mgr.Transcript()} {
+		last := turns[len(turns)-1]
+		if last.Response != "first" || last.Model != "claude-sonnet-4.5" {
+			t.Errorf("%s: original answer changed: %+v", name, last)
+		}
+		if len(last.Alternatives) != 1 || last.Alternatives[0].Model != "gpt-4.1" || last.Alternatives[0].Response != "answer from gpt-4.1" {
+			t.Errorf("%s: alternatives = %+v", name, last.Alternatives)
+		}
+	}
+	// Alternatives stay out of the conversation the model sees
+	if strings.Contains(transcript(mgr.Turns()), "answer from") {
+		t.Error("transcript carries the regenerated answer")
+	}
+	if usage := mgr.Usage(); len(usage) != 2 || usage[1].Model != "gpt-4.1" || usage[1].PremiumRequests != 1 {
+		t.Errorf("usage = %+v", usage)
+	}
+}
+
+func TestMarkdownTranscript_Alternatives(t *testing.T) {
+	at := time.Date(2025, 3, 1, 9, 30, 0, 0, time.UTC)
+	turns := []Turn{{
+		Prompt: "Hi", Response: "Hello.", At: at, Model: "claude-sonnet-4.5",
+		Alternatives: []Alternative{{Model: "gpt-4.1", Response: "Hey!", At: at}},
+	}}
+	got := markdownTranscript("", turns, at)
+	want := "### Assistant · claude-sonnet-4.5\n\nHello.\n\n### Assistant · gpt-4.1 (regenerated)\n\nHey!\n"
+	if !strings.HasSuffix(got, want) {
+		t.Errorf("markdownTranscript() =\n%s\nwant it to end with\n%s", got, want)
+	}
+}
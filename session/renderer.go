@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"sync/atomic"
+
+	"atulm/cocli/config"
 
 	"github.com/charmbracelet/glamour"
 )
@@ -16,6 +19,26 @@ type StreamingMarkdownRenderer struct {
 	inCodeBlock     bool
 	codeFenceMarker string
 	writer          io.Writer
+	// style and wordWrap configure the glamour renderer when none is given
+	style    string
+	wordWrap int
+	// codeStyle is the chroma style for code blocks; "" keeps the style's own
+	codeStyle string
+	// ownGlamour is set when the glamour renderer was built from style and
+	// wordWrap, so it can be rebuilt for a new width
+	ownGlamour bool
+	// pendingWrap is a width set by SetWordWrap, applied before the next render
+	pendingWrap atomic.Int64
+	// copySafe prints code blocks as plain, unwrapped text
+	copySafe bool
+	// plain writes the markdown as received, without glamour or colours
+	plain    bool
+	callouts map[string]CalloutStyle
+	// live previews unfinished elements as raw text; preview is the text
+	// currently shown, and liveSize reports the terminal's size
+	live     bool
+	preview  string
+	liveSize func() (int, int)
 }
 
 // RendererOption is a functional option for configuring the renderer
@@ -28,6 +51,35 @@ func WithWriter(w io.Writer) RendererOption {
 	}
 }
 
+// WithStyle sets the glamour style name (e.g. "dark", "light", "notty", or
+// "auto" to match the terminal's background) or the path of a JSON style
+// file, which may start with ~/; "" keeps the default dark style
+func WithStyle(style string) RendererOption {
+	return func(r *StreamingMarkdownRenderer) {
+		if style != "" {
+			r.style = config.ExpandHome(style)
+		}
+	}
+}
+
+// WithWordWrap sets the column output is wrapped at; 0 keeps the default
+func WithWordWrap(n int) RendererOption {
+	return func(r *StreamingMarkdownRenderer) {
+		if n > 0 {
+			r.wordWrap = n
+		}
+	}
+}
+
+// WithPlainText writes replies as the raw markdown text, without glamour,
+// colours or wrapping. Output is still held back until code blocks, lists and
+// tables are complete.
+func WithPlainText() RendererOption {
+	return func(r *StreamingMarkdownRenderer) {
+		r.plain = true
+	}
+}
+
 // WithGlamourRenderer sets a custom glamour renderer (useful for testing)
 func WithGlamourRenderer(gr *glamour.TermRenderer) RendererOption {
 	return func(r *StreamingMarkdownRenderer) {
@@ -35,28 +87,75 @@ func WithGlamourRenderer(gr *glamour.TermRenderer) RendererOption {
 	}
 }
 
-// NewStreamingMarkdownRenderer creates a new renderer with dark theme and syntax highlighting
+// NewStreamingMarkdownRenderer creates a new renderer with syntax
+// highlighting, using the dark theme wrapped at 80 columns unless options
+// say otherwise
 func NewStreamingMarkdownRenderer(opts ...RendererOption) (*StreamingMarkdownRenderer, error) {
-	gr, err := glamour.NewTermRenderer(
-		glamour.WithStylePath("dark"),
-		glamour.WithWordWrap(80),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create glamour renderer: %w", err)
-	}
-
 	r := &StreamingMarkdownRenderer{
-		glamourRenderer: gr,
-		writer:          nil, // nil means use fmt.Print (stdout)
+		writer:   nil, // nil means use fmt.Print (stdout)
+		style:    "dark",
+		wordWrap: 80,
+		callouts: DefaultCalloutStyles(),
 	}
 
 	for _, opt := range opts {
 		opt(r)
 	}
 
+	if r.glamourRenderer == nil && !r.plain {
+		gr, err := r.newGlamour(r.wordWrap)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create glamour renderer: %w", err)
+		}
+		r.glamourRenderer = gr
+		r.ownGlamour = true
+	}
+
 	return r, nil
 }
 
+// newGlamour builds a glamour renderer for the configured style and width
+func (r *StreamingMarkdownRenderer) newGlamour(wordWrap int) (*glamour.TermRenderer, error) {
+	style, err := r.glamourStyle()
+	if err != nil {
+		return nil, err
+	}
+	return glamour.NewTermRenderer(
+		style,
+		glamour.WithWordWrap(wordWrap),
+	)
+}
+
+// SetWordWrap changes the wrap column, e.g. after the terminal is resized.
+// It is safe to call while a reply is streaming; the new width applies from
+// the next rendered block. It has no effect with WithGlamourRenderer.
+func (r *StreamingMarkdownRenderer) SetWordWrap(n int) {
+	if n > 0 {
+		r.pendingWrap.Store(int64(n))
+	}
+}
+
+// WordWrap returns the column output is wrapped at
+func (r *StreamingMarkdownRenderer) WordWrap() int {
+	if n := r.pendingWrap.Load(); n > 0 {
+		return int(n)
+	}
+	return r.wordWrap
+}
+
+// applyWordWrap rebuilds the glamour renderer if SetWordWrap changed the
+// width, keeping the old one if that fails
+func (r *StreamingMarkdownRenderer) applyWordWrap() {
+	n := int(r.pendingWrap.Swap(0))
+	if n == 0 || n == r.wordWrap || !r.ownGlamour {
+		return
+	}
+	if gr, err := r.newGlamour(n); err == nil {
+		r.glamourRenderer = gr
+		r.wordWrap = n
+	}
+}
+
 // ProcessDelta processes an incoming delta chunk from the streaming response.
 // It buffers content and renders complete markdown elements as they are detected.
 func (r *StreamingMarkdownRenderer) ProcessDelta(delta string) {
@@ -64,6 +163,9 @@ func (r *StreamingMarkdownRenderer) ProcessDelta(delta string) {
 
 	// Process the buffer to find and render complete elements
 	r.processBuffer()
+	if r.live && !r.plain {
+		r.showPreview()
+	}
 }
 
 // processBuffer analyzes the buffer and renders complete markdown elements
@@ -141,6 +243,19 @@ func (r *StreamingMarkdownRenderer) findRenderPoint(content string) int {
 		return 0
 	}
 
+	point := r.findBoundary(content)
+
+	// Hold back a list or table that is still streaming so it is rendered
+	// whole
+	if start := pendingBlockStart(content); start >= 0 && start < point {
+		return start
+	}
+	return point
+}
+
+// findBoundary finds the last paragraph or line break in content
+func (r *StreamingMarkdownRenderer) findBoundary(content string) int {
+
 	// Look for double newline (paragraph break) - render everything before it
 	if idx := strings.LastIndex(content, "\n\n"); idx != -1 {
 		return idx + 2 // Include the double newline
@@ -163,23 +278,67 @@ func (r *StreamingMarkdownRenderer) findRenderPoint(content string) int {
 	return 0
 }
 
-// renderContent renders the given markdown content using glamour
+// renderContent renders the given markdown content using glamour. Diff code
+// blocks are coloured line by line instead, in copy-safe mode every code
+// block is printed as plain text, and callouts get their own styles. In
+// plain text mode the content is written unchanged.
 func (r *StreamingMarkdownRenderer) renderContent(content string) {
 	if content == "" {
 		return
 	}
-
-	rendered, err := r.glamourRenderer.Render(content)
-	if err != nil {
-		// Fallback to plain text if rendering fails
+	if r.plain {
 		r.output(content)
 		return
 	}
+	r.clearPreview()
+
+	var rendered, pending strings.Builder
+	flush := func() {
+		if pending.Len() > 0 {
+			rendered.WriteString(r.renderProse(pending.String()))
+			pending.Reset()
+		}
+	}
+	for _, seg := range splitCodeBlocks(content) {
+		switch {
+		case seg.code && r.copySafe:
+			flush()
+			rendered.WriteString(renderPlainCode(seg.body))
+		case seg.code && diffLanguages[seg.lang]:
+			flush()
+			rendered.WriteString(renderDiff(seg.body))
+		default:
+			pending.WriteString(seg.text)
+		}
+	}
+	flush()
 
 	// Glamour adds extra newlines, trim trailing ones to avoid double spacing
-	rendered = strings.TrimSuffix(rendered, "\n")
+	r.output(strings.TrimSuffix(rendered.String(), "\n"))
+}
+
+// renderProse renders markdown that contains no code blocks
+func (r *StreamingMarkdownRenderer) renderProse(content string) string {
+	var rendered strings.Builder
+	for _, seg := range splitCallouts(content) {
+		if seg.callout != nil {
+			rendered.WriteString(r.renderCallout(seg.callout))
+		} else {
+			rendered.WriteString(r.renderMarkdown(seg.text))
+		}
+	}
+	return rendered.String()
+}
 
-	r.output(rendered)
+// renderMarkdown renders markdown with glamour, falling back to the plain
+// text if rendering fails
+func (r *StreamingMarkdownRenderer) renderMarkdown(content string) string {
+	r.applyWordWrap()
+	rendered, err := r.glamourRenderer.Render(content)
+	if err != nil {
+		return content
+	}
+	return rendered
 }
 
 // output writes content to the configured writer or stdout
@@ -196,6 +355,7 @@ func (r *StreamingMarkdownRenderer) output(content string) {
 func (r *StreamingMarkdownRenderer) Flush() {
 	content := r.buffer.String()
 	if content == "" {
+		r.clearPreview()
 		return
 	}
 
@@ -208,6 +368,7 @@ func (r *StreamingMarkdownRenderer) Flush() {
 // Reset clears the buffer and resets state for a new message.
 func (r *StreamingMarkdownRenderer) Reset() {
 	r.buffer.Reset()
+	r.preview = ""
 	r.inCodeBlock = false
 	r.codeFenceMarker = ""
 }
@@ -221,3 +382,15 @@ func (r *StreamingMarkdownRenderer) GetBufferContent() string {
 func (r *StreamingMarkdownRenderer) IsInCodeBlock() bool {
 	return r.inCodeBlock
 }
+
+// SetCopySafe turns copy-safe code blocks on or off. When on, code blocks are
+// printed without wrapping, colours, indentation or padding so they can be
+// copied from the terminal as-is.
+func (r *StreamingMarkdownRenderer) SetCopySafe(on bool) {
+	r.copySafe = on
+}
+
+// CopySafe reports whether copy-safe code blocks are on
+func (r *StreamingMarkdownRenderer) CopySafe() bool {
+	return r.copySafe
+}
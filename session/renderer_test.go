@@ -2,6 +2,8 @@ package session
 
 import (
 	"bytes"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"testing"
@@ -66,6 +68,106 @@ func TestNewStreamingMarkdownRendererWithWriter(t *testing.T) {
 	}
 }
 
+func TestNewStreamingMarkdownRendererWithStyle(t *testing.T) {
+	r, err := NewStreamingMarkdownRenderer(WithStyle("light"), WithWordWrap(40))
+	if err != nil {
+		t.Fatalf("NewStreamingMarkdownRenderer() error = %v", err)
+	}
+	if r.style != "light" || r.wordWrap != 40 {
+		t.Errorf("style = %q, wordWrap = %d; want light and 40", r.style, r.wordWrap)
+	}
+
+	r, _ = NewStreamingMarkdownRenderer(WithStyle(""), WithWordWrap(0))
+	if r.style != "dark" || r.wordWrap != 80 {
+		t.Errorf("style = %q, wordWrap = %d; want the defaults", r.style, r.wordWrap)
+	}
+
+	if _, err := NewStreamingMarkdownRenderer(WithStyle("no-such-style")); err == nil {
+		t.Error("an unknown style should fail")
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	buf := &bytes.Buffer{}
+	r, err := NewStreamingMarkdownRenderer(WithWriter(buf), WithPlainText())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.glamourRenderer != nil {
+		t.Error("plain text mode should not create a glamour renderer")
+	}
+
+	reply := "# Title\n\nSome **bold** text.\n\n```go\nfunc main() {}\n```\n\n> [!NOTE]\n> Heads up.\n"
+	streamDeltas(r, []string{"# Title\n\nSome **bold** text.\n\n```go\nfunc main"})
+	if strings.Contains(buf.String(), "func") {
+		t.Errorf("output = %q, want the open code block held back", buf.String())
+	}
+	streamDeltas(r, []string{"() {}\n```\n\n> [!NOTE]\n> Heads up.\n"})
+	r.Flush()
+
+	if buf.String() != reply {
+		t.Errorf("output = %q, want the markdown unchanged %q", buf.String(), reply)
+	}
+}
+
+func TestSetWordWrap(t *testing.T) {
+	buf := &bytes.Buffer{}
+	r, err := NewStreamingMarkdownRenderer(WithWriter(buf), WithStyle("notty"), WithWordWrap(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	widest := func() int {
+		n := 0
+		for _, line := range strings.Split(stripANSI(buf.String()), "\n") {
+			n = max(n, len(strings.TrimRight(line, " ")))
+		}
+		return n
+	}
+	para := strings.Repeat("word ", 30) + "\n\n"
+
+	r.ProcessDelta(para)
+	r.Flush()
+	if w := widest(); w <= 40 {
+		t.Fatalf("widest line = %d at wrap 100, want longer than 40", w)
+	}
+
+	buf.Reset()
+	r.SetWordWrap(40)
+	if r.WordWrap() != 40 {
+		t.Errorf("WordWrap() = %d, want 40", r.WordWrap())
+	}
+	r.ProcessDelta(para)
+	r.Flush()
+	if w := widest(); w > 40 {
+		t.Errorf("widest line = %d after SetWordWrap(40), want at most 40", w)
+	}
+}
+
+func TestNewStreamingMarkdownRendererCustomStyle(t *testing.T) {
+	if _, err := NewStreamingMarkdownRenderer(WithStyle("auto")); err != nil {
+		t.Errorf("auto style error = %v", err)
+	}
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.WriteFile(filepath.Join(home, "style.json"), []byte(`{"document": {"color": "205"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	r, err := NewStreamingMarkdownRenderer(WithWriter(buf), WithStyle("~/style.json"))
+	if err != nil {
+		t.Fatalf("custom style error = %v", err)
+	}
+	if r.style != filepath.Join(home, "style.json") {
+		t.Errorf("style = %q, want ~ expanded", r.style)
+	}
+	r.ProcessDelta("hello\n\n")
+	r.Flush()
+	if !containsText(buf.String(), "hello") {
+		t.Errorf("output = %q, want the text rendered", buf.String())
+	}
+}
+
 // =============================================================================
 // Test: Basic Markdown Elements
 // =============================================================================
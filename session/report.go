@@ -0,0 +1,85 @@
+package session
+
+import (
+	"errors"
+	"time"
+)
+
+// Turn statuses reported in TurnReport.Status
+const (
+	TurnOK        = "ok"
+	TurnCancelled = "cancelled"
+	TurnStalled   = "stalled"
+	TurnError     = "error"
+)
+
+// TurnReport summarizes a finished Send for completion hooks
+type TurnReport struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	Model  string `json:"model"`
+	// Turn is the turn's number in the conversation (0 if it never completed)
+	Turn int `json:"turn"`
+	// SessionStartedAt is when the conversation's first turn was sent
+	SessionStartedAt *time.Time `json:"session_started_at,omitempty"`
+	StartedAt        time.Time  `json:"started_at"`
+	DurationSeconds  float64    `json:"duration_seconds"`
+	InputTokens      int64      `json:"input_tokens"`
+	OutputTokens     int64      `json:"output_tokens"`
+	ContextTokens    int64      `json:"context_tokens"`
+	TokenLimit       int64      `json:"token_limit,omitempty"`
+}
+
+// Duration returns how long the turn took
+func (r TurnReport) Duration() time.Duration {
+	return time.Duration(r.DurationSeconds * float64(time.Second))
+}
+
+// OnTurnComplete registers a function called after every Send, whether it
+// succeeded, was cancelled or failed. Hooks run in the order they were added.
+func (m *Manager) OnTurnComplete(fn func(TurnReport)) {
+	m.turnHooks = append(m.turnHooks, fn)
+}
+
+// reportTurn runs the completion hooks for a Send that began at start
+func (m *Manager) reportTurn(start time.Time, err error) {
+	if len(m.turnHooks) == 0 {
+		return
+	}
+
+	report := TurnReport{
+		Status:          turnStatus(err),
+		Model:           m.currentModel,
+		StartedAt:       start,
+		DurationSeconds: time.Since(start).Seconds(),
+		InputTokens:     m.turnInputTokens,
+		OutputTokens:    m.turnOutputTokens,
+		ContextTokens:   m.currentTokens,
+		TokenLimit:      m.tokenLimit,
+	}
+	if !m.startedAt.IsZero() {
+		started := m.startedAt
+		report.SessionStartedAt = &started
+	}
+	if err == nil {
+		report.Turn = m.turnCount
+	} else {
+		report.Error = err.Error()
+	}
+	for _, fn := range m.turnHooks {
+		fn(report)
+	}
+}
+
+// turnStatus classifies Send's result
+func turnStatus(err error) string {
+	switch {
+	case err == nil:
+		return TurnOK
+	case errors.Is(err, ErrPromptCancelled), errors.Is(err, ErrResponseCancelled):
+		return TurnCancelled
+	case errors.Is(err, ErrResponseStalled):
+		return TurnStalled
+	}
+	return TurnError
+}
@@ -0,0 +1,48 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestOnTurnComplete(t *testing.T) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	var reports []TurnReport
+	mgr.OnTurnComplete(func(r TurnReport) { reports = append(reports, r) })
+
+	if err := mgr.Send("hello"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	mgr.AddPromptStage(func(p *Prompt) error { return ErrPromptCancelled })
+	mgr.Send("again")
+
+	if len(reports) != 2 {
+		t.Fatalf("got %d reports, want 2", len(reports))
+	}
+	ok := reports[0]
+	if ok.Status != TurnOK || ok.Turn != 1 || ok.Model != mgr.GetCurrentModel() || ok.SessionStartedAt == nil || ok.Error != "" {
+		t.Errorf("first report = %+v, want turn 1 ok", ok)
+	}
+	if r := reports[1]; r.Status != TurnCancelled || r.Turn != 0 || r.Error == "" {
+		t.Errorf("second report = %+v, want cancelled", r)
+	}
+}
+
+func TestTurnStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{err: nil, want: TurnOK},
+		{err: ErrPromptCancelled, want: TurnCancelled},
+		{err: ErrResponseCancelled, want: TurnCancelled},
+		{err: ErrResponseStalled, want: TurnStalled},
+		{err: fmt.Errorf("failed to send message: %w", errors.New("eof")), want: TurnError},
+	}
+	for _, tt := range tests {
+		if got := turnStatus(tt.err); got != tt.want {
+			t.Errorf("turnStatus(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// RetentionPolicy bounds the conversations kept in the history directory.
+// Conversations are kept most recently updated first until one of the
+// limits is reached; the rest are removed.
+type RetentionPolicy struct {
+	// MaxSessions is how many conversations are kept; 0 means no limit
+	MaxSessions int
+	// MaxAge removes conversations not updated for this long; 0 means no
+	// limit
+	MaxAge time.Duration
+	// MaxBytes caps the size of the conversations kept; 0 means no limit
+	MaxBytes int64
+}
+
+// Limited reports whether the policy sets any limit
+func (p RetentionPolicy) Limited() bool {
+	return p.MaxSessions > 0 || p.MaxAge > 0 || p.MaxBytes > 0
+}
+
+// PruneResult describes what PruneHistory removed
+type PruneResult struct {
+	Removed []ConversationInfo
+	// Kept is how many conversations are left
+	Kept int
+	// Freed is the size of the removed files in bytes
+	Freed int64
+}
+
+// PruneHistory removes the conversations in dir that policy doesn't keep.
+// Conversations in keep, and those a running cocli is in, are never removed,
+// though they count toward the limits.
+func PruneHistory(dir string, policy RetentionPolicy, keep ...string) (*PruneResult, error) {
+	result := &PruneResult{}
+	if dir == "" || !policy.Limited() {
+		return result, nil
+	}
+	conversations, err := savedConversations(dir)
+	if err != nil {
+		return nil, err
+	}
+	inUse := runningConversations(dir, "")
+	for _, id := range keep {
+		inUse[id] = true
+	}
+
+	now := time.Now()
+	kept, size := 0, int64(0)
+	for _, c := range conversations {
+		expired := (policy.MaxSessions > 0 && kept >= policy.MaxSessions) ||
+			(policy.MaxAge > 0 && now.Sub(c.UpdatedAt) > policy.MaxAge) ||
+			(policy.MaxBytes > 0 && size+c.Size > policy.MaxBytes)
+		if !expired || inUse[c.ID] {
+			kept++
+			size += c.Size
+			continue
+		}
+		if err := os.Remove(conversationPath(dir, c.ID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return result, fmt.Errorf("failed to remove conversation %s: %w", c.ID, err)
+		}
+		result.Removed = append(result.Removed, c)
+		result.Freed += c.Size
+	}
+	result.Kept = kept
+	return result, nil
+}
+
+// PruneHistory applies policy to the history directory, keeping the
+// conversations in keep and those open in this run
+func (m *Manager) PruneHistory(policy RetentionPolicy, keep ...string) (*PruneResult, error) {
+	return PruneHistory(m.historyDir, policy, append(keep, m.openConversations()...)...)
+}
+
+// DeleteConversation removes a saved conversation. The current
+// conversation, those open in this run and those another cocli is in can't
+// be deleted.
+func (m *Manager) DeleteConversation(id string) error {
+	if m.historyDir == "" {
+		return fmt.Errorf("conversation history is not enabled")
+	}
+	if _, err := loadConversation(m.historyDir, id); err != nil {
+		return err
+	}
+	for _, open := range m.openConversations() {
+		if open == id {
+			return fmt.Errorf("conversation %s is open in this run", id)
+		}
+	}
+	own := ""
+	if m.run != nil {
+		own = m.run.path
+	}
+	if runningConversations(m.historyDir, own)[id] {
+		return fmt.Errorf("conversation %s is in use by another cocli", id)
+	}
+	if err := os.Remove(conversationPath(m.historyDir, id)); err != nil {
+		return fmt.Errorf("failed to delete conversation: %w", err)
+	}
+	return nil
+}
+
+// openConversations returns the IDs of the current conversation and those
+// set aside in this run
+func (m *Manager) openConversations() []string {
+	var ids []string
+	if m.conversationID != "" {
+		ids = append(ids, m.conversationID)
+	}
+	for _, b := range m.openSessions {
+		if b.conversationID != "" {
+			ids = append(ids, b.conversationID)
+		}
+	}
+	for _, b := range m.branches {
+		if b.conversationID != "" {
+			ids = append(ids, b.conversationID)
+		}
+	}
+	return ids
+}
+
+// runningConversations returns the conversations recorded by the run
+// markers in dir, apart from the marker at skip: those of running cocli
+// processes, and of runs that crashed and haven't been recovered yet
+func runningConversations(dir, skip string) map[string]bool {
+	ids := make(map[string]bool)
+	entries, err := os.ReadDir(filepath.Join(dir, runsDir))
+	if err != nil {
+		return ids
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(dir, runsDir, e.Name())
+		if path == skip {
+			continue
+		}
+		var marker runMarker
+		data, err := os.ReadFile(path)
+		if err == nil && json.Unmarshal(data, &marker) == nil && marker.ConversationID != "" {
+			ids[marker.ConversationID] = true
+		}
+	}
+	return ids
+}
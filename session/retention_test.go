@@ -0,0 +1,136 @@
+package session
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeHistory saves a conversation per ID in dir, the first updated now and
+// each later one a day before the one before it
+func writeHistory(t *testing.T, dir string, ids ...string) {
+	t.Helper()
+	now := time.Now()
+	for i, id := range ids {
+		data, err := json.Marshal(&SavedConversation{
+			ID:        id,
+			Model:     "gpt-4.1",
+			UpdatedAt: now.Add(-time.Duration(i) * 24 * time.Hour),
+			Turns:     []Turn{{Prompt: strings.Repeat("x", 1000)}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(conversationPath(dir, id), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// historyIDs lists the conversations left in dir, newest first
+func historyIDs(t *testing.T, dir string) []string {
+	t.Helper()
+	infos, err := savedConversations(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, info := range infos {
+		ids = append(ids, info.ID)
+	}
+	return ids
+}
+
+func TestPruneHistory(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy RetentionPolicy
+		// files sets MaxBytes to this many conversations' size
+		files float64
+		keep  []string
+		want  []string
+	}{
+		{name: "no limits", want: []string{"a", "b", "c", "d"}},
+		{name: "max sessions", policy: RetentionPolicy{MaxSessions: 2}, want: []string{"a", "b"}},
+		{name: "max age", policy: RetentionPolicy{MaxAge: 36 * time.Hour}, want: []string{"a", "b"}},
+		{name: "max size", files: 3.5, want: []string{"a", "b", "c"}},
+		{name: "strictest limit wins", policy: RetentionPolicy{MaxSessions: 3, MaxAge: 12 * time.Hour}, want: []string{"a"}},
+		{name: "kept conversations count toward the limit", policy: RetentionPolicy{MaxSessions: 2}, keep: []string{"d"}, want: []string{"a", "b", "d"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeHistory(t, dir, "a", "b", "c", "d")
+			if tt.files > 0 {
+				info, err := os.Stat(conversationPath(dir, "a"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				tt.policy.MaxBytes = int64(tt.files * float64(info.Size()))
+			}
+
+			result, err := PruneHistory(dir, tt.policy, tt.keep...)
+			if err != nil {
+				t.Fatalf("PruneHistory() error = %v", err)
+			}
+			if got := historyIDs(t, dir); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("left %v, want %v", got, tt.want)
+			}
+			if !tt.policy.Limited() {
+				return
+			}
+			if result.Kept != len(tt.want) || len(result.Removed) != 4-len(tt.want) {
+				t.Errorf("result = %d kept, %d removed; want %d kept", result.Kept, len(result.Removed), len(tt.want))
+			}
+			if len(result.Removed) > 0 && result.Freed <= 0 {
+				t.Errorf("Freed = %d, want the removed files' size", result.Freed)
+			}
+		})
+	}
+}
+
+func TestPruneHistory_KeepsRunningConversations(t *testing.T) {
+	dir := t.TempDir()
+	writeHistory(t, dir, "a", "b", "c")
+	if err := os.MkdirAll(filepath.Join(dir, runsDir), 0700); err != nil {
+		t.Fatal(err)
+	}
+	marker, _ := json.Marshal(&runMarker{ConversationID: "c"})
+	if err := os.WriteFile(filepath.Join(dir, runsDir, "1-1.json"), marker, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := PruneHistory(dir, RetentionPolicy{MaxSessions: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if got := historyIDs(t, dir); !reflect.DeepEqual(got, []string{"a", "c"}) {
+		t.Errorf("left %v, want the newest and the running one", got)
+	}
+}
+
+func TestDeleteConversation(t *testing.T) {
+	dir := t.TempDir()
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	mgr.SetHistoryDir(dir)
+	if err := mgr.Send("hello"); err != nil {
+		t.Fatal(err)
+	}
+	writeHistory(t, dir, "old")
+
+	if err := mgr.DeleteConversation(mgr.ConversationID()); err == nil || !strings.Contains(err.Error(), "open in this run") {
+		t.Errorf("DeleteConversation(current) error = %v, want it refused", err)
+	}
+	if err := mgr.DeleteConversation("missing"); err == nil {
+		t.Error("DeleteConversation(missing) succeeded")
+	}
+	if err := mgr.DeleteConversation("old"); err != nil {
+		t.Fatalf("DeleteConversation() error = %v", err)
+	}
+	if got := historyIDs(t, dir); !reflect.DeepEqual(got, []string{mgr.ConversationID()}) {
+		t.Errorf("left %v, want only the current conversation", got)
+	}
+}
@@ -0,0 +1,29 @@
+package session
+
+import "fmt"
+
+// LastPrompt returns the prompt most recently passed to Send, as it was
+// typed, or "" before the first
+func (m *Manager) LastPrompt() string {
+	return m.lastPrompt
+}
+
+// PrepareRetry readies the last prompt to be sent again and returns it. If
+// its reply is still the latest turn of the conversation, the conversation
+// is forked before that turn so the new reply takes its place; the old
+// conversation is saved as a branch whose index is returned. Otherwise (the
+// prompt failed, or /model started a new conversation since) nothing
+// changes and the branch is 0.
+func (m *Manager) PrepareRetry() (prompt string, branch int, err error) {
+	if m.lastPrompt == "" {
+		return "", 0, fmt.Errorf("no prompt to retry yet")
+	}
+	if m.lastPromptTurn == 0 || m.lastPromptTurn != m.turnCount || len(m.turns) == 0 {
+		return m.lastPrompt, 0, nil
+	}
+	branch, err = m.ForkAt(len(m.turns))
+	if err != nil {
+		return "", 0, err
+	}
+	return m.lastPrompt, branch, nil
+}
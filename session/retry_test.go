@@ -0,0 +1,76 @@
+package session
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPrepareRetry(t *testing.T) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	if _, _, err := mgr.PrepareRetry(); err == nil {
+		t.Error("PrepareRetry() before any prompt should fail")
+	}
+
+	sess := mgr.session.(*mockSession)
+	sess.reply = "first answer"
+	if err := mgr.Send("one"); err != nil {
+		t.Fatal(err)
+	}
+	sess.reply = "bad answer"
+	if err := mgr.Send("two"); err != nil {
+		t.Fatal(err)
+	}
+
+	prompt, branch, err := mgr.PrepareRetry()
+	if err != nil {
+		t.Fatalf("PrepareRetry() error = %v", err)
+	}
+	if prompt != "two" || branch != 1 {
+		t.Errorf("PrepareRetry() = %q, branch %d; want two and branch 1", prompt, branch)
+	}
+	if turns := mgr.Turns(); len(turns) != 1 || turns[0].Prompt != "one" {
+		t.Errorf("Turns() = %+v, want the bad answer's turn dropped", turns)
+	}
+	if b := mgr.Branches(); len(b) != 1 || b[0].LastPrompt != "two" {
+		t.Errorf("Branches() = %+v, want the old answer kept", b)
+	}
+}
+
+func TestPrepareRetry_NoFork(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(mgr *Manager)
+	}{
+		{
+			name: "after a model switch",
+			setup: func(mgr *Manager) {
+				mgr.SetModel("gpt-5", 1)
+			},
+		},
+		{
+			name: "after a failed send",
+			setup: func(mgr *Manager) {
+				mgr.session.(*mockSession).err = errors.New("boom")
+				mgr.Send("q")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr := createTestManagerWithSession(&mockSDKClient{})
+			mgr.session.(*mockSession).reply = "answer"
+			if err := mgr.Send("q"); err != nil {
+				t.Fatal(err)
+			}
+			tt.setup(mgr)
+
+			prompt, branch, err := mgr.PrepareRetry()
+			if err != nil || prompt != "q" || branch != 0 {
+				t.Errorf("PrepareRetry() = %q, %d, %v; want q without a fork", prompt, branch, err)
+			}
+			if len(mgr.Branches()) != 0 {
+				t.Error("PrepareRetry() saved a branch")
+			}
+		})
+	}
+}
@@ -0,0 +1,306 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"atulm/cocli/models"
+	"atulm/cocli/storage"
+)
+
+// conversationIDFormat names saved conversations after their first turn
+const conversationIDFormat = "20060102-150405"
+
+// conversationIDRegex matches IDs that are safe to use as file names
+var conversationIDRegex = regexp.MustCompile(`^[0-9A-Za-z_-]+$`)
+
+// SavedConversation is a conversation as written to the history directory
+// after every turn
+type SavedConversation struct {
+	ID string `json:"id"`
+	// Name is set for named sessions (/session new)
+	Name string `json:"name,omitempty"`
+	// Title is generated after the first turn or set with /title
+	Title string `json:"title,omitempty"`
+	// Parent is the session this one was forked from (/fork)
+	Parent       string    `json:"parent,omitempty"`
+	Model        string    `json:"model"`
+	SystemPrompt string    `json:"system_prompt,omitempty"`
+	StartedAt    time.Time `json:"started_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	// Summary is the compacted part of the conversation, if any
+	Summary string `json:"summary,omitempty"`
+	// TurnCount includes turns that were compacted into Summary
+	TurnCount int    `json:"turn_count"`
+	Turns     []Turn `json:"turns"`
+	// Tokens and TokenLimit are the context window usage when last saved
+	Tokens     int64 `json:"tokens,omitempty"`
+	TokenLimit int64 `json:"token_limit,omitempty"`
+	// Pending is a prompt whose reply didn't finish, with the part of the
+	// reply that arrived: the reply was cancelled, or cocli exited while
+	// waiting for it
+	Pending *Turn `json:"pending,omitempty"`
+}
+
+// ConversationInfo describes a saved conversation for display
+type ConversationInfo struct {
+	ID          string
+	Name        string
+	Title       string
+	Parent      string
+	Model       string
+	UpdatedAt   time.Time
+	TurnCount   int
+	FirstPrompt string
+	Tokens      int64
+	TokenLimit  int64
+	// Size is the saved file's size in bytes
+	Size int64
+}
+
+// SetHistoryDir sets the directory conversations are saved to as they
+// progress; "" turns saving off
+func (m *Manager) SetHistoryDir(dir string) {
+	m.historyDir = dir
+}
+
+// ConversationID returns the ID the current conversation is saved under,
+// or "" before its first turn
+func (m *Manager) ConversationID() string {
+	return m.conversationID
+}
+
+// saveConversation writes the current conversation to the history
+// directory, assigning it an ID on its first save
+func (m *Manager) saveConversation() error {
+	return m.writeConversation(nil)
+}
+
+// savePending saves the conversation with a prompt whose reply hasn't
+// finished, so it isn't lost if cocli exits before the reply does
+func (m *Manager) savePending(pending Turn) {
+	if m.startedAt.IsZero() {
+		m.startedAt = pending.At
+	}
+	if err := m.writeConversation(&pending); err != nil {
+		slog.Warn("pending prompt not saved", "err", err)
+	}
+}
+
+// writeConversation saves the conversation with pending as its unfinished
+// prompt, if any
+func (m *Manager) writeConversation(pending *Turn) error {
+	if m.historyDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(m.historyDir, 0700); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	if m.conversationID == "" {
+		m.conversationID = m.newConversationID()
+	}
+
+	data, err := json.MarshalIndent(&SavedConversation{
+		ID:           m.conversationID,
+		Name:         m.sessionName,
+		Title:        m.Title(),
+		Parent:       m.parentSession,
+		Model:        m.currentModel,
+		SystemPrompt: m.systemPrompt,
+		StartedAt:    m.startedAt,
+		UpdatedAt:    time.Now(),
+		Summary:      m.contextSummary,
+		TurnCount:    m.turnCount,
+		Turns:        m.turns,
+		Tokens:       m.currentTokens,
+		TokenLimit:   m.tokenLimit,
+		Pending:      pending,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := storage.WriteFileAtomic(conversationPath(m.historyDir, m.conversationID), data, 0600); err != nil {
+		return fmt.Errorf("failed to save conversation: %w", err)
+	}
+	m.noteRun()
+	return nil
+}
+
+// newConversationID derives an unused ID from the conversation's start time
+func (m *Manager) newConversationID() string {
+	base := m.startedAt.Format(conversationIDFormat)
+	id := base
+	for n := 2; ; n++ {
+		if _, err := os.Stat(conversationPath(m.historyDir, id)); errors.Is(err, os.ErrNotExist) {
+			return id
+		}
+		id = fmt.Sprintf("%s-%d", base, n)
+	}
+}
+
+func conversationPath(dir, id string) string {
+	return filepath.Join(dir, id+".json")
+}
+
+// SavedConversations lists the conversations in the history directory,
+// most recently updated first
+func (m *Manager) SavedConversations() ([]ConversationInfo, error) {
+	if m.historyDir == "" {
+		return nil, nil
+	}
+	return savedConversations(m.historyDir)
+}
+
+// savedConversations lists the conversations in dir, most recently updated
+// first
+func savedConversations(dir string) ([]ConversationInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var infos []ConversationInfo
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if e.IsDir() || !ok {
+			continue
+		}
+		saved, err := loadConversation(dir, id)
+		if err != nil {
+			continue // skip files that aren't conversations
+		}
+		info := ConversationInfo{
+			ID:         id,
+			Name:       saved.Name,
+			Title:      saved.Title,
+			Parent:     saved.Parent,
+			Model:      saved.Model,
+			UpdatedAt:  saved.UpdatedAt,
+			TurnCount:  saved.TurnCount,
+			Tokens:     saved.Tokens,
+			TokenLimit: saved.TokenLimit,
+		}
+		if len(saved.Turns) > 0 {
+			info.FirstPrompt = saved.Turns[0].Prompt
+		}
+		if fi, err := e.Info(); err == nil {
+			info.Size = fi.Size()
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].UpdatedAt.After(infos[j].UpdatedAt)
+	})
+	return infos, nil
+}
+
+// loadConversation reads a saved conversation
+func loadConversation(dir, id string) (*SavedConversation, error) {
+	if !conversationIDRegex.MatchString(id) {
+		return nil, fmt.Errorf("invalid conversation ID %q", id)
+	}
+	data, err := os.ReadFile(conversationPath(dir, id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no saved conversation %q", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var saved SavedConversation
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("conversation %q is corrupt: %w", id, err)
+	}
+	return &saved, nil
+}
+
+// Resume reloads a saved conversation and replays it into a new session on
+// its model, so it can be continued. When the transcript doesn't fit in half
+// the context window, the oldest turns are left out of the replay and the
+// continuation is saved under a new ID, leaving the full original intact.
+func (m *Manager) Resume(id string) error {
+	if m.historyDir == "" {
+		return fmt.Errorf("conversation history is not enabled")
+	}
+	saved, err := loadConversation(m.historyDir, id)
+	if err != nil {
+		return err
+	}
+	return m.replay(saved)
+}
+
+// replay makes saved the current conversation in a new session, leaving out
+// the oldest turns if they don't fit in half the context window. A saved
+// conversation without an ID gets one when it is next saved.
+func (m *Manager) replay(saved *SavedConversation) error {
+	previous := m.saveBranch()
+	previousPrompt, previousID := m.systemPrompt, m.conversationID
+
+	m.adopt(saved)
+	m.currentTokens, m.tokenLimit = 0, 0
+
+	turns := saved.Turns
+	if limit := m.AvailableTokens() / 2; limit > 0 {
+		for len(turns) > 0 && EstimateTokens(transcript(turns)) > limit {
+			turns = turns[1:]
+		}
+	}
+	m.turns = append([]Turn(nil), turns...)
+	m.recorded = append([]Turn(nil), saved.Turns...)
+	dropped := len(saved.Turns) - len(turns)
+	if dropped > 0 {
+		m.conversationID = ""
+	}
+
+	if err := m.Create(m.currentModel); err != nil {
+		m.restoreBranch(previous)
+		m.systemPrompt, m.conversationID = previousPrompt, previousID
+		return err
+	}
+	if saved.Pending != nil {
+		m.lastPrompt, m.lastPromptTurn = saved.Pending.Prompt, 0
+		m.notice("The last prompt's reply didn't finish; /retry sends it again.\n")
+	}
+	if dropped > 0 && saved.ID != "" {
+		m.notice("The conversation is too long to replay in full; its %d oldest turns were left out and it continues under a new ID.\n", dropped)
+	} else if dropped > 0 {
+		m.notice("The conversation is too long to replay in full; its %d oldest turns were left out.\n", dropped)
+	}
+	return nil
+}
+
+// adopt makes saved the current conversation, apart from its turns
+func (m *Manager) adopt(saved *SavedConversation) {
+	m.currentModel = saved.Model
+	m.currentMultiplier = m.modelMultiplier(saved.Model)
+	m.systemPrompt = saved.SystemPrompt
+	m.contextSummary = saved.Summary
+	m.turnCount = saved.TurnCount
+	m.startedAt = saved.StartedAt
+	m.conversationID = saved.ID
+	m.sessionName = saved.Name
+	m.title = saved.Title
+	m.parentSession = saved.Parent
+}
+
+// modelMultiplier returns the billing multiplier of model, or 0 if unknown
+func (m *Manager) modelMultiplier(model string) float64 {
+	list, err := m.client.GetModels()
+	if err != nil {
+		return 0
+	}
+	if info, ok := models.Find(list, model); ok {
+		return models.Multiplier(info)
+	}
+	return 0
+}
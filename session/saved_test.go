@@ -0,0 +1,128 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSaveConversation_EveryTurn(t *testing.T) {
+	dir := t.TempDir()
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	mgr.SetHistoryDir(dir)
+
+	for _, prompt := range []string{"one", "two"} {
+		if err := mgr.Send(prompt); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	id := mgr.ConversationID()
+	if id == "" {
+		t.Fatal("conversation was not assigned an ID")
+	}
+	saved, err := loadConversation(dir, id)
+	if err != nil {
+		t.Fatalf("loadConversation() error = %v", err)
+	}
+	if saved.TurnCount != 2 || len(saved.Turns) != 2 || saved.Turns[1].Prompt != "two" || saved.Model != mgr.GetCurrentModel() {
+		t.Errorf("saved = %+v, want both turns", saved)
+	}
+
+	// A new conversation gets its own file
+	if err := mgr.SetModel("gpt-4.1", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.Send("three"); err != nil {
+		t.Fatal(err)
+	}
+	if mgr.ConversationID() == id {
+		t.Error("a new conversation should not reuse the previous ID")
+	}
+	infos, err := mgr.SavedConversations()
+	if err != nil || len(infos) != 2 {
+		t.Fatalf("SavedConversations() = %v, %v; want 2", infos, err)
+	}
+	if infos[0].ID != mgr.ConversationID() || infos[0].FirstPrompt != "three" {
+		t.Errorf("newest conversation = %+v, want the gpt-4.1 one first", infos[0])
+	}
+}
+
+func TestSaveConversation_Disabled(t *testing.T) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	if err := mgr.Send("hello"); err != nil {
+		t.Fatal(err)
+	}
+	if mgr.ConversationID() != "" {
+		t.Error("conversation should not be saved without a history directory")
+	}
+}
+
+func TestResume(t *testing.T) {
+	dir := t.TempDir()
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	mgr.SetHistoryDir(dir)
+	mgr.SetSystemPrompt("Be terse.")
+	if err := mgr.Send("What is Go?"); err != nil {
+		t.Fatal(err)
+	}
+	id := mgr.ConversationID()
+
+	if err := mgr.SetModel("gpt-4.1", 1); err != nil {
+		t.Fatal(err)
+	}
+	mgr.SetSystemPrompt("")
+
+	if err := mgr.Resume(id); err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+	if mgr.GetCurrentModel() != "Claude Sonnet 4.5" || mgr.SystemPrompt() != "Be terse." || mgr.ConversationID() != id {
+		t.Errorf("resumed model %q, system prompt %q, ID %q; want the saved conversation's", mgr.GetCurrentModel(), mgr.SystemPrompt(), mgr.ConversationID())
+	}
+	if msg := mgr.systemMessage(); !strings.Contains(msg, "User: What is Go?") {
+		t.Errorf("system message = %q, want the transcript replayed", msg)
+	}
+
+	// Continuing appends to the same file
+	if err := mgr.Send("Who made it?"); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := loadConversation(dir, id)
+	if err != nil || len(saved.Turns) != 2 || saved.TurnCount != 2 {
+		t.Errorf("saved = %+v, %v; want the resumed conversation extended", saved, err)
+	}
+}
+
+func TestResume_Errors(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0600)
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+
+	if err := mgr.Resume("x"); err == nil {
+		t.Error("Resume() without a history directory should fail")
+	}
+	mgr.SetHistoryDir(dir)
+	for _, id := range []string{"missing", "../etc/passwd", "broken"} {
+		if err := mgr.Resume(id); err == nil {
+			t.Errorf("Resume(%q) should fail", id)
+		}
+	}
+	if infos, err := mgr.SavedConversations(); err != nil || len(infos) != 0 {
+		t.Errorf("SavedConversations() = %v, %v; want corrupt files skipped", infos, err)
+	}
+}
+
+func TestNewConversationID_Unique(t *testing.T) {
+	dir := t.TempDir()
+	mgr := createTestManager(&mockSDKClient{})
+	mgr.SetHistoryDir(dir)
+	mgr.startedAt = time.Date(2025, 3, 1, 9, 30, 0, 0, time.Local)
+
+	first := mgr.newConversationID()
+	os.WriteFile(conversationPath(dir, first), []byte("{}"), 0600)
+	if second := mgr.newConversationID(); second != first+"-2" {
+		t.Errorf("newConversationID() = %q, want %q", second, first+"-2")
+	}
+}
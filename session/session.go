@@ -2,9 +2,14 @@ package session
 
 import (
 	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
 	"time"
 
 	"atulm/cocli/client"
+	"atulm/cocli/models"
 
 	copilot "github.com/github/copilot-sdk/go"
 )
@@ -13,6 +18,7 @@ import (
 type SessionInterface interface {
 	On(copilot.SessionEventHandler) func()
 	SendAndWait(copilot.MessageOptions, time.Duration) (*copilot.SessionEvent, error)
+	Abort() error
 }
 
 // copilotSession wraps the actual copilot.Session to implement SessionInterface
@@ -28,38 +34,171 @@ type Manager struct {
 	tokenLimit        int64
 	currentModel      string
 	currentMultiplier float64
+	aliases           models.Aliases
 	renderer          *StreamingMarkdownRenderer
+	jsonOut           *jsonWriter // replaces rendering in JSON output mode
+	mcpServers        map[string]copilot.MCPServerConfig
+	promptStages      []PromptStage
+	turnHooks         []func(TurnReport)
+
+	// projectInstructions are the instruction files under projectRoot, as
+	// they were when the session was created
+	projectRoot         string
+	projectInstructions string
+	instructionFiles    []string
+
+	// title names the conversation (/title); generatedTitles holds titles
+	// generated in the background, by conversation ID, until adopted
+	title           string
+	autoTitle       bool
+	titleMu         sync.Mutex
+	generatedTitles map[string]string
+
+	contextPolicy  ContextPolicy
+	warnedAt       int    // highest warning threshold already shown for this session
+	summarizeOff   bool   // oldest turns aren't summarized near the limit (/compact auto off)
+	contextSummary string // summary carried over by /compact
+	configPrompt   string // instructions from the config files (system_prompt)
+	systemPrompt   string // custom system instructions (/system)
+	projectContext string // description of the project (/context tree)
+	agentMode      bool   // the model proposes file edits as diffs (/agent)
+	turns          []Turn // conversation since the last compaction
+	recorded       []Turn // every turn of the conversation, for /save
+	lastReply      string // most recent assistant message, for /copy
+	lastPrompt     string // most recent prompt passed to Send, for /retry
+	lastPromptTurn int    // turnCount once lastPrompt was answered, else 0
+	branches       []branch
+	pendingContext []ContextItem     // attached to the next prompt (/tmux capture)
+	vars           map[string]string // {{name}} values set with /set var
+	shellPolicy    ShellPolicy       // what !(command) in a prompt may run
+	shellConfirm   ShellConfirm
+	toolMode       ToolMode     // which tool calls are approved (/tools)
+	toolApprover   ToolApprover // asks the user in ToolsAsk mode
+	muted          bool         // suppresses streamed output (e.g. while compacting)
+	showStats      bool         // prints a stats line after each reply (/stats)
+	quiet          bool         // suppresses notices (--quiet)
+	retryPolicy    RetryPolicy  // when a failed reply is sent again
+	debug          debugLog     // raw session events, when on (/debug)
+	tee            teeFile      // file replies are appended to (--output, /tee)
+
+	sessionPolicy  SessionPolicy
+	historyDir     string            // conversations are saved here as they progress
+	conversationID string            // file the current conversation is saved under
+	sessionName    string            // name given by /session new, "" if unnamed
+	parentSession  string            // session this one was forked from by /fork
+	handlePath     string            // the daemon session's handle is kept here (SetHandlePath)
+	run            *run              // marks this run as in progress (TrackRun)
+	openSessions   map[string]branch // named sessions set aside by /session
+	turnCount      int               // turns in this conversation, including compacted ones
+	startedAt      time.Time         // time of the conversation's first turn
+
+	turnInputTokens  int64 // usage reported during the current Send
+	turnOutputTokens int64
+	turnRateLimits   int          // times the current Send was throttled
+	rateLimitedUntil time.Time    // when the API should take prompts again
+	usage            []ModelUsage // per-model totals since the manager was created
+	requestHooks     []func(ModelUsage)
+
+	stallTimeout   time.Duration
+	requestTimeout time.Duration // how long Send waits for a reply, 0 for no limit
+	respMu         sync.Mutex
+	resp           *response // reply currently being streamed, if any
+
+	progress        io.Writer // shows a spinner until a reply's first output, nil for none
+	animateProgress bool      // progress is a terminal, so the spinner is animated
+}
+
+// DefaultModel is the model new sessions use unless configured otherwise
+const DefaultModel = "Claude Sonnet 4.5"
+
+// ManagerOption is a functional option for configuring the manager
+type ManagerOption func(*managerOptions)
+
+type managerOptions struct {
+	model           string
+	aliases         models.Aliases
+	rendererOptions []RendererOption
+	instructions    string
+	mcpServers      map[string]copilot.MCPServerConfig
+	projectRoot     string
+}
+
+// WithModel sets the model of the initial session, by name or ID; ""
+// keeps DefaultModel
+func WithModel(model string) ManagerOption {
+	return func(o *managerOptions) {
+		if model != "" {
+			o.model = model
+		}
+	}
+}
+
+// WithModelAliases sets the aliases that model names may use, both for the
+// initial model and for SwitchModel
+func WithModelAliases(aliases models.Aliases) ManagerOption {
+	return func(o *managerOptions) {
+		o.aliases = aliases
+	}
+}
+
+// WithInstructions adds text to the system message of every session,
+// before the instructions set with SetSystemPrompt
+func WithInstructions(text string) ManagerOption {
+	return func(o *managerOptions) {
+		o.instructions = strings.TrimSpace(text)
+	}
+}
+
+// WithMCPServers gives every session the MCP servers, by name
+func WithMCPServers(servers map[string]copilot.MCPServerConfig) ManagerOption {
+	return func(o *managerOptions) {
+		o.mcpServers = servers
+	}
+}
+
+// WithRendererOptions configures the markdown renderer
+func WithRendererOptions(opts ...RendererOption) ManagerOption {
+	return func(o *managerOptions) {
+		o.rendererOptions = append(o.rendererOptions, opts...)
+	}
 }
 
 // NewManager creates a new session manager with the given client.
 // It creates an initial session with the default model.
-func NewManager(cli *client.Client) (*Manager, error) {
-	renderer, err := NewStreamingMarkdownRenderer()
+func NewManager(cli *client.Client, opts ...ManagerOption) (*Manager, error) {
+	options := managerOptions{model: DefaultModel}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	renderer, err := NewStreamingMarkdownRenderer(options.rendererOptions...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create markdown renderer: %w", err)
 	}
 
-	defaultModel := "Claude Sonnet 4.5"
+	defaultModel := options.model
 
 	mgr := &Manager{
 		client:            cli,
 		currentModel:      defaultModel,
 		currentMultiplier: 0,
+		aliases:           options.aliases,
+		configPrompt:      options.instructions,
+		projectRoot:       options.projectRoot,
+		mcpServers:        options.mcpServers,
 		renderer:          renderer,
+		contextPolicy:     DefaultContextPolicy(),
+		retryPolicy:       DefaultRetryPolicy(),
+		stallTimeout:      DefaultStallTimeout,
+		requestTimeout:    DefaultRequestTimeout,
 	}
 
 	// Try to fetch billing multiplier for default model
-	models, err := cli.GetModels()
-	if err == nil {
-		for _, model := range models {
-			if model.Name == defaultModel {
-				if model.Billing != nil {
-					mgr.currentMultiplier = model.Billing.Multiplier
-				}
-				// Use the actual model ID for session creation
-				mgr.currentModel = model.ID
-				break
-			}
+	if list, err := cli.GetModels(); err == nil {
+		if model, err := models.Resolve(list, mgr.aliases, defaultModel); err == nil {
+			mgr.currentMultiplier = models.Multiplier(model)
+			// Use the actual model ID for session creation
+			mgr.currentModel = model.ID
 		}
 	}
 
@@ -75,9 +214,13 @@ func NewManager(cli *client.Client) (*Manager, error) {
 func NewManagerForTesting(cli *client.Client) *Manager {
 	return &Manager{
 		client:            cli,
-		currentModel:      "Claude Sonnet 4.5",
+		currentModel:      DefaultModel,
 		currentMultiplier: 0,
 		renderer:          nil,
+		contextPolicy:     DefaultContextPolicy(),
+		retryPolicy:       DefaultRetryPolicy(),
+		stallTimeout:      DefaultStallTimeout,
+		requestTimeout:    DefaultRequestTimeout,
 	}
 }
 
@@ -86,6 +229,60 @@ func (m *Manager) SetRenderer(r *StreamingMarkdownRenderer) {
 	m.renderer = r
 }
 
+// SetCopySafeCode turns copy-safe code blocks on or off; see
+// StreamingMarkdownRenderer.SetCopySafe
+func (m *Manager) SetCopySafeCode(on bool) {
+	if m.renderer != nil {
+		m.renderer.SetCopySafe(on)
+	}
+}
+
+// CopySafeCode reports whether copy-safe code blocks are on
+func (m *Manager) CopySafeCode() bool {
+	return m.renderer != nil && m.renderer.CopySafe()
+}
+
+// SetWordWrap changes the column output is wrapped at; see
+// StreamingMarkdownRenderer.SetWordWrap
+func (m *Manager) SetWordWrap(n int) {
+	if m.renderer != nil {
+		m.renderer.SetWordWrap(n)
+	}
+}
+
+// SetCalloutStyles overrides how callouts are drawn; see
+// StreamingMarkdownRenderer.SetCalloutStyles
+func (m *Manager) SetCalloutStyles(styles map[string]CalloutStyle) {
+	if m.renderer != nil {
+		m.renderer.SetCalloutStyles(styles)
+	}
+}
+
+// SetCodeStyle switches the chroma style code blocks are highlighted with;
+// see StreamingMarkdownRenderer.SetCodeStyle
+func (m *Manager) SetCodeStyle(name string) error {
+	if m.renderer == nil {
+		return nil
+	}
+	return m.renderer.SetCodeStyle(name)
+}
+
+// CodeStyle returns the chroma style code blocks are highlighted with, or
+// DefaultCodeStyle
+func (m *Manager) CodeStyle() string {
+	if m.renderer == nil {
+		return DefaultCodeStyle
+	}
+	return m.renderer.CodeStyle()
+}
+
+// PreviewCodeStyle prints a code sample in the current code style
+func (m *Manager) PreviewCodeStyle() {
+	if m.renderer != nil {
+		m.renderer.PreviewCodeStyle()
+	}
+}
+
 // IsUsingDaemon returns true if the client is connected to a daemon
 func (m *Manager) IsUsingDaemon() bool {
 	return m.client.IsUsingDaemon()
@@ -94,15 +291,19 @@ func (m *Manager) IsUsingDaemon() bool {
 // Create creates a new session with the given model and sets up event handlers.
 // The session is configured with a system message that instructs the model to
 // always format responses using markdown, ensuring consistent, high-quality output
-// that works well with the streaming markdown renderer.
+// that works well with the streaming markdown renderer. The project's
+// instruction files are read again, so edits to them apply to each new session.
 func (m *Manager) Create(model string) error {
+	m.loadInstructions()
 	sess, err := m.client.CreateSession(&copilot.SessionConfig{
 		Model:     model,
 		Streaming: true,
 		SystemMessage: &copilot.SystemMessageConfig{
 			Mode:    "append",
-			Content: "Always format responses using markdown with code blocks.",
+			Content: m.systemMessage(),
 		},
+		MCPServers:          m.mcpServers,
+		OnPermissionRequest: m.handlePermission,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create session: %w", err)
@@ -114,88 +315,272 @@ func (m *Manager) Create(model string) error {
 	}
 	m.currentTokens = 0
 	m.tokenLimit = 0
+	m.warnedAt = 0
 
 	// Set up event listeners only if session exists
 	if m.session != nil {
 		m.setupEventHandlers()
 	}
+	slog.Debug("session created", "model", model, "id", m.sessionID())
 
 	return nil
 }
 
 // setupEventHandlers configures the session event listeners
 func (m *Manager) setupEventHandlers() {
-	m.session.On(func(event copilot.SessionEvent) {
-		if event.Type == "assistant.message_delta" {
-			if event.Data.DeltaContent != nil {
-				if m.renderer != nil {
-					m.renderer.ProcessDelta(*event.Data.DeltaContent)
-				} else {
-					// Fallback to plain text if renderer not available
-					fmt.Print(*event.Data.DeltaContent)
-				}
-			}
-		} else if event.Type == "session.idle" {
-			if m.renderer != nil {
-				m.renderer.Flush()
-			}
-			fmt.Println()
+	m.session.On(m.handleEvent)
+}
+
+// handleEvent renders a session event and records what it reports
+func (m *Manager) handleEvent(event copilot.SessionEvent) {
+	m.logEvent(event)
+	m.updateSpinner(event)
+	switch event.Type {
+	case copilot.AssistantMessageDelta, copilot.ToolExecutionStart, copilot.ToolExecutionProgress, copilot.ToolExecutionComplete:
+		m.touchResponse()
+	}
+	if event.Type == copilot.AssistantMessageDelta && event.Data.DeltaContent != nil {
+		m.addPartial(*event.Data.DeltaContent)
+	}
+	if event.Type == copilot.SessionError {
+		m.noteRateLimit(event)
+	}
+
+	// Output is suppressed while muted (e.g. compacting); token counts
+	// below still update
+	if !m.muted {
+		m.renderEvent(event)
+	}
+
+	// Update token counts from events
+	if event.Data.CurrentTokens != nil {
+		m.currentTokens = int64(*event.Data.CurrentTokens)
+	}
+	if event.Data.TokenLimit != nil {
+		m.tokenLimit = int64(*event.Data.TokenLimit)
+	}
+	if event.Type == copilot.AssistantUsage {
+		if event.Data.InputTokens != nil {
+			m.turnInputTokens += int64(*event.Data.InputTokens)
 		}
+		if event.Data.OutputTokens != nil {
+			m.turnOutputTokens += int64(*event.Data.OutputTokens)
+		}
+	}
+}
 
-		// Update token counts from events
-		if event.Data.CurrentTokens != nil {
-			m.currentTokens = int64(*event.Data.CurrentTokens)
+// renderEvent streams assistant output to the terminal, or as JSON events
+// in JSON output mode
+func (m *Manager) renderEvent(event copilot.SessionEvent) {
+	if m.jsonOut != nil {
+		m.writeJSONEvent(event)
+		return
+	}
+	if event.Type == "assistant.message_delta" {
+		if event.Data.DeltaContent != nil {
+			if m.renderer != nil {
+				m.renderer.ProcessDelta(*event.Data.DeltaContent)
+			} else {
+				// Fallback to plain text if renderer not available
+				fmt.Print(*event.Data.DeltaContent)
+			}
 		}
-		if event.Data.TokenLimit != nil {
-			m.tokenLimit = int64(*event.Data.TokenLimit)
+	} else if event.Type == copilot.ToolExecutionStart || event.Type == copilot.ToolExecutionComplete {
+		m.renderToolEvent(event)
+	} else if event.Type == "session.idle" {
+		if m.renderer != nil {
+			m.renderer.Flush()
 		}
-	})
+		fmt.Println()
+	}
 }
 
-// Send sends a message to the current session and waits for response
+// Send runs the prompt through the pre-processing pipeline, sends it to the
+// current session and waits for the response, then reports the turn to the
+// OnTurnComplete hooks
 func (m *Manager) Send(prompt string) error {
+	m.lastPrompt, m.lastPromptTurn = prompt, 0
+	start := time.Now()
+	err := m.send(prompt)
+	m.reportTurn(start, err)
+	return err
+}
+
+// send does the work of Send
+func (m *Manager) send(prompt string) error {
 	if m.session == nil {
 		return fmt.Errorf("no active session")
 	}
+	if err := m.waitForRateLimit(); err != nil {
+		return err
+	}
+
+	if err := m.maybeArchive(); err != nil {
+		slog.Warn("failed to archive the conversation", "err", err)
+	}
+	if err := m.maybeAutoCompact(); err != nil {
+		slog.Warn("automatic compaction failed", "err", err)
+	} else if err := m.maybeSummarize(); err != nil {
+		slog.Warn("automatic summarization failed", "err", err)
+	}
 
-	_, err := m.session.SendAndWait(copilot.MessageOptions{
-		Prompt: prompt,
-	}, 0)
+	p, err := m.preparePrompt(prompt)
 	if err != nil {
-		return fmt.Errorf("failed to send message: %w", err)
+		return err
 	}
+	defer p.done()
+	m.turnInputTokens, m.turnOutputTokens, m.turnRateLimits = 0, 0, 0
+	defer m.recordUsage(time.Now())
+
+	// Saved first so the prompt survives a crash while waiting for the reply
+	pending := Turn{Prompt: p.Text, At: time.Now(), Model: m.currentModel}
+	m.savePending(pending)
 
+	reply, partial, err := m.exchangeWithRetry(p)
+	if err != nil {
+		pending.Response = partial
+		m.savePending(pending)
+		return err
+	}
+	m.recordTurn(p.Text, reply)
+	m.lastPromptTurn = m.turnCount
+	if err := m.saveConversation(); err != nil {
+		slog.Warn("conversation not saved", "err", err)
+	}
+	if m.turnCount == 1 {
+		m.generateTitle(m.turns[len(m.turns)-1])
+	}
+	if err := m.writeHandle(); err != nil {
+		slog.Warn("session handle not saved", "err", err)
+	}
+
+	m.checkContextUsage()
 	return nil
 }
 
+// exchange sends p to the current session and waits for the reply, unless
+// the user cancels it, it stalls or the request timeout passes. A reply cut
+// short returns the part of it that was streamed.
+func (m *Manager) exchange(p *Prompt) (reply *copilot.SessionEvent, partial string, err error) {
+	resp := m.beginResponse()
+	defer m.endResponse(resp)
+
+	type result struct {
+		reply *copilot.SessionEvent
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		// The request timeout is enforced below so the turn can be aborted
+		reply, err := m.session.SendAndWait(copilot.MessageOptions{
+			Prompt:      p.Text,
+			Attachments: p.Attachments,
+		}, noTimeout)
+		done <- result{reply, err}
+	}()
+
+	var timeout <-chan time.Time
+	if m.requestTimeout > 0 {
+		timer := time.NewTimer(m.requestTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			return nil, m.partialReply(resp), m.asRateLimit(resp, fmt.Errorf("failed to send message: %w", r.err))
+		}
+		if m.showStats {
+			m.printStats(m.responseStats(resp))
+		}
+		return r.reply, "", nil
+	case <-timeout:
+		m.abortResponse(resp)
+		return nil, m.partialReply(resp), fmt.Errorf("%w: no reply within %s", ErrRequestTimedOut, m.requestTimeout)
+	case <-resp.abort:
+		stalled := resp.watchdog.isStalled()
+		m.abortResponse(resp)
+		if stalled {
+			return nil, m.partialReply(resp), ErrResponseStalled
+		}
+		return nil, m.partialReply(resp), ErrResponseCancelled
+	}
+}
+
+// abortResponse stops the turn resp is receiving and ends its output
+func (m *Manager) abortResponse(resp *response) {
+	resp.watchdog.stop()
+	resp.spinner.stop()
+	if err := m.session.Abort(); err != nil {
+		slog.Warn("failed to abort the response", "err", err)
+	}
+	if m.renderer != nil {
+		m.renderer.Flush()
+	}
+	fmt.Println()
+}
+
+// reconnectIfLost reconnects to the server if the connection was lost, e.g.
+// because the daemon died, and recreates the session with its model, system
+// prompt and conversation. It reports whether the session was recreated.
+func (m *Manager) reconnectIfLost() bool {
+	if m.client.Connected() {
+		return false
+	}
+	m.notice("Lost the connection to the server, reconnecting... ")
+	slog.Debug("connection lost", "model", m.currentModel)
+	if err := m.client.Reconnect(); err != nil {
+		m.notice("\n")
+		slog.Warn("failed to reconnect to the server", "err", err)
+		return false
+	}
+	if err := m.Create(m.currentModel); err != nil {
+		m.notice("\n")
+		slog.Warn("failed to recreate the session after reconnecting", "model", m.currentModel, "err", err)
+		return false
+	}
+	// Set-aside conversations lost their sessions with the connection
+	for i := range m.branches {
+		m.branches[i].stale = true
+	}
+	for name, b := range m.openSessions {
+		b.stale = true
+		m.openSessions[name] = b
+	}
+	m.notice("reconnected.\n")
+	return true
+}
+
 // GetModels returns cached models from the client
 func (m *Manager) GetModels() ([]copilot.ModelInfo, error) {
 	return m.client.GetModels()
 }
 
-// DisplayModels prints the list of available models with billing info
-func (m *Manager) DisplayModels() error {
-	models, err := m.client.GetModels()
+// DisplayModels prints the available models that match filter, with billing
+// info and capability badges, and returns the models it listed
+func (m *Manager) DisplayModels(filter models.Filter) ([]copilot.ModelInfo, error) {
+	list, err := m.client.GetModels()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("no models available")
 	}
-	if len(models) == 0 {
-		return fmt.Errorf("no models available")
+	list = filter.Apply(list)
+	if len(list) == 0 {
+		return nil, fmt.Errorf("no models match %s", filter)
 	}
 
 	fmt.Println("\nAvailable models:")
-	for i, model := range models {
+	for i, model := range list {
 		prefix := "  "
 		if model.ID == m.currentModel {
 			prefix = "* "
 		}
-		billingInfo := ""
-		if model.Billing != nil {
-			billingInfo = fmt.Sprintf(" (%.2fx)", model.Billing.Multiplier)
-		}
-		fmt.Printf("%s%d. %s (ID: %s)%s\n", prefix, i+1, model.Name, model.ID, billingInfo)
+		fmt.Printf("%s%d. %s\n", prefix, i+1, models.Describe(model))
 	}
-	return nil
+	return list, nil
 }
 
 // ListModels returns available models from the server
@@ -205,6 +590,8 @@ func (m *Manager) ListModels() ([]copilot.ModelInfo, error) {
 
 // SetModel switches to a new model with the given billing multiplier and creates a new session
 func (m *Manager) SetModel(modelID string, multiplier float64) error {
+	// A new model starts a fresh conversation
+	m.resetConversation()
 	if err := m.Create(modelID); err != nil {
 		return err
 	}
@@ -213,6 +600,29 @@ func (m *Manager) SetModel(modelID string, multiplier float64) error {
 	return nil
 }
 
+// SwitchModel switches to the model name refers to: an alias, a model ID,
+// a model's display name, or an unambiguous prefix or part of one. It
+// returns the model switched to.
+func (m *Manager) SwitchModel(name string) (copilot.ModelInfo, error) {
+	list, err := m.client.GetModels()
+	if err != nil {
+		return copilot.ModelInfo{}, fmt.Errorf("failed to list models: %w", err)
+	}
+	model, err := models.Match(list, m.aliases, name)
+	if err != nil {
+		return copilot.ModelInfo{}, err
+	}
+	if err := m.SetModel(model.ID, models.Multiplier(model)); err != nil {
+		return copilot.ModelInfo{}, err
+	}
+	return model, nil
+}
+
+// ModelAliases returns the configured model aliases
+func (m *Manager) ModelAliases() models.Aliases {
+	return m.aliases
+}
+
 // GetCurrentModel returns the ID of the currently selected model
 func (m *Manager) GetCurrentModel() string {
 	return m.currentModel
@@ -237,9 +647,3 @@ func (m *Manager) HasTokenLimit() bool {
 func (m *Manager) GetTokenLimit() int64 {
 	return m.tokenLimit
 }
-
-// Close is a no-op for session manager - client lifecycle is managed separately
-func (m *Manager) Close() []error {
-	// Client lifecycle is managed by the caller
-	return nil
-}
@@ -4,12 +4,14 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"strings"
 	"testing"
 	"time"
 
 	"atulm/cocli/client"
+	"atulm/cocli/models"
 
 	copilot "github.com/github/copilot-sdk/go"
 )
@@ -21,6 +23,9 @@ type mockSDKClient struct {
 	models      []copilot.ModelInfo
 	createError error
 	listError   error
+	pingErr     error
+	// created is the config of the last session created
+	created *copilot.SessionConfig
 }
 
 func (m *mockSDKClient) ListModels() ([]copilot.ModelInfo, error) {
@@ -34,6 +39,7 @@ func (m *mockSDKClient) ListModels() ([]copilot.ModelInfo, error) {
 }
 
 func (m *mockSDKClient) CreateSession(config *copilot.SessionConfig) (*copilot.Session, error) {
+	m.created = config
 	if m.createError != nil {
 		return nil, m.createError
 	}
@@ -41,6 +47,10 @@ func (m *mockSDKClient) CreateSession(config *copilot.SessionConfig) (*copilot.S
 	return nil, nil
 }
 
+func (m *mockSDKClient) ResumeSessionWithOptions(string, *copilot.ResumeSessionConfig) (*copilot.Session, error) {
+	return nil, nil
+}
+
 func (m *mockSDKClient) Start() error {
 	return nil
 }
@@ -49,9 +59,34 @@ func (m *mockSDKClient) Stop() []error {
 	return nil
 }
 
+func (m *mockSDKClient) ForceStop() {}
+
+func (m *mockSDKClient) GetAuthStatus() (*copilot.GetAuthStatusResponse, error) {
+	return &copilot.GetAuthStatusResponse{IsAuthenticated: true}, nil
+}
+
+func (m *mockSDKClient) GetStatus() (*copilot.GetStatusResponse, error) {
+	return &copilot.GetStatusResponse{}, nil
+}
+
+func (m *mockSDKClient) Ping(string) (*copilot.PingResponse, error) {
+	if m.pingErr != nil {
+		return nil, m.pingErr
+	}
+	return &copilot.PingResponse{}, nil
+}
+
 // mockSession implements SessionInterface for testing
 type mockSession struct {
-	model string
+	model       string
+	sendCount   int
+	lastOptions copilot.MessageOptions
+	sent        []string
+	reply       string // content of the final assistant message, if set
+	err         error  // returned by SendAndWait, if set
+	aborted     bool
+	// block, if set, makes SendAndWait wait until it is closed
+	block chan struct{}
 }
 
 func (m *mockSession) On(handler copilot.SessionEventHandler) func() {
@@ -59,8 +94,26 @@ func (m *mockSession) On(handler copilot.SessionEventHandler) func() {
 	return func() {} // Return unsubscribe function
 }
 
+func (m *mockSession) Abort() error {
+	m.aborted = true
+	return nil
+}
+
 func (m *mockSession) SendAndWait(options copilot.MessageOptions, timeout time.Duration) (*copilot.SessionEvent, error) {
 	// Mock send functionality
+	m.sendCount++
+	m.lastOptions = options
+	m.sent = append(m.sent, options.Prompt)
+	if m.block != nil {
+		<-m.block
+	}
+	if m.err != nil {
+		return nil, m.err
+	}
+	if m.reply != "" {
+		content := m.reply
+		return &copilot.SessionEvent{Type: "assistant.message", Data: copilot.Data{Content: &content}}, nil
+	}
 	return nil, nil
 }
 
@@ -80,6 +133,17 @@ func captureOutput(f func()) string {
 	return buf.String()
 }
 
+// captureLog returns what f logs through slog
+func captureLog(f func()) string {
+	old := slog.Default()
+	var buf bytes.Buffer
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	defer slog.SetDefault(old)
+
+	f()
+	return buf.String()
+}
+
 // createTestManager creates a Manager with mock client for testing
 func createTestManager(mockSDK *mockSDKClient) *Manager {
 	cli := client.NewClientWithSDK(mockSDK)
@@ -546,6 +610,49 @@ func TestSetModel(t *testing.T) {
 	}
 }
 
+// TestSwitchModel tests switching by alias, ID and display name
+func TestSwitchModel(t *testing.T) {
+	available := []copilot.ModelInfo{
+		{ID: "claude-haiku-4.5", Name: "Claude Haiku 4.5", Billing: &copilot.ModelBilling{Multiplier: 0.33}},
+		{ID: "claude-opus-4.5", Name: "Claude Opus 4.5", Billing: &copilot.ModelBilling{Multiplier: 3}},
+	}
+	tests := []struct {
+		name      string
+		input     string
+		wantModel string
+		wantMult  float64
+		wantError bool
+	}{
+		{name: "alias", input: "fast", wantModel: "claude-haiku-4.5", wantMult: 0.33},
+		{name: "id", input: "claude-opus-4.5", wantModel: "claude-opus-4.5", wantMult: 3},
+		{name: "display name", input: "claude opus 4.5", wantModel: "claude-opus-4.5", wantMult: 3},
+		{name: "unknown", input: "nope", wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr := createTestManager(&mockSDKClient{models: available})
+			mgr.aliases = models.Aliases{"fast": "claude-haiku-4.5"}
+
+			model, err := mgr.SwitchModel(tt.input)
+			if tt.wantError {
+				if err == nil {
+					t.Error("SwitchModel() expected error, got nil")
+				}
+				if mgr.GetCurrentModel() != DefaultModel {
+					t.Errorf("model = %v, want it unchanged", mgr.GetCurrentModel())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SwitchModel() error = %v", err)
+			}
+			if model.ID != tt.wantModel || mgr.GetCurrentModel() != tt.wantModel || mgr.GetCurrentMultiplier() != tt.wantMult {
+				t.Errorf("switched to %v (%v), want %v (%v)", mgr.GetCurrentModel(), mgr.GetCurrentMultiplier(), tt.wantModel, tt.wantMult)
+			}
+		})
+	}
+}
+
 // TestGetModels tests the GetModels method (delegates to client)
 func TestGetModels(t *testing.T) {
 	testModels := []copilot.ModelInfo{
@@ -676,13 +783,18 @@ func TestDisplayModels(t *testing.T) {
 		{ID: "claude-sonnet-4.5", Name: "Claude Sonnet 4.5", Billing: &copilot.ModelBilling{Multiplier: 1.0}},
 		{ID: "claude-opus-4.5", Name: "Claude Opus 4.5", Billing: &copilot.ModelBilling{Multiplier: 3.0}},
 	}
+	visionModel := copilot.ModelInfo{ID: "gpt-4.1", Name: "GPT-4.1", Billing: &copilot.ModelBilling{Multiplier: 0}}
+	visionModel.Capabilities.Supports.Vision = true
+	visionModel.Capabilities.Limits.MaxContextWindowTokens = 128000
 
 	tests := []struct {
 		name           string
 		models         []copilot.ModelInfo
+		filter         models.Filter
 		currentModel   string
 		wantError      bool
 		expectInOutput []string
+		wantListed     int
 	}{
 		{
 			name:         "display models with current selection",
@@ -695,6 +807,39 @@ func TestDisplayModels(t *testing.T) {
 				"* 2. Claude Sonnet 4.5 (ID: claude-sonnet-4.5) (1.00x)",
 				"3. Claude Opus 4.5 (ID: claude-opus-4.5) (3.00x)",
 			},
+			wantListed: 3,
+		},
+		{
+			name:   "capability badges",
+			models: []copilot.ModelInfo{visionModel},
+			expectInOutput: []string{
+				"1. GPT-4.1 (ID: gpt-4.1) (0.00x) [vision, 128k context]",
+			},
+			wantListed: 1,
+		},
+		{
+			name:   "vision filter",
+			models: append([]copilot.ModelInfo{visionModel}, testModels...),
+			filter: models.Filter{Vision: true},
+			expectInOutput: []string{
+				"1. GPT-4.1 (ID: gpt-4.1)",
+			},
+			wantListed: 1,
+		},
+		{
+			name:   "cheap filter numbers the matching models",
+			models: testModels,
+			filter: models.Filter{Cheap: true},
+			expectInOutput: []string{
+				"1. Claude Sonnet 4.5 (ID: claude-haiku-4.5) (0.33x)",
+			},
+			wantListed: 1,
+		},
+		{
+			name:      "filter matches nothing",
+			models:    testModels,
+			filter:    models.Filter{Vision: true},
+			wantError: true,
 		},
 		{
 			name:      "no models error",
@@ -711,6 +856,7 @@ func TestDisplayModels(t *testing.T) {
 			expectInOutput: []string{
 				"* 1. Free Model (ID: free-model)",
 			},
+			wantListed: 1,
 		},
 	}
 
@@ -721,7 +867,10 @@ func TestDisplayModels(t *testing.T) {
 			mgr.currentModel = tt.currentModel
 
 			output := captureOutput(func() {
-				err := mgr.DisplayModels()
+				listed, err := mgr.DisplayModels(tt.filter)
+				if len(listed) != tt.wantListed {
+					t.Errorf("DisplayModels() listed %d models, want %d", len(listed), tt.wantListed)
+				}
 
 				if tt.wantError {
 					if err == nil {
@@ -958,6 +1107,98 @@ func TestSendWithSession(t *testing.T) {
 	}
 }
 
+// TestSendReconnects tests that a prompt sent over a lost connection is sent
+// again once the client has reconnected
+func TestSendReconnects(t *testing.T) {
+	sess := &mockSession{err: fmt.Errorf("failed to write header: write: broken pipe"), reply: "answer"}
+	dead := &mockSDKClient{pingErr: fmt.Errorf("client not connected")}
+	connects := 0
+	cli, err := client.NewClientWithConnector(func() (client.ClientInterface, error) {
+		if connects++; connects == 1 {
+			return dead, nil
+		}
+		sess.err = nil // the new connection works
+		return &mockSDKClient{}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := NewManagerForTesting(cli)
+	mgr.session = sess
+	mgr.systemPrompt = "be brief"
+	mgr.branches = []branch{{model: "gpt-5"}}
+
+	out := captureOutput(func() {
+		err = mgr.Send("question")
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if connects != 2 || len(sess.sent) != 2 {
+		t.Errorf("%d connections, %d sends; want a reconnect and the prompt sent again", connects, len(sess.sent))
+	}
+	if !strings.Contains(out, "reconnected") {
+		t.Errorf("output = %q, want a reconnected notice", out)
+	}
+	if mgr.LastReply() != "answer" || mgr.systemPrompt != "be brief" {
+		t.Errorf("after reconnecting: reply %q, system prompt %q; want both kept", mgr.LastReply(), mgr.systemPrompt)
+	}
+	if !mgr.branches[0].stale {
+		t.Error("branches should be marked stale after a reconnect")
+	}
+}
+
+// TestSendReconnectFails tests that a session that can't be recreated after
+// a reconnect is logged as a warning and the send fails
+func TestSendReconnectFails(t *testing.T) {
+	sess := &mockSession{err: fmt.Errorf("failed to write header: write: broken pipe")}
+	dead := &mockSDKClient{pingErr: fmt.Errorf("client not connected")}
+	connects := 0
+	cli, err := client.NewClientWithConnector(func() (client.ClientInterface, error) {
+		if connects++; connects == 1 {
+			return dead, nil
+		}
+		return &mockSDKClient{createError: fmt.Errorf("model not available")}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := NewManagerForTesting(cli)
+	mgr.session = sess
+	mgr.SetRetryPolicy(RetryPolicy{Attempts: 1})
+
+	var logged string
+	out := captureOutput(func() {
+		logged = captureLog(func() {
+			err = mgr.Send("question")
+		})
+	})
+	if err == nil {
+		t.Error("Send() error = nil, want the send to fail")
+	}
+	if !strings.Contains(logged, "level=WARN") || !strings.Contains(logged, "model not available") {
+		t.Errorf("log = %q, want a warning with the session error", logged)
+	}
+	if strings.Contains(out, "model not available") {
+		t.Errorf("output = %q, want the failure only in the log", out)
+	}
+}
+
+// TestSendFailsWithoutReconnect tests that a failure on a live connection is
+// reported without reconnecting
+func TestSendFailsWithoutReconnect(t *testing.T) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	sess := mgr.session.(*mockSession)
+	sess.err = fmt.Errorf("session error: model not available")
+
+	if err := mgr.Send("question"); err == nil || !strings.Contains(err.Error(), "model not available") {
+		t.Errorf("Send() error = %v, want the session error", err)
+	}
+	if sess.sendCount != 1 {
+		t.Errorf("sendCount = %d, want 1", sess.sendCount)
+	}
+}
+
 // TestIsUsingDaemon tests daemon flag delegation to client
 func TestIsUsingDaemon(t *testing.T) {
 	mockSDK := &mockSDKClient{}
@@ -0,0 +1,204 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultShellMaxTokens caps the output of a single !(command)
+	DefaultShellMaxTokens = 2000
+
+	// DefaultShellTimeout is how long a !(command) may run
+	DefaultShellTimeout = 30 * time.Second
+)
+
+// shellOperators make a command more than the program and arguments it
+// starts with, so it never runs without asking
+const shellOperators = ";&|<>`$()\n"
+
+// ShellPolicy controls the commands !(command) in a prompt may run
+type ShellPolicy struct {
+	// Allow lists commands that run without asking. An entry matches the
+	// command itself and the command with more arguments, so "git log"
+	// allows "git log -5 --oneline". Commands using shell operators such as
+	// ; | or $( ) always ask.
+	Allow []string
+	// MaxTokens caps the output of each command; 0 means
+	// DefaultShellMaxTokens
+	MaxTokens int64
+	// Timeout is how long a command may run; 0 means DefaultShellTimeout
+	Timeout time.Duration
+}
+
+// ShellConfirm asks the user whether to run a command that isn't allowed
+// by the policy
+type ShellConfirm func(command string) bool
+
+// shellRef is a !(command) token in a prompt
+type shellRef struct {
+	start, end int // byte range of the whole token
+	command    string
+}
+
+// runShell runs command through the platform shell and returns its
+// combined output; replaced in tests
+var runShell = func(ctx context.Context, command string) ([]byte, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	// Don't wait on children still holding the output open after a timeout
+	cmd.WaitDelay = time.Second
+	return cmd.CombinedOutput()
+}
+
+// SetShellPolicy controls the !(command) tokens in typed prompts. Commands
+// not allowed by policy run only once confirm agrees, and declining cancels
+// the prompt; with a nil confirm they are refused.
+func (m *Manager) SetShellPolicy(policy ShellPolicy, confirm ShellConfirm) {
+	m.shellPolicy, m.shellConfirm = policy, confirm
+}
+
+// runShellCommands runs each !(command) in text and puts its output in
+// place of the token
+func (m *Manager) runShellCommands(text string) (string, error) {
+	refs := findShellRefs(text)
+	if len(refs) == 0 {
+		return text, nil
+	}
+	policy := m.shellPolicy
+	for _, ref := range refs {
+		if allowedCommand(policy.Allow, ref.command) {
+			continue
+		}
+		if m.shellConfirm == nil {
+			return "", fmt.Errorf("!(%s) is not allowed by shell.allow", ref.command)
+		}
+		if !m.shellConfirm(ref.command) {
+			return "", ErrPromptCancelled
+		}
+	}
+
+	maxTokens := policy.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = DefaultShellMaxTokens
+	}
+	timeout := policy.Timeout
+	if timeout <= 0 {
+		timeout = DefaultShellTimeout
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, ref := range refs {
+		out, err := runCommand(ref.command, timeout)
+		if err != nil {
+			return "", err
+		}
+		m.notice("Ran %s (%d lines, ~%d tokens)\n", ref.command, strings.Count(out, "\n")+1, EstimateTokens(out))
+		b.WriteString(text[last:ref.start])
+		b.WriteString(truncateTail(out, maxTokens))
+		last = ref.end
+	}
+	b.WriteString(text[last:])
+	return b.String(), nil
+}
+
+// runCommand runs command with a time limit. A command that exits with an
+// error still returns its output, with the exit status noted after it.
+func runCommand(command string, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	out, err := runShell(ctx, command)
+	text := strings.TrimRight(string(out), "\r\n")
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("!(%s) did not finish within %s", command, timeout)
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		slog.Warn("shell command failed", "command", command, "status", exitErr.ExitCode())
+		return strings.TrimLeft(fmt.Sprintf("%s\n(exit status %d)", text, exitErr.ExitCode()), "\n"), nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("!(%s): %w", command, err)
+	}
+	return text, nil
+}
+
+// findShellRefs returns the !(command) tokens in text that start it or
+// follow whitespace. Parentheses inside the command must balance, except
+// within quotes; a token without its closing parenthesis is left alone.
+func findShellRefs(text string) []shellRef {
+	var refs []shellRef
+	for i := 0; i+1 < len(text); i++ {
+		if text[i] != '!' || text[i+1] != '(' || (i > 0 && !isSpace(text[i-1])) {
+			continue
+		}
+		end := closingParen(text, i+2)
+		if end < 0 {
+			continue
+		}
+		command := strings.TrimSpace(text[i+2 : end])
+		if command == "" {
+			continue
+		}
+		refs = append(refs, shellRef{start: i, end: end + 1, command: command})
+		i = end
+	}
+	return refs
+}
+
+// closingParen returns the index of the parenthesis closing the one before
+// start, or -1
+func closingParen(text string, start int) int {
+	depth := 0
+	var quote byte
+	for i := start; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// allowedCommand reports whether command matches an allow entry and uses no
+// shell operators
+func allowedCommand(allow []string, command string) bool {
+	if strings.ContainsAny(command, shellOperators) {
+		return false
+	}
+	command = strings.Join(strings.Fields(command), " ")
+	for _, entry := range allow {
+		entry = strings.Join(strings.Fields(entry), " ")
+		if entry != "" && (command == entry || strings.HasPrefix(command, entry+" ")) {
+			return true
+		}
+	}
+	return false
+}
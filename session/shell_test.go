@@ -0,0 +1,142 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFindShellRefs(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "none", text: "plain prompt", want: nil},
+		{name: "start", text: "!(git log -5) explain", want: []string{"git log -5"}},
+		{name: "after space", text: "explain !(git status)", want: []string{"git status"}},
+		{name: "two", text: "!(date) and !(pwd)", want: []string{"date", "pwd"}},
+		{name: "nested parens", text: "see !(echo $(date))", want: []string{"echo $(date)"}},
+		{name: "quoted paren", text: `see !(echo ")")`, want: []string{`echo ")"`}},
+		{name: "inside a word", text: "if x!(y) then", want: nil},
+		{name: "unclosed", text: "see !(git log", want: nil},
+		{name: "empty", text: "see !( )", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, ref := range findShellRefs(tt.text) {
+				got = append(got, ref.command)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findShellRefs(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllowedCommand(t *testing.T) {
+	allow := []string{"git log", "ls"}
+	tests := []struct {
+		command string
+		want    bool
+	}{
+		{"git log", true},
+		{"git log -5 --oneline", true},
+		{"git  log -5", true},
+		{"git logx", false},
+		{"git push", false},
+		{"ls -la", true},
+		{"git log; rm -rf x", false},
+		{"ls $(cat list)", false},
+		{"git log | head", false},
+	}
+	for _, tt := range tests {
+		if got := allowedCommand(allow, tt.command); got != tt.want {
+			t.Errorf("allowedCommand(%q) = %v, want %v", tt.command, got, tt.want)
+		}
+	}
+}
+
+// fakeShell replaces runShell with one that answers from outputs
+func fakeShell(t *testing.T, outputs map[string]string) *[]string {
+	t.Helper()
+	var ran []string
+	old := runShell
+	runShell = func(ctx context.Context, command string) ([]byte, error) {
+		ran = append(ran, command)
+		return []byte(outputs[command] + "\n"), nil
+	}
+	t.Cleanup(func() { runShell = old })
+	return &ran
+}
+
+func TestRunShellCommands(t *testing.T) {
+	ran := fakeShell(t, map[string]string{"git log -2": "abc first\ndef second", "date": "today"})
+
+	m := &Manager{}
+	m.SetShellPolicy(ShellPolicy{Allow: []string{"git log"}}, nil)
+	got, err := m.runShellCommands("Recent commits:\n!(git log -2)\nexplain them")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Recent commits:\nabc first\ndef second\nexplain them"; got != want {
+		t.Errorf("runShellCommands() = %q, want %q", got, want)
+	}
+
+	// Without a confirm, commands outside the allow list are refused
+	if _, err := m.runShellCommands("it is !(date)"); err == nil {
+		t.Error("runShellCommands() ran a command outside the allow list")
+	}
+
+	var asked []string
+	m.SetShellPolicy(ShellPolicy{}, func(command string) bool {
+		asked = append(asked, command)
+		return command == "date"
+	})
+	got, err = m.runShellCommands("it is !(date)")
+	if err != nil || got != "it is today" {
+		t.Errorf("runShellCommands() = %q, %v, want %q", got, err, "it is today")
+	}
+	// Declining any command cancels the prompt before anything runs
+	*ran = nil
+	if _, err := m.runShellCommands("!(date) !(git log -2)"); !errors.Is(err, ErrPromptCancelled) {
+		t.Errorf("runShellCommands() error = %v, want ErrPromptCancelled", err)
+	}
+	if len(*ran) != 0 {
+		t.Errorf("ran %q after a command was declined", *ran)
+	}
+	if want := []string{"date", "date", "git log -2"}; !reflect.DeepEqual(asked, want) {
+		t.Errorf("asked about %q, want %q", asked, want)
+	}
+}
+
+func TestRunShellCommands_Truncates(t *testing.T) {
+	fakeShell(t, map[string]string{"cat big": strings.Repeat("x", 1000)})
+	m := &Manager{}
+	m.SetShellPolicy(ShellPolicy{Allow: []string{"cat"}, MaxTokens: 10}, nil)
+	got, err := m.runShellCommands("!(cat big)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(got, strings.Repeat("x", 40)) || strings.Contains(got, strings.Repeat("x", 41)) {
+		t.Errorf("runShellCommands() = %q, want the first 40 bytes and a truncation marker", got)
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	got, err := runCommand("echo out; exit 3", time.Second)
+	if err != nil || got != "out\n(exit status 3)" {
+		t.Errorf("runCommand() = %q, %v, want output with the exit status", got, err)
+	}
+	if _, err := runCommand("sleep 5", 50*time.Millisecond); err == nil {
+		t.Error("runCommand() succeeded past its timeout")
+	}
+}
@@ -0,0 +1,170 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"unicode/utf8"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+// OversizeAction is the user's choice for a prompt that exceeds the context window
+type OversizeAction int
+
+const (
+	// OversizeSend sends the prompt unchanged
+	OversizeSend OversizeAction = iota
+	// OversizeCancel drops the prompt
+	OversizeCancel
+	// OversizeTruncateHead drops the beginning of the prompt, keeping the end
+	OversizeTruncateHead
+	// OversizeTruncateTail drops the end of the prompt, keeping the beginning
+	OversizeTruncateTail
+	// OversizeSummarize replaces the prompt with a model-generated summary
+	OversizeSummarize
+	// OversizeAttachFile moves the prompt into an attached file
+	OversizeAttachFile
+)
+
+// truncationMarker replaces the text removed by truncation
+const truncationMarker = "\n[... %d characters truncated ...]\n"
+
+// OversizeChooser decides what to do with a prompt estimated at `estimated`
+// tokens when only `available` tokens remain
+type OversizeChooser func(estimated, available int64) (OversizeAction, error)
+
+// AvailableTokens returns the tokens left in the context window, falling back
+// to the current model's context window size before the session has reported
+// usage. It returns 0 when neither is known.
+func (m *Manager) AvailableTokens() int64 {
+	if m.HasTokenLimit() {
+		return m.GetTokensLeft()
+	}
+	models, err := m.client.GetModels()
+	if err != nil {
+		return 0
+	}
+	for _, model := range models {
+		if model.ID == m.currentModel {
+			return int64(model.Capabilities.Limits.MaxContextWindowTokens)
+		}
+	}
+	return 0
+}
+
+// SizeGuard returns a prompt stage that warns before sending a prompt larger
+// than the remaining context window and applies the action chosen by choose
+func (m *Manager) SizeGuard(choose OversizeChooser) PromptStage {
+	return func(p *Prompt) error {
+		available := m.AvailableTokens()
+		estimated := EstimateTokens(p.Text)
+		if available <= 0 || estimated <= available {
+			return nil
+		}
+
+		action, err := choose(estimated, available)
+		if err != nil {
+			return err
+		}
+
+		// Leave a tenth of the window for the response
+		budget := available - available/10
+		switch action {
+		case OversizeSend:
+			return nil
+		case OversizeTruncateHead:
+			p.Text = truncateHead(p.Text, budget)
+		case OversizeTruncateTail:
+			p.Text = truncateTail(p.Text, budget)
+		case OversizeSummarize:
+			summary, err := m.summarize(p.Text, budget)
+			if err != nil {
+				return fmt.Errorf("failed to summarize prompt: %w", err)
+			}
+			p.Text = summary
+		case OversizeAttachFile:
+			return attachAsFile(p)
+		default:
+			return ErrPromptCancelled
+		}
+		return nil
+	}
+}
+
+// truncateHead keeps the end of text so it fits in maxTokens
+func truncateHead(text string, maxTokens int64) string {
+	keep := int(maxTokens) * charsPerToken
+	if keep >= len(text) {
+		return text
+	}
+	start := len(text) - keep
+	for start < len(text) && !utf8.RuneStart(text[start]) {
+		start++
+	}
+	return fmt.Sprintf(truncationMarker, start) + text[start:]
+}
+
+// truncateTail keeps the beginning of text so it fits in maxTokens
+func truncateTail(text string, maxTokens int64) string {
+	keep := int(maxTokens) * charsPerToken
+	if keep >= len(text) {
+		return text
+	}
+	end := keep
+	for end > 0 && !utf8.RuneStart(text[end]) {
+		end--
+	}
+	return text[:end] + fmt.Sprintf(truncationMarker, len(text)-end)
+}
+
+// summarize asks the current model, in a throwaway session, to condense text
+// to roughly maxTokens
+func (m *Manager) summarize(text string, maxTokens int64) (string, error) {
+	sess, err := m.client.CreateSession(&copilot.SessionConfig{Model: m.currentModel})
+	if err != nil {
+		return "", err
+	}
+	if sess == nil {
+		return "", fmt.Errorf("no session available for summarization")
+	}
+	defer sess.Destroy()
+
+	// Roughly three words per four tokens
+	words := maxTokens * 3 / 4
+	event, err := sess.SendAndWait(copilot.MessageOptions{
+		Prompt: fmt.Sprintf("Summarize the following text in at most %d words. "+
+			"Preserve instructions, questions, code identifiers, and error messages verbatim. "+
+			"Reply with the summary only.\n\n%s", words, text),
+	}, m.sdkTimeout())
+	if err != nil {
+		return "", err
+	}
+	if event == nil || event.Data.Content == nil {
+		return "", fmt.Errorf("model returned no summary")
+	}
+	return *event.Data.Content, nil
+}
+
+// attachAsFile writes the prompt to a temp file and attaches it, replacing
+// the prompt text with a pointer to the attachment
+func attachAsFile(p *Prompt) error {
+	f, err := os.CreateTemp("", "cocli-prompt-*.md")
+	if err != nil {
+		return fmt.Errorf("failed to create prompt file: %w", err)
+	}
+	if _, err := f.WriteString(p.Text); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return fmt.Errorf("failed to write prompt file: %w", err)
+	}
+	f.Close()
+
+	p.Attachments = append(p.Attachments, copilot.Attachment{
+		Type:        copilot.File,
+		Path:        f.Name(),
+		DisplayName: "prompt.md",
+	})
+	p.Text = "My full request is in the attached file prompt.md. Read it and respond to it."
+	p.OnSent(func() { os.Remove(f.Name()) })
+	return nil
+}
@@ -0,0 +1,170 @@
+package session
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+// createGuardedManager returns a manager with a mock session, a known token
+// budget, and a size guard that always picks action
+func createGuardedManager(tokensLeft int64, action OversizeAction) (*Manager, *int) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	mgr.tokenLimit = tokensLeft
+	mgr.currentTokens = 0
+	calls := 0
+	mgr.AddPromptStage(mgr.SizeGuard(func(estimated, available int64) (OversizeAction, error) {
+		calls++
+		return action, nil
+	}))
+	return mgr, &calls
+}
+
+func TestSizeGuard_FitsWithoutPrompting(t *testing.T) {
+	mgr, calls := createGuardedManager(100, OversizeCancel)
+
+	if err := mgr.Send(strings.Repeat("a", 100)); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if *calls != 0 {
+		t.Error("chooser should not be consulted for prompts that fit")
+	}
+}
+
+func TestSizeGuard_UnknownLimit(t *testing.T) {
+	mgr, calls := createGuardedManager(0, OversizeCancel)
+
+	if err := mgr.Send(strings.Repeat("a", 100000)); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if *calls != 0 {
+		t.Error("chooser should not be consulted when the window size is unknown")
+	}
+}
+
+func TestSizeGuard_Actions(t *testing.T) {
+	prompt := "BEGIN" + strings.Repeat("x", 1000) + "END"
+
+	tests := []struct {
+		name   string
+		action OversizeAction
+		check  func(t *testing.T, sent copilot.MessageOptions)
+	}{
+		{
+			name:   "send anyway",
+			action: OversizeSend,
+			check: func(t *testing.T, sent copilot.MessageOptions) {
+				if sent.Prompt != prompt {
+					t.Error("prompt should be sent unchanged")
+				}
+			},
+		},
+		{
+			name:   "truncate head",
+			action: OversizeTruncateHead,
+			check: func(t *testing.T, sent copilot.MessageOptions) {
+				if strings.Contains(sent.Prompt, "BEGIN") || !strings.HasSuffix(sent.Prompt, "END") {
+					t.Errorf("truncate head should keep only the end, got %q", sent.Prompt)
+				}
+				if !strings.Contains(sent.Prompt, "truncated") {
+					t.Error("truncated prompt should contain a marker")
+				}
+			},
+		},
+		{
+			name:   "truncate tail",
+			action: OversizeTruncateTail,
+			check: func(t *testing.T, sent copilot.MessageOptions) {
+				if !strings.HasPrefix(sent.Prompt, "BEGIN") || strings.Contains(sent.Prompt, "END") {
+					t.Errorf("truncate tail should keep only the beginning, got %q", sent.Prompt)
+				}
+			},
+		},
+		{
+			name:   "attach as file",
+			action: OversizeAttachFile,
+			check: func(t *testing.T, sent copilot.MessageOptions) {
+				if len(sent.Attachments) != 1 {
+					t.Fatalf("attachments = %d, want 1", len(sent.Attachments))
+				}
+				if strings.Contains(sent.Prompt, "BEGIN") {
+					t.Error("prompt text should be replaced by a pointer to the attachment")
+				}
+				if _, err := os.Stat(sent.Attachments[0].Path); !os.IsNotExist(err) {
+					t.Error("temp prompt file should be removed after sending")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr, calls := createGuardedManager(100, tt.action)
+			if err := mgr.Send(prompt); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+			if *calls != 1 {
+				t.Errorf("chooser calls = %d, want 1", *calls)
+			}
+			tt.check(t, mgr.session.(*mockSession).lastOptions)
+		})
+	}
+}
+
+func TestSizeGuard_Cancel(t *testing.T) {
+	mgr, _ := createGuardedManager(10, OversizeCancel)
+
+	err := mgr.Send(strings.Repeat("a", 1000))
+	if !errors.Is(err, ErrPromptCancelled) {
+		t.Errorf("Send() error = %v, want ErrPromptCancelled", err)
+	}
+	if mgr.session.(*mockSession).sendCount != 0 {
+		t.Error("cancelled prompt should not be sent")
+	}
+}
+
+func TestSizeGuard_SummarizeWithoutSession(t *testing.T) {
+	// The mock client returns no session, so summarization must fail cleanly
+	mgr, _ := createGuardedManager(10, OversizeSummarize)
+
+	if err := mgr.Send(strings.Repeat("a", 1000)); err == nil {
+		t.Error("Send() expected error when summarization is unavailable")
+	}
+}
+
+func TestAvailableTokens_FromModelInfo(t *testing.T) {
+	mgr := createTestManager(&mockSDKClient{
+		models: []copilot.ModelInfo{{
+			ID: "Claude Sonnet 4.5",
+			Capabilities: copilot.ModelCapabilities{
+				Limits: copilot.ModelLimits{MaxContextWindowTokens: 200000},
+			},
+		}},
+	})
+
+	captureOutput(func() {
+		if got := mgr.AvailableTokens(); got != 200000 {
+			t.Errorf("AvailableTokens() = %d, want 200000", got)
+		}
+	})
+}
+
+func TestTruncate_RuneBoundaries(t *testing.T) {
+	text := strings.Repeat("é", 50)
+
+	for _, got := range []string{truncateHead(text, 5), truncateTail(text, 5)} {
+		if !strings.Contains(got, "truncated") {
+			t.Errorf("expected truncation marker in %q", got)
+		}
+		if strings.ContainsRune(got, '�') || !isValidUTF8(got) {
+			t.Errorf("truncation split a rune: %q", got)
+		}
+	}
+}
+
+func isValidUTF8(s string) bool {
+	return strings.ToValidUTF8(s, "?") == s
+}
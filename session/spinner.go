@@ -0,0 +1,110 @@
+package session
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+// spinnerInterval is how often the spinner is redrawn
+const spinnerInterval = 100 * time.Millisecond
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// spinner shows that a reply is on its way until its first output arrives.
+// On a terminal it animates with the elapsed time and erases itself when
+// stopped; otherwise it writes one plain line.
+type spinner struct {
+	w       io.Writer
+	started time.Time
+
+	mu    sync.Mutex
+	label string
+
+	once sync.Once
+	quit chan struct{}
+	done chan struct{}
+}
+
+func startSpinner(w io.Writer, animate bool) *spinner {
+	s := &spinner{
+		w:       w,
+		started: time.Now(),
+		label:   "Thinking",
+		quit:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if !animate {
+		fmt.Fprintln(w, "Thinking...")
+		close(s.done)
+		return s
+	}
+	go s.run()
+	return s
+}
+
+func (s *spinner) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(spinnerInterval)
+	defer ticker.Stop()
+	for frame := 0; ; frame++ {
+		s.mu.Lock()
+		label := s.label
+		s.mu.Unlock()
+		elapsed := time.Since(s.started).Truncate(time.Second)
+		fmt.Fprintf(s.w, "\r\x1b[K%s %s... %s", spinnerFrames[frame%len(spinnerFrames)], label, elapsed)
+		select {
+		case <-s.quit:
+			fmt.Fprint(s.w, "\r\x1b[K")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// setLabel changes what the spinner says is happening
+func (s *spinner) setLabel(label string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.label = label
+	s.mu.Unlock()
+}
+
+// stop erases the spinner, returning once it is gone so output that follows
+// starts on a clean line. It is safe to call more than once.
+func (s *spinner) stop() {
+	if s == nil {
+		return
+	}
+	s.once.Do(func() { close(s.quit) })
+	<-s.done
+}
+
+// SetProgress shows a spinner on w between sending a prompt and the first
+// output of the reply, animated when animate is set (w is a terminal) and
+// as a plain line otherwise. A nil w turns it off.
+func (m *Manager) SetProgress(w io.Writer, animate bool) {
+	m.progress, m.animateProgress = w, animate
+}
+
+// updateSpinner moves the current reply's spinner along with its events,
+// stopping it once the reply produces output (text or a tool call)
+func (m *Manager) updateSpinner(event copilot.SessionEvent) {
+	m.respMu.Lock()
+	resp := m.resp
+	m.respMu.Unlock()
+	if resp == nil {
+		return
+	}
+	switch event.Type {
+	case copilot.AssistantReasoningDelta:
+		resp.spinner.setLabel("Reasoning")
+	case copilot.AssistantMessageDelta, copilot.ToolExecutionStart, copilot.SessionIdle, copilot.SessionError, copilot.Abort:
+		resp.spinner.stop()
+	}
+}
@@ -0,0 +1,88 @@
+package session
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+// syncBuffer is a bytes.Buffer safe for the spinner's goroutine
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestSpinner(t *testing.T) {
+	t.Run("animated", func(t *testing.T) {
+		out := &syncBuffer{}
+		s := startSpinner(out, true)
+		s.setLabel("Reasoning")
+		time.Sleep(2 * spinnerInterval)
+		s.stop()
+		s.stop() // stopping twice is harmless
+		got := out.String()
+		if !strings.Contains(got, "Reasoning... ") {
+			t.Errorf("spinner output = %q, want the label and elapsed time", got)
+		}
+		if !strings.HasSuffix(got, "\r\x1b[K") {
+			t.Errorf("spinner output = %q, want it erased when stopped", got)
+		}
+	})
+
+	t.Run("plain", func(t *testing.T) {
+		out := &syncBuffer{}
+		startSpinner(out, false).stop()
+		if got := out.String(); got != "Thinking...\n" {
+			t.Errorf("plain spinner output = %q, want one line without escapes", got)
+		}
+	})
+}
+
+func TestSendStopsSpinnerOnFirstDelta(t *testing.T) {
+	mgr := createTestManager(&mockSDKClient{})
+	out := &syncBuffer{}
+	mgr.SetProgress(out, true)
+	sess := &mockSession{block: make(chan struct{})}
+	mgr.session = sess
+
+	done := make(chan error, 1)
+	captureOutput(func() {
+		go func() { done <- mgr.Send("question") }()
+		waitFor(t, func() bool { return strings.Contains(out.String(), "Thinking") })
+
+		delta := "Hi"
+		mgr.handleEvent(copilot.SessionEvent{Type: copilot.AssistantMessageDelta, Data: copilot.Data{DeltaContent: &delta}})
+		if got := out.String(); !strings.HasSuffix(got, "\r\x1b[K") {
+			t.Errorf("spinner output = %q, want it erased by the first delta", got)
+		}
+		close(sess.block)
+		<-done
+	})
+}
+
+// waitFor polls cond until it holds or a second passes
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
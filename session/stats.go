@@ -0,0 +1,100 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ResponseStats times one reply, for the line /stats prints after it
+type ResponseStats struct {
+	Model string
+	// FirstToken is how long the reply's first text took to arrive; 0 if
+	// it streamed none
+	FirstToken time.Duration
+	// Duration is how long the whole reply took
+	Duration time.Duration
+	// Tokens is the reply's output tokens, estimated from its text when the
+	// server didn't report them
+	Tokens int64
+}
+
+// TokensPerSecond is the rate the reply streamed at, from its first token
+// to its end
+func (s ResponseStats) TokensPerSecond() float64 {
+	streaming := s.Duration - s.FirstToken
+	if streaming <= 0 {
+		streaming = s.Duration
+	}
+	if streaming <= 0 {
+		return 0
+	}
+	return float64(s.Tokens) / streaming.Seconds()
+}
+
+// String formats s as "first token 1.2s · 8.4s · 512 tokens · 61.0 tok/s ·
+// model"
+func (s ResponseStats) String() string {
+	var parts []string
+	if s.FirstToken > 0 {
+		parts = append(parts, "first token "+roundDuration(s.FirstToken).String())
+	}
+	parts = append(parts,
+		roundDuration(s.Duration).String(),
+		fmt.Sprintf("%d tokens", s.Tokens),
+		fmt.Sprintf("%.1f tok/s", s.TokensPerSecond()),
+		s.Model)
+	return strings.Join(parts, " · ")
+}
+
+// roundDuration keeps a tenth of a second, or milliseconds under a second
+func roundDuration(d time.Duration) time.Duration {
+	if d < time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(100 * time.Millisecond)
+}
+
+// SetShowStats turns the stats line after each reply on or off
+func (m *Manager) SetShowStats(on bool) {
+	m.showStats = on
+}
+
+// ShowStats reports whether a stats line is printed after each reply
+func (m *Manager) ShowStats() bool {
+	return m.showStats
+}
+
+// responseStats times the reply resp received
+func (m *Manager) responseStats(resp *response) ResponseStats {
+	m.respMu.Lock()
+	first := resp.firstOutput
+	text := resp.partial.String()
+	m.respMu.Unlock()
+
+	stats := ResponseStats{
+		Model:    m.currentModel,
+		Duration: time.Since(resp.started),
+		Tokens:   m.turnOutputTokens,
+	}
+	if !first.IsZero() {
+		stats.FirstToken = first.Sub(resp.started)
+	}
+	if stats.Tokens == 0 {
+		stats.Tokens = EstimateTokens(text)
+	}
+	return stats
+}
+
+// printStats writes a dimmed stats line below a reply
+func (m *Manager) printStats(stats ResponseStats) {
+	if m.muted || m.quiet || m.jsonOut != nil {
+		return
+	}
+	line := "[" + stats.String() + "]"
+	if m.renderer == nil || m.renderer.plain {
+		fmt.Println(line)
+		return
+	}
+	m.renderer.output(toolStyle + line + ansiReset + "\n")
+}
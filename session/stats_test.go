@@ -0,0 +1,118 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResponseStats_String(t *testing.T) {
+	tests := []struct {
+		name  string
+		stats ResponseStats
+		want  string
+	}{
+		{
+			name:  "streamed",
+			stats: ResponseStats{Model: "gpt-4.1", FirstToken: 1234 * time.Millisecond, Duration: 5234 * time.Millisecond, Tokens: 200},
+			want:  "first token 1.2s · 5.2s · 200 tokens · 50.0 tok/s · gpt-4.1",
+		},
+		{
+			name:  "nothing streamed",
+			stats: ResponseStats{Model: "gpt-4.1", Duration: 400 * time.Millisecond},
+			want:  "400ms · 0 tokens · 0.0 tok/s · gpt-4.1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.stats.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponseStats_TokensPerSecond(t *testing.T) {
+	tests := []struct {
+		name  string
+		stats ResponseStats
+		want  float64
+	}{
+		{name: "from first token", stats: ResponseStats{FirstToken: time.Second, Duration: 3 * time.Second, Tokens: 100}, want: 50},
+		{name: "whole reply", stats: ResponseStats{Duration: 2 * time.Second, Tokens: 100}, want: 50},
+		{name: "no time", stats: ResponseStats{Tokens: 100}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.stats.TokensPerSecond(); got != tt.want {
+				t.Errorf("TokensPerSecond() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSend_PrintsStats(t *testing.T) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	out := captureOutput(func() {
+		if err := mgr.Send("hi"); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if strings.Contains(out, "tok/s") {
+		t.Errorf("stats printed while off: %q", out)
+	}
+
+	mgr.SetShowStats(true)
+	mgr.session.(*mockSession).reply = "hello"
+	out = captureOutput(func() {
+		if err := mgr.Send("hi"); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if !strings.Contains(out, "tok/s · "+mgr.GetCurrentModel()+"]") {
+		t.Errorf("output %q, want a stats line", out)
+	}
+}
+
+func TestResponseStats_FirstToken(t *testing.T) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	mgr.turnOutputTokens = 0
+	resp := mgr.beginResponse()
+	mgr.addPartial("Hello there, how are you?")
+	mgr.endResponse(resp)
+
+	stats := mgr.responseStats(resp)
+	if resp.firstOutput.IsZero() || stats.FirstToken > stats.Duration {
+		t.Errorf("FirstToken = %s, Duration = %s", stats.FirstToken, stats.Duration)
+	}
+	if stats.Tokens != EstimateTokens("Hello there, how are you?") {
+		t.Errorf("Tokens = %d, want the streamed text's estimate", stats.Tokens)
+	}
+	mgr.turnOutputTokens = 42
+	if stats := mgr.responseStats(resp); stats.Tokens != 42 {
+		t.Errorf("Tokens = %d, want the reported output tokens", stats.Tokens)
+	}
+}
+
+func TestSend_Quiet(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(file, []byte("a note\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	mgr.AddPromptStage(mgr.AttachFiles(FilePolicy{}))
+	mgr.SetShowStats(true)
+	mgr.SetQuiet(true)
+	mgr.session.(*mockSession).reply = "hello"
+
+	out := captureOutput(func() {
+		if err := mgr.Send("summarize @" + file); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if strings.Contains(out, "Attached") || strings.Contains(out, "tok/s") {
+		t.Errorf("output = %q, want only the reply", out)
+	}
+}
@@ -0,0 +1,123 @@
+package session
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// baseSystemMessage is appended to the model's system message in every session
+const baseSystemMessage = "Always format responses using markdown with code blocks."
+
+// SystemPrompt returns the custom system instructions, if any
+func (m *Manager) SystemPrompt() string {
+	return m.systemPrompt
+}
+
+// SetSystemPrompt changes the custom system instructions. The SDK fixes the
+// system message when a session is created, so the session is recreated and
+// the conversation so far is carried into it: verbatim when it fits in half
+// the context window, otherwise as a model-written summary.
+func (m *Manager) SetSystemPrompt(text string) error {
+	previous := m.systemPrompt
+	m.systemPrompt = strings.TrimSpace(text)
+	if err := m.recreateSession(); err != nil {
+		m.systemPrompt = previous
+		return err
+	}
+	return nil
+}
+
+// MCPServers returns the names of the MCP servers sessions are given
+func (m *Manager) MCPServers() []string {
+	names := make([]string, 0, len(m.mcpServers))
+	for name := range m.mcpServers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ProjectContext returns the description of the project added to the
+// system message, if any
+func (m *Manager) ProjectContext() string {
+	return m.projectContext
+}
+
+// SetProjectContext changes the description of the project (such as its
+// file tree) added to the system message; "" removes it. Like
+// SetSystemPrompt, it recreates the session, carrying the conversation over.
+func (m *Manager) SetProjectContext(text string) error {
+	previous := m.projectContext
+	m.projectContext = strings.TrimSpace(text)
+	if err := m.recreateSession(); err != nil {
+		m.projectContext = previous
+		return err
+	}
+	return nil
+}
+
+// recreateSession creates the session again with the current system
+// message, carrying the conversation into it
+func (m *Manager) recreateSession() error {
+	if m.session == nil {
+		return nil
+	}
+	if limit := m.AvailableTokens() / 2; limit > 0 && EstimateTokens(transcript(m.turns)) > limit {
+		summary, err := m.summarizeConversation()
+		if err != nil {
+			return err
+		}
+		m.contextSummary = summary
+		m.turns = nil
+	}
+	return m.Create(m.currentModel)
+}
+
+// instructions returns the base formatting instructions, those from the
+// config files and the project's instruction files, the custom system
+// prompt and the project context
+func (m *Manager) instructions() string {
+	text := baseSystemMessage
+	if m.configPrompt != "" {
+		text += "\n\n" + m.configPrompt
+	}
+	if m.projectInstructions != "" {
+		text += "\n\n" + m.projectInstructions
+	}
+	if m.systemPrompt != "" {
+		text += "\n\n" + m.systemPrompt
+	}
+	if m.projectContext != "" {
+		text += "\n\n" + m.projectContext
+	}
+	return text
+}
+
+// systemMessage builds the session system message: the base formatting
+// instructions, the custom system prompt, the project context, agent mode's
+// instructions, and any conversation carried over from a previous session
+func (m *Manager) systemMessage() string {
+	return m.systemMessageWith(m.turns)
+}
+
+// systemMessageWith builds the system message for a session that carries
+// turns as its recent conversation
+func (m *Manager) systemMessageWith(turns []Turn) string {
+	var b strings.Builder
+	b.WriteString(m.instructions())
+	if m.agentMode {
+		b.WriteString("\n\n")
+		b.WriteString(agentInstructions)
+	}
+	if m.contextSummary != "" || len(turns) > 0 {
+		b.WriteString("\n\nThis conversation continues an earlier one.")
+	}
+	if m.contextSummary != "" {
+		fmt.Fprintf(&b, " Summary of the earlier conversation:\n\n%s", m.contextSummary)
+	}
+	if len(turns) > 0 {
+		fmt.Fprintf(&b, "\n\nTranscript of the most recent messages:\n\n%s", transcript(turns))
+	}
+	return b.String()
+}
@@ -0,0 +1,147 @@
+package session
+
+import (
+	"strings"
+	"testing"
+
+	"atulm/cocli/client"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+func TestSend_RecordsTurns(t *testing.T) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	mgr.session.(*mockSession).reply = "Hi there"
+
+	captureOutput(func() {
+		if err := mgr.Send("hello"); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	})
+
+	turns := mgr.Turns()
+	if len(turns) != 1 {
+		t.Fatalf("Turns() = %d turns, want 1", len(turns))
+	}
+	if turns[0].Prompt != "hello" || turns[0].Response != "Hi there" {
+		t.Errorf("turn = %+v, want hello / Hi there", turns[0])
+	}
+}
+
+func TestSystemMessage(t *testing.T) {
+	mgr := createTestManager(&mockSDKClient{})
+	if got := mgr.systemMessage(); got != baseSystemMessage {
+		t.Errorf("systemMessage() = %q, want only the base message", got)
+	}
+
+	mgr.systemPrompt = "Answer like a pirate."
+	mgr.contextSummary = "We discussed ships."
+	mgr.turns = []Turn{{Prompt: "Where is the treasure?", Response: "Buried."}}
+
+	got := mgr.systemMessage()
+	for _, want := range []string{baseSystemMessage, "Answer like a pirate.", "We discussed ships.", "User: Where is the treasure?", "Assistant: Buried."} {
+		if !strings.Contains(got, want) {
+			t.Errorf("systemMessage() missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestNewManager_ConfigInstructionsAndMCPServers(t *testing.T) {
+	sdk := &mockSDKClient{}
+	servers := map[string]copilot.MCPServerConfig{"github": {"type": "http", "url": "https://example.com/mcp"}}
+	mgr, err := NewManager(client.NewClientWithSDK(sdk), WithInstructions(" Use tabs. "), WithMCPServers(servers))
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	mgr.systemPrompt = "Answer like a pirate."
+
+	got := mgr.systemMessage()
+	if want := baseSystemMessage + "\n\nUse tabs.\n\nAnswer like a pirate."; got != want {
+		t.Errorf("systemMessage() = %q, want %q", got, want)
+	}
+	if sdk.created == nil || len(sdk.created.MCPServers) != 1 {
+		t.Fatalf("session config = %+v, want the MCP servers passed on", sdk.created)
+	}
+	if names := mgr.MCPServers(); len(names) != 1 || names[0] != "github" {
+		t.Errorf("MCPServers() = %v, want [github]", names)
+	}
+}
+
+func TestSetSystemPrompt_CarriesTranscript(t *testing.T) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	mgr.turns = []Turn{{Prompt: "q1", Response: "a1"}}
+	mgr.tokenLimit = 100000
+
+	if err := mgr.SetSystemPrompt("  Be brief.  "); err != nil {
+		t.Fatalf("SetSystemPrompt() error = %v", err)
+	}
+
+	if mgr.SystemPrompt() != "Be brief." {
+		t.Errorf("SystemPrompt() = %q, want %q", mgr.SystemPrompt(), "Be brief.")
+	}
+	if len(mgr.Turns()) != 1 {
+		t.Errorf("Turns() = %d, want history kept", len(mgr.Turns()))
+	}
+	if sent := mgr.session.(*mockSession).sent; len(sent) != 0 {
+		t.Errorf("sent %q, want no model calls for a transcript that fits", sent)
+	}
+	if !strings.Contains(mgr.systemMessage(), "User: q1") {
+		t.Error("systemMessage() does not carry the transcript")
+	}
+}
+
+func TestSetSystemPrompt_SummarizesLongHistory(t *testing.T) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	sess := mgr.session.(*mockSession)
+	sess.reply = "short summary"
+	mgr.turns = []Turn{{Prompt: strings.Repeat("x", 1000), Response: strings.Repeat("y", 1000)}}
+	mgr.tokenLimit = 200
+
+	if err := mgr.SetSystemPrompt("Be brief."); err != nil {
+		t.Fatalf("SetSystemPrompt() error = %v", err)
+	}
+
+	if len(sess.sent) != 1 || sess.sent[0] != compactPrompt {
+		t.Errorf("sent = %q, want one summarization request", sess.sent)
+	}
+	if len(mgr.Turns()) != 0 || mgr.contextSummary != "short summary" {
+		t.Errorf("turns = %d, summary = %q; want history replaced by the summary", len(mgr.Turns()), mgr.contextSummary)
+	}
+}
+
+func TestSetSystemPrompt_RestoresOnFailure(t *testing.T) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	mgr.systemPrompt = "old"
+	mgr.turns = []Turn{{Prompt: strings.Repeat("x", 1000)}}
+	mgr.tokenLimit = 200 // forces a summary, which the mock can't produce
+
+	if err := mgr.SetSystemPrompt("new"); err == nil {
+		t.Fatal("SetSystemPrompt() error = nil, want summarization failure")
+	}
+	if mgr.SystemPrompt() != "old" {
+		t.Errorf("SystemPrompt() = %q after failure, want %q", mgr.SystemPrompt(), "old")
+	}
+}
+
+func TestSetProjectContext(t *testing.T) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	mgr.turns = []Turn{{Prompt: "q1", Response: "a1"}}
+	mgr.tokenLimit = 100000
+
+	if err := mgr.SetProjectContext("Project files:\nmain.go\n"); err != nil {
+		t.Fatalf("SetProjectContext() error = %v", err)
+	}
+	got := mgr.systemMessage()
+	for _, want := range []string{"Project files:\nmain.go", "User: q1"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("systemMessage() missing %q:\n%s", want, got)
+		}
+	}
+
+	if err := mgr.SetProjectContext(""); err != nil {
+		t.Fatalf("SetProjectContext() error = %v", err)
+	}
+	if strings.Contains(mgr.systemMessage(), "Project files:") {
+		t.Error("systemMessage() still has the project context after clearing it")
+	}
+}
@@ -0,0 +1,71 @@
+package session
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// teeFile receives the raw markdown of every reply, for building notes
+// while replies are rendered as usual
+type teeFile struct {
+	path string
+	file *os.File
+	// wrote is set once a reply was appended, so later ones are set apart
+	wrote bool
+}
+
+// StartTee appends the raw markdown of every reply from now on to path,
+// replacing a file replies were appended to before
+func (m *Manager) StartTee(path string) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to open %s: %w", path, err)
+		}
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	// A file that already has notes gets a blank line before the next reply
+	info, err := f.Stat()
+	wrote := err == nil && info.Size() > 0
+	if err := m.StopTee(); err != nil {
+		slog.Warn("failed to close the previous tee file", "err", err)
+	}
+	m.tee = teeFile{path: path, file: f, wrote: wrote}
+	return nil
+}
+
+// StopTee stops appending replies to a file
+func (m *Manager) StopTee() error {
+	f := m.tee.file
+	m.tee = teeFile{}
+	if f == nil {
+		return nil
+	}
+	return f.Close()
+}
+
+// TeePath returns the file replies are appended to, or "" when there is none
+func (m *Manager) TeePath() string {
+	return m.tee.path
+}
+
+// teeReply appends reply to the tee file, if there is one
+func (m *Manager) teeReply(reply string) {
+	if m.tee.file == nil || reply == "" {
+		return
+	}
+	text := strings.TrimRight(reply, "\n") + "\n"
+	if m.tee.wrote {
+		text = "\n" + text
+	}
+	if _, err := m.tee.file.WriteString(text); err != nil {
+		slog.Warn("reply not written", "file", m.tee.path, "err", err)
+		return
+	}
+	m.tee.wrote = true
+}
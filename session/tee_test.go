@@ -0,0 +1,47 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+func TestTee(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes", "session.md")
+	mgr := createTestManager(&mockSDKClient{})
+	reply := func(content string) *copilot.SessionEvent {
+		return &copilot.SessionEvent{Type: "assistant.message", Data: copilot.Data{Content: &content}}
+	}
+
+	mgr.recordTurn("before", reply("not saved"))
+	if err := mgr.StartTee(path); err != nil {
+		t.Fatalf("StartTee() error = %v", err)
+	}
+	if mgr.TeePath() != path {
+		t.Errorf("TeePath() = %q, want %q", mgr.TeePath(), path)
+	}
+	mgr.recordTurn("one", reply("# First\n\n- a point\n"))
+	mgr.recordTurn("two", reply("```go\nfmt.Println()\n```"))
+	if err := mgr.StopTee(); err != nil {
+		t.Fatalf("StopTee() error = %v", err)
+	}
+	mgr.recordTurn("after", reply("not saved either"))
+
+	// Starting again appends after what is there
+	if err := mgr.StartTee(path); err != nil {
+		t.Fatalf("StartTee() error = %v", err)
+	}
+	mgr.recordTurn("three", reply("Third"))
+	mgr.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# First\n\n- a point\n\n```go\nfmt.Println()\n```\n\nThird\n"
+	if string(data) != want {
+		t.Errorf("file = %q, want %q", data, want)
+	}
+}
@@ -0,0 +1,39 @@
+package session
+
+import (
+	"errors"
+	"time"
+)
+
+// DefaultRequestTimeout is how long Send waits for a reply to finish before
+// giving up on it
+const DefaultRequestTimeout = 10 * time.Minute
+
+// noTimeout stands in for "no limit" in SDK calls, which treat 0 as their
+// own default of a minute
+const noTimeout = 100 * 365 * 24 * time.Hour
+
+// ErrRequestTimedOut is returned by Send when the reply didn't finish
+// within the request timeout; the caller may offer to retry the prompt
+var ErrRequestTimedOut = errors.New("request timed out")
+
+// SetRequestTimeout sets how long Send waits for a reply to finish; 0 waits
+// indefinitely
+func (m *Manager) SetRequestTimeout(d time.Duration) {
+	m.requestTimeout = d
+}
+
+// RequestTimeout returns how long Send waits for a reply, 0 meaning
+// indefinitely
+func (m *Manager) RequestTimeout() time.Duration {
+	return m.requestTimeout
+}
+
+// sdkTimeout is the timeout for SendAndWait calls outside Send, such as
+// compaction, which don't enforce the request timeout themselves
+func (m *Manager) sdkTimeout() time.Duration {
+	if m.requestTimeout <= 0 {
+		return noTimeout
+	}
+	return m.requestTimeout
+}
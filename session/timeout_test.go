@@ -0,0 +1,51 @@
+package session
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSend_RequestTimeout(t *testing.T) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	mgr.SetRequestTimeout(20 * time.Millisecond)
+	sess := mgr.session.(*mockSession)
+	sess.block = make(chan struct{})
+	defer close(sess.block)
+
+	var sendErr error
+	captureOutput(func() {
+		result := make(chan error, 1)
+		go func() { result <- mgr.Send("hello") }()
+		select {
+		case sendErr = <-result:
+		case <-time.After(time.Second):
+			t.Error("Send() did not return after the request timeout")
+		}
+	})
+
+	if !errors.Is(sendErr, ErrRequestTimedOut) {
+		t.Errorf("Send() error = %v, want ErrRequestTimedOut", sendErr)
+	}
+	if !sess.aborted {
+		t.Error("the timed-out turn was not aborted")
+	}
+	if len(mgr.Turns()) != 0 {
+		t.Error("a timed-out turn should not be recorded")
+	}
+}
+
+func TestSdkTimeout(t *testing.T) {
+	mgr := createTestManager(&mockSDKClient{})
+	if got := mgr.RequestTimeout(); got != DefaultRequestTimeout {
+		t.Errorf("RequestTimeout() = %s, want the default %s", got, DefaultRequestTimeout)
+	}
+	if got := mgr.sdkTimeout(); got != DefaultRequestTimeout {
+		t.Errorf("sdkTimeout() = %s, want the request timeout", got)
+	}
+	// 0 would be the SDK's one-minute default
+	mgr.SetRequestTimeout(0)
+	if got := mgr.sdkTimeout(); got != noTimeout {
+		t.Errorf("sdkTimeout() with no limit = %s, want noTimeout", got)
+	}
+}
@@ -0,0 +1,143 @@
+package session
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode"
+
+	"atulm/cocli/models"
+)
+
+// titleWords is the most words a generated title keeps
+const titleWords = 5
+
+// maxTitleLength caps titles, generated or given with SetTitle
+const maxTitleLength = 80
+
+// titlePrompt asks for a title for the first exchange of a conversation
+const titlePrompt = "Write a title of at most 5 words for the conversation below. Reply with the title only, without quotes or a full stop.\n\n%s"
+
+// SetAutoTitle turns on asking a cheap model for a title after the first
+// exchange of each conversation
+func (m *Manager) SetAutoTitle(on bool) {
+	m.autoTitle = on
+}
+
+// Title returns the current conversation's title, or "" before it has one
+func (m *Manager) Title() string {
+	m.adoptTitle()
+	return m.title
+}
+
+// SetTitle names the current conversation, replacing a generated title, and
+// saves it
+func (m *Manager) SetTitle(title string) error {
+	title = cleanTitle(title, 0)
+	if title == "" {
+		return fmt.Errorf("title is empty")
+	}
+	m.title = title
+	if m.conversationID == "" {
+		return nil
+	}
+	return m.saveConversation()
+}
+
+// generateTitle asks a cheap model in the background for a title for the
+// conversation's first exchange. The title is taken up by adoptTitle once
+// it arrives, if the conversation still has none.
+func (m *Manager) generateTitle(turn Turn) {
+	if !m.autoTitle || m.title != "" || m.conversationID == "" || turn.Response == "" {
+		return
+	}
+	id, model := m.conversationID, m.titleModel()
+	prompt := fmt.Sprintf(titlePrompt, transcript([]Turn{turn}))
+	go func() {
+		result := m.ask(model, "", &Prompt{Text: prompt})
+		if result.Err != nil {
+			slog.Debug("no title generated", "model", model, "err", result.Err)
+			return
+		}
+		title := cleanTitle(result.Reply, titleWords)
+		if title == "" {
+			return
+		}
+		m.titleMu.Lock()
+		defer m.titleMu.Unlock()
+		if m.generatedTitles == nil {
+			m.generatedTitles = make(map[string]string)
+		}
+		m.generatedTitles[id] = title
+	}()
+}
+
+// adoptTitle gives the current conversation the title generated for it, if
+// one arrived and it has none yet
+func (m *Manager) adoptTitle() {
+	m.titleMu.Lock()
+	defer m.titleMu.Unlock()
+	title, ok := m.generatedTitles[m.conversationID]
+	if !ok {
+		return
+	}
+	delete(m.generatedTitles, m.conversationID)
+	if m.title == "" {
+		m.title = title
+	}
+}
+
+// titleModel returns the cheapest available model, for generating titles,
+// or the current model when prices aren't known
+func (m *Manager) titleModel() string {
+	list, err := m.client.GetModels()
+	if err != nil {
+		return m.currentModel
+	}
+	if info, ok := models.Cheapest(list); ok {
+		return info.ID
+	}
+	return m.currentModel
+}
+
+// cleanTitle makes s a one-line title of at most maxWords words (0 for no
+// limit), without the quotes or labels models tend to add
+func cleanTitle(s string, maxWords int) string {
+	s, _, _ = strings.Cut(strings.TrimSpace(s), "\n")
+	s = strings.TrimPrefix(s, "Title:")
+	s = strings.Trim(s, " \t*\"'`.")
+	words := strings.Fields(s)
+	if maxWords > 0 && len(words) > maxWords {
+		words = words[:maxWords]
+	}
+	s = strings.Join(words, " ")
+	if runes := []rune(s); len(runes) > maxTitleLength {
+		s = strings.TrimSpace(string(runes[:maxTitleLength]))
+	}
+	return s
+}
+
+// maxSlugLength caps the title part of file names
+const maxSlugLength = 40
+
+// TitleSlug turns a title into a file name part such as
+// "fix-the-flaky-login-test", or "" for an empty title
+func TitleSlug(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			dash = true
+			continue
+		}
+		if b.Len() >= maxSlugLength {
+			break
+		}
+		if dash && b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		dash = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
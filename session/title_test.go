@@ -0,0 +1,117 @@
+package session
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"atulm/cocli/client"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+func TestSend_GeneratesTitle(t *testing.T) {
+	var mu sync.Mutex
+	var asked []*copilot.SessionConfig
+	orig := newThrowawaySession
+	newThrowawaySession = func(_ *client.Client, cfg *copilot.SessionConfig) (throwawaySession, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		asked = append(asked, cfg)
+		return &compareSession{mockSession: mockSession{reply: "\"Fixing the flaky login test.\"\n"}}, nil
+	}
+	defer func() { newThrowawaySession = orig }()
+
+	dir := t.TempDir()
+	mgr := createTestManager(&mockSDKClient{models: []copilot.ModelInfo{
+		{ID: "claude-sonnet-4.5", Billing: &copilot.ModelBilling{Multiplier: 1}},
+		{ID: "gpt-4.1", Billing: &copilot.ModelBilling{Multiplier: 0}},
+	}})
+	mgr.session = &mockSession{reply: "Wait for the server before logging in."}
+	mgr.SetHistoryDir(dir)
+	mgr.SetAutoTitle(true)
+
+	if err := mgr.Send("why does the login test fail sometimes?"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for mgr.Title() == "" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := mgr.Title(); got != "Fixing the flaky login test" {
+		t.Fatalf("Title() = %q, want the generated title", got)
+	}
+	mu.Lock()
+	if len(asked) != 1 || asked[0].Model != "gpt-4.1" {
+		t.Errorf("title asked of %d sessions, want one on the cheapest model", len(asked))
+	}
+	mu.Unlock()
+
+	// Later turns don't ask again, and the title is saved with them
+	if err := mgr.Send("thanks"); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	if len(asked) != 1 {
+		t.Errorf("title asked %d times, want once", len(asked))
+	}
+	mu.Unlock()
+	saved, err := loadConversation(dir, mgr.ConversationID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Title != "Fixing the flaky login test" {
+		t.Errorf("saved title = %q", saved.Title)
+	}
+
+	// /title replaces it
+	if err := mgr.SetTitle("  Login race  "); err != nil {
+		t.Fatalf("SetTitle() error = %v", err)
+	}
+	conversations, err := mgr.SavedConversations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conversations) != 1 || conversations[0].Title != "Login race" {
+		t.Errorf("SavedConversations() = %+v, want the new title", conversations)
+	}
+	if err := mgr.SetTitle(" "); err == nil {
+		t.Error("SetTitle(\" \") succeeded, want an error")
+	}
+}
+
+func TestCleanTitle(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "Debugging Go Test Failures", want: "Debugging Go Test Failures"},
+		{in: "\"Setting up CI caching.\"", want: "Setting up CI caching"},
+		{in: "Title: Refactoring the parser", want: "Refactoring the parser"},
+		{in: "**Bold title**\nwith an explanation", want: "Bold title"},
+		{in: "one two three four five six seven", want: "one two three four five"},
+		{in: "  ", want: ""},
+	}
+	for _, tt := range tests {
+		if got := cleanTitle(tt.in, titleWords); got != tt.want {
+			t.Errorf("cleanTitle(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTitleSlug(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{title: "Fix the flaky login test", want: "fix-the-flaky-login-test"},
+		{title: "C++ / Go: interop?", want: "c-go-interop"},
+		{title: "", want: ""},
+		{title: "A very long title that goes on and on past the limit", want: "a-very-long-title-that-goes-on-and-on-pa"},
+	}
+	for _, tt := range tests {
+		if got := TitleSlug(tt.title); got != tt.want {
+			t.Errorf("TitleSlug(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
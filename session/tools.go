@@ -0,0 +1,242 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+// ToolMode decides which tool calls the model may make
+type ToolMode string
+
+const (
+	// ToolsOff denies every tool call that needs permission
+	ToolsOff ToolMode = "off"
+	// ToolsAsk lets reads run and asks before shell commands, file writes
+	// and other tools that may change something
+	ToolsAsk ToolMode = "ask"
+	// ToolsAuto approves every tool call
+	ToolsAuto ToolMode = "auto"
+)
+
+// ParseToolMode parses "auto", "ask" or "off"
+func ParseToolMode(s string) (ToolMode, error) {
+	switch mode := ToolMode(strings.ToLower(s)); mode {
+	case ToolsOff, ToolsAsk, ToolsAuto:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown tool mode %q (use auto, ask or off)", s)
+}
+
+// readOnlyToolKinds are the permission kinds ToolsAsk approves without asking
+var readOnlyToolKinds = map[string]bool{"read": true, "url": true}
+
+// toolSummaryKeys are argument and request fields that best describe what a
+// tool call acts on, most telling first
+var toolSummaryKeys = []string{"fullCommandText", "command", "fileName", "path", "url", "pattern", "query", "toolName"}
+
+// maxToolSummary caps the width of a tool call's summary
+const maxToolSummary = 100
+
+// toolStyle dims tool call lines so they stand apart from the reply
+const toolStyle = diffMeta
+
+// ToolRequest is a tool call asking for permission to run
+type ToolRequest struct {
+	// Kind is the permission kind: "shell", "write", "read", "url" or "mcp"
+	Kind string
+	// Summary is the command, file or URL the call acts on
+	Summary string
+	// Intention is the model's explanation of the call, if it gave one
+	Intention string
+}
+
+// ToolApprover asks the user whether a tool call may run
+type ToolApprover func(req ToolRequest) bool
+
+// SetToolMode sets which tool calls are approved. Changes apply to the
+// next permission request, including in the current session.
+func (m *Manager) SetToolMode(mode ToolMode) {
+	m.toolMode = mode
+}
+
+// ToolMode returns which tool calls are approved
+func (m *Manager) ToolMode() ToolMode {
+	if m.toolMode == "" {
+		return ToolsOff
+	}
+	return m.toolMode
+}
+
+// SetToolApprover sets how ToolsAsk asks the user about a tool call; without
+// one, those calls are denied
+func (m *Manager) SetToolApprover(fn ToolApprover) {
+	m.toolApprover = fn
+}
+
+// handlePermission answers the server's request to run a tool according to
+// the tool mode
+func (m *Manager) handlePermission(req copilot.PermissionRequest, _ copilot.PermissionInvocation) (copilot.PermissionRequestResult, error) {
+	tr := toolRequest(req)
+	switch m.ToolMode() {
+	case ToolsAuto:
+		return copilot.PermissionRequestResult{Kind: "approved"}, nil
+	case ToolsAsk:
+		if readOnlyToolKinds[tr.Kind] {
+			return copilot.PermissionRequestResult{Kind: "approved"}, nil
+		}
+		if m.toolApprover == nil {
+			break
+		}
+		if m.askTool(tr) {
+			return copilot.PermissionRequestResult{Kind: "approved"}, nil
+		}
+		return copilot.PermissionRequestResult{Kind: "denied-interactively-by-user"}, nil
+	}
+	m.printToolLine(fmt.Sprintf("✗ %s denied (/tools is %s): %s", tr.Kind, m.ToolMode(), tr.Summary))
+	return copilot.PermissionRequestResult{Kind: "denied-no-approval-rule-and-could-not-request-from-user"}, nil
+}
+
+// askTool puts the reply on hold while the approver asks the user
+func (m *Manager) askTool(tr ToolRequest) bool {
+	m.respMu.Lock()
+	resp := m.resp
+	m.respMu.Unlock()
+	if resp != nil {
+		resp.spinner.stop()
+	}
+	if m.renderer != nil && m.jsonOut == nil {
+		m.renderer.Flush()
+		m.renderer.output("\n")
+	}
+	ok := m.toolApprover(tr)
+	// Time spent answering doesn't count as a stalled reply
+	m.touchResponse()
+	return ok
+}
+
+// toolRequest describes a permission request for the user
+func toolRequest(req copilot.PermissionRequest) ToolRequest {
+	tr := ToolRequest{Kind: req.Kind, Summary: summarizeArgs(req.Extra)}
+	if intention, ok := req.Extra["intention"].(string); ok {
+		tr.Intention = intention
+	}
+	return tr
+}
+
+// renderToolEvent prints the start and result of a tool call
+func (m *Manager) renderToolEvent(event copilot.SessionEvent) {
+	switch event.Type {
+	case copilot.ToolExecutionStart:
+		name := "tool"
+		if event.Data.ToolName != nil {
+			name = *event.Data.ToolName
+		}
+		line := "⚙ " + name
+		if args := summarizeArgs(event.Data.Arguments); args != "" {
+			line += " " + args
+		}
+		m.printToolLine(line)
+	case copilot.ToolExecutionComplete:
+		m.printToolLine("  " + toolResult(event.Data))
+	}
+}
+
+// printToolLine writes a dimmed line about a tool call after any reply
+// text so far
+func (m *Manager) printToolLine(line string) {
+	if m.muted || m.quiet || m.jsonOut != nil {
+		return
+	}
+	if m.renderer == nil {
+		fmt.Println(line)
+		return
+	}
+	m.renderer.Flush()
+	if m.renderer.plain {
+		m.renderer.output("\n" + line)
+	} else {
+		m.renderer.output("\n" + toolStyle + line + ansiReset)
+	}
+}
+
+// toolResult summarizes how a tool call ended
+func toolResult(data copilot.Data) string {
+	if data.Success != nil && !*data.Success {
+		msg := "failed"
+		if data.Error != nil && data.Error.ErrorClass != nil {
+			msg = data.Error.ErrorClass.Message
+		} else if data.Error != nil && data.Error.String != nil {
+			msg = *data.Error.String
+		}
+		return "✗ " + truncateSummary(firstLine(msg))
+	}
+	if data.Result == nil || strings.TrimSpace(data.Result.Content) == "" {
+		return "✓ done"
+	}
+	content := strings.TrimSpace(data.Result.Content)
+	summary := "✓ " + truncateSummary(firstLine(content))
+	if n := strings.Count(content, "\n") + 1; n > 1 {
+		summary += fmt.Sprintf(" (%d lines)", n)
+	}
+	return summary
+}
+
+// summarizeArgs describes a tool call's arguments in one line: the value of
+// the most telling field if there is one, otherwise compact JSON
+func summarizeArgs(args interface{}) string {
+	if args == nil {
+		return ""
+	}
+	if fields, ok := args.(map[string]interface{}); ok {
+		for _, key := range toolSummaryKeys {
+			if s, ok := fields[key].(string); ok && s != "" {
+				return truncateSummary(firstLine(s))
+			}
+		}
+		if len(fields) == 0 {
+			return ""
+		}
+		// Describe the arguments with sorted keys so the line is stable
+		keys := make([]string, 0, len(fields))
+		for k := range fields {
+			if k != "kind" && k != "toolCallId" && k != "intention" {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		parts := make([]string, 0, len(keys))
+		for _, k := range keys {
+			v, _ := json.Marshal(fields[k])
+			parts = append(parts, k+"="+string(v))
+		}
+		return truncateSummary(strings.Join(parts, " "))
+	}
+	if s, ok := args.(string); ok {
+		return truncateSummary(firstLine(s))
+	}
+	data, err := json.Marshal(args)
+	if err != nil {
+		return ""
+	}
+	return truncateSummary(string(data))
+}
+
+func firstLine(s string) string {
+	line, rest, _ := strings.Cut(s, "\n")
+	if rest != "" {
+		return line + " …"
+	}
+	return line
+}
+
+func truncateSummary(s string) string {
+	runes := []rune(s)
+	if len(runes) <= maxToolSummary {
+		return s
+	}
+	return string(runes[:maxToolSummary-1]) + "…"
+}
@@ -0,0 +1,121 @@
+package session
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	copilot "github.com/github/copilot-sdk/go"
+)
+
+func TestParseToolMode(t *testing.T) {
+	for _, s := range []string{"auto", "ASK", "off"} {
+		if _, err := ParseToolMode(s); err != nil {
+			t.Errorf("ParseToolMode(%q) error = %v", s, err)
+		}
+	}
+	if _, err := ParseToolMode("always"); err == nil {
+		t.Error("ParseToolMode(always) succeeded, want error")
+	}
+}
+
+func TestHandlePermission(t *testing.T) {
+	shell := copilot.PermissionRequest{Kind: "shell", Extra: map[string]interface{}{
+		"kind": "shell", "fullCommandText": "git push", "intention": "Publish the branch",
+	}}
+	read := copilot.PermissionRequest{Kind: "read", Extra: map[string]interface{}{"kind": "read", "path": "main.go"}}
+
+	tests := []struct {
+		name     string
+		mode     ToolMode
+		approve  *bool
+		req      copilot.PermissionRequest
+		want     string
+		wantAsks int
+	}{
+		{name: "default denies", req: shell, want: "denied-no-approval-rule-and-could-not-request-from-user"},
+		{name: "auto approves", mode: ToolsAuto, req: shell, want: "approved"},
+		{name: "ask lets reads run", mode: ToolsAsk, req: read, want: "approved"},
+		{name: "ask approved", mode: ToolsAsk, approve: boolPtr(true), req: shell, want: "approved", wantAsks: 1},
+		{name: "ask denied", mode: ToolsAsk, approve: boolPtr(false), req: shell, want: "denied-interactively-by-user", wantAsks: 1},
+		{name: "ask without approver", mode: ToolsAsk, req: shell, want: "denied-no-approval-rule-and-could-not-request-from-user"},
+		{name: "off", mode: ToolsOff, approve: boolPtr(true), req: read, want: "denied-no-approval-rule-and-could-not-request-from-user"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			m := &Manager{}
+			m.renderer, _ = NewStreamingMarkdownRenderer(WithWriter(&out), WithPlainText())
+			m.SetToolMode(tt.mode)
+			var asked []ToolRequest
+			if tt.approve != nil {
+				m.SetToolApprover(func(req ToolRequest) bool {
+					asked = append(asked, req)
+					return *tt.approve
+				})
+			}
+			got, err := m.handlePermission(tt.req, copilot.PermissionInvocation{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Kind != tt.want {
+				t.Errorf("result = %q, want %q", got.Kind, tt.want)
+			}
+			if len(asked) != tt.wantAsks {
+				t.Fatalf("asked %d times, want %d", len(asked), tt.wantAsks)
+			}
+			if tt.wantAsks > 0 && (asked[0] != ToolRequest{Kind: "shell", Summary: "git push", Intention: "Publish the branch"}) {
+				t.Errorf("asked about %+v", asked[0])
+			}
+		})
+	}
+}
+
+func TestRenderToolEvent(t *testing.T) {
+	var out bytes.Buffer
+	m := &Manager{}
+	m.renderer, _ = NewStreamingMarkdownRenderer(WithWriter(&out), WithPlainText())
+
+	name, ok, failed := "bash", true, false
+	m.renderEvent(copilot.SessionEvent{Type: copilot.ToolExecutionStart, Data: copilot.Data{
+		ToolName:  &name,
+		Arguments: map[string]interface{}{"command": "go test ./...", "timeout": 60},
+	}})
+	m.renderEvent(copilot.SessionEvent{Type: copilot.ToolExecutionComplete, Data: copilot.Data{
+		Success: &ok,
+		Result:  &copilot.Result{Content: "ok  atulm/cocli\nok  atulm/cocli/git"},
+	}})
+	msg := "exit status 1"
+	m.renderEvent(copilot.SessionEvent{Type: copilot.ToolExecutionComplete, Data: copilot.Data{
+		Success: &failed,
+		Error:   &copilot.ErrorUnion{ErrorClass: &copilot.ErrorClass{Message: msg}},
+	}})
+
+	want := "\n⚙ bash go test ./...\n  ✓ ok  atulm/cocli … (2 lines)\n  ✗ exit status 1"
+	if got := out.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestSummarizeArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args interface{}
+		want string
+	}{
+		{name: "nil", args: nil, want: ""},
+		{name: "telling key", args: map[string]interface{}{"path": "a.go", "view_range": []int{1, 2}}, want: "a.go"},
+		{name: "other keys sorted", args: map[string]interface{}{"b": 2, "a": "x"}, want: `a="x" b=2`},
+		{name: "string", args: "line one\nline two", want: "line one …"},
+		{name: "long", args: map[string]interface{}{"command": strings.Repeat("x", 200)}, want: strings.Repeat("x", maxToolSummary-1) + "…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := summarizeArgs(tt.args); got != tt.want {
+				t.Errorf("summarizeArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func boolPtr(b bool) *bool { return &b }
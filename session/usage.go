@@ -0,0 +1,99 @@
+package session
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// ModelUsage totals what this session sent to one model
+type ModelUsage struct {
+	Model        string
+	Messages     int
+	InputTokens  int64
+	OutputTokens int64
+	// PremiumRequests is the sum of the model's multiplier over its messages
+	PremiumRequests float64
+	// Streaming is the time spent waiting for and streaming replies
+	Streaming time.Duration
+	// RateLimits counts the times the API throttled the messages
+	RateLimits int
+}
+
+// Tokens returns the input and output tokens combined
+func (u ModelUsage) Tokens() int64 {
+	return u.InputTokens + u.OutputTokens
+}
+
+// add accumulates other into u
+func (u *ModelUsage) add(other ModelUsage) {
+	u.Messages += other.Messages
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.PremiumRequests += other.PremiumRequests
+	u.Streaming += other.Streaming
+	u.RateLimits += other.RateLimits
+}
+
+// Usage returns per-model statistics for everything sent since the manager
+// was created, in order of first use. Model switches, /compact and /resume
+// don't reset them.
+func (m *Manager) Usage() []ModelUsage {
+	return append([]ModelUsage(nil), m.usage...)
+}
+
+// OnRequest registers a function called with the usage of each message once
+// its reply has finished or been cancelled (Messages is 1)
+func (m *Manager) OnRequest(fn func(ModelUsage)) {
+	m.requestHooks = append(m.requestHooks, fn)
+}
+
+// recordUsage adds a message sent at sentAt to the current model's totals
+// and reports it to the OnRequest hooks
+func (m *Manager) recordUsage(sentAt time.Time) {
+	m.addUsage(ModelUsage{
+		Model:           m.currentModel,
+		Messages:        1,
+		InputTokens:     m.turnInputTokens,
+		OutputTokens:    m.turnOutputTokens,
+		PremiumRequests: m.currentMultiplier,
+		Streaming:       time.Since(sentAt),
+		RateLimits:      m.turnRateLimits,
+	})
+}
+
+// addUsage adds one message's usage to its model's totals and reports it to
+// the OnRequest hooks
+func (m *Manager) addUsage(turn ModelUsage) {
+	for _, fn := range m.requestHooks {
+		fn(turn)
+	}
+	for i := range m.usage {
+		if m.usage[i].Model == turn.Model {
+			m.usage[i].add(turn)
+			return
+		}
+	}
+	m.usage = append(m.usage, turn)
+}
+
+// WriteUsageTable writes usage as a table with one row per model and, when
+// there are several, a total row
+func WriteUsageTable(w io.Writer, usage []ModelUsage) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Model\tMessages\tInput\tOutput\tTokens\tPremium\tStreaming")
+	row := func(u ModelUsage) {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%.2f\t%s\n", u.Model, u.Messages, u.InputTokens, u.OutputTokens, u.Tokens(), u.PremiumRequests, u.Streaming.Round(time.Second))
+	}
+	var total ModelUsage
+	for _, u := range usage {
+		row(u)
+		total.add(u)
+	}
+	if len(usage) > 1 {
+		total.Model = "Total"
+		row(total)
+	}
+	return tw.Flush()
+}
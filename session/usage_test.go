@@ -0,0 +1,106 @@
+package session
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUsage_PerModel(t *testing.T) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	mgr.currentModel, mgr.currentMultiplier = "gpt-5", 1
+	if err := mgr.Send("one"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	mgr.currentModel, mgr.currentMultiplier = "claude-haiku-4.5", 0.33
+	for _, prompt := range []string{"two", "three"} {
+		if err := mgr.Send(prompt); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	usage := mgr.Usage()
+	if len(usage) != 2 {
+		t.Fatalf("Usage() = %+v, want a row per model", usage)
+	}
+	if usage[0].Model != "gpt-5" || usage[0].Messages != 1 || usage[0].PremiumRequests != 1 {
+		t.Errorf("usage[0] = %+v, want 1 gpt-5 message costing 1", usage[0])
+	}
+	if usage[1].Model != "claude-haiku-4.5" || usage[1].Messages != 2 || usage[1].PremiumRequests != 0.66 {
+		t.Errorf("usage[1] = %+v, want 2 haiku messages costing 0.66", usage[1])
+	}
+}
+
+func TestRecordUsage_Tokens(t *testing.T) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	mgr.currentModel = "gpt-5"
+	mgr.turnInputTokens, mgr.turnOutputTokens = 100, 20
+	mgr.recordUsage(time.Now().Add(-3 * time.Second))
+	mgr.turnInputTokens, mgr.turnOutputTokens = 150, 30
+	mgr.recordUsage(time.Now().Add(-2 * time.Second))
+
+	u := mgr.Usage()[0]
+	if u.InputTokens != 250 || u.OutputTokens != 50 || u.Tokens() != 300 {
+		t.Errorf("usage = %+v, want 250 in and 50 out", u)
+	}
+	if u.Streaming < 5*time.Second {
+		t.Errorf("Streaming = %v, want at least 5s", u.Streaming)
+	}
+}
+
+func TestWriteUsageTable(t *testing.T) {
+	tests := []struct {
+		name      string
+		usage     []ModelUsage
+		wantTotal bool
+	}{
+		{name: "one model", usage: []ModelUsage{{Model: "gpt-5", Messages: 2, InputTokens: 10, OutputTokens: 5, PremiumRequests: 2, Streaming: 4 * time.Second}}},
+		{
+			name: "two models",
+			usage: []ModelUsage{
+				{Model: "gpt-5", Messages: 2, InputTokens: 10, OutputTokens: 5, PremiumRequests: 2},
+				{Model: "gpt-4.1", Messages: 1, InputTokens: 3, OutputTokens: 1},
+			},
+			wantTotal: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteUsageTable(&buf, tt.usage); err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if !strings.HasPrefix(lines[0], "Model") || !strings.Contains(lines[1], "gpt-5") {
+				t.Fatalf("table = %q, want a header and a gpt-5 row", buf.String())
+			}
+			if fields := strings.Fields(lines[1]); strings.Join(fields[1:6], " ") != "2 10 5 15 2.00" {
+				t.Errorf("row = %q, want 2 messages, 10+5 tokens and 2.00 premium", lines[1])
+			}
+			last := strings.Fields(lines[len(lines)-1])
+			if gotTotal := last[0] == "Total"; gotTotal != tt.wantTotal {
+				t.Errorf("last row = %q, want total row %v", lines[len(lines)-1], tt.wantTotal)
+			}
+			if tt.wantTotal && strings.Join(last[1:5], " ") != "3 13 6 19" {
+				t.Errorf("total row = %q, want the sums", lines[len(lines)-1])
+			}
+		})
+	}
+}
+
+func TestOnRequest(t *testing.T) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	mgr.currentModel, mgr.currentMultiplier = "gpt-5", 1
+	var got []ModelUsage
+	mgr.OnRequest(func(u ModelUsage) { got = append(got, u) })
+
+	for _, prompt := range []string{"one", "two"} {
+		if err := mgr.Send(prompt); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+	if len(got) != 2 || got[1].Model != "gpt-5" || got[1].Messages != 1 || got[1].PremiumRequests != 1 {
+		t.Errorf("requests = %+v, want one report per message", got)
+	}
+}
@@ -0,0 +1,93 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"atulm/cocli/git"
+)
+
+var (
+	// varRefRegex matches {{name}} references in a prompt
+	varRefRegex = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+	// varNameRegex matches names usable with SetVar
+	varNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// builtinVars are always available in prompts; they are evaluated when a
+// prompt uses them
+var builtinVars = map[string]func() string{
+	"cwd": func() string {
+		dir, _ := os.Getwd()
+		return dir
+	},
+	"date": func() string {
+		return time.Now().Format("2006-01-02")
+	},
+	"git_branch": func() string {
+		return gitBranch()
+	},
+}
+
+// gitBranch returns the current directory's git branch; replaced in tests
+var gitBranch = git.Branch
+
+// BuiltinVars returns the names of the built-in prompt variables
+func BuiltinVars() []string {
+	names := make([]string, 0, len(builtinVars))
+	for name := range builtinVars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetVar sets a variable that {{name}} in a prompt is replaced with. It
+// shadows a built-in of the same name. An empty value removes it.
+func (m *Manager) SetVar(name, value string) error {
+	if !varNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid variable name %q (use letters, digits and _)", name)
+	}
+	if value == "" {
+		delete(m.vars, name)
+		return nil
+	}
+	if m.vars == nil {
+		m.vars = make(map[string]string)
+	}
+	m.vars[name] = value
+	return nil
+}
+
+// Vars returns the variables set with SetVar
+func (m *Manager) Vars() map[string]string {
+	vars := make(map[string]string, len(m.vars))
+	for name, value := range m.vars {
+		vars[name] = value
+	}
+	return vars
+}
+
+// expandVars replaces {{name}} references in text with the variables set
+// with SetVar or the built-ins. Unknown names are left as typed, so
+// templates in prompts survive.
+func (m *Manager) expandVars(text string) string {
+	if !strings.Contains(text, "{{") {
+		return text
+	}
+	return varRefRegex.ReplaceAllStringFunc(text, func(ref string) string {
+		name := varRefRegex.FindStringSubmatch(ref)[1]
+		if value, ok := m.vars[name]; ok {
+			return value
+		}
+		if builtin, ok := builtinVars[name]; ok {
+			return builtin()
+		}
+		return ref
+	})
+}
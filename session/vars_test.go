@@ -0,0 +1,63 @@
+package session
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestExpandVars(t *testing.T) {
+	oldBranch := gitBranch
+	gitBranch = func() string { return "main" }
+	defer func() { gitBranch = oldBranch }()
+	cwd, _ := os.Getwd()
+	date := time.Now().Format("2006-01-02")
+
+	m := &Manager{}
+	if err := m.SetVar("svc", "payments"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetVar("date", "yesterday"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "no vars", text: "plain prompt", want: "plain prompt"},
+		{name: "user var", text: "check {{svc}} logs", want: "check payments logs"},
+		{name: "spaces", text: "check {{ svc }}", want: "check payments"},
+		{name: "repeated", text: "{{svc}}/{{svc}}", want: "payments/payments"},
+		{name: "builtins", text: "{{cwd}} on {{git_branch}}", want: cwd + " on main"},
+		{name: "user var shadows builtin", text: "{{date}}", want: "yesterday"},
+		{name: "unknown kept", text: "{{ .Name }} and {{missing}}", want: "{{ .Name }} and {{missing}}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.expandVars(tt.text); got != tt.want {
+				t.Errorf("expandVars(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+
+	if err := m.SetVar("date", ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.expandVars("{{date}}"); got != date {
+		t.Errorf("after removing date, expandVars = %q, want %q", got, date)
+	}
+}
+
+func TestSetVarInvalidName(t *testing.T) {
+	m := &Manager{}
+	for _, name := range []string{"", "1st", "a-b", "a b"} {
+		if err := m.SetVar(name, "x"); err == nil {
+			t.Errorf("SetVar(%q) succeeded, want error", name)
+		}
+	}
+	if len(m.Vars()) != 0 {
+		t.Errorf("Vars() = %v, want none", m.Vars())
+	}
+}
@@ -0,0 +1,193 @@
+package session
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultStallTimeout is how long a response may go without a delta before
+// it is reported as stalled
+const DefaultStallTimeout = 30 * time.Second
+
+var (
+	// ErrResponseCancelled is returned by Send when the response was aborted
+	ErrResponseCancelled = errors.New("response cancelled")
+
+	// ErrResponseStalled is returned by Send when a stalled response was
+	// aborted; the caller may offer to retry the prompt
+	ErrResponseStalled = errors.New("response stalled and was cancelled")
+)
+
+// stallWatchdog reports a response that stops streaming. It is armed by the
+// first delta and reset by every delta after it, so slow first tokens are not
+// mistaken for a stall.
+type stallWatchdog struct {
+	timeout time.Duration
+	onStall func(time.Duration)
+
+	mu      sync.Mutex
+	timer   *time.Timer
+	stalled bool
+	stopped bool
+}
+
+func newStallWatchdog(timeout time.Duration, onStall func(time.Duration)) *stallWatchdog {
+	return &stallWatchdog{timeout: timeout, onStall: onStall}
+}
+
+// touch records a delta, (re)arming the timer
+func (w *stallWatchdog) touch() {
+	if w == nil || w.timeout <= 0 {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stopped {
+		return
+	}
+	w.stalled = false
+	if w.timer == nil {
+		w.timer = time.AfterFunc(w.timeout, w.fire)
+		return
+	}
+	w.timer.Reset(w.timeout)
+}
+
+// fire marks the response stalled and notifies the handler
+func (w *stallWatchdog) fire() {
+	w.mu.Lock()
+	if w.stopped {
+		w.mu.Unlock()
+		return
+	}
+	w.stalled = true
+	w.mu.Unlock()
+	w.onStall(w.timeout)
+}
+
+// isStalled reports whether the response is currently stalled
+func (w *stallWatchdog) isStalled() bool {
+	if w == nil {
+		return false
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stalled
+}
+
+// stop disarms the watchdog
+func (w *stallWatchdog) stop() {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stopped = true
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+}
+
+// response tracks the in-flight reply to a Send
+type response struct {
+	watchdog *stallWatchdog
+	// spinner shows progress until the first output; nil when off
+	spinner *spinner
+	abort   chan struct{}
+	once    sync.Once
+	// partial is the reply streamed so far; guarded by Manager.respMu
+	partial strings.Builder
+	// started is when the prompt was sent and firstOutput when the reply's
+	// first text arrived, for /stats; firstOutput is guarded by respMu
+	started     time.Time
+	firstOutput time.Time
+	// rateLimited is set by a session error event that throttled the
+	// reply, with the wait it asked for, if any; guarded by respMu
+	rateLimited bool
+	retryAfter  time.Duration
+}
+
+// cancel signals Send to abort the response
+func (r *response) cancel() {
+	r.once.Do(func() { close(r.abort) })
+}
+
+// SetStallTimeout sets how long a response may go without output before it
+// is reported as stalled; 0 disables the watchdog
+func (m *Manager) SetStallTimeout(d time.Duration) {
+	m.stallTimeout = d
+}
+
+// Abort cancels the response currently being streamed. It reports whether
+// a response was in flight.
+func (m *Manager) Abort() bool {
+	m.respMu.Lock()
+	resp := m.resp
+	m.respMu.Unlock()
+	if resp == nil {
+		return false
+	}
+	resp.cancel()
+	return true
+}
+
+// beginResponse starts tracking a reply and arms its stall watchdog
+func (m *Manager) beginResponse() *response {
+	resp := &response{
+		watchdog: newStallWatchdog(m.stallTimeout, func(idle time.Duration) {
+			m.notice("\n[stalled: no output for %s, still waiting... press Ctrl+C to cancel]\n", idle)
+		}),
+		abort:   make(chan struct{}),
+		started: time.Now(),
+	}
+	if m.progress != nil && !m.muted && m.jsonOut == nil {
+		resp.spinner = startSpinner(m.progress, m.animateProgress)
+	}
+	m.respMu.Lock()
+	m.resp = resp
+	m.respMu.Unlock()
+	return resp
+}
+
+// endResponse stops tracking the current reply
+func (m *Manager) endResponse(resp *response) {
+	resp.watchdog.stop()
+	resp.spinner.stop()
+	m.respMu.Lock()
+	if m.resp == resp {
+		m.resp = nil
+	}
+	m.respMu.Unlock()
+}
+
+// addPartial records streamed reply text, to keep it if the reply is cut
+// short
+func (m *Manager) addPartial(text string) {
+	m.respMu.Lock()
+	defer m.respMu.Unlock()
+	if m.resp != nil {
+		if m.resp.firstOutput.IsZero() {
+			m.resp.firstOutput = time.Now()
+		}
+		m.resp.partial.WriteString(text)
+	}
+}
+
+// partialReply returns the reply resp streamed before it ended
+func (m *Manager) partialReply(resp *response) string {
+	m.respMu.Lock()
+	defer m.respMu.Unlock()
+	return resp.partial.String()
+}
+
+// touchResponse records streamed output for the stall watchdog
+func (m *Manager) touchResponse() {
+	m.respMu.Lock()
+	resp := m.resp
+	m.respMu.Unlock()
+	if resp != nil {
+		resp.watchdog.touch()
+	}
+}
@@ -0,0 +1,140 @@
+package session
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStallWatchdog(t *testing.T) {
+	var fired atomic.Int32
+	w := newStallWatchdog(20*time.Millisecond, func(time.Duration) { fired.Add(1) })
+
+	// Not armed until the first delta
+	time.Sleep(40 * time.Millisecond)
+	if fired.Load() != 0 || w.isStalled() {
+		t.Fatal("watchdog fired before the first delta")
+	}
+
+	// Deltas arriving in time keep it quiet
+	for i := 0; i < 5; i++ {
+		w.touch()
+		time.Sleep(5 * time.Millisecond)
+	}
+	if fired.Load() != 0 {
+		t.Fatal("watchdog fired while deltas were arriving")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if fired.Load() != 1 || !w.isStalled() {
+		t.Fatalf("fired = %d, stalled = %v; want 1, true", fired.Load(), w.isStalled())
+	}
+
+	// A late delta clears the stall
+	w.touch()
+	if w.isStalled() {
+		t.Error("isStalled() = true after output resumed")
+	}
+
+	w.stop()
+	time.Sleep(40 * time.Millisecond)
+	if fired.Load() != 1 {
+		t.Errorf("watchdog fired after stop (fired = %d)", fired.Load())
+	}
+}
+
+func TestStallWatchdog_Disabled(t *testing.T) {
+	w := newStallWatchdog(0, func(time.Duration) { t.Error("disabled watchdog fired") })
+	w.touch()
+	time.Sleep(10 * time.Millisecond)
+	w.stop()
+}
+
+// startBlockedSend sends a prompt on a session that never answers and
+// returns a channel with Send's result
+func startBlockedSend(t *testing.T, mgr *Manager) (*mockSession, <-chan error) {
+	t.Helper()
+	sess := mgr.session.(*mockSession)
+	sess.block = make(chan struct{})
+	t.Cleanup(func() { close(sess.block) })
+
+	result := make(chan error, 1)
+	go func() { result <- mgr.Send("hello") }()
+
+	deadline := time.Now().Add(time.Second)
+	for !mgr.Abort() {
+		if time.Now().After(deadline) {
+			t.Fatal("Send never started a response")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return sess, result
+}
+
+func TestSend_Abort(t *testing.T) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+
+	var sess *mockSession
+	var sendErr error
+	captureOutput(func() {
+		var result <-chan error
+		sess, result = startBlockedSend(t, mgr)
+		select {
+		case sendErr = <-result:
+		case <-time.After(time.Second):
+			t.Error("Send() did not return after Abort")
+		}
+	})
+
+	if !errors.Is(sendErr, ErrResponseCancelled) {
+		t.Errorf("Send() error = %v, want ErrResponseCancelled", sendErr)
+	}
+	if !sess.aborted {
+		t.Error("session was not aborted")
+	}
+	if mgr.Abort() {
+		t.Error("Abort() = true with no response in flight")
+	}
+}
+
+func TestSend_AbortAfterStall(t *testing.T) {
+	mgr := createTestManagerWithSession(&mockSDKClient{})
+	mgr.SetStallTimeout(10 * time.Millisecond)
+	sess := mgr.session.(*mockSession)
+	sess.block = make(chan struct{})
+	defer close(sess.block)
+
+	var sendErr error
+	out := captureOutput(func() {
+		result := make(chan error, 1)
+		go func() { result <- mgr.Send("hello") }()
+		// Wait for the response to start, stream one delta, then go quiet
+		for {
+			mgr.respMu.Lock()
+			started := mgr.resp != nil
+			mgr.respMu.Unlock()
+			if started {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		mgr.touchResponse()
+		time.Sleep(50 * time.Millisecond)
+		mgr.Abort()
+
+		select {
+		case sendErr = <-result:
+		case <-time.After(time.Second):
+			t.Error("Send() did not return after Abort")
+		}
+	})
+
+	if !errors.Is(sendErr, ErrResponseStalled) {
+		t.Errorf("Send() error = %v, want ErrResponseStalled", sendErr)
+	}
+	if !strings.Contains(out, "stalled") {
+		t.Errorf("output %q, want a stall indicator", out)
+	}
+}
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// lockSuffix is appended to a state file's path to name its lock file
+const lockSuffix = ".lock"
+
+// ErrLocked is returned by TryLock when another process holds the lock
+var ErrLocked = errors.New("file is locked by another process")
+
+// FileLock is an advisory lock on a state file, held through a sibling
+// "<path>.lock" file so the state file itself can be replaced atomically
+type FileLock struct {
+	f *os.File
+}
+
+// Lock blocks until it holds an exclusive lock for path
+func Lock(path string) (*FileLock, error) {
+	return acquire(path, true, true)
+}
+
+// RLock blocks until it holds a shared (read) lock for path
+func RLock(path string) (*FileLock, error) {
+	return acquire(path, false, true)
+}
+
+// TryLock takes an exclusive lock for path without waiting, returning
+// ErrLocked if another process holds it
+func TryLock(path string) (*FileLock, error) {
+	return acquire(path, true, false)
+}
+
+// acquire opens the lock file for path and locks it
+func acquire(path string, exclusive, wait bool) (*FileLock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path+lockSuffix, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f, exclusive, wait); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &FileLock{f: f}, nil
+}
+
+// Unlock releases the lock
+func (l *FileLock) Unlock() error {
+	if l == nil || l.f == nil {
+		return nil
+	}
+	err := unlockFile(l.f)
+	if closeErr := l.f.Close(); err == nil {
+		err = closeErr
+	}
+	l.f = nil
+	return err
+}
+
+// WithLock runs fn while holding an exclusive lock for path
+func WithLock(path string, fn func() error) error {
+	lock, err := Lock(path)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	return fn()
+}
+
+// WriteFileAtomic writes data to a temp file in path's directory and renames
+// it over path, so readers never see a partially written file
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	// Removing after a successful rename is a harmless no-op
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LockedWrite atomically replaces path with data while holding its lock
+func LockedWrite(path string, data []byte, perm os.FileMode) error {
+	return WithLock(path, func() error {
+		return WriteFileAtomic(path, data, perm)
+	})
+}
+
+// LockedRead reads path while holding a shared lock
+func LockedRead(path string) ([]byte, error) {
+	lock, err := RLock(path)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+	return os.ReadFile(path)
+}
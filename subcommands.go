@@ -0,0 +1,499 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"atulm/cocli/config"
+	"atulm/cocli/models"
+	"atulm/cocli/server"
+	"atulm/cocli/session"
+
+	"golang.org/x/term"
+)
+
+// subcommand is a `cocli <name> ...` command
+type subcommand struct {
+	name string
+	// usage shows the arguments after the name
+	usage   string
+	summary string
+	// prompt is set when the arguments are prompt text, so global flags are
+	// only taken from before it
+	prompt bool
+	// run runs the command with the arguments after its name, or with all
+	// of them for a flag such as -p, and returns the process exit code
+	run func(args []string) int
+}
+
+// subcommands lists the commands in the order help shows them; it is filled
+// in by init because help refers to it
+var subcommands []*subcommand
+
+func init() {
+	subcommands = []*subcommand{
+		{name: "chat", usage: "[prompt]", summary: "Start an interactive session, sending prompt first (the default)", prompt: true, run: runChat},
+		{name: "ask", usage: "[--model m] [--json] [question]", summary: "Answer one question, from the arguments or stdin, with exit codes for plugins", prompt: true, run: runAsk},
+		{name: "gen", usage: "--out file [--block N] [--force] [--model m] [prompt]", summary: "Write the code block of an answer to a file", prompt: true, run: runGen},
+		{name: "-p", usage: "[--json] [prompt]", summary: "Answer one prompt, from the arguments or stdin, and exit", prompt: true, run: runPrint},
+		{name: "server", usage: "start|stop|status|logs|install|uninstall|token|run", summary: "Manage the background daemon", run: runServer},
+		{name: "models", usage: "list [--refresh] [--vision] [--cheap] [--json]", summary: "List the available models", run: runModels},
+		{name: "sessions", usage: "prune", summary: "Remove the saved conversations beyond the history limits in the config", run: runSessions},
+		{name: "auth", usage: "[status]", summary: "Show who the copilot CLI is signed in as", run: runAuth},
+		{name: "config", usage: "get [key] | set <key> <value> | validate [path] | path | env", summary: "Read, change or check the config file", run: runConfig},
+		{name: "serve-api", usage: "[--host addr] [--port n]", summary: "Serve an OpenAI-compatible API", run: runServeAPI},
+		{name: "--stdio", summary: "Speak JSON-RPC on stdin/stdout for editor integrations", run: func([]string) int { return runStdio() }},
+		{name: "help", summary: "Show this help", run: runHelp},
+	}
+}
+
+// subcommandAliases are other names for subcommands
+var subcommandAliases = map[string]string{
+	"--print": "-p",
+	"--json":  "-p",
+	"-h":      "help",
+	"--help":  "help",
+}
+
+// findSubcommand returns the subcommand args asks for and the arguments to
+// run it with. Anything else, including no arguments, is a prompt for chat.
+func findSubcommand(args []string) (*subcommand, []string) {
+	if len(args) > 0 {
+		name := args[0]
+		if alias, ok := subcommandAliases[name]; ok {
+			name = alias
+		}
+		for _, sub := range subcommands {
+			if sub.name != name {
+				continue
+			}
+			// A flag-like command sees its flag, e.g. -p sees --json
+			if strings.HasPrefix(sub.name, "-") {
+				return sub, args
+			}
+			return sub, args[1:]
+		}
+	}
+	return subcommands[0], args
+}
+
+// runHelp prints the commands and global flags
+func runHelp([]string) int {
+	fmt.Println("Usage: cocli [global flags] [command] [arguments]")
+	fmt.Println("")
+	fmt.Println("Commands:")
+	for _, sub := range subcommands {
+		fmt.Printf("  %-10s %s\n", sub.name, sub.summary)
+		if sub.usage != "" {
+			fmt.Printf("  %-10s   cocli %s %s\n", "", sub.name, sub.usage)
+		}
+	}
+	fmt.Println("")
+	fmt.Println("Global flags, before the command or among its arguments (but not after a prompt):")
+	fmt.Println("  --server host:port  Use the daemon on another machine")
+	fmt.Printf("  --style name        Renderer style (%s, or a .json style file)\n", strings.Join(config.StyleNames(), ", "))
+	fmt.Println("  --no-color          Write replies as plain text")
+	fmt.Println("  --output path       Append the markdown of every reply to a file")
+	fmt.Println("  --quiet             Print only replies, warnings and errors")
+	fmt.Println("  --verbose           Log debug diagnostics")
+	fmt.Println("  --debug             Log session events to ~/.cocli/debug.log")
+	return 0
+}
+
+// usageError reports wrong arguments to sub and returns the exit code for
+// them
+func usageError(sub string, err error) int {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+	for _, s := range subcommands {
+		if s.name == sub {
+			fmt.Fprintf(os.Stderr, "usage: cocli %s %s\n", s.name, s.usage)
+		}
+	}
+	return 2
+}
+
+// Exit codes of cocli ask, so editor plugins can tell failures apart
+const (
+	exitOK          = 0
+	exitFailed      = 1 // the request failed
+	exitUsage       = 2 // bad arguments, no question or an unknown model
+	exitConfig      = 3 // the config file is invalid
+	exitUnavailable = 4 // the copilot server couldn't be started or reached
+	exitAuth        = 5 // the copilot CLI isn't signed in
+	exitOffline     = 6 // GitHub can't be reached
+	exitRateLimited = 7 // rate limited for longer than the retries waited
+	exitCancelled   = 130
+)
+
+// exitCode returns the exit code for err, or fallback when it isn't one of
+// the failures with a code of its own
+func exitCode(err error, fallback int) int {
+	var limit *session.RateLimitError
+	var actionable *server.ActionableError
+	switch {
+	case server.IsAuthFailure(err):
+		return exitAuth
+	case server.IsOffline(err):
+		return exitOffline
+	case errors.As(err, &limit):
+		return exitRateLimited
+	case errors.As(err, &actionable):
+		// What Diagnose can't put down to the network or sign-in is the
+		// server being gone
+		return exitUnavailable
+	}
+	return fallback
+}
+
+// runAuth shows who the copilot CLI is signed in as, exiting with exitAuth
+// when it isn't
+func runAuth(args []string) int {
+	if len(args) > 1 || (len(args) == 1 && args[0] != "status") {
+		return usageError("auth", nil)
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitConfig
+	}
+	cli, err := newClient(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitCode(err, exitUnavailable)
+	}
+	defer cli.Stop()
+
+	status, err := cli.AuthStatus()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", cli.Diagnose(err))
+		return exitCode(err, exitFailed)
+	}
+	printAuthStatus(status)
+	if !status.Authenticated {
+		return exitAuth
+	}
+	return exitOK
+}
+
+// runSessions manages the saved conversations: prune applies the history
+// limits, as every chat does when it starts
+func runSessions(args []string) int {
+	if len(args) != 1 || args[0] != "prune" {
+		return usageError("sessions", nil)
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitConfig
+	}
+	policy := retentionPolicy(cfg)
+	if !policy.Limited() {
+		fmt.Println("No history limits are set; set history.max_sessions, history.max_age or history.max_size in the config.")
+		return exitOK
+	}
+	dir, err := historyDir()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitFailed
+	}
+	result, err := session.PruneHistory(dir, policy)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitFailed
+	}
+	for _, c := range result.Removed {
+		fmt.Printf("Removed %s  %d turns on %s, updated %s", c.ID, c.TurnCount, c.Model, c.UpdatedAt.Format("Jan 2 15:04"))
+		if label := conversationLabel(&c); label != "" {
+			fmt.Printf(" (%s)", label)
+		}
+		fmt.Println()
+	}
+	fmt.Printf("Removed %d conversations (%s); %d kept.\n", len(result.Removed), config.FormatSize(result.Freed), result.Kept)
+	return exitOK
+}
+
+// runAsk answers one question and exits, like -p but with a choice of model
+// and an exit code for each kind of failure
+func runAsk(args []string) int {
+	fs := flag.NewFlagSet("cocli ask", flag.ContinueOnError)
+	model := fs.String("model", "", "answer with this model (ID, name or alias)")
+	asJSON := fs.Bool("json", false, "stream newline-delimited JSON events instead of markdown")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	return answerOnce(question{
+		prompt: strings.Join(fs.Args(), " "),
+		model:  *model,
+		json:   *asJSON,
+		usage:  "usage: cocli ask [--model m] [--json] <question> (or pipe the question on stdin)",
+	})
+}
+
+// genInstruction asks for the whole file in one block when gen isn't told
+// which block to take
+const genInstruction = "\n\nReply with the complete contents of %s in a single fenced code block."
+
+// runGen answers a prompt and writes a code block of the answer to a file,
+// for code generation from Makefiles and scripts
+func runGen(args []string) int {
+	fs := flag.NewFlagSet("cocli gen", flag.ContinueOnError)
+	out := fs.String("out", "", "write the code to this file (- for stdout)")
+	block := fs.Int("block", 0, "take this code block of the answer, counting from 1 (default: the first)")
+	force := fs.Bool("force", false, "overwrite the file without asking")
+	model := fs.String("model", "", "answer with this model (ID, name or alias)")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	switch {
+	case *out == "":
+		return usageError("gen", errors.New("--out is required"))
+	case *block < 0:
+		return usageError("gen", fmt.Errorf("invalid --block %d", *block))
+	}
+	if *out != "-" && !*force && !confirmOverwrite(*out) {
+		fmt.Fprintf(os.Stderr, "%s exists; not overwritten (use --force)\n", *out)
+		return exitFailed
+	}
+
+	prompt := strings.Join(fs.Args(), " ")
+	if prompt != "" && *block == 0 {
+		prompt += fmt.Sprintf(genInstruction, filepath.Base(*out))
+	}
+	return answerOnce(question{
+		prompt: prompt,
+		model:  *model,
+		usage:  "usage: cocli gen --out file [--block N] [--force] [--model m] <prompt> (or pipe the prompt on stdin)",
+		after: func(sessionMgr *session.Manager) int {
+			return writeCodeBlock(sessionMgr.LastReply(), max(*block, 1), *out)
+		},
+	})
+}
+
+// writeCodeBlock writes code block n of reply, counting from 1, to path, or
+// to stdout when path is "-"
+func writeCodeBlock(reply string, n int, path string) int {
+	blocks := session.CodeBlocks(reply)
+	switch {
+	case len(blocks) == 0:
+		fmt.Fprintln(os.Stderr, "Error: the answer has no code blocks")
+		return exitFailed
+	case n > len(blocks):
+		fmt.Fprintf(os.Stderr, "Error: no code block %d (the answer has %d)\n", n, len(blocks))
+		return exitFailed
+	}
+	code := blocks[n-1].Code + "\n"
+	if path == "-" {
+		fmt.Print(code)
+		return exitOK
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitFailed
+		}
+	}
+	if err := os.WriteFile(path, []byte(code), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to write %s: %v\n", path, err)
+		return exitFailed
+	}
+	lines := strings.Count(code, "\n")
+	fmt.Fprintf(os.Stderr, "Wrote %d %s to %s\n", lines, plural(lines, "line", "lines"), path)
+	return exitOK
+}
+
+// confirmOverwrite reports whether path may be written: it doesn't exist,
+// or the user at the terminal agrees to replace it
+func confirmOverwrite(path string) bool {
+	if _, err := os.Stat(path); err != nil {
+		return true
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return false
+	}
+	fmt.Fprintf(os.Stderr, "%s exists. Overwrite it? [y/N]: ", path)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// runServer manages this machine's daemon like /server, and runs the
+// server in the foreground with `run`
+func runServer(args []string) int {
+	if len(args) == 0 {
+		printServerHelp()
+		return 2
+	}
+	switch args[0] {
+	case "run":
+		return runServerForeground(args[1:])
+	case "token":
+		return runServerToken()
+	case "help":
+		printServerHelp()
+		return 0
+	case "start", "stop", "status", "logs", "install", "uninstall":
+		if _, err := handleServerCommand(args, nil); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	return usageError("server", fmt.Errorf("unknown server command %q", args[0]))
+}
+
+// runModels prints the models the server offers, one per line or as JSON
+func runModels(args []string) int {
+	if len(args) == 0 || args[0] != "list" {
+		return usageError("models", nil)
+	}
+	var refresh, asJSON bool
+	var flags []string
+	for _, arg := range args[1:] {
+		switch arg {
+		case "--refresh":
+			refresh = true
+		case "--json":
+			asJSON = true
+		default:
+			flags = append(flags, arg)
+		}
+	}
+	filter, err := models.ParseFilter(flags)
+	if err != nil {
+		return usageError("models", err)
+	}
+
+	// Only the list goes to stdout; connection messages go to stderr
+	out := os.Stdout
+	os.Stdout = os.Stderr
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	cli, err := newClient(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer cli.Stop()
+
+	fetch := cli.GetModels
+	if refresh {
+		fetch = cli.RefreshModels
+	}
+	list, err := fetch()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", cli.Diagnose(err))
+		return 1
+	}
+	list = filter.Apply(list)
+	if asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(list); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	for _, info := range list {
+		fmt.Fprintln(out, models.Describe(info))
+	}
+	return 0
+}
+
+// runConfig reads and changes keys of the config file, checks it, prints
+// where it is, or lists the environment variables that override it
+func runConfig(args []string) int {
+	if len(args) == 0 {
+		return usageError("config", nil)
+	}
+	if args[0] == "validate" {
+		return runConfigValidate(args[1:])
+	}
+	if args[0] == "env" && len(args) == 1 {
+		printEnv()
+		return 0
+	}
+	path, err := config.DefaultPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	switch {
+	case args[0] == "path" && len(args) == 1:
+		fmt.Println(path)
+		return 0
+	case args[0] == "get" && len(args) == 1:
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "%s: not found (built-in defaults are used)\n", path)
+			return 0
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		os.Stdout.Write(data)
+		return 0
+	case args[0] == "get" && len(args) == 2:
+		value, ok, err := config.Get(path, args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if !ok {
+			fmt.Fprintf(os.Stderr, "%s is not set in %s (the default is used)\n", args[1], path)
+			return 1
+		}
+		// Strings print bare, for scripts
+		var s string
+		if json.Unmarshal(value, &s) == nil {
+			fmt.Println(s)
+		} else {
+			fmt.Println(string(value))
+		}
+		return 0
+	case args[0] == "set" && len(args) == 3:
+		if err := config.Set(path, args[1], args[2]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Printf("Set %s in %s\n", args[1], path)
+		return 0
+	}
+	return usageError("config", nil)
+}
+
+// printEnv lists the environment variables cocli reads, with the config key
+// each overrides and its value here
+func printEnv() {
+	for _, env := range config.EnvVars {
+		value := os.Getenv(env.Name)
+		switch {
+		case value == "":
+			value = "(not set)"
+		case env.Secret:
+			value = "(set)"
+		}
+		fmt.Printf("%s=%s\n", env.Name, value)
+		help := env.Help
+		if env.Key != "" {
+			help += "; overrides " + env.Key
+		}
+		fmt.Printf("    %s\n", help)
+	}
+	fmt.Println("")
+	fmt.Println("Flags override these, and these override the config file.")
+}
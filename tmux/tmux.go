@@ -0,0 +1,68 @@
+// Package tmux captures the contents of tmux panes so they can be attached
+// to prompts as context.
+package tmux
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// DefaultLines is how much scrollback Capture grabs
+const DefaultLines = 200
+
+// LastPane targets the previously active pane, which is the pane the user
+// was in before switching to cocli's
+const LastPane = "{last}"
+
+// ErrNotInTmux is returned when cocli is not running inside tmux
+var ErrNotInTmux = errors.New("not running inside tmux")
+
+// runTmux runs the tmux command and returns its output; tests replace it
+var runTmux = func(args ...string) ([]byte, error) {
+	out, err := exec.Command("tmux", args...).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+		return nil, errors.New(strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return out, err
+}
+
+// Capture returns the last lines of pane's scrollback (visible screen
+// included) with trailing blank lines removed. An empty pane means LastPane.
+func Capture(pane string, lines int) (string, error) {
+	if os.Getenv("TMUX") == "" {
+		return "", ErrNotInTmux
+	}
+	if pane == "" {
+		pane = LastPane
+	}
+	if lines <= 0 {
+		lines = DefaultLines
+	}
+
+	// -J joins wrapped lines so long output isn't split mid-line
+	out, err := runTmux("capture-pane", "-p", "-J", "-t", pane, "-S", fmt.Sprintf("-%d", lines))
+	if err != nil {
+		return "", fmt.Errorf("failed to capture tmux pane %s: %w", pane, err)
+	}
+	return trimCapture(string(out), lines), nil
+}
+
+// trimCapture drops trailing blank lines (an idle pane is mostly empty
+// screen) and keeps at most the last n lines
+func trimCapture(out string, n int) string {
+	all := strings.Split(out, "\n")
+	for i := range all {
+		all[i] = strings.TrimRight(all[i], " \t")
+	}
+	for len(all) > 0 && all[len(all)-1] == "" {
+		all = all[:len(all)-1]
+	}
+	if len(all) > n {
+		all = all[len(all)-n:]
+	}
+	return strings.Join(all, "\n")
+}
@@ -0,0 +1,59 @@
+package tmux
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCapture(t *testing.T) {
+	t.Setenv("TMUX", "/tmp/tmux-1000/default,1234,0")
+	orig := runTmux
+	t.Cleanup(func() { runTmux = orig })
+	var gotArgs []string
+	runTmux = func(args ...string) ([]byte, error) {
+		gotArgs = args
+		return []byte("$ go test\nFAIL   \n\n\n"), nil
+	}
+
+	got, err := Capture("", 0)
+	if err != nil {
+		t.Fatalf("Capture() error = %v", err)
+	}
+	if got != "$ go test\nFAIL" {
+		t.Errorf("Capture() = %q, want trailing blanks trimmed", got)
+	}
+	want := []string{"capture-pane", "-p", "-J", "-t", LastPane, "-S", "-200"}
+	if !reflect.DeepEqual(gotArgs, want) {
+		t.Errorf("tmux args = %q, want %q", gotArgs, want)
+	}
+
+	runTmux = func(args ...string) ([]byte, error) { return nil, errors.New("can't find pane: %9") }
+	if _, err := Capture("%9", 10); err == nil {
+		t.Error("Capture() of a missing pane should fail")
+	}
+}
+
+func TestCapture_NotInTmux(t *testing.T) {
+	t.Setenv("TMUX", "")
+	if _, err := Capture("", 0); !errors.Is(err, ErrNotInTmux) {
+		t.Errorf("Capture() error = %v, want ErrNotInTmux", err)
+	}
+}
+
+func TestTrimCapture(t *testing.T) {
+	tests := []struct {
+		out  string
+		n    int
+		want string
+	}{
+		{out: "", n: 5, want: ""},
+		{out: "a\nb\nc\n", n: 2, want: "b\nc"},
+		{out: "a  \n\n b\n\n", n: 5, want: "a\n\n b"},
+	}
+	for _, tt := range tests {
+		if got := trimCapture(tt.out, tt.n); got != tt.want {
+			t.Errorf("trimCapture(%q, %d) = %q, want %q", tt.out, tt.n, got, tt.want)
+		}
+	}
+}